@@ -20,6 +20,7 @@ package diskcache
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -33,6 +34,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ErrHashMismatch is returned by Put if the content does not match the hash
+var ErrHashMismatch = errors.New("hashes did not match")
+
 // ReadHandler should be implemented by cache users for use with Cache.Get
 type ReadHandler func(exists bool, contents io.ReadSeeker) error
 
@@ -124,8 +128,8 @@ func (c *Cache) Put(key string, content io.Reader, contentSHA256 string) error {
 		if actualContentSHA256 != contentSHA256 {
 			removeTemp(temp.Name())
 			return fmt.Errorf(
-				"hashes did not match for '%s', given: '%s' actual: '%s",
-				key, contentSHA256, actualContentSHA256)
+				"%w for '%s', given: '%s' actual: '%s",
+				ErrHashMismatch, key, contentSHA256, actualContentSHA256)
 		}
 	}
 
@@ -154,9 +158,24 @@ func (c *Cache) Get(key string, readHandler ReadHandler) error {
 		}
 		return fmt.Errorf("failed to get key: %w", err)
 	}
+	defer f.Close()
 	return readHandler(true, f)
 }
 
+// Contains returns true if there is an entry at key, the access time of the
+// entry is updated so that it is not evicted before it is read
+func (c *Cache) Contains(key string) bool {
+	path := c.KeyToPath(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if err := os.Chtimes(path, time.Now(), info.ModTime()); err != nil {
+		logrus.WithError(err).Warnf("Failed to update access time of %s", path)
+	}
+	return true
+}
+
 // EntryInfo are returned when getting entries from the cache
 type EntryInfo struct {
 	Path       string
@@ -162,6 +162,9 @@ func TestCacheStorage(t *testing.T) {
 		} else if err == nil {
 			expectedKeys.Insert(tc.Key)
 		}
+		if cache.Contains(tc.Key) == tc.PutShouldError {
+			t.Fatalf("Expected Contains to be %t for test case '%s'", !tc.PutShouldError, tc.Name)
+		}
 
 		err = cache.Get(tc.Key, func(exists bool, contents io.ReadSeeker) error {
 			if exists && tc.PutShouldError {
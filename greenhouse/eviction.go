@@ -28,8 +28,10 @@ import (
 // monitorDiskAndEvict loops monitoring the disk, evicting cache entries
 // when the disk passes either minPercentBlocksFree until the disk is above
 // evictUntilPercentBlocksFree
+// if owns is not nil, entries of keys it does not own are evicted first
 func monitorDiskAndEvict(
 	c *diskcache.Cache,
+	owns func(key string) bool,
 	interval time.Duration,
 	minPercentBlocksFree, evictUntilPercentBlocksFree float64,
 ) {
@@ -53,7 +55,18 @@ func monitorDiskAndEvict(
 			// get all cache entries and sort by lastaccess
 			// so we can pop entries until we have evicted enough
 			files := c.GetEntries()
+			// entries owned by peers are replicas or left over from before
+			// the peers changed
+			foreign := map[string]bool{}
+			if owns != nil {
+				for _, file := range files {
+					foreign[file.Path] = !owns(c.PathToKey(file.Path))
+				}
+			}
 			sort.Slice(files, func(i, j int) bool {
+				if foreign[files[i].Path] != foreign[files[j].Path] {
+					return foreign[files[i].Path]
+				}
 				return files[i].LastAccess.Before(files[j].LastAccess)
 			})
 			// evict until we pass the safe threshold so we don't thrash at the eviction trigger
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hashring implements consistent hashing of cache keys to greenhouse
+// instances
+package hashring
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of times each member is placed on the
+// ring, which spreads keys evenly across a handful of members
+const DefaultVirtualNodes = 128
+
+// Ring assigns keys to members such that adding or removing a member only
+// moves the keys of that member
+type Ring struct {
+	points  []uint32
+	owners  map[uint32]string
+	members []string
+}
+
+// New returns a ring placing each of the members virtualNodes times
+func New(virtualNodes int, members ...string) *Ring {
+	r := &Ring{owners: map[uint32]string{}}
+	seen := map[string]bool{}
+	for _, member := range members {
+		if seen[member] {
+			continue
+		}
+		seen[member] = true
+		r.members = append(r.members, member)
+		for i := 0; i < virtualNodes; i++ {
+			point := hash(member + "#" + strconv.Itoa(i))
+			// on collision the member that sorts first wins, independent
+			// of the order of members
+			if owner, exists := r.owners[point]; exists && owner < member {
+				continue
+			} else if !exists {
+				r.points = append(r.points, point)
+			}
+			r.owners[point] = member
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	sort.Strings(r.members)
+	return r
+}
+
+// Owner returns the member that owns key, or "" if the ring is empty
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	point := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= point })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Members returns the sorted members of the ring
+func (r *Ring) Members() []string {
+	return r.members
+}
+
+func hash(s string) uint32 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
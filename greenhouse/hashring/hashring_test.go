@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hashring
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEmptyRing(t *testing.T) {
+	if owner := New(DefaultVirtualNodes).Owner("key"); owner != "" {
+		t.Errorf("expected no owner, got %q", owner)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	members := []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080"}
+	ring := New(DefaultVirtualNodes, members...)
+	reordered := New(DefaultVirtualNodes, members[2], members[0], members[1], members[0])
+	if !reflect.DeepEqual(ring.Members(), members) || !reflect.DeepEqual(reordered.Members(), members) {
+		t.Fatalf("expected members %v, got %v and %v", members, ring.Members(), reordered.Members())
+	}
+
+	keys := 3000
+	counts := map[string]int{}
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("repo/cas/%d", i)
+		owner := ring.Owner(key)
+		if other := reordered.Owner(key); other != owner {
+			t.Fatalf("expected the owner of %s not to depend on the order of members, got %s and %s", key, owner, other)
+		}
+		counts[owner]++
+	}
+	for _, member := range members {
+		// each member should get roughly a third of the keys
+		if counts[member] < keys/5 {
+			t.Errorf("expected keys to be spread evenly, got %v", counts)
+		}
+	}
+}
+
+func TestOwnerStability(t *testing.T) {
+	ring := New(DefaultVirtualNodes, "a", "b", "c")
+	grown := New(DefaultVirtualNodes, "a", "b", "c", "d")
+	keys, moved := 3000, 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("repo/cas/%d", i)
+		before, after := ring.Owner(key), grown.Owner(key)
+		if before == after {
+			continue
+		}
+		if after != "d" {
+			t.Fatalf("expected %s to move from %s to the new member, got %s", key, before, after)
+		}
+		moved++
+	}
+	// about a quarter of the keys should move to the new member
+	if moved == 0 || moved > keys/2 {
+		t.Errorf("expected about %d keys to move, got %d", keys/4, moved)
+	}
+}
@@ -21,10 +21,18 @@ limitations under the License.
 // the first path segment in each {PUT,GET} request is mapped to an individual
 // workspace cache, the remaining segments should follow [2].
 //
+// the same cache is also served over gRPC using the ContentAddressableStorage,
+// ActionCache and ByteStream services of the remote execution API [3], with
+// the instance name taking the place of the first path segment.
+//
+// several instances can shard the cache by consistent hashing of the keys,
+// see peers.go.
+//
 // nursery assumes you are using SHA256
 //
 // [1] https://docs.bazel.build/versions/master/remote-caching.html
 // [2] https://docs.bazel.build/versions/master/remote-caching.html#http-caching-protocol
+// [3] https://github.com/bazelbuild/remote-apis
 package main
 
 import (
@@ -32,6 +40,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -43,11 +52,13 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 var dir = flag.String("dir", "", "location to store cache entries on disk")
 var host = flag.String("host", "", "host address to listen on")
 var cachePort = flag.Int("cache-port", 8080, "port to listen on for cache requests")
+var grpcPort = flag.Int("grpc-port", 0, "port to listen on for remote execution API cache requests, disabled if 0")
 var metricsPort = flag.Int("metrics-port", 9090, "port to listen on for prometheus metrics scraping")
 var metricsUpdateInterval = flag.Duration("metrics-update-interval", time.Second*10,
 	"interval between updating disk metrics")
@@ -60,6 +71,22 @@ var evictUntilPercentBlocksFree = flag.Float64("evict-until-percent-blocks-free"
 var diskCheckInterval = flag.Duration("disk-check-interval", time.Second*10,
 	"interval between checking disk usage (and potentially evicting entries)")
 
+// sharding knobs, see peers.go
+var peerList = flag.String("peers", "",
+	"comma separated host:port cache addresses of greenhouse instances to shard the cache with")
+var peerDNS = flag.String("peer-dns", "",
+	"headless service resolving to greenhouse instances to shard the cache with, reached at --cache-port")
+var advertiseAddress = flag.String("advertise-address", "",
+	"host:port at which peers reach the cache of this instance, required with --peers or --peer-dns")
+var peerMode = flag.String("peer-mode", peerModeForward,
+	"how requests for keys owned by peers are served, either 'forward' or 'redirect'")
+var peerRefreshInterval = flag.Duration("peer-refresh-interval", time.Second*30,
+	"interval between refreshing the peers (and resetting the read counts for replication)")
+var peerTimeout = flag.Duration("peer-timeout", time.Minute,
+	"timeout of requests to peers, entries that cannot be read from their owner in time are served from the local disk or are a miss")
+var replicateAfter = flag.Int("replicate-after", 10,
+	"reads of an entry owned by a peer within --peer-refresh-interval after which it is stored locally too, 0 disables replication")
+
 // global metrics object, see prometheus.go
 var promMetrics *prometheusMetrics
 
@@ -77,8 +104,31 @@ func main() {
 	}
 
 	cache := diskcache.NewCache(*dir)
+	var store cacheStore = cache
+	handler := cacheHandler(cache)
+	var owns func(key string) bool
+	if *peerList != "" || *peerDNS != "" {
+		if *peerList != "" && *peerDNS != "" {
+			logrus.Fatal("only one of --peers and --peer-dns may be set!")
+		}
+		if *advertiseAddress == "" {
+			logrus.Fatal("--advertise-address must be set when sharding!")
+		}
+		if *peerMode != peerModeForward && *peerMode != peerModeRedirect {
+			logrus.Fatalf("--peer-mode must be '%s' or '%s'!", peerModeForward, peerModeRedirect)
+		}
+		source := staticPeers(strings.Split(*peerList, ","))
+		if *peerDNS != "" {
+			source = dnsPeers(*peerDNS, *cachePort)
+		}
+		sharded := newShardedCache(cache, *advertiseAddress, *replicateAfter, *peerTimeout)
+		sharded.refreshPeers(source)
+		go sharded.syncPeers(*peerRefreshInterval, source)
+		store, handler, owns = sharded, sharded.handler(*peerMode), sharded.owns
+	}
+
 	go monitorDiskAndEvict(
-		cache, *diskCheckInterval,
+		cache, owns, *diskCheckInterval,
 		*minPercentBlocksFree, *evictUntilPercentBlocksFree,
 	)
 
@@ -95,9 +145,26 @@ func main() {
 		).Fatal("ListenAndServe returned.")
 	}()
 
+	// listen for remote execution API cache requests
+	if *grpcPort != 0 {
+		grpcAddr := fmt.Sprintf("%s:%d", *host, *grpcPort)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Failed to listen on: %s", grpcAddr)
+		}
+		grpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(maxGRPCMessageSize))
+		registerRemoteCache(grpcServer, store)
+		go func() {
+			logrus.Infof("gRPC Cache Listening on: %s", grpcAddr)
+			logrus.WithField("mux", "grpc").WithError(
+				grpcServer.Serve(listener),
+			).Fatal("Serve returned.")
+		}()
+	}
+
 	// listen for cache requests
 	cacheMux := http.NewServeMux()
-	cacheMux.Handle("/", cacheHandler(cache))
+	cacheMux.Handle("/", handler)
 	cacheAddr := fmt.Sprintf("%s:%d", *host, *cachePort)
 	logrus.Infof("Cache Listening on: %s", cacheAddr)
 	logrus.WithField("mux", "cache").WithError(
@@ -108,7 +175,15 @@ func main() {
 // file not found error, used below
 var errNotFound = errors.New("entry not found")
 
-func cacheHandler(cache *diskcache.Cache) http.Handler {
+// cacheStore stores cache entries by key, either on the local disk or on the
+// peer that owns the key
+type cacheStore interface {
+	Contains(key string) bool
+	Get(key string, readHandler diskcache.ReadHandler) error
+	Put(key string, content io.Reader, contentSHA256 string) error
+}
+
+func cacheHandler(cache cacheStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger := logrus.WithFields(logrus.Fields{
 			"method": r.Method,
@@ -166,6 +241,12 @@ func cacheHandler(cache *diskcache.Cache) http.Handler {
 				promMetrics.CASHits.Inc()
 			}
 
+		// handle existence checks
+		case http.MethodHead:
+			if !cache.Contains(r.URL.Path) {
+				w.WriteHeader(http.StatusNotFound)
+			}
+
 		// handle upload
 		case http.MethodPut:
 			// only hash CAS, not action cache
@@ -0,0 +1,333 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/greenhouse/diskcache"
+	"k8s.io/test-infra/greenhouse/hashring"
+)
+
+const (
+	// peerModeForward serves requests for keys owned by a peer by reading
+	// and writing the entry on the peer
+	peerModeForward = "forward"
+	// peerModeRedirect redirects requests for keys owned by a peer to it
+	peerModeRedirect = "redirect"
+
+	// peerHeader marks requests from peers, which are always served from
+	// the local disk
+	peerHeader = "X-Greenhouse-Peer"
+
+	// entries owned by peers up to this size are read into memory instead of
+	// a temporary file
+	maxInMemoryPeerEntry = 1024 * 1024
+
+	// peerDialTimeout bounds connecting to a peer, unreachable peers are
+	// treated like a cache miss
+	peerDialTimeout = 5 * time.Second
+)
+
+// peerSource lists the cache addresses (host:port) of greenhouse instances
+type peerSource func() ([]string, error)
+
+// staticPeers returns a fixed list of peers
+func staticPeers(peers []string) peerSource {
+	return func() ([]string, error) {
+		return peers, nil
+	}
+}
+
+// dnsPeers looks up the pods behind a headless service
+func dnsPeers(name string, port int) peerSource {
+	return func() ([]string, error) {
+		hosts, err := net.LookupHost(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", name, err)
+		}
+		var peers []string
+		for _, host := range hosts {
+			peers = append(peers, net.JoinHostPort(host, strconv.Itoa(port)))
+		}
+		return peers, nil
+	}
+}
+
+// shardedCache spreads cache entries over several greenhouse instances by
+// consistent hashing of their keys. Entries owned by peers are read and
+// written using the HTTP caching protocol of the peer.
+type shardedCache struct {
+	local  *diskcache.Cache
+	self   string
+	client *http.Client
+	// replicateAfter is the number of reads of an entry owned by a peer after
+	// which it is stored on the local disk too, 0 disables replication
+	replicateAfter int
+
+	lock  sync.RWMutex
+	ring  *hashring.Ring
+	reads map[string]int
+}
+
+// newShardedCache returns a cache that is only sharded with itself until the
+// peers are set, requests to peers taking longer than timeout fail
+func newShardedCache(local *diskcache.Cache, self string, replicateAfter int, timeout time.Duration) *shardedCache {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   peerDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	return &shardedCache{
+		local:          local,
+		self:           self,
+		client:         &http.Client{Transport: transport, Timeout: timeout},
+		replicateAfter: replicateAfter,
+		ring:           hashring.New(hashring.DefaultVirtualNodes, self),
+		reads:          map[string]int{},
+	}
+}
+
+// setPeers updates the ring, this instance is always a member
+func (s *shardedCache) setPeers(peers []string) {
+	var members []string
+	for _, peer := range append([]string{s.self}, peers...) {
+		if peer != "" {
+			members = append(members, peer)
+		}
+	}
+	ring := hashring.New(hashring.DefaultVirtualNodes, members...)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	promMetrics.Peers.Set(float64(len(ring.Members())))
+	if reflect.DeepEqual(ring.Members(), s.ring.Members()) {
+		return
+	}
+	logrus.WithField("peers", ring.Members()).Info("Cache peers changed")
+	s.ring = ring
+}
+
+// refreshPeers updates the peers from source
+func (s *shardedCache) refreshPeers(source peerSource) {
+	peers, err := source()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list peers")
+		return
+	}
+	s.setPeers(peers)
+}
+
+// syncPeers refreshes the peers and resets the read counts every interval
+func (s *shardedCache) syncPeers(interval time.Duration, source peerSource) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		s.refreshPeers(source)
+		s.lock.Lock()
+		s.reads = map[string]int{}
+		s.lock.Unlock()
+	}
+}
+
+// owner returns the cache address of the instance owning key
+func (s *shardedCache) owner(key string) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.ring.Owner(strings.TrimPrefix(key, "/"))
+}
+
+// owns returns true if key belongs on the local disk
+func (s *shardedCache) owns(key string) bool {
+	return s.owner(key) == s.self
+}
+
+// isCAS returns true for content addressed entries, only these are
+// replicated since they never change
+func isCAS(key string) bool {
+	parts := strings.Split(key, "/")
+	return len(parts) >= 2 && parts[len(parts)-2] == "cas"
+}
+
+// hot counts a read of an entry owned by a peer and returns true once the
+// entry should be replicated
+func (s *shardedCache) hot(key string) bool {
+	if s.replicateAfter <= 0 || !isCAS(key) {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reads[key]++
+	return s.reads[key] >= s.replicateAfter
+}
+
+func peerURL(peer, key string) string {
+	return "http://" + peer + "/" + strings.TrimPrefix(key, "/")
+}
+
+func (s *shardedCache) peerRequest(method, peer, key string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, peerURL(peer, key), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(peerHeader, s.self)
+	return s.client.Do(req)
+}
+
+// Contains implements cacheStore
+func (s *shardedCache) Contains(key string) bool {
+	owner := s.owner(key)
+	if owner == s.self {
+		return s.local.Contains(key)
+	}
+	if isCAS(key) && s.local.Contains(key) {
+		return true
+	}
+	resp, err := s.peerRequest(http.MethodHead, owner, key, nil)
+	if err != nil {
+		promMetrics.PeerErrors.Inc()
+		logrus.WithError(err).Warnf("Failed to look up %s on %s", key, owner)
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// Get implements cacheStore, entries that cannot be read from their owner
+// are served from the local disk if they were replicated and are a miss
+// otherwise
+func (s *shardedCache) Get(key string, readHandler diskcache.ReadHandler) error {
+	owner := s.owner(key)
+	if owner == s.self || (isCAS(key) && s.local.Contains(key)) {
+		return s.local.Get(key, readHandler)
+	}
+	handled, err := s.getFromPeer(owner, key, readHandler)
+	if handled {
+		return err
+	}
+	promMetrics.PeerErrors.Inc()
+	logrus.WithError(err).Warnf("Failed to get %s from %s, falling back to the local disk", key, owner)
+	return s.local.Get(key, readHandler)
+}
+
+// getFromPeer reads key from its owner, it returns false if the entry could
+// not be read and readHandler was not called
+func (s *shardedCache) getFromPeer(owner, key string, readHandler diskcache.ReadHandler) (bool, error) {
+	resp, err := s.peerRequest(http.MethodGet, owner, key, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get key from %s: %w", owner, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return true, readHandler(false, nil)
+	default:
+		return false, fmt.Errorf("failed to get key from %s: %s", owner, resp.Status)
+	}
+
+	if s.hot(key) {
+		if err := s.local.Put(key, resp.Body, path.Base(key)); err != nil {
+			return false, fmt.Errorf("failed to replicate key: %w", err)
+		}
+		promMetrics.ReplicatedEntries.Inc()
+		return true, s.local.Get(key, readHandler)
+	}
+	// readers need to seek, so the entry has to be kept while it is read
+	if resp.ContentLength >= 0 && resp.ContentLength <= maxInMemoryPeerEntry {
+		content, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("failed to get key from %s: %w", owner, err)
+		}
+		return true, readHandler(true, bytes.NewReader(content))
+	}
+	temp, err := ioutil.TempFile("", "greenhouse-peer-get")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer removeTemp(temp)
+	if _, err := io.Copy(temp, resp.Body); err != nil {
+		return false, fmt.Errorf("failed to get key from %s: %w", owner, err)
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return false, fmt.Errorf("failed to read temp file: %w", err)
+	}
+	return true, readHandler(true, temp)
+}
+
+func removeTemp(f *os.File) {
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		logrus.WithError(err).Errorf("Failed to remove a temp file: %v", f.Name())
+	}
+}
+
+// Put implements cacheStore
+func (s *shardedCache) Put(key string, content io.Reader, contentSHA256 string) error {
+	owner := s.owner(key)
+	if owner == s.self {
+		return s.local.Put(key, content, contentSHA256)
+	}
+	hasher := sha256.New()
+	resp, err := s.peerRequest(http.MethodPut, owner, key, io.TeeReader(content, hasher))
+	if err != nil {
+		promMetrics.PeerErrors.Inc()
+		return fmt.Errorf("failed to put key on %s: %w", owner, err)
+	}
+	resp.Body.Close()
+	if actual := hex.EncodeToString(hasher.Sum(nil)); contentSHA256 != "" && actual != contentSHA256 {
+		return fmt.Errorf(
+			"%w for '%s', given: '%s' actual: '%s",
+			diskcache.ErrHashMismatch, key, contentSHA256, actual)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put key on %s: %s", owner, resp.Status)
+	}
+	return nil
+}
+
+// handler serves HTTP cache requests, requests from peers are always
+// served from the local disk
+func (s *shardedCache) handler(mode string) http.Handler {
+	local := cacheHandler(s.local)
+	sharded := cacheHandler(s)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(peerHeader) != "" {
+			local.ServeHTTP(w, r)
+			return
+		}
+		if owner := s.owner(r.URL.Path); mode == peerModeRedirect && owner != s.self {
+			http.Redirect(w, r, peerURL(owner, r.URL.Path), http.StatusTemporaryRedirect)
+			return
+		}
+		sharded.ServeHTTP(w, r)
+	})
+}
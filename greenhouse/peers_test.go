@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/greenhouse/diskcache"
+)
+
+type testPeer struct {
+	cache  *shardedCache
+	server *httptest.Server
+}
+
+func newTestPeers(t *testing.T, mode string, n int) []testPeer {
+	var peers []testPeer
+	var addresses []string
+	for i := 0; i < n; i++ {
+		dir, err := ioutil.TempDir("", "peer-tests")
+		if err != nil {
+			t.Fatalf("Failed to create tempdir for tests! %v", err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		server := httptest.NewUnstartedServer(nil)
+		address := server.Listener.Addr().String()
+		cache := newShardedCache(diskcache.NewCache(dir), address, 2, time.Minute)
+		server.Config.Handler = cache.handler(mode)
+		server.Start()
+		t.Cleanup(server.Close)
+		peers = append(peers, testPeer{cache: cache, server: server})
+		addresses = append(addresses, address)
+	}
+	for _, peer := range peers {
+		peer.cache.setPeers(addresses)
+	}
+	return peers
+}
+
+// keyOwnedBy returns content whose CAS key is owned by peer
+func keyOwnedBy(t *testing.T, peer testPeer) (string, []byte) {
+	for i := 0; i < 1000; i++ {
+		content := []byte(fmt.Sprintf("blob-%d", i))
+		key := "/repo/cas/" + digestOf(content).Hash
+		if peer.cache.owns(key) {
+			return key, content
+		}
+	}
+	t.Fatal("failed to find a key owned by the peer")
+	return "", nil
+}
+
+func TestShardedCache(t *testing.T) {
+	peers := newTestPeers(t, peerModeForward, 2)
+	a, b := peers[0], peers[1]
+	key, content := keyOwnedBy(t, b)
+	if a.cache.owns(key) {
+		t.Fatalf("expected %s to be owned by a single peer", key)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, a.server.URL+key, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected put to succeed, got %s", resp.Status)
+	}
+	if !b.cache.local.Contains(key) || a.cache.local.Contains(key) {
+		t.Fatalf("expected the entry to be stored only by its owner")
+	}
+	if !a.cache.Contains(key) {
+		t.Errorf("expected the entry owned by the peer to be found")
+	}
+
+	get := func() {
+		resp, err := http.Get(a.server.URL + key)
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		defer resp.Body.Close()
+		if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || !bytes.Equal(body, content) {
+			t.Errorf("expected to get %q, got %s: %q", content, resp.Status, body)
+		}
+	}
+	get()
+	if a.cache.local.Contains(key) {
+		t.Errorf("expected the entry not to be replicated after one read")
+	}
+	get()
+	if !a.cache.local.Contains(key) {
+		t.Errorf("expected the entry to be replicated after two reads")
+	}
+	get()
+
+	missing := "/repo/cas/" + digestOf([]byte("missing")).Hash
+	for _, peer := range peers {
+		resp, err := http.Head(peer.server.URL + missing)
+		if err != nil {
+			t.Fatalf("failed to look up missing entry: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected missing entry not to be found, got %s", resp.Status)
+		}
+	}
+
+	// a hash mismatch is detected before the owner rejects the entry
+	err = a.cache.Put(key, bytes.NewReader([]byte("other")), digestOf(content).Hash)
+	if err == nil {
+		t.Errorf("expected putting content with the wrong hash to fail")
+	}
+}
+
+func TestShardedCacheRedirect(t *testing.T) {
+	peers := newTestPeers(t, peerModeRedirect, 2)
+	a, b := peers[0], peers[1]
+	key, _ := keyOwnedBy(t, b)
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(a.server.URL + key)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	resp.Body.Close()
+	if expected := b.server.URL + key; resp.StatusCode != http.StatusTemporaryRedirect || resp.Header.Get("Location") != expected {
+		t.Errorf("expected a redirect to %s, got %s to %s", expected, resp.Status, resp.Header.Get("Location"))
+	}
+}
+
+func TestShardedCachePeerFailure(t *testing.T) {
+	hung := make(chan struct{})
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	t.Cleanup(peer.Close)
+	t.Cleanup(func() { close(hung) })
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	dir, err := ioutil.TempDir("", "peer-tests")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir for tests! %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	self := "self:8080"
+	cache := newShardedCache(diskcache.NewCache(dir), self, 0, 100*time.Millisecond)
+
+	for _, failing := range []*httptest.Server{peer, unreachable} {
+		address := failing.Listener.Addr().String()
+		cache.setPeers([]string{address})
+		var key string
+		var content []byte
+		for i := 0; i < 1000 && key == ""; i++ {
+			content = []byte(fmt.Sprintf("blob-%d", i))
+			if candidate := "/repo/cas/" + digestOf(content).Hash; cache.owner(candidate) == address {
+				key = candidate
+			}
+		}
+		if key == "" {
+			t.Fatal("failed to find a key owned by the peer")
+		}
+
+		if cache.Contains(key) {
+			t.Errorf("expected %s not to be found on a failing peer", key)
+		}
+		var found bool
+		if err := cache.Get(key, func(exists bool, _ io.ReadSeeker) error {
+			found = exists
+			return nil
+		}); err != nil {
+			t.Errorf("expected a failing peer to be a miss, got: %v", err)
+		}
+		if found {
+			t.Errorf("expected %s not to be found on a failing peer", key)
+		}
+
+		// replicated entries are served from the local disk
+		if err := cache.local.Put(key, bytes.NewReader(content), digestOf(content).Hash); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+		if err := cache.Get(key, func(exists bool, _ io.ReadSeeker) error {
+			found = exists
+			return nil
+		}); err != nil || !found {
+			t.Errorf("expected the local entry to be found, got %t: %v", found, err)
+		}
+	}
+}
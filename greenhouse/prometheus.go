@@ -31,6 +31,9 @@ type prometheusMetrics struct {
 	ActionCacheMisses    prometheus.Counter
 	CASMisses            prometheus.Counter
 	LastEvictedAccessAge prometheus.Gauge
+	Peers                prometheus.Gauge
+	ReplicatedEntries    prometheus.Counter
+	PeerErrors           prometheus.Counter
 }
 
 func initMetrics() *prometheusMetrics {
@@ -71,6 +74,18 @@ func initMetrics() *prometheusMetrics {
 			Name: "bazel_cache_last_evicted_access_age",
 			Help: "Hours since last access of most recently evicted file (at eviction time).",
 		}),
+		Peers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "bazel_cache_peers",
+			Help: "Number of greenhouse instances the cache is sharded with, including this one.",
+		}),
+		ReplicatedEntries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bazel_cache_replicated_entries",
+			Help: "Number of entries owned by peers that were stored locally since last server start.",
+		}),
+		PeerErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "bazel_cache_peer_errors",
+			Help: "Number of requests to peers that failed or timed out since last server start.",
+		}),
 	}
 	prometheus.MustRegister(metrics.DiskFree)
 	prometheus.MustRegister(metrics.DiskUsed)
@@ -81,5 +96,8 @@ func initMetrics() *prometheusMetrics {
 	prometheus.MustRegister(metrics.ActionCacheMisses)
 	prometheus.MustRegister(metrics.CASMisses)
 	prometheus.MustRegister(metrics.LastEvictedAccessAge)
+	prometheus.MustRegister(metrics.Peers)
+	prometheus.MustRegister(metrics.ReplicatedEntries)
+	prometheus.MustRegister(metrics.PeerErrors)
 	return metrics
 }
@@ -0,0 +1,469 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/bazelbuild/remote-apis/build/bazel/semver"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"k8s.io/test-infra/greenhouse/diskcache"
+)
+
+const (
+	// maxBatchTotalSize is the maximum size of the blobs in a batch request,
+	// larger blobs are transferred with the ByteStream API
+	maxBatchTotalSize = 4 * 1024 * 1024
+	// maxGRPCMessageSize leaves room for the digests in a batch request
+	maxGRPCMessageSize = maxBatchTotalSize + 1024*1024
+	// byteStreamChunkSize is the size of the messages returned by Read
+	byteStreamChunkSize = 1024 * 1024
+	// emptyHash is the SHA256 of the empty blob, which is never uploaded
+	emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+var hashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// remoteCache implements the cache services of the Bazel Remote Execution
+// API [1] on top of the same keys as the HTTP caching protocol, so both can
+// share entries: blobs are stored at <instance>/cas/<hash> and serialized
+// ActionResults at <instance>/ac/<hash>
+//
+// [1] https://github.com/bazelbuild/remote-apis/blob/main/build/bazel/remote/execution/v2/remote_execution.proto
+type remoteCache struct {
+	cache cacheStore
+}
+
+// registerRemoteCache registers the CAS, action cache, ByteStream and
+// capabilities services with server
+func registerRemoteCache(server *grpc.Server, cache cacheStore) {
+	rc := &remoteCache{cache: cache}
+	repb.RegisterContentAddressableStorageServer(server, rc)
+	repb.RegisterActionCacheServer(server, rc)
+	repb.RegisterCapabilitiesServer(server, rc)
+	bytestream.RegisterByteStreamServer(server, rc)
+}
+
+// validateInstanceName makes sure the instance name cannot escape the cache
+// directory or collide with the segments of resource names
+func validateInstanceName(instance string) error {
+	if instance == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(instance, "/") {
+		switch segment {
+		case "", ".", "..", "ac", "cas", "blobs", "uploads", "compressed-blobs":
+			return status.Errorf(codes.InvalidArgument, "invalid instance name %q", instance)
+		}
+	}
+	return nil
+}
+
+func validateDigest(digest *repb.Digest) error {
+	if digest == nil {
+		return status.Error(codes.InvalidArgument, "missing digest")
+	}
+	if !hashRegex.MatchString(digest.Hash) || digest.SizeBytes < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid digest %s/%d, only SHA256 is supported", digest.Hash, digest.SizeBytes)
+	}
+	return nil
+}
+
+func isEmpty(digest *repb.Digest) bool {
+	return digest.SizeBytes == 0 && digest.Hash == emptyHash
+}
+
+func casKey(instance string, digest *repb.Digest) string {
+	return path.Join(instance, "cas", digest.Hash)
+}
+
+func acKey(instance string, digest *repb.Digest) string {
+	return path.Join(instance, "ac", digest.Hash)
+}
+
+// parseResourceName parses ByteStream resource names, which are
+// [{instance_name}/]blobs/{hash}/{size} for reads and
+// [{instance_name}/]uploads/{uuid}/blobs/{hash}/{size}[/{metadata}] for writes
+func parseResourceName(name string, write bool) (string, *repb.Digest, error) {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if part != "blobs" || i+2 >= len(parts) {
+			continue
+		}
+		instanceParts := parts[:i]
+		if write {
+			if i < 2 || parts[i-2] != "uploads" {
+				continue
+			}
+			instanceParts = parts[:i-2]
+		} else if i+3 != len(parts) {
+			continue
+		}
+		size, err := strconv.ParseInt(parts[i+2], 10, 64)
+		if err != nil {
+			return "", nil, status.Errorf(codes.InvalidArgument, "invalid size in resource name %q", name)
+		}
+		instance := strings.Join(instanceParts, "/")
+		if err := validateInstanceName(instance); err != nil {
+			return "", nil, err
+		}
+		digest := &repb.Digest{Hash: parts[i+1], SizeBytes: size}
+		if err := validateDigest(digest); err != nil {
+			return "", nil, err
+		}
+		return instance, digest, nil
+	}
+	return "", nil, status.Errorf(codes.InvalidArgument, "invalid resource name %q", name)
+}
+
+// putError maps errors storing a blob to gRPC errors
+func putError(err error) error {
+	if errors.Is(err, diskcache.ErrHashMismatch) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Errorf(codes.Internal, "failed to put in cache: %v", err)
+}
+
+// FindMissingBlobs returns the digests that are not in the cache
+func (rc *remoteCache) FindMissingBlobs(ctx context.Context, req *repb.FindMissingBlobsRequest) (*repb.FindMissingBlobsResponse, error) {
+	if err := validateInstanceName(req.InstanceName); err != nil {
+		return nil, err
+	}
+	resp := &repb.FindMissingBlobsResponse{}
+	for _, digest := range req.BlobDigests {
+		if err := validateDigest(digest); err != nil {
+			return nil, err
+		}
+		if isEmpty(digest) {
+			continue
+		}
+		if !rc.cache.Contains(casKey(req.InstanceName, digest)) {
+			resp.MissingBlobDigests = append(resp.MissingBlobDigests, digest)
+		}
+	}
+	return resp, nil
+}
+
+// BatchUpdateBlobs stores small blobs
+func (rc *remoteCache) BatchUpdateBlobs(ctx context.Context, req *repb.BatchUpdateBlobsRequest) (*repb.BatchUpdateBlobsResponse, error) {
+	if err := validateInstanceName(req.InstanceName); err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, blob := range req.Requests {
+		total += int64(len(blob.Data))
+	}
+	if total > maxBatchTotalSize {
+		return nil, status.Errorf(codes.InvalidArgument, "batch of %d bytes exceeds the maximum of %d bytes", total, maxBatchTotalSize)
+	}
+	resp := &repb.BatchUpdateBlobsResponse{}
+	for _, blob := range req.Requests {
+		resp.Responses = append(resp.Responses, &repb.BatchUpdateBlobsResponse_Response{
+			Digest: blob.Digest,
+			Status: status.Convert(rc.updateBlob(req.InstanceName, blob)).Proto(),
+		})
+	}
+	return resp, nil
+}
+
+func (rc *remoteCache) updateBlob(instance string, blob *repb.BatchUpdateBlobsRequest_Request) error {
+	if err := validateDigest(blob.Digest); err != nil {
+		return err
+	}
+	if blob.Compressor != repb.Compressor_IDENTITY {
+		return status.Errorf(codes.InvalidArgument, "unsupported compressor %s", blob.Compressor)
+	}
+	if int64(len(blob.Data)) != blob.Digest.SizeBytes {
+		return status.Errorf(codes.InvalidArgument, "got %d bytes for digest %s/%d", len(blob.Data), blob.Digest.Hash, blob.Digest.SizeBytes)
+	}
+	if err := rc.cache.Put(casKey(instance, blob.Digest), bytes.NewReader(blob.Data), blob.Digest.Hash); err != nil {
+		return putError(err)
+	}
+	return nil
+}
+
+// BatchReadBlobs returns the content of small blobs
+func (rc *remoteCache) BatchReadBlobs(ctx context.Context, req *repb.BatchReadBlobsRequest) (*repb.BatchReadBlobsResponse, error) {
+	if err := validateInstanceName(req.InstanceName); err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, digest := range req.Digests {
+		if err := validateDigest(digest); err != nil {
+			return nil, err
+		}
+		total += digest.SizeBytes
+	}
+	if total > maxBatchTotalSize {
+		return nil, status.Errorf(codes.InvalidArgument, "batch of %d bytes exceeds the maximum of %d bytes", total, maxBatchTotalSize)
+	}
+	resp := &repb.BatchReadBlobsResponse{}
+	for _, digest := range req.Digests {
+		data, err := rc.readBlob(req.InstanceName, digest)
+		resp.Responses = append(resp.Responses, &repb.BatchReadBlobsResponse_Response{
+			Digest: digest,
+			Data:   data,
+			Status: status.Convert(err).Proto(),
+		})
+	}
+	return resp, nil
+}
+
+func (rc *remoteCache) readBlob(instance string, digest *repb.Digest) ([]byte, error) {
+	if isEmpty(digest) {
+		return nil, nil
+	}
+	var data []byte
+	err := rc.cache.Get(casKey(instance, digest), func(exists bool, contents io.ReadSeeker) error {
+		if !exists {
+			return errNotFound
+		}
+		var err error
+		data, err = ioutil.ReadAll(contents)
+		return err
+	})
+	if err == errNotFound {
+		promMetrics.CASMisses.Inc()
+		return nil, status.Errorf(codes.NotFound, "blob %s/%d not found", digest.Hash, digest.SizeBytes)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read blob: %v", err)
+	}
+	promMetrics.CASHits.Inc()
+	return data, nil
+}
+
+// GetTree is not supported since greenhouse does not look into the blobs
+func (rc *remoteCache) GetTree(req *repb.GetTreeRequest, stream repb.ContentAddressableStorage_GetTreeServer) error {
+	return status.Error(codes.Unimplemented, "GetTree is not supported")
+}
+
+// GetActionResult returns the cached result of an action
+func (rc *remoteCache) GetActionResult(ctx context.Context, req *repb.GetActionResultRequest) (*repb.ActionResult, error) {
+	if err := validateInstanceName(req.InstanceName); err != nil {
+		return nil, err
+	}
+	if err := validateDigest(req.ActionDigest); err != nil {
+		return nil, err
+	}
+	result := &repb.ActionResult{}
+	err := rc.cache.Get(acKey(req.InstanceName, req.ActionDigest), func(exists bool, contents io.ReadSeeker) error {
+		if !exists {
+			return errNotFound
+		}
+		data, err := ioutil.ReadAll(contents)
+		if err != nil {
+			return err
+		}
+		return proto.Unmarshal(data, result)
+	})
+	if err == errNotFound {
+		promMetrics.ActionCacheMisses.Inc()
+		return nil, status.Errorf(codes.NotFound, "action %s not found", req.ActionDigest.Hash)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("action", req.ActionDigest.Hash).Error("error getting action result")
+		return nil, status.Errorf(codes.Internal, "failed to get action result: %v", err)
+	}
+	promMetrics.ActionCacheHits.Inc()
+	return result, nil
+}
+
+// UpdateActionResult stores the result of an action
+func (rc *remoteCache) UpdateActionResult(ctx context.Context, req *repb.UpdateActionResultRequest) (*repb.ActionResult, error) {
+	if err := validateInstanceName(req.InstanceName); err != nil {
+		return nil, err
+	}
+	if err := validateDigest(req.ActionDigest); err != nil {
+		return nil, err
+	}
+	if req.ActionResult == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing action result")
+	}
+	data, err := proto.Marshal(req.ActionResult)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to marshal action result: %v", err)
+	}
+	// the action cache is hash -> metadata, so the content is not hashed
+	if err := rc.cache.Put(acKey(req.InstanceName, req.ActionDigest), bytes.NewReader(data), ""); err != nil {
+		return nil, putError(err)
+	}
+	return req.ActionResult, nil
+}
+
+// GetCapabilities tells clients to use the cache with SHA256 digests
+func (rc *remoteCache) GetCapabilities(ctx context.Context, req *repb.GetCapabilitiesRequest) (*repb.ServerCapabilities, error) {
+	return &repb.ServerCapabilities{
+		CacheCapabilities: &repb.CacheCapabilities{
+			DigestFunction: []repb.DigestFunction_Value{repb.DigestFunction_SHA256},
+			ActionCacheUpdateCapabilities: &repb.ActionCacheUpdateCapabilities{
+				UpdateEnabled: true,
+			},
+			MaxBatchTotalSizeBytes: maxBatchTotalSize,
+		},
+		LowApiVersion:  &semver.SemVer{Major: 2},
+		HighApiVersion: &semver.SemVer{Major: 2},
+	}, nil
+}
+
+// Read streams the content of a blob
+func (rc *remoteCache) Read(req *bytestream.ReadRequest, stream bytestream.ByteStream_ReadServer) error {
+	instance, digest, err := parseResourceName(req.ResourceName, false)
+	if err != nil {
+		return err
+	}
+	if req.ReadOffset < 0 || req.ReadLimit < 0 {
+		return status.Error(codes.OutOfRange, "negative read offset or limit")
+	}
+	if isEmpty(digest) {
+		return nil
+	}
+	err = rc.cache.Get(casKey(instance, digest), func(exists bool, contents io.ReadSeeker) error {
+		if !exists {
+			return errNotFound
+		}
+		size, err := contents.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if req.ReadOffset > size {
+			return status.Errorf(codes.OutOfRange, "read offset %d is beyond the size of the blob", req.ReadOffset)
+		}
+		if _, err := contents.Seek(req.ReadOffset, io.SeekStart); err != nil {
+			return err
+		}
+		var r io.Reader = contents
+		if req.ReadLimit > 0 {
+			r = io.LimitReader(contents, req.ReadLimit)
+		}
+		buf := make([]byte, byteStreamChunkSize)
+		for {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				if err := stream.Send(&bytestream.ReadResponse{Data: buf[:n]}); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err == errNotFound {
+		promMetrics.CASMisses.Inc()
+		return status.Errorf(codes.NotFound, "blob %s/%d not found", digest.Hash, digest.SizeBytes)
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Internal, "failed to read blob: %v", err)
+	}
+	promMetrics.CASHits.Inc()
+	return nil
+}
+
+// Write stores a blob streamed by the client
+func (rc *remoteCache) Write(stream bytestream.ByteStream_WriteServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	instance, digest, err := parseResourceName(req.ResourceName, true)
+	if err != nil {
+		return err
+	}
+	key := casKey(instance, digest)
+	// the client stops uploading once told that the blob is already complete
+	if isEmpty(digest) || rc.cache.Contains(key) {
+		return stream.SendAndClose(&bytestream.WriteResponse{CommittedSize: digest.SizeBytes})
+	}
+
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := rc.cache.Put(key, reader, digest.Hash)
+		reader.CloseWithError(err)
+		done <- err
+	}()
+	abort := func(err error) error {
+		writer.CloseWithError(err)
+		<-done
+		return err
+	}
+
+	var committed int64
+	for {
+		if req.WriteOffset != committed {
+			return abort(status.Errorf(codes.InvalidArgument, "write offset %d does not match the %d bytes written", req.WriteOffset, committed))
+		}
+		if _, err := writer.Write(req.Data); err != nil {
+			// Put has already returned
+			return putError(<-done)
+		}
+		committed += int64(len(req.Data))
+		if req.FinishWrite {
+			break
+		}
+		req, err = stream.Recv()
+		if err == io.EOF {
+			return abort(status.Error(codes.InvalidArgument, "stream ended before the write was finished"))
+		}
+		if err != nil {
+			return abort(err)
+		}
+	}
+	if committed != digest.SizeBytes {
+		return abort(status.Errorf(codes.InvalidArgument, "got %d bytes for digest %s/%d", committed, digest.Hash, digest.SizeBytes))
+	}
+	writer.Close()
+	if err := <-done; err != nil {
+		return putError(err)
+	}
+	return stream.SendAndClose(&bytestream.WriteResponse{CommittedSize: committed})
+}
+
+// QueryWriteStatus reports whether a blob is complete, partial writes are
+// not kept so clients have to start over otherwise
+func (rc *remoteCache) QueryWriteStatus(ctx context.Context, req *bytestream.QueryWriteStatusRequest) (*bytestream.QueryWriteStatusResponse, error) {
+	instance, digest, err := parseResourceName(req.ResourceName, true)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty(digest) || rc.cache.Contains(casKey(instance, digest)) {
+		return &bytestream.QueryWriteStatusResponse{CommittedSize: digest.SizeBytes, Complete: true}, nil
+	}
+	return &bytestream.QueryWriteStatusResponse{}, nil
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	repb "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/bytestream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"k8s.io/test-infra/greenhouse/diskcache"
+)
+
+func digestOf(b []byte) *repb.Digest {
+	hash := sha256.Sum256(b)
+	return &repb.Digest{Hash: hex.EncodeToString(hash[:]), SizeBytes: int64(len(b))}
+}
+
+func newTestRemoteCache(t *testing.T) *remoteCache {
+	dir, err := ioutil.TempDir("", "remote-cache-tests")
+	if err != nil {
+		t.Fatalf("Failed to create tempdir for tests! %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return &remoteCache{cache: diskcache.NewCache(dir)}
+}
+
+type fakeWriteStream struct {
+	grpc.ServerStream
+	requests []*bytestream.WriteRequest
+	response *bytestream.WriteResponse
+}
+
+func (s *fakeWriteStream) Recv() (*bytestream.WriteRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *fakeWriteStream) SendAndClose(resp *bytestream.WriteResponse) error {
+	s.response = resp
+	return nil
+}
+
+type fakeReadStream struct {
+	grpc.ServerStream
+	data []byte
+}
+
+func (s *fakeReadStream) Send(resp *bytestream.ReadResponse) error {
+	s.data = append(s.data, resp.Data...)
+	return nil
+}
+
+func TestParseResourceName(t *testing.T) {
+	hash := digestOf([]byte("foo")).Hash
+	testCases := []struct {
+		name             string
+		resource         string
+		write            bool
+		expectedInstance string
+		expectedSize     int64
+		expectedErr      bool
+	}{
+		{
+			name:         "read without instance",
+			resource:     "blobs/" + hash + "/3",
+			expectedSize: 3,
+		},
+		{
+			name:             "read with instance",
+			resource:         "some/repo/blobs/" + hash + "/3",
+			expectedInstance: "some/repo",
+			expectedSize:     3,
+		},
+		{
+			name:             "write with metadata",
+			resource:         "repo/uploads/1234-abcd/blobs/" + hash + "/3/some/metadata",
+			write:            true,
+			expectedInstance: "repo",
+			expectedSize:     3,
+		},
+		{
+			name:        "write resource for read",
+			resource:    "repo/uploads/1234-abcd/blobs/" + hash + "/3",
+			expectedErr: true,
+		},
+		{
+			name:        "read resource for write",
+			resource:    "repo/blobs/" + hash + "/3",
+			write:       true,
+			expectedErr: true,
+		},
+		{
+			name:        "instance outside of the cache",
+			resource:    "../blobs/" + hash + "/3",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid hash",
+			resource:    "blobs/../../etc/passwd/3",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid size",
+			resource:    "blobs/" + hash + "/three",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance, digest, err := parseResourceName(tc.resource, tc.write)
+			if tc.expectedErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("expected an invalid argument error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if instance != tc.expectedInstance || digest.Hash != hash || digest.SizeBytes != tc.expectedSize {
+				t.Errorf("expected instance %q and digest %s/%d, got %q and %s/%d", tc.expectedInstance, hash, tc.expectedSize, instance, digest.Hash, digest.SizeBytes)
+			}
+		})
+	}
+}
+
+func TestBatchBlobs(t *testing.T) {
+	rc := newTestRemoteCache(t)
+	ctx := context.Background()
+	foo, bar := []byte("foo"), []byte("bar")
+
+	missing, err := rc.FindMissingBlobs(ctx, &repb.FindMissingBlobsRequest{
+		InstanceName: "repo",
+		BlobDigests:  []*repb.Digest{digestOf(foo), digestOf(nil)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]*repb.Digest{digestOf(foo)}, missing.MissingBlobDigests, protocmp.Transform()); diff != "" {
+		t.Errorf("missing blobs differ from expected (-want +got):\n%s", diff)
+	}
+
+	updated, err := rc.BatchUpdateBlobs(ctx, &repb.BatchUpdateBlobsRequest{
+		InstanceName: "repo",
+		Requests: []*repb.BatchUpdateBlobsRequest_Request{
+			{Digest: digestOf(foo), Data: foo},
+			{Digest: digestOf(foo), Data: bar},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := codes.Code(updated.Responses[0].Status.Code); code != codes.OK {
+		t.Errorf("expected foo to be stored, got %v", code)
+	}
+	if code := codes.Code(updated.Responses[1].Status.Code); code != codes.InvalidArgument {
+		t.Errorf("expected a hash mismatch to be rejected, got %v", code)
+	}
+
+	missing, err = rc.FindMissingBlobs(ctx, &repb.FindMissingBlobsRequest{
+		InstanceName: "repo",
+		BlobDigests:  []*repb.Digest{digestOf(foo), digestOf(bar)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]*repb.Digest{digestOf(bar)}, missing.MissingBlobDigests, protocmp.Transform()); diff != "" {
+		t.Errorf("missing blobs differ from expected (-want +got):\n%s", diff)
+	}
+
+	read, err := rc.BatchReadBlobs(ctx, &repb.BatchReadBlobsRequest{
+		InstanceName: "repo",
+		Digests:      []*repb.Digest{digestOf(foo), digestOf(bar)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(read.Responses[0].Data, foo) || codes.Code(read.Responses[0].Status.Code) != codes.OK {
+		t.Errorf("expected to read foo, got %+v", read.Responses[0])
+	}
+	if codes.Code(read.Responses[1].Status.Code) != codes.NotFound {
+		t.Errorf("expected bar to be missing, got %+v", read.Responses[1])
+	}
+
+	// the blob is shared with the HTTP caching protocol
+	server := httptest.NewServer(cacheHandler(rc.cache))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/repo/cas/" + digestOf(foo).Hash)
+	if err != nil {
+		t.Fatalf("failed to get blob over HTTP: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || !bytes.Equal(body, foo) {
+		t.Errorf("expected to get foo over HTTP, got %d: %q", resp.StatusCode, body)
+	}
+}
+
+func TestActionResults(t *testing.T) {
+	rc := newTestRemoteCache(t)
+	ctx := context.Background()
+	action := digestOf([]byte("action"))
+
+	if _, err := rc.GetActionResult(ctx, &repb.GetActionResultRequest{ActionDigest: action}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected a missing action result, got %v", err)
+	}
+	result := &repb.ActionResult{
+		ExitCode:    1,
+		OutputFiles: []*repb.OutputFile{{Path: "out", Digest: digestOf([]byte("out"))}},
+	}
+	if _, err := rc.UpdateActionResult(ctx, &repb.UpdateActionResultRequest{ActionDigest: action, ActionResult: result}); err != nil {
+		t.Fatalf("failed to update action result: %v", err)
+	}
+	got, err := rc.GetActionResult(ctx, &repb.GetActionResultRequest{ActionDigest: action})
+	if err != nil {
+		t.Fatalf("failed to get action result: %v", err)
+	}
+	if diff := cmp.Diff(result, got, protocmp.Transform()); diff != "" {
+		t.Errorf("action result differs from expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestByteStream(t *testing.T) {
+	rc := newTestRemoteCache(t)
+	content := []byte("some content that is uploaded in chunks")
+	digest := digestOf(content)
+	upload := "repo/uploads/1234/blobs/" + digest.Hash + "/39"
+	write := &fakeWriteStream{requests: []*bytestream.WriteRequest{
+		{ResourceName: upload, Data: content[:10]},
+		{WriteOffset: 10, Data: content[10:20]},
+		{WriteOffset: 20, Data: content[20:], FinishWrite: true},
+	}}
+	if err := rc.Write(write); err != nil {
+		t.Fatalf("failed to write blob: %v", err)
+	}
+	if write.response.CommittedSize != digest.SizeBytes {
+		t.Errorf("expected %d bytes to be committed, got %d", digest.SizeBytes, write.response.CommittedSize)
+	}
+
+	written, err := rc.QueryWriteStatus(context.Background(), &bytestream.QueryWriteStatusRequest{ResourceName: upload})
+	if err != nil {
+		t.Fatalf("failed to query write status: %v", err)
+	}
+	if !written.Complete || written.CommittedSize != digest.SizeBytes {
+		t.Errorf("expected the write to be complete, got %+v", written)
+	}
+
+	read := &fakeReadStream{}
+	if err := rc.Read(&bytestream.ReadRequest{ResourceName: "repo/blobs/" + digest.Hash + "/39", ReadOffset: 5, ReadLimit: 7}, read); err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if string(read.data) != "content" {
+		t.Errorf("expected to read %q, got %q", "content", read.data)
+	}
+
+	other := []byte("other")
+	badOffset := &fakeWriteStream{requests: []*bytestream.WriteRequest{
+		{ResourceName: "repo/uploads/5678/blobs/" + digestOf(other).Hash + "/5", Data: other[:2]},
+		{WriteOffset: 3, Data: other[2:], FinishWrite: true},
+	}}
+	if err := rc.Write(badOffset); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected a write at the wrong offset to fail, got %v", err)
+	}
+	unfinished := &fakeWriteStream{requests: []*bytestream.WriteRequest{
+		{ResourceName: "repo/uploads/5678/blobs/" + digestOf(other).Hash + "/5", Data: other[:2]},
+	}}
+	if err := rc.Write(unfinished); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected an unfinished write to fail, got %v", err)
+	}
+	if err := rc.Read(&bytestream.ReadRequest{ResourceName: "repo/blobs/" + digestOf(other).Hash + "/5"}, &fakeReadStream{}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected failed writes not to be stored, got %v", err)
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"mime"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -317,8 +319,9 @@ func (rac *RerunAuthConfig) IsAllowAnyone() bool {
 }
 
 type ReporterConfig struct {
-	Slack  *SlackReporterConfig  `json:"slack,omitempty"`
-	GitHub *GitHubReporterConfig `json:"github,omitempty"`
+	Slack   *SlackReporterConfig   `json:"slack,omitempty"`
+	GitHub  *GitHubReporterConfig  `json:"github,omitempty"`
+	Webhook *WebhookReporterConfig `json:"webhook,omitempty"`
 }
 
 type SlackReporterConfig struct {
@@ -372,6 +375,45 @@ func (src *SlackReporterConfig) ApplyDefault(def *SlackReporterConfig) *SlackRep
 	return &merged
 }
 
+// WebhookReporterConfig configures the generic webhook reporter, which POSTs
+// a signed JSON rendering of the ProwJob to the configured endpoints.
+type WebhookReporterConfig struct {
+	// Endpoints are the URLs that the ProwJob is POSTed to.
+	Endpoints         []string       `json:"endpoints,omitempty"`
+	JobStatesToReport []ProwJobState `json:"job_states_to_report,omitempty"`
+	// Report is derived from JobStatesToReport, it's used for differentiating
+	// nil from empty slice, same as SlackReporterConfig.Report.
+	Report *bool `json:"report,omitempty"`
+}
+
+// ApplyDefault is called by jobConfig.ApplyDefault(globalConfig)
+func (src *WebhookReporterConfig) ApplyDefault(def *WebhookReporterConfig) *WebhookReporterConfig {
+	if src == nil && def == nil {
+		return nil
+	}
+	var merged WebhookReporterConfig
+	if src != nil {
+		merged = *src.DeepCopy()
+	} else {
+		merged = *def.DeepCopy()
+	}
+	if src == nil || def == nil {
+		return &merged
+	}
+
+	if merged.Endpoints == nil {
+		merged.Endpoints = def.Endpoints
+	}
+	// Note: `job_states_to_report: []` also results in JobStatesToReport == nil
+	if merged.JobStatesToReport == nil {
+		merged.JobStatesToReport = def.JobStatesToReport
+	}
+	if merged.Report == nil {
+		merged.Report = def.Report
+	}
+	return &merged
+}
+
 type GitHubReporterConfig struct {
 	// CommentOnPostsubmits determines if any comments related to the
 	// postsubmit should be left on the corresponding commit and PR.
@@ -445,6 +487,12 @@ type DecorationConfig struct {
 	// S3CredentialsSecret is the name of the Kubernetes secret
 	// that holds blob storage push credentials.
 	S3CredentialsSecret *string `json:"s3_credentials_secret,omitempty"`
+	// AzureCredentialsSecret is the name of the Kubernetes secret
+	// that holds Azure Blob Storage push credentials.
+	AzureCredentialsSecret *string `json:"azure_credentials_secret,omitempty"`
+	// FileStorageClaim is the name of the PersistentVolumeClaim that
+	// holds the buckets of file:// paths, e.g. a shared NFS volume.
+	FileStorageClaim *string `json:"file_storage_claim,omitempty"`
 	// DefaultServiceAccountName is the name of the Kubernetes service account
 	// that should be used by the pod if one is not specified in the podspec.
 	DefaultServiceAccountName *string `json:"default_service_account_name,omitempty"`
@@ -479,11 +527,89 @@ type DecorationConfig struct {
 	// CensoringOptions exposes options for censoring output logs and artifacts.
 	CensoringOptions *CensoringOptions `json:"censoring_options,omitempty"`
 
+	// GitCache is a read-only volume with git repositories which
+	// are used as a cache of git objects when cloning.
+	GitCache *GitCache `json:"git_cache,omitempty"`
+
+	// ResourceUsage makes sidecar sample the CPU, memory and IO usage
+	// of the test containers and upload it as resource-usage.json.
+	// This shares the process namespace of the pod between its containers.
+	ResourceUsage *ResourceUsage `json:"resource_usage,omitempty"`
+
+	// ArtifactUploads are policies for uploading the artifacts of the job,
+	// which keep huge or useless files from blowing up storage costs and
+	// upload time.
+	ArtifactUploads *ArtifactUploads `json:"artifact_uploads,omitempty"`
+
 	// UploadIgnoresInterrupts causes sidecar to ignore interrupts for the upload process in
 	// hope that the test process exits cleanly before starting an upload.
 	UploadIgnoresInterrupts *bool `json:"upload_ignores_interrupts,omitempty"`
 }
 
+// GitCache is a volume with git repositories, e.g. mirrors that are kept
+// up to date by a periodic job. Repositories are looked up at
+// <host>/<org>/<repo> below the volume, e.g. github.com/kubernetes/test-infra,
+// and may be bare or not.
+type GitCache struct {
+	// HostPath is the directory on the node that holds the cache.
+	HostPath string `json:"host_path,omitempty"`
+	// ClaimName is the name of the PersistentVolumeClaim that holds
+	// the cache.
+	ClaimName string `json:"claim_name,omitempty"`
+}
+
+// ResourceUsage configures the sampling of the resource usage of test
+// containers.
+type ResourceUsage struct {
+	// Interval is how often the resource usage is sampled, defaults to 10s.
+	Interval *Duration `json:"interval,omitempty"`
+}
+
+const (
+	// OversizedArtifactSkip skips artifacts above the size cap.
+	OversizedArtifactSkip = "skip"
+	// OversizedArtifactTruncate uploads only the beginning of artifacts
+	// above the size cap.
+	OversizedArtifactTruncate = "truncate"
+)
+
+// ArtifactUploads are policies for uploading artifacts. The globs are
+// relative to $ARTIFACTS and are parsed with the go-zglob library.
+type ArtifactUploads struct {
+	// Include are globs of the artifacts to upload. If present, only
+	// artifacts matching one of them are uploaded.
+	Include []string `json:"include,omitempty"`
+	// Exclude are globs of the artifacts not to upload, even if they
+	// match a glob in Include.
+	Exclude []string `json:"exclude,omitempty"`
+	// GzipAboveBytes compresses artifacts larger than this many bytes
+	// with gzip. They are uploaded with the gzip content encoding, so
+	// they are transparently decompressed on download. Artifacts with
+	// extensions of compressed formats are never compressed again.
+	GzipAboveBytes *int64 `json:"gzip_above_bytes,omitempty"`
+	// MaxBytes is the size cap for artifacts, those above it are handled
+	// as set by Oversized.
+	MaxBytes *int64 `json:"max_bytes,omitempty"`
+	// Oversized is either "skip" to upload a marker instead of artifacts
+	// above the size cap, or "truncate" to upload their first MaxBytes
+	// followed by a marker. Defaults to "skip".
+	Oversized string `json:"oversized,omitempty"`
+}
+
+// Validate ensures all the values set in the ArtifactUploads are valid.
+func (a *ArtifactUploads) Validate() error {
+	if a.GzipAboveBytes != nil && *a.GzipAboveBytes < 0 {
+		return errors.New("gzip_above_bytes must not be negative")
+	}
+	if a.MaxBytes != nil && *a.MaxBytes < 0 {
+		return errors.New("max_bytes must not be negative")
+	}
+	if a.Oversized != "" && a.Oversized != OversizedArtifactSkip && a.Oversized != OversizedArtifactTruncate {
+		return fmt.Errorf("oversized must be one of %q or %q", OversizedArtifactSkip, OversizedArtifactTruncate)
+	}
+	return nil
+}
+
 type CensoringOptions struct {
 	// CensoringConcurrency is the maximum number of goroutines that should be censoring
 	// artifacts and logs at any time. If unset, defaults to 10.
@@ -509,6 +635,42 @@ type CensoringOptions struct {
 	// matches a glob in IncludeDirectories. Entries in this list are relative to $ARTIFACTS,
 	// and are parsed with the go-zglob library, allowing for globbed matches.
 	ExcludeDirectories []string `json:"exclude_directories,omitempty"`
+
+	// Patterns are regular expressions matching secrets that should be censored even
+	// though they are not in the secret directories, e.g. credentials minted by the
+	// test. If a pattern has a capturing group, only the first group is censored.
+	Patterns []string `json:"patterns,omitempty"`
+
+	// BuiltinPatterns enables patterns for common token formats, like GitHub tokens,
+	// cloud provider credentials, JWTs and private keys.
+	BuiltinPatterns *bool `json:"builtin_patterns,omitempty"`
+
+	// HighEntropyStrings enables censoring strings which look random, like most
+	// generated credentials. This may censor other random strings too, like hashes
+	// that are not hex encoded.
+	HighEntropyStrings *bool `json:"high_entropy_strings,omitempty"`
+	// EntropyMinLength is the length of the shortest string that is checked for its
+	// entropy. If unset, defaults to 20.
+	EntropyMinLength *int `json:"entropy_min_length,omitempty"`
+	// EntropyThreshold is the Shannon entropy in bits per character from which strings
+	// are censored, as a decimal number like "4.5". If unset, defaults to 4.5, which
+	// hex strings cannot reach.
+	EntropyThreshold *string `json:"entropy_threshold,omitempty"`
+}
+
+// GetEntropyThreshold returns the parsed EntropyThreshold, or zero if it is unset.
+func (g *CensoringOptions) GetEntropyThreshold() (float64, error) {
+	if g == nil || g.EntropyThreshold == nil {
+		return 0, nil
+	}
+	threshold, err := strconv.ParseFloat(*g.EntropyThreshold, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entropy threshold %q: %w", *g.EntropyThreshold, err)
+	}
+	if threshold <= 0 {
+		return 0, fmt.Errorf("entropy threshold must be positive, not %q", *g.EntropyThreshold)
+	}
+	return threshold, nil
 }
 
 // ApplyDefault applies the defaults for CensoringOptions decorations. If a field has a zero value,
@@ -542,6 +704,26 @@ func (g *CensoringOptions) ApplyDefault(def *CensoringOptions) *CensoringOptions
 	if merged.ExcludeDirectories == nil {
 		merged.ExcludeDirectories = def.ExcludeDirectories
 	}
+
+	if merged.Patterns == nil {
+		merged.Patterns = def.Patterns
+	}
+
+	if merged.BuiltinPatterns == nil {
+		merged.BuiltinPatterns = def.BuiltinPatterns
+	}
+
+	if merged.HighEntropyStrings == nil {
+		merged.HighEntropyStrings = def.HighEntropyStrings
+	}
+
+	if merged.EntropyMinLength == nil {
+		merged.EntropyMinLength = def.EntropyMinLength
+	}
+
+	if merged.EntropyThreshold == nil {
+		merged.EntropyThreshold = def.EntropyThreshold
+	}
 	return &merged
 }
 
@@ -649,6 +831,12 @@ func (d *DecorationConfig) ApplyDefault(def *DecorationConfig) *DecorationConfig
 	if merged.S3CredentialsSecret == nil {
 		merged.S3CredentialsSecret = def.S3CredentialsSecret
 	}
+	if merged.AzureCredentialsSecret == nil {
+		merged.AzureCredentialsSecret = def.AzureCredentialsSecret
+	}
+	if merged.FileStorageClaim == nil {
+		merged.FileStorageClaim = def.FileStorageClaim
+	}
 	if merged.DefaultServiceAccountName == nil {
 		merged.DefaultServiceAccountName = def.DefaultServiceAccountName
 	}
@@ -679,6 +867,15 @@ func (d *DecorationConfig) ApplyDefault(def *DecorationConfig) *DecorationConfig
 	if merged.CensorSecrets == nil {
 		merged.CensorSecrets = def.CensorSecrets
 	}
+	if merged.GitCache == nil {
+		merged.GitCache = def.GitCache
+	}
+	if merged.ResourceUsage == nil {
+		merged.ResourceUsage = def.ResourceUsage
+	}
+	if merged.ArtifactUploads == nil {
+		merged.ArtifactUploads = def.ArtifactUploads
+	}
 
 	if merged.UploadIgnoresInterrupts == nil {
 		merged.UploadIgnoresInterrupts = def.UploadIgnoresInterrupts
@@ -722,6 +919,27 @@ func (d *DecorationConfig) Validate() error {
 	if d.OauthTokenSecret != nil && len(d.SSHKeySecrets) > 0 {
 		return errors.New("both OAuth token and SSH key secrets are specified")
 	}
+	if d.GitCache != nil && (d.GitCache.HostPath == "") == (d.GitCache.ClaimName == "") {
+		return errors.New("git cache must specify exactly one of host_path and claim_name")
+	}
+	if d.ResourceUsage != nil && d.ResourceUsage.Interval != nil && d.ResourceUsage.Interval.Duration < time.Second {
+		return errors.New("resource usage interval must be at least 1s")
+	}
+	if d.ArtifactUploads != nil {
+		if err := d.ArtifactUploads.Validate(); err != nil {
+			return fmt.Errorf("artifact upload policies are invalid: %w", err)
+		}
+	}
+	if d.CensoringOptions != nil {
+		for _, pattern := range d.CensoringOptions.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid censoring pattern %q: %w", pattern, err)
+			}
+		}
+		if _, err := d.CensoringOptions.GetEntropyThreshold(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -784,6 +1002,8 @@ type GCSConfiguration struct {
 	// Bucket is the bucket to upload to, it can be:
 	// * a GCS bucket: with gs:// prefix
 	// * a S3 bucket: with s3:// prefix
+	// * an Azure Blob Storage container: with azblob:// prefix
+	// * a directory below the file storage root: with file:// prefix
 	// * a GCS bucket: without a prefix (deprecated, it's discouraged to use Bucket without prefix please add the gs:// prefix)
 	Bucket string `json:"bucket,omitempty"`
 	// PathPrefix is an optional path that follows the
@@ -1031,6 +1251,14 @@ type Refs struct {
 	// Multiheaded repos may need to not make this call.
 	// The git fetch <remote> <BaseRef> call occurs regardless.
 	SkipFetchHead bool `json:"skip_fetch_head,omitempty"`
+	// SparseCheckout are the patterns of the paths to check out,
+	// in the format of .gitignore files. All paths are checked
+	// out if unset.
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+	// PartialClone determines if blobs are left out of the clone
+	// and only fetched when they are needed, e.g. on checkout.
+	// Blobs are fetched without credentials after cloning.
+	PartialClone bool `json:"partial_clone,omitempty"`
 
 	// Sender is the GitHub login of the commit author
 	Author string `json:"author,omitempty"`
@@ -501,6 +501,48 @@ func TestRerunAuthConfigValidate(t *testing.T) {
 	}
 }
 
+func TestCensoringOptionsGetEntropyThreshold(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		options     *CensoringOptions
+		expected    float64
+		errExpected bool
+	}{
+		{
+			name:     "unset",
+			options:  &CensoringOptions{},
+			expected: 0,
+		},
+		{
+			name:     "decimal number",
+			options:  &CensoringOptions{EntropyThreshold: pStr("4.2")},
+			expected: 4.2,
+		},
+		{
+			name:        "not a number",
+			options:     &CensoringOptions{EntropyThreshold: pStr("high")},
+			errExpected: true,
+		},
+		{
+			name:        "not positive",
+			options:     &CensoringOptions{EntropyThreshold: pStr("0")},
+			errExpected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			threshold, err := tc.options.GetEntropyThreshold()
+			if (err != nil) != tc.errExpected {
+				t.Fatalf("Expected error %v, got %v", tc.errExpected, err)
+			}
+			if threshold != tc.expected {
+				t.Errorf("Expected threshold %v, got %v", tc.expected, threshold)
+			}
+		})
+	}
+}
+
 func TestRerunAuthConfigIsAuthorized(t *testing.T) {
 	var testCases = []struct {
 		name       string
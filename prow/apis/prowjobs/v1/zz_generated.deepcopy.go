@@ -29,6 +29,42 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactUploads) DeepCopyInto(out *ArtifactUploads) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GzipAboveBytes != nil {
+		in, out := &in.GzipAboveBytes, &out.GzipAboveBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxBytes != nil {
+		in, out := &in.MaxBytes, &out.MaxBytes
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactUploads.
+func (in *ArtifactUploads) DeepCopy() *ArtifactUploads {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactUploads)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CensoringOptions) DeepCopyInto(out *CensoringOptions) {
 	*out = *in
@@ -52,6 +88,31 @@ func (in *CensoringOptions) DeepCopyInto(out *CensoringOptions) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BuiltinPatterns != nil {
+		in, out := &in.BuiltinPatterns, &out.BuiltinPatterns
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HighEntropyStrings != nil {
+		in, out := &in.HighEntropyStrings, &out.HighEntropyStrings
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EntropyMinLength != nil {
+		in, out := &in.EntropyMinLength, &out.EntropyMinLength
+		*out = new(int)
+		**out = **in
+	}
+	if in.EntropyThreshold != nil {
+		in, out := &in.EntropyThreshold, &out.EntropyThreshold
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -103,6 +164,16 @@ func (in *DecorationConfig) DeepCopyInto(out *DecorationConfig) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.AzureCredentialsSecret != nil {
+		in, out := &in.AzureCredentialsSecret, &out.AzureCredentialsSecret
+		*out = new(string)
+		**out = **in
+	}
+	if in.FileStorageClaim != nil {
+		in, out := &in.FileStorageClaim, &out.FileStorageClaim
+		*out = new(string)
+		**out = **in
+	}
 	if in.DefaultServiceAccountName != nil {
 		in, out := &in.DefaultServiceAccountName, &out.DefaultServiceAccountName
 		*out = new(string)
@@ -153,6 +224,21 @@ func (in *DecorationConfig) DeepCopyInto(out *DecorationConfig) {
 		*out = new(CensoringOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.GitCache != nil {
+		in, out := &in.GitCache, &out.GitCache
+		*out = new(GitCache)
+		**out = **in
+	}
+	if in.ResourceUsage != nil {
+		in, out := &in.ResourceUsage, &out.ResourceUsage
+		*out = new(ResourceUsage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArtifactUploads != nil {
+		in, out := &in.ArtifactUploads, &out.ArtifactUploads
+		*out = new(ArtifactUploads)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.UploadIgnoresInterrupts != nil {
 		in, out := &in.UploadIgnoresInterrupts, &out.UploadIgnoresInterrupts
 		*out = new(bool)
@@ -210,6 +296,22 @@ func (in *GCSConfiguration) DeepCopy() *GCSConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitCache) DeepCopyInto(out *GitCache) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitCache.
+func (in *GitCache) DeepCopy() *GitCache {
+	if in == nil {
+		return nil
+	}
+	out := new(GitCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubAppPrivateKeySecret) DeepCopyInto(out *GitHubAppPrivateKeySecret) {
 	*out = *in
@@ -507,6 +609,11 @@ func (in *Refs) DeepCopyInto(out *Refs) {
 		*out = make([]Pull, len(*in))
 		copy(*out, *in)
 	}
+	if in.SparseCheckout != nil {
+		in, out := &in.SparseCheckout, &out.SparseCheckout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -533,6 +640,11 @@ func (in *ReporterConfig) DeepCopyInto(out *ReporterConfig) {
 		*out = new(GitHubReporterConfig)
 		**out = **in
 	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -582,6 +694,27 @@ func (in *RerunAuthConfig) DeepCopy() *RerunAuthConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceUsage) DeepCopyInto(out *ResourceUsage) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceUsage.
+func (in *ResourceUsage) DeepCopy() *ResourceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resources) DeepCopyInto(out *Resources) {
 	*out = *in
@@ -659,3 +792,34 @@ func (in *UtilityImages) DeepCopy() *UtilityImages {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookReporterConfig) DeepCopyInto(out *WebhookReporterConfig) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.JobStatesToReport != nil {
+		in, out := &in.JobStatesToReport, &out.JobStatesToReport
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	if in.Report != nil {
+		in, out := &in.Report, &out.Report
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookReporterConfig.
+func (in *WebhookReporterConfig) DeepCopy() *WebhookReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -95,6 +95,9 @@ type Client interface {
 	ForPlugin(plugin string) Client
 	ForSubcomponent(subcomponent string) Client
 	WithFields(fields logrus.Fields) Client
+	// WithRequestGate allows to fail requests with the error of gate, which
+	// is called before every request.
+	WithRequestGate(gate func() error) Client
 	Used() bool
 
 	// SearchBugs returns all bugs that meet the given criteria
@@ -164,6 +167,8 @@ type client struct {
 	// identifier is used to add more identification to the user-agent header
 	identifier string
 	used       bool
+	// gate is called before every request if it is set.
+	gate func() error
 	*delegate
 }
 
@@ -188,6 +193,7 @@ func (c *client) forKeyValue(key, value string) Client {
 	return &client{
 		identifier: value,
 		logger:     c.logger.WithField(key, value),
+		gate:       c.gate,
 		delegate:   c.delegate,
 	}
 }
@@ -204,10 +210,23 @@ func (c *client) userAgent() string {
 func (c *client) WithFields(fields logrus.Fields) Client {
 	return &client{
 		logger:   c.logger.WithFields(fields),
+		gate:     c.gate,
 		delegate: c.delegate,
 	}
 }
 
+// WithRequestGate clones the client, keeping the underlying delegate the same but
+// calling gate before every request to Bugzilla. Requests fail with the error of
+// the gate if it returns one.
+func (c *client) WithRequestGate(gate func() error) Client {
+	return &client{
+		logger:     c.logger,
+		identifier: c.identifier,
+		gate:       gate,
+		delegate:   c.delegate,
+	}
+}
+
 // delegate actually does the work to talk to Bugzilla
 type delegate struct {
 	client                  *http.Client
@@ -695,6 +714,11 @@ func (c *client) GetComments(bugID int) ([]Comment, error) {
 }
 
 func (c *client) request(req *http.Request, logger *logrus.Entry) ([]byte, error) {
+	if c.gate != nil {
+		if err := c.gate(); err != nil {
+			return nil, err
+		}
+	}
 	c.used = true
 	if apiKey := c.getAPIKey(); len(apiKey) > 0 {
 		switch c.authMethod {
@@ -19,6 +19,7 @@ package bugzilla
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -1279,3 +1280,31 @@ func TestClone(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRequestGate(t *testing.T) {
+	var requests int
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(bugData)
+	}))
+	defer testServer.Close()
+
+	gateErr := errors.New("closed")
+	var closed bool
+	client := clientForUrl(testServer.URL).WithRequestGate(func() error {
+		if closed {
+			return gateErr
+		}
+		return nil
+	})
+	if _, err := client.GetBug(1705243); err != nil {
+		t.Fatalf("expected the request to pass the gate, got %v", err)
+	}
+	closed = true
+	if _, err := client.ForPlugin("plugin").GetBug(1705243); !errors.Is(err, gateErr) {
+		t.Errorf("expected the request to fail with the error of the gate, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected only one request to reach the server, got %d", requests)
+	}
+}
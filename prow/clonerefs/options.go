@@ -69,6 +69,10 @@ type Options struct {
 
 	CookiePath string `json:"cookie_path,omitempty"`
 
+	// GitCacheDir is a directory with git repositories
+	// whose objects are used instead of fetching them.
+	GitCacheDir string `json:"git_cache_dir,omitempty"`
+
 	GitHubAPIEndpoints      []string `json:"github_api_endpoints,omitempty"`
 	GitHubAppID             string   `json:"github_app_id,omitempty"`
 	GitHubAppPrivateKeyFile string   `json:"github_app_private_key_file,omitempty"`
@@ -182,6 +186,7 @@ func (o *Options) AddFlags(fs *flag.FlagSet) {
 	fs.Var(&o.cloneURI, "uri-prefix", "Format string for the URI prefix to clone from")
 	fs.IntVar(&o.MaxParallelWorkers, "max-workers", 0, "Maximum number of parallel workers, unset for unlimited.")
 	fs.StringVar(&o.CookiePath, "cookiefile", "", "Path to git http.cookiefile")
+	fs.StringVar(&o.GitCacheDir, "git-cache-dir", "", "Directory with git repositories at <host>/<org>/<repo> to use objects from")
 	fs.BoolVar(&o.Fail, "fail", false, "Exit with failure if any of the refs can't be fetched.")
 }
 
@@ -139,7 +139,7 @@ func (o *Options) createRecords() []clone.Record {
 		go func() {
 			defer wg.Done()
 			for ref := range input {
-				output <- cloneFunc(ref, o.SrcRoot, o.GitUserName, o.GitUserEmail, o.CookiePath, env, userGenerator, tokenGenerator)
+				output <- cloneFunc(ref, o.SrcRoot, o.GitUserName, o.GitUserEmail, o.CookiePath, o.GitCacheDir, env, userGenerator, tokenGenerator)
 			}
 		}()
 	}
@@ -96,7 +96,7 @@ func TestRun(t *testing.T) {
 	var recordedClones []cloneRec
 	var lock sync.Mutex
 	cloneFuncOld := cloneFunc
-	cloneFunc = func(refs prowapi.Refs, root, user, email, cookiePath string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) clone.Record {
+	cloneFunc = func(refs prowapi.Refs, root, user, email, cookiePath, cacheDir string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) clone.Record {
 		lock.Lock()
 		defer lock.Unlock()
 		var (
@@ -397,7 +397,7 @@ func validate(o options) error {
 		}
 	}
 	if o.warningEnabled(validateClusterFieldWarning) {
-		opener, err := io.NewOpener(context.Background(), o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
+		opener, err := io.NewOpenerWithOptions(context.Background(), o.storage.OpenerOptions())
 		if err != nil {
 			logrus.WithError(err).Fatal("Error creating opener")
 		}
@@ -34,8 +34,10 @@ import (
 	k8sgcsreporter "k8s.io/test-infra/prow/crier/reporters/gcs/kubernetes"
 	gerritreporter "k8s.io/test-infra/prow/crier/reporters/gerrit"
 	githubreporter "k8s.io/test-infra/prow/crier/reporters/github"
+	gitlabreporter "k8s.io/test-infra/prow/crier/reporters/gitlab"
 	pubsubreporter "k8s.io/test-infra/prow/crier/reporters/pubsub"
 	slackreporter "k8s.io/test-infra/prow/crier/reporters/slack"
+	webhookreporter "k8s.io/test-infra/prow/crier/reporters/webhook"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
 	gerritclient "k8s.io/test-infra/prow/gerrit/client"
@@ -52,6 +54,7 @@ type options struct {
 	gerritProjects   gerritclient.ProjectsFlag
 	github           prowflagutil.GitHubOptions
 	githubEnablement prowflagutil.GitHubEnablementOptions
+	gitlab           prowflagutil.GitLabOptions
 
 	config configflagutil.ConfigOptions
 
@@ -62,10 +65,14 @@ type options struct {
 	k8sGCSWorkers         int
 	blobStorageWorkers    int
 	k8sBlobStorageWorkers int
+	webhookWorkers        int
+	gitlabWorkers         int
 
 	slackTokenFile            string
 	additionalSlackTokenFiles slackclient.HostsFlag
 
+	webhookHMACSecretFile string
+
 	storage prowflagutil.StorageClientOptions
 
 	instrumentationOptions prowflagutil.InstrumentationOptions
@@ -77,7 +84,7 @@ type options struct {
 }
 
 func (o *options) validate() error {
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers+o.webhookWorkers+o.gitlabWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -101,6 +108,15 @@ func (o *options) validate() error {
 		}
 	}
 
+	if o.gitlabWorkers > 0 {
+		if err := o.gitlab.Validate(o.dryrun); err != nil {
+			return err
+		}
+		if o.gitlab.Endpoint == "" {
+			return errors.New("--gitlab-endpoint must be set with --gitlab-workers")
+		}
+	}
+
 	if o.slackWorkers > 0 {
 		if o.slackTokenFile == "" && len(o.additionalSlackTokenFiles) == 0 {
 			return errors.New("one of --slack-token-file or --additional-slack-token-files must be set")
@@ -131,13 +147,17 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.k8sBlobStorageWorkers, "kubernetes-blob-storage-workers", 0, "Number of Kubernetes-specific blob storage report workers (0 means disabled)")
 	fs.Float64Var(&o.k8sReportFraction, "kubernetes-report-fraction", 1.0, "Approximate portion of jobs to report pod information for, if kubernetes-blob-storage-workers are enabled (0 - > none, 1.0 -> all)")
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to a Slack token file")
+	fs.IntVar(&o.webhookWorkers, "webhook-workers", 0, "Number of webhook report workers (0 means disabled)")
+	fs.StringVar(&o.webhookHMACSecretFile, "webhook-hmac-secret-file", "", "Path to the token used to sign webhook reporter payloads, leave empty to send unsigned payloads")
+	fs.IntVar(&o.gitlabWorkers, "gitlab-workers", 0, "Number of GitLab report workers (0 means disabled)")
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github and Slack only)")
 
 	// TODO(krzyzacy): implement dryrun for gerrit/pubsub
-	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github and Slack only)")
+	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github, GitLab, Slack and webhook only)")
 
 	o.config.AddFlags(fs)
 	o.github.AddFlags(fs)
+	o.gitlab.AddFlags(fs)
 	o.client.AddFlags(fs)
 	o.storage.AddFlags(fs)
 	o.instrumentationOptions.AddFlags(fs)
@@ -237,6 +257,18 @@ func main() {
 		}
 	}
 
+	if o.gitlabWorkers > 0 {
+		gitlabClient, err := o.gitlab.Client(o.dryrun)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting GitLab client.")
+		}
+
+		hasReporter = true
+		if err := crier.New(mgr, gitlabreporter.NewReporter(gitlabClient, mgr.GetClient()), o.gitlabWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct gitlab reporter controller")
+		}
+	}
+
 	if o.pubsubWorkers > 0 {
 		hasReporter = true
 		if err := crier.New(mgr, pubsubreporter.NewReporter(cfg), o.pubsubWorkers, o.githubEnablement.EnablementChecker()); err != nil {
@@ -244,6 +276,17 @@ func main() {
 		}
 	}
 
+	// The opener is used by the blob storage reporters to upload and by the
+	// github reporter to read the junit results of jobs reported as check runs.
+	var opener io.Opener
+	if o.githubWorkers > 0 || o.blobStorageWorkers > 0 || o.k8sBlobStorageWorkers > 0 {
+		var err error
+		opener, err = io.NewOpenerWithOptions(context.Background(), o.storage.OpenerOptions())
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating opener")
+		}
+	}
+
 	if o.githubWorkers > 0 {
 		if o.github.TokenPath != "" {
 			if err := secret.Add(o.github.TokenPath); err != nil {
@@ -257,18 +300,13 @@ func main() {
 		}
 
 		hasReporter = true
-		githubReporter := githubreporter.NewReporter(githubClient, cfg, prowapi.ProwJobAgent(o.reportAgent), mgr.GetCache())
+		githubReporter := githubreporter.NewReporter(githubClient, cfg, prowapi.ProwJobAgent(o.reportAgent), mgr.GetCache(), opener)
 		if err := crier.New(mgr, githubReporter, o.githubWorkers, o.githubEnablement.EnablementChecker()); err != nil {
 			logrus.WithError(err).Fatal("failed to construct github reporter controller")
 		}
 	}
 
 	if o.blobStorageWorkers > 0 || o.k8sBlobStorageWorkers > 0 {
-		opener, err := io.NewOpener(context.Background(), o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
-		if err != nil {
-			logrus.WithError(err).Fatal("Error creating opener")
-		}
-
 		hasReporter = true
 		if o.blobStorageWorkers > 0 {
 			if err := crier.New(mgr, gcsreporter.New(cfg, opener, o.dryrun), o.blobStorageWorkers, o.githubEnablement.EnablementChecker()); err != nil {
@@ -289,6 +327,26 @@ func main() {
 		}
 	}
 
+	if o.webhookWorkers > 0 {
+		if cfg().WebhookReporterConfigs == nil {
+			logrus.Fatal("webhookreporter is enabled but has no config")
+		}
+		webhookConfig := func(refs *prowapi.Refs) config.WebhookReporter {
+			return cfg().WebhookReporterConfigs.GetWebhookReporter(refs)
+		}
+		var tokenGenerator func() []byte
+		if o.webhookHMACSecretFile != "" {
+			if err := secret.Add(o.webhookHMACSecretFile); err != nil {
+				logrus.WithError(err).Fatal("could not read webhook hmac secret")
+			}
+			tokenGenerator = secret.GetTokenGenerator(o.webhookHMACSecretFile)
+		}
+		hasReporter = true
+		if err := crier.New(mgr, webhookreporter.New(webhookConfig, tokenGenerator, o.dryrun), o.webhookWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct webhook reporter controller")
+		}
+	}
+
 	if !hasReporter {
 		logrus.Fatalf("should have at least one controller to start crier.")
 	}
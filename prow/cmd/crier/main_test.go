@@ -153,6 +153,51 @@ func TestOptions(t *testing.T) {
 				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
 			},
 		},
+		//Webhook Reporter
+		{
+			name: "webhook workers, sets workers",
+			args: []string{"--webhook-workers=3", "--webhook-hmac-secret-file=/etc/webhook/hmac", "--config-path=foo"},
+			expected: &options{
+				webhookWorkers:        3,
+				webhookHMACSecretFile: "/etc/webhook/hmac",
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+				},
+				github:                 defaultGitHubOptions,
+				gerritProjects:         defaultGerritProjects,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		//GitLab Reporter
+		{
+			name: "gitlab workers, sets workers",
+			args: []string{"--gitlab-workers=2", "--gitlab-endpoint=https://gitlab.example.com", "--gitlab-token-path=/etc/gitlab/token", "--config-path=foo"},
+			expected: &options{
+				gitlabWorkers: 2,
+				gitlab: flagutil.GitLabOptions{
+					Endpoint:  "https://gitlab.example.com",
+					TokenPath: "/etc/gitlab/token",
+				},
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+				},
+				github:                 defaultGitHubOptions,
+				gerritProjects:         defaultGerritProjects,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: prowflagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		{
+			name: "gitlab workers without endpoint, reject",
+			args: []string{"--gitlab-workers=2", "--config-path=foo"},
+		},
 		{
 			name: "k8s-gcs enables k8s-gcs",
 			args: []string{"--kubernetes-blob-storage-workers=3", "--config-path=foo"},
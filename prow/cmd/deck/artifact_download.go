@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	pkgio "k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/io/providers"
+)
+
+// handleArtifactDownload serves the content of artifacts whose storage provider
+// can't create signed URLs, e.g. file://.
+// The path is expected to be <storage-provider>/<bucket>/<object>.
+func handleArtifactDownload(opener pkgio.Opener, cfg config.Getter, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		pathSegments := strings.SplitN(r.URL.Path, "/", 3)
+		if len(pathSegments) != 3 || pathSegments[2] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		storageProvider, bucket, object := pathSegments[0], pathSegments[1], pathSegments[2]
+		// Only serve providers that can't sign URLs, everything else is linked to directly
+		// and must not be proxied with the credentials of deck.
+		if storageProvider != providers.File && storageProvider != providers.Azure {
+			http.NotFound(w, r)
+			return
+		}
+		if err := cfg().ValidateStorageBucket(bucket); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		storagePath := fmt.Sprintf("%s://%s/%s", storageProvider, bucket, object)
+		l := log.WithField("path", storagePath)
+
+		attrs, err := opener.Attributes(r.Context(), storagePath)
+		if err != nil {
+			if pkgio.IsNotExist(err) {
+				http.NotFound(w, r)
+				return
+			}
+			l.WithError(err).Warn("Failed to get artifact attributes.")
+			http.Error(w, "Failed to get artifact.", http.StatusInternalServerError)
+			return
+		}
+		reader, err := opener.Reader(r.Context(), storagePath)
+		if err != nil {
+			l.WithError(err).Warn("Failed to read artifact.")
+			http.Error(w, "Failed to read artifact.", http.StatusInternalServerError)
+			return
+		}
+		defer pkgio.LogClose(reader)
+
+		contentType := mime.TypeByExtension(path.Ext(object))
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		// Artifacts are untrusted, don't let them run scripts on the origin of deck.
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// The providers served here return the stored bytes without decompressing them.
+		if attrs.ContentEncoding != "" {
+			w.Header().Set("Content-Encoding", attrs.ContentEncoding)
+		}
+		if _, err := io.Copy(w, reader); err != nil {
+			l.WithError(err).Debug("Failed to write artifact.")
+		}
+	}
+}
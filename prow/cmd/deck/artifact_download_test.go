@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	pkgio "k8s.io/test-infra/prow/io"
+)
+
+func TestHandleArtifactDownload(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "prow-artifacts", "logs", "job", "1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create bucket directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "build-log.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write artifact: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "other-bucket"), 0755); err != nil {
+		t.Fatalf("failed to create bucket directory: %v", err)
+	}
+	opener, err := pkgio.NewOpenerWithOptions(context.Background(), pkgio.OpenerOptions{FileStorageRoot: root})
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	cfg := func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{
+			AllKnownStorageBuckets: sets.NewString("prow-artifacts"),
+		}}}
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "artifact is served",
+			path:           "file/prow-artifacts/logs/job/1/build-log.txt",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "hello",
+		},
+		{
+			name:           "missing artifact",
+			path:           "file/prow-artifacts/logs/job/1/missing.txt",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bucket is not allowed",
+			path:           "file/other-bucket/logs/job/1/build-log.txt",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "providers with signed URLs are not proxied",
+			path:           "gs/prow-artifacts/logs/job/1/build-log.txt",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no object",
+			path:           "file/prow-artifacts",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.path, nil)
+			req.URL.Path = tc.path
+			rr := httptest.NewRecorder()
+			handleArtifactDownload(opener, cfg, logrus.WithField("handler", "test")).ServeHTTP(rr, req)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedBody != "" && rr.Body.String() != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusOK && rr.Header().Get("Content-Security-Policy") != "sandbox" {
+				t.Error("expected the artifact to be sandboxed")
+			}
+		})
+	}
+}
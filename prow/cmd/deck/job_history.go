@@ -283,7 +283,7 @@ func (bucket blobStorageBucket) listBuildIDs(ctx context.Context, root string) (
 // Newly generated URLs will include the storageProvider. We still support old URLs so they don't break.
 // For old URLs we assume that the storageProvider is `gs`.
 // examples return values:
-// * storageProvider: gs, s3
+// * storageProvider: gs, s3, azblob, file
 // * bucketName: kubernetes-jenkins
 // * root: pr-logs/directory/pull-capi
 // * buildID: 1245584383100850177
@@ -26,6 +26,7 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	stdio "io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
@@ -37,6 +38,7 @@ import (
 
 	"k8s.io/test-infra/prow/io/providers"
 	"k8s.io/test-infra/prow/tide"
+	"k8s.io/test-infra/prow/tide/history"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/csrf"
@@ -56,6 +58,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/cache"
 	prowv1 "k8s.io/test-infra/prow/client/clientset/versioned/typed/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/deck/jobs"
@@ -89,7 +92,9 @@ import (
 	_ "k8s.io/test-infra/prow/spyglass/lenses/links"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/metadata"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/podinfo"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/resources"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/restcoverage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/timeline"
 )
 
 // Omittable ProwJob fields.
@@ -118,6 +123,7 @@ type options struct {
 	github                 prowflagutil.GitHubOptions
 	tideURL                string
 	hookURL                string
+	hookPluginStatsURL     string
 	oauthURL               string
 	githubOAuthConfigFile  string
 	cookieSecretFile       string
@@ -173,6 +179,7 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	var o options
 	fs.StringVar(&o.tideURL, "tide-url", "", "Path to tide. If empty, do not serve tide data.")
 	fs.StringVar(&o.hookURL, "hook-url", "", "Path to hook plugin help endpoint.")
+	fs.StringVar(&o.hookPluginStatsURL, "hook-plugin-stats-url", "", "Path to hook plugin stats endpoint. If empty, do not serve plugin stats.")
 	fs.StringVar(&o.oauthURL, "oauth-url", "", "Path to deck user dashboard endpoint.")
 	fs.StringVar(&o.githubOAuthConfigFile, "github-oauth-config-file", "/etc/github/secret", "Path to the file containing the GitHub App Client secret.")
 	fs.StringVar(&o.cookieSecretFile, "cookie-secret", "", "Path to the file containing the cookie secret key.")
@@ -237,6 +244,8 @@ var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicing the
 	l("log"),
 	l("plugin-config"),
 	l("plugin-help"),
+	l("plugin-stats"),
+	l("plugin-stats.js"),
 	l("plugins"),
 	l("pr"),
 	l("pr-data.js"),
@@ -258,6 +267,7 @@ var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicing the
 	l("tide-history.js"),
 	l("tide.js"),
 	l("view",
+		l("compare"),
 		v("job"),
 		l("gs", v("bucket", l("logs", v("job", v("build"))))),
 	),
@@ -321,6 +331,7 @@ func main() {
 	mux.Handle("/tide", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "tide.html", nil)))
 	mux.Handle("/tide-history", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "tide-history.html", nil)))
 	mux.Handle("/plugins", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "plugins.html", nil)))
+	mux.Handle("/plugin-stats", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "plugin-stats.html", nil)))
 
 	runLocal := o.pregeneratedData != ""
 
@@ -527,6 +538,10 @@ func (c *podLogClient) GetLogs(name, container string) ([]byte, error) {
 	return ioutil.ReadAll(reader)
 }
 
+func (c *podLogClient) StreamLogs(ctx context.Context, name, container string) (stdio.ReadCloser, error) {
+	return c.client.GetLogs(name, &coreapi.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+}
+
 type pjListingClientWrapper struct {
 	reader ctrlruntimeclient.Reader
 }
@@ -562,9 +577,17 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 		mux.Handle("/plugin-help.js",
 			gziphandler.GzipHandler(handlePluginHelp(newHelpAgent(o.hookURL), logrus.WithField("handler", "/plugin-help.js"))))
 	}
+	if o.hookPluginStatsURL != "" {
+		mux.Handle("/plugin-stats.js",
+			gziphandler.GzipHandler(handlePluginStats(newPluginStatsAgent(o.hookPluginStatsURL), logrus.WithField("handler", "/plugin-stats.js"))))
+	}
 
 	// tide could potentially be mocked by static data
 	if o.tideURL != "" {
+		historyQueries, err := cache.NewLRUCache(historyQueryCacheSize)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating Tide history query cache.")
+		}
 		ta := &tideAgent{
 			log:  logrus.WithField("agent", "tide"),
 			path: o.tideURL,
@@ -574,10 +597,12 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 			hiddenRepos: func() []string {
 				return cfg().Deck.HiddenRepos
 			},
-			hiddenOnly: o.hiddenOnly,
-			showHidden: o.showHidden,
-			tenantIDs:  sets.NewString(o.tenantIDs.Strings()...),
-			cfg:        cfg,
+			hiddenOnly:     o.hiddenOnly,
+			showHidden:     o.showHidden,
+			tenantIDs:      sets.NewString(o.tenantIDs.Strings()...),
+			cfg:            cfg,
+			historyClient:  &http.Client{Timeout: historyQueryTimeout},
+			historyQueries: historyQueries,
 		}
 		go func() {
 			ta.start()
@@ -680,7 +705,7 @@ func prodOnlyMain(cfg config.Getter, pluginAgent *plugins.ConfigAgent, authCfgGe
 
 func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.JobAgent, gitHubClient deckGitHubClient, gitClient git.ClientFactory) {
 	ctx := context.TODO()
-	opener, err := io.NewOpener(ctx, o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
+	opener, err := io.NewOpenerWithOptions(ctx, o.storage.OpenerOptions())
 	if err != nil {
 		logrus.WithError(err).Fatal("Error creating opener")
 	}
@@ -688,8 +713,19 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	sg.Start()
 
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
-	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
+	artifactViewHandler := http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))
+	gzipArtifactViewHandler := gziphandler.GzipHandler(artifactViewHandler)
+	mux.Handle("/spyglass/lens/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Compressing would buffer the events of streams.
+		if strings.HasSuffix(r.URL.Path, "/stream") {
+			artifactViewHandler.ServeHTTP(w, r)
+			return
+		}
+		gzipArtifactViewHandler.ServeHTTP(w, r)
+	}))
+	mux.Handle(spyglass.DownloadPath, http.StripPrefix(spyglass.DownloadPath, handleArtifactDownload(opener, cfg, logrus.WithField("handler", spyglass.DownloadPath))))
 	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o, logrus.WithField("handler", "/view"))))
+	mux.Handle("/view/compare", gziphandler.GzipHandler(handleCompareJobViews(sg, cfg, o, logrus.WithField("handler", "/view/compare"))))
 	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, opener, logrus.WithField("handler", "/job-history"))))
 	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, opener, gitHubClient, gitClient, logrus.WithField("handler", "/pr-history"))))
 	if err := initLocalLensHandler(cfg, o, sg); err != nil {
@@ -955,6 +991,44 @@ func handleRequestJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options,
 	}
 }
 
+// handleCompareJobViews handles requests to compare the artifacts of two job
+// runs. Both runs are specified like the sources of /view/:
+//
+// /view/compare?a=<key-type>/<key>&b=<key-type>/<key>
+//
+// Example:
+// - /view/compare?a=gs/kubernetes-jenkins/logs/ci-test-infra-bazel/1563&b=gs/kubernetes-jenkins/logs/ci-test-infra-bazel/1564
+func handleCompareJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		setHeadersNoCaching(w)
+		// Accept links to /view/ pages as well.
+		a := strings.TrimPrefix(r.URL.Query().Get("a"), "/view/")
+		b := strings.TrimPrefix(r.URL.Query().Get("b"), "/view/")
+		if a == "" || b == "" {
+			http.Error(w, "the a and b parameters must specify the runs to compare", http.StatusBadRequest)
+			return
+		}
+
+		comparison, err := sg.Compare(r.Context(), a, b)
+		if err != nil {
+			msg := fmt.Sprintf("error comparing runs: %v", err)
+			if shouldLogHTTPErrors(err) {
+				log.WithError(err).Warn(msg)
+			}
+			http.Error(w, msg, httpStatusForError(err))
+			return
+		}
+
+		handleSimpleTemplate(o, cfg, "compare.html", comparison)(w, r)
+		log.WithFields(logrus.Fields{
+			"duration": time.Since(start).String(),
+			"a":        a,
+			"b":        b,
+		}).Info("Comparing runs completed.")
+	}
+}
+
 // renderSpyglass returns a pre-rendered Spyglass page from the given source string
 func renderSpyglass(ctx context.Context, sg *spyglass.Spyglass, cfg config.Getter, src string, o options, csrfToken string, log *logrus.Entry) (string, error) {
 	renderStart := time.Now()
@@ -1239,13 +1313,18 @@ func handleRemoteLens(lens config.LensFileConfig, w http.ResponseWriter, r *http
 		requestType = spyglassapi.RequestActionRerender
 	case "callback":
 		requestType = spyglassapi.RequestActionCallBack
+	case "stream":
+		requestType = spyglassapi.RequestActionStream
 	default:
 		http.NotFound(w, r)
 		return
 	}
 
 	var data string
-	if requestType != spyglassapi.RequestActionInitial {
+	if requestType == spyglassapi.RequestActionStream {
+		// Streams are opened with an EventSource, which can only send GET requests.
+		data = r.URL.Query().Get("data")
+	} else if requestType != spyglassapi.RequestActionInitial {
 		dataBytes, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to read body: %v", err), http.StatusInternalServerError)
@@ -1269,13 +1348,17 @@ func handleRemoteLens(lens config.LensFileConfig, w http.ResponseWriter, r *http
 		return
 	}
 
-	(&httputil.ReverseProxy{
+	proxy := &httputil.ReverseProxy{
 		Director: func(r *http.Request) {
 			r.URL = lens.RemoteConfig.ParsedEndpoint
 			r.ContentLength = int64(len(serializedRequest))
 			r.Body = ioutil.NopCloser(bytes.NewBuffer(serializedRequest))
 		},
-	}).ServeHTTP(w, r)
+	}
+	if requestType == spyglassapi.RequestActionStream {
+		proxy.FlushInterval = -1
+	}
+	proxy.ServeHTTP(w, r)
 }
 
 func handleTidePools(cfg config.Getter, ta *tideAgent, log *logrus.Entry) http.HandlerFunc {
@@ -1299,6 +1382,7 @@ func handleTidePools(cfg config.Getter, ta *tideAgent, log *logrus.Entry) http.H
 			Queries:     queries,
 			TideQueries: queryConfigs,
 			Pools:       poolsForDeck,
+			Freezes:     ta.filterFreezes(cfg().Tide.Freezes, time.Now()),
 		}
 		pd, err := json.Marshal(payload)
 		if err != nil {
@@ -1313,12 +1397,30 @@ func handleTideHistory(ta *tideAgent, log *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
 
-		ta.Lock()
-		history := ta.history
-		ta.Unlock()
+		var hist map[string][]history.Record
+		if values := r.URL.Query(); history.IsQuery(values) {
+			if _, err := history.ParseQuery(values); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid history query: %v", err), http.StatusBadRequest)
+				return
+			}
+			var err error
+			var queryErr *historyQueryError
+			if hist, err = ta.queryHistory(values); errors.As(err, &queryErr) {
+				http.Error(w, fmt.Sprintf("Invalid history query: %s", queryErr.message), queryErr.code)
+				return
+			} else if err != nil {
+				log.WithError(err).Warning("Error querying Tide history.")
+				http.Error(w, "Failed to query Tide history.", http.StatusBadGateway)
+				return
+			}
+		} else {
+			ta.Lock()
+			hist = ta.history
+			ta.Unlock()
+		}
 
 		payload := tideHistory{
-			History: history,
+			History: hist,
 		}
 		pd, err := json.Marshal(payload)
 		if err != nil {
@@ -1346,6 +1448,23 @@ func handlePluginHelp(ha *helpAgent, log *logrus.Entry) http.HandlerFunc {
 	}
 }
 
+func handlePluginStats(sa *pluginStatsAgent, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		stats, err := sa.getStats()
+		if err != nil {
+			log.WithError(err).Error("Getting plugin stats from hook.")
+			stats = []plugins.HandlerStats{}
+		}
+		b, err := json.Marshal(stats)
+		if err != nil {
+			log.WithError(err).Error("Marshaling plugin stats.")
+			b = []byte("[]")
+		}
+		writeJSONResponse(w, r, b)
+	}
+}
+
 type logClient interface {
 	GetJobLog(job, id, container string) ([]byte, error)
 }
@@ -46,6 +46,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/cache"
 	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/deck/jobs"
@@ -544,6 +545,105 @@ func TestTideHistory(t *testing.T) {
 	}
 }
 
+func TestTideHistoryQuery(t *testing.T) {
+	testHist := map[string][]history.Record{
+		"o/r:b": {{Action: "MERGE"}},
+	}
+	var gotQuery url.Values
+	var queries int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		queries++
+		switch gotQuery.Get("repo") {
+		case "o/broken":
+			http.Error(w, "no history archive is configured", http.StatusInternalServerError)
+			return
+		case "o/busy":
+			http.Error(w, "query matches more than 10000 records", http.StatusBadRequest)
+			return
+		}
+		b, err := json.Marshal(testHist)
+		if err != nil {
+			t.Fatalf("Marshaling: %v", err)
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer s.Close()
+
+	historyQueries, err := cache.NewLRUCache(10)
+	if err != nil {
+		t.Fatalf("Creating cache: %v", err)
+	}
+	ta := tideAgent{
+		path: s.URL,
+		hiddenRepos: func() []string {
+			return []string{}
+		},
+		updatePeriod:   func() time.Duration { return time.Minute },
+		cfg:            func() *config.Config { return &config.Config{} },
+		historyClient:  &http.Client{Timeout: time.Minute},
+		historyQueries: historyQueries,
+	}
+	handler := handleTideHistory(&ta, logrus.WithField("handler", "/tide-history.js"))
+
+	testCases := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedQuery url.Values
+	}{
+		{
+			name:          "query is forwarded to tide",
+			query:         "repo=o/r&author=alice&var=tideHistory",
+			expectedCode:  http.StatusOK,
+			expectedQuery: url.Values{"repo": {"o/r"}, "author": {"alice"}},
+		},
+		{
+			name:         "invalid query is rejected",
+			query:        "pr=one",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:          "tide errors are surfaced",
+			query:         "repo=o/broken",
+			expectedCode:  http.StatusBadGateway,
+			expectedQuery: url.Values{"repo": {"o/broken"}},
+		},
+		{
+			name:          "tide rejections are surfaced",
+			query:         "repo=o/busy",
+			expectedCode:  http.StatusBadRequest,
+			expectedQuery: url.Values{"repo": {"o/busy"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotQuery = nil
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tide-history.js?"+tc.query, nil))
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if !reflect.DeepEqual(gotQuery, tc.expectedQuery) {
+				t.Errorf("Expected tide to be queried with %v, got %v", tc.expectedQuery, gotQuery)
+			}
+		})
+	}
+
+	queries = 0
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tide-history.js?repo=o/cached", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("Expected the query result to be cached, tide was queried %d times", queries)
+	}
+}
+
 func TestHelp(t *testing.T) {
 	hitCount := 0
 	help := pluginhelp.Help{
@@ -596,6 +696,41 @@ func TestHelp(t *testing.T) {
 	handleAndCheck()
 }
 
+func TestPluginStats(t *testing.T) {
+	hitCount := 0
+	stats := []plugins.HandlerStats{
+		{Plugin: "jira", Handled: 10, Errors: 2, Timeouts: 1, LatencyP50: 0.5, LatencyP90: 2, LatencyP99: 30},
+		{Plugin: "lgtm", Handled: 100, Running: 1},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hitCount++
+		b, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatalf("Marshaling: %v", err)
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer s.Close()
+	handler := handlePluginStats(newPluginStatsAgent(s.URL), logrus.WithField("handler", "/plugin-stats.js"))
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/plugin-stats.js", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Bad error code: %d", rr.Code)
+		}
+		var res []plugins.HandlerStats
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error unmarshaling: %v", err)
+		}
+		if !reflect.DeepEqual(stats, res) {
+			t.Errorf("Invalid plugin stats. Got %v, expected %v", res, stats)
+		}
+	}
+	if hitCount != 1 {
+		t.Errorf("Expected fake hook endpoint to be hit once, but endpoint was hit %d times.", hitCount)
+	}
+}
+
 func Test_gatherOptions(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// statsCacheLife is the time that we keep plugin stats before considering them stale.
+// Stats change with every event, so they are only cached to prevent excessive calls to hook.
+const statsCacheLife = 10 * time.Second
+
+type pluginStatsAgent struct {
+	path string
+
+	sync.Mutex
+	stats  []plugins.HandlerStats
+	expiry time.Time
+}
+
+func newPluginStatsAgent(path string) *pluginStatsAgent {
+	return &pluginStatsAgent{
+		path: path,
+	}
+}
+
+func (sa *pluginStatsAgent) getStats() ([]plugins.HandlerStats, error) {
+	sa.Lock()
+	defer sa.Unlock()
+	if time.Now().Before(sa.expiry) {
+		return sa.stats, nil
+	}
+
+	var stats []plugins.HandlerStats
+	resp, err := http.Get(sa.path)
+	if err != nil {
+		return nil, fmt.Errorf("error Getting plugin stats: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("response has status code %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("error decoding json plugin stats: %w", err)
+	}
+
+	sa.stats = stats
+	sa.expiry = time.Now().Add(statsCacheLife)
+	return stats, nil
+}
@@ -48,11 +48,14 @@ var (
 		kube.GerritRevision,
 		kube.GerritPatchset,
 		kube.GerritReportLabel,
+		kube.GitLabRevision,
 		github.EventGUID,
 		"created-by-tide",
 		// Annotations
 		kube.GerritID,
 		kube.GerritInstance,
+		kube.GitLabInstance,
+		kube.GitLabProject,
 	)
 )
 
@@ -163,53 +166,6 @@ func getProwJobSpec(pjType v1.ProwJobType, cfg config.Getter, name string, refs
 	}
 }
 
-type pluginsCfg func() *plugins.Configuration
-
-// canTriggerJob determines whether the given user can trigger any job.
-func canTriggerJob(user string, pj prowapi.ProwJob, cfg *prowapi.RerunAuthConfig, cli deckGitHubClient, pluginsCfg pluginsCfg, log *logrus.Entry) (bool, error) {
-	var org string
-	if pj.Spec.Refs != nil {
-		org = pj.Spec.Refs.Org
-	} else if len(pj.Spec.ExtraRefs) > 0 {
-		org = pj.Spec.ExtraRefs[0].Org
-	}
-
-	// Then check config-level rerun auth config.
-	if auth, err := cfg.IsAuthorized(org, user, cli); err != nil {
-		return false, err
-	} else if auth {
-		return true, err
-	}
-
-	// Check job-level rerun auth config.
-	if auth, err := pj.Spec.RerunAuthConfig.IsAuthorized(org, user, cli); err != nil {
-		return false, err
-	} else if auth {
-		return true, nil
-	}
-
-	if cli == nil {
-		log.Warning("No GitHub token was provided, so we cannot retrieve GitHub teams")
-		return false, nil
-	}
-
-	// If the job is a presubmit and has an associated PR, and a plugin config is provided,
-	// do the same checks as for /test
-	if pj.Spec.Type == prowapi.PresubmitJob && pj.Spec.Refs != nil && len(pj.Spec.Refs.Pulls) > 0 {
-		if pluginsCfg == nil {
-			log.Info("No plugin config was provided so we cannot check if the user would be allowed to use /test.")
-		} else {
-			pcfg := pluginsCfg()
-			pull := pj.Spec.Refs.Pulls[0]
-			org := pj.Spec.Refs.Org
-			repo := pj.Spec.Refs.Repo
-			_, allowed, err := trigger.TrustedPullRequest(cli, pcfg.TriggerFor(org, repo), user, org, repo, pull.Number, nil)
-			return allowed, err
-		}
-	}
-	return false, nil
-}
-
 func isAllowedToRerun(r *http.Request, acfg authCfgGetter, goa *githuboauth.Agent, ghc githuboauth.AuthenticatedUserIdentifier, pj prowapi.ProwJob, cli deckGitHubClient, pluginAgent *plugins.ConfigAgent, log *logrus.Entry) (bool, string, error, int) {
 	authConfig := acfg(&pj.Spec)
 	var allowed bool
@@ -228,7 +184,7 @@ func isAllowedToRerun(r *http.Request, acfg authCfgGetter, goa *githuboauth.Agen
 			return allowed, "", errors.New("Error retrieving GitHub login."), http.StatusUnauthorized
 		}
 		log = log.WithField("user", login)
-		allowed, err = canTriggerJob(login, pj, authConfig, cli, pluginAgent.Config, log)
+		allowed, err = trigger.CanRerunJob(login, pj, authConfig, cli, pluginAgent.Config, log)
 		if err != nil {
 			return allowed, "", err, http.StatusInternalServerError
 		}
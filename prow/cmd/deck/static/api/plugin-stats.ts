@@ -0,0 +1,13 @@
+export interface HandlerStats {
+  plugin: string;
+  handled: number;
+  errors: number;
+  timeouts: number;
+  github_budget_exceeded: number;
+  running: number;
+  waiting: number;
+  runaway: number;
+  latency_p50: number;
+  latency_p90: number;
+  latency_p99: number;
+}
@@ -13,6 +13,17 @@ export interface TideQuery {
   reviewApprovedRequired?: boolean;
 }
 
+export interface TideFreeze {
+  name: string;
+  orgs?: string[];
+  repos?: string[];
+  branches?: string[];
+  start: string;
+  end?: string;
+  exemption_labels?: string[];
+  message?: string;
+}
+
 export interface PullRequest extends BasePullRequest {
   Title: string;
 }
@@ -45,4 +56,5 @@ export interface TideData {
   Queries: string[];
   TideQueries: TideQuery[];
   Pools: TidePool[];
+  Freezes?: TideFreeze[];
 }
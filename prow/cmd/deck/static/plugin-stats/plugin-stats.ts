@@ -0,0 +1,45 @@
+import {HandlerStats} from "../api/plugin-stats";
+import {cell} from "../common/common";
+
+declare const pluginStats: HandlerStats[];
+
+function percentage(part: number, total: number): string {
+  if (total === 0) {
+    return "-";
+  }
+  return `${(100 * part / total).toFixed(1)}%`;
+}
+
+function latency(seconds: number): string {
+  if (seconds < 1) {
+    return `${Math.round(seconds * 1000)}ms`;
+  }
+  return `${seconds.toFixed(1)}s`;
+}
+
+function redraw(): void {
+  const tbody = document.querySelector("#stats tbody")!;
+  while (tbody.firstChild) {
+    tbody.removeChild(tbody.firstChild);
+  }
+  const stats = typeof pluginStats !== 'undefined' ? pluginStats : [];
+  for (const s of stats) {
+    const r = document.createElement("tr");
+    r.appendChild(cell.text(s.plugin));
+    r.appendChild(cell.text(String(s.handled)));
+    r.appendChild(cell.text(percentage(s.errors, s.handled)));
+    r.appendChild(cell.text(String(s.timeouts)));
+    r.appendChild(cell.text(String(s.github_budget_exceeded)));
+    r.appendChild(cell.text(`${s.running} / ${s.waiting} / ${s.runaway}`));
+    r.appendChild(cell.text(latency(s.latency_p50)));
+    r.appendChild(cell.text(latency(s.latency_p90)));
+    r.appendChild(cell.text(latency(s.latency_p99)));
+    if (s.errors > 0 && s.errors * 10 >= s.handled) {
+      r.classList.add("plugin-stats-failing");
+    }
+    tbody.appendChild(r);
+  }
+  document.getElementById("stats-count")!.textContent = `${stats.length} plugins`;
+}
+
+window.onload = redraw;
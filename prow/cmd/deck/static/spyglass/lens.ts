@@ -32,6 +32,15 @@ export interface Spyglass {
    * recommended, but not required.
    */
   request(data: string): Promise<string>;
+  /**
+   * Opens a stream of server-sent events from the server-side lens backend,
+   * which must implement StreamingLens, with the provided data. The stream
+   * ends with a "done" event whose data is the error that ended it, if any.
+   *
+   * @param data Some data to pass back to the server. JSON encoding is
+   * recommended, but not required.
+   */
+  stream(data: string): EventSource;
   /**
    * Inform Spyglass that the lens content has updated. This should be called whenever
    * the visible content changes, so Spyglass can ensure that all content is visible.
@@ -97,6 +106,11 @@ class SpyglassImpl implements Spyglass {
     const result = await this.postMessage({type: 'request', data});
     return result.data;
   }
+  public stream(data: string): EventSource {
+    const q = parseQuery(location.search.substr(1));
+    const path = location.pathname.replace(/\/iframe$/, '/stream');
+    return new EventSource(`${path}?req=${encodeURIComponent(q.req!)}&data=${encodeURIComponent(data)}`);
+  }
   public contentUpdated(): void {
     this.updateHeight();
     clearTimeout(this.pendingUpdateTimer);
@@ -8,6 +8,10 @@ declare const tideHistory: HistoryData;
 
 const recordDisplayLimit = 500;
 
+// hist holds the recent history that Deck polls from Tide, or the archived
+// history of the selected time range.
+let hist: {[key: string]: Record[]} = typeof tideHistory !== 'undefined' ? tideHistory.History : {};
+
 interface FilteredRecord extends Record {
   // The following are not initially present and are instead populated based on the 'History' map key while filtering.
   repo: string;
@@ -33,7 +37,6 @@ function optionsForRepoBranch(repo: string, branch: string): Options {
     states: {},
   };
 
-  const hist: {[key: string]: Record[]} = typeof tideHistory !== 'undefined' ? tideHistory.History : {};
   const poolKeys = Object.keys(hist);
   for (const poolKey of poolKeys) {
     const match = RegExp('(.*?):(.*)').exec(poolKey);
@@ -109,15 +112,79 @@ window.onload = (): void => {
   const options = filterBox.querySelectorAll("select")!;
   options.forEach((opt) => {
     opt.onchange = () => {
-      redraw();
+      // The archive is queried by repo and branch, the other filters only
+      // apply to the loaded records.
+      if ((opt.id === "repo" || opt.id === "branch") && archiveQuery() !== undefined) {
+        loadArchive();
+      } else {
+        redraw();
+      }
     };
   });
+  for (const id of ["from", "to"]) {
+    const input = document.getElementById(id) as HTMLInputElement;
+    input.value = getParameterByName(id) || "";
+    input.onchange = () => {
+      loadArchive();
+    };
+  }
 
   // set dropdown based on options from query string
   redrawOptions(optionsForRepoBranch("", ""));
   redraw();
+  if (archiveQuery() !== undefined) {
+    loadArchive();
+  }
 };
 
+// archiveQuery returns the parameters to query the archived history of the
+// selected time range, or undefined if no time range is selected.
+function archiveQuery(): string | undefined {
+  const from = (document.getElementById("from") as HTMLInputElement).value;
+  const to = (document.getElementById("to") as HTMLInputElement).value;
+  if (!from && !to) {
+    return undefined;
+  }
+  const params: string[] = [];
+  for (const id of ["repo", "branch"]) {
+    const sel = (document.getElementById(id) as HTMLSelectElement).value;
+    if (sel) {
+      params.push(`${id}=${encodeURIComponent(sel)}`);
+    }
+  }
+  if (from) {
+    params.push(`from=${encodeURIComponent(`${from}T00:00:00Z`)}`);
+  }
+  if (to) {
+    params.push(`to=${encodeURIComponent(`${to}T23:59:59Z`)}`);
+  }
+  return params.join("&");
+}
+
+// loadArchive loads the archived history of the selected time range, or
+// restores the recent history if no time range is selected.
+function loadArchive(): void {
+  const query = archiveQuery();
+  if (query === undefined) {
+    hist = typeof tideHistory !== 'undefined' ? tideHistory.History : {};
+    redraw();
+    return;
+  }
+  const recCount = document.getElementById("record-count")!;
+  recCount.textContent = "Loading archived records...";
+  fetch(`/tide-history.js?${query}`).then(async (resp) => {
+    if (!resp.ok) {
+      throw new Error(await resp.text());
+    }
+    return resp.json();
+  }).then((data: HistoryData) => {
+    hist = data.History || {};
+    redraw();
+  }).catch((err: Error) => {
+    recCount.textContent = `Failed to load archived records: ${err.message}`;
+  });
+}
+
 function addOptions(options: string[], selectID: string): string | undefined {
   const sel = document.getElementById(selectID)! as HTMLSelectElement;
   while (sel.length > 1) {
@@ -164,6 +231,12 @@ function redraw(): void {
   const authorSel = getSelection("author");
   const actionSel = getSelection("action");
   const stateSel = getSelection("state");
+  for (const id of ["from", "to"]) {
+    const value = (document.getElementById(id) as HTMLInputElement).value;
+    if (value) {
+      args.push(`${id}=${encodeURIComponent(value)}`);
+    }
+  }
 
   if (window.history && window.history.replaceState !== undefined) {
     if (args.length > 0) {
@@ -175,7 +248,6 @@ function redraw(): void {
   redrawOptions(opts);
 
   let filteredRecs: FilteredRecord[] = [];
-  const hist: {[key: string]: Record[]} = typeof tideHistory !== 'undefined' ? tideHistory.History : {};
   const poolKeys = Object.keys(hist);
   for (const poolKey of poolKeys) {
     const match = RegExp('(.*?):(.*)').exec(poolKey);
@@ -25,6 +25,7 @@ function infoToggle(toToggle: HTMLElement): (event: Event) => void {
 
 function redraw(): void {
   redrawQueries();
+  redrawFreezes();
   redrawPools();
 }
 
@@ -106,6 +107,46 @@ function fillDetail(data: string | string[] | undefined, type: string, connector
   }
 }
 
+function redrawFreezes(): void {
+  const freezes = document.getElementById("freezes")!;
+  while (freezes.firstChild) {
+    freezes.removeChild(freezes.firstChild);
+  }
+
+  const article = document.getElementById("freezes-article")!;
+  if (!tideData.Freezes || tideData.Freezes.length === 0) {
+    article.classList.add("hidden");
+    return;
+  }
+  article.classList.remove("hidden");
+  for (const freeze of tideData.Freezes) {
+    const li = document.createElement("li");
+    li.appendChild(createStrong(freeze.name));
+
+    const targets = (freeze.orgs || []).concat(freeze.repos || []);
+    const branches = freeze.branches || [];
+    let description = branches.length > 0 ? ` - Merging into ${branches.join(", ")} of ` : " - Merging into ";
+    description += targets.join(", ");
+    description += freeze.end ? ` is frozen until ${new Date(freeze.end).toLocaleString()}.` : " is frozen until further notice.";
+    li.appendChild(document.createTextNode(description));
+
+    const exemptionLabels = freeze.exemption_labels || [];
+    if (exemptionLabels.length > 0) {
+      li.appendChild(document.createTextNode(" Pull Requests with one of these labels can still merge: "));
+      for (const label of exemptionLabels) {
+        li.appendChild(createLabelEl(label));
+        li.appendChild(document.createTextNode(" "));
+      }
+    }
+    if (freeze.message) {
+      const p = document.createElement("p");
+      p.textContent = freeze.message;
+      li.appendChild(p);
+    }
+    freezes.appendChild(li);
+  }
+}
+
 function redrawQueries(): void {
   const queries = document.getElementById("queries")!;
   while (queries.firstChild) {
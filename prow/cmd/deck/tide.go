@@ -19,7 +19,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +31,7 @@ import (
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/cache"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/tide"
 	"k8s.io/test-infra/prow/tide/history"
@@ -37,6 +41,7 @@ type tidePools struct {
 	Queries     []string
 	TideQueries []config.TideQuery
 	Pools       []tide.PoolForDeck
+	Freezes     []config.TideFreeze
 }
 
 type tideHistory struct {
@@ -56,11 +61,44 @@ type tideAgent struct {
 	tenantIDs sets.String
 	cfg       func() *config.Config
 
+	// historyClient queries the history archive of Tide, historyQueries
+	// caches the results for historyQueryCacheTTL.
+	historyClient  *http.Client
+	historyQueries *cache.LRUCache
+
 	sync.Mutex
 	pools   []tide.Pool
 	history map[string][]history.Record
 }
 
+const (
+	// historyQueryTimeout limits how long a history query may take, as it
+	// may read weeks of archived history.
+	historyQueryTimeout = time.Minute
+	// historyQueryCacheTTL is how long the results of a history query are
+	// reused.
+	historyQueryCacheTTL = time.Minute
+	// historyQueryCacheSize is how many history query results are cached.
+	historyQueryCacheSize = 100
+)
+
+// historyQueryKey identifies the results of a history query in the cache.
+// Results expire with the window they were queried in.
+type historyQueryKey struct {
+	query  string
+	window time.Time
+}
+
+// historyQueryError is returned when Tide rejects a history query.
+type historyQueryError struct {
+	code    int
+	message string
+}
+
+func (e *historyQueryError) Error() string {
+	return fmt.Sprintf("response has status code %d: %s", e.code, e.message)
+}
+
 func (ta *tideAgent) start() {
 	startTimePool := time.Now()
 	if err := ta.updatePools(); err != nil {
@@ -159,6 +197,50 @@ func (ta *tideAgent) updateHistory() error {
 	return nil
 }
 
+// queryHistory forwards a history query to Tide. Results are cached for
+// historyQueryCacheTTL, as queries may read weeks of archived history.
+func (ta *tideAgent) queryHistory(values url.Values) (map[string][]history.Record, error) {
+	params := url.Values{}
+	for key, vals := range values {
+		if key != "var" {
+			params[key] = vals
+		}
+	}
+	query := params.Encode()
+	key := historyQueryKey{query: query, window: time.Now().Truncate(historyQueryCacheTTL)}
+	val, _, err := ta.historyQueries.GetOrAdd(key, func() (interface{}, error) {
+		return ta.fetchHistoryQuery(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	hist, ok := val.(map[string][]history.Record)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T in history query cache", val)
+	}
+	return hist, nil
+}
+
+func (ta *tideAgent) fetchHistoryQuery(query string) (map[string][]history.Record, error) {
+	resp, err := ta.historyClient.Get(strings.TrimSuffix(ta.path, "/") + "/history?" + query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &historyQueryError{code: resp.StatusCode, message: strings.TrimSpace(string(message))}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("response has status code %d", resp.StatusCode)
+	}
+	var hist map[string][]history.Record
+	if err := json.NewDecoder(resp.Body).Decode(&hist); err != nil {
+		return nil, err
+	}
+	return ta.filterHistory(hist), nil
+}
+
 func (ta *tideAgent) matchingIDs(ids []string) bool {
 	return len(ids) > 0 && ta.tenantIDs.HasAll(ids...)
 }
@@ -251,6 +333,29 @@ func (ta *tideAgent) filterQueries(queries []config.TideQuery) []config.TideQuer
 	return filtered
 }
 
+// filterFreezes returns the freezes in effect at the given time that apply to
+// repos this deck may show.
+func (ta *tideAgent) filterFreezes(freezes []config.TideFreeze, now time.Time) []config.TideFreeze {
+	filtered := make([]config.TideFreeze, 0, len(freezes))
+	for _, freeze := range freezes {
+		if !freeze.ActiveAt(now) {
+			continue
+		}
+		curIDs := sets.NewString()
+		needsHide := false
+		for _, orgOrRepo := range append(append([]string{}, freeze.Orgs...), freeze.Repos...) {
+			curIDs.Insert(ta.cfg().GetProwJobDefault(orgOrRepo, "*").TenantID)
+			if matches(orgOrRepo, ta.hiddenRepos()) {
+				needsHide = true
+			}
+		}
+		if ta.filter("", curIDs, needsHide) {
+			filtered = append(filtered, freeze)
+		}
+	}
+	return filtered
+}
+
 // matches returns whether the provided repo intersects
 // with repos. repo has always the "org/repo" format but
 // repos can include both orgs and repos.
@@ -19,6 +19,7 @@ package main
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -720,6 +721,32 @@ func TestFilter(t *testing.T) {
 	}
 }
 
+func TestFilterFreezes(t *testing.T) {
+	now := time.Now()
+	ended := now.Add(-time.Hour)
+	freezes := []config.TideFreeze{
+		{Name: "active", Orgs: []string{"kubernetes"}, Start: now.Add(-2 * time.Hour)},
+		{Name: "hidden", Repos: []string{"kubernetes-security/apiserver"}, Start: now.Add(-2 * time.Hour)},
+		{Name: "ended", Orgs: []string{"kubernetes"}, Start: now.Add(-2 * time.Hour), End: &ended},
+		{Name: "upcoming", Orgs: []string{"kubernetes"}, Start: now.Add(time.Hour)},
+	}
+	ta := &tideAgent{
+		hiddenRepos: func() []string {
+			return []string{"kubernetes-security"}
+		},
+		log: logrus.WithField("agent", "tide"),
+		cfg: func() *config.Config { return &config.Config{} },
+	}
+
+	var names []string
+	for _, freeze := range ta.filterFreezes(freezes, now) {
+		names = append(names, freeze.Name)
+	}
+	if expected := []string{"active"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected freezes %v, got %v", expected, names)
+	}
+}
+
 func TestMatches(t *testing.T) {
 	tests := []struct {
 		name string
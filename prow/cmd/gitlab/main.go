@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/pkg/flagutil"
+	"k8s.io/test-infra/prow/config/secret"
+	prowflagutil "k8s.io/test-infra/prow/flagutil"
+	configflagutil "k8s.io/test-infra/prow/flagutil/config"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/gitlab/adapter"
+	"k8s.io/test-infra/prow/interrupts"
+	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/metrics"
+	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/pjutil/pprof"
+)
+
+type options struct {
+	port        int
+	gracePeriod time.Duration
+
+	config                 configflagutil.ConfigOptions
+	gitlab                 prowflagutil.GitLabOptions
+	kubernetes             prowflagutil.KubernetesOptions
+	instrumentationOptions prowflagutil.InstrumentationOptions
+
+	webhookPath        string
+	webhookTokenFile   string
+	trustedAccessLevel int
+	dryRun             bool
+}
+
+func (o *options) validate() error {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.gitlab, &o.config} {
+		if err := group.Validate(o.dryRun); err != nil {
+			return err
+		}
+	}
+	if o.gitlab.Endpoint == "" {
+		return errors.New("--gitlab-endpoint must be set")
+	}
+	if o.webhookTokenFile == "" {
+		return errors.New("--webhook-token-file must be set")
+	}
+	switch gitlab.AccessLevel(o.trustedAccessLevel) {
+	case gitlab.GuestAccess, gitlab.ReporterAccess, gitlab.DeveloperAccess, gitlab.MaintainerAccess, gitlab.OwnerAccess:
+	default:
+		return fmt.Errorf("--trusted-access-level must be one of 10, 20, 30, 40 or 50, got %d", o.trustedAccessLevel)
+	}
+	return nil
+}
+
+func gatherOptions(fs *flag.FlagSet, args ...string) options {
+	var o options
+	fs.IntVar(&o.port, "port", 8888, "Port to listen on.")
+	fs.DurationVar(&o.gracePeriod, "grace-period", 180*time.Second, "On shutdown, try to handle remaining events for the specified duration.")
+	fs.StringVar(&o.webhookPath, "webhook-path", "/hook", "The path to which GitLab sends webhooks.")
+	fs.StringVar(&o.webhookTokenFile, "webhook-token-file", "/etc/webhook/token", "Path to the file containing the secret token of the GitLab webhooks.")
+	fs.IntVar(&o.trustedAccessLevel, "trusted-access-level", int(gitlab.DeveloperAccess), "The minimum access level in a project of users whose merge requests and comments trigger jobs, e.g. 30 for developers.")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Run in dry-run mode, performing no modifying actions.")
+	for _, group := range []flagutil.OptionGroup{&o.gitlab, &o.kubernetes, &o.instrumentationOptions, &o.config} {
+		group.AddFlags(fs)
+	}
+	fs.Parse(args)
+	return o
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o := gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	pprof.Instrument(o.instrumentationOptions)
+
+	ca, err := o.config.ConfigAgent()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error starting config agent.")
+	}
+	cfg := ca.Config
+
+	metrics.ExposeMetrics("gitlab", cfg().PushGateway, o.instrumentationOptions.MetricsPort)
+
+	if err := secret.Add(o.webhookTokenFile); err != nil {
+		logrus.WithError(err).Fatal("Error starting secrets agent.")
+	}
+
+	gitlabClient, err := o.gitlab.Client(o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitLab client.")
+	}
+
+	prowJobClient, err := o.kubernetes.ProwJobClient(cfg().ProwJobNamespace, o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting ProwJob client.")
+	}
+
+	defer interrupts.WaitForGracefulShutdown()
+
+	server := adapter.NewServer(cfg, gitlabClient, prowJobClient, secret.GetTokenGenerator(o.webhookTokenFile), gitlab.AccessLevel(o.trustedAccessLevel))
+	interrupts.OnInterrupt(server.GracefulShutdown)
+
+	health := pjutil.NewHealthOnPort(o.instrumentationOptions.HealthPort)
+
+	mux := http.NewServeMux()
+	mux.Handle(o.webhookPath, server)
+	httpServer := &http.Server{Addr: ":" + strconv.Itoa(o.port), Handler: mux}
+
+	health.ServeReady()
+
+	interrupts.ListenAndServe(httpServer, o.gracePeriod)
+}
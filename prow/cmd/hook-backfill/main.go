@@ -0,0 +1,346 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// hook-backfill finds the deliveries of a webhook that hook missed through
+// the deliveries API of GitHub and delivers them again.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/pkg/flagutil"
+	prowflagutil "k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/eventqueue"
+	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/phony"
+)
+
+type options struct {
+	github prowflagutil.GitHubOptions
+
+	repo    string
+	app     bool
+	hookURL string
+	hookID  int
+
+	since time.Duration
+	until time.Duration
+
+	eventQueueDir       string
+	eventQueuePath      string
+	eventQueueRetention time.Duration
+	storage             prowflagutil.StorageClientOptions
+
+	sendTo         string
+	hmacSecretFile string
+
+	confirm bool
+}
+
+func (o *options) Validate() error {
+	if (o.repo == "") == !o.app {
+		return errors.New("exactly one of --repo and --app is required")
+	}
+	if o.repo != "" && (o.hookURL == "") == (o.hookID == 0) {
+		return errors.New("exactly one of --hook-url and --hook-id is required with --repo")
+	}
+	if o.since <= o.until {
+		return errors.New("--since has to be longer than --until")
+	}
+	if o.eventQueueDir != "" && o.eventQueuePath != "" {
+		return errors.New("--event-queue-dir and --event-queue-path are mutually exclusive")
+	}
+	if o.sendTo != "" && o.hmacSecretFile == "" {
+		return errors.New("--hmac-secret-file is required with --send-to")
+	}
+	o.github.AllowDirectAccess = true
+	for _, group := range []flagutil.OptionGroup{&o.github, &o.storage} {
+		if err := group.Validate(!o.confirm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func gatherOptions(fs *flag.FlagSet, args ...string) options {
+	var o options
+	fs.StringVar(&o.repo, "repo", "", "Org or org/repo of the webhook.")
+	fs.BoolVar(&o.app, "app", false, "Use the webhook of the GitHub App configured with --github-app-id instead of an org or repo webhook.")
+	fs.StringVar(&o.hookURL, "hook-url", "", "URL of the webhook, to find it among the webhooks of --repo.")
+	fs.IntVar(&o.hookID, "hook-id", 0, "ID of the webhook of --repo.")
+	fs.DurationVar(&o.since, "since", time.Hour, "Start of the window of deliveries to check, as a duration ago. GitHub keeps deliveries for three days.")
+	fs.DurationVar(&o.until, "until", 0, "End of the window of deliveries to check, as a duration ago.")
+	fs.StringVar(&o.eventQueueDir, "event-queue-dir", "", "Directory of the write-ahead log of hook, or of a copy of it. Successful deliveries that are not in the event queue of hook are delivered again as well.")
+	fs.StringVar(&o.eventQueuePath, "event-queue-path", "", "Object storage path of the event queue of hook, e.g. gs://bucket/hook. Successful deliveries that are not in the event queue of hook are delivered again as well.")
+	fs.DurationVar(&o.eventQueueRetention, "event-queue-retention", 24*time.Hour, "Retention of handled events of the event queue of hook.")
+	fs.StringVar(&o.sendTo, "send-to", "", "Send the events straight to this URL of hook, e.g. http://hook:8888/hook, instead of asking GitHub to redeliver them.")
+	fs.StringVar(&o.hmacSecretFile, "hmac-secret-file", "", "Path to the file containing the GitHub HMAC secret of hook, to sign the events sent with --send-to.")
+	fs.BoolVar(&o.confirm, "confirm", false, "Deliver the events again instead of only listing them.")
+	for _, group := range []flagutil.OptionGroup{&o.github, &o.storage} {
+		group.AddFlags(fs)
+	}
+	fs.Parse(args)
+	return o
+}
+
+type deliveryClient interface {
+	ListOrgHooks(org string) ([]github.Hook, error)
+	ListRepoHooks(org, repo string) ([]github.Hook, error)
+	github.HookDeliveryClient
+}
+
+// webhook accesses the deliveries of an org, repo or app webhook.
+type webhook struct {
+	name      string
+	list      func(since time.Time) ([]github.HookDelivery, error)
+	get       func(deliveryID int64) (*github.HookDelivery, error)
+	redeliver func(deliveryID int64) error
+}
+
+func appWebhook(client deliveryClient) webhook {
+	return webhook{
+		name:      "app webhook",
+		list:      client.ListAppHookDeliveries,
+		get:       client.GetAppHookDelivery,
+		redeliver: client.RedeliverAppHookDelivery,
+	}
+}
+
+func orgWebhook(client deliveryClient, org string, id int) webhook {
+	return webhook{
+		name: fmt.Sprintf("webhook %d of %s", id, org),
+		list: func(since time.Time) ([]github.HookDelivery, error) {
+			return client.ListOrgHookDeliveries(org, id, since)
+		},
+		get: func(deliveryID int64) (*github.HookDelivery, error) {
+			return client.GetOrgHookDelivery(org, id, deliveryID)
+		},
+		redeliver: func(deliveryID int64) error {
+			return client.RedeliverOrgHookDelivery(org, id, deliveryID)
+		},
+	}
+}
+
+func repoWebhook(client deliveryClient, org, repo string, id int) webhook {
+	return webhook{
+		name: fmt.Sprintf("webhook %d of %s/%s", id, org, repo),
+		list: func(since time.Time) ([]github.HookDelivery, error) {
+			return client.ListRepoHookDeliveries(org, repo, id, since)
+		},
+		get: func(deliveryID int64) (*github.HookDelivery, error) {
+			return client.GetRepoHookDelivery(org, repo, id, deliveryID)
+		},
+		redeliver: func(deliveryID int64) error {
+			return client.RedeliverRepoHookDelivery(org, repo, id, deliveryID)
+		},
+	}
+}
+
+func (o *options) webhook(client deliveryClient) (webhook, error) {
+	if o.app {
+		return appWebhook(client), nil
+	}
+	parts := strings.SplitN(o.repo, "/", 2)
+	id := o.hookID
+	if id == 0 {
+		var hooks []github.Hook
+		var err error
+		if len(parts) == 1 {
+			hooks, err = client.ListOrgHooks(parts[0])
+		} else {
+			hooks, err = client.ListRepoHooks(parts[0], parts[1])
+		}
+		if err != nil {
+			return webhook{}, fmt.Errorf("failed to list the webhooks of %s: %w", o.repo, err)
+		}
+		for _, h := range hooks {
+			if h.Config.URL == o.hookURL {
+				id = h.ID
+				break
+			}
+		}
+		if id == 0 {
+			return webhook{}, fmt.Errorf("%s has no webhook for %s", o.repo, o.hookURL)
+		}
+	}
+	if len(parts) == 1 {
+		return orgWebhook(client, parts[0], id), nil
+	}
+	return repoWebhook(client, parts[0], parts[1], id), nil
+}
+
+// queuedGUIDs returns the GUIDs of the events in the event queue of hook, or
+// nil if no event queue is configured.
+func (o *options) queuedGUIDs(ctx context.Context) (map[string]bool, error) {
+	var entries []eventqueue.Entry
+	var err error
+	switch {
+	case o.eventQueueDir != "":
+		entries, err = eventqueue.ReadLog(filepath.Join(o.eventQueueDir, eventqueue.LogFile))
+	case o.eventQueuePath != "":
+		opener, openerErr := o.storage.StorageClient(ctx)
+		if openerErr != nil {
+			return nil, openerErr
+		}
+		entries, err = eventqueue.NewObjectQueue(opener, o.eventQueuePath, o.eventQueueRetention).List(ctx, true)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	guids := map[string]bool{}
+	for _, e := range entries {
+		guids[e.GUID] = true
+	}
+	return guids, nil
+}
+
+// missed is an event that hook missed.
+type missed struct {
+	// delivery is the latest delivery of the event.
+	delivery github.HookDelivery
+	reason   string
+}
+
+// findMissed returns the events of the deliveries within the window that
+// failed every time they were delivered, and the ones that succeeded but are
+// not in the given event queue of hook if it is not nil. Events are sorted
+// oldest first.
+func findMissed(deliveries []github.HookDelivery, from, to time.Time, queued map[string]bool) []missed {
+	latest := map[string]github.HookDelivery{}
+	succeeded := map[string]bool{}
+	for _, d := range deliveries {
+		if d.DeliveredAt.Before(from) || d.DeliveredAt.After(to) {
+			continue
+		}
+		if l, ok := latest[d.GUID]; !ok || d.DeliveredAt.After(l.DeliveredAt) {
+			latest[d.GUID] = d
+		}
+		if d.Succeeded() {
+			succeeded[d.GUID] = true
+		}
+	}
+	var res []missed
+	for guid, d := range latest {
+		switch {
+		case !succeeded[guid]:
+			res = append(res, missed{delivery: d, reason: fmt.Sprintf("failed: %d %s", d.StatusCode, d.Status)})
+		case queued != nil && !queued[guid]:
+			res = append(res, missed{delivery: d, reason: "not in the event queue of hook"})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].delivery.DeliveredAt.Before(res[j].delivery.DeliveredAt) })
+	return res
+}
+
+// send fetches the request of the delivery and sends it straight to hook.
+func send(client *http.Client, hook webhook, sendTo string, d github.HookDelivery, tokenGenerator func() []byte) error {
+	full, err := hook.get(d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if full.Request == nil {
+		return errors.New("delivery has no request")
+	}
+	header := http.Header{}
+	for k, v := range full.Request.Headers {
+		header.Set(k, v)
+	}
+	return phony.SendEvent(client, sendTo, d.Event, d.GUID, full.Request.Payload, header, tokenGenerator)
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o := gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
+	if err := o.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	githubClient, err := o.github.GitHubClient(!o.confirm)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client.")
+	}
+	hook, err := o.webhook(githubClient)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error finding the webhook.")
+	}
+	queued, err := o.queuedGUIDs(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Error reading the event queue of hook.")
+	}
+
+	now := time.Now()
+	from, to := now.Add(-o.since), now.Add(-o.until)
+	deliveries, err := hook.list(from)
+	if err != nil {
+		logrus.WithError(err).Fatalf("Error listing the deliveries of the %s.", hook.name)
+	}
+	events := findMissed(deliveries, from, to, queued)
+	logrus.Infof("Found %d missed events in %d deliveries of the %s between %s and %s.", len(events), len(deliveries), hook.name, from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	var tokenGenerator func() []byte
+	if o.sendTo != "" {
+		secret, err := ioutil.ReadFile(o.hmacSecretFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error reading the HMAC secret.")
+		}
+		tokenGenerator = func() []byte { return secret }
+	}
+	client := &http.Client{Timeout: time.Minute}
+	var failed int
+	for _, e := range events {
+		l := logrus.WithFields(logrus.Fields{
+			"event-type":     e.delivery.Event,
+			"action":         e.delivery.Action,
+			github.EventGUID: e.delivery.GUID,
+			"delivered-at":   e.delivery.DeliveredAt,
+			"reason":         e.reason,
+		})
+		if !o.confirm {
+			l.Info("Would deliver event again.")
+			continue
+		}
+		if o.sendTo != "" {
+			err = send(client, hook, o.sendTo, e.delivery, tokenGenerator)
+		} else {
+			err = hook.redeliver(e.delivery.ID)
+		}
+		if err != nil {
+			l.WithError(err).Error("Error delivering event again.")
+			failed++
+			continue
+		}
+		l.Info("Delivered event again.")
+	}
+	if failed > 0 {
+		logrus.Fatalf("Failed to deliver %d events again.", failed)
+	}
+}
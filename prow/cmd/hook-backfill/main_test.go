@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+type fakeDeliveryClient struct {
+	github.HookDeliveryClient
+	hooks       []github.Hook
+	deliveries  map[int64]*github.HookDelivery
+	redelivered []int64
+}
+
+func (f *fakeDeliveryClient) ListOrgHooks(org string) ([]github.Hook, error) {
+	return f.hooks, nil
+}
+
+func (f *fakeDeliveryClient) ListRepoHooks(org, repo string) ([]github.Hook, error) {
+	return f.hooks, nil
+}
+
+func (f *fakeDeliveryClient) GetRepoHookDelivery(org, repo string, id int, deliveryID int64) (*github.HookDelivery, error) {
+	return f.deliveries[deliveryID], nil
+}
+
+func (f *fakeDeliveryClient) RedeliverRepoHookDelivery(org, repo string, id int, deliveryID int64) error {
+	f.redelivered = append(f.redelivered, deliveryID)
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		invalid bool
+	}{
+		{
+			name: "repo webhook by URL",
+			args: []string{"--repo=org/repo", "--hook-url=https://hook.example.com/hook"},
+		},
+		{
+			name: "app webhook",
+			args: []string{"--app"},
+		},
+		{
+			name:    "repo and app",
+			args:    []string{"--app", "--repo=org"},
+			invalid: true,
+		},
+		{
+			name:    "repo without webhook",
+			args:    []string{"--repo=org"},
+			invalid: true,
+		},
+		{
+			name:    "empty window",
+			args:    []string{"--app", "--since=1h", "--until=2h"},
+			invalid: true,
+		},
+		{
+			name:    "sending without secret",
+			args:    []string{"--app", "--send-to=http://hook:8888/hook"},
+			invalid: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := gatherOptions(flag.NewFlagSet("hook-backfill", flag.PanicOnError), tc.args...)
+			if err := o.Validate(); (err != nil) != tc.invalid {
+				t.Errorf("expected invalid %t, got %v", tc.invalid, err)
+			}
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	client := &fakeDeliveryClient{hooks: []github.Hook{
+		{ID: 1, Config: github.HookConfig{URL: "https://other.example.com"}},
+		{ID: 2, Config: github.HookConfig{URL: "https://hook.example.com/hook"}},
+	}}
+	o := options{repo: "org/repo", hookURL: "https://hook.example.com/hook"}
+	hook, err := o.webhook(client)
+	if err != nil {
+		t.Fatalf("failed to find webhook: %v", err)
+	}
+	if hook.name != "webhook 2 of org/repo" {
+		t.Errorf("expected the webhook with the URL, got %s", hook.name)
+	}
+	if err := hook.redeliver(12); err != nil || !reflect.DeepEqual(client.redelivered, []int64{12}) {
+		t.Errorf("expected the delivery to be redelivered, got %v, %v", client.redelivered, err)
+	}
+
+	o.hookURL = "https://missing.example.com"
+	if _, err := o.webhook(client); err == nil {
+		t.Error("expected an error for a missing webhook")
+	}
+}
+
+func TestFindMissed(t *testing.T) {
+	from := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	delivery := func(id int64, guid string, after time.Duration, code int) github.HookDelivery {
+		return github.HookDelivery{ID: id, GUID: guid, DeliveredAt: from.Add(after), StatusCode: code}
+	}
+	deliveries := []github.HookDelivery{
+		delivery(1, "before", -time.Minute, 502),
+		delivery(2, "failed", time.Minute, 502),
+		delivery(3, "failed", 2*time.Minute, 0),
+		delivery(4, "recovered", 3*time.Minute, 502),
+		delivery(5, "recovered", 4*time.Minute, 200),
+		delivery(6, "succeeded", 5*time.Minute, 200),
+		delivery(7, "queued", 6*time.Minute, 200),
+		delivery(8, "after", 2*time.Hour, 502),
+	}
+	testCases := []struct {
+		name     string
+		queued   map[string]bool
+		expected []int64
+	}{
+		{
+			name:     "failed deliveries",
+			expected: []int64{3},
+		},
+		{
+			name:     "failed deliveries and ones missing in the event queue",
+			queued:   map[string]bool{"recovered": true, "queued": true},
+			expected: []int64{3, 6},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []int64
+			for _, m := range findMissed(deliveries, from, to, tc.queued) {
+				actual = append(actual, m.delivery.ID)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected deliveries %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	tokenGenerator := func() []byte { return []byte("abc") }
+	payload := json.RawMessage(`{"repository": {"full_name": "org/repo"}}`)
+	client := &fakeDeliveryClient{deliveries: map[int64]*github.HookDelivery{
+		1: {ID: 1, Request: &github.HookDeliveryRequest{
+			Headers: map[string]string{"X-GitHub-Event": "issue_comment", "X-GitHub-Hook-ID": "2"},
+			Payload: payload,
+		}},
+	}}
+	var received *http.Request
+	var body []byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer hook.Close()
+
+	d := github.HookDelivery{ID: 1, GUID: "delivery", Event: "issue_comment"}
+	if err := send(hook.Client(), repoWebhook(client, "org", "repo", 2), hook.URL, d, tokenGenerator); err != nil {
+		t.Fatalf("failed to send event: %v", err)
+	}
+	if string(body) != string(payload) || !github.ValidatePayload(body, received.Header.Get("X-Hub-Signature"), tokenGenerator) {
+		t.Errorf("expected the signed payload of the delivery, got %s", body)
+	}
+	if received.Header.Get("X-GitHub-Delivery") != "delivery" || received.Header.Get("X-GitHub-Hook-ID") != "2" {
+		t.Errorf("expected the headers of the delivery, got %v", received.Header)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// hook-replay sends webhooks persisted by the event queue of hook to hook
+// again, e.g. after plugins failed to handle them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowflagutil "k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/eventqueue"
+	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/phony"
+)
+
+type options struct {
+	hookURL        string
+	hmacSecretFile string
+
+	eventQueueDir       string
+	eventQueuePath      string
+	eventQueueRetention time.Duration
+	storage             prowflagutil.StorageClientOptions
+
+	since          time.Duration
+	deliveries     prowflagutil.Strings
+	eventTypes     prowflagutil.Strings
+	includeHandled bool
+
+	dryRun bool
+}
+
+func (o *options) Validate() error {
+	if (o.eventQueueDir == "") == (o.eventQueuePath == "") {
+		return errors.New("exactly one of --event-queue-dir and --event-queue-path is required")
+	}
+	if o.hookURL == "" {
+		return errors.New("--hook-url is required")
+	}
+	return o.storage.Validate(o.dryRun)
+}
+
+func gatherOptions(fs *flag.FlagSet, args ...string) options {
+	var o options
+	fs.StringVar(&o.hookURL, "hook-url", "http://hook:8888/hook", "URL of the webhook endpoint of hook to send the events to.")
+	fs.StringVar(&o.hmacSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret of hook.")
+	fs.StringVar(&o.eventQueueDir, "event-queue-dir", "", "Directory of the write-ahead log of hook, or of a copy of it.")
+	fs.StringVar(&o.eventQueuePath, "event-queue-path", "", "Object storage path of the event queue of hook, e.g. gs://bucket/hook.")
+	fs.DurationVar(&o.eventQueueRetention, "event-queue-retention", 24*time.Hour, "Retention of handled events of the event queue of hook.")
+	fs.DurationVar(&o.since, "since", time.Hour, "Only replay events received within this duration. Zero replays all events of the queue.")
+	fs.Var(&o.deliveries, "delivery", "Only replay the event with this X-GitHub-Delivery header. Can be passed multiple times.")
+	fs.Var(&o.eventTypes, "event-type", "Only replay events of this type, e.g. issue_comment. Can be passed multiple times.")
+	fs.BoolVar(&o.includeHandled, "include-handled", false, "Replay events that hook handled already as well, not just the ones it did not handle yet.")
+	fs.BoolVar(&o.dryRun, "dry-run", true, "Only list the events that would be replayed.")
+	o.storage.AddFlags(fs)
+	fs.Parse(args)
+	return o
+}
+
+func (o *options) entries(ctx context.Context) ([]eventqueue.Entry, error) {
+	if o.eventQueueDir != "" {
+		// Read the log instead of opening the queue, hook may be appending
+		// to it and opening the queue compacts it.
+		return eventqueue.ReadLog(filepath.Join(o.eventQueueDir, eventqueue.LogFile))
+	}
+	opener, err := o.storage.StorageClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return eventqueue.NewObjectQueue(opener, o.eventQueuePath, o.eventQueueRetention).List(ctx, o.includeHandled)
+}
+
+// selected returns the events of the entries that the options select.
+func (o *options) selected(entries []eventqueue.Entry, now time.Time) []eventqueue.Event {
+	deliveries := o.deliveries.StringSet()
+	eventTypes := o.eventTypes.StringSet()
+	var res []eventqueue.Event
+	for _, e := range entries {
+		if e.Acked && !o.includeHandled {
+			continue
+		}
+		if o.since > 0 && now.Sub(e.Received) > o.since {
+			continue
+		}
+		if len(deliveries) > 0 && !deliveries.Has(e.GUID) {
+			continue
+		}
+		if len(eventTypes) > 0 && !eventTypes.Has(e.Type) {
+			continue
+		}
+		res = append(res, e.Event)
+	}
+	return res
+}
+
+// replay sends the event to hook. The event gets a new delivery ID, hook
+// ignores it as a redelivery otherwise.
+func replay(client *http.Client, hookURL string, e eventqueue.Event, tokenGenerator func() []byte, now time.Time) error {
+	guid := fmt.Sprintf("%s-replay-%d", e.GUID, now.Unix())
+	return phony.SendEvent(client, hookURL, e.Type, guid, e.Payload, e.Header, tokenGenerator)
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o := gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
+	if err := o.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	entries, err := o.entries(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Error reading the event queue.")
+	}
+	now := time.Now()
+	events := o.selected(entries, now)
+	logrus.Infof("Replaying %d of %d queued events.", len(events), len(entries))
+
+	secret, err := ioutil.ReadFile(o.hmacSecretFile)
+	if err != nil && !o.dryRun {
+		logrus.WithError(err).Fatal("Error reading the HMAC secret.")
+	}
+	tokenGenerator := func() []byte { return secret }
+	client := &http.Client{Timeout: time.Minute}
+	var failed int
+	for _, e := range events {
+		l := logrus.WithFields(logrus.Fields{"event-type": e.Type, github.EventGUID: e.GUID, "received": e.Received})
+		if o.dryRun {
+			l.Info("Would replay event.")
+			continue
+		}
+		if err := replay(client, o.hookURL, e, tokenGenerator, now); err != nil {
+			l.WithError(err).Error("Error replaying event.")
+			failed++
+			continue
+		}
+		l.Info("Replayed event.")
+	}
+	if failed > 0 {
+		logrus.Fatalf("Failed to replay %d events.", failed)
+	}
+}
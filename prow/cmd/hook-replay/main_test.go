@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook/eventqueue"
+)
+
+func TestSelected(t *testing.T) {
+	now := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	entries := []eventqueue.Entry{
+		{Event: eventqueue.Event{GUID: "old", Type: "issue_comment", Received: now.Add(-2 * time.Hour)}},
+		{Event: eventqueue.Event{GUID: "handled", Type: "issue_comment", Received: now.Add(-time.Minute)}, Acked: true},
+		{Event: eventqueue.Event{GUID: "comment", Type: "issue_comment", Received: now.Add(-time.Minute)}},
+		{Event: eventqueue.Event{GUID: "push", Type: "push", Received: now.Add(-time.Minute)}},
+	}
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "defaults replay recent unhandled events",
+			expected: []string{"comment", "push"},
+		},
+		{
+			name:     "all events",
+			args:     []string{"--since=0", "--include-handled"},
+			expected: []string{"old", "handled", "comment", "push"},
+		},
+		{
+			name:     "by delivery",
+			args:     []string{"--include-handled", "--delivery=handled", "--delivery=push"},
+			expected: []string{"handled", "push"},
+		},
+		{
+			name:     "by event type",
+			args:     []string{"--since=3h", "--event-type=issue_comment"},
+			expected: []string{"old", "comment"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := gatherOptions(flag.NewFlagSet("hook-replay", flag.PanicOnError), tc.args...)
+			var actual []string
+			for _, e := range o.selected(entries, now) {
+				actual = append(actual, e.GUID)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected events %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tokenGenerator := func() []byte { return []byte("abc") }
+	var received *http.Request
+	var body []byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer hook.Close()
+
+	now := time.Unix(1654084800, 0)
+	e := eventqueue.Event{
+		GUID:    "delivery",
+		Type:    "issue_comment",
+		Payload: []byte(`{"repository": {"full_name": "org/repo"}}`),
+		Header:  http.Header{"X-Hub-Signature": []string{"sha1=stale"}, "User-Agent": []string{"GitHub-Hookshot/abc"}},
+	}
+	if err := replay(hook.Client(), hook.URL, e, tokenGenerator, now); err != nil {
+		t.Fatalf("failed to replay event: %v", err)
+	}
+	if !github.ValidatePayload(body, received.Header.Get("X-Hub-Signature"), tokenGenerator) {
+		t.Error("expected the replayed event to be signed")
+	}
+	if guid := received.Header.Get("X-GitHub-Delivery"); guid != "delivery-replay-1654084800" {
+		t.Errorf("expected a new delivery ID, got %q", guid)
+	}
+	if received.Header.Get("X-GitHub-Event") != "issue_comment" || received.Header.Get("User-Agent") != "GitHub-Hookshot/abc" {
+		t.Errorf("expected the headers of the event, got %v", received.Header)
+	}
+}
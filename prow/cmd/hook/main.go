@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"net/http"
 	"os"
@@ -36,6 +38,7 @@ import (
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/githubeventserver"
 	"k8s.io/test-infra/prow/hook"
+	"k8s.io/test-infra/prow/hook/eventqueue"
 	"k8s.io/test-infra/prow/interrupts"
 	jiraclient "k8s.io/test-infra/prow/jira"
 	"k8s.io/test-infra/prow/logrusutil"
@@ -71,17 +74,29 @@ type options struct {
 	bugzilla               prowflagutil.BugzillaOptions
 	instrumentationOptions prowflagutil.InstrumentationOptions
 	jira                   prowflagutil.JiraOptions
+	storage                prowflagutil.StorageClientOptions
 
 	webhookSecretFile string
 	slackTokenFile    string
+
+	eventQueueDir       string
+	eventQueuePath      string
+	eventQueueRetention time.Duration
+	eventQueueReplay    time.Duration
 }
 
 func (o *options) Validate() error {
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig, &o.storage} {
 		if err := group.Validate(o.dryRun); err != nil {
 			return err
 		}
 	}
+	if o.eventQueueDir != "" && o.eventQueuePath != "" {
+		return errors.New("--event-queue-dir and --event-queue-path are mutually exclusive")
+	}
+	if o.eventQueueReplay <= 0 {
+		return errors.New("--event-queue-replay-after must be positive")
+	}
 
 	return nil
 }
@@ -94,12 +109,16 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
 	fs.DurationVar(&o.gracePeriod, "grace-period", 180*time.Second, "On shutdown, try to handle remaining events for the specified duration. ")
 	o.pluginsConfig.PluginConfigPathDefault = "/etc/plugins/plugins.yaml"
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.instrumentationOptions, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.bugzilla, &o.instrumentationOptions, &o.jira, &o.githubEnablement, &o.config, &o.pluginsConfig, &o.storage} {
 		group.AddFlags(fs)
 	}
 
 	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to the file containing the Slack token to use.")
+	fs.StringVar(&o.eventQueueDir, "event-queue-dir", "", "Directory of a write-ahead log that persists webhooks until they are handled, e.g. on a persistent volume. Events are only kept in memory if neither this nor --event-queue-path is set.")
+	fs.StringVar(&o.eventQueuePath, "event-queue-path", "", "Object storage path that persists webhooks until they are handled, e.g. gs://bucket/hook. Can be shared by replicas of hook.")
+	fs.DurationVar(&o.eventQueueRetention, "event-queue-retention", 24*time.Hour, "How long handled webhooks are kept in the event queue to ignore redeliveries of them and to replay them.")
+	fs.DurationVar(&o.eventQueueReplay, "event-queue-replay-after", 15*time.Minute, "How long after their receipt queued webhooks that were not handled yet are handled again, as the replica of hook that received them is assumed to have stopped. A replica that handles a webhook again has this long to do so before another one takes over.")
 	fs.Parse(args)
 	return o
 }
@@ -249,6 +268,29 @@ func main() {
 		Metrics:        promMetrics,
 		RepoEnabled:    o.githubEnablement.EnablementChecker(),
 		TokenGenerator: secret.GetTokenGenerator(o.webhookSecretFile),
+		ReplayAfter:    o.eventQueueReplay,
+	}
+	switch {
+	case o.eventQueueDir != "":
+		server.Queue, err = eventqueue.NewDiskQueue(o.eventQueueDir, o.eventQueueRetention)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error opening event queue.")
+		}
+	case o.eventQueuePath != "":
+		opener, err := o.storage.StorageClient(context.Background())
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating opener for the event queue.")
+		}
+		server.Queue = eventqueue.NewObjectQueue(opener, o.eventQueuePath, o.eventQueueRetention)
+	}
+	if server.Queue != nil {
+		// Handle the events that were received but not handled, e.g.
+		// before the last shutdown of a replica.
+		interrupts.TickLiteral(func() {
+			if err := server.ReplayPending(context.Background()); err != nil {
+				logrus.WithError(err).Error("Error handling queued events.")
+			}
+		}, time.Minute)
 	}
 	interrupts.OnInterrupt(func() {
 		server.GracefulShutdown()
@@ -268,6 +310,8 @@ func main() {
 	hookMux.Handle(o.webhookPath, server)
 	// Serve plugin help information from /plugin-help.
 	hookMux.Handle("/plugin-help", pluginhelp.NewHelpAgent(pluginAgent, githubClient))
+	// Serve stats about how plugins handle events from /plugin-stats.
+	hookMux.HandleFunc("/plugin-stats", server.ServePluginStats)
 
 	httpServer := &http.Server{Addr: ":" + strconv.Itoa(o.port), Handler: hookMux}
 
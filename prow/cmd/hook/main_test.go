@@ -86,6 +86,30 @@ func Test_gatherOptions(t *testing.T) {
 				o.webhookPath = "/random/hook"
 			},
 		},
+		{
+			name: "explicitly set --event-queue-dir",
+			args: map[string]string{
+				"--event-queue-dir": "/var/lib/hook",
+			},
+			expected: func(o *options) {
+				o.eventQueueDir = "/var/lib/hook"
+			},
+		},
+		{
+			name: "--event-queue-dir and --event-queue-path are mutually exclusive",
+			args: map[string]string{
+				"--event-queue-dir":  "/var/lib/hook",
+				"--event-queue-path": "gs://bucket/hook",
+			},
+			err: true,
+		},
+		{
+			name: "--event-queue-replay-after must be positive",
+			args: map[string]string{
+				"--event-queue-replay-after": "0s",
+			},
+			err: true,
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -107,6 +131,8 @@ func Test_gatherOptions(t *testing.T) {
 				gracePeriod:            180 * time.Second,
 				webhookSecretFile:      "/etc/webhook/hmac",
 				instrumentationOptions: flagutil.DefaultInstrumentationOptions(),
+				eventQueueRetention:    24 * time.Hour,
+				eventQueueReplay:       15 * time.Minute,
 			}
 			expectedfs := flag.NewFlagSet("fake-flags", flag.PanicOnError)
 			expected.github.AddFlags(expectedfs)
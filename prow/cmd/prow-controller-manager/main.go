@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	uberzap "go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/labels"
@@ -42,11 +43,16 @@ import (
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/plank"
+	"k8s.io/test-infra/prow/stale"
 
 	_ "k8s.io/test-infra/prow/version"
 )
 
-var allControllers = sets.NewString(plank.ControllerName)
+var allControllers = sets.NewString(plank.ControllerName, stale.ControllerName)
+
+// defaultControllers are enabled unless --enable-controller is passed, the
+// stale controller has to be enabled explicitly as it needs GitHub credentials
+var defaultControllers = sets.NewString(plank.ControllerName)
 
 type options struct {
 	totURL string
@@ -66,11 +72,11 @@ type options struct {
 
 func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	var o options
-	o.enabledControllers = prowflagutil.NewStrings(allControllers.List()...)
+	o.enabledControllers = prowflagutil.NewStrings(defaultControllers.List()...)
 	fs.StringVar(&o.totURL, "tot-url", "", "Tot URL")
 
 	fs.StringVar(&o.selector, "label-selector", labels.Everything().String(), "Label selector to be applied in prowjobs. See https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors for constructing a label selector.")
-	fs.Var(&o.enabledControllers, "enable-controller", fmt.Sprintf("Controllers to enable. Can be passed multiple times. Defaults to all controllers (%v)", allControllers.List()))
+	fs.Var(&o.enabledControllers, "enable-controller", fmt.Sprintf("Controllers to enable. Can be passed multiple times. Available controllers are %v, defaults to %v", allControllers.List(), defaultControllers.List()))
 
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether or not to make mutating API calls to GitHub.")
 	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.instrumentationOptions, &o.config, &o.storage} {
@@ -82,7 +88,8 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 }
 
 func (o *options) Validate() error {
-	o.github.AllowAnonymous = true
+	// only the stale controller needs to authenticate against GitHub
+	o.github.AllowAnonymous = !sets.NewString(o.enabledControllers.Strings()...).Has(stale.ControllerName)
 
 	var errs []error
 	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.instrumentationOptions, &o.config, &o.storage} {
@@ -173,7 +180,7 @@ func main() {
 		}
 	}
 
-	opener, err := io.NewOpener(context.Background(), o.storage.GCSCredentialsFile, o.storage.S3CredentialsFile)
+	opener, err := io.NewOpenerWithOptions(context.Background(), o.storage.OpenerOptions())
 	if err != nil {
 		logrus.WithError(err).Fatal("Error creating opener")
 	}
@@ -197,6 +204,17 @@ func main() {
 		if err := plank.Add(mgr, buildManagers, knownClusters, cfg, opener, o.totURL, o.selector); err != nil {
 			logrus.WithError(err).Fatal("Failed to add plank to manager")
 		}
+		metrics.RegisterFairShareMetrics(prometheus.DefaultRegisterer)
+	}
+
+	if enabledControllersSet.Has(stale.ControllerName) {
+		githubClient, err := o.github.GitHubClient(o.dryRun)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting GitHub client.")
+		}
+		if err := stale.Add(mgr, cfg, githubClient); err != nil {
+			logrus.WithError(err).Fatal("Failed to add stale controller to manager")
+		}
 	}
 
 	// Expose prometheus metrics
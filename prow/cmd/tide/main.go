@@ -18,7 +18,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
@@ -29,19 +31,36 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"k8s.io/test-infra/pkg/flagutil"
+	"k8s.io/test-infra/prow/config"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
+	pluginsflagutil "k8s.io/test-infra/prow/flagutil/plugins"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/interrupts"
+	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/io/providers"
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
+	"k8s.io/test-infra/prow/plugins"
 	"k8s.io/test-infra/prow/tide"
 )
 
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+)
+
 type options struct {
 	port int
 
 	config configflagutil.ConfigOptions
+	// pluginsConfig is optional, merge policies use its OWNERS file
+	// configuration if it is set.
+	pluginsConfig pluginsflagutil.PluginOptions
+
+	// provider is the source code provider whose PRs are merged, github or
+	// gitlab.
+	provider string
 
 	syncThrottle   int
 	statusThrottle int
@@ -50,6 +69,7 @@ type options struct {
 	runOnce                bool
 	kubernetes             prowflagutil.KubernetesOptions
 	github                 prowflagutil.GitHubOptions
+	gitlab                 prowflagutil.GitLabOptions
 	storage                prowflagutil.StorageClientOptions
 	instrumentationOptions prowflagutil.InstrumentationOptions
 
@@ -60,6 +80,10 @@ type options struct {
 	// a) the gcs credentials can write to this bucket
 	// b) the default acls do not expose any private info
 	historyURI string
+	// historyArchiveURI where Tide should append all of its action history in
+	// daily partitions, e.g. gs://path/to/dir. The archive can be queried
+	// through the /history endpoint.
+	historyArchiveURI string
 
 	// statusURI where Tide store status update state.
 	// Can be a /local/path, gs://path/to/object or s3://path/to/object.
@@ -70,11 +94,25 @@ type options struct {
 }
 
 func (o *options) Validate() error {
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.storage, &o.config} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github, &o.gitlab, &o.storage, &o.config, &o.pluginsConfig} {
 		if err := group.Validate(o.dryRun); err != nil {
 			return err
 		}
 	}
+	switch o.provider {
+	case providerGitHub:
+	case providerGitLab:
+		if o.gitlab.Endpoint == "" {
+			return errors.New("--gitlab-endpoint must be set with --provider=gitlab")
+		}
+	default:
+		return fmt.Errorf("--provider must be %s or %s, got %q", providerGitHub, providerGitLab, o.provider)
+	}
+	if o.historyArchiveURI != "" {
+		if _, _, _, err := providers.ParseStoragePath(o.historyArchiveURI); err != nil {
+			return fmt.Errorf("--history-archive-uri must be a gs://, s3://, azblob:// or file:// path: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -83,14 +121,16 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.IntVar(&o.port, "port", 8888, "Port to listen on.")
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether to mutate any real-world state.")
 	fs.BoolVar(&o.runOnce, "run-once", false, "If true, run only once then quit.")
+	fs.StringVar(&o.provider, "provider", providerGitHub, "The source code provider whose PRs are merged, github or gitlab. The gitlab provider merges the merge requests selected by tide.gitlab.queries.")
 	o.github.AddCustomizedFlags(fs, prowflagutil.DisableThrottlerOptions())
-	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.storage, &o.instrumentationOptions, &o.config} {
+	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.gitlab, &o.storage, &o.instrumentationOptions, &o.config, &o.pluginsConfig} {
 		group.AddFlags(fs)
 	}
 	fs.IntVar(&o.syncThrottle, "sync-hourly-tokens", 800, "The maximum number of tokens per hour to be used by the sync controller.")
 	fs.IntVar(&o.statusThrottle, "status-hourly-tokens", 400, "The maximum number of tokens per hour to be used by the status controller.")
 	fs.IntVar(&o.maxRecordsPerPool, "max-records-per-pool", 1000, "The maximum number of history records stored for an individual Tide pool.")
 	fs.StringVar(&o.historyURI, "history-uri", "", "The /local/path,gs://path/to/object or s3://path/to/object to store tide action history. GCS writes will use the default object ACL for the bucket")
+	fs.StringVar(&o.historyArchiveURI, "history-archive-uri", "", "The gs://, s3://, azblob:// or file:// path to a directory where tide appends all of its action history in daily partitions. Unlike --history-uri it is not size limited and can be queried.")
 	fs.StringVar(&o.statusURI, "status-path", "", "The /local/path, gs://path/to/object or s3://path/to/object to store status controller state. GCS writes will use the default object ACL for the bucket.")
 
 	fs.Parse(args)
@@ -120,30 +160,6 @@ func main() {
 	}
 	cfg := configAgent.Config
 
-	githubSync, err := o.github.GitHubClientWithLogFields(o.dryRun, logrus.Fields{"controller": "sync"})
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting GitHub client for sync.")
-	}
-
-	githubStatus, err := o.github.GitHubClientWithLogFields(o.dryRun, logrus.Fields{"controller": "status-update"})
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting GitHub client for status.")
-	}
-
-	// The sync loop should be allowed more tokens than the status loop because
-	// it has to list all PRs in the pool every loop while the status loop only
-	// has to list changed PRs every loop.
-	// The sync loop should have a much lower burst allowance than the status
-	// loop which may need to update many statuses upon restarting Tide after
-	// changing the context format or starting Tide on a new repo.
-	githubSync.Throttle(o.syncThrottle, 3*tokensPerIteration(o.syncThrottle, cfg().Tide.SyncPeriod.Duration))
-	githubStatus.Throttle(o.statusThrottle, o.statusThrottle/2)
-
-	gitClient, err := o.github.GitClient(o.dryRun)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error getting Git client.")
-	}
-
 	kubeCfg, err := o.kubernetes.InfrastructureClusterConfig(o.dryRun)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting kubeconfig.")
@@ -154,21 +170,13 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatal("Error constructing mgr.")
 	}
-	c, err := tide.NewController(
-		githubSync,
-		githubStatus,
-		mgr,
-		cfg,
-		git.ClientFactoryFrom(gitClient),
-		o.maxRecordsPerPool,
-		opener,
-		o.historyURI,
-		o.statusURI,
-		nil,
-		o.github.AppPrivateKeyPath != "",
-	)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error creating Tide controller.")
+
+	var c *tide.Controller
+	var gitClient git.ClientFactory
+	if o.provider == providerGitLab {
+		c, gitClient = gitLabController(o, mgr, cfg, opener)
+	} else {
+		c, gitClient = gitHubController(o, mgr, cfg, opener)
 	}
 	interrupts.Run(func(ctx context.Context) {
 		if err := mgr.Start(ctx); err != nil {
@@ -192,6 +200,7 @@ func main() {
 	controllerMux := http.NewServeMux()
 	controllerMux.Handle("/", c)
 	controllerMux.Handle("/history", c.History())
+	controllerMux.HandleFunc("/what-if", c.ServeWhatIf)
 	server := &http.Server{Addr: ":" + strconv.Itoa(o.port), Handler: controllerMux}
 
 	// Push metrics to the configured prometheus pushgateway endpoint or serve them
@@ -215,6 +224,90 @@ func main() {
 	})
 }
 
+func gitHubController(o options, mgr manager.Manager, cfg config.Getter, opener io.Opener) (*tide.Controller, git.ClientFactory) {
+	var pluginsCfg func() *plugins.Configuration
+	if o.pluginsConfig.PluginConfigPath != "" {
+		pluginAgent, err := o.pluginsConfig.PluginAgent()
+		if err != nil {
+			logrus.WithError(err).Fatal("Error starting plugins agent.")
+		}
+		pluginsCfg = pluginAgent.Config
+	}
+
+	githubSync, err := o.github.GitHubClientWithLogFields(o.dryRun, logrus.Fields{"controller": "sync"})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client for sync.")
+	}
+
+	githubStatus, err := o.github.GitHubClientWithLogFields(o.dryRun, logrus.Fields{"controller": "status-update"})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client for status.")
+	}
+
+	// The sync loop should be allowed more tokens than the status loop because
+	// it has to list all PRs in the pool every loop while the status loop only
+	// has to list changed PRs every loop.
+	// The sync loop should have a much lower burst allowance than the status
+	// loop which may need to update many statuses upon restarting Tide after
+	// changing the context format or starting Tide on a new repo.
+	githubSync.Throttle(o.syncThrottle, 3*tokensPerIteration(o.syncThrottle, cfg().Tide.SyncPeriod.Duration))
+	githubStatus.Throttle(o.statusThrottle, o.statusThrottle/2)
+
+	gitClient, err := o.github.GitClient(o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting Git client.")
+	}
+	gitClientFactory := git.ClientFactoryFrom(gitClient)
+
+	c, err := tide.NewController(
+		githubSync,
+		githubStatus,
+		mgr,
+		cfg,
+		pluginsCfg,
+		gitClientFactory,
+		o.maxRecordsPerPool,
+		opener,
+		o.historyURI,
+		o.historyArchiveURI,
+		o.statusURI,
+		nil,
+		o.github.AppPrivateKeyPath != "",
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Tide controller.")
+	}
+	return c, gitClientFactory
+}
+
+func gitLabController(o options, mgr manager.Manager, cfg config.Getter, opener io.Opener) (*tide.Controller, git.ClientFactory) {
+	gitlabClient, err := o.gitlab.Client(o.dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitLab client.")
+	}
+
+	gitClientFactory, err := o.gitlab.GitClientFactory()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting Git client.")
+	}
+
+	c, err := tide.NewGitLabController(
+		gitlabClient,
+		mgr,
+		cfg,
+		gitClientFactory,
+		o.maxRecordsPerPool,
+		opener,
+		o.historyURI,
+		o.historyArchiveURI,
+		nil,
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Tide controller.")
+	}
+	return c, gitClientFactory
+}
+
 func sync(c *tide.Controller) {
 	if err := c.Sync(); err != nil {
 		logrus.WithError(err).Error("Error syncing.")
@@ -25,6 +25,7 @@ import (
 
 	"k8s.io/test-infra/prow/flagutil"
 	configflagutil "k8s.io/test-infra/prow/flagutil/config"
+	pluginsflagutil "k8s.io/test-infra/prow/flagutil/plugins"
 )
 
 func Test_gatherOptions(t *testing.T) {
@@ -78,6 +79,35 @@ func Test_gatherOptions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "gitlab provider",
+			args: map[string]string{
+				"--provider":          "gitlab",
+				"--gitlab-endpoint":   "https://gitlab.example.com",
+				"--gitlab-token-path": "/etc/gitlab/token",
+			},
+			expected: func(o *options) {
+				o.provider = providerGitLab
+				o.gitlab = flagutil.GitLabOptions{
+					Endpoint:  "https://gitlab.example.com",
+					TokenPath: "/etc/gitlab/token",
+				}
+			},
+		},
+		{
+			name: "gitlab provider requires --gitlab-endpoint",
+			args: map[string]string{
+				"--provider": "gitlab",
+			},
+			err: true,
+		},
+		{
+			name: "unknown provider",
+			args: map[string]string{
+				"--provider": "bitbucket",
+			},
+			err: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -90,6 +120,10 @@ func Test_gatherOptions(t *testing.T) {
 					ConfigPath:                            "yo",
 					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
 				},
+				pluginsConfig: pluginsflagutil.PluginOptions{
+					SupplementalPluginsConfigsFileNameSuffix: "_pluginconfig.yaml",
+				},
+				provider:               providerGitHub,
 				dryRun:                 true,
 				syncThrottle:           800,
 				statusThrottle:         400,
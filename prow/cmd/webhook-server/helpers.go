@@ -440,7 +440,7 @@ func (wa *webhookAgent) fetchClusters(d time.Duration, ctx context.Context, stat
 	ticker := time.NewTicker(d)
 	defer ticker.Stop()
 	cfg := configAgent.Config()
-	opener, err := io.NewOpener(context.Background(), wa.storage.GCSCredentialsFile, wa.storage.S3CredentialsFile)
+	opener, err := io.NewOpenerWithOptions(context.Background(), wa.storage.OpenerOptions())
 	if err != nil {
 		return err
 	}
@@ -131,13 +131,16 @@ type ProwConfig struct {
 	Tide                 Tide                 `json:"tide,omitempty"`
 	Plank                Plank                `json:"plank,omitempty"`
 	Sinker               Sinker               `json:"sinker,omitempty"`
+	Stale                Stale                `json:"stale,omitempty"`
 	Deck                 Deck                 `json:"deck,omitempty"`
 	BranchProtection     BranchProtection     `json:"branch-protection"`
 	Gerrit               Gerrit               `json:"gerrit"`
 	GitHubReporter       GitHubReporter       `json:"github_reporter"`
 	Horologium           Horologium           `json:"horologium"`
 	SlackReporterConfigs SlackReporterConfigs `json:"slack_reporter_configs,omitempty"`
-	InRepoConfig         InRepoConfig         `json:"in_repo_config"`
+	// WebhookReporterConfigs configures the generic webhook reporter of crier.
+	WebhookReporterConfigs WebhookReporterConfigs `json:"webhook_reporter_configs,omitempty"`
+	InRepoConfig           InRepoConfig           `json:"in_repo_config"`
 
 	// TODO: Move this out of the main config.
 	JenkinsOperators []JenkinsOperator `json:"jenkins_operators,omitempty"`
@@ -639,6 +642,99 @@ type Plank struct {
 	// scheduling of jobs using boskos resources. This mechanism is separate from
 	// ProwJob's MaxConcurrency setting.
 	JobQueueConcurrencies map[string]int `json:"job_queue_capacities,omitempty"`
+
+	// FairShare is an optional field used to enable weighted fair-share
+	// scheduling of triggered jobs between tenants, so that a burst of jobs
+	// from one tenant can not starve the jobs of all other tenants.
+	FairShare *FairShare `json:"fair_share,omitempty"`
+}
+
+const (
+	// FairShareTenantKeyTenantID groups ProwJobs into tenants by their ProwJobDefault.TenantID.
+	FairShareTenantKeyTenantID = "tenant_id"
+	// FairShareTenantKeyOrg groups ProwJobs into tenants by the org of their refs.
+	FairShareTenantKeyOrg = "org"
+)
+
+// FairShare configures weighted fair-share scheduling in plank.
+// If Plank's max_concurrency is set, the available slots are handed out to
+// the tenant that has the fewest pending jobs relative to its weight. Quotas
+// are enforced regardless of max_concurrency.
+type FairShare struct {
+	// TenantKey determines what ProwJobs are grouped into a tenant, either
+	// `tenant_id` (the default) or `org`.
+	TenantKey string `json:"tenant_key,omitempty"`
+	// Weights maps a tenant to its relative share of the available slots.
+	// Use "*" as key to set the default, which is 1 otherwise.
+	Weights map[string]int `json:"weights,omitempty"`
+	// Quotas maps a tenant to the maximum number of its jobs that may be
+	// pending at the same time. Use "*" as key to set a default.
+	// Zero or unset means no quota.
+	Quotas map[string]int `json:"quotas,omitempty"`
+	// PriorityClasses maps the name of a priority class to its priority.
+	// Jobs select a class with the `prow.k8s.io/priority-class` label.
+	// Jobs of a tenant with a higher priority are started before older
+	// jobs of the same tenant with a lower priority.
+	PriorityClasses map[string]int `json:"priority_classes,omitempty"`
+}
+
+// TenantFor returns the fair-share tenant of the ProwJob.
+func (fs *FairShare) TenantFor(pj *prowapi.ProwJob) string {
+	if fs.TenantKey == FairShareTenantKeyOrg {
+		refs := pj.Spec.Refs
+		if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+			refs = &pj.Spec.ExtraRefs[0]
+		}
+		if refs != nil {
+			return refs.Org
+		}
+		return DefaultTenantID
+	}
+	if pj.Spec.ProwJobDefault != nil && pj.Spec.ProwJobDefault.TenantID != "" {
+		return pj.Spec.ProwJobDefault.TenantID
+	}
+	return DefaultTenantID
+}
+
+// WeightFor returns the weight of the tenant.
+func (fs *FairShare) WeightFor(tenant string) int {
+	if weight, ok := fs.Weights[tenant]; ok {
+		return weight
+	}
+	if weight, ok := fs.Weights["*"]; ok {
+		return weight
+	}
+	return 1
+}
+
+// QuotaFor returns the quota of the tenant, zero means no quota.
+func (fs *FairShare) QuotaFor(tenant string) int {
+	if quota, ok := fs.Quotas[tenant]; ok {
+		return quota
+	}
+	return fs.Quotas["*"]
+}
+
+// PriorityFor returns the priority of the ProwJob according to its priority class.
+func (fs *FairShare) PriorityFor(pj *prowapi.ProwJob) int {
+	return fs.PriorityClasses[pj.Labels[kube.PriorityClassLabel]]
+}
+
+func (fs *FairShare) validate() error {
+	if fs.TenantKey != "" && fs.TenantKey != FairShareTenantKeyTenantID && fs.TenantKey != FairShareTenantKeyOrg {
+		return fmt.Errorf("tenant_key must be one of %q or %q, got %q", FairShareTenantKeyTenantID, FairShareTenantKeyOrg, fs.TenantKey)
+	}
+	for tenant, weight := range fs.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("weight %d for tenant %q must be a positive number", weight, tenant)
+		}
+	}
+	for tenant, quota := range fs.Quotas {
+		if quota < 0 {
+			return fmt.Errorf("quota %d for tenant %q must be a non-negative number", quota, tenant)
+		}
+	}
+	return nil
 }
 
 type ProwJobDefaultEntry struct {
@@ -955,6 +1051,10 @@ type GitHubReporter struct {
 	// comments is only sent when all jobs from current SHA are finished. Status
 	// contexts will still be written.
 	SummaryCommentRepos []string `json:"summary_comment_repos,omitempty"`
+	// CheckRunRepos is a list of orgs and org/repos for which job results are
+	// reported as GitHub check runs instead of status contexts. Check runs can
+	// only be created when authenticating as a GitHub App.
+	CheckRunRepos []string `json:"check_run_repos,omitempty"`
 }
 
 // Sinker is config for the sinker controller.
@@ -1486,6 +1586,74 @@ func (cfg *SlackReporter) DefaultAndValidate() error {
 	return nil
 }
 
+// WebhookReporter represents the config for the webhook reporter. The endpoints can be
+// overridden on the job via the .reporter_config.webhook.endpoints property.
+type WebhookReporter struct {
+	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
+	// AllowedJobEndpointHosts are the hosts that jobs may send reports to via
+	// `reporter_config.webhook.endpoints`. Job level endpoints with any other
+	// host are ignored.
+	AllowedJobEndpointHosts       []string `json:"allowed_job_endpoint_hosts,omitempty"`
+	prowapi.WebhookReporterConfig `json:",inline"`
+}
+
+// AllowsJobEndpoint returns true if jobs may send reports to the endpoint.
+func (cfg *WebhookReporter) AllowsJobEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	for _, host := range cfg.AllowedJobEndpointHosts {
+		if u.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookReporterConfigs represents the config for the webhook reporter(s).
+// Use `org/repo`, `org` or `*` as key and an `WebhookReporter` struct as value.
+type WebhookReporterConfigs map[string]WebhookReporter
+
+func (cfg WebhookReporterConfigs) GetWebhookReporter(refs *prowapi.Refs) WebhookReporter {
+	if refs == nil {
+		return cfg["*"]
+	}
+
+	if webhook, ok := cfg[fmt.Sprintf("%s/%s", refs.Org, refs.Repo)]; ok {
+		return webhook
+	}
+
+	if webhook, ok := cfg[refs.Org]; ok {
+		return webhook
+	}
+
+	return cfg["*"]
+}
+
+func (cfg *WebhookReporter) DefaultAndValidate() error {
+	if len(cfg.Endpoints) == 0 {
+		return errors.New("endpoints must be set")
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if err := validateWebhookEndpoint(endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWebhookEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("endpoint %q must use http or https", endpoint)
+	}
+	return nil
+}
+
 // Load loads and parses the config at path.
 func Load(prowConfig, jobConfig string, supplementalProwConfigDirs []string, supplementalProwConfigsFileNameSuffix string, additionals ...func(*Config) error) (c *Config, err error) {
 	return loadWithYamlOpts(nil, prowConfig, jobConfig, supplementalProwConfigDirs, supplementalProwConfigsFileNameSuffix, additionals...)
@@ -2045,6 +2213,21 @@ func (c *Config) validateComponentConfig() error {
 		}
 	}
 
+	if c.WebhookReporterConfigs != nil {
+		for k, config := range c.WebhookReporterConfigs {
+			if err := config.DefaultAndValidate(); err != nil {
+				return fmt.Errorf("failed to validate webhookreporter config: %w", err)
+			}
+			c.WebhookReporterConfigs[k] = config
+		}
+	}
+
+	if c.Plank.FairShare != nil {
+		if err := c.Plank.FairShare.validate(); err != nil {
+			return fmt.Errorf("invalid plank.fair_share: %w", err)
+		}
+	}
+
 	if err := c.Deck.FinalizeDefaultRerunAuthConfigs(); err != nil {
 		return err
 	}
@@ -2085,6 +2268,16 @@ func (c Config) validateJobBase(v JobBase, jobType prowapi.ProwJobType) error {
 	if err := validateJobQueueName(v.JobQueueName, validJobQueueNames); err != nil {
 		return err
 	}
+	if err := validatePriorityClass(v.Labels[kube.PriorityClassLabel], c.Plank.FairShare); err != nil {
+		return err
+	}
+	if v.ReporterConfig != nil && v.ReporterConfig.Webhook != nil {
+		for _, endpoint := range v.ReporterConfig.Webhook.Endpoints {
+			if err := validateWebhookEndpoint(endpoint); err != nil {
+				return fmt.Errorf("reporter_config.webhook: %w", err)
+			}
+		}
+	}
 	if v.Spec == nil || len(v.Spec.Containers) == 0 {
 		return nil // jenkins jobs have no spec.
 	}
@@ -2470,6 +2663,10 @@ func parseProwConfig(c *Config) error {
 		c.Sinker.TerminatedPodTTL = &metav1.Duration{Duration: c.Sinker.MaxPodAge.Duration}
 	}
 
+	if err := c.Stale.defaultAndValidate(); err != nil {
+		return err
+	}
+
 	if c.Tide.SyncPeriod == nil {
 		c.Tide.SyncPeriod = &metav1.Duration{Duration: time.Minute}
 	}
@@ -2553,6 +2750,24 @@ func parseProwConfig(c *Config) error {
 		}
 	}
 
+	for i, freeze := range c.Tide.Freezes {
+		if err := freeze.Validate(); err != nil {
+			return fmt.Errorf("tide freeze (index %d) is invalid: %w", i, err)
+		}
+	}
+
+	for i, policy := range c.Tide.MergePolicies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("tide merge policy (index %d) is invalid: %w", i, err)
+		}
+	}
+
+	if c.Tide.GitLab != nil {
+		if err := c.Tide.GitLab.Validate(); err != nil {
+			return fmt.Errorf("tide gitlab config is invalid: %w", err)
+		}
+	}
+
 	if c.ProwJobNamespace == "" {
 		c.ProwJobNamespace = "default"
 	}
@@ -2632,6 +2847,19 @@ func validateJobQueueName(name string, validNames sets.String) error {
 	return nil
 }
 
+func validatePriorityClass(name string, fairShare *FairShare) error {
+	if name == "" {
+		return nil
+	}
+	if fairShare == nil {
+		return fmt.Errorf("priority class %s is set but plank.fair_share is not configured", name)
+	}
+	if _, ok := fairShare.PriorityClasses[name]; !ok {
+		return fmt.Errorf("invalid priority class %s", name)
+	}
+	return nil
+}
+
 func validateAgent(v JobBase, podNamespace string) error {
 	k := string(prowapi.KubernetesAgent)
 	j := string(prowapi.JenkinsAgent)
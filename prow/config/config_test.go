@@ -1293,7 +1293,7 @@ func TestValidatePodSpec(t *testing.T) {
 			name: "reject reserved mount name",
 			spec: func(s *v1.PodSpec) {
 				s.Containers[0].VolumeMounts = append(s.Containers[0].VolumeMounts, v1.VolumeMount{
-					Name:      decorate.VolumeMounts(nil).List()[0],
+					Name:      decorate.VolumeMountsOnTestContainer().List()[0],
 					MountPath: "/whatever",
 				})
 			},
@@ -1843,6 +1843,18 @@ func TestValidateJobBase(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "webhook reporter endpoint with unsupported scheme",
+			base: JobBase{
+				Name:      "name",
+				Agent:     ka,
+				Spec:      &goodSpec,
+				Namespace: &cfg.PodNamespace,
+				ReporterConfig: &prowjobv1.ReporterConfig{
+					Webhook: &prowjobv1.WebhookReporterConfig{Endpoints: []string{"file:///etc/passwd"}},
+				},
+			},
+		},
 		{
 			name: "invalid concurrency",
 			base: JobBase{
@@ -8990,3 +9002,90 @@ func TestSplitRepoName(t *testing.T) {
 		})
 	}
 }
+
+func TestFairShareTenantFor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tenantKey string
+		pj        *prowapi.ProwJob
+		expected  string
+	}{
+		{
+			name:     "tenant id is used by default",
+			pj:       &prowapi.ProwJob{Spec: prowapi.ProwJobSpec{ProwJobDefault: &prowapi.ProwJobDefault{TenantID: "tenant"}}},
+			expected: "tenant",
+		},
+		{
+			name:     "missing tenant id uses the default tenant id",
+			pj:       &prowapi.ProwJob{},
+			expected: DefaultTenantID,
+		},
+		{
+			name:      "org of refs",
+			tenantKey: FairShareTenantKeyOrg,
+			pj:        &prowapi.ProwJob{Spec: prowapi.ProwJobSpec{Refs: &prowapi.Refs{Org: "org"}}},
+			expected:  "org",
+		},
+		{
+			name:      "org of extra refs",
+			tenantKey: FairShareTenantKeyOrg,
+			pj:        &prowapi.ProwJob{Spec: prowapi.ProwJobSpec{ExtraRefs: []prowapi.Refs{{Org: "extra-org"}}}},
+			expected:  "extra-org",
+		},
+		{
+			name:      "no refs uses the default tenant id",
+			tenantKey: FairShareTenantKeyOrg,
+			pj:        &prowapi.ProwJob{},
+			expected:  DefaultTenantID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := &FairShare{TenantKey: tc.tenantKey}
+			if actual := fs.TenantFor(tc.pj); actual != tc.expected {
+				t.Errorf("expected tenant %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFairShareValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fs        FairShare
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			fs: FairShare{
+				TenantKey: FairShareTenantKeyOrg,
+				Weights:   map[string]int{"*": 1, "org": 5},
+				Quotas:    map[string]int{"org": 0},
+			},
+		},
+		{
+			name:      "unknown tenant key",
+			fs:        FairShare{TenantKey: "repo"},
+			expectErr: true,
+		},
+		{
+			name:      "zero weight",
+			fs:        FairShare{Weights: map[string]int{"org": 0}},
+			expectErr: true,
+		},
+		{
+			name:      "negative quota",
+			fs:        FairShare{Quotas: map[string]int{"org": -1}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.fs.validate(); (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %t, got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -532,6 +532,14 @@ type UtilityConfig struct {
 	// SkipFetchHead tells prow to avoid a git fetch <remote> call.
 	// The git fetch <remote> <BaseRef> call occurs regardless.
 	SkipFetchHead bool `json:"skip_fetch_head,omitempty"`
+	// SparseCheckout are the patterns of the paths to check out,
+	// in the format of .gitignore files. All paths are checked
+	// out if unset.
+	SparseCheckout []string `json:"sparse_checkout,omitempty"`
+	// PartialClone determines if blobs are left out of the clone
+	// and only fetched when they are needed, e.g. on checkout.
+	// Blobs are fetched without credentials after cloning.
+	PartialClone bool `json:"partial_clone,omitempty"`
 
 	// ExtraRefs are auxiliary repositories that
 	// need to be cloned, determined from config
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StaleKindIssue limits a stale rule to issues.
+	StaleKindIssue = "issue"
+	// StaleKindPR limits a stale rule to pull requests.
+	StaleKindPR = "pr"
+
+	defaultStaleComment = `This {{ .Kind }} has not been updated in {{ .StaleAfter }} and is now stale.
+{{- if .RottenAfter }} It will be marked as rotten after {{ .RottenAfter }} of further inactivity.{{ end }}
+
+You can mark it as fresh with ` + "`/remove-lifecycle stale`" + ` or exempt it with ` + "`/lifecycle frozen`" + `.`
+	defaultRottenComment = `This {{ .Kind }} has not been updated in {{ .RottenAfter }} since it became stale and is now rotten.
+{{- if .CloseAfter }} It will be closed after {{ .CloseAfter }} of further inactivity.{{ end }}
+
+You can mark it as fresh with ` + "`/remove-lifecycle rotten`" + ` or exempt it with ` + "`/lifecycle frozen`" + `.`
+	defaultCloseComment = `This {{ .Kind }} has not been updated in {{ .CloseAfter }} since it became rotten and is now closed.
+
+You can reopen it with ` + "`/reopen`" + `.`
+)
+
+// Stale configures the stale controller of prow-controller-manager, which
+// marks inactive issues and pull requests as stale, then as rotten, and
+// eventually closes them.
+type Stale struct {
+	// ResyncPeriod is how often the rules are applied.
+	// Defaults to one hour.
+	ResyncPeriod *metav1.Duration `json:"resync_period,omitempty"`
+	// Rules select the issues and pull requests to age. An issue or pull
+	// request should be matched by at most one rule.
+	Rules []StaleRule `json:"rules,omitempty"`
+}
+
+// StaleRule ages the issues and pull requests of some repos. Each stage is
+// reached after a period of inactivity in the previous one, so any update
+// (including the lifecycle label and comment of the previous stage) restarts
+// the period.
+type StaleRule struct {
+	// Orgs and Repos (in "org/repo" form) the rule applies to.
+	Orgs  []string `json:"orgs,omitempty"`
+	Repos []string `json:"repos,omitempty"`
+	// ExcludedRepos (in "org/repo" form) are skipped even if their org is
+	// listed in Orgs.
+	ExcludedRepos []string `json:"excluded_repos,omitempty"`
+	// Kind limits the rule to issues ("issue") or pull requests ("pr").
+	// Defaults to both.
+	Kind string `json:"kind,omitempty"`
+	// ExemptLabels are labels that exempt issues and pull requests from the
+	// rule. The lifecycle/frozen label always exempts them.
+	ExemptLabels []string `json:"exempt_labels,omitempty"`
+
+	// StaleAfter is the period of inactivity after which the lifecycle/stale
+	// label is added. Defaults to 90 days.
+	StaleAfter *metav1.Duration `json:"stale_after,omitempty"`
+	// RottenAfter is the period of inactivity after which the lifecycle/stale
+	// label is replaced with lifecycle/rotten. Defaults to 30 days.
+	RottenAfter *metav1.Duration `json:"rotten_after,omitempty"`
+	// CloseAfter is the period of inactivity after which rotten issues and
+	// pull requests are closed. Defaults to 30 days.
+	CloseAfter *metav1.Duration `json:"close_after,omitempty"`
+	// DisableClose keeps rotten issues and pull requests open.
+	DisableClose bool `json:"disable_close,omitempty"`
+
+	// StaleComment, RottenComment and CloseComment are the Go templates of
+	// the comments explaining each stage. They are executed with the Kind
+	// ("issue" or "pull request") and the StaleAfter, RottenAfter and
+	// CloseAfter periods (empty if closing is disabled). Defaults explain the
+	// stage and how to leave it.
+	StaleComment  string `json:"stale_comment,omitempty"`
+	RottenComment string `json:"rotten_comment,omitempty"`
+	CloseComment  string `json:"close_comment,omitempty"`
+
+	// DryRun only reports which issues and pull requests the rule would
+	// change, without changing them.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+func (s *Stale) defaultAndValidate() error {
+	if s.ResyncPeriod == nil {
+		s.ResyncPeriod = &metav1.Duration{Duration: time.Hour}
+	}
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		rule.defaults()
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("stale rule (index %d) is invalid: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *StaleRule) defaults() {
+	if r.StaleAfter == nil {
+		r.StaleAfter = &metav1.Duration{Duration: 90 * 24 * time.Hour}
+	}
+	if r.RottenAfter == nil {
+		r.RottenAfter = &metav1.Duration{Duration: 30 * 24 * time.Hour}
+	}
+	if r.CloseAfter == nil {
+		r.CloseAfter = &metav1.Duration{Duration: 30 * 24 * time.Hour}
+	}
+	if r.StaleComment == "" {
+		r.StaleComment = defaultStaleComment
+	}
+	if r.RottenComment == "" {
+		r.RottenComment = defaultRottenComment
+	}
+	if r.CloseComment == "" {
+		r.CloseComment = defaultCloseComment
+	}
+}
+
+// Validate returns an error if the rule does not apply to any repo, or its
+// periods or comment templates are invalid.
+func (r *StaleRule) Validate() error {
+	if len(r.Orgs) == 0 && len(r.Repos) == 0 {
+		return errors.New("at least one org or repo must be set")
+	}
+	for i, org := range r.Orgs {
+		if org == "" || strings.Contains(org, "/") {
+			return fmt.Errorf("orgs[%d]: %q is not a valid org", i, org)
+		}
+	}
+	for field, repos := range map[string][]string{"repos": r.Repos, "excluded_repos": r.ExcludedRepos} {
+		for i, repo := range repos {
+			if org, name, ok := splitOrgRepoString(repo); !ok || org == "" || name == "" {
+				return fmt.Errorf("%s[%d]: %q is not of the form \"org/repo\"", field, i, repo)
+			}
+		}
+	}
+	if r.Kind != "" && r.Kind != StaleKindIssue && r.Kind != StaleKindPR {
+		return fmt.Errorf("kind must be %q or %q, got %q", StaleKindIssue, StaleKindPR, r.Kind)
+	}
+	for field, period := range map[string]*metav1.Duration{"stale_after": r.StaleAfter, "rotten_after": r.RottenAfter, "close_after": r.CloseAfter} {
+		if period != nil && period.Duration <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", field, period.Duration)
+		}
+	}
+	for field, comment := range map[string]string{"stale_comment": r.StaleComment, "rotten_comment": r.RottenComment, "close_comment": r.CloseComment} {
+		if _, err := template.New(field).Parse(comment); err != nil {
+			return fmt.Errorf("%s is not a valid template: %w", field, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStaleDefaultAndValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		rule        StaleRule
+		expectError bool
+	}{
+		{
+			name: "defaulted rule",
+			rule: StaleRule{Orgs: []string{"kuber"}},
+		},
+		{
+			name:        "rule without orgs or repos is invalid",
+			rule:        StaleRule{},
+			expectError: true,
+		},
+		{
+			name: "invalid excluded repo",
+			rule: StaleRule{
+				Orgs:          []string{"kuber"},
+				ExcludedRepos: []string{"netes"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid kind",
+			rule: StaleRule{
+				Repos: []string{"kuber/netes"},
+				Kind:  "discussion",
+			},
+			expectError: true,
+		},
+		{
+			name: "negative period",
+			rule: StaleRule{
+				Repos:      []string{"kuber/netes"},
+				StaleAfter: &metav1.Duration{Duration: -time.Hour},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid comment template",
+			rule: StaleRule{
+				Repos:        []string{"kuber/netes"},
+				StaleComment: "{{ .Kind ",
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stale := Stale{Rules: []StaleRule{tc.rule}}
+			err := stale.defaultAndValidate()
+			if err != nil && !tc.expectError {
+				t.Errorf("Unexpected error: %v.", err)
+			} else if err == nil && tc.expectError {
+				t.Error("Expected a validation error, but didn't get one.")
+			}
+			if err != nil {
+				return
+			}
+			rule := stale.Rules[0]
+			if stale.ResyncPeriod.Duration != time.Hour || rule.StaleAfter.Duration != 90*24*time.Hour || rule.CloseComment == "" {
+				t.Errorf("Expected defaults to be set, got %+v.", stale)
+			}
+		})
+	}
+}
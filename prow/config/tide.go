@@ -19,9 +19,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -82,6 +84,7 @@ type TidePriority struct {
 // Tide is config for the tide pool.
 type Tide struct {
 	Gerrit *TideGerritConfig `json:"gerrit,omitempty"`
+	GitLab *TideGitLabConfig `json:"gitlab,omitempty"`
 	// SyncPeriod specifies how often Tide will sync jobs with GitHub. Defaults to 1m.
 	SyncPeriod *metav1.Duration `json:"sync_period,omitempty"`
 	// MaxGoroutines is the maximum number of goroutines spawned inside the
@@ -100,10 +103,275 @@ type Tide struct {
 	// starting a new one requires to start new instances of all tests.
 	// Use '*' as key to set this globally. Defaults to true.
 	PrioritizeExistingBatchesMap map[string]bool `json:"prioritize_existing_batches,omitempty"`
+	// Freezes are windows during which Tide does not merge PRs into the
+	// matching branches unless they carry an exemption label.
+	Freezes []TideFreeze `json:"freezes,omitempty"`
+	// MergePolicies are rules PRs have to satisfy in addition to matching a
+	// query in order to be merged.
+	MergePolicies []TideMergePolicy `json:"merge_policies,omitempty"`
 
 	TideGitHubConfig `json:",inline"`
 }
 
+// TideFreeze is a test or code freeze of some branches. While it is active,
+// Tide only merges PRs that carry one of the exemption labels.
+type TideFreeze struct {
+	// Name of the freeze, e.g. "Code Freeze for v1.25". It is included in
+	// the Tide status of frozen PRs.
+	Name string `json:"name"`
+	// Orgs and Repos (in "org/repo" form) the freeze applies to.
+	Orgs  []string `json:"orgs,omitempty"`
+	Repos []string `json:"repos,omitempty"`
+	// Branches the freeze applies to, all branches if empty.
+	Branches []string `json:"branches,omitempty"`
+	// Start of the freeze.
+	Start time.Time `json:"start"`
+	// End of the freeze, the freeze lasts until it is removed if unset.
+	End *time.Time `json:"end,omitempty"`
+	// ExemptionLabels are labels that allow PRs to merge during the freeze.
+	ExemptionLabels []string `json:"exemption_labels,omitempty"`
+	// Message explains the freeze to authors of PRs opened during it,
+	// e.g. by linking the release schedule.
+	Message string `json:"message,omitempty"`
+}
+
+// AppliesTo returns true if the freeze covers the branch of the repo.
+func (f *TideFreeze) AppliesTo(org, repo, branch string) bool {
+	if !sets.NewString(f.Orgs...).Has(org) && !sets.NewString(f.Repos...).Has(org+"/"+repo) {
+		return false
+	}
+	return len(f.Branches) == 0 || sets.NewString(f.Branches...).Has(branch)
+}
+
+// ActiveAt returns true if the freeze is in effect at the given time.
+func (f *TideFreeze) ActiveAt(t time.Time) bool {
+	if t.Before(f.Start) {
+		return false
+	}
+	return f.End == nil || t.Before(*f.End)
+}
+
+// Exempts returns true if any of the labels allows merging during the freeze.
+func (f *TideFreeze) Exempts(labels []string) bool {
+	return sets.NewString(f.ExemptionLabels...).HasAny(labels...)
+}
+
+// Validate returns an error if the freeze does not apply to any repo or ends
+// before it starts.
+func (f *TideFreeze) Validate() error {
+	if f.Name == "" {
+		return errors.New("name must be set")
+	}
+	if len(f.Orgs) == 0 && len(f.Repos) == 0 {
+		return errors.New("at least one org or repo must be set")
+	}
+	for i, org := range f.Orgs {
+		if org == "" || strings.Contains(org, "/") {
+			return fmt.Errorf("orgs[%d]: %q is not a valid org", i, org)
+		}
+	}
+	for i, repo := range f.Repos {
+		if org, name, ok := splitOrgRepoString(repo); !ok || org == "" || name == "" {
+			return fmt.Errorf("repos[%d]: %q is not of the form \"org/repo\"", i, repo)
+		}
+	}
+	if f.End != nil && !f.End.After(f.Start) {
+		return fmt.Errorf("end %s is not after start %s", f.End, f.Start)
+	}
+	return nil
+}
+
+// ActiveFreezes returns the freezes of the branch of the repo that are in
+// effect at the given time.
+func (t *Tide) ActiveFreezes(org, repo, branch string, now time.Time) []TideFreeze {
+	var active []TideFreeze
+	for _, freeze := range t.Freezes {
+		if freeze.AppliesTo(org, repo, branch) && freeze.ActiveAt(now) {
+			active = append(active, freeze)
+		}
+	}
+	return active
+}
+
+// TideMergePolicy requires PRs of some branches that match its conditions to
+// satisfy additional requirements, e.g. approvals from several companies for
+// changes of an API.
+type TideMergePolicy struct {
+	// Name of the policy. It is included in the Tide status of PRs that do
+	// not satisfy the policy.
+	Name string `json:"name"`
+	// Orgs and Repos (in "org/repo" form) the policy applies to.
+	Orgs  []string `json:"orgs,omitempty"`
+	Repos []string `json:"repos,omitempty"`
+	// Branches the policy applies to, all branches if empty.
+	Branches []string `json:"branches,omitempty"`
+	// If limits the policy to PRs matching all of its conditions. The policy
+	// applies to all PRs if it is empty.
+	If TidePolicyConditions `json:"if,omitempty"`
+	// Require lists what PRs the policy applies to need in order to merge.
+	Require TidePolicyRequirements `json:"require"`
+}
+
+// TidePolicyConditions select the PRs a merge policy applies to.
+type TidePolicyConditions struct {
+	// ChangedFiles is a regular expression, PRs changing a file whose path
+	// matches it are selected.
+	ChangedFiles string `json:"changed_files,omitempty"`
+	// Labels that PRs must all have to be selected.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// TidePolicyRequirements are the requirements of a merge policy.
+type TidePolicyRequirements struct {
+	// Labels that PRs must have.
+	Labels []string `json:"labels,omitempty"`
+	// MissingLabels that PRs must not have.
+	MissingLabels []string `json:"missing_labels,omitempty"`
+	// Approvals is the minimum number of reviewers whose latest review
+	// approves the PR.
+	Approvals int `json:"approvals,omitempty"`
+	// Approvers limits the approvals that are counted to these GitHub logins.
+	Approvers []string `json:"approvers,omitempty"`
+	// OwnersApprovers limits the approvals that are counted to approvers
+	// listed in the OWNERS files of the changed files. If the changed_files
+	// condition is set, only the files matching it are considered.
+	OwnersApprovers bool `json:"owners_approvers,omitempty"`
+	// Groups maps names of groups of reviewers, e.g. companies, to their
+	// GitHub logins.
+	Groups map[string][]string `json:"groups,omitempty"`
+	// DistinctGroups is the minimum number of groups that the counted
+	// approvals have to come from.
+	DistinctGroups int `json:"distinct_groups,omitempty"`
+	// Expression is a Go template for requirements that the other fields can
+	// not express. It is evaluated against the PR, which provides .Org, .Repo,
+	// .Branch, .Number, .Author, .Labels, .ChangedFiles, .Approvers and
+	// .OwnersApprovers, and can use the functions has, matching and intersect.
+	// The PR does not satisfy the policy if the expression renders any text,
+	// which is included in the Tide status, e.g.
+	// '{{if not (has .Approvers "sig-security-lead")}}an approval of sig-security-lead{{end}}'.
+	Expression string `json:"expression,omitempty"`
+}
+
+// TidePolicyExpressionFuncs are the functions that expressions of merge
+// policies can use in addition to the builtin functions of Go templates.
+var TidePolicyExpressionFuncs = template.FuncMap{
+	// has returns true if the list contains the item.
+	"has": func(list []string, item string) bool {
+		return sets.NewString(list...).Has(item)
+	},
+	// matching returns the items of the list that match the regular
+	// expression.
+	"matching": func(pattern string, list []string) ([]string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var matching []string
+		for _, item := range list {
+			if re.MatchString(item) {
+				matching = append(matching, item)
+			}
+		}
+		return matching, nil
+	},
+	// intersect returns the items that both lists contain.
+	"intersect": func(a, b []string) []string {
+		return sets.NewString(a...).Intersection(sets.NewString(b...)).List()
+	},
+}
+
+// ParseExpression parses the expression of the requirements, it returns nil
+// if the expression is not set.
+func (r *TidePolicyRequirements) ParseExpression() (*template.Template, error) {
+	if r.Expression == "" {
+		return nil, nil
+	}
+	return template.New("expression").Funcs(TidePolicyExpressionFuncs).Parse(r.Expression)
+}
+
+// AppliesTo returns true if the policy covers the branch of the repo. The
+// conditions of the policy still need to be checked for each PR.
+func (p *TideMergePolicy) AppliesTo(org, repo, branch string) bool {
+	if !sets.NewString(p.Orgs...).Has(org) && !sets.NewString(p.Repos...).Has(org+"/"+repo) {
+		return false
+	}
+	return len(p.Branches) == 0 || sets.NewString(p.Branches...).Has(branch)
+}
+
+// Validate returns an error if the policy does not apply to any repo or its
+// requirements can not be satisfied.
+func (p *TideMergePolicy) Validate() error {
+	if p.Name == "" {
+		return errors.New("name must be set")
+	}
+	if len(p.Orgs) == 0 && len(p.Repos) == 0 {
+		return errors.New("at least one org or repo must be set")
+	}
+	for i, org := range p.Orgs {
+		if org == "" || strings.Contains(org, "/") {
+			return fmt.Errorf("orgs[%d]: %q is not a valid org", i, org)
+		}
+	}
+	for i, repo := range p.Repos {
+		if org, name, ok := splitOrgRepoString(repo); !ok || org == "" || name == "" {
+			return fmt.Errorf("repos[%d]: %q is not of the form \"org/repo\"", i, repo)
+		}
+	}
+	if _, err := regexp.Compile(p.If.ChangedFiles); err != nil {
+		return fmt.Errorf("if.changed_files: %w", err)
+	}
+
+	require := p.Require
+	if labels := sets.NewString(require.Labels...).Intersection(sets.NewString(require.MissingLabels...)); labels.Len() > 0 {
+		return fmt.Errorf("require: labels %v are both required and forbidden", labels.List())
+	}
+	if require.Approvals < 0 || require.DistinctGroups < 0 {
+		return errors.New("require: approvals and distinct_groups must not be negative")
+	}
+	if require.DistinctGroups > len(require.Groups) {
+		return fmt.Errorf("require: approvals from %d distinct groups are required but only %d groups are defined", require.DistinctGroups, len(require.Groups))
+	}
+	if require.DistinctGroups > require.Approvals {
+		return fmt.Errorf("require: approvals from %d distinct groups need at least as many approvals, got %d", require.DistinctGroups, require.Approvals)
+	}
+	if require.Approvals == 0 && (len(require.Approvers) > 0 || require.OwnersApprovers) {
+		return errors.New("require: approvers and owners_approvers need approvals to be set")
+	}
+	if _, err := require.ParseExpression(); err != nil {
+		return fmt.Errorf("require.expression: %w", err)
+	}
+	return nil
+}
+
+// MatchesFiles returns true if any of the files matches the changed_files
+// condition, or if the condition is not set.
+func (c *TidePolicyConditions) MatchesFiles(files []string) (bool, error) {
+	if c.ChangedFiles == "" {
+		return true, nil
+	}
+	re, err := regexp.Compile(c.ChangedFiles)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range files {
+		if re.MatchString(file) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MergePoliciesFor returns the merge policies of the branch of the repo.
+func (t *Tide) MergePoliciesFor(org, repo, branch string) []TideMergePolicy {
+	var policies []TideMergePolicy
+	for _, policy := range t.MergePolicies {
+		if policy.AppliesTo(org, repo, branch) {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
 // TideGitHubConfig is the tide config for GitHub.
 type TideGitHubConfig struct {
 	// StatusUpdatePeriod specifies how often Tide will update GitHub status contexts.
@@ -194,12 +462,48 @@ type TideGerritConfig struct {
 	RateLimit int `json:"ratelimit,omitempty"`
 }
 
+// TideGitLabConfig contains all GitLab related configurations for tide.
+type TideGitLabConfig struct {
+	Queries []TideGitLabQuery `json:"queries"`
+}
+
+// TideGitLabQuery selects the open merge requests of GitLab projects that
+// have all of the labels and none of the missing labels. Draft merge requests
+// are never selected.
+type TideGitLabQuery struct {
+	// Projects are the full paths of the projects, e.g. "group/project".
+	Projects      []string `json:"projects"`
+	Labels        []string `json:"labels,omitempty"`
+	MissingLabels []string `json:"missingLabels,omitempty"`
+}
+
+// Validate returns an error if a query does not select any project or
+// selects merge requests with conflicting labels.
+func (c *TideGitLabConfig) Validate() error {
+	for i, q := range c.Queries {
+		if len(q.Projects) == 0 {
+			return fmt.Errorf("queries[%d]: at least one project must be set", i)
+		}
+		for _, project := range q.Projects {
+			if !strings.Contains(strings.Trim(project, "/"), "/") {
+				return fmt.Errorf("queries[%d]: %q is not the full path of a project", i, project)
+			}
+		}
+		if labels := sets.NewString(q.Labels...).Intersection(sets.NewString(q.MissingLabels...)); labels.Len() > 0 {
+			return fmt.Errorf("queries[%d]: labels %v are both required and missing", i, labels.List())
+		}
+	}
+	return nil
+}
+
 func (t *Tide) mergeFrom(additional *Tide) error {
 
 	// Duplicate queries are pointless but not harmful, we
 	// have code to de-duplicate them down the line to not
 	// increase token usage needlessly.
 	t.Queries = append(t.Queries, additional.Queries...)
+	t.Freezes = append(t.Freezes, additional.Freezes...)
+	t.MergePolicies = append(t.MergePolicies, additional.MergePolicies...)
 
 	if t.MergeType == nil {
 		t.MergeType = additional.MergeType
@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -921,6 +922,369 @@ func TestTideQuery_Validate(t *testing.T) {
 	}
 }
 
+func TestTideFreeze_Validate(t *testing.T) {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(14 * 24 * time.Hour)
+	testCases := []struct {
+		name        string
+		freeze      TideFreeze
+		expectError bool
+	}{
+		{
+			name: "good freeze",
+			freeze: TideFreeze{
+				Name:            "Code Freeze",
+				Orgs:            []string{"kuber"},
+				Repos:           []string{"foo/bar"},
+				Branches:        []string{"master"},
+				Start:           start,
+				End:             &end,
+				ExemptionLabels: []string{"freeze-exempt"},
+			},
+		},
+		{
+			name: "freeze without end is valid",
+			freeze: TideFreeze{
+				Name:  "Code Freeze",
+				Repos: []string{"foo/bar"},
+				Start: start,
+			},
+		},
+		{
+			name: "freeze without name is invalid",
+			freeze: TideFreeze{
+				Repos: []string{"foo/bar"},
+				Start: start,
+			},
+			expectError: true,
+		},
+		{
+			name: "freeze without orgs or repos is invalid",
+			freeze: TideFreeze{
+				Name:  "Code Freeze",
+				Start: start,
+			},
+			expectError: true,
+		},
+		{
+			name: "repo without org is invalid",
+			freeze: TideFreeze{
+				Name:  "Code Freeze",
+				Repos: []string{"/bar"},
+				Start: start,
+			},
+			expectError: true,
+		},
+		{
+			name: "freeze ending before its start is invalid",
+			freeze: TideFreeze{
+				Name:  "Code Freeze",
+				Orgs:  []string{"kuber"},
+				Start: end,
+				End:   &start,
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.freeze.Validate()
+			if err != nil && !tc.expectError {
+				t.Errorf("Unexpected error: %v.", err)
+			} else if err == nil && tc.expectError {
+				t.Error("Expected a validation error, but didn't get one.")
+			}
+		})
+	}
+}
+
+func TestActiveFreezes(t *testing.T) {
+	start := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(14 * 24 * time.Hour)
+	tide := Tide{Freezes: []TideFreeze{
+		{
+			Name:            "Code Freeze",
+			Orgs:            []string{"kuber"},
+			Branches:        []string{"master"},
+			Start:           start,
+			End:             &end,
+			ExemptionLabels: []string{"freeze-exempt"},
+		},
+		{
+			Name:  "Test Freeze",
+			Repos: []string{"foo/bar"},
+			Start: start,
+		},
+	}}
+	testCases := []struct {
+		name                  string
+		org                   string
+		repo                  string
+		branch                string
+		now                   time.Time
+		expectedFreezes       []string
+		expectedExemptByLabel bool
+	}{
+		{
+			name:   "before the freezes",
+			org:    "kuber",
+			repo:   "netes",
+			branch: "master",
+			now:    start.Add(-time.Minute),
+		},
+		{
+			name:                  "freeze of the org",
+			org:                   "kuber",
+			repo:                  "netes",
+			branch:                "master",
+			now:                   start,
+			expectedFreezes:       []string{"Code Freeze"},
+			expectedExemptByLabel: true,
+		},
+		{
+			name:   "other branch of the org",
+			org:    "kuber",
+			repo:   "netes",
+			branch: "release-1.25",
+			now:    start,
+		},
+		{
+			name:   "after the end of the freeze",
+			org:    "kuber",
+			repo:   "netes",
+			branch: "master",
+			now:    end,
+		},
+		{
+			name:            "freeze of the repo without end",
+			org:             "foo",
+			repo:            "bar",
+			branch:          "release-1.25",
+			now:             end,
+			expectedFreezes: []string{"Test Freeze"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			exempt := true
+			for _, freeze := range tide.ActiveFreezes(tc.org, tc.repo, tc.branch, tc.now) {
+				names = append(names, freeze.Name)
+				exempt = exempt && freeze.Exempts([]string{"lgtm", "freeze-exempt"})
+			}
+			if !reflect.DeepEqual(names, tc.expectedFreezes) {
+				t.Errorf("Expected active freezes %v, got %v.", tc.expectedFreezes, names)
+			}
+			if len(names) > 0 && exempt != tc.expectedExemptByLabel {
+				t.Errorf("Expected exemption to be %t, got %t.", tc.expectedExemptByLabel, exempt)
+			}
+		})
+	}
+}
+
+func TestTideMergePolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      TideMergePolicy
+		expectError bool
+	}{
+		{
+			name: "good policy",
+			policy: TideMergePolicy{
+				Name:     "api-review",
+				Repos:    []string{"foo/bar"},
+				Branches: []string{"master"},
+				If:       TidePolicyConditions{ChangedFiles: "^api/"},
+				Require: TidePolicyRequirements{
+					Approvals:       2,
+					OwnersApprovers: true,
+					Groups: map[string][]string{
+						"a": {"alice"},
+						"b": {"bob"},
+					},
+					DistinctGroups: 2,
+				},
+			},
+		},
+		{
+			name: "policy without name is invalid",
+			policy: TideMergePolicy{
+				Orgs: []string{"kuber"},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy without orgs or repos is invalid",
+			policy: TideMergePolicy{
+				Name: "api-review",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid changed files regexp",
+			policy: TideMergePolicy{
+				Name: "api-review",
+				Orgs: []string{"kuber"},
+				If:   TidePolicyConditions{ChangedFiles: "api/("},
+			},
+			expectError: true,
+		},
+		{
+			name: "label that is required and forbidden",
+			policy: TideMergePolicy{
+				Name: "security",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Labels:        []string{"lgtm"},
+					MissingLabels: []string{"lgtm"},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "more distinct groups than groups",
+			policy: TideMergePolicy{
+				Name: "api-review",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Approvals:      2,
+					Groups:         map[string][]string{"a": {"alice"}},
+					DistinctGroups: 2,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "more distinct groups than approvals",
+			policy: TideMergePolicy{
+				Name: "api-review",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Approvals: 1,
+					Groups: map[string][]string{
+						"a": {"alice"},
+						"b": {"bob"},
+					},
+					DistinctGroups: 2,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "approvers without approvals",
+			policy: TideMergePolicy{
+				Name: "security",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Approvers: []string{"alice"},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "expression",
+			policy: TideMergePolicy{
+				Name: "security",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Expression: `{{if not (has .Approvers "alice")}}an approval of alice{{end}}`,
+				},
+			},
+		},
+		{
+			name: "invalid expression",
+			policy: TideMergePolicy{
+				Name: "security",
+				Orgs: []string{"kuber"},
+				Require: TidePolicyRequirements{
+					Expression: `{{if not (contains .Approvers "alice")}}an approval of alice{{end}}`,
+				},
+			},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if err != nil && !tc.expectError {
+				t.Errorf("Unexpected error: %v.", err)
+			} else if err == nil && tc.expectError {
+				t.Error("Expected a validation error, but didn't get one.")
+			}
+		})
+	}
+}
+
+func TestMergePoliciesFor(t *testing.T) {
+	tide := Tide{MergePolicies: []TideMergePolicy{
+		{
+			Name:     "api-review",
+			Orgs:     []string{"kuber"},
+			Branches: []string{"master"},
+			If:       TidePolicyConditions{ChangedFiles: "^api/"},
+		},
+		{
+			Name:  "security",
+			Repos: []string{"foo/bar"},
+		},
+	}}
+	testCases := []struct {
+		name             string
+		org              string
+		repo             string
+		branch           string
+		files            []string
+		expectedPolicies []string
+	}{
+		{
+			name:             "policy of the org",
+			org:              "kuber",
+			repo:             "netes",
+			branch:           "master",
+			files:            []string{"README.md", "api/types.go"},
+			expectedPolicies: []string{"api-review"},
+		},
+		{
+			name:   "files not matching the condition",
+			org:    "kuber",
+			repo:   "netes",
+			branch: "master",
+			files:  []string{"README.md"},
+		},
+		{
+			name:   "other branch of the org",
+			org:    "kuber",
+			repo:   "netes",
+			branch: "release-1.25",
+			files:  []string{"api/types.go"},
+		},
+		{
+			name:             "policy of the repo without conditions",
+			org:              "foo",
+			repo:             "bar",
+			branch:           "release-1.25",
+			expectedPolicies: []string{"security"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			for _, policy := range tide.MergePoliciesFor(tc.org, tc.repo, tc.branch) {
+				matches, err := policy.If.MatchesFiles(tc.files)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v.", err)
+				}
+				if matches {
+					names = append(names, policy.Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tc.expectedPolicies) {
+				t.Errorf("Expected policies %v, got %v.", tc.expectedPolicies, names)
+			}
+		})
+	}
+}
+
 func TestTideContextPolicy_Validate(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -1099,3 +1463,46 @@ func fakeProwYAMLGetterFactory(presubmits []Presubmit, postsubmits []Postsubmit)
 		}, nil
 	}
 }
+
+func TestTideGitLabConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		c      TideGitLabConfig
+		failed bool
+	}{
+		{
+			name: "good config",
+			c: TideGitLabConfig{Queries: []TideGitLabQuery{{
+				Projects:      []string{"group/project", "group/subgroup/project"},
+				Labels:        []string{"lgtm"},
+				MissingLabels: []string{"do-not-merge/hold"},
+			}}},
+		},
+		{
+			name:   "projects must be set",
+			c:      TideGitLabConfig{Queries: []TideGitLabQuery{{Labels: []string{"lgtm"}}}},
+			failed: true,
+		},
+		{
+			name:   "projects must be full paths",
+			c:      TideGitLabConfig{Queries: []TideGitLabQuery{{Projects: []string{"project"}}}},
+			failed: true,
+		},
+		{
+			name: "labels cannot be both required and missing",
+			c: TideGitLabConfig{Queries: []TideGitLabQuery{{
+				Projects:      []string{"group/project"},
+				Labels:        []string{"lgtm"},
+				MissingLabels: []string{"lgtm"},
+			}}},
+			failed: true,
+		},
+	}
+	for _, tc := range testCases {
+		err := tc.c.Validate()
+		failed := err != nil
+		if failed != tc.failed {
+			t.Errorf("%s - expected %v got %v", tc.name, tc.failed, err)
+		}
+	}
+}
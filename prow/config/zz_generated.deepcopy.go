@@ -311,6 +311,11 @@ func (in *UtilityConfig) DeepCopyInto(out *UtilityConfig) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.SparseCheckout != nil {
+		in, out := &in.SparseCheckout, &out.SparseCheckout
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraRefs != nil {
 		in, out := &in.ExtraRefs, &out.ExtraRefs
 		*out = make([]prowjobsv1.Refs, len(*in))
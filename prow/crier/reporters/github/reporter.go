@@ -22,9 +22,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"path"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	"github.com/sirupsen/logrus"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -32,32 +36,50 @@ import (
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+	"k8s.io/test-infra/prow/crier/reporters/gcs/util"
 	"k8s.io/test-infra/prow/github/report"
+	pkgio "k8s.io/test-infra/prow/io"
 	"k8s.io/test-infra/prow/kube"
 )
 
 const (
 	// GitHubReporterName is the name for github reporter
 	GitHubReporterName = "github-reporter"
+
+	// maxJUnitFiles and maxJUnitSize limit the junit results that are read
+	// to summarize a job in its check run.
+	maxJUnitFiles = 20
+	maxJUnitSize  = 10 * 1024 * 1024
 )
 
+var junitRegex = regexp.MustCompile(`^junit.*\.xml$`)
+
+// GitHubClient is the github client used by the reporter
+type GitHubClient interface {
+	report.GitHubClient
+	report.CheckRunClient
+}
+
 // Client is a github reporter client
 type Client struct {
-	gc          report.GitHubClient
+	gc          GitHubClient
 	config      config.Getter
 	reportAgent v1.ProwJobAgent
 	locks       *criercommonlib.ShardedLock
 	lister      ctrlruntimeclient.Reader
+	opener      pkgio.Opener
 }
 
-// NewReporter returns a reporter client
-func NewReporter(gc report.GitHubClient, cfg config.Getter, reportAgent v1.ProwJobAgent, lister ctrlruntimeclient.Reader) *Client {
+// NewReporter returns a reporter client. The opener is used to read the junit
+// results of jobs that are reported as check runs, it may be nil.
+func NewReporter(gc GitHubClient, cfg config.Getter, reportAgent v1.ProwJobAgent, lister ctrlruntimeclient.Reader, opener pkgio.Opener) *Client {
 	c := &Client{
 		gc:          gc,
 		config:      cfg,
 		reportAgent: reportAgent,
 		locks:       criercommonlib.NewShardedLock(),
 		lister:      lister,
+		opener:      opener,
 	}
 	c.locks.RunCleanup()
 	return c
@@ -77,6 +99,8 @@ func (c *Client) ShouldReport(_ context.Context, _ *logrus.Entry, pj *v1.ProwJob
 	switch {
 	case pj.Labels[kube.GerritReportLabel] != "":
 		return false // TODO(fejta): opt-in to github reporting
+	case pj.Annotations[kube.GitLabInstance] != "":
+		return false // Reported by the gitlab reporter
 	case pj.Spec.Type != v1.PresubmitJob && pj.Spec.Type != v1.PostsubmitJob:
 		return false // Report presubmit and postsubmit github jobs for github reporter
 	case c.reportAgent != "" && pj.Spec.Agent != c.reportAgent:
@@ -92,7 +116,12 @@ func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob)
 	defer cancel()
 
 	// TODO(krzyzacy): ditch ReportTemplate, and we can drop reference to config.Getter
-	err := report.ReportStatusContext(ctx, c.gc, *pj, c.config().GitHubReporter)
+	var err error
+	if report.UsesCheckRuns(*pj, c.config().GitHubReporter) {
+		err = report.ReportCheckRun(ctx, c.gc, *pj, c.config().GitHubReporter, c.junitSuites(ctx, log, pj))
+	} else {
+		err = report.ReportStatusContext(ctx, c.gc, *pj, c.config().GitHubReporter)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "This SHA and context has reached the maximum number of statuses") {
 			// This is completely unrecoverable, so just swallow the error to make sure we wont retry, even when crier gets restarted.
@@ -152,6 +181,66 @@ func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob)
 	return []*v1.ProwJob{pj}, nil, err
 }
 
+// junitSuites reads the junit results that a completed job uploaded to its
+// artifacts. Failures are only logged, the check run is reported without the
+// test results in that case.
+func (c *Client) junitSuites(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob) []*junit.Suites {
+	if c.opener == nil || !pj.Complete() || pj.Status.BuildID == "" {
+		return nil
+	}
+	bucket, dir, err := util.GetJobDestination(c.config, pj)
+	if err != nil {
+		log.WithError(err).Debug("Could not get job destination, reporting check run without test results")
+		return nil
+	}
+	pp, err := v1.ParsePath(bucket)
+	if err != nil {
+		log.WithError(err).Debug("Could not parse bucket, reporting check run without test results")
+		return nil
+	}
+	root := fmt.Sprintf("%s://%s/", pp.StorageProvider(), pp.Bucket())
+	it, err := c.opener.Iterator(ctx, root+path.Join(dir, "artifacts")+"/", "")
+	if err != nil {
+		log.WithError(err).Warn("Could not list artifacts, reporting check run without test results")
+		return nil
+	}
+
+	var suites []*junit.Suites
+	for len(suites) < maxJUnitFiles {
+		attr, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithError(err).Warn("Could not list artifacts, reporting check run without all test results")
+			break
+		}
+		if attr.IsDir || !junitRegex.MatchString(attr.ObjName) {
+			continue
+		}
+		s, err := readJUnit(ctx, c.opener, root+attr.Name)
+		if err != nil {
+			log.WithError(err).WithField("artifact", attr.Name).Warn("Could not read junit results")
+			continue
+		}
+		suites = append(suites, s)
+	}
+	return suites
+}
+
+func readJUnit(ctx context.Context, opener pkgio.Opener, name string) (*junit.Suites, error) {
+	r, err := opener.Reader(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(io.LimitReader(r, maxJUnitSize))
+	if err != nil {
+		return nil, err
+	}
+	return junit.Parse(content)
+}
+
 func pjsToReport(ctx context.Context, log *logrus.Entry, lister ctrlruntimeclient.Reader, pj *v1.ProwJob) ([]v1.ProwJob, error) {
 	if len(pj.Spec.Refs.Pulls) != 1 {
 		return nil, nil
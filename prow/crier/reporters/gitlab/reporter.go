@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitlab contains a crier reporter that sets GitLab commit statuses
+// and comments on merge requests once all of their presubmits finished.
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/kube"
+)
+
+const reporterName = "gitlab-reporter"
+
+// Client reports ProwJobs triggered for the merge requests of a GitLab instance.
+type Client struct {
+	gc     gitlab.Client
+	lister ctrlruntimeclient.Reader
+	locks  *criercommonlib.ShardedLock
+}
+
+// NewReporter returns a reporter for the jobs of the instance of the client.
+func NewReporter(gc gitlab.Client, lister ctrlruntimeclient.Reader) *Client {
+	c := &Client{
+		gc:     gc,
+		lister: lister,
+		locks:  criercommonlib.NewShardedLock(),
+	}
+	c.locks.RunCleanup()
+	return c
+}
+
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport returns true for the presubmits that the GitLab adapter
+// triggered for the instance of the client.
+func (c *Client) ShouldReport(_ context.Context, _ *logrus.Entry, pj *prowapi.ProwJob) bool {
+	return pj.Spec.Report &&
+		pj.Spec.Type == prowapi.PresubmitJob &&
+		pj.Spec.Refs != nil && len(pj.Spec.Refs.Pulls) == 1 &&
+		pj.Annotations[kube.GitLabInstance] == c.gc.Instance() &&
+		pj.Annotations[kube.GitLabProject] != ""
+}
+
+// Report sets the commit status of the job. Once all presubmits of the head of
+// the merge request finished, the last one to finish also comments a summary
+// on the merge request.
+func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	project := pj.Annotations[kube.GitLabProject]
+	pull := pj.Spec.Refs.Pulls[0]
+
+	status := gitlab.CommitStatus{
+		Name:        pj.Spec.Context,
+		Status:      commitState(pj.Status.State),
+		Description: config.ContextDescriptionWithBaseSha(pj.Status.Description, pj.Spec.Refs.BaseSHA),
+		TargetURL:   pj.Status.URL,
+	}
+	if err := c.gc.SetCommitStatus(project, pull.SHA, status); err != nil {
+		return nil, nil, err
+	}
+	if pj.Complete() {
+		if err := c.reportSummary(ctx, log, pj, project, pull); err != nil {
+			return nil, nil, err
+		}
+	}
+	return []*prowapi.ProwJob{pj}, nil, nil
+}
+
+// reportSummary comments on the merge request once all of the latest jobs for
+// its head finished. Only the job that finished last comments so that the
+// summary is posted once.
+func (c *Client) reportSummary(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob, project string, pull prowapi.Pull) error {
+	lock, err := c.locks.GetLock(ctx, criercommonlib.NewSimpleCommit(pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pull.SHA))
+	if err != nil {
+		return err
+	}
+	if err := lock.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer lock.Release(1)
+
+	var pjs prowapi.ProwJobList
+	selector := map[string]string{
+		kube.GitLabRevision:   pull.SHA,
+		kube.ProwJobTypeLabel: string(prowapi.PresubmitJob),
+	}
+	if err := c.lister.List(ctx, &pjs, ctrlruntimeclient.MatchingLabels(selector)); err != nil {
+		return fmt.Errorf("failed to list jobs with selector %v: %w", selector, err)
+	}
+	latest := map[string]prowapi.ProwJob{pj.Spec.Context: *pj}
+	for _, other := range pjs.Items {
+		if other.Name == pj.Name || other.Annotations[kube.GitLabProject] != project || other.Annotations[kube.GitLabInstance] != c.gc.Instance() {
+			continue
+		}
+		if current, ok := latest[other.Spec.Context]; ok && current.CreationTimestamp.After(other.CreationTimestamp.Time) {
+			continue
+		}
+		latest[other.Spec.Context] = other
+	}
+	for _, job := range latest {
+		if !job.Complete() {
+			log.WithField("pending", job.Spec.Job).Debug("Not commenting until all jobs finished.")
+			return nil
+		}
+		if finishedAfter(&job, pj) {
+			return nil
+		}
+	}
+	return c.gc.CreateMergeRequestNote(project, pull.Number, summary(pull.SHA, latest))
+}
+
+// finishedAfter orders jobs by completion time and name.
+func finishedAfter(a, b *prowapi.ProwJob) bool {
+	aTime, bTime := a.Status.CompletionTime.Time, b.Status.CompletionTime.Time
+	if !aTime.Equal(bTime) {
+		return aTime.After(bTime)
+	}
+	return a.Name > b.Name
+}
+
+func summary(sha string, jobs map[string]prowapi.ProwJob) string {
+	var contexts []string
+	var failed int
+	for context, job := range jobs {
+		contexts = append(contexts, context)
+		if job.Status.State != prowapi.SuccessState {
+			failed++
+		}
+	}
+	sort.Strings(contexts)
+
+	var b strings.Builder
+	if failed == 0 {
+		fmt.Fprintf(&b, "All %d jobs passed for commit %s.\n\n", len(jobs), sha)
+	} else {
+		fmt.Fprintf(&b, "%d of %d jobs failed for commit %s.\n\n", failed, len(jobs), sha)
+	}
+	b.WriteString("| Job | Result |\n| --- | --- |\n")
+	for _, context := range contexts {
+		job := jobs[context]
+		result := string(job.Status.State)
+		if job.Status.URL != "" {
+			result = fmt.Sprintf("[%s](%s)", job.Status.State, job.Status.URL)
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", context, result)
+	}
+	if failed > 0 {
+		b.WriteString("\nComment `/retest` to rerun the failed jobs.")
+	}
+	return b.String()
+}
+
+// commitState maps ProwJob states to the states of GitLab commit statuses.
+func commitState(state prowapi.ProwJobState) string {
+	switch state {
+	case prowapi.TriggeredState:
+		return gitlab.StatusPending
+	case prowapi.PendingState:
+		return gitlab.StatusRunning
+	case prowapi.SuccessState:
+		return gitlab.StatusSuccess
+	case prowapi.AbortedState:
+		return gitlab.StatusCanceled
+	default:
+		return gitlab.StatusFailed
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/gitlab/fakegitlab"
+	"k8s.io/test-infra/prow/kube"
+)
+
+const project = "group/project"
+
+var start = time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func prowJob(name string, state prowapi.ProwJobState, completedAfter time.Duration) *prowapi.ProwJob {
+	pj := &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "prowjobs",
+			CreationTimestamp: metav1.NewTime(start),
+			Labels: map[string]string{
+				kube.GitLabRevision:   "head",
+				kube.ProwJobTypeLabel: string(prowapi.PresubmitJob),
+			},
+			Annotations: map[string]string{
+				kube.GitLabInstance: "https://gitlab.example.com",
+				kube.GitLabProject:  project,
+			},
+		},
+		Spec: prowapi.ProwJobSpec{
+			Type:    prowapi.PresubmitJob,
+			Job:     name,
+			Context: name,
+			Report:  true,
+			Refs: &prowapi.Refs{
+				Org:     "gitlab.example.com",
+				Repo:    project,
+				BaseSHA: "base",
+				Pulls:   []prowapi.Pull{{Number: 1, SHA: "head"}},
+			},
+		},
+		Status: prowapi.ProwJobStatus{
+			State: state,
+			URL:   "https://prow.example.com/view/" + name,
+		},
+	}
+	if pj.Complete() || completedAfter > 0 {
+		completion := metav1.NewTime(start.Add(completedAfter))
+		pj.Status.CompletionTime = &completion
+	}
+	return pj
+}
+
+func TestShouldReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(pj *prowapi.ProwJob)
+		expected bool
+	}{
+		{
+			name:     "presubmit triggered by the adapter",
+			expected: true,
+		},
+		{
+			name:   "other instance",
+			modify: func(pj *prowapi.ProwJob) { pj.Annotations[kube.GitLabInstance] = "https://gitlab.other.com" },
+		},
+		{
+			name:   "not triggered by the adapter",
+			modify: func(pj *prowapi.ProwJob) { pj.Annotations = nil },
+		},
+		{
+			name:   "reporting disabled",
+			modify: func(pj *prowapi.ProwJob) { pj.Spec.Report = false },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := prowJob("unit", prowapi.PendingState, 0)
+			if tc.modify != nil {
+				tc.modify(pj)
+			}
+			c := NewReporter(fakegitlab.NewClient(), fakectrlruntimeclient.NewFakeClient())
+			if actual := c.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	testCases := []struct {
+		name            string
+		pj              *prowapi.ProwJob
+		others          []ctrlruntimeclient.Object
+		expectedStatus  string
+		expectedSummary string
+	}{
+		{
+			name:           "running job",
+			pj:             prowJob("unit", prowapi.PendingState, 0),
+			others:         []ctrlruntimeclient.Object{prowJob("e2e", prowapi.SuccessState, time.Minute)},
+			expectedStatus: gitlab.StatusRunning,
+		},
+		{
+			name:           "other jobs still running",
+			pj:             prowJob("unit", prowapi.FailureState, time.Minute),
+			others:         []ctrlruntimeclient.Object{prowJob("e2e", prowapi.PendingState, 0)},
+			expectedStatus: gitlab.StatusFailed,
+		},
+		{
+			name:           "another job finished last",
+			pj:             prowJob("unit", prowapi.SuccessState, time.Minute),
+			others:         []ctrlruntimeclient.Object{prowJob("e2e", prowapi.SuccessState, time.Hour)},
+			expectedStatus: gitlab.StatusSuccess,
+		},
+		{
+			name:            "last job to finish comments",
+			pj:              prowJob("unit", prowapi.FailureState, time.Hour),
+			others:          []ctrlruntimeclient.Object{prowJob("e2e", prowapi.SuccessState, time.Minute)},
+			expectedStatus:  gitlab.StatusFailed,
+			expectedSummary: "1 of 2 jobs failed for commit head.\n\n| Job | Result |\n| --- | --- |\n| e2e | [success](https://prow.example.com/view/e2e) |\n| unit | [failure](https://prow.example.com/view/unit) |\n\nComment `/retest` to rerun the failed jobs.",
+		},
+		{
+			name: "jobs superseded by a rerun are ignored",
+			pj:   prowJob("unit", prowapi.SuccessState, time.Hour),
+			others: []ctrlruntimeclient.Object{
+				prowJob("e2e", prowapi.SuccessState, time.Minute),
+				func() *prowapi.ProwJob {
+					pj := prowJob("e2e-old", prowapi.FailureState, time.Minute)
+					pj.Spec.Context = "e2e"
+					pj.CreationTimestamp = metav1.NewTime(start.Add(-time.Hour))
+					return pj
+				}(),
+			},
+			expectedStatus:  gitlab.StatusSuccess,
+			expectedSummary: "All 2 jobs passed for commit head.",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gc := fakegitlab.NewClient()
+			c := NewReporter(gc, fakectrlruntimeclient.NewFakeClient(append(tc.others, tc.pj)...))
+			if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			statuses := gc.Statuses[project]["head"]
+			if len(statuses) != 1 || statuses[0].Name != "unit" || statuses[0].Status != tc.expectedStatus || !strings.HasSuffix(statuses[0].Description, "base") {
+				t.Errorf("expected a %s status for unit with the base SHA, got %+v", tc.expectedStatus, statuses)
+			}
+			notes := gc.Notes[project][1]
+			switch {
+			case tc.expectedSummary == "" && len(notes) > 0:
+				t.Errorf("expected no comment, got %q", notes)
+			case tc.expectedSummary != "" && (len(notes) != 1 || !strings.HasPrefix(notes[0], tc.expectedSummary)):
+				t.Errorf("expected a comment starting with %q, got %q", tc.expectedSummary, notes)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook contains a crier reporter that POSTs a JSON rendering of
+// ProwJobs to arbitrary HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+	"k8s.io/test-infra/prow/github"
+)
+
+const (
+	reporterName = "webhookreporter"
+
+	// EventHeader is the header that carries the kind of payload being delivered.
+	EventHeader = "X-Prow-Event"
+	// SignatureHeader is the header that carries the HMAC signature of the payload,
+	// in the same format that GitHub uses for X-Hub-Signature.
+	SignatureHeader = "X-Prow-Signature"
+	// DeliveryHeader is the header that carries a unique ID for the delivery.
+	// It is stable across retries of the same ProwJob state.
+	DeliveryHeader = "X-Prow-Delivery"
+
+	eventProwJob = "prowjob"
+
+	// Retries are driven by requeueing the ProwJob, the backoff doubles
+	// from initialBackoff up to maxBackoff and gives up after maxRetries.
+	initialBackoff = 5 * time.Second
+	maxBackoff     = 5 * time.Minute
+	maxRetries     = 8
+)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type webhookReporter struct {
+	config         func(*prowapi.Refs) config.WebhookReporter
+	client         httpClient
+	tokenGenerator func() []byte
+	dryRun         bool
+
+	lock sync.Mutex
+	// deliveries tracks the deliveries per ProwJob and state, so that
+	// subsequent requeues only retry the endpoints that failed and can
+	// back off exponentially.
+	deliveries map[string]*delivery
+}
+
+type delivery struct {
+	// attempts is the number of failed attempts so far.
+	attempts int
+	// delivered holds the endpoints that already accepted the payload.
+	delivered sets.String
+}
+
+// New creates a webhook reporter. The tokenGenerator returns the key used to
+// sign the payloads, no signature is sent if it is nil.
+func New(cfg func(refs *prowapi.Refs) config.WebhookReporter, tokenGenerator func() []byte, dryRun bool) *webhookReporter {
+	return &webhookReporter{
+		config:         cfg,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		tokenGenerator: tokenGenerator,
+		dryRun:         dryRun,
+		deliveries:     map[string]*delivery{},
+	}
+}
+
+func (wr *webhookReporter) GetName() string {
+	return reporterName
+}
+
+func (wr *webhookReporter) getConfig(pj *prowapi.ProwJob) (*config.WebhookReporter, *prowapi.WebhookReporterConfig) {
+	refs := pj.Spec.Refs
+	if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+		refs = &pj.Spec.ExtraRefs[0]
+	}
+	globalConfig := wr.config(refs)
+	var jobWebhookConfig *prowapi.WebhookReporterConfig
+	if pj.Spec.ReporterConfig != nil && pj.Spec.ReporterConfig.Webhook != nil {
+		jobWebhookConfig = pj.Spec.ReporterConfig.Webhook.DeepCopy()
+		// Jobs can be defined by anyone who can change in-repo config, so they
+		// may only send reports to the hosts that the global config allows.
+		var allowed []string
+		for _, endpoint := range jobWebhookConfig.Endpoints {
+			if globalConfig.AllowsJobEndpoint(endpoint) {
+				allowed = append(allowed, endpoint)
+			}
+		}
+		jobWebhookConfig.Endpoints = allowed
+	}
+	return &globalConfig, jobWebhookConfig
+}
+
+func (wr *webhookReporter) ShouldReport(_ context.Context, logger *logrus.Entry, pj *prowapi.ProwJob) bool {
+	globalConfig, jobConfig := wr.getConfig(pj)
+
+	var typeShouldReport bool
+	for _, tp := range globalConfig.JobTypesToReport {
+		if tp == pj.Spec.Type {
+			typeShouldReport = true
+			break
+		}
+	}
+
+	// Jobs that configure their own allowed endpoints are reported
+	// regardless of the job types setting.
+	var jobShouldReport bool
+	if jobConfig != nil && len(jobConfig.Endpoints) > 0 {
+		jobShouldReport = true
+	}
+
+	var stateShouldReport bool
+	if merged := jobConfig.ApplyDefault(&globalConfig.WebhookReporterConfig); merged != nil {
+		if merged.Report != nil && !*merged.Report {
+			logger.WithField("job_states_to_report", merged.JobStatesToReport).Debug("Skip webhook reporting as 'report: false', could result from 'job_states_to_report: []'.")
+			return false
+		}
+		if len(merged.Endpoints) == 0 {
+			return false
+		}
+		for _, stateToReport := range merged.JobStatesToReport {
+			if pj.Status.State == stateToReport {
+				stateShouldReport = true
+				break
+			}
+		}
+	}
+
+	shouldReport := stateShouldReport && (typeShouldReport || jobShouldReport)
+	logger.WithField("reporting", shouldReport).Debug("Determined should report")
+	return shouldReport
+}
+
+// Report POSTs the ProwJob to all configured endpoints. If any delivery fails
+// with a retriable error, the ProwJob is requeued with an exponential backoff
+// and only the endpoints that did not accept the payload yet are retried.
+func (wr *webhookReporter) Report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	globalConfig, jobConfig := wr.getConfig(pj)
+	merged := jobConfig.ApplyDefault(&globalConfig.WebhookReporterConfig)
+	if merged == nil || len(merged.Endpoints) == 0 {
+		return nil, nil, errors.New("resolved webhook config has no endpoints") // Shouldn't happen at all, just in case
+	}
+
+	payload, err := json.Marshal(pj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal prowjob: %w", err)
+	}
+	key := attemptKey(pj)
+
+	if wr.dryRun {
+		log.WithField("endpoints", merged.Endpoints).Debug("Skipping reporting because dry-run is enabled")
+		return []*prowapi.ProwJob{pj}, nil, nil
+	}
+
+	d := wr.startDelivery(pj)
+	var errs, userErrs []error
+	for _, endpoint := range merged.Endpoints {
+		if wr.isDelivered(d, endpoint) {
+			continue
+		}
+		if err := wr.send(ctx, endpoint, key, payload); err != nil {
+			if criercommonlib.IsUserError(err) {
+				userErrs = append(userErrs, err)
+			} else {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		wr.lock.Lock()
+		d.delivered.Insert(endpoint)
+		wr.lock.Unlock()
+	}
+
+	if len(errs) > 0 {
+		wr.lock.Lock()
+		attempt := d.attempts
+		d.attempts++
+		wr.lock.Unlock()
+		if attempt < maxRetries {
+			backoff := backoffFor(attempt)
+			log.WithError(utilerrors.NewAggregate(errs)).WithField("attempt", attempt+1).WithField("backoff", backoff).Info("Failed to deliver webhook, will retry.")
+			return nil, &reconcile.Result{RequeueAfter: backoff}, nil
+		}
+		wr.forget(key)
+		return nil, nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, utilerrors.NewAggregate(append(errs, userErrs...)))
+	}
+
+	wr.forget(key)
+	if len(userErrs) > 0 {
+		return nil, nil, criercommonlib.UserError(utilerrors.NewAggregate(userErrs))
+	}
+	return []*prowapi.ProwJob{pj}, nil, nil
+}
+
+// startDelivery returns the delivery of the current state of the ProwJob.
+// Deliveries of previous states are dropped, the ProwJob moved on.
+func (wr *webhookReporter) startDelivery(pj *prowapi.ProwJob) *delivery {
+	key := attemptKey(pj)
+	prefix := fmt.Sprintf("%s/%s/", pj.Namespace, pj.Name)
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	for k := range wr.deliveries {
+		if k != key && strings.HasPrefix(k, prefix) {
+			delete(wr.deliveries, k)
+		}
+	}
+	d, ok := wr.deliveries[key]
+	if !ok {
+		d = &delivery{delivered: sets.NewString()}
+		wr.deliveries[key] = d
+	}
+	return d
+}
+
+func (wr *webhookReporter) isDelivered(d *delivery, endpoint string) bool {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	return d.delivered.Has(endpoint)
+}
+
+func (wr *webhookReporter) send(ctx context.Context, endpoint, delivery string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return criercommonlib.UserError(fmt.Errorf("failed to create request for %q: %w", endpoint, err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventProwJob)
+	req.Header.Set(DeliveryHeader, delivery)
+	if wr.tokenGenerator != nil {
+		req.Header.Set(SignatureHeader, github.PayloadSignature(payload, wr.tokenGenerator()))
+	}
+
+	resp, err := wr.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to POST to %q: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("endpoint %q responded with %s", endpoint, resp.Status)
+	default:
+		// The receiver rejected the payload, retrying won't help.
+		return criercommonlib.UserError(fmt.Errorf("endpoint %q responded with %s", endpoint, resp.Status))
+	}
+}
+
+func (wr *webhookReporter) forget(key string) {
+	wr.lock.Lock()
+	delete(wr.deliveries, key)
+	wr.lock.Unlock()
+}
+
+func attemptKey(pj *prowapi.ProwJob) string {
+	return fmt.Sprintf("%s/%s/%s", pj.Namespace, pj.Name, pj.Status.State)
+}
+
+func backoffFor(attempt int) time.Duration {
+	backoff := initialBackoff
+	for i := 0; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,367 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/crier/reporters/criercommonlib"
+	"k8s.io/test-infra/prow/github"
+)
+
+func TestShouldReport(t *testing.T) {
+	boolPtr := func(b bool) *bool {
+		return &b
+	}
+	testCases := []struct {
+		name     string
+		config   config.WebhookReporter
+		pj       *v1.ProwJob
+		expected bool
+	}{
+		{
+			name: "Presubmit Job should report",
+			config: config.WebhookReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					Endpoints:         []string{"https://example.com"},
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: true,
+		},
+		{
+			name: "Wrong job type should not report",
+			config: config.WebhookReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					Endpoints:         []string{"https://example.com"},
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: false,
+		},
+		{
+			name: "Wrong job state should not report",
+			config: config.WebhookReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					Endpoints:         []string{"https://example.com"},
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob},
+				Status: v1.ProwJobStatus{State: v1.PendingState},
+			},
+			expected: false,
+		},
+		{
+			name: "Job with endpoints should report regardless of type",
+			config: config.WebhookReporter{
+				AllowedJobEndpointHosts: []string{"example.com"},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type: v1.PeriodicJob,
+					ReporterConfig: &v1.ReporterConfig{
+						Webhook: &v1.WebhookReporterConfig{Endpoints: []string{"https://example.com"}},
+					},
+				},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: true,
+		},
+		{
+			name: "Job with endpoints of a host that is not allowed should not report",
+			config: config.WebhookReporter{
+				AllowedJobEndpointHosts: []string{"example.com"},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type: v1.PeriodicJob,
+					ReporterConfig: &v1.ReporterConfig{
+						Webhook: &v1.WebhookReporterConfig{Endpoints: []string{"http://kubernetes.default.svc"}},
+					},
+				},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: false,
+		},
+		{
+			name: "Job with report: false should not report",
+			config: config.WebhookReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					Endpoints:         []string{"https://example.com"},
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type: v1.PresubmitJob,
+					ReporterConfig: &v1.ReporterConfig{
+						Webhook: &v1.WebhookReporterConfig{Report: boolPtr(false)},
+					},
+				},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: false,
+		},
+		{
+			name: "No endpoints should not report",
+			config: config.WebhookReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+				WebhookReporterConfig: v1.WebhookReporterConfig{
+					JobStatesToReport: []v1.ProwJobState{v1.SuccessState},
+				},
+			},
+			pj: &v1.ProwJob{
+				Spec:   v1.ProwJobSpec{Type: v1.PresubmitJob},
+				Status: v1.ProwJobStatus{State: v1.SuccessState},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgGetter := func(*v1.Refs) config.WebhookReporter {
+				return tc.config
+			}
+			reporter := New(cfgGetter, nil, false)
+			if result := reporter.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); result != tc.expected {
+				t.Errorf("expected result to be %t but was %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	token := []byte("abc123")
+	testCases := []struct {
+		name          string
+		status        int
+		priorAttempts int
+		expectRequeue time.Duration
+		expectPJs     int
+		expectErr     bool
+		expectUserErr bool
+	}{
+		{
+			name:      "successful delivery reports the job",
+			status:    http.StatusOK,
+			expectPJs: 1,
+		},
+		{
+			name:          "server error is requeued with backoff",
+			status:        http.StatusBadGateway,
+			expectRequeue: initialBackoff,
+		},
+		{
+			name:          "backoff grows with each attempt",
+			status:        http.StatusServiceUnavailable,
+			priorAttempts: 2,
+			expectRequeue: 4 * initialBackoff,
+		},
+		{
+			name:          "retries are exhausted",
+			status:        http.StatusInternalServerError,
+			priorAttempts: maxRetries,
+			expectErr:     true,
+		},
+		{
+			name:          "client error is not retried",
+			status:        http.StatusBadRequest,
+			expectErr:     true,
+			expectUserErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &v1.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "prowjobs"},
+				Spec:       v1.ProwJobSpec{Type: v1.PresubmitJob, Job: "pull-test"},
+				Status:     v1.ProwJobStatus{State: v1.FailureState},
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatalf("failed to read body: %v", err)
+				}
+				if expected, actual := github.PayloadSignature(payload, token), r.Header.Get(SignatureHeader); expected != actual {
+					t.Errorf("expected signature %q, got %q", expected, actual)
+				}
+				if actual := r.Header.Get(EventHeader); actual != eventProwJob {
+					t.Errorf("expected event %q, got %q", eventProwJob, actual)
+				}
+				var received v1.ProwJob
+				if err := json.Unmarshal(payload, &received); err != nil {
+					t.Errorf("failed to unmarshal payload: %v", err)
+				}
+				if received.Spec.Job != pj.Spec.Job {
+					t.Errorf("expected job %q in payload, got %q", pj.Spec.Job, received.Spec.Job)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			cfgGetter := func(*v1.Refs) config.WebhookReporter {
+				return config.WebhookReporter{
+					JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+					WebhookReporterConfig: v1.WebhookReporterConfig{
+						Endpoints:         []string{server.URL},
+						JobStatesToReport: []v1.ProwJobState{v1.FailureState},
+					},
+				}
+			}
+			reporter := New(cfgGetter, func() []byte { return token }, false)
+			if tc.priorAttempts > 0 {
+				reporter.startDelivery(pj).attempts = tc.priorAttempts
+			}
+
+			pjs, result, err := reporter.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", tc.expectErr, err)
+			}
+			if tc.expectUserErr != criercommonlib.IsUserError(err) {
+				t.Errorf("expected user error: %t, got: %v", tc.expectUserErr, err)
+			}
+			var requeue time.Duration
+			if result != nil {
+				requeue = result.RequeueAfter
+			}
+			if requeue != tc.expectRequeue {
+				t.Errorf("expected requeue after %v, got %v", tc.expectRequeue, requeue)
+			}
+			if len(pjs) != tc.expectPJs {
+				t.Errorf("expected %d reported prowjobs, got %d", tc.expectPJs, len(pjs))
+			}
+		})
+	}
+}
+
+func TestReportRetriesOnlyFailedEndpoints(t *testing.T) {
+	requests := map[string]int{}
+	statuses := map[string]int{"healthy": http.StatusOK, "flaky": http.StatusBadGateway}
+	var lock sync.Mutex
+	handler := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			requests[name]++
+			status := statuses[name]
+			lock.Unlock()
+			w.WriteHeader(status)
+		}))
+	}
+	healthy := handler("healthy")
+	defer healthy.Close()
+	flaky := handler("flaky")
+	defer flaky.Close()
+
+	cfgGetter := func(*v1.Refs) config.WebhookReporter {
+		return config.WebhookReporter{
+			JobTypesToReport: []v1.ProwJobType{v1.PresubmitJob},
+			WebhookReporterConfig: v1.WebhookReporterConfig{
+				Endpoints:         []string{healthy.URL, flaky.URL},
+				JobStatesToReport: []v1.ProwJobState{v1.FailureState},
+			},
+		}
+	}
+	reporter := New(cfgGetter, nil, false)
+	log := logrus.NewEntry(logrus.StandardLogger())
+	pj := &v1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "prowjobs"},
+		Spec:       v1.ProwJobSpec{Type: v1.PresubmitJob, Job: "pull-test"},
+		Status:     v1.ProwJobStatus{State: v1.FailureState},
+	}
+
+	if _, result, err := reporter.Report(context.Background(), log, pj); err != nil || result == nil {
+		t.Fatalf("expected the first report to be requeued, got result %v and error %v", result, err)
+	}
+	lock.Lock()
+	statuses["flaky"] = http.StatusOK
+	lock.Unlock()
+	pjs, _, err := reporter.Report(context.Background(), log, pj)
+	if err != nil {
+		t.Fatalf("unexpected error on retry: %v", err)
+	}
+	if len(pjs) != 1 {
+		t.Errorf("expected the retry to report the prowjob, got %d prowjobs", len(pjs))
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if expected := map[string]int{"healthy": 1, "flaky": 2}; !reflect.DeepEqual(expected, requests) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+	if len(reporter.deliveries) != 0 {
+		t.Errorf("expected no deliveries to be tracked after success, got %v", reporter.deliveries)
+	}
+}
+
+func TestStartDeliveryPrunesPreviousStates(t *testing.T) {
+	reporter := New(nil, nil, false)
+	pj := &v1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "prowjobs"},
+		Status:     v1.ProwJobStatus{State: v1.PendingState},
+	}
+	other := &v1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-job", Namespace: "prowjobs"},
+		Status:     v1.ProwJobStatus{State: v1.PendingState},
+	}
+	reporter.startDelivery(pj).attempts = 3
+	reporter.startDelivery(other).attempts = 2
+
+	pj.Status.State = v1.FailureState
+	if d := reporter.startDelivery(pj); d.attempts != 0 {
+		t.Errorf("expected a new delivery for the new state, got %d attempts", d.attempts)
+	}
+	if _, ok := reporter.deliveries["prowjobs/some-job/pending"]; ok {
+		t.Error("expected the delivery of the previous state to be pruned")
+	}
+	if d, ok := reporter.deliveries[attemptKey(other)]; !ok || d.attempts != 2 {
+		t.Error("expected the delivery of another prowjob to be kept")
+	}
+}
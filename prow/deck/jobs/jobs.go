@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"sort"
@@ -81,6 +82,12 @@ type PodLogClient interface {
 	GetLogs(name, container string) ([]byte, error)
 }
 
+// PodLogStreamer is implemented by PodLogClients that can follow the logs of
+// running pods.
+type PodLogStreamer interface {
+	StreamLogs(ctx context.Context, name, container string) (io.ReadCloser, error)
+}
+
 // PJListingClient is an interface to list ProwJobs
 type PJListingClient interface {
 	List(context.Context, *prowapi.ProwJobList, ...ctrlruntimeclient.ListOption) error
@@ -271,6 +278,27 @@ func (ja *JobAgent) GetJobLog(job, id string, container string) ([]byte, error)
 	return nil, fmt.Errorf("cannot get logs for prowjob %q with agent %q: the agent is missing from the prow config file", j.ObjectMeta.Name, j.Spec.Agent)
 }
 
+// StreamJobLog returns a reader of the log of the job's pod that follows the
+// log until the container terminates.
+func (ja *JobAgent) StreamJobLog(ctx context.Context, job, id, container string) (io.ReadCloser, error) {
+	j, err := ja.GetProwJob(job, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting prowjob: %w", err)
+	}
+	if j.Spec.Agent != prowapi.KubernetesAgent {
+		return nil, fmt.Errorf("cannot stream logs for prowjob %q with agent %q", j.ObjectMeta.Name, j.Spec.Agent)
+	}
+	client, ok := ja.pkcs[j.ClusterAlias()]
+	if !ok {
+		return nil, fmt.Errorf("cannot stream logs for prowjob %q with agent %q: unknown cluster alias %q", j.ObjectMeta.Name, j.Spec.Agent, j.ClusterAlias())
+	}
+	streamer, ok := client.(PodLogStreamer)
+	if !ok {
+		return nil, fmt.Errorf("cannot stream logs for prowjob %q: the client of cluster %q does not support streaming", j.ObjectMeta.Name, j.ClusterAlias())
+	}
+	return streamer.StreamLogs(ctx, j.Status.PodName, container)
+}
+
 func (ja *JobAgent) tryUpdate() {
 	if err := ja.update(); err != nil {
 		logrus.WithError(err).Warning("Error updating job list.")
@@ -50,6 +50,10 @@ type Options struct {
 	// If specified, it is created by entrypoint before starting the test process.
 	// May be ignored if not using sidecar.
 	ArtifactDir string `json:"artifact_dir,omitempty"`
+	// PhasesFile is where entrypoint writes the phases that the test
+	// process marks in its output, see PhaseMarker. Nothing is written
+	// if unset or if the test process does not mark any phases.
+	PhasesFile string `json:"phases_file,omitempty"`
 
 	// PreviousMarker has no effect when empty (default).
 	// When set it causes entrypoint to:
@@ -101,6 +105,7 @@ func (o *Options) AddFlags(flags *flag.FlagSet) {
 	flags.DurationVar(&o.Timeout, "timeout", DefaultTimeout, "Timeout for the test command.")
 	flags.DurationVar(&o.GracePeriod, "grace-period", DefaultGracePeriod, "Grace period after timeout for the test command.")
 	flags.StringVar(&o.ArtifactDir, "artifact-dir", "", "directory where test artifacts should be placed for upload to persistent storage")
+	flags.StringVar(&o.PhasesFile, "phases-file", "", "file where the phases marked by the test process are written to")
 	flags.BoolVar(&o.CopyModeOnly, "copy-mode-only", false, "If true, copy current binary to /tools/entrypoint, dst can be overridden by --copy-destination")
 	flags.StringVar(&o.CopyDst, "copy-destination", defaultCopyDst, "Must be used with --copy-mode-only, default is /tools/entrypoint")
 	o.Options.AddFlags(flags)
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PhaseMarker prefixes the lines that the test process writes to
+	// stdout or stderr to start a phase, e.g.
+	//
+	//	##prow-phase: cluster-up
+	//
+	// A phase ends when the next phase starts, when a marker without
+	// a name is written or when the process exits.
+	PhaseMarker = "##prow-phase:"
+
+	// maxMarkerLength is the length of the longest line that is checked
+	// for a marker, longer lines are ignored.
+	maxMarkerLength = 1024
+)
+
+// Phase is a step of the test process like building or testing.
+type Phase struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns how long the phase took.
+func (p Phase) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// Phases holds the phases of a test process, which entrypoint writes to the
+// phases file once the process exits.
+type Phases struct {
+	// Start and End are the times the test process started and exited.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Timeout is the timeout of the test process.
+	Timeout time.Duration `json:"timeout"`
+	// TimedOut is set if the test process did not finish before the
+	// timeout, Aborted if it was interrupted.
+	TimedOut bool `json:"timed_out,omitempty"`
+	Aborted  bool `json:"aborted,omitempty"`
+
+	Phases []Phase `json:"phases"`
+}
+
+// Last returns the last phase, which is the one the process was in when it
+// exited.
+func (p Phases) Last() *Phase {
+	if len(p.Phases) == 0 {
+		return nil
+	}
+	return &p.Phases[len(p.Phases)-1]
+}
+
+// phaseRecorder records the phases marked in the output of the test process.
+type phaseRecorder struct {
+	now func() time.Time
+
+	lock     sync.Mutex
+	line     []byte
+	overflow bool
+	start    time.Time
+	phases   []Phase
+}
+
+func newPhaseRecorder(now func() time.Time) *phaseRecorder {
+	return &phaseRecorder{now: now}
+}
+
+// started records the start of the test process.
+func (r *phaseRecorder) started() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.start = r.now()
+}
+
+// Write scans the output for markers, it never fails.
+func (r *phaseRecorder) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.buffer(p)
+			return n, nil
+		}
+		r.buffer(p[:i])
+		r.endLine()
+		p = p[i+1:]
+	}
+}
+
+func (r *phaseRecorder) buffer(p []byte) {
+	if r.overflow || len(r.line)+len(p) > maxMarkerLength {
+		r.overflow = true
+		return
+	}
+	r.line = append(r.line, p...)
+}
+
+func (r *phaseRecorder) endLine() {
+	line := strings.TrimSpace(string(r.line))
+	overflow := r.overflow
+	r.line = r.line[:0]
+	r.overflow = false
+	if overflow || !strings.HasPrefix(line, PhaseMarker) {
+		return
+	}
+	r.mark(strings.TrimSpace(strings.TrimPrefix(line, PhaseMarker)), r.now())
+}
+
+// mark ends the current phase and starts the named one, if any.
+func (r *phaseRecorder) mark(name string, now time.Time) {
+	if n := len(r.phases); n > 0 && r.phases[n-1].End.IsZero() {
+		r.phases[n-1].End = now
+	}
+	if name != "" {
+		r.phases = append(r.phases, Phase{Name: name, Start: now})
+	}
+}
+
+// finish ends the current phase and returns the phases of the test process.
+func (r *phaseRecorder) finish(timeout time.Duration, commandErr error) Phases {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.endLine()
+	now := r.now()
+	r.mark("", now)
+	return Phases{
+		Start:    r.start,
+		End:      now,
+		Timeout:  timeout,
+		TimedOut: commandErr == errTimedOut,
+		Aborted:  commandErr == errAborted,
+		Phases:   append([]Phase(nil), r.phases...),
+	}
+}
+
+// writePhases writes the phases to the phases file, unless the test process
+// did not mark any phases.
+func (o *Options) writePhases(phases Phases) error {
+	if len(phases.Phases) == 0 {
+		return nil
+	}
+	content, err := json.Marshal(phases)
+	if err != nil {
+		return fmt.Errorf("could not marshal phases: %w", err)
+	}
+	if err := ioutil.WriteFile(o.PhasesFile, content, 0644); err != nil {
+		return fmt.Errorf("could not write phases file (%s): %w", o.PhasesFile, err)
+	}
+	return nil
+}
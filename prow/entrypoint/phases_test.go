@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entrypoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+)
+
+func TestPhaseRecorder(t *testing.T) {
+	start := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		name     string
+		writes   []string
+		err      error
+		expected Phases
+	}{
+		{
+			name:     "no markers",
+			writes:   []string{"building\n", "testing\n"},
+			expected: Phases{Start: start, End: start.Add(time.Minute), Timeout: time.Hour},
+		},
+		{
+			name:   "phases end when the next one starts and on exit",
+			writes: []string{"##prow-phase: build\nbuilding\n", "##prow-phase: test\n", "testing\n"},
+			expected: Phases{Start: start, End: start.Add(3 * time.Minute), Timeout: time.Hour, Phases: []Phase{
+				{Name: "build", Start: start.Add(time.Minute), End: start.Add(2 * time.Minute)},
+				{Name: "test", Start: start.Add(2 * time.Minute), End: start.Add(3 * time.Minute)},
+			}},
+		},
+		{
+			name:   "markers split across writes and without a name",
+			writes: []string{"  ##prow-", "phase: build\r\n", "##prow-phase:\n", "cleaning up\n"},
+			expected: Phases{Start: start, End: start.Add(3 * time.Minute), Timeout: time.Hour, Phases: []Phase{
+				{Name: "build", Start: start.Add(time.Minute), End: start.Add(2 * time.Minute)},
+			}},
+		},
+		{
+			name:     "markers must start the line",
+			writes:   []string{"echo ##prow-phase: build\n", strings.Repeat("x", maxMarkerLength) + "##prow-phase: test\n"},
+			expected: Phases{Start: start, End: start.Add(time.Minute), Timeout: time.Hour},
+		},
+		{
+			name:   "unterminated marker at exit",
+			writes: []string{"##prow-phase: cluster-up"},
+			err:    errTimedOut,
+			expected: Phases{Start: start, End: start.Add(2 * time.Minute), Timeout: time.Hour, TimedOut: true, Phases: []Phase{
+				{Name: "cluster-up", Start: start.Add(time.Minute), End: start.Add(2 * time.Minute)},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start
+			r := newPhaseRecorder(func() time.Time {
+				defer func() { now = now.Add(time.Minute) }()
+				return now
+			})
+			r.started()
+			for _, w := range tc.writes {
+				if n, err := r.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("expected write of %d bytes, got %d, %v", len(w), n, err)
+				}
+			}
+			if diff := cmp.Diff(tc.expected, r.finish(time.Hour, tc.err)); diff != "" {
+				t.Errorf("unexpected phases (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOptions_RunWritesPhases(t *testing.T) {
+	tmpDir := t.TempDir()
+	options := Options{
+		PhasesFile: path.Join(tmpDir, "phases.json"),
+		Options: &wrapper.Options{
+			Args:       []string{"sh", "-c", "echo '##prow-phase: build' && echo '##prow-phase: test' >&2"},
+			ProcessLog: path.Join(tmpDir, "process-log.txt"),
+			MarkerFile: path.Join(tmpDir, "marker-file.txt"),
+		},
+	}
+	if code := options.Run(); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	raw, err := ioutil.ReadFile(options.PhasesFile)
+	if err != nil {
+		t.Fatalf("could not read phases file: %v", err)
+	}
+	var phases Phases
+	if err := json.Unmarshal(raw, &phases); err != nil {
+		t.Fatalf("could not unmarshal phases file: %v", err)
+	}
+	var names []string
+	for _, p := range phases.Phases {
+		names = append(names, p.Name)
+		if p.Start.Before(phases.Start) || p.End.Before(p.Start) || phases.End.Before(p.End) {
+			t.Errorf("phase %s from %s to %s is not within the process from %s to %s", p.Name, p.Start, p.End, phases.Start, phases.End)
+		}
+	}
+	if diff := cmp.Diff([]string{"build", "test"}, names); diff != "" {
+		t.Errorf("unexpected phases (-want, +got):\n%s", diff)
+	}
+	if phases.Timeout != DefaultTimeout {
+		t.Errorf("expected the default timeout %s, got %s", DefaultTimeout, phases.Timeout)
+	}
+}
@@ -75,10 +75,19 @@ var (
 // Run executes the test process then writes the exit code to the marker file.
 // This function returns the status code that should be passed to os.Exit().
 func (o Options) Run() int {
-	code, err := o.ExecuteProcess()
+	var phases *phaseRecorder
+	if o.PhasesFile != "" {
+		phases = newPhaseRecorder(time.Now)
+	}
+	code, err := o.executeProcess(phases)
 	if err != nil {
 		logrus.WithError(err).Error("Error executing test process")
 	}
+	if phases != nil {
+		if err := o.writePhases(phases.finish(optionOrDefault(o.Timeout, DefaultTimeout), err)); err != nil {
+			logrus.WithError(err).Error("Error writing phases file")
+		}
+	}
 	if err := o.Mark(code); err != nil {
 		logrus.WithError(err).Error("Error writing exit code to marker file")
 		return InternalErrorCode // we need to mark the real error code to safely return AlwaysZero
@@ -92,6 +101,12 @@ func (o Options) Run() int {
 // ExecuteProcess creates the artifact directory then executes the process as
 // configured, writing the output to the process log.
 func (o Options) ExecuteProcess() (int, error) {
+	return o.executeProcess(nil)
+}
+
+// executeProcess executes the process like ExecuteProcess and records the
+// phases the process marks in its output, unless phases is nil.
+func (o Options) executeProcess(phases *phaseRecorder) (int, error) {
 	if o.ArtifactDir != "" {
 		if err := os.MkdirAll(o.ArtifactDir, os.ModePerm); err != nil {
 			return InternalErrorCode, fmt.Errorf("could not create artifact directory(%s): %w", o.ArtifactDir, err)
@@ -140,8 +155,12 @@ func (o Options) ExecuteProcess() (int, error) {
 		arguments = o.Args[1:]
 	}
 	command := exec.Command(executable, arguments...)
-	command.Stderr = output
-	command.Stdout = output
+	processOutput := output
+	if phases != nil {
+		processOutput = io.MultiWriter(output, phases)
+	}
+	command.Stderr = processOutput
+	command.Stdout = processOutput
 	if err := command.Start(); err != nil {
 		errs := []error{fmt.Errorf("could not start the process: %w", err)}
 		if _, err := processLogFile.Write([]byte(errs[0].Error())); err != nil {
@@ -149,6 +168,9 @@ func (o Options) ExecuteProcess() (int, error) {
 		}
 		return InternalErrorCode, utilerrors.NewAggregate(errs)
 	}
+	if phases != nil {
+		phases.started()
+	}
 
 	timeout := optionOrDefault(o.Timeout, DefaultTimeout)
 	gracePeriod := optionOrDefault(o.GracePeriod, DefaultGracePeriod)
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flagutil
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+// GitLabOptions holds options for interacting with a GitLab instance.
+type GitLabOptions struct {
+	Endpoint  string
+	TokenPath string
+}
+
+// AddFlags injects GitLab options into the given FlagSet.
+func (o *GitLabOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "gitlab-endpoint", "", "The URL of the GitLab instance, e.g. https://gitlab.example.com")
+	fs.StringVar(&o.TokenPath, "gitlab-token-path", "", "Path to the file containing the GitLab access token.")
+}
+
+// Validate validates GitLab options. Both flags are required once the
+// endpoint is set.
+func (o *GitLabOptions) Validate(_ bool) error {
+	if o.Endpoint == "" {
+		return nil
+	}
+	if _, err := url.ParseRequestURI(o.Endpoint); err != nil {
+		return fmt.Errorf("--gitlab-endpoint %q is invalid: %w", o.Endpoint, err)
+	}
+	if o.TokenPath == "" {
+		return errors.New("--gitlab-token-path must be set with --gitlab-endpoint")
+	}
+	return nil
+}
+
+// Client returns a GitLab client.
+func (o *GitLabOptions) Client(dryRun bool) (gitlab.Client, error) {
+	if o.Endpoint == "" {
+		return nil, errors.New("empty --gitlab-endpoint, can not create a client")
+	}
+	if err := secret.Add(o.TokenPath); err != nil {
+		return nil, fmt.Errorf("failed to get --gitlab-token-path: %w", err)
+	}
+	return gitlab.NewClient(o.Endpoint, secret.GetTokenGenerator(o.TokenPath), dryRun)
+}
+
+// GitClientFactory returns a factory of git clients that clone the projects of
+// the GitLab instance over HTTPS, authenticated with the access token. The
+// clients expect the path of the group of a project as org and its name as
+// repo.
+func (o *GitLabOptions) GitClientFactory() (git.ClientFactory, error) {
+	if o.Endpoint == "" {
+		return nil, errors.New("empty --gitlab-endpoint, can not create a git client")
+	}
+	u, err := url.ParseRequestURI(o.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("--gitlab-endpoint %q is invalid: %w", o.Endpoint, err)
+	}
+	if err := secret.Add(o.TokenPath); err != nil {
+		return nil, fmt.Errorf("failed to get --gitlab-token-path: %w", err)
+	}
+	opts := git.ClientFactoryOpts{
+		Host: u.Host,
+		// GitLab accepts access tokens as password of any user.
+		Username: func() (string, error) { return "oauth2", nil },
+		Token:    secret.GetTokenGenerator(o.TokenPath),
+	}
+	return git.NewClientFactory(opts.Apply)
+}
@@ -36,12 +36,22 @@ type StorageClientOptions struct {
 	// If not, go cloud credential auto-discovery is used
 	// For more details see the prow/io/providers pkg.
 	S3CredentialsFile string `json:"s3_credentials_file,omitempty"`
+	// AzureCredentialsFile is used for reading/writing to Azure Blob Storage.
+	// It's optional, if set this file is used to read/write to azblob:// paths
+	// If not, go cloud credential auto-discovery is used
+	// For more details see the prow/io/providers pkg.
+	AzureCredentialsFile string `json:"azure_credentials_file,omitempty"`
+	// FileStorageRoot is the directory that holds the buckets of file:// paths,
+	// e.g. a mounted NFS volume. file:// paths can only be used if it is set.
+	FileStorageRoot string `json:"file_storage_root,omitempty"`
 }
 
 // AddFlags injects status client options into the given FlagSet.
 func (o *StorageClientOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&o.GCSCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored")
 	fs.StringVar(&o.S3CredentialsFile, "s3-credentials-file", "", "File where s3 credentials are stored. For the exact format see https://github.com/kubernetes/test-infra/blob/master/prow/io/providers/providers.go")
+	fs.StringVar(&o.AzureCredentialsFile, "azure-credentials-file", "", "File where Azure Blob Storage credentials are stored. For the exact format see https://github.com/kubernetes/test-infra/blob/master/prow/io/providers/providers.go")
+	fs.StringVar(&o.FileStorageRoot, "file-storage-root", "", "Directory that holds the buckets of file:// paths, e.g. a mounted NFS volume.")
 }
 
 func (o *StorageClientOptions) HasGCSCredentials() bool {
@@ -52,6 +62,20 @@ func (o *StorageClientOptions) HasS3Credentials() bool {
 	return o.S3CredentialsFile != ""
 }
 
+func (o *StorageClientOptions) HasAzureCredentials() bool {
+	return o.AzureCredentialsFile != ""
+}
+
+// OpenerOptions returns the options to create an io.Opener with.
+func (o *StorageClientOptions) OpenerOptions() io.OpenerOptions {
+	return io.OpenerOptions{
+		GCSCredentialsFile:   o.GCSCredentialsFile,
+		S3CredentialsFile:    o.S3CredentialsFile,
+		AzureCredentialsFile: o.AzureCredentialsFile,
+		FileStorageRoot:      o.FileStorageRoot,
+	}
+}
+
 // Validate validates options.
 func (o *StorageClientOptions) Validate(dryRun bool) error {
 	return nil
@@ -59,7 +83,7 @@ func (o *StorageClientOptions) Validate(dryRun bool) error {
 
 // StorageClient returns a Storage client.
 func (o *StorageClientOptions) StorageClient(ctx context.Context) (io.Opener, error) {
-	opener, err := io.NewOpener(ctx, o.GCSCredentialsFile, o.S3CredentialsFile)
+	opener, err := io.NewOpenerWithOptions(ctx, o.OpenerOptions())
 	if err != nil {
 		message := ""
 		if o.GCSCredentialsFile != "" {
@@ -68,6 +92,9 @@ func (o *StorageClientOptions) StorageClient(ctx context.Context) (io.Opener, er
 		if o.S3CredentialsFile != "" {
 			message = fmt.Sprintf("%s s3-credentials-file: %s", message, o.S3CredentialsFile)
 		}
+		if o.AzureCredentialsFile != "" {
+			message = fmt.Sprintf("%s azure-credentials-file: %s", message, o.AzureCredentialsFile)
+		}
 		return opener, fmt.Errorf("error creating opener%s: %w", message, err)
 	}
 	return opener, nil
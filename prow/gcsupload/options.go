@@ -52,6 +52,9 @@ type Options struct {
 
 	DryRun bool `json:"dry_run"`
 
+	// ArtifactUploads are policies for uploading the files in Items.
+	ArtifactUploads *prowapi.ArtifactUploads `json:"artifact_uploads,omitempty"`
+
 	// mediaTypes holds additional extension media types to add to Go's
 	// builtin's and the local system's defaults.  Values are
 	// colon-delimited {extension}:{media-type}, for example:
@@ -67,6 +70,12 @@ type Options struct {
 // Validate ensures that the set of options are
 // self-consistent and valid.
 func (o *Options) Validate() error {
+	if o.ArtifactUploads != nil {
+		if err := o.ArtifactUploads.Validate(); err != nil {
+			return fmt.Errorf("artifact upload policies are invalid: %w", err)
+		}
+	}
+
 	if o.LocalOutputDir != "" {
 		return nil
 	}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcsupload
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-zglob"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	pkgio "k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+)
+
+// skippedSuffix is appended to the destination of an artifact that is
+// skipped for its size to get the destination of the marker.
+const skippedSuffix = ".skipped.txt"
+
+// compressedExtensions are the extensions of compressed formats, which
+// are not worth compressing again.
+var compressedExtensions = sets.NewString(".gz", ".tgz", ".bz2", ".xz", ".zst", ".zip", ".jar", ".png", ".jpg", ".jpeg", ".gif", ".webp")
+
+// shouldUpload determines whether the artifact at the path relative to
+// the artifact directory matches the artifact upload globs.
+func (o Options) shouldUpload(relPath string) (bool, error) {
+	if o.ArtifactUploads == nil {
+		return true, nil
+	}
+	for _, glob := range o.ArtifactUploads.Exclude {
+		found, err := zglob.Match(glob, relPath)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return false, nil // when explicitly excluded, do not upload
+		}
+	}
+	for _, glob := range o.ArtifactUploads.Include {
+		found, err := zglob.Match(glob, relPath)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return true, nil // when explicitly included, upload
+		}
+	}
+	return len(o.ArtifactUploads.Include) == 0, nil // upload if no explicit includes exist
+}
+
+// addArtifact adds the upload of the file to the upload targets, which
+// compresses, truncates or skips it as the artifact upload policies
+// prescribe.
+func (o Options) addArtifact(uploadTargets map[string]gcs.UploadFunc, destination, file string, size int64, writerOptions pkgio.WriterOptions) {
+	policy := o.ArtifactUploads
+	if policy == nil {
+		uploadTargets[destination] = gcs.FileUploadWithOptions(file, writerOptions)
+		return
+	}
+	log := logrus.WithField("dest", destination)
+
+	upload := gcs.FileUploadWithOptions(file, writerOptions)
+	if policy.MaxBytes != nil && size > *policy.MaxBytes {
+		switch policy.Oversized {
+		case prowapi.OversizedArtifactTruncate:
+			log.Warnf("Truncating %s of %d bytes to the size cap of %d bytes", file, size, *policy.MaxBytes)
+			marker := fmt.Sprintf("\n[truncated: only the first %d of %d bytes were uploaded]\n", *policy.MaxBytes, size)
+			upload = gcs.TruncatedFileUploadWithOptions(file, *policy.MaxBytes, marker, writerOptions)
+			size = *policy.MaxBytes
+		default:
+			log.Warnf("Skipping %s of %d bytes above the size cap of %d bytes", file, size, *policy.MaxBytes)
+			marker := fmt.Sprintf("%s was not uploaded as its size of %d bytes is above the size cap of %d bytes.\n", path.Base(destination), size, *policy.MaxBytes)
+			markerDestination, markerOptions := gcs.WriterOptionsFromFileName(destination + skippedSuffix)
+			uploadTargets[markerDestination] = gcs.DataUploadWithOptions(strings.NewReader(marker), markerOptions)
+			return
+		}
+	}
+
+	// Objects are only decompressed on download from blob storage, so
+	// local copies are never compressed.
+	if policy.GzipAboveBytes != nil && size > *policy.GzipAboveBytes && o.LocalOutputDir == "" &&
+		writerOptions.ContentEncoding == nil && !compressedExtensions.Has(strings.ToLower(filepath.Ext(file))) {
+		upload = gcs.GzipUpload(upload)
+	}
+	uploadTargets[destination] = upload
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcsupload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	utilpointer "k8s.io/utils/pointer"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	pkgio "k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+)
+
+func TestOptions_ShouldUpload(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		policy   *prowapi.ArtifactUploads
+		path     string
+		expected bool
+	}{
+		{
+			name:     "no policy",
+			path:     "core.1234",
+			expected: true,
+		},
+		{
+			name:     "no globs",
+			policy:   &prowapi.ArtifactUploads{},
+			path:     "core.1234",
+			expected: true,
+		},
+		{
+			name:     "excluded",
+			policy:   &prowapi.ArtifactUploads{Exclude: []string{"**/core.*"}},
+			path:     "e2e/node/core.1234",
+			expected: false,
+		},
+		{
+			name:     "not excluded",
+			policy:   &prowapi.ArtifactUploads{Exclude: []string{"**/core.*"}},
+			path:     "e2e/node/kubelet.log",
+			expected: true,
+		},
+		{
+			name:     "included",
+			policy:   &prowapi.ArtifactUploads{Include: []string{"junit_*.xml", "logs/**/*"}},
+			path:     "logs/node/kubelet.log",
+			expected: true,
+		},
+		{
+			name:     "not included",
+			policy:   &prowapi.ArtifactUploads{Include: []string{"junit_*.xml", "logs/**/*"}},
+			path:     "tmp/cache.bin",
+			expected: false,
+		},
+		{
+			name:     "excluded even though included",
+			policy:   &prowapi.ArtifactUploads{Include: []string{"logs/**/*"}, Exclude: []string{"logs/huge/**/*"}},
+			path:     "logs/huge/dump.log",
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := Options{ArtifactUploads: testCase.policy}.shouldUpload(testCase.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != testCase.expected {
+				t.Errorf("expected %t, got %t", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+type fakeDataWriter struct {
+	bytes.Buffer
+	opts pkgio.WriterOptions
+}
+
+func (w *fakeDataWriter) Close() error {
+	return nil
+}
+
+func (w *fakeDataWriter) ApplyWriterOptions(opts pkgio.WriterOptions) {
+	opts.Apply(&w.opts)
+}
+
+type uploaded struct {
+	content string
+	gzipped bool
+}
+
+func TestOptions_AddArtifact(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		options  Options
+		file     string
+		content  string
+		expected map[string]uploaded
+	}{
+		{
+			name:     "no policy",
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "0123456789"}},
+		},
+		{
+			name:     "compressed above the threshold",
+			options:  Options{ArtifactUploads: &prowapi.ArtifactUploads{GzipAboveBytes: utilpointer.Int64Ptr(5)}},
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "0123456789", gzipped: true}},
+		},
+		{
+			name:     "not compressed below the threshold",
+			options:  Options{ArtifactUploads: &prowapi.ArtifactUploads{GzipAboveBytes: utilpointer.Int64Ptr(10)}},
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "0123456789"}},
+		},
+		{
+			name:     "compressed formats are not compressed again",
+			options:  Options{ArtifactUploads: &prowapi.ArtifactUploads{GzipAboveBytes: utilpointer.Int64Ptr(5)}},
+			file:     "screenshot.png",
+			content:  "0123456789",
+			expected: map[string]uploaded{"screenshot.png": {content: "0123456789"}},
+		},
+		{
+			name: "not compressed when copied locally",
+			options: Options{
+				GCSConfiguration: &prowapi.GCSConfiguration{LocalOutputDir: "/output"},
+				ArtifactUploads:  &prowapi.ArtifactUploads{GzipAboveBytes: utilpointer.Int64Ptr(5)},
+			},
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "0123456789"}},
+		},
+		{
+			name:    "skipped above the size cap",
+			options: Options{ArtifactUploads: &prowapi.ArtifactUploads{MaxBytes: utilpointer.Int64Ptr(5)}},
+			file:    "core",
+			content: "0123456789",
+			expected: map[string]uploaded{
+				"core.skipped.txt": {content: "core was not uploaded as its size of 10 bytes is above the size cap of 5 bytes.\n"},
+			},
+		},
+		{
+			name:     "not skipped at the size cap",
+			options:  Options{ArtifactUploads: &prowapi.ArtifactUploads{MaxBytes: utilpointer.Int64Ptr(10)}},
+			file:     "core",
+			content:  "0123456789",
+			expected: map[string]uploaded{"core": {content: "0123456789"}},
+		},
+		{
+			name:     "truncated above the size cap",
+			options:  Options{ArtifactUploads: &prowapi.ArtifactUploads{MaxBytes: utilpointer.Int64Ptr(5), Oversized: prowapi.OversizedArtifactTruncate}},
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "01234\n[truncated: only the first 5 of 10 bytes were uploaded]\n"}},
+		},
+		{
+			name: "truncated and compressed",
+			options: Options{ArtifactUploads: &prowapi.ArtifactUploads{
+				GzipAboveBytes: utilpointer.Int64Ptr(3),
+				MaxBytes:       utilpointer.Int64Ptr(5),
+				Oversized:      prowapi.OversizedArtifactTruncate,
+			}},
+			file:     "build.log",
+			content:  "0123456789",
+			expected: map[string]uploaded{"build.log": {content: "01234\n[truncated: only the first 5 of 10 bytes were uploaded]\n", gzipped: true}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.options.GCSConfiguration == nil {
+				testCase.options.GCSConfiguration = &prowapi.GCSConfiguration{}
+			}
+			file := path.Join(t.TempDir(), testCase.file)
+			if err := ioutil.WriteFile(file, []byte(testCase.content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			destination, writerOptions := gcs.WriterOptionsFromFileName(testCase.file)
+			uploadTargets := map[string]gcs.UploadFunc{}
+			testCase.options.addArtifact(uploadTargets, destination, file, int64(len(testCase.content)), writerOptions)
+
+			actual := map[string]uploaded{}
+			for destination, upload := range uploadTargets {
+				writer := &fakeDataWriter{}
+				if err := upload(writer); err != nil {
+					t.Fatalf("failed to upload %s: %v", destination, err)
+				}
+				var u uploaded
+				content := writer.Bytes()
+				if writer.opts.ContentEncoding != nil && *writer.opts.ContentEncoding == "gzip" {
+					u.gzipped = true
+					reader, err := gzip.NewReader(&writer.Buffer)
+					if err != nil {
+						t.Fatalf("failed to read gzip header of %s: %v", destination, err)
+					}
+					if content, err = ioutil.ReadAll(reader); err != nil {
+						t.Fatalf("failed to decompress %s: %v", destination, err)
+					}
+				}
+				u.content = string(content)
+				actual[destination] = u
+			}
+			if diff := cmp.Diff(testCase.expected, actual, cmp.AllowUnexported(uploaded{})); diff != "" {
+				t.Errorf("unexpected uploads (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -72,7 +72,7 @@ func completeUpload(ctx context.Context, o Options, uploadTargets map[string]gcs
 	}
 
 	if o.LocalOutputDir == "" {
-		if err := gcs.Upload(ctx, o.Bucket, o.StorageClientOptions.GCSCredentialsFile, o.StorageClientOptions.S3CredentialsFile, uploadTargets); err != nil {
+		if err := gcs.Upload(ctx, o.Bucket, o.StorageClientOptions.OpenerOptions(), uploadTargets); err != nil {
 			return fmt.Errorf("failed to upload to blob storage: %w", err)
 		}
 		logrus.Info("Finished upload to blob storage")
@@ -131,7 +131,7 @@ func (o Options) assembleTargets(spec *downwardapi.JobSpec, extra map[string]gcs
 			continue
 		}
 		if info.IsDir() {
-			gatherArtifacts(item, blobStoragePath, info.Name(), uploadTargets)
+			o.gatherArtifacts(item, blobStoragePath, info.Name(), uploadTargets)
 		} else {
 			metadataFromFileName, writerOptions := gcs.WriterOptionsFromFileName(info.Name())
 			destination := path.Join(blobStoragePath, metadataFromFileName)
@@ -139,7 +139,7 @@ func (o Options) assembleTargets(spec *downwardapi.JobSpec, extra map[string]gcs
 				logrus.Warnf("Encountered duplicate upload of %s, skipping...", destination)
 				continue
 			}
-			uploadTargets[destination] = gcs.FileUploadWithOptions(item, writerOptions)
+			o.addArtifact(uploadTargets, destination, item, info.Size(), writerOptions)
 		}
 	}
 
@@ -190,7 +190,7 @@ func builderForStrategy(strategy, defaultOrg, defaultRepo string) gcs.RepoPathBu
 	return builder
 }
 
-func gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets map[string]gcs.UploadFunc) {
+func (o Options) gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets map[string]gcs.UploadFunc) {
 	logrus.Printf("Gathering artifacts from artifact directory: %s", artifactDir)
 	filepath.Walk(artifactDir, func(fspath string, info os.FileInfo, err error) error {
 		if info == nil || info.IsDir() {
@@ -202,6 +202,12 @@ func gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets
 		// this error as we can be certain it won't occur and best-
 		// effort upload is OK in any case
 		if relPath, err := filepath.Rel(artifactDir, fspath); err == nil {
+			if upload, err := o.shouldUpload(filepath.ToSlash(relPath)); err != nil {
+				logrus.Warnf("Encountered error in matching %s against the artifact upload globs: %v", fspath, err)
+			} else if !upload {
+				logrus.Printf("Found %s in artifact directory. Not uploading it as the artifact upload globs exclude it\n", fspath)
+				return nil
+			}
 			dir, filename := path.Split(path.Join(blobStoragePath, subDir, relPath))
 			metadataFromFileName, writerOptions := gcs.WriterOptionsFromFileName(filename)
 			destination := escapeFileName(path.Join(dir, metadataFromFileName))
@@ -210,7 +216,7 @@ func gatherArtifacts(artifactDir, blobStoragePath, subDir string, uploadTargets
 				return nil
 			}
 			logrus.Printf("Found %s in artifact directory. Uploading as %s\n", fspath, destination)
-			uploadTargets[destination] = gcs.FileUploadWithOptions(fspath, writerOptions)
+			o.addArtifact(uploadTargets, destination, fspath, info.Size(), writerOptions)
 		} else {
 			logrus.Warnf("Encountered error in relative path calculation for %s under %s: %v", fspath, artifactDir, err)
 		}
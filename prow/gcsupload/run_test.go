@@ -157,6 +157,25 @@ func TestOptions_AssembleTargets(t *testing.T) {
 				"pr-logs/pull/org_repo/1/job/latest-build.txt",
 			},
 		},
+		{
+			name:    "artifacts excluded by the upload policies should not be uploaded",
+			jobType: prowapi.PresubmitJob,
+			options: Options{
+				Items: []string{"something"},
+				GCSConfiguration: &prowapi.GCSConfiguration{
+					PathStrategy: prowapi.PathStrategyExplicit,
+					Bucket:       "bucket",
+				},
+				ArtifactUploads: &prowapi.ArtifactUploads{Exclude: []string{"**/core.*"}},
+			},
+			paths: []string{"something/", "something/else", "something/nested/", "something/nested/core.1234"},
+			expected: []string{
+				"pr-logs/pull/org_repo/1/job/build/something/else",
+				"pr-logs/directory/job/build.txt",
+				"pr-logs/directory/job/latest-build.txt",
+				"pr-logs/pull/org_repo/1/job/latest-build.txt",
+			},
+		},
 		{
 			name:    "only job dir files should be output in local mode",
 			jobType: prowapi.PresubmitJob,
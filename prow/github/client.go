@@ -90,6 +90,19 @@ type HookClient interface {
 	AcceptUserOrgInvitation(org string) error
 }
 
+// HookDeliveryClient interface for the deliveries of webhooks
+type HookDeliveryClient interface {
+	ListRepoHookDeliveries(org, repo string, id int, since time.Time) ([]HookDelivery, error)
+	ListOrgHookDeliveries(org string, id int, since time.Time) ([]HookDelivery, error)
+	ListAppHookDeliveries(since time.Time) ([]HookDelivery, error)
+	GetRepoHookDelivery(org, repo string, id int, deliveryID int64) (*HookDelivery, error)
+	GetOrgHookDelivery(org string, id int, deliveryID int64) (*HookDelivery, error)
+	GetAppHookDelivery(deliveryID int64) (*HookDelivery, error)
+	RedeliverRepoHookDelivery(org, repo string, id int, deliveryID int64) error
+	RedeliverOrgHookDelivery(org string, id int, deliveryID int64) error
+	RedeliverAppHookDelivery(deliveryID int64) error
+}
+
 // CommentClient interface for comment related API actions
 type CommentClient interface {
 	CreateComment(org, repo string, number int, comment string) error
@@ -152,6 +165,9 @@ type CommitClient interface {
 	GetSingleCommit(org, repo, SHA string) (RepositoryCommit, error)
 	GetCombinedStatus(org, repo, ref string) (*CombinedStatus, error)
 	ListCheckRuns(org, repo, ref string) (*CheckRunList, error)
+	ListCheckRunsByNameWithContext(ctx context.Context, org, repo, ref, name string) ([]CheckRun, error)
+	CreateCheckRunWithContext(ctx context.Context, org, repo string, checkRun CheckRun) (int64, error)
+	UpdateCheckRunWithContext(ctx context.Context, org, repo string, id int64, checkRun CheckRun) error
 	GetRef(org, repo, ref string) (string, error)
 	DeleteRef(org, repo, ref string) error
 	ListFileCommits(org, repo, path string) ([]RepositoryCommit, error)
@@ -261,6 +277,7 @@ type Client interface {
 	MilestoneClient
 	UserClient
 	HookClient
+	HookDeliveryClient
 	ListAppInstallations() ([]AppInstallation, error)
 	GetApp() (*App, error)
 	GetAppWithContext(ctx context.Context) (*App, error)
@@ -275,6 +292,7 @@ type Client interface {
 	WithFields(fields logrus.Fields) Client
 	ForPlugin(plugin string) Client
 	ForSubcomponent(subcomponent string) Client
+	WithRequestGate(gate func() error) Client
 	TriggerGitHubWorkflow(org, repo string, id int) error
 }
 
@@ -287,6 +305,9 @@ type client struct {
 	// identifier is used to add more identification to the user-agent header
 	identifier string
 	gqlc       gqlClient
+	// If gate is non-nil, it is called before every request to GitHub and
+	// the request fails with its error instead of being sent.
+	gate func() error
 	*delegate
 }
 
@@ -334,6 +355,7 @@ func (c *client) forKeyValue(key, value string) Client {
 	newClient := &client{
 		identifier: value,
 		logger:     c.logger.WithField(key, value),
+		gate:       c.gate,
 		delegate:   c.delegate,
 	}
 	newClient.gqlc = c.gqlc.forUserAgent(newClient.userAgent())
@@ -354,10 +376,33 @@ func (c *client) WithFields(fields logrus.Fields) Client {
 		logger:     c.logger.WithFields(fields),
 		identifier: c.identifier,
 		gqlc:       c.gqlc,
+		gate:       c.gate,
+		delegate:   c.delegate,
+	}
+}
+
+// WithRequestGate clones the client, keeping the underlying delegate the same but
+// calling gate before every request to GitHub. Requests fail with the error of the
+// gate if it returns one, which allows to enforce deadlines and budgets on the
+// requests of a single consumer of the shared delegate.
+func (c *client) WithRequestGate(gate func() error) Client {
+	return &client{
+		logger:     c.logger,
+		identifier: c.identifier,
+		gqlc:       c.gqlc,
+		gate:       gate,
 		delegate:   c.delegate,
 	}
 }
 
+// passGate calls the request gate of the client, if it has one.
+func (c *client) passGate() error {
+	if c.gate == nil {
+		return nil
+	}
+	return c.gate()
+}
+
 var (
 	teamRe = regexp.MustCompile(`^(.*)/(.*)$`)
 )
@@ -1027,6 +1072,9 @@ func (c *client) requestRetry(method, path, accept, org string, body interface{}
 }
 
 func (c *client) requestRetryWithContext(ctx context.Context, method, path, accept, org string, body interface{}) (*http.Response, error) {
+	if err := c.passGate(); err != nil {
+		return nil, err
+	}
 	var hostIndex int
 	var resp *http.Response
 	var err error
@@ -1502,6 +1550,127 @@ func (c *client) DeleteOrgHook(org string, id int, req HookRequest) error {
 	return c.deleteHook(org, path)
 }
 
+// listHookDeliveries returns the deliveries of the webhook with the given
+// deliveries path that were delivered after since, newest first.
+func (c *client) listHookDeliveries(org, path string, since time.Time) ([]HookDelivery, error) {
+	var ret []HookDelivery
+	err := c.readPaginatedResultsWhile(
+		context.Background(),
+		path,
+		url.Values{"per_page": []string{"100"}},
+		acceptNone,
+		org,
+		func() interface{} {
+			return &[]HookDelivery{}
+		},
+		func(obj interface{}) bool {
+			// Deliveries are listed newest first, so the remaining pages
+			// are older than since once a delivery is.
+			for _, d := range *(obj.(*[]HookDelivery)) {
+				if d.DeliveredAt.Before(since) {
+					return false
+				}
+				ret = append(ret, d)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListRepoHookDeliveries returns the deliveries of a repo webhook since the given time.
+// https://docs.github.com/en/rest/webhooks/repo-deliveries#list-deliveries-for-a-repository-webhook
+func (c *client) ListRepoHookDeliveries(org, repo string, id int, since time.Time) ([]HookDelivery, error) {
+	c.log("ListRepoHookDeliveries", org, repo, id, since)
+	return c.listHookDeliveries(org, fmt.Sprintf("/repos/%s/%s/hooks/%d/deliveries", org, repo, id), since)
+}
+
+// ListOrgHookDeliveries returns the deliveries of an org webhook since the given time.
+// https://docs.github.com/en/rest/orgs/webhooks#list-deliveries-for-an-organization-webhook
+func (c *client) ListOrgHookDeliveries(org string, id int, since time.Time) ([]HookDelivery, error) {
+	c.log("ListOrgHookDeliveries", org, id, since)
+	return c.listHookDeliveries(org, fmt.Sprintf("/orgs/%s/hooks/%d/deliveries", org, id), since)
+}
+
+// ListAppHookDeliveries returns the deliveries of the webhook of the GitHub App since the given time.
+// https://docs.github.com/en/rest/apps/webhooks#list-deliveries-for-an-app-webhook
+func (c *client) ListAppHookDeliveries(since time.Time) ([]HookDelivery, error) {
+	c.log("ListAppHookDeliveries", since)
+	return c.listHookDeliveries("", "/app/hook/deliveries", since)
+}
+
+func (c *client) getHookDelivery(org, path string) (*HookDelivery, error) {
+	var ret HookDelivery
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      path,
+		org:       org,
+		exitCodes: []int{200},
+	}, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// GetRepoHookDelivery returns a delivery of a repo webhook including its request and response.
+// https://docs.github.com/en/rest/webhooks/repo-deliveries#get-a-delivery-for-a-repository-webhook
+func (c *client) GetRepoHookDelivery(org, repo string, id int, deliveryID int64) (*HookDelivery, error) {
+	c.log("GetRepoHookDelivery", org, repo, id, deliveryID)
+	return c.getHookDelivery(org, fmt.Sprintf("/repos/%s/%s/hooks/%d/deliveries/%d", org, repo, id, deliveryID))
+}
+
+// GetOrgHookDelivery returns a delivery of an org webhook including its request and response.
+// https://docs.github.com/en/rest/orgs/webhooks#get-a-webhook-delivery-for-an-organization-webhook
+func (c *client) GetOrgHookDelivery(org string, id int, deliveryID int64) (*HookDelivery, error) {
+	c.log("GetOrgHookDelivery", org, id, deliveryID)
+	return c.getHookDelivery(org, fmt.Sprintf("/orgs/%s/hooks/%d/deliveries/%d", org, id, deliveryID))
+}
+
+// GetAppHookDelivery returns a delivery of the webhook of the GitHub App including its request and response.
+// https://docs.github.com/en/rest/apps/webhooks#get-a-delivery-for-an-app-webhook
+func (c *client) GetAppHookDelivery(deliveryID int64) (*HookDelivery, error) {
+	c.log("GetAppHookDelivery", deliveryID)
+	return c.getHookDelivery("", fmt.Sprintf("/app/hook/deliveries/%d", deliveryID))
+}
+
+func (c *client) redeliverHookDelivery(org, path string) error {
+	if c.dry {
+		return nil
+	}
+	_, err := c.request(&request{
+		method:    http.MethodPost,
+		path:      path,
+		org:       org,
+		exitCodes: []int{202},
+	}, nil)
+	return err
+}
+
+// RedeliverRepoHookDelivery asks GitHub to deliver a delivery of a repo webhook again.
+// https://docs.github.com/en/rest/webhooks/repo-deliveries#redeliver-a-delivery-for-a-repository-webhook
+func (c *client) RedeliverRepoHookDelivery(org, repo string, id int, deliveryID int64) error {
+	c.log("RedeliverRepoHookDelivery", org, repo, id, deliveryID)
+	return c.redeliverHookDelivery(org, fmt.Sprintf("/repos/%s/%s/hooks/%d/deliveries/%d/attempts", org, repo, id, deliveryID))
+}
+
+// RedeliverOrgHookDelivery asks GitHub to deliver a delivery of an org webhook again.
+// https://docs.github.com/en/rest/orgs/webhooks#redeliver-a-delivery-for-an-organization-webhook
+func (c *client) RedeliverOrgHookDelivery(org string, id int, deliveryID int64) error {
+	c.log("RedeliverOrgHookDelivery", org, id, deliveryID)
+	return c.redeliverHookDelivery(org, fmt.Sprintf("/orgs/%s/hooks/%d/deliveries/%d/attempts", org, id, deliveryID))
+}
+
+// RedeliverAppHookDelivery asks GitHub to deliver a delivery of the webhook of the GitHub App again.
+// https://docs.github.com/en/rest/apps/webhooks#redeliver-a-delivery-for-an-app-webhook
+func (c *client) RedeliverAppHookDelivery(deliveryID int64) error {
+	c.log("RedeliverAppHookDelivery", deliveryID)
+	return c.redeliverHookDelivery("", fmt.Sprintf("/app/hook/deliveries/%d/attempts", deliveryID))
+}
+
 // GetOrg returns current metadata for the org
 //
 // https://developer.github.com/v3/orgs/#get-an-organization
@@ -1960,6 +2129,15 @@ func (c *client) readPaginatedResultsWithValues(path string, values url.Values,
 }
 
 func (c *client) readPaginatedResultsWithValuesWithContext(ctx context.Context, path string, values url.Values, accept, org string, newObj func() interface{}, accumulate func(interface{})) error {
+	return c.readPaginatedResultsWhile(ctx, path, values, accept, org, newObj, func(obj interface{}) bool {
+		accumulate(obj)
+		return true
+	})
+}
+
+// readPaginatedResultsWhile reads pages until there is no next page or
+// accumulate returns false.
+func (c *client) readPaginatedResultsWhile(ctx context.Context, path string, values url.Values, accept, org string, newObj func() interface{}, accumulate func(interface{}) bool) error {
 	pagedPath := path
 	if len(values) > 0 {
 		pagedPath += "?" + values.Encode()
@@ -1984,7 +2162,9 @@ func (c *client) readPaginatedResultsWithValuesWithContext(ctx context.Context,
 			return err
 		}
 
-		accumulate(obj)
+		if !accumulate(obj) {
+			break
+		}
 
 		link := parseLinks(resp.Header.Get("Link"))["next"]
 		if link == "" {
@@ -3611,11 +3791,17 @@ func (c *client) GetFile(org, repo, filepath, commit string) ([]byte, error) {
 func (c *client) QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error {
 	// Don't log query here because Query is typically called multiple times to get all pages.
 	// Instead log once per search and include total search cost.
+	if err := c.passGate(); err != nil {
+		return err
+	}
 	return c.gqlc.QueryWithGitHubAppsSupport(ctx, q, vars, org)
 }
 
 // MutateWithGitHubAppsSupport runs a GraphQL mutation using shurcooL/githubql's client.
 func (c *client) MutateWithGitHubAppsSupport(ctx context.Context, m interface{}, input githubql.Input, vars map[string]interface{}, org string) error {
+	if err := c.passGate(); err != nil {
+		return err
+	}
 	return c.gqlc.MutateWithGitHubAppsSupport(ctx, m, input, vars, org)
 }
 
@@ -4701,6 +4887,108 @@ func (c *client) ListCheckRuns(org, repo, ref string) (*CheckRunList, error) {
 	return &checkRunList, nil
 }
 
+// ListCheckRunsByNameWithContext lists all check runs with the given name for
+// the given ref, including the ones that were superseded by newer check runs.
+//
+// See https://docs.github.com/en/rest/checks/runs#list-check-runs-for-a-git-reference
+func (c *client) ListCheckRunsByNameWithContext(ctx context.Context, org, repo, ref, name string) ([]CheckRun, error) {
+	durationLogger := c.log("ListCheckRunsByName", org, repo, ref, name)
+	defer durationLogger()
+
+	query := url.Values{
+		"check_name": []string{name},
+		"filter":     []string{"all"},
+		"per_page":   []string{"100"},
+	}
+	var checkRunList CheckRunList
+	_, err := c.requestWithContext(ctx, &request{
+		accept:    "application/vnd.github.antiope-preview+json",
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?%s", org, repo, ref, query.Encode()),
+		org:       org,
+		exitCodes: []int{200},
+	}, &checkRunList)
+	if err != nil {
+		return nil, err
+	}
+	return checkRunList.CheckRuns, nil
+}
+
+// checkRunRequest holds the fields of a check run that can be set when
+// creating or updating it.
+type checkRunRequest struct {
+	Name        string           `json:"name,omitempty"`
+	HeadSHA     string           `json:"head_sha,omitempty"`
+	DetailsURL  string           `json:"details_url,omitempty"`
+	ExternalID  string           `json:"external_id,omitempty"`
+	Status      string           `json:"status,omitempty"`
+	StartedAt   string           `json:"started_at,omitempty"`
+	Conclusion  string           `json:"conclusion,omitempty"`
+	CompletedAt string           `json:"completed_at,omitempty"`
+	Output      *CheckRunOutput  `json:"output,omitempty"`
+	Actions     []CheckRunAction `json:"actions,omitempty"`
+}
+
+func newCheckRunRequest(checkRun CheckRun) *checkRunRequest {
+	req := &checkRunRequest{
+		Name:        checkRun.Name,
+		HeadSHA:     checkRun.HeadSHA,
+		DetailsURL:  checkRun.DetailsURL,
+		ExternalID:  checkRun.ExternalID,
+		Status:      checkRun.Status,
+		StartedAt:   checkRun.StartedAt,
+		Conclusion:  checkRun.Conclusion,
+		CompletedAt: checkRun.CompletedAt,
+		Actions:     checkRun.Actions,
+	}
+	if checkRun.Output.Title != "" || checkRun.Output.Summary != "" {
+		req.Output = &checkRun.Output
+	}
+	return req
+}
+
+// CreateCheckRunWithContext creates a check run and returns its ID. Only
+// GitHub Apps can create check runs.
+//
+// See https://docs.github.com/en/rest/checks/runs#create-a-check-run
+func (c *client) CreateCheckRunWithContext(ctx context.Context, org, repo string, checkRun CheckRun) (int64, error) {
+	durationLogger := c.log("CreateCheckRun", org, repo, checkRun.Name)
+	defer durationLogger()
+
+	var created CheckRun
+	_, err := c.requestWithContext(ctx, &request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs", org, repo),
+		org:         org,
+		requestBody: newCheckRunRequest(checkRun),
+		exitCodes:   []int{201},
+	}, &created)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// UpdateCheckRunWithContext updates the check run with the given ID. The head
+// SHA of a check run can not be changed.
+//
+// See https://docs.github.com/en/rest/checks/runs#update-a-check-run
+func (c *client) UpdateCheckRunWithContext(ctx context.Context, org, repo string, id int64, checkRun CheckRun) error {
+	durationLogger := c.log("UpdateCheckRun", org, repo, id)
+	defer durationLogger()
+
+	req := newCheckRunRequest(checkRun)
+	req.HeadSHA = ""
+	_, err := c.requestWithContext(ctx, &request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("/repos/%s/%s/check-runs/%d", org, repo, id),
+		org:         org,
+		requestBody: req,
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
 // ListAppInstallations lists the installations for the current app. Will not work with
 // a Personal Access Token.
 //
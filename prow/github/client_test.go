@@ -149,6 +149,34 @@ func TestRetry404(t *testing.T) {
 	}
 }
 
+func TestRequestGate(t *testing.T) {
+	var requests int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"number": 1}`)
+	}))
+	defer ts.Close()
+	closed := errors.New("gate closed")
+	open := true
+	c := getClient(ts.URL).WithRequestGate(func() error {
+		if !open {
+			return closed
+		}
+		return nil
+	}).WithFields(logrus.Fields{"plugin": "plugin"})
+
+	if _, err := c.GetIssue("org", "repo", 1); err != nil {
+		t.Fatalf("Expected the request to pass the open gate, got %v", err)
+	}
+	open = false
+	if _, err := c.GetIssue("org", "repo", 1); !errors.Is(err, closed) {
+		t.Errorf("Expected the closed gate to fail the request, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single request to reach GitHub, got %d", requests)
+	}
+}
+
 func TestIncorrectOAuthScopes(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -3714,3 +3742,54 @@ func TestIsAppInstalled(t *testing.T) {
 		})
 	}
 }
+
+func TestListRepoHookDeliveries(t *testing.T) {
+	since := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		var deliveries []HookDelivery
+		switch r.URL.Path {
+		case "/repos/k8s/kuber/hooks/1/deliveries":
+			deliveries = []HookDelivery{{ID: 3, DeliveredAt: since.Add(time.Hour)}, {ID: 2, DeliveredAt: since.Add(time.Minute)}}
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+		case "/someotherpath":
+			deliveries = []HookDelivery{{ID: 1, DeliveredAt: since.Add(-time.Minute)}}
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/lastpath>; rel="next"`, r.Host))
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := json.Marshal(deliveries)
+		if err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	deliveries, err := c.ListRepoHookDeliveries("k8s", "kuber", 1, since)
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != 3 || deliveries[1].ID != 2 {
+		t.Errorf("Expected the deliveries since %s, got %v", since, deliveries)
+	}
+}
+
+func TestRedeliverAppHookDelivery(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/app/hook/deliveries/12/attempts" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.RedeliverAppHookDelivery(12); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
@@ -61,6 +61,9 @@ type FakeClient struct {
 	IssueEvents                map[int][]github.ListedIssueEvent
 	Commits                    map[string]github.RepositoryCommit
 
+	// org/repo -> check runs, IDs are 1-based indices
+	CheckRuns map[string][]github.CheckRun
+
 	// All Labels That Exist In The Repo
 	RepoLabelsExisting []string
 	// org/repo#number:label
@@ -522,6 +525,55 @@ func (f *FakeClient) CreateStatusWithContext(_ context.Context, owner, repo, SHA
 	return nil
 }
 
+// ListCheckRunsByNameWithContext returns the check runs with the given name on a commit.
+func (f *FakeClient) ListCheckRunsByNameWithContext(_ context.Context, org, repo, ref, name string) ([]github.CheckRun, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if f.Error != nil {
+		return nil, f.Error
+	}
+	var checkRuns []github.CheckRun
+	for _, checkRun := range f.CheckRuns[org+"/"+repo] {
+		if checkRun.HeadSHA == ref && checkRun.Name == name {
+			checkRuns = append(checkRuns, checkRun)
+		}
+	}
+	return checkRuns, nil
+}
+
+// CreateCheckRunWithContext adds a check run.
+func (f *FakeClient) CreateCheckRunWithContext(_ context.Context, org, repo string, checkRun github.CheckRun) (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.Error != nil {
+		return 0, f.Error
+	}
+	if f.CheckRuns == nil {
+		f.CheckRuns = make(map[string][]github.CheckRun)
+	}
+	key := org + "/" + repo
+	checkRun.ID = int64(len(f.CheckRuns[key]) + 1)
+	f.CheckRuns[key] = append(f.CheckRuns[key], checkRun)
+	return checkRun.ID, nil
+}
+
+// UpdateCheckRunWithContext replaces a check run, keeping its head SHA.
+func (f *FakeClient) UpdateCheckRunWithContext(_ context.Context, org, repo string, id int64, checkRun github.CheckRun) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.Error != nil {
+		return f.Error
+	}
+	checkRuns := f.CheckRuns[org+"/"+repo]
+	if id < 1 || int(id) > len(checkRuns) {
+		return fmt.Errorf("check run %d not found in %s/%s", id, org, repo)
+	}
+	checkRun.ID = id
+	checkRun.HeadSHA = checkRuns[id-1].HeadSHA
+	checkRuns[id-1] = checkRun
+	return nil
+}
+
 // ListStatuses returns individual status contexts on a commit.
 func (f *FakeClient) ListStatuses(org, repo, ref string) ([]github.Status, error) {
 	f.lock.RLock()
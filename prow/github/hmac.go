@@ -86,6 +86,27 @@ func PayloadSignature(payload []byte, key []byte) string {
 	return "sha1=" + hex.EncodeToString(sum)
 }
 
+// SignPayload returns a signature of the payload that ValidatePayload accepts
+// with the same tokens, e.g. to resend a webhook to hook.
+func SignPayload(payload []byte, tokenGenerator func() []byte) (string, error) {
+	var event GenericEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the github event payload: %w", err)
+	}
+	orgRepo := event.Repo.FullName
+	if orgRepo == "" {
+		orgRepo = event.Org.Login
+	}
+	hmacs, err := extractHMACs(orgRepo, tokenGenerator)
+	if err != nil {
+		return "", err
+	}
+	if len(hmacs) == 0 {
+		return "", fmt.Errorf("no hmac is configured for the org/repo %q", orgRepo)
+	}
+	return PayloadSignature(payload, hmacs[0]), nil
+}
+
 // extractHMACs returns all *valid* HMAC tokens for given repository/organization.
 // It considers only the tokens at the most specific level configured for the given repo.
 // For example : if a token for repo is present and it doesn't match the repo, we will
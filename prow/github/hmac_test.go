@@ -101,3 +101,22 @@ func TestValidatePayload(t *testing.T) {
 		}
 	}
 }
+
+func TestSignPayload(t *testing.T) {
+	for _, payload := range []string{
+		`{}`,
+		`{"repository": {"full_name": "org2/repo"}}`,
+		`{"organization": {"login": "org1"}}`,
+	} {
+		sig, err := SignPayload([]byte(payload), defaultTokenGenerator)
+		if err != nil {
+			t.Fatalf("failed to sign %s: %v", payload, err)
+		}
+		if !ValidatePayload([]byte(payload), sig, defaultTokenGenerator) {
+			t.Errorf("expected the signature of %s to be valid", payload)
+		}
+	}
+	if _, err := SignPayload([]byte("not json"), defaultTokenGenerator); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
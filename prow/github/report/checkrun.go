@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+const (
+	// CheckRunRerunAction is the identifier of the check run action that
+	// re-runs the ProwJob whose name is the external ID of the check run.
+	CheckRunRerunAction = "rerun"
+
+	checkRunQueued     = "queued"
+	checkRunInProgress = "in_progress"
+	checkRunCompleted  = "completed"
+
+	// GitHub accepts at most 50 annotations per request and 65535 characters
+	// in the summary.
+	maxCheckRunAnnotations   = 50
+	maxCheckRunSummaryLength = 65535
+	// maxCheckRunFailures is the number of failed tests listed in the summary.
+	maxCheckRunFailures = 20
+	// maxCheckRunFailureLength truncates the failure output of a test.
+	maxCheckRunFailureLength = 2000
+)
+
+// fileLineRegex matches file:line references in test failure output, e.g.
+// pkg/foo/foo_test.go:42 or /go/src/k8s.io/test-infra/prow/foo.go:42:10.
+var fileLineRegex = regexp.MustCompile(`([\w.+\-/]*\w\.\w+):(\d+)`)
+
+// CheckRunClient provides a client interface to report job status updates
+// through GitHub check runs.
+type CheckRunClient interface {
+	ListCheckRunsByNameWithContext(ctx context.Context, org, repo, ref, name string) ([]github.CheckRun, error)
+	CreateCheckRunWithContext(ctx context.Context, org, repo string, checkRun github.CheckRun) (int64, error)
+	UpdateCheckRunWithContext(ctx context.Context, org, repo string, id int64, checkRun github.CheckRun) error
+}
+
+// UsesCheckRuns returns true if the results of the prowjob are reported as
+// check runs instead of status contexts.
+func UsesCheckRuns(pj prowapi.ProwJob, config config.GitHubReporter) bool {
+	refs := pj.Spec.Refs
+	if refs == nil {
+		return false
+	}
+	for _, ident := range config.CheckRunRepos {
+		if refs.Org == ident || refs.Org+"/"+refs.Repo == ident {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportCheckRun creates or updates the check run of the prowjob. The check
+// run is found by its external ID, which is the name of the prowjob. Results
+// of the junit suites of a completed job are summarized in the check run.
+func ReportCheckRun(ctx context.Context, ghc CheckRunClient, pj prowapi.ProwJob, config config.GitHubReporter, suites []*junit.Suites) error {
+	if ghc == nil {
+		return fmt.Errorf("trying to report pj %s, but found empty github client", pj.ObjectMeta.Name)
+	}
+	if !ShouldReport(pj, config.JobTypesToReport) {
+		return nil
+	}
+	refs := pj.Spec.Refs
+	// we are not reporting for batch jobs, we can consider support that in the future
+	if len(refs.Pulls) > 1 {
+		return nil
+	}
+
+	checkRun, err := checkRunForProwJob(pj, suites)
+	if err != nil {
+		return err
+	}
+	existing, err := ghc.ListCheckRunsByNameWithContext(ctx, refs.Org, refs.Repo, checkRun.HeadSHA, checkRun.Name)
+	if err != nil {
+		return fmt.Errorf("error listing check runs: %w", err)
+	}
+	for _, run := range existing {
+		if run.ExternalID == checkRun.ExternalID {
+			if err := ghc.UpdateCheckRunWithContext(ctx, refs.Org, refs.Repo, run.ID, checkRun); err != nil {
+				return fmt.Errorf("error updating check run: %w", err)
+			}
+			return nil
+		}
+	}
+	if _, err := ghc.CreateCheckRunWithContext(ctx, refs.Org, refs.Repo, checkRun); err != nil {
+		return fmt.Errorf("error creating check run: %w", err)
+	}
+	return nil
+}
+
+// prowjobStateToCheckRun maps prowjob states to the status and conclusion of
+// a check run.
+func prowjobStateToCheckRun(pjState prowapi.ProwJobState) (status, conclusion string, err error) {
+	switch pjState {
+	case prowapi.TriggeredState:
+		return checkRunQueued, "", nil
+	case prowapi.PendingState:
+		return checkRunInProgress, "", nil
+	case prowapi.SuccessState:
+		return checkRunCompleted, "success", nil
+	case prowapi.ErrorState, prowapi.FailureState:
+		return checkRunCompleted, "failure", nil
+	case prowapi.AbortedState:
+		return checkRunCompleted, "cancelled", nil
+	}
+	return "", "", fmt.Errorf("Unknown prowjob state: %s", pjState)
+}
+
+func checkRunForProwJob(pj prowapi.ProwJob, suites []*junit.Suites) (github.CheckRun, error) {
+	refs := pj.Spec.Refs
+	status, conclusion, err := prowjobStateToCheckRun(pj.Status.State)
+	if err != nil {
+		return github.CheckRun{}, err
+	}
+	sha := refs.BaseSHA
+	if len(refs.Pulls) > 0 && pj.Spec.Type != prowapi.PostsubmitJob {
+		sha = refs.Pulls[0].SHA
+	}
+	if pj.Name == "" {
+		return github.CheckRun{}, errors.New("prowjob has no name")
+	}
+
+	checkRun := github.CheckRun{
+		Name:       pj.Spec.Context,
+		HeadSHA:    sha,
+		DetailsURL: pj.Status.URL,
+		ExternalID: pj.Name,
+		Status:     status,
+		Conclusion: conclusion,
+	}
+	if status != checkRunQueued {
+		checkRun.StartedAt = pj.Status.StartTime.UTC().Format(time.RFC3339)
+	}
+	if status == checkRunCompleted {
+		if pj.Status.CompletionTime != nil {
+			checkRun.CompletedAt = pj.Status.CompletionTime.UTC().Format(time.RFC3339)
+		}
+		checkRun.Actions = []github.CheckRunAction{{
+			Label:       "Re-run",
+			Description: "Run this job again",
+			Identifier:  CheckRunRerunAction,
+		}}
+	}
+
+	title := pj.Status.Description
+	if title == "" {
+		title = fmt.Sprintf("Job %s", pj.Status.State)
+	}
+	checkRun.Output = github.CheckRunOutput{Title: title}
+	checkRun.Output.Summary, checkRun.Output.Annotations = checkRunSummary(pj, suites)
+	return checkRun, nil
+}
+
+// checkRunSummary returns markdown that summarizes the test results and
+// annotations for the files that failed tests point at.
+func checkRunSummary(pj prowapi.ProwJob, suites []*junit.Suites) (string, []github.CheckRunAnnotation) {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "**%s** is in state `%s`.", pj.Spec.Job, pj.Status.State)
+	if pj.Status.URL != "" {
+		fmt.Fprintf(&summary, " [Full job results](%s)", pj.Status.URL)
+	}
+	summary.WriteString("\n")
+	if len(suites) == 0 {
+		return summary.String(), nil
+	}
+
+	var passed, skipped int
+	var failed []junit.Result
+	var record func(suite junit.Suite)
+	record = func(suite junit.Suite) {
+		for _, sub := range suite.Suites {
+			record(sub)
+		}
+		for _, result := range suite.Results {
+			switch {
+			case result.Skipped != nil:
+				skipped++
+			case result.Failure != nil || result.Errored != nil:
+				failed = append(failed, result)
+			default:
+				passed++
+			}
+		}
+	}
+	for _, s := range suites {
+		for _, suite := range s.Suites {
+			record(suite)
+		}
+	}
+
+	summary.WriteString("\n| Passed | Failed | Skipped |\n| --- | --- | --- |\n")
+	fmt.Fprintf(&summary, "| %d | %d | %d |\n", passed, len(failed), skipped)
+
+	var annotations []github.CheckRunAnnotation
+	for i, result := range failed {
+		name := result.Name
+		if result.ClassName != "" {
+			name = result.ClassName + " " + name
+		}
+		message := result.Message(maxCheckRunFailureLength)
+		if i < maxCheckRunFailures {
+			fmt.Fprintf(&summary, "\n#### %s\n```\n%s\n```\n", name, strings.ReplaceAll(message, "```", "'''"))
+		} else if i == maxCheckRunFailures {
+			fmt.Fprintf(&summary, "\nand %d more failed tests.\n", len(failed)-maxCheckRunFailures)
+		}
+		if len(annotations) < maxCheckRunAnnotations {
+			if annotation, ok := checkRunAnnotation(pj.Spec.Refs, name, message); ok {
+				annotations = append(annotations, annotation)
+			}
+		}
+	}
+
+	res := summary.String()
+	if len(res) > maxCheckRunSummaryLength {
+		res = res[:maxCheckRunSummaryLength-len("\n...")] + "\n..."
+	}
+	return res, annotations
+}
+
+// checkRunAnnotation creates an annotation for the first file:line reference
+// in the failure message that can be resolved to a path in the repo.
+func checkRunAnnotation(refs *prowapi.Refs, test, message string) (github.CheckRunAnnotation, bool) {
+	for _, match := range fileLineRegex.FindAllStringSubmatch(message, -1) {
+		path := match[1]
+		// Strip the checkout location, e.g. /home/prow/go/src/github.com/org/repo/
+		if i := strings.Index(path, "/"+refs.Org+"/"+refs.Repo+"/"); i >= 0 {
+			path = path[i+len(refs.Org)+len(refs.Repo)+3:]
+		} else if strings.HasPrefix(path, "/") {
+			continue
+		}
+		line, err := strconv.Atoi(match[2])
+		if err != nil || line == 0 {
+			continue
+		}
+		return github.CheckRunAnnotation{
+			Path:            path,
+			StartLine:       line,
+			EndLine:         line,
+			AnnotationLevel: "failure",
+			Title:           test,
+			Message:         message,
+		}, true
+	}
+	return github.CheckRunAnnotation{}, false
+}
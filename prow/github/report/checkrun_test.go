@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestUsesCheckRuns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		repos    []string
+		refs     *prowapi.Refs
+		expected bool
+	}{
+		{
+			name:  "not configured",
+			refs:  &prowapi.Refs{Org: "org", Repo: "repo"},
+			repos: nil,
+		},
+		{
+			name:     "org configured",
+			refs:     &prowapi.Refs{Org: "org", Repo: "repo"},
+			repos:    []string{"org"},
+			expected: true,
+		},
+		{
+			name:     "repo configured",
+			refs:     &prowapi.Refs{Org: "org", Repo: "repo"},
+			repos:    []string{"other", "org/repo"},
+			expected: true,
+		},
+		{
+			name:  "other repo configured",
+			refs:  &prowapi.Refs{Org: "org", Repo: "repo"},
+			repos: []string{"org/other"},
+		},
+		{
+			name:  "no refs",
+			repos: []string{"org"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := prowapi.ProwJob{Spec: prowapi.ProwJobSpec{Refs: tc.refs}}
+			if got := UsesCheckRuns(pj, config.GitHubReporter{CheckRunRepos: tc.repos}); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReportCheckRun(t *testing.T) {
+	start := metav1.NewTime(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC))
+	end := metav1.NewTime(start.Add(10 * time.Minute))
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-pj"},
+		Spec: prowapi.ProwJobSpec{
+			Type:    prowapi.PresubmitJob,
+			Job:     "pull-test",
+			Context: "pull-test",
+			Report:  true,
+			Refs: &prowapi.Refs{
+				Org:     "org",
+				Repo:    "repo",
+				BaseSHA: "base",
+				Pulls:   []prowapi.Pull{{Number: 1, SHA: "head"}},
+			},
+		},
+		Status: prowapi.ProwJobStatus{
+			State:       prowapi.PendingState,
+			Description: "Job triggered.",
+			URL:         "https://prow.k8s.io/view/some-pj",
+			StartTime:   start,
+		},
+	}
+	reporterConfig := config.GitHubReporter{JobTypesToReport: []prowapi.ProwJobType{prowapi.PresubmitJob}}
+	ghc := fakegithub.NewFakeClient()
+
+	if err := ReportCheckRun(context.Background(), ghc, pj, reporterConfig, nil); err != nil {
+		t.Fatalf("failed to report pending job: %v", err)
+	}
+	checkRuns := ghc.CheckRuns["org/repo"]
+	if len(checkRuns) != 1 {
+		t.Fatalf("expected one check run to be created, got %d", len(checkRuns))
+	}
+	pending := checkRuns[0]
+	if pending.HeadSHA != "head" || pending.ExternalID != "some-pj" || pending.Status != "in_progress" || pending.Conclusion != "" || pending.StartedAt != "2022-06-01T12:00:00Z" {
+		t.Errorf("unexpected pending check run: %+v", pending)
+	}
+	if len(pending.Actions) != 0 {
+		t.Errorf("expected no actions on pending check run, got %+v", pending.Actions)
+	}
+
+	failure := "foo_test.go:12: expected 1, got 2\n/home/prow/go/src/github.com/org/repo/pkg/bar/bar.go:34 +0x1d"
+	suites := []*junit.Suites{{
+		Suites: []junit.Suite{{
+			Results: []junit.Result{
+				{Name: "TestPass"},
+				{Name: "TestSkip", Skipped: &junit.Skipped{}},
+				{Name: "TestFail", ClassName: "pkg/foo", Failure: &junit.Failure{Value: failure}},
+				{Name: "TestPanic", ClassName: "pkg/bar", Failure: &junit.Failure{Value: "panic\n/usr/local/go/src/runtime/panic.go:838\n" + failure[strings.Index(failure, "\n")+1:]}},
+			},
+		}},
+	}}
+	pj.Status.State = prowapi.FailureState
+	pj.Status.Description = "Job failed."
+	pj.Status.CompletionTime = &end
+	if err := ReportCheckRun(context.Background(), ghc, pj, reporterConfig, suites); err != nil {
+		t.Fatalf("failed to report failed job: %v", err)
+	}
+	checkRuns = ghc.CheckRuns["org/repo"]
+	if len(checkRuns) != 1 {
+		t.Fatalf("expected the check run to be updated, got %d check runs", len(checkRuns))
+	}
+	completed := checkRuns[0]
+	if completed.Status != "completed" || completed.Conclusion != "failure" || completed.CompletedAt != "2022-06-01T12:10:00Z" || completed.HeadSHA != "head" {
+		t.Errorf("unexpected completed check run: %+v", completed)
+	}
+	if diff := cmp.Diff([]github.CheckRunAction{{Label: "Re-run", Description: "Run this job again", Identifier: CheckRunRerunAction}}, completed.Actions); diff != "" {
+		t.Errorf("actions differ from expected (-want +got):\n%s", diff)
+	}
+	if completed.Output.Title != "Job failed." {
+		t.Errorf("expected title %q, got %q", "Job failed.", completed.Output.Title)
+	}
+	for _, expected := range []string{"| 1 | 2 | 1 |", "#### pkg/foo TestFail", "#### pkg/bar TestPanic", "expected 1, got 2"} {
+		if !strings.Contains(completed.Output.Summary, expected) {
+			t.Errorf("expected summary to contain %q, got:\n%s", expected, completed.Output.Summary)
+		}
+	}
+	expectedAnnotations := []github.CheckRunAnnotation{
+		{
+			Path:            "foo_test.go",
+			StartLine:       12,
+			EndLine:         12,
+			AnnotationLevel: "failure",
+			Title:           "pkg/foo TestFail",
+			Message:         failure,
+		},
+		{
+			Path:            "pkg/bar/bar.go",
+			StartLine:       34,
+			EndLine:         34,
+			AnnotationLevel: "failure",
+			Title:           "pkg/bar TestPanic",
+			Message:         "panic\n/usr/local/go/src/runtime/panic.go:838\n/home/prow/go/src/github.com/org/repo/pkg/bar/bar.go:34 +0x1d",
+		},
+	}
+	if diff := cmp.Diff(expectedAnnotations, completed.Output.Annotations); diff != "" {
+		t.Errorf("annotations differ from expected (-want +got):\n%s", diff)
+	}
+
+	// A rerun of the job creates a new check run with the same name.
+	rerun := pj.DeepCopy()
+	rerun.Name = "other-pj"
+	rerun.Status.State = prowapi.TriggeredState
+	if err := ReportCheckRun(context.Background(), ghc, *rerun, reporterConfig, nil); err != nil {
+		t.Fatalf("failed to report rerun: %v", err)
+	}
+	if checkRuns := ghc.CheckRuns["org/repo"]; len(checkRuns) != 2 || checkRuns[1].Status != "queued" || checkRuns[0].Status != "completed" {
+		t.Errorf("expected a second queued check run, got %+v", checkRuns)
+	}
+}
+
+func TestProwjobStateToCheckRun(t *testing.T) {
+	testCases := []struct {
+		state              prowapi.ProwJobState
+		expectedStatus     string
+		expectedConclusion string
+	}{
+		{state: prowapi.TriggeredState, expectedStatus: "queued"},
+		{state: prowapi.PendingState, expectedStatus: "in_progress"},
+		{state: prowapi.SuccessState, expectedStatus: "completed", expectedConclusion: "success"},
+		{state: prowapi.FailureState, expectedStatus: "completed", expectedConclusion: "failure"},
+		{state: prowapi.ErrorState, expectedStatus: "completed", expectedConclusion: "failure"},
+		{state: prowapi.AbortedState, expectedStatus: "completed", expectedConclusion: "cancelled"},
+	}
+	for _, tc := range testCases {
+		status, conclusion, err := prowjobStateToCheckRun(tc.state)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.state, err)
+		}
+		if status != tc.expectedStatus || conclusion != tc.expectedConclusion {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tc.state, tc.expectedStatus, tc.expectedConclusion, status, conclusion)
+		}
+	}
+	if _, _, err := prowjobStateToCheckRun("unknown"); err == nil {
+		t.Error("expected an error for an unknown state")
+	}
+}
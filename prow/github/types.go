@@ -652,6 +652,43 @@ type Hook struct {
 	Config HookConfig `json:"config"`
 }
 
+// HookDelivery is a delivery of a webhook. Only a single delivery includes
+// its request and response.
+type HookDelivery struct {
+	ID          int64     `json:"id"`
+	GUID        string    `json:"guid"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	Redelivery  bool      `json:"redelivery"`
+	Duration    float64   `json:"duration"`
+	// Status is e.g. "OK" or the reason why the delivery failed.
+	Status string `json:"status"`
+	// StatusCode is 0 if GitHub failed to connect to the webhook.
+	StatusCode     int                   `json:"status_code"`
+	Event          string                `json:"event"`
+	Action         string                `json:"action,omitempty"`
+	InstallationID int64                 `json:"installation_id,omitempty"`
+	RepositoryID   int64                 `json:"repository_id,omitempty"`
+	Request        *HookDeliveryRequest  `json:"request,omitempty"`
+	Response       *HookDeliveryResponse `json:"response,omitempty"`
+}
+
+// Succeeded returns whether the webhook accepted the delivery.
+func (d HookDelivery) Succeeded() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// HookDeliveryRequest is the request GitHub sent for a delivery.
+type HookDeliveryRequest struct {
+	Headers map[string]string `json:"headers"`
+	Payload json.RawMessage   `json:"payload"`
+}
+
+// HookDeliveryResponse is the response of the webhook to a delivery.
+type HookDeliveryResponse struct {
+	Headers map[string]string `json:"headers"`
+	Payload string            `json:"payload"`
+}
+
 // HookRequest can create and/or edit a webhook.
 //
 // AddEvents and RemoveEvents are only valid during an edit, and only for a repo
@@ -926,6 +963,7 @@ type Review struct {
 	State       ReviewState `json:"state"`
 	HTMLURL     string      `json:"html_url"`
 	SubmittedAt time.Time   `json:"submitted_at"`
+	CommitID    string      `json:"commit_id"`
 }
 
 // ReviewCommentEventAction enumerates the triggers for this
@@ -1336,6 +1374,22 @@ type CheckRun struct {
 	CheckSuite   CheckSuite     `json:"check_suite,omitempty"`
 	App          App            `json:"app,omitempty"`
 	PullRequests []PullRequest  `json:"pull_requests,omitempty"`
+	// Actions are only set when creating or updating a check run, GitHub
+	// does not return them.
+	Actions []CheckRunAction `json:"actions,omitempty"`
+}
+
+// CheckRunAction is a button on a check run that sends a check_run event
+// with the requested_action action to the app that created the check run.
+//
+// See https://docs.github.com/en/rest/guides/getting-started-with-the-checks-api#check-runs-and-requested-actions
+type CheckRunAction struct {
+	// Label is shown on the button, max 20 characters.
+	Label string `json:"label"`
+	// Description is shown as the tooltip, max 40 characters.
+	Description string `json:"description"`
+	// Identifier is sent back in the check_run event, max 20 characters.
+	Identifier string `json:"identifier"`
 }
 
 type CheckRunOutput struct {
@@ -1468,6 +1522,73 @@ type DirectoryContent struct {
 	Path string `json:"path"`
 }
 
+// CheckRunEventAction enumerates the triggers for this
+// webhook payload type. See also:
+// https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#check_run
+type CheckRunEventAction string
+
+const (
+	// CheckRunActionCreated means a new check run was created.
+	CheckRunActionCreated CheckRunEventAction = "created"
+	// CheckRunActionCompleted means the status of the check run is completed.
+	CheckRunActionCompleted CheckRunEventAction = "completed"
+	// CheckRunActionRerequested means someone requested to re-run the check
+	// run from the pull request UI.
+	CheckRunActionRerequested CheckRunEventAction = "rerequested"
+	// CheckRunActionRequestedAction means someone requested an action of the
+	// check run, only the app that created the check run receives it.
+	CheckRunActionRequestedAction CheckRunEventAction = "requested_action"
+)
+
+// CheckRunEvent is what GitHub sends us when a check run is created,
+// completed, re-requested or an action of it is requested.
+type CheckRunEvent struct {
+	Action   CheckRunEventAction `json:"action"`
+	CheckRun CheckRun            `json:"check_run"`
+	// RequestedAction is only set for the requested_action action.
+	RequestedAction *CheckRunRequestedAction `json:"requested_action,omitempty"`
+	Repo            Repo                     `json:"repository"`
+	Sender          User                     `json:"sender"`
+
+	// GUID is included in the header of the request received by GitHub.
+	GUID string
+}
+
+// CheckRunRequestedAction is the action of a check run that was requested.
+type CheckRunRequestedAction struct {
+	// Identifier is the identifier of the CheckRunAction.
+	Identifier string `json:"identifier"`
+}
+
+// CheckSuiteEventAction enumerates the triggers for this
+// webhook payload type. See also:
+// https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#check_suite
+type CheckSuiteEventAction string
+
+const (
+	// CheckSuiteActionCompleted means all check runs of the check suite
+	// are completed.
+	CheckSuiteActionCompleted CheckSuiteEventAction = "completed"
+	// CheckSuiteActionRequested means new code was pushed, only the app
+	// that created the check suite receives it.
+	CheckSuiteActionRequested CheckSuiteEventAction = "requested"
+	// CheckSuiteActionRerequested means someone requested to re-run the
+	// entire check suite from the pull request UI.
+	CheckSuiteActionRerequested CheckSuiteEventAction = "rerequested"
+)
+
+// CheckSuiteEvent is what GitHub sends us when a check suite is completed,
+// requested or re-requested.
+type CheckSuiteEvent struct {
+	Action     CheckSuiteEventAction `json:"action"`
+	CheckSuite CheckSuite            `json:"check_suite"`
+	Repo       Repo                  `json:"repository"`
+	Sender     User                  `json:"sender"`
+
+	// GUID is included in the header of the request received by GitHub.
+	GUID string
+}
+
 // WorkflowRunEvent holds information about an `workflow_run` GitHub webhook event.
 // see // https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_run
 type WorkflowRunEvent struct {
@@ -48,6 +48,8 @@ const (
 	issueCommentEvent             = "issue_comment"
 	issuesEvent                   = "issues"
 	workflowRunEvent              = "workflow_run"
+	checkRunEvent                 = "check_run"
+	checkSuiteEvent               = "check_suite"
 )
 
 // GitHubEventServer hold all the information needed for the
@@ -119,6 +121,12 @@ type StatusEventHandler func(*logrus.Entry, github.StatusEvent)
 // WorkflowRunEventHandler is a type of function that handles GitHub's workflow run events.
 type WorkflowRunEventHandler func(*logrus.Entry, github.WorkflowRunEvent)
 
+// CheckRunEventHandler is a type of function that handles GitHub's check run events.
+type CheckRunEventHandler func(*logrus.Entry, github.CheckRunEvent)
+
+// CheckSuiteEventHandler is a type of function that handles GitHub's check suite events.
+type CheckSuiteEventHandler func(*logrus.Entry, github.CheckSuiteEvent)
+
 // RegisterReviewCommentEventHandler registers an ReviewCommentEventHandler function in GitHubEventServerOptions
 func (g *GitHubEventServer) RegisterReviewCommentEventHandler(fn ReviewCommentEventHandler) {
 	g.serveMuxHandler.reviewCommentEventHandlers = append(g.serveMuxHandler.reviewCommentEventHandlers, fn)
@@ -159,6 +167,16 @@ func (g *GitHubEventServer) RegisterWorkflowRunEventHandler(fn WorkflowRunEventH
 	g.serveMuxHandler.workflowRunEventHandler = append(g.serveMuxHandler.workflowRunEventHandler, fn)
 }
 
+// RegisterCheckRunEventHandler registers an CheckRunEventHandler function in GitHubEventServerOptions
+func (g *GitHubEventServer) RegisterCheckRunEventHandler(fn CheckRunEventHandler) {
+	g.serveMuxHandler.checkRunEventHandlers = append(g.serveMuxHandler.checkRunEventHandlers, fn)
+}
+
+// RegisterCheckSuiteEventHandler registers an CheckSuiteEventHandler function in GitHubEventServerOptions
+func (g *GitHubEventServer) RegisterCheckSuiteEventHandler(fn CheckSuiteEventHandler) {
+	g.serveMuxHandler.checkSuiteEventHandlers = append(g.serveMuxHandler.checkSuiteEventHandlers, fn)
+}
+
 // RegisterExternalPlugins registers the external plugins in GitHubEventServerOptions
 func (g *GitHubEventServer) RegisterExternalPlugins(p map[string][]plugins.ExternalPlugin) {
 	g.serveMuxHandler.externalPlugins = p
@@ -200,6 +218,8 @@ type serveMuxHandler struct {
 	issueEventHandlers         []IssueEventHandler
 	statusEventHandlers        []StatusEventHandler
 	workflowRunEventHandler    []WorkflowRunEventHandler
+	checkRunEventHandlers      []CheckRunEventHandler
+	checkSuiteEventHandlers    []CheckSuiteEventHandler
 
 	externalPlugins map[string][]plugins.ExternalPlugin
 
@@ -415,6 +435,53 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 			}()
 		}
 
+	case checkRunEvent:
+		var cre github.CheckRunEvent
+		if err := json.Unmarshal(payload, &cre); err != nil {
+			return err
+		}
+		cre.GUID = eventGUID
+		org = cre.Repo.Owner.Login
+		repo = cre.Repo.Name
+
+		for _, checkRunEventHandler := range s.checkRunEventHandlers {
+			fn := checkRunEventHandler
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				fn(l.WithFields(logrus.Fields{
+					github.OrgLogField:  cre.Repo.Owner.Login,
+					github.RepoLogField: cre.Repo.Name,
+					"check_run":         cre.CheckRun.Name,
+					"sha":               cre.CheckRun.HeadSHA,
+					"id":                cre.CheckRun.ID,
+				}), cre)
+			}()
+		}
+
+	case checkSuiteEvent:
+		var cse github.CheckSuiteEvent
+		if err := json.Unmarshal(payload, &cse); err != nil {
+			return err
+		}
+		cse.GUID = eventGUID
+		org = cse.Repo.Owner.Login
+		repo = cse.Repo.Name
+
+		for _, checkSuiteEventHandler := range s.checkSuiteEventHandlers {
+			fn := checkSuiteEventHandler
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				fn(l.WithFields(logrus.Fields{
+					github.OrgLogField:  cse.Repo.Owner.Login,
+					github.RepoLogField: cse.Repo.Name,
+					"sha":               cse.CheckSuite.HeadSHA,
+					"id":                cse.CheckSuite.ID,
+				}), cse)
+			}()
+		}
+
 	default:
 		l.Debug("Ignoring unhandled event type.")
 	}
@@ -17,14 +17,21 @@ limitations under the License.
 package githubeventserver
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins"
 )
 
@@ -262,3 +269,91 @@ func TestGetExternalPluginsForEvent(t *testing.T) {
 		})
 	}
 }
+
+type roundTripFunc func(req *http.Request) *http.Response
+
+// RoundTrip .
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func TestHandleCheckEvents(t *testing.T) {
+	const payload = `{
+  "action": "requested_action",
+  "check_run": {"id": 1, "name": "pull-test", "head_sha": "abc"},
+  "check_suite": {"id": 2, "head_sha": "abc"},
+  "repository": {"name": "repo", "full_name": "org/repo", "owner": {"login": "org"}}
+}`
+
+	testCases := []struct {
+		name               string
+		eventType          string
+		expectedHandled    []string
+		expectedDispatched []string
+	}{
+		{
+			name:               "check run event",
+			eventType:          "check_run",
+			expectedHandled:    []string{"check_run guid org/repo 1"},
+			expectedDispatched: []string{"/checks", "/everything"},
+		},
+		{
+			name:               "check suite event",
+			eventType:          "check_suite",
+			expectedHandled:    []string{"check_suite guid org/repo 2"},
+			expectedDispatched: []string{"/everything"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var handled, dispatched []string
+			record := func(to *[]string, entry string) {
+				lock.Lock()
+				defer lock.Unlock()
+				*to = append(*to, entry)
+			}
+
+			var wg sync.WaitGroup
+			s := &serveMuxHandler{
+				wg:      &wg,
+				metrics: NewMetrics(),
+				checkRunEventHandlers: []CheckRunEventHandler{func(_ *logrus.Entry, cre github.CheckRunEvent) {
+					record(&handled, fmt.Sprintf("check_run %s %s %d", cre.GUID, cre.Repo.FullName, cre.CheckRun.ID))
+				}},
+				checkSuiteEventHandlers: []CheckSuiteEventHandler{func(_ *logrus.Entry, cse github.CheckSuiteEvent) {
+					record(&handled, fmt.Sprintf("check_suite %s %s %d", cse.GUID, cse.Repo.FullName, cse.CheckSuite.ID))
+				}},
+				externalPlugins: map[string][]plugins.ExternalPlugin{
+					"org/repo": {
+						{Name: "checks", Endpoint: "/checks", Events: []string{"check_run"}},
+						{Name: "everything", Endpoint: "/everything"},
+					},
+					"org/other": {{Name: "other", Endpoint: "/other"}},
+				},
+				c: http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+					record(&dispatched, req.URL.String())
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(bytes.NewBufferString("OK")),
+						Header:     make(http.Header),
+					}
+				})},
+			}
+
+			if err := s.handleEvent(tc.eventType, "guid", []byte(payload), http.Header{}); err != nil {
+				t.Fatalf("failed to handle event: %v", err)
+			}
+			wg.Wait()
+
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			if diff := cmp.Diff(tc.expectedHandled, handled, sortStrings); diff != "" {
+				t.Errorf("unexpected handled events (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedDispatched, dispatched, sortStrings); diff != "" {
+				t.Errorf("unexpected dispatched events (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -41,6 +41,30 @@ var (
 		Name: "prow_plugin_handle_errors",
 		Help: "Prow errors handling an event by plugin, event type and action.",
 	}, []string{"event_type", "action", "plugin", "took_action"})
+	pluginHandleTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_plugin_handle_timeouts",
+		Help: "Prow timeouts handling an event by plugin, event type and action.",
+	}, []string{"event_type", "action", "plugin"})
+	pluginHandlersWaiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prow_plugin_handlers_waiting",
+		Help: "Events waiting for a plugin to have a free handler by plugin.",
+	}, []string{"plugin"})
+	pluginHandlersRunaway = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prow_plugin_handlers_runaway",
+		Help: "Handlers that are still running after they timed out by plugin.",
+	}, []string{"plugin"})
+	pluginGitHubBudgetExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_plugin_github_budget_exceeded",
+		Help: "GitHub API requests that failed because the plugin used up its budget by plugin.",
+	}, []string{"plugin"})
+	duplicateWebhookCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_webhook_duplicates",
+		Help: "A counter of the webhooks that hook received before and ignored by event type.",
+	}, []string{"event_type"})
+	replayedWebhookCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_webhook_replayed",
+		Help: "A counter of the queued webhooks that hook processed after a restart by event type.",
+	}, []string{"event_type"})
 )
 
 func init() {
@@ -48,14 +72,26 @@ func init() {
 	prometheus.MustRegister(responseCounter)
 	prometheus.MustRegister(pluginHandleDuration)
 	prometheus.MustRegister(pluginHandleErrors)
+	prometheus.MustRegister(pluginHandleTimeouts)
+	prometheus.MustRegister(pluginHandlersWaiting)
+	prometheus.MustRegister(pluginHandlersRunaway)
+	prometheus.MustRegister(pluginGitHubBudgetExceeded)
+	prometheus.MustRegister(duplicateWebhookCounter)
+	prometheus.MustRegister(replayedWebhookCounter)
 }
 
 // Metrics is a set of metrics gathered by hook.
 type Metrics struct {
-	WebhookCounter       *prometheus.CounterVec
-	ResponseCounter      *prometheus.CounterVec
-	PluginHandleDuration *prometheus.HistogramVec
-	PluginHandleErrors   *prometheus.CounterVec
+	WebhookCounter             *prometheus.CounterVec
+	ResponseCounter            *prometheus.CounterVec
+	PluginHandleDuration       *prometheus.HistogramVec
+	PluginHandleErrors         *prometheus.CounterVec
+	PluginHandleTimeouts       *prometheus.CounterVec
+	PluginHandlersWaiting      *prometheus.GaugeVec
+	PluginHandlersRunaway      *prometheus.GaugeVec
+	PluginGitHubBudgetExceeded *prometheus.CounterVec
+	DuplicateWebhookCounter    *prometheus.CounterVec
+	ReplayedWebhookCounter     *prometheus.CounterVec
 	*plugins.Metrics
 }
 
@@ -69,10 +105,16 @@ type PluginMetrics struct {
 // NewMetrics creates a new set of metrics for the hook server.
 func NewMetrics() *Metrics {
 	return &Metrics{
-		WebhookCounter:       webhookCounter,
-		ResponseCounter:      responseCounter,
-		PluginHandleDuration: pluginHandleDuration,
-		PluginHandleErrors:   pluginHandleErrors,
-		Metrics:              plugins.NewMetrics(),
+		WebhookCounter:             webhookCounter,
+		ResponseCounter:            responseCounter,
+		PluginHandleDuration:       pluginHandleDuration,
+		PluginHandleErrors:         pluginHandleErrors,
+		PluginHandleTimeouts:       pluginHandleTimeouts,
+		PluginHandlersWaiting:      pluginHandlersWaiting,
+		PluginHandlersRunaway:      pluginHandlersRunaway,
+		PluginGitHubBudgetExceeded: pluginGitHubBudgetExceeded,
+		DuplicateWebhookCounter:    duplicateWebhookCounter,
+		ReplayedWebhookCounter:     replayedWebhookCounter,
+		Metrics:                    plugins.NewMetrics(),
 	}
 }
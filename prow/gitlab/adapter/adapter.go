@@ -0,0 +1,303 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package adapter implements a server that turns GitLab merge request
+// webhooks into presubmit ProwJobs.
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+var processingResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gitlab_processing_results",
+	Help: "Count of GitLab webhook events processed by project, event type and result.",
+}, []string{"project", "event_type", "result"})
+
+func init() {
+	prometheus.MustRegister(processingResults)
+}
+
+type prowJobClient interface {
+	Create(context.Context, *prowapi.ProwJob, metav1.CreateOptions) (*prowapi.ProwJob, error)
+}
+
+// Server handles the webhooks of a GitLab instance.
+type Server struct {
+	config         config.Getter
+	gc             gitlab.Client
+	prowJobClient  prowJobClient
+	tokenGenerator func() []byte
+	// trustedAccessLevel is the minimum access level in a project of the
+	// users whose merge requests and comments trigger jobs.
+	trustedAccessLevel gitlab.AccessLevel
+
+	wg  sync.WaitGroup
+	log *logrus.Entry
+}
+
+// NewServer returns a server that handles webhooks carrying the secret token
+// returned by tokenGenerator.
+func NewServer(cfg config.Getter, gc gitlab.Client, prowJobClient prowJobClient, tokenGenerator func() []byte, trustedAccessLevel gitlab.AccessLevel) *Server {
+	return &Server{
+		config:             cfg,
+		gc:                 gc,
+		prowJobClient:      prowJobClient,
+		tokenGenerator:     tokenGenerator,
+		trustedAccessLevel: trustedAccessLevel,
+		log:                logrus.WithField("client", "gitlab-adapter"),
+	}
+}
+
+// ServeHTTP validates an incoming webhook and handles it in the background.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	eventType, payload, ok := gitlab.ValidateWebhook(w, r, s.tokenGenerator)
+	if !ok {
+		return
+	}
+	fmt.Fprint(w, "Event received. Have a nice day.")
+	if err := s.demuxEvent(eventType, payload); err != nil {
+		s.log.WithError(err).Error("Error parsing event.")
+	}
+}
+
+// GracefulShutdown waits for the events being handled.
+func (s *Server) GracefulShutdown() {
+	s.wg.Wait()
+}
+
+func (s *Server) demuxEvent(eventType string, payload []byte) error {
+	var handle func() error
+	var project string
+	switch eventType {
+	case gitlab.MergeRequestHook:
+		var e gitlab.MergeRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return err
+		}
+		project = e.Project.PathWithNamespace
+		handle = func() error { return s.handleMergeRequestEvent(&e) }
+	case gitlab.NoteHook:
+		var e gitlab.NoteEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return err
+		}
+		project = e.Project.PathWithNamespace
+		handle = func() error { return s.handleNoteEvent(&e) }
+	default:
+		s.log.WithField("event-type", eventType).Debug("Ignoring unhandled event type.")
+		return nil
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		result := "success"
+		if err := handle(); err != nil {
+			result = "error"
+			s.log.WithError(err).WithFields(logrus.Fields{"event-type": eventType, "project": project}).Error("Error handling event.")
+		}
+		processingResults.WithLabelValues(project, eventType, result).Inc()
+	}()
+	return nil
+}
+
+// handleMergeRequestEvent runs all presubmits when a merge request is opened
+// or reopened, or when new commits are pushed to it.
+func (s *Server) handleMergeRequestEvent(e *gitlab.MergeRequestEvent) error {
+	attrs := e.ObjectAttributes
+	switch attrs.Action {
+	case gitlab.MergeRequestActionOpen, gitlab.MergeRequestActionReopen:
+	case gitlab.MergeRequestActionUpdate:
+		if attrs.OldRev == "" {
+			// Only the title, description, labels, etc. changed.
+			return nil
+		}
+	default:
+		return nil
+	}
+	log := s.log.WithFields(logrus.Fields{"project": e.Project.PathWithNamespace, "mr": attrs.IID, "action": attrs.Action})
+	mr, err := s.gc.GetMergeRequest(e.Project.PathWithNamespace, attrs.IID)
+	if err != nil {
+		return err
+	}
+	if mr.Draft {
+		log.Debug("Not running presubmits for a draft merge request.")
+		return nil
+	}
+	if trusted, err := s.trusted(e.Project.PathWithNamespace, mr.Author.ID); err != nil {
+		return err
+	} else if !trusted {
+		log.WithField("author", mr.Author.Username).Info("Not running presubmits for a merge request of an untrusted author, a trusted user can run them with /test all.")
+		return nil
+	}
+	return s.trigger(log, e.Project, mr, pjutil.NewTestAllFilter())
+}
+
+// handleNoteEvent runs the presubmits requested by /test and /retest
+// comments of trusted users on merge requests.
+func (s *Server) handleNoteEvent(e *gitlab.NoteEvent) error {
+	if e.ObjectAttributes.NoteableType != gitlab.NoteableTypeMergeRequest || e.MergeRequest == nil {
+		return nil
+	}
+	note := e.ObjectAttributes.Note
+	if !pjutil.TestWithAnyTargetRe.MatchString(note) && !pjutil.RetestRe.MatchString(note) && !pjutil.RetestRequiredRe.MatchString(note) {
+		return nil
+	}
+	log := s.log.WithFields(logrus.Fields{"project": e.Project.PathWithNamespace, "mr": e.MergeRequest.IID, "user": e.User.Username})
+	mr, err := s.gc.GetMergeRequest(e.Project.PathWithNamespace, e.MergeRequest.IID)
+	if err != nil {
+		return err
+	}
+	if mr.State != "opened" {
+		return nil
+	}
+	if trusted, err := s.trusted(e.Project.PathWithNamespace, e.User.ID); err != nil {
+		return err
+	} else if !trusted {
+		log.Info("Ignoring comment of an untrusted user.")
+		return nil
+	}
+
+	contextGetter := func() (sets.String, sets.String, error) {
+		statuses, err := s.gc.ListCommitStatuses(e.Project.PathWithNamespace, mr.SHA)
+		if err != nil {
+			return nil, nil, err
+		}
+		return presubmitContexts(statuses)
+	}
+	filter, err := pjutil.PresubmitFilter(false, contextGetter, note, log)
+	if err != nil {
+		return err
+	}
+	return s.trigger(log, e.Project, mr, filter)
+}
+
+// presubmitContexts returns the contexts that failed and all contexts that
+// were reported for the head of a merge request.
+func presubmitContexts(statuses []gitlab.CommitStatus) (sets.String, sets.String, error) {
+	failed, all := sets.NewString(), sets.NewString()
+	for _, status := range statuses {
+		all.Insert(status.Name)
+		if status.Status == gitlab.StatusFailed || status.Status == gitlab.StatusCanceled {
+			failed.Insert(status.Name)
+		}
+	}
+	return failed, all, nil
+}
+
+func (s *Server) trusted(project string, userID int) (bool, error) {
+	level, err := s.gc.GetMemberAccessLevel(project, userID)
+	if err != nil {
+		return false, err
+	}
+	return level >= s.trustedAccessLevel, nil
+}
+
+// trigger creates the ProwJobs of the presubmits that the filter selects.
+func (s *Server) trigger(log *logrus.Entry, project gitlab.Project, mr *gitlab.MergeRequest, filter pjutil.Filter) error {
+	instance, err := url.Parse(s.gc.Instance())
+	if err != nil {
+		return fmt.Errorf("invalid GitLab instance: %w", err)
+	}
+	presubmits := s.config().GetPresubmitsStatic(identifier(s.gc.Instance(), project.PathWithNamespace))
+	if len(presubmits) == 0 {
+		return nil
+	}
+	changes := func() ([]string, error) {
+		return s.gc.GetMergeRequestChanges(project.PathWithNamespace, mr.IID)
+	}
+	toTrigger, err := pjutil.FilterPresubmits(filter, changes, mr.TargetBranch, presubmits, log)
+	if err != nil {
+		return fmt.Errorf("failed to filter presubmits: %w", err)
+	}
+	if len(toTrigger) == 0 {
+		return nil
+	}
+	branch, err := s.gc.GetBranch(project.PathWithNamespace, mr.TargetBranch)
+	if err != nil {
+		return err
+	}
+	refs := createRefs(instance.Host, project, mr, branch.Commit.ID)
+	annotations := map[string]string{
+		kube.GitLabInstance: s.gc.Instance(),
+		kube.GitLabProject:  project.PathWithNamespace,
+	}
+
+	var errs []error
+	for _, presubmit := range toTrigger {
+		labels := map[string]string{kube.GitLabRevision: mr.SHA}
+		for k, v := range presubmit.Labels {
+			labels[k] = v
+		}
+		pj := pjutil.NewProwJob(pjutil.PresubmitSpec(presubmit, refs), labels, annotations)
+		if _, err := s.prowJobClient.Create(context.TODO(), &pj, metav1.CreateOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create ProwJob for %s: %w", presubmit.Name, err))
+			continue
+		}
+		log.WithFields(logrus.Fields{"job": presubmit.Name, "prowjob": pj.Name}).Info("Triggered new job.")
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// identifier is the key of the jobs of a GitLab project in the job config,
+// e.g. gitlab.example.com/group/project
+func identifier(instance, project string) string {
+	if u, err := url.Parse(instance); err == nil && u.Host != "" {
+		instance = u.Host
+	}
+	return instance + "/" + project
+}
+
+func createRefs(host string, project gitlab.Project, mr *gitlab.MergeRequest, baseSHA string) prowapi.Refs {
+	return prowapi.Refs{
+		Org:      host,                      // Something like gitlab.example.com
+		Repo:     project.PathWithNamespace, // Something like group/project
+		RepoLink: project.WebURL,
+		BaseRef:  mr.TargetBranch,
+		BaseSHA:  baseSHA,
+		BaseLink: fmt.Sprintf("%s/-/commit/%s", project.WebURL, baseSHA),
+		CloneURI: project.GitHTTPURL,
+		Pulls: []prowapi.Pull{
+			{
+				Number:     mr.IID,
+				Author:     mr.Author.Username,
+				SHA:        mr.SHA,
+				Title:      mr.Title,
+				Ref:        fmt.Sprintf("refs/merge-requests/%d/head", mr.IID),
+				Link:       mr.WebURL,
+				CommitLink: fmt.Sprintf("%s/-/commit/%s", project.WebURL, mr.SHA),
+				AuthorLink: mr.Author.WebURL,
+			},
+		},
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/gitlab/fakegitlab"
+	"k8s.io/test-infra/prow/kube"
+)
+
+type fakeProwJobClient struct {
+	lock sync.Mutex
+	pjs  []prowapi.ProwJob
+}
+
+func (f *fakeProwJobClient) Create(_ context.Context, pj *prowapi.ProwJob, _ metav1.CreateOptions) (*prowapi.ProwJob, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.pjs = append(f.pjs, *pj)
+	return pj, nil
+}
+
+func (f *fakeProwJobClient) jobs() []string {
+	var jobs []string
+	for _, pj := range f.pjs {
+		jobs = append(jobs, pj.Spec.Job)
+	}
+	sort.Strings(jobs)
+	return jobs
+}
+
+const (
+	project   = "group/project"
+	developer = 1
+	guest     = 2
+)
+
+func testServer(t *testing.T) (*Server, *fakegitlab.Client, *fakeProwJobClient) {
+	presubmit := func(name string, alwaysRun bool, runIfChanged string) config.Presubmit {
+		ps := config.Presubmit{
+			JobBase:      config.JobBase{Name: name},
+			AlwaysRun:    alwaysRun,
+			Reporter:     config.Reporter{Context: name},
+			Trigger:      config.DefaultTriggerFor(name),
+			RerunCommand: config.DefaultRerunCommandFor(name),
+		}
+		ps.RegexpChangeMatcher.RunIfChanged = runIfChanged
+		return ps
+	}
+	presubmits := []config.Presubmit{
+		presubmit("unit", true, ""),
+		presubmit("e2e", true, ""),
+		presubmit("docs", false, "^docs/"),
+		presubmit("manual", false, ""),
+	}
+	if err := config.SetPresubmitRegexes(presubmits); err != nil {
+		t.Fatalf("failed to set presubmit regexes: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.PresubmitsStatic = map[string][]config.Presubmit{"gitlab.example.com/" + project: presubmits}
+
+	gc := fakegitlab.NewClient()
+	gc.MergeRequests[project] = []gitlab.MergeRequest{
+		{IID: 1, State: "opened", SHA: "head", TargetBranch: "main", Author: gitlab.User{ID: developer, Username: "dev"}},
+		{IID: 2, State: "opened", SHA: "head", TargetBranch: "main", Author: gitlab.User{ID: guest, Username: "guest"}},
+		{IID: 3, State: "opened", SHA: "head", TargetBranch: "main", Author: gitlab.User{ID: developer, Username: "dev"}, Draft: true},
+	}
+	gc.Changes[project] = map[int][]string{1: {"docs/README.md"}}
+	gc.Branches[project+"/main"] = "base"
+	gc.Members[project] = map[int]gitlab.AccessLevel{developer: gitlab.DeveloperAccess, guest: gitlab.GuestAccess}
+
+	pjc := &fakeProwJobClient{}
+	s := NewServer(func() *config.Config { return cfg }, gc, pjc, func() []byte { return []byte("token") }, gitlab.DeveloperAccess)
+	return s, gc, pjc
+}
+
+func TestHandleMergeRequestEvent(t *testing.T) {
+	testCases := []struct {
+		name         string
+		iid          int
+		action       string
+		oldRev       string
+		expectedJobs []string
+	}{
+		{
+			name:         "opened merge request runs presubmits",
+			iid:          1,
+			action:       gitlab.MergeRequestActionOpen,
+			expectedJobs: []string{"docs", "e2e", "unit"},
+		},
+		{
+			name:         "pushed commits run presubmits",
+			iid:          1,
+			action:       gitlab.MergeRequestActionUpdate,
+			oldRev:       "previous",
+			expectedJobs: []string{"docs", "e2e", "unit"},
+		},
+		{
+			name:   "other updates do not run presubmits",
+			iid:    1,
+			action: gitlab.MergeRequestActionUpdate,
+		},
+		{
+			name:   "merge requests of untrusted authors do not run presubmits",
+			iid:    2,
+			action: gitlab.MergeRequestActionOpen,
+		},
+		{
+			name:   "draft merge requests do not run presubmits",
+			iid:    3,
+			action: gitlab.MergeRequestActionOpen,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, pjc := testServer(t)
+			e := &gitlab.MergeRequestEvent{Project: gitlab.Project{PathWithNamespace: project}}
+			e.ObjectAttributes.IID = tc.iid
+			e.ObjectAttributes.Action = tc.action
+			e.ObjectAttributes.OldRev = tc.oldRev
+			if err := s.handleMergeRequestEvent(e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := pjc.jobs(); !reflect.DeepEqual(actual, tc.expectedJobs) {
+				t.Errorf("expected jobs %v, got %v", tc.expectedJobs, actual)
+			}
+		})
+	}
+}
+
+func TestHandleNoteEvent(t *testing.T) {
+	testCases := []struct {
+		name         string
+		note         string
+		user         int
+		expectedJobs []string
+	}{
+		{
+			name:         "test a job",
+			note:         "/test manual",
+			user:         developer,
+			expectedJobs: []string{"manual"},
+		},
+		{
+			name:         "retest failed jobs",
+			note:         "/retest",
+			user:         developer,
+			expectedJobs: []string{"e2e"},
+		},
+		{
+			name: "untrusted users cannot test",
+			note: "/test all",
+			user: guest,
+		},
+		{
+			name: "other comments are ignored",
+			note: "looks good",
+			user: developer,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, gc, pjc := testServer(t)
+			gc.Statuses[project] = map[string][]gitlab.CommitStatus{"head": {
+				{Name: "unit", Status: gitlab.StatusSuccess},
+				{Name: "e2e", Status: gitlab.StatusFailed},
+				{Name: "gitlab-ci", Status: gitlab.StatusFailed},
+			}}
+			e := &gitlab.NoteEvent{
+				Project:      gitlab.Project{PathWithNamespace: project},
+				User:         gitlab.User{ID: tc.user},
+				MergeRequest: &gitlab.MergeRequestAttributes{IID: 2},
+			}
+			e.ObjectAttributes.Note = tc.note
+			e.ObjectAttributes.NoteableType = gitlab.NoteableTypeMergeRequest
+			if err := s.handleNoteEvent(e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := pjc.jobs(); !reflect.DeepEqual(actual, tc.expectedJobs) {
+				t.Errorf("expected jobs %v, got %v", tc.expectedJobs, actual)
+			}
+		})
+	}
+}
+
+func TestTriggeredProwJob(t *testing.T) {
+	s, _, pjc := testServer(t)
+	e := &gitlab.MergeRequestEvent{Project: gitlab.Project{
+		PathWithNamespace: project,
+		WebURL:            "https://gitlab.example.com/group/project",
+		GitHTTPURL:        "https://gitlab.example.com/group/project.git",
+	}}
+	e.ObjectAttributes.IID = 1
+	e.ObjectAttributes.Action = gitlab.MergeRequestActionOpen
+	if err := s.handleMergeRequestEvent(e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pjc.pjs) == 0 {
+		t.Fatal("expected jobs to be triggered")
+	}
+	pj := pjc.pjs[0]
+	refs := pj.Spec.Refs
+	if refs.Org != "gitlab.example.com" || refs.Repo != project || refs.BaseSHA != "base" || refs.CloneURI != "https://gitlab.example.com/group/project.git" {
+		t.Errorf("unexpected refs %+v", refs)
+	}
+	if pull := refs.Pulls[0]; pull.Number != 1 || pull.SHA != "head" || pull.Ref != "refs/merge-requests/1/head" {
+		t.Errorf("unexpected pull %+v", pull)
+	}
+	if pj.Labels[kube.GitLabRevision] != "head" || pj.Annotations[kube.GitLabInstance] != "https://gitlab.example.com" || pj.Annotations[kube.GitLabProject] != project {
+		t.Errorf("unexpected labels %v and annotations %v", pj.Labels, pj.Annotations)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	testCases := []struct {
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{name: "valid token", token: "token", expectedCode: http.StatusOK},
+		{name: "invalid token", token: "wrong", expectedCode: http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, pjc := testServer(t)
+			body := `{"object_kind": "merge_request", "project": {"path_with_namespace": "group/project"}, "object_attributes": {"iid": 1, "action": "open"}}`
+			req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+			req.Header.Set(gitlab.EventHeader, gitlab.MergeRequestHook)
+			req.Header.Set(gitlab.TokenHeader, tc.token)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			s.GracefulShutdown()
+			if w.Code != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, w.Code)
+			}
+			if triggered := len(pjc.jobs()) > 0; triggered != (tc.expectedCode == http.StatusOK) {
+				t.Errorf("expected jobs to be triggered only for valid webhooks, got %v", pjc.jobs())
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitlab contains a client for the subset of the GitLab REST API
+// that Prow uses, and the types of the GitLab webhooks it handles.
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/version"
+)
+
+// Client interacts with a GitLab instance on behalf of a single user,
+// usually a bot account. Projects are identified by their full path,
+// e.g. "group/subgroup/project".
+type Client interface {
+	// Instance returns the URL of the GitLab instance, e.g. https://gitlab.example.com
+	Instance() string
+	GetMergeRequest(project string, iid int) (*MergeRequest, error)
+	// ListMergeRequests lists the open merge requests of a project that have
+	// all of the labels.
+	ListMergeRequests(project string, labels []string) ([]MergeRequest, error)
+	// GetMergeRequestChanges lists the paths of the files changed by a merge request.
+	GetMergeRequestChanges(project string, iid int) ([]string, error)
+	CreateMergeRequestNote(project string, iid int, body string) error
+	// AcceptMergeRequest merges a merge request if its head is still sha.
+	AcceptMergeRequest(project string, iid int, sha string, squash bool) error
+	GetBranch(project, branch string) (*Branch, error)
+	// ListCommitStatuses lists the latest status of each context of a commit.
+	ListCommitStatuses(project, sha string) ([]CommitStatus, error)
+	SetCommitStatus(project, sha string, status CommitStatus) error
+	// GetMemberAccessLevel returns the access level a user has in a project,
+	// including the access inherited from its groups, or NoAccess.
+	GetMemberAccessLevel(project string, userID int) (AccessLevel, error)
+}
+
+// AccessLevel is the role of a project member.
+type AccessLevel int
+
+// The access levels of project members.
+const (
+	NoAccess         AccessLevel = 0
+	GuestAccess      AccessLevel = 10
+	ReporterAccess   AccessLevel = 20
+	DeveloperAccess  AccessLevel = 30
+	MaintainerAccess AccessLevel = 40
+	OwnerAccess      AccessLevel = 50
+)
+
+// The states of commit statuses.
+const (
+	StatusPending  = "pending"
+	StatusRunning  = "running"
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// The merge statuses of merge requests.
+const (
+	MergeStatusCanBeMerged    = "can_be_merged"
+	MergeStatusCannotBeMerged = "cannot_be_merged"
+)
+
+// User is a GitLab user.
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	WebURL   string `json:"web_url,omitempty"`
+}
+
+// MergeRequest is a merge request as returned by the REST API.
+type MergeRequest struct {
+	ID           int        `json:"id"`
+	IID          int        `json:"iid"`
+	ProjectID    int        `json:"project_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	State        string     `json:"state"`
+	TargetBranch string     `json:"target_branch"`
+	SourceBranch string     `json:"source_branch"`
+	SHA          string     `json:"sha"`
+	Author       User       `json:"author"`
+	Labels       []string   `json:"labels"`
+	Draft        bool       `json:"draft"`
+	MergeStatus  string     `json:"merge_status"`
+	HasConflicts bool       `json:"has_conflicts"`
+	WebURL       string     `json:"web_url"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// HasLabel returns true if the merge request has the label.
+func (mr *MergeRequest) HasLabel(label string) bool {
+	for _, l := range mr.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Branch is a branch of a project.
+type Branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+// CommitStatus is the status of a context of a commit, GitLab calls the
+// context the name of the status.
+type CommitStatus struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+type member struct {
+	AccessLevel AccessLevel `json:"access_level"`
+}
+
+// RequestError is returned for unsuccessful responses.
+type RequestError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the error is a 404 response.
+func IsNotFound(err error) bool {
+	var reqErr *RequestError
+	return errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusNotFound
+}
+
+type client struct {
+	instance string
+	token    func() []byte
+	dryRun   bool
+	http     *retryablehttp.Client
+	logger   *logrus.Entry
+}
+
+// NewClient returns a client for the GitLab instance that authenticates with
+// the personal, group or project access token returned by token. A dry run
+// client only logs the requests that would change anything.
+func NewClient(instance string, token func() []byte, dryRun bool) (Client, error) {
+	u, err := url.ParseRequestURI(instance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitLab instance %q: %w", instance, err)
+	}
+	logger := logrus.WithFields(logrus.Fields{"client": "gitlab", "instance": u.Host})
+	retryingClient := retryablehttp.NewClient()
+	retryingClient.HTTPClient.Timeout = time.Minute
+	retryingClient.Logger = nil
+	return &client{
+		instance: strings.TrimSuffix(u.String(), "/"),
+		token:    token,
+		dryRun:   dryRun,
+		http:     retryingClient,
+		logger:   logger,
+	}, nil
+}
+
+func (c *client) Instance() string {
+	return c.instance
+}
+
+// request sends a request to the API and decodes the JSON response into ret,
+// unless it is nil. It returns the next page of paginated responses.
+func (c *client) request(method, path string, query url.Values, body, ret interface{}) (int, error) {
+	if c.dryRun && method != http.MethodGet {
+		c.logger.WithFields(logrus.Fields{"method": method, "path": path}).Info("Dry run, not sending request.")
+		return 0, nil
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	u := c.instance + "/api/v4" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := retryablehttp.NewRequest(method, u, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", string(c.token()))
+	req.Header.Set("User-Agent", version.UserAgent())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, &RequestError{StatusCode: resp.StatusCode, Message: string(b)}
+	}
+	if ret != nil {
+		if err := json.Unmarshal(b, ret); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return next, nil
+}
+
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+func (c *client) GetMergeRequest(project string, iid int) (*MergeRequest, error) {
+	var mr MergeRequest
+	if _, err := c.request(http.MethodGet, fmt.Sprintf("%s/merge_requests/%d", projectPath(project), iid), nil, nil, &mr); err != nil {
+		return nil, fmt.Errorf("failed to get merge request %s!%d: %w", project, iid, err)
+	}
+	return &mr, nil
+}
+
+func (c *client) ListMergeRequests(project string, labels []string) ([]MergeRequest, error) {
+	var all []MergeRequest
+	query := url.Values{"state": {"opened"}, "per_page": {"100"}}
+	if len(labels) > 0 {
+		query.Set("labels", strings.Join(labels, ","))
+	}
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var mrs []MergeRequest
+		next, err := c.request(http.MethodGet, projectPath(project)+"/merge_requests", query, nil, &mrs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list merge requests of %s: %w", project, err)
+		}
+		all = append(all, mrs...)
+		page = next
+	}
+	return all, nil
+}
+
+func (c *client) GetMergeRequestChanges(project string, iid int) ([]string, error) {
+	var changes struct {
+		Changes []struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+		} `json:"changes"`
+	}
+	if _, err := c.request(http.MethodGet, fmt.Sprintf("%s/merge_requests/%d/changes", projectPath(project), iid), nil, nil, &changes); err != nil {
+		return nil, fmt.Errorf("failed to get changes of merge request %s!%d: %w", project, iid, err)
+	}
+	var files []string
+	for _, change := range changes.Changes {
+		files = append(files, change.NewPath)
+		if change.OldPath != change.NewPath {
+			files = append(files, change.OldPath)
+		}
+	}
+	return files, nil
+}
+
+func (c *client) CreateMergeRequestNote(project string, iid int, body string) error {
+	if _, err := c.request(http.MethodPost, fmt.Sprintf("%s/merge_requests/%d/notes", projectPath(project), iid), nil, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("failed to comment on merge request %s!%d: %w", project, iid, err)
+	}
+	return nil
+}
+
+func (c *client) AcceptMergeRequest(project string, iid int, sha string, squash bool) error {
+	body := map[string]interface{}{"sha": sha, "squash": squash}
+	if _, err := c.request(http.MethodPut, fmt.Sprintf("%s/merge_requests/%d/merge", projectPath(project), iid), nil, body, nil); err != nil {
+		return fmt.Errorf("failed to merge merge request %s!%d: %w", project, iid, err)
+	}
+	return nil
+}
+
+func (c *client) GetBranch(project, branch string) (*Branch, error) {
+	var b Branch
+	if _, err := c.request(http.MethodGet, projectPath(project)+"/repository/branches/"+url.PathEscape(branch), nil, nil, &b); err != nil {
+		return nil, fmt.Errorf("failed to get branch %s of %s: %w", branch, project, err)
+	}
+	return &b, nil
+}
+
+func (c *client) ListCommitStatuses(project, sha string) ([]CommitStatus, error) {
+	var all []CommitStatus
+	query := url.Values{"per_page": {"100"}}
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var statuses []CommitStatus
+		next, err := c.request(http.MethodGet, fmt.Sprintf("%s/repository/commits/%s/statuses", projectPath(project), sha), query, nil, &statuses)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statuses of %s@%s: %w", project, sha, err)
+		}
+		all = append(all, statuses...)
+		page = next
+	}
+	return all, nil
+}
+
+func (c *client) SetCommitStatus(project, sha string, status CommitStatus) error {
+	body := map[string]string{
+		"state":       status.Status,
+		"name":        status.Name,
+		"description": status.Description,
+		"target_url":  status.TargetURL,
+	}
+	if _, err := c.request(http.MethodPost, fmt.Sprintf("%s/statuses/%s", projectPath(project), sha), nil, body, nil); err != nil {
+		return fmt.Errorf("failed to set status %s of %s@%s: %w", status.Name, project, sha, err)
+	}
+	return nil
+}
+
+func (c *client) GetMemberAccessLevel(project string, userID int) (AccessLevel, error) {
+	var m member
+	if _, err := c.request(http.MethodGet, fmt.Sprintf("%s/members/all/%d", projectPath(project), userID), nil, nil, &m); err != nil {
+		if IsNotFound(err) {
+			return NoAccess, nil
+		}
+		return NoAccess, fmt.Errorf("failed to get member %d of %s: %w", userID, project, err)
+	}
+	return m.AccessLevel, nil
+}
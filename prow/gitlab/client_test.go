@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, dryRun bool) Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("PRIVATE-TOKEN"); token != "secret" {
+			t.Errorf("expected token secret, got %q", token)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	c, err := NewClient(server.URL, func() []byte { return []byte("secret") }, dryRun)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestListMergeRequests(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/merge_requests" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		if labels := r.URL.Query().Get("labels"); labels != "lgtm,approved" {
+			t.Errorf("expected labels lgtm,approved, got %q", labels)
+		}
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("X-Next-Page", "2")
+		}
+		fmt.Fprintf(w, `[{"iid": %s}]`, page)
+	}, false)
+
+	mrs, err := c.ListMergeRequests("group/project", []string{"lgtm", "approved"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var iids []int
+	for _, mr := range mrs {
+		iids = append(iids, mr.IID)
+	}
+	if expected := []int{1, 2}; !reflect.DeepEqual(iids, expected) {
+		t.Errorf("expected merge requests %v, got %v", expected, iids)
+	}
+}
+
+func TestSetCommitStatus(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %t", dryRun), func(t *testing.T) {
+			var body map[string]string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject/statuses/abc" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+				fmt.Fprint(w, `{}`)
+			}, dryRun)
+
+			if err := c.SetCommitStatus("group/project", "abc", CommitStatus{Name: "unit", Status: StatusRunning}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dryRun && body != nil {
+				t.Errorf("expected no request in dry run, got %v", body)
+			}
+			if !dryRun && (body["name"] != "unit" || body["state"] != StatusRunning) {
+				t.Errorf("unexpected body %v", body)
+			}
+		})
+	}
+}
+
+func TestGetMemberAccessLevel(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/members/all/1"):
+			fmt.Fprint(w, `{"access_level": 30}`)
+		case strings.HasSuffix(r.URL.Path, "/members/all/2"):
+			http.Error(w, `{"message": "404 Not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, "boom", http.StatusBadRequest)
+		}
+	}, false)
+
+	testCases := []struct {
+		user          int
+		expected      AccessLevel
+		expectedError bool
+	}{
+		{user: 1, expected: DeveloperAccess},
+		{user: 2, expected: NoAccess},
+		{user: 3, expectedError: true},
+	}
+	for _, tc := range testCases {
+		level, err := c.GetMemberAccessLevel("group/project", tc.user)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("user %d: expected error %t, got %v", tc.user, tc.expectedError, err)
+		}
+		if level != tc.expected {
+			t.Errorf("user %d: expected access level %d, got %d", tc.user, tc.expected, level)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakegitlab provides a fake GitLab client for tests.
+package fakegitlab
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/test-infra/prow/gitlab"
+)
+
+// Client is a fake gitlab.Client. Merge requests, branches, statuses and
+// members are keyed by project.
+type Client struct {
+	lock sync.Mutex
+
+	URL string
+	// MergeRequests by project
+	MergeRequests map[string][]gitlab.MergeRequest
+	// Changes by project and merge request IID
+	Changes map[string]map[int][]string
+	// Branches maps project/branch to the SHA of the branch
+	Branches map[string]string
+	// Statuses by project and SHA, in the order they were set
+	Statuses map[string]map[string][]gitlab.CommitStatus
+	// Members maps projects and user IDs to access levels
+	Members map[string]map[int]gitlab.AccessLevel
+
+	// Notes by project and merge request IID
+	Notes map[string]map[int][]string
+	// Merged are the merged merge requests, as project!iid
+	Merged []string
+	// MergeErrors fail merging merge requests, by project!iid
+	MergeErrors map[string]error
+}
+
+var _ gitlab.Client = (*Client)(nil)
+
+// NewClient returns a fake client for https://gitlab.example.com.
+func NewClient() *Client {
+	return &Client{
+		URL:           "https://gitlab.example.com",
+		MergeRequests: map[string][]gitlab.MergeRequest{},
+		Changes:       map[string]map[int][]string{},
+		Branches:      map[string]string{},
+		Statuses:      map[string]map[string][]gitlab.CommitStatus{},
+		Members:       map[string]map[int]gitlab.AccessLevel{},
+		Notes:         map[string]map[int][]string{},
+		MergeErrors:   map[string]error{},
+	}
+}
+
+func (c *Client) Instance() string {
+	return c.URL
+}
+
+func (c *Client) GetMergeRequest(project string, iid int) (*gitlab.MergeRequest, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, mr := range c.MergeRequests[project] {
+		if mr.IID == iid {
+			mr := mr
+			return &mr, nil
+		}
+	}
+	return nil, &gitlab.RequestError{StatusCode: 404, Message: "merge request not found"}
+}
+
+func (c *Client) ListMergeRequests(project string, labels []string) ([]gitlab.MergeRequest, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var mrs []gitlab.MergeRequest
+	for _, mr := range c.MergeRequests[project] {
+		if mr.State != "opened" {
+			continue
+		}
+		hasLabels := true
+		for _, label := range labels {
+			hasLabels = hasLabels && mr.HasLabel(label)
+		}
+		if hasLabels {
+			mrs = append(mrs, mr)
+		}
+	}
+	return mrs, nil
+}
+
+func (c *Client) GetMergeRequestChanges(project string, iid int) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Changes[project][iid], nil
+}
+
+func (c *Client) CreateMergeRequestNote(project string, iid int, body string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.Notes[project] == nil {
+		c.Notes[project] = map[int][]string{}
+	}
+	c.Notes[project][iid] = append(c.Notes[project][iid], body)
+	return nil
+}
+
+func (c *Client) AcceptMergeRequest(project string, iid int, sha string, squash bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := fmt.Sprintf("%s!%d", project, iid)
+	if err := c.MergeErrors[key]; err != nil {
+		return err
+	}
+	for i, mr := range c.MergeRequests[project] {
+		if mr.IID != iid {
+			continue
+		}
+		if mr.SHA != sha {
+			return &gitlab.RequestError{StatusCode: 409, Message: "SHA does not match HEAD of source branch"}
+		}
+		c.MergeRequests[project][i].State = "merged"
+		c.Merged = append(c.Merged, key)
+		return nil
+	}
+	return &gitlab.RequestError{StatusCode: 404, Message: "merge request not found"}
+}
+
+func (c *Client) GetBranch(project, branch string) (*gitlab.Branch, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	sha, ok := c.Branches[project+"/"+branch]
+	if !ok {
+		return nil, &gitlab.RequestError{StatusCode: 404, Message: "branch not found"}
+	}
+	b := &gitlab.Branch{Name: branch}
+	b.Commit.ID = sha
+	return b, nil
+}
+
+// ListCommitStatuses returns the latest status of each context, like GitLab does.
+func (c *Client) ListCommitStatuses(project, sha string) ([]gitlab.CommitStatus, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	latest := map[string]int{}
+	var statuses []gitlab.CommitStatus
+	for _, status := range c.Statuses[project][sha] {
+		if i, ok := latest[status.Name]; ok {
+			statuses[i] = status
+			continue
+		}
+		latest[status.Name] = len(statuses)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (c *Client) SetCommitStatus(project, sha string, status gitlab.CommitStatus) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.Statuses[project] == nil {
+		c.Statuses[project] = map[string][]gitlab.CommitStatus{}
+	}
+	c.Statuses[project][sha] = append(c.Statuses[project][sha], status)
+	return nil
+}
+
+func (c *Client) GetMemberAccessLevel(project string, userID int) (gitlab.AccessLevel, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Members[project][userID], nil
+}
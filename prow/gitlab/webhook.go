@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitlab
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EventHeader is the header that carries the kind of webhook event.
+	EventHeader = "X-Gitlab-Event"
+	// TokenHeader is the header that carries the secret token of the webhook.
+	TokenHeader = "X-Gitlab-Token"
+
+	MergeRequestHook = "Merge Request Hook"
+	NoteHook         = "Note Hook"
+
+	// The actions of merge request events.
+	MergeRequestActionOpen   = "open"
+	MergeRequestActionReopen = "reopen"
+	MergeRequestActionUpdate = "update"
+
+	// NoteableTypeMergeRequest is the noteable type of merge request notes.
+	NoteableTypeMergeRequest = "MergeRequest"
+)
+
+// Project is the project of a webhook event.
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	GitHTTPURL        string `json:"git_http_url"`
+}
+
+// MergeRequestAttributes are the attributes of a merge request in webhook
+// events, which differ from the ones returned by the REST API.
+type MergeRequestAttributes struct {
+	IID             int    `json:"iid"`
+	Title           string `json:"title"`
+	State           string `json:"state"`
+	TargetBranch    string `json:"target_branch"`
+	SourceBranch    string `json:"source_branch"`
+	SourceProjectID int    `json:"source_project_id"`
+	TargetProjectID int    `json:"target_project_id"`
+	AuthorID        int    `json:"author_id"`
+	URL             string `json:"url"`
+	Draft           bool   `json:"draft"`
+	WorkInProgress  bool   `json:"work_in_progress"`
+	LastCommit      struct {
+		ID string `json:"id"`
+	} `json:"last_commit"`
+	// Action is only set in merge request events.
+	Action string `json:"action,omitempty"`
+	// OldRev is set in update events that pushed new commits.
+	OldRev string `json:"oldrev,omitempty"`
+}
+
+// MergeRequestEvent is sent when a merge request is opened, updated, merged
+// or closed.
+type MergeRequestEvent struct {
+	ObjectKind       string                 `json:"object_kind"`
+	User             User                   `json:"user"`
+	Project          Project                `json:"project"`
+	ObjectAttributes MergeRequestAttributes `json:"object_attributes"`
+}
+
+// NoteEvent is sent when a comment is made on a commit, issue, snippet or
+// merge request.
+type NoteEvent struct {
+	ObjectKind       string  `json:"object_kind"`
+	User             User    `json:"user"`
+	Project          Project `json:"project"`
+	ObjectAttributes struct {
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		URL          string `json:"url"`
+	} `json:"object_attributes"`
+	// MergeRequest is only set for merge request notes.
+	MergeRequest *MergeRequestAttributes `json:"merge_request,omitempty"`
+}
+
+// ValidateWebhook ensures that the request carries the secret token of the
+// webhook. It returns the event type and payload, and whether the webhook is
+// valid. Invalid requests are responded to.
+func ValidateWebhook(w http.ResponseWriter, r *http.Request, tokenGenerator func() []byte) (string, []byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "405 Method not allowed", http.StatusMethodNotAllowed)
+		return "", nil, false
+	}
+	token := tokenGenerator()
+	if len(token) == 0 || subtle.ConstantTimeCompare([]byte(r.Header.Get(TokenHeader)), token) != 1 {
+		logrus.Debug("Invalid GitLab webhook token.")
+		http.Error(w, "403 Forbidden: invalid token", http.StatusForbidden)
+		return "", nil, false
+	}
+	eventType := r.Header.Get(EventHeader)
+	if eventType == "" {
+		http.Error(w, "400 Bad Request: missing "+EventHeader+" header", http.StatusBadRequest)
+		return "", nil, false
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: failed to read request body", http.StatusInternalServerError)
+		return "", nil, false
+	}
+	return eventType, payload, true
+}
@@ -0,0 +1,429 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventqueue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LogFile is the name of the write-ahead log in the directory of a
+	// disk queue.
+	LogFile = "events.wal"
+	// compactAfter is the number of records appended to the log after
+	// which it is compacted.
+	compactAfter = 10000
+)
+
+// record is a line of the write-ahead log.
+type record struct {
+	Enqueue *Event `json:"enqueue,omitempty"`
+	Ack     string `json:"ack,omitempty"`
+}
+
+type diskEntry struct {
+	received time.Time
+	acked    bool
+}
+
+// diskQueue is a Queue that appends its events and acks to a write-ahead
+// log on a local disk, e.g. a persistent volume of hook. The log belongs
+// to a single replica of hook.
+type diskQueue struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+
+	// lock guards the fields below. It is held while appending records to
+	// the log, but not while waiting for them to be persisted.
+	lock sync.Mutex
+	log  *os.File
+	// size is the size of the log up to the end of its last record.
+	size int64
+	// written is the number of records written to the log, it is never
+	// reset so that appends can wait for their record to be persisted.
+	written uint64
+	// entries are the events in the log by GUID.
+	entries map[string]*diskEntry
+	// appended is the number of records appended since the last compaction.
+	appended   int
+	compacting bool
+
+	// syncLock serializes syncing the log and replacing it. A single sync
+	// persists the records of all appends that wait for it. It has to be
+	// acquired before lock.
+	syncLock sync.Mutex
+	// synced is the number of records that were persisted.
+	synced uint64
+}
+
+// NewDiskQueue returns a Queue that persists events in a write-ahead log in
+// the given directory. Processed events are kept for the retention in order
+// to deduplicate and replay them, events that were not processed yet are
+// kept until they are.
+func NewDiskQueue(dir string, retention time.Duration) (Queue, error) {
+	return newDiskQueue(dir, retention, time.Now)
+}
+
+func newDiskQueue(dir string, retention time.Duration, now func() time.Time) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	q := &diskQueue{dir: dir, retention: retention, now: now, entries: map[string]*diskEntry{}}
+	info, err := os.Stat(q.path())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat %s: %w", q.path(), err)
+	}
+	if err == nil {
+		q.size = info.Size()
+	}
+	q.compacting = true
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *diskQueue) path() string {
+	return filepath.Join(q.dir, LogFile)
+}
+
+// ReadLog returns the events of the write-ahead log at the given path in
+// the order they were enqueued, regardless of their retention.
+func ReadLog(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	return readLog(f, path)
+}
+
+func readLog(log io.Reader, path string) ([]Entry, error) {
+	var entries []Entry
+	index := map[string]int{}
+	r := bufio.NewReader(log)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing line without newline is a record that was not
+			// completely written, its event was never acknowledged to GitHub.
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			logrus.WithError(err).WithField("path", path).Warn("Skipping corrupt record of the event log.")
+			continue
+		}
+		switch {
+		case rec.Enqueue != nil:
+			index[rec.Enqueue.GUID] = len(entries)
+			entries = append(entries, Entry{Event: *rec.Enqueue})
+		case rec.Ack != "":
+			if i, ok := index[rec.Ack]; ok {
+				entries[i].Acked = true
+			}
+		}
+	}
+	return entries, nil
+}
+
+// readLogUpTo reads the records of the log that end before size. Records
+// are only appended to the log and it is only replaced by a compaction, so
+// a file that was opened while holding the lock can be read after
+// releasing it.
+func (q *diskQueue) readLogUpTo(f *os.File, size int64) ([]Entry, error) {
+	return readLog(io.NewSectionReader(f, 0, size), q.path())
+}
+
+// openLog opens the log for reading and returns it with the size of its
+// records. It returns a nil file if the log does not exist.
+func (q *diskQueue) openLog() (*os.File, int64, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	f, err := os.Open(q.path())
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open %s: %w", q.path(), err)
+	}
+	return f, q.size, nil
+}
+
+// retained returns whether an event has to be kept in the queue.
+func (q *diskQueue) retained(received time.Time, acked bool) bool {
+	return !acked || q.now().Sub(received) < q.retention
+}
+
+// compact rewrites the log with the retained events only. The log is
+// rewritten without holding the locks, only the records that were appended
+// in the meantime are copied while holding them before the log is
+// replaced. The caller has to set compacting.
+func (q *diskQueue) compact() error {
+	defer func() {
+		q.lock.Lock()
+		q.compacting = false
+		q.lock.Unlock()
+	}()
+
+	q.lock.Lock()
+	appended := q.appended
+	q.lock.Unlock()
+	log, size, err := q.openLog()
+	if err != nil {
+		return err
+	}
+	var entries []Entry
+	if log != nil {
+		defer log.Close()
+		if entries, err = q.readLogUpTo(log, size); err != nil {
+			return err
+		}
+	}
+
+	tmp := q.path() + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	index := map[string]*diskEntry{}
+	var dropped []string
+	for i := range entries {
+		e := &entries[i]
+		if !q.retained(e.Received, e.Acked) {
+			dropped = append(dropped, e.GUID)
+			continue
+		}
+		if err := enc.Encode(record{Enqueue: &e.Event}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", tmp, err)
+		}
+		if e.Acked {
+			if err := enc.Encode(record{Ack: e.GUID}); err != nil {
+				return fmt.Errorf("failed to write %s: %w", tmp, err)
+			}
+		}
+		index[e.GUID] = &diskEntry{received: e.Received, acked: e.Acked}
+	}
+
+	q.syncLock.Lock()
+	defer q.syncLock.Unlock()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.size > size {
+		// Copy the records that were appended while rewriting the log.
+		if _, err := io.Copy(w, io.NewSectionReader(log, size, q.size-size)); err != nil {
+			return fmt.Errorf("failed to copy the records appended to %s: %w", q.path(), err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmp, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, q.path()); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", q.path(), err)
+	}
+	if err := syncDir(q.dir); err != nil {
+		return err
+	}
+
+	if q.log != nil {
+		q.log.Close()
+	}
+	q.log, err = os.OpenFile(q.path(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", q.path(), err)
+	}
+	q.size = info.Size()
+	// The copied records were persisted with the new log.
+	q.synced = q.written
+	q.appended -= appended
+	// Entries that were enqueued or acked in the meantime are up to date
+	// already, events of the log are only missing when opening the queue.
+	for guid, entry := range index {
+		if _, ok := q.entries[guid]; !ok {
+			q.entries[guid] = entry
+		}
+	}
+	for _, guid := range dropped {
+		delete(q.entries, guid)
+	}
+	return nil
+}
+
+// syncDir persists the entries of a directory, e.g. a renamed file.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// append writes the record to the log and returns its number, persist
+// waits for it to be persisted. The caller has to hold the lock.
+func (q *diskQueue) append(rec record) (uint64, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal record: %w", err)
+	}
+	n, err := q.log.Write(append(b, '\n'))
+	q.size += int64(n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", q.path(), err)
+	}
+	q.written++
+	q.appended++
+	return q.written, nil
+}
+
+// persist waits until the record with the given number is persisted.
+// Appends that wait at the same time share a single sync of the log.
+func (q *diskQueue) persist(written uint64) error {
+	q.syncLock.Lock()
+	defer q.syncLock.Unlock()
+	if q.synced >= written {
+		return nil
+	}
+	q.lock.Lock()
+	log, target := q.log, q.written
+	q.lock.Unlock()
+	if err := log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", q.path(), err)
+	}
+	q.synced = target
+	return nil
+}
+
+// compactInBackground compacts the log once enough records were appended to
+// it. The caller has to hold the lock.
+func (q *diskQueue) compactInBackground() {
+	if q.compacting || q.appended < compactAfter {
+		return
+	}
+	q.compacting = true
+	go func() {
+		// The log is compacted again after the next append if this fails.
+		if err := q.compact(); err != nil {
+			logrus.WithError(err).Warn("Failed to compact the event log.")
+		}
+	}()
+}
+
+func (q *diskQueue) Enqueue(_ context.Context, e Event) (bool, error) {
+	q.lock.Lock()
+	if _, ok := q.entries[e.GUID]; ok {
+		q.lock.Unlock()
+		return false, nil
+	}
+	written, err := q.append(record{Enqueue: &e})
+	if err != nil {
+		q.lock.Unlock()
+		return false, err
+	}
+	q.entries[e.GUID] = &diskEntry{received: e.Received}
+	q.compactInBackground()
+	q.lock.Unlock()
+
+	if err := q.persist(written); err != nil {
+		// GitHub delivers the event again, it must not be ignored then.
+		q.lock.Lock()
+		delete(q.entries, e.GUID)
+		q.lock.Unlock()
+		return false, err
+	}
+	return true, nil
+}
+
+func (q *diskQueue) Ack(_ context.Context, guid string) error {
+	q.lock.Lock()
+	entry, ok := q.entries[guid]
+	if !ok || entry.acked {
+		q.lock.Unlock()
+		return nil
+	}
+	written, err := q.append(record{Ack: guid})
+	if err != nil {
+		q.lock.Unlock()
+		return err
+	}
+	entry.acked = true
+	q.compactInBackground()
+	q.lock.Unlock()
+	return q.persist(written)
+}
+
+// Claim only checks whether the event was acked, the log belongs to a
+// single replica of hook which does not need to claim events from others.
+func (q *diskQueue) Claim(_ context.Context, guid string, _ time.Duration) (bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	entry, ok := q.entries[guid]
+	return ok && !entry.acked, nil
+}
+
+// List reads the log without holding the lock, so that it does not block
+// appending to it.
+func (q *diskQueue) List(_ context.Context, acked bool) ([]Entry, error) {
+	log, size, err := q.openLog()
+	if err != nil || log == nil {
+		return nil, err
+	}
+	defer log.Close()
+	entries, err := q.readLogUpTo(log, size)
+	if err != nil {
+		return nil, err
+	}
+	var res []Entry
+	for _, e := range entries {
+		if (acked || !e.Acked) && q.retained(e.Received, e.Acked) {
+			res = append(res, e)
+		}
+	}
+	sortEntries(res)
+	return res, nil
+}
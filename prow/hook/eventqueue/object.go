@@ -0,0 +1,299 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	utilpointer "k8s.io/utils/pointer"
+
+	pkgio "k8s.io/test-infra/prow/io"
+)
+
+// objectQueue is a Queue that stores every event, ack and claim as an
+// object, so that it can be shared by the replicas of hook. Objects are
+// never deleted, the bucket needs a lifecycle rule that deletes them once
+// they are older than the retention.
+type objectQueue struct {
+	opener    pkgio.Opener
+	path      string
+	retention time.Duration
+	now       func() time.Time
+
+	lock sync.Mutex
+	// seen are the GUIDs enqueued by this replica and when, so that
+	// retries of GitHub do not need to hit the storage.
+	seen map[string]time.Time
+}
+
+// NewObjectQueue returns a Queue that persists events as objects under the
+// given path, e.g. gs://bucket/hook. Processed events are listed for the
+// retention, events that were not processed yet until they are deleted.
+func NewObjectQueue(opener pkgio.Opener, path string, retention time.Duration) Queue {
+	return newObjectQueue(opener, path, retention, time.Now)
+}
+
+func newObjectQueue(opener pkgio.Opener, path string, retention time.Duration, now func() time.Time) *objectQueue {
+	return &objectQueue{
+		opener:    opener,
+		path:      strings.TrimSuffix(path, "/"),
+		retention: retention,
+		now:       now,
+		seen:      map[string]time.Time{},
+	}
+}
+
+func (q *objectQueue) eventsDir() string {
+	return q.path + "/events/"
+}
+
+func (q *objectQueue) acksDir() string {
+	return q.path + "/acks/"
+}
+
+// claimsDir is the directory of the claims of an event. Its claims are
+// numbered in the order they were created.
+func (q *objectQueue) claimsDir(guid string) string {
+	return q.path + "/claims/" + url.PathEscape(guid) + "/"
+}
+
+func (q *objectQueue) eventPath(guid string) string {
+	return q.eventsDir() + url.PathEscape(guid) + ".json"
+}
+
+func (q *objectQueue) ackPath(guid string) string {
+	return q.acksDir() + url.PathEscape(guid)
+}
+
+// markSeen records the GUID and returns whether it was seen before. GUIDs
+// that are older than the retention are forgotten.
+func (q *objectQueue) markSeen(guid string, received time.Time) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, ok := q.seen[guid]; ok {
+		return true
+	}
+	for g, r := range q.seen {
+		if q.now().Sub(r) >= q.retention {
+			delete(q.seen, g)
+		}
+	}
+	q.seen[guid] = received
+	return false
+}
+
+func (q *objectQueue) forget(guid string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.seen, guid)
+}
+
+func (q *objectQueue) Enqueue(ctx context.Context, e Event) (bool, error) {
+	if q.markSeen(e.GUID, e.Received) {
+		return false, nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		q.forget(e.GUID)
+		return false, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	// Another replica may have received the event already.
+	created, err := q.write(ctx, q.eventPath(e.GUID), b)
+	if err != nil {
+		q.forget(e.GUID)
+		return false, err
+	}
+	return created, nil
+}
+
+// write creates the object unless it exists already and returns whether it
+// did.
+func (q *objectQueue) write(ctx context.Context, path string, content []byte) (bool, error) {
+	if _, err := q.opener.Attributes(ctx, path); err == nil {
+		return false, nil
+	} else if !pkgio.IsNotExist(err) {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	w, err := q.opener.Writer(ctx, path, pkgio.WriterOptions{PreconditionDoesNotExist: utilpointer.BoolPtr(true)})
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	_, writeErr := w.Write(content)
+	closeErr := w.Close()
+	for _, err := range []error{writeErr, closeErr} {
+		if isAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return true, nil
+}
+
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return true
+	}
+	return errors.Is(err, pkgio.PreconditionFailedObjectAlreadyExists)
+}
+
+func (q *objectQueue) Ack(ctx context.Context, guid string) error {
+	_, err := q.write(ctx, q.ackPath(guid), nil)
+	return err
+}
+
+func (q *objectQueue) exists(ctx context.Context, path string) (bool, error) {
+	_, err := q.opener.Attributes(ctx, path)
+	if pkgio.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// Claim creates the next claim of the event. Objects can only be created
+// but not replaced conditionally, so a replica takes over an expired claim
+// by creating the claim after it. Only one of the replicas that try to take
+// it over at the same time succeeds creating it.
+func (q *objectQueue) Claim(ctx context.Context, guid string, lease time.Duration) (bool, error) {
+	if acked, err := q.exists(ctx, q.ackPath(guid)); err != nil || acked {
+		return false, err
+	}
+	names, err := q.names(ctx, q.claimsDir(guid))
+	if err != nil {
+		return false, err
+	}
+	next := 0
+	for _, name := range names {
+		if n, err := strconv.Atoi(name); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	if next > 0 {
+		last := q.claimsDir(guid) + strconv.Itoa(next-1)
+		claimed, err := q.readTime(ctx, last)
+		if err != nil {
+			return false, err
+		}
+		if q.now().Sub(claimed) < lease {
+			return false, nil
+		}
+	}
+	return q.write(ctx, q.claimsDir(guid)+strconv.Itoa(next), []byte(q.now().Format(time.RFC3339Nano)))
+}
+
+// readTime reads the time a claim was created at.
+func (q *objectQueue) readTime(ctx context.Context, path string) (time.Time, error) {
+	r, err := q.opener.Reader(ctx, path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(content))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// names returns the names of the objects in the directory.
+func (q *objectQueue) names(ctx context.Context, dir string) ([]string, error) {
+	it, err := q.opener.Iterator(ctx, dir, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	var names []string
+	for {
+		attrs, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		if !attrs.IsDir {
+			names = append(names, attrs.ObjName)
+		}
+	}
+	return names, nil
+}
+
+// List only reads the events that are listed, the events that were acked
+// are skipped unless acked is true.
+func (q *objectQueue) List(ctx context.Context, acked bool) ([]Entry, error) {
+	ackNames, err := q.names(ctx, q.acksDir())
+	if err != nil {
+		return nil, err
+	}
+	ackedGUIDs := map[string]bool{}
+	for _, name := range ackNames {
+		ackedGUIDs[name] = true
+	}
+	eventNames, err := q.names(ctx, q.eventsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, name := range eventNames {
+		isAcked := ackedGUIDs[strings.TrimSuffix(name, ".json")]
+		if isAcked && !acked {
+			continue
+		}
+		r, err := q.opener.Reader(ctx, q.eventsDir()+name)
+		if pkgio.IsNotExist(err) {
+			// Deleted by the lifecycle of the bucket in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		var e Entry
+		err = json.NewDecoder(r).Decode(&e.Event)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		e.Acked = isAcked
+		if e.Acked && q.now().Sub(e.Received) >= q.retention {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
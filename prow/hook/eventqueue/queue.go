@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventqueue persists the webhooks hook receives until they are
+// processed, so that restarts of hook do not lose events.
+package eventqueue
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Event is a webhook received by hook.
+type Event struct {
+	// GUID is the X-GitHub-Delivery header of the webhook. Events are
+	// deduplicated by it.
+	GUID     string      `json:"guid"`
+	Type     string      `json:"type"`
+	Payload  []byte      `json:"payload"`
+	Header   http.Header `json:"header,omitempty"`
+	Received time.Time   `json:"received"`
+}
+
+// Entry is an event of a queue.
+type Entry struct {
+	Event
+	// Acked is true once the event was processed.
+	Acked bool `json:"acked,omitempty"`
+}
+
+// Queue persists events between their receipt and their processing.
+// Implementations are safe for concurrent use.
+type Queue interface {
+	// Enqueue persists the event. It returns false without persisting the
+	// event if an event with the same GUID is in the queue already.
+	Enqueue(ctx context.Context, e Event) (bool, error)
+	// Ack marks the event with the given GUID as processed.
+	Ack(ctx context.Context, guid string) error
+	// Claim claims the event with the given GUID in order to process it
+	// again, e.g. because the replica of hook that received it stopped. It
+	// returns false if the event was acked already or if it was claimed less
+	// than the lease ago.
+	Claim(ctx context.Context, guid string, lease time.Duration) (bool, error)
+	// List returns the events of the queue that were not acked yet, oldest
+	// first. If acked is true, it returns the acked events as well, they are
+	// only kept for the retention of the queue.
+	List(ctx context.Context, acked bool) ([]Entry, error)
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Received.Before(entries[j].Received) })
+}
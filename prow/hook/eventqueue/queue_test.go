@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventqueue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pkgio "k8s.io/test-infra/prow/io"
+)
+
+var start = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func event(guid string, received time.Duration) Event {
+	return Event{
+		GUID:     guid,
+		Type:     "issue_comment",
+		Payload:  []byte(`{"action": "created"}`),
+		Received: start.Add(received),
+	}
+}
+
+func guids(entries []Entry) map[string]bool {
+	res := map[string]bool{}
+	for _, e := range entries {
+		res[e.GUID] = e.Acked
+	}
+	return res
+}
+
+// testQueue checks the behavior shared by all queues. newQueue is called
+// again to check that a restarted hook sees the same queue.
+func testQueue(t *testing.T, newQueue func(now func() time.Time) Queue) {
+	ctx := context.Background()
+	now := start
+	clock := func() time.Time { return now }
+	q := newQueue(clock)
+
+	for _, e := range []Event{event("old", 0), event("pending", time.Minute), event("acked", 2*time.Minute)} {
+		added, err := q.Enqueue(ctx, e)
+		if err != nil {
+			t.Fatalf("failed to enqueue %s: %v", e.GUID, err)
+		}
+		if !added {
+			t.Errorf("expected %s to be added", e.GUID)
+		}
+	}
+	if added, err := q.Enqueue(ctx, event("pending", 3*time.Minute)); err != nil || added {
+		t.Errorf("expected a redelivered event to be ignored, got %t, %v", added, err)
+	}
+	for _, guid := range []string{"old", "acked", "acked"} {
+		if err := q.Ack(ctx, guid); err != nil {
+			t.Fatalf("failed to ack %s: %v", guid, err)
+		}
+	}
+
+	entries, err := q.List(ctx, true)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if expected := map[string]bool{"old": true, "pending": false, "acked": true}; !reflect.DeepEqual(guids(entries), expected) {
+		t.Errorf("expected events %v, got %v", expected, guids(entries))
+	}
+	if len(entries) != 3 || entries[0].GUID != "old" || string(entries[1].Payload) != `{"action": "created"}` {
+		t.Errorf("expected the events oldest first with their payloads, got %+v", entries)
+	}
+	pending, err := q.List(ctx, false)
+	if err != nil {
+		t.Fatalf("failed to list pending events: %v", err)
+	}
+	if len(pending) != 1 || pending[0].GUID != "pending" {
+		t.Errorf("expected a single pending event, got %+v", pending)
+	}
+
+	if claimed, err := q.Claim(ctx, "acked", time.Minute); err != nil || claimed {
+		t.Errorf("expected an acked event to not be claimed, got %t, %v", claimed, err)
+	}
+	if claimed, err := q.Claim(ctx, "pending", time.Minute); err != nil || !claimed {
+		t.Errorf("expected a pending event to be claimed, got %t, %v", claimed, err)
+	}
+
+	// Processed events are forgotten after the retention, pending ones are
+	// kept until they are processed.
+	now = start.Add(time.Hour + 90*time.Second)
+	q = newQueue(clock)
+	entries, err = q.List(ctx, true)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if expected := map[string]bool{"pending": false, "acked": true}; !reflect.DeepEqual(guids(entries), expected) {
+		t.Errorf("expected events %v after the retention of old, got %v", expected, guids(entries))
+	}
+	if added, err := q.Enqueue(ctx, event("acked", 3*time.Minute)); err != nil || added {
+		t.Errorf("expected a redelivered event to be ignored after a restart, got %t, %v", added, err)
+	}
+}
+
+func TestDiskQueue(t *testing.T) {
+	dir := t.TempDir()
+	testQueue(t, func(now func() time.Time) Queue {
+		q, err := newDiskQueue(dir, time.Hour, now)
+		if err != nil {
+			t.Fatalf("failed to open queue: %v", err)
+		}
+		return q
+	})
+}
+
+func TestDiskQueuePartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newDiskQueue(dir, time.Hour, time.Now)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	if _, err := q.Enqueue(context.Background(), event("complete", 0)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	// A crash while appending leaves a partial record behind.
+	f, err := os.OpenFile(filepath.Join(dir, LogFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	if _, err := f.WriteString(`{"enqueue": {"guid": "partial"`); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	f.Close()
+
+	q, err = newDiskQueue(dir, time.Hour, time.Now)
+	if err != nil {
+		t.Fatalf("failed to reopen queue: %v", err)
+	}
+	if _, err := q.Enqueue(context.Background(), event("next", time.Minute)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	entries, err := q.List(context.Background(), true)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if expected := map[string]bool{"complete": false, "next": false}; !reflect.DeepEqual(guids(entries), expected) {
+		t.Errorf("expected events %v, got %v", expected, guids(entries))
+	}
+}
+
+func TestDiskQueueCompaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := start.Add(2 * time.Hour)
+	q, err := newDiskQueue(dir, time.Hour, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	for _, guid := range []string{"expired", "pending"} {
+		if _, err := q.Enqueue(ctx, event(guid, 0)); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	}
+	if err := q.Ack(ctx, "expired"); err != nil {
+		t.Fatalf("failed to ack: %v", err)
+	}
+
+	// Events are enqueued and listed while the log is compacted.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			guid := fmt.Sprintf("concurrent-%d", i)
+			if added, err := q.Enqueue(ctx, event(guid, 2*time.Hour)); err != nil || !added {
+				t.Errorf("expected %s to be added, got %t, %v", guid, added, err)
+			}
+			if _, err := q.List(ctx, false); err != nil {
+				t.Errorf("failed to list events: %v", err)
+			}
+		}(i)
+	}
+	q.lock.Lock()
+	q.compacting = true
+	q.lock.Unlock()
+	if err := q.compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	wg.Wait()
+
+	entries, err := ReadLog(filepath.Join(dir, LogFile))
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if len(entries) != 21 {
+		t.Errorf("expected the pending and the concurrently enqueued events in the log, got %d events", len(entries))
+	}
+	if added, err := q.Enqueue(ctx, event("expired", 2*time.Hour)); err != nil || !added {
+		t.Errorf("expected an event to be added again after its retention, got %t, %v", added, err)
+	}
+	if added, err := q.Enqueue(ctx, event("concurrent-3", 2*time.Hour)); err != nil || added {
+		t.Errorf("expected an event enqueued during the compaction to be ignored, got %t, %v", added, err)
+	}
+}
+
+func TestObjectQueue(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "hook"), 0755); err != nil {
+		t.Fatalf("failed to create bucket directory: %v", err)
+	}
+	opener, err := pkgio.NewOpenerWithOptions(context.Background(), pkgio.OpenerOptions{FileStorageRoot: root})
+	if err != nil {
+		t.Fatalf("failed to create opener: %v", err)
+	}
+	testQueue(t, func(now func() time.Time) Queue {
+		return newObjectQueue(opener, "file://hook/queue/", time.Hour, now)
+	})
+
+	// Replicas share the queue.
+	now := start.Add(2 * time.Hour)
+	other := newObjectQueue(opener, "file://hook/queue", time.Hour, func() time.Time { return now })
+	if added, err := other.Enqueue(context.Background(), event("pending", time.Hour)); err != nil || added {
+		t.Errorf("expected an event received by another replica to be ignored, got %t, %v", added, err)
+	}
+
+	// Claims of replicas expire after their lease.
+	ctx := context.Background()
+	if _, err := other.Enqueue(ctx, event("claimed", time.Hour)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	if claimed, err := other.Claim(ctx, "claimed", time.Minute); err != nil || !claimed {
+		t.Fatalf("expected the event to be claimed, got %t, %v", claimed, err)
+	}
+	third := newObjectQueue(opener, "file://hook/queue", time.Hour, func() time.Time { return now })
+	if claimed, err := third.Claim(ctx, "claimed", time.Minute); err != nil || claimed {
+		t.Errorf("expected the event to be claimed by another replica already, got %t, %v", claimed, err)
+	}
+	now = now.Add(time.Minute)
+	if claimed, err := third.Claim(ctx, "claimed", time.Minute); err != nil || !claimed {
+		t.Errorf("expected the expired claim to be taken over, got %t, %v", claimed, err)
+	}
+	if claimed, err := other.Claim(ctx, "claimed", time.Minute); err != nil || claimed {
+		t.Errorf("expected the claim that was taken over to be held, got %t, %v", claimed, err)
+	}
+}
@@ -19,10 +19,7 @@ package hook
 import (
 	"fmt"
 	"runtime/debug"
-	"strconv"
-	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
@@ -69,8 +66,8 @@ var (
 	}
 )
 
-func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewEvent(l *logrus.Entry, wg *eventWaitGroup, re github.ReviewEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  re.Repo.Owner.Login,
 		github.RepoLogField: re.Repo.Name,
@@ -81,23 +78,18 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	})
 	l.Infof("Review %s.", re.Action)
 	for p, h := range s.Plugins.ReviewEventHandlers(re.PullRequest.Base.Repo.Owner.Login, re.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.ReviewEventHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, re.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				re.Repo.Owner.Login,
-				re.Repo.Name,
-				re.PullRequest.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, re) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(re.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling ReviewEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "ReviewEvent", string(re.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, re.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					re.Repo.Owner.Login,
+					re.Repo.Name,
+					re.PullRequest.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, re) })
 		}(p, h)
 	}
 	action := genericCommentAction(string(re.Action))
@@ -107,6 +99,7 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			GUID:         re.GUID,
 			NodeID:       re.Review.NodeID,
@@ -127,8 +120,8 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	)
 }
 
-func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewCommentEvent(l *logrus.Entry, wg *eventWaitGroup, rce github.ReviewCommentEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  rce.Repo.Owner.Login,
 		github.RepoLogField: rce.Repo.Name,
@@ -139,23 +132,18 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	})
 	l.Infof("Review comment %s.", rce.Action)
 	for p, h := range s.Plugins.ReviewCommentEventHandlers(rce.PullRequest.Base.Repo.Owner.Login, rce.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.ReviewCommentEventHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, rce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				rce.Repo.Owner.Login,
-				rce.Repo.Name,
-				rce.PullRequest.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, rce) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(rce.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling ReviewCommentEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "ReviewCommentEvent", string(rce.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, rce.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					rce.Repo.Owner.Login,
+					rce.Repo.Name,
+					rce.PullRequest.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, rce) })
 		}(p, h)
 	}
 	action := genericCommentAction(string(rce.Action))
@@ -165,6 +153,7 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			GUID:         rce.GUID,
 			NodeID:       rce.Comment.NodeID,
@@ -186,8 +175,8 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	)
 }
 
-func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePullRequestEvent(l *logrus.Entry, wg *eventWaitGroup, pr github.PullRequestEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pr.Repo.Owner.Login,
 		github.RepoLogField: pr.Repo.Name,
@@ -197,23 +186,18 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	})
 	l.Infof("Pull request %s.", pr.Action)
 	for p, h := range s.Plugins.PullRequestHandlers(pr.PullRequest.Base.Repo.Owner.Login, pr.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.PullRequestHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pr.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				pr.Repo.Owner.Login,
-				pr.Repo.Name,
-				pr.PullRequest.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, pr) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(pr.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling PullRequestEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "PullRequestEvent", string(pr.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, pr.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					pr.Repo.Owner.Login,
+					pr.Repo.Name,
+					pr.PullRequest.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, pr) })
 		}(p, h)
 	}
 	action := genericCommentAction(string(pr.Action))
@@ -225,6 +209,7 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           pr.PullRequest.ID,
 			NodeID:       pr.PullRequest.NodeID,
@@ -246,8 +231,8 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	)
 }
 
-func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePushEvent(l *logrus.Entry, wg *eventWaitGroup, pe github.PushEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pe.Repo.Owner.Name,
 		github.RepoLogField: pe.Repo.Name,
@@ -256,24 +241,18 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	})
 	l.Info("Push event.")
 	for p, h := range s.Plugins.PushEventHandlers(pe.Repo.Owner.Name, pe.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.PushEventHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pe.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, pe) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling PushEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "PushEvent", "none", func(gates plugins.RequestGates) plugins.Agent {
+				return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, pe.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+			}, func(agent plugins.Agent) error { return h(agent, pe) })
 		}(p, h)
 	}
 }
 
-func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueEvent(l *logrus.Entry, wg *eventWaitGroup, i github.IssueEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  i.Repo.Owner.Login,
 		github.RepoLogField: i.Repo.Name,
@@ -283,23 +262,18 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	})
 	l.Infof("Issue %s.", i.Action)
 	for p, h := range s.Plugins.IssueHandlers(i.Repo.Owner.Login, i.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.IssueHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, i.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				i.Repo.Owner.Login,
-				i.Repo.Name,
-				i.Issue.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, i) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(i.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling IssueEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "IssueEvent", string(i.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, i.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					i.Repo.Owner.Login,
+					i.Repo.Name,
+					i.Issue.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, i) })
 		}(p, h)
 	}
 	action := genericCommentAction(string(i.Action))
@@ -311,6 +285,7 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           i.Issue.ID,
 			NodeID:       i.Issue.NodeID,
@@ -332,8 +307,8 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	)
 }
 
-func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueCommentEvent(l *logrus.Entry, wg *eventWaitGroup, ic github.IssueCommentEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  ic.Repo.Owner.Login,
 		github.RepoLogField: ic.Repo.Name,
@@ -343,23 +318,18 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	})
 	l.Infof("Issue comment %s.", ic.Action)
 	for p, h := range s.Plugins.IssueCommentHandlers(ic.Repo.Owner.Login, ic.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.IssueCommentHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ic.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				ic.Repo.Owner.Login,
-				ic.Repo.Name,
-				ic.Issue.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, ic) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ic.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling IssueCommentEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "IssueCommentEvent", string(ic.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, ic.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					ic.Repo.Owner.Login,
+					ic.Repo.Name,
+					ic.Issue.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, ic) })
 		}(p, h)
 	}
 	action := genericCommentAction(string(ic.Action))
@@ -369,6 +339,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	}
 	s.handleGenericComment(
 		l,
+		wg,
 		&github.GenericCommentEvent{
 			ID:           ic.Issue.ID,
 			NodeID:       ic.Issue.NodeID,
@@ -391,8 +362,8 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	)
 }
 
-func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
-	defer s.wg.Done()
+func (s *Server) handleStatusEvent(l *logrus.Entry, wg *eventWaitGroup, se github.StatusEvent) {
+	defer wg.Done()
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  se.Repo.Owner.Login,
 		github.RepoLogField: se.Repo.Name,
@@ -403,18 +374,75 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	})
 	l.Infof("Status description %s.", se.Description)
 	for p, h := range s.Plugins.StatusEventHandlers(se.Repo.Owner.Login, se.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.StatusEventHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, se.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, se) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling StatusEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "StatusEvent", "none", func(gates plugins.RequestGates) plugins.Agent {
+				return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, se.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+			}, func(agent plugins.Agent) error { return h(agent, se) })
+		}(p, h)
+	}
+}
+
+func (s *Server) handleCheckRunEvent(l *logrus.Entry, wg *eventWaitGroup, cre github.CheckRunEvent) {
+	defer wg.Done()
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  cre.Repo.Owner.Login,
+		github.RepoLogField: cre.Repo.Name,
+		"check_run":         cre.CheckRun.Name,
+		"sha":               cre.CheckRun.HeadSHA,
+		"external_id":       cre.CheckRun.ExternalID,
+		"id":                cre.CheckRun.ID,
+	})
+	l.Infof("Check run %s.", cre.Action)
+	for p, h := range s.Plugins.CheckRunEventHandlers(cre.Repo.Owner.Login, cre.Repo.Name) {
+		wg.Add(1)
+		go func(p string, h plugins.CheckRunEventHandler) {
+			defer wg.Done()
+			s.handleWithLimits(l, p, "CheckRunEvent", string(cre.Action), func(gates plugins.RequestGates) plugins.Agent {
+				return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, cre.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+			}, func(agent plugins.Agent) error { return h(agent, cre) })
+		}(p, h)
+	}
+}
+
+func (s *Server) handleCheckSuiteEvent(l *logrus.Entry, wg *eventWaitGroup, cse github.CheckSuiteEvent) {
+	defer wg.Done()
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  cse.Repo.Owner.Login,
+		github.RepoLogField: cse.Repo.Name,
+		"sha":               cse.CheckSuite.HeadSHA,
+		"id":                cse.CheckSuite.ID,
+	})
+	l.Infof("Check suite %s.", cse.Action)
+	for p, h := range s.Plugins.CheckSuiteEventHandlers(cse.Repo.Owner.Login, cse.Repo.Name) {
+		wg.Add(1)
+		go func(p string, h plugins.CheckSuiteEventHandler) {
+			defer wg.Done()
+			s.handleWithLimits(l, p, "CheckSuiteEvent", string(cse.Action), func(gates plugins.RequestGates) plugins.Agent {
+				return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, cse.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+			}, func(agent plugins.Agent) error { return h(agent, cse) })
+		}(p, h)
+	}
+}
+
+func (s *Server) handleWorkflowRunEvent(l *logrus.Entry, wg *eventWaitGroup, wre github.WorkflowRunEvent) {
+	defer wg.Done()
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  wre.Repo.Owner.Login,
+		github.RepoLogField: wre.Repo.Name,
+		"workflow_id":       wre.WorkflowRun.WorkflowID,
+		"sha":               wre.WorkflowRun.HeadSha,
+		"id":                wre.WorkflowRun.ID,
+	})
+	l.Infof("Workflow run %s.", wre.Action)
+	for p, h := range s.Plugins.WorkflowRunEventHandlers(wre.Repo.Owner.Login, wre.Repo.Name) {
+		wg.Add(1)
+		go func(p string, h plugins.WorkflowRunEventHandler) {
+			defer wg.Done()
+			s.handleWithLimits(l, p, "WorkflowRunEvent", wre.Action, func(gates plugins.RequestGates) plugins.Agent {
+				return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, wre.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+			}, func(agent plugins.Agent) error { return h(agent, wre) })
 		}(p, h)
 	}
 }
@@ -434,25 +462,20 @@ func genericCommentAction(action string) github.GenericCommentEventAction {
 	return ""
 }
 
-func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericCommentEvent) {
+func (s *Server) handleGenericComment(l *logrus.Entry, wg *eventWaitGroup, ce *github.GenericCommentEvent) {
 	for p, h := range s.Plugins.GenericCommentHandlers(ce.Repo.Owner.Login, ce.Repo.Name) {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p string, h plugins.GenericCommentHandler) {
-			defer s.wg.Done()
-			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
-			agent.InitializeCommentPruner(
-				ce.Repo.Owner.Login,
-				ce.Repo.Name,
-				ce.Number,
-			)
-			start := time.Now()
-			err := errorOnPanic(func() error { return h(agent, *ce) })
-			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ce.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
-			if err != nil {
-				agent.Logger.WithError(err).Error("Error handling GenericCommentEvent.")
-				s.Metrics.PluginHandleErrors.With(labels).Inc()
-			}
-			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+			defer wg.Done()
+			s.handleWithLimits(l, p, "GenericCommentEvent", string(ce.Action), func(gates plugins.RequestGates) plugins.Agent {
+				agent := plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p, gates)
+				agent.InitializeCommentPruner(
+					ce.Repo.Owner.Login,
+					ce.Repo.Name,
+					ce.Number,
+				)
+				return agent
+			}, func(agent plugins.Agent) error { return h(agent, *ce) })
 		}(p, h)
 	}
 }
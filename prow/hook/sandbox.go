@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// latencyWindow is the number of recently handled events of a plugin that
+// its latency percentiles are computed over.
+const latencyWindow = 500
+
+var (
+	errPluginTimedOut       = errors.New("plugin timed out handling the event")
+	errGitHubBudgetExceeded = errors.New("plugin used up its GitHub API budget")
+)
+
+// pluginSandbox enforces the configured plugins.PluginLimits on the event
+// handlers of plugins and keeps stats about how they perform. The zero value
+// is ready to use.
+type pluginSandbox struct {
+	lock     sync.Mutex
+	limiters map[string]*pluginLimiter
+	stats    map[string]*pluginStats
+}
+
+// pluginLimiter enforces the limits of a plugin. It is replaced when the
+// limits of the plugin change.
+type pluginLimiter struct {
+	limit plugins.PluginLimit
+	// slots is nil if the concurrency of the plugin is not capped.
+	slots chan struct{}
+	// budget is nil if the plugin has no GitHub API budget.
+	budget *rate.Limiter
+}
+
+type pluginStats struct {
+	plugins.HandlerStats
+	// latencies of the recently handled events, a ring buffer that next
+	// is the index of the oldest entry in once it is full.
+	latencies []time.Duration
+	next      int
+}
+
+func (sb *pluginSandbox) limiterFor(plugin string, limit plugins.PluginLimit) *pluginLimiter {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+	if sb.limiters == nil {
+		sb.limiters = map[string]*pluginLimiter{}
+	}
+	if l, ok := sb.limiters[plugin]; ok && l.limit == limit {
+		return l
+	}
+	l := &pluginLimiter{limit: limit}
+	if limit.MaxConcurrency > 0 {
+		l.slots = make(chan struct{}, limit.MaxConcurrency)
+	}
+	if limit.GitHubHourlyTokens > 0 {
+		l.budget = rate.NewLimiter(rate.Limit(float64(limit.GitHubHourlyTokens)/time.Hour.Seconds()), limit.GitHubBurst)
+	}
+	sb.limiters[plugin] = l
+	return l
+}
+
+// update changes the stats of a plugin while holding the lock.
+func (sb *pluginSandbox) update(plugin string, f func(*pluginStats)) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+	if sb.stats == nil {
+		sb.stats = map[string]*pluginStats{}
+	}
+	stats, ok := sb.stats[plugin]
+	if !ok {
+		stats = &pluginStats{HandlerStats: plugins.HandlerStats{Plugin: plugin}}
+		sb.stats[plugin] = stats
+	}
+	f(stats)
+}
+
+func (s *pluginStats) recordLatency(took time.Duration) {
+	if len(s.latencies) < latencyWindow {
+		s.latencies = append(s.latencies, took)
+		return
+	}
+	s.latencies[s.next] = took
+	s.next = (s.next + 1) % latencyWindow
+}
+
+// Stats returns the stats of all plugins that handled events, sorted by
+// plugin name.
+func (sb *pluginSandbox) Stats() []plugins.HandlerStats {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+	res := make([]plugins.HandlerStats, 0, len(sb.stats))
+	for _, stats := range sb.stats {
+		s := stats.HandlerStats
+		latencies := append([]time.Duration(nil), stats.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		s.LatencyP50 = percentile(latencies, 0.5)
+		s.LatencyP90 = percentile(latencies, 0.9)
+		s.LatencyP99 = percentile(latencies, 0.99)
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Plugin < res[j].Plugin })
+	return res
+}
+
+// percentile returns the p-th percentile of the sorted durations in seconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i].Seconds()
+}
+
+// handleWithLimits runs the event handler of a plugin within the limits
+// configured for the plugin and records how it performed. newAgent creates
+// the agent for the handler, its clients have to call the given gates before
+// every request.
+//
+// Once the timeout of the plugin passed, the handler is considered to have
+// failed and the requests of all of its clients fail. As it may still be
+// stuck outside of its clients, the handler is then accounted as runaway and
+// gives up its slot of the concurrency cap, so it can not block the plugin
+// from handling further events.
+func (s *Server) handleWithLimits(l *logrus.Entry, plugin, eventName, action string, newAgent func(plugins.RequestGates) plugins.Agent, handle func(plugins.Agent) error) {
+	limiter := s.sandbox.limiterFor(plugin, s.Plugins.Config().PluginLimits.For(plugin))
+	limit := limiter.limit
+
+	if limiter.slots != nil {
+		s.sandbox.update(plugin, func(stats *pluginStats) { stats.Waiting++ })
+		s.Metrics.PluginHandlersWaiting.WithLabelValues(plugin).Inc()
+		limiter.slots <- struct{}{}
+		s.Metrics.PluginHandlersWaiting.WithLabelValues(plugin).Dec()
+		s.sandbox.update(plugin, func(stats *pluginStats) { stats.Waiting-- })
+	}
+	var releaseSlot sync.Once
+	release := func() {
+		if limiter.slots != nil {
+			releaseSlot.Do(func() { <-limiter.slots })
+		}
+	}
+	s.sandbox.update(plugin, func(stats *pluginStats) { stats.Running++ })
+
+	start := time.Now()
+	var deadline time.Time
+	if limit.TimeoutDuration > 0 {
+		deadline = start.Add(limit.TimeoutDuration)
+	}
+	gates := plugins.RequestGates{
+		All: func() error {
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return errPluginTimedOut
+			}
+			return nil
+		},
+	}
+	if limiter.budget != nil {
+		gates.GitHub = func() error {
+			if !limiter.budget.Allow() {
+				s.Metrics.PluginGitHubBudgetExceeded.WithLabelValues(plugin).Inc()
+				s.sandbox.update(plugin, func(stats *pluginStats) { stats.GitHubBudgetExceeded++ })
+				return errGitHubBudgetExceeded
+			}
+			return nil
+		}
+	}
+	agent := newAgent(gates)
+
+	// runaway is set once the handler timed out, finished once it returned.
+	var lock sync.Mutex
+	var runaway, finished bool
+	errs := make(chan error, 1)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := errorOnPanic(func() error { return handle(agent) })
+		lock.Lock()
+		finished = true
+		if runaway {
+			s.Metrics.PluginHandlersRunaway.WithLabelValues(plugin).Dec()
+		}
+		s.sandbox.update(plugin, func(stats *pluginStats) {
+			stats.Running--
+			if runaway {
+				stats.Runaway--
+			}
+		})
+		lock.Unlock()
+		release()
+		errs <- err
+	}()
+
+	var timeout <-chan time.Time
+	if limit.TimeoutDuration > 0 {
+		timer := time.NewTimer(limit.TimeoutDuration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	var timedOut bool
+	select {
+	case err = <-errs:
+	case <-timeout:
+		timedOut = true
+		err = fmt.Errorf("%w within %s", errPluginTimedOut, limit.Timeout)
+		lock.Lock()
+		if !finished {
+			runaway = true
+			s.Metrics.PluginHandlersRunaway.WithLabelValues(plugin).Inc()
+			s.sandbox.update(plugin, func(stats *pluginStats) { stats.Runaway++ })
+		}
+		lock.Unlock()
+		release()
+	}
+	took := time.Since(start)
+
+	labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": action, "plugin": plugin, "took_action": strconv.FormatBool(agent.TookAction())}
+	if err != nil {
+		agent.Logger.WithError(err).Errorf("Error handling %s.", eventName)
+		s.Metrics.PluginHandleErrors.With(labels).Inc()
+	}
+	if timedOut {
+		s.Metrics.PluginHandleTimeouts.WithLabelValues(labels["event_type"], action, plugin).Inc()
+	}
+	s.Metrics.PluginHandleDuration.With(labels).Observe(took.Seconds())
+	s.sandbox.update(plugin, func(stats *pluginStats) {
+		stats.Handled++
+		if err != nil {
+			stats.Errors++
+		}
+		if timedOut {
+			stats.Timeouts++
+		}
+		stats.recordLatency(took)
+	})
+}
+
+// ServePluginStats serves the stats of the plugins as JSON.
+func (s *Server) ServePluginStats(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(s.sandbox.Stats())
+	if err != nil {
+		logrus.WithError(err).Error("Marshaling plugin stats.")
+		http.Error(w, "failed to marshal plugin stats", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/bugzilla"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/jira/fakejira"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+func newSandboxTestServer(limits plugins.PluginLimits) *Server {
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{PluginLimits: limits})
+	return &Server{
+		ClientAgent: &plugins.ClientAgent{
+			GitHubClient:   github.NewFakeClient(),
+			OwnersClient:   repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver),
+			JiraClient:     &fakejira.FakeClient{},
+			BugzillaClient: &bugzilla.Fake{},
+		},
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		Metrics:     githubeventserver.NewMetrics(),
+	}
+}
+
+// handleInSandbox runs handle like hook runs a plugin handler, passing the
+// request gates of the agent to it.
+func (s *Server) handleInSandbox(plugin string, handle func(gates plugins.RequestGates) error) {
+	var gates plugins.RequestGates
+	l := logrus.WithField(eventTypeField, "issues")
+	s.handleWithLimits(l, plugin, "IssueEvent", "opened", func(g plugins.RequestGates) plugins.Agent {
+		gates = g
+		return plugins.NewAgentWithRequestGates(s.ConfigAgent, s.Plugins, s.ClientAgent, "org", s.Metrics.Metrics, l, plugin, g)
+	}, func(plugins.Agent) error { return handle(gates) })
+}
+
+func TestHandleWithLimitsTimeout(t *testing.T) {
+	s := newSandboxTestServer(plugins.PluginLimits{Default: plugins.PluginLimit{Timeout: "10ms", TimeoutDuration: 10 * time.Millisecond}})
+	unblock := make(chan struct{})
+	var gateErr error
+	s.handleInSandbox("slow", func(gates plugins.RequestGates) error {
+		<-unblock
+		gateErr = gates.All()
+		return nil
+	})
+
+	stats := s.sandbox.Stats()
+	if len(stats) != 1 || stats[0].Handled != 1 || stats[0].Timeouts != 1 || stats[0].Errors != 1 || stats[0].Running != 1 || stats[0].Runaway != 1 {
+		t.Errorf("expected a single timed out and still running handler, got %+v", stats)
+	}
+	close(unblock)
+	s.GracefulShutdown()
+	if !errors.Is(gateErr, errPluginTimedOut) {
+		t.Errorf("expected requests to fail after the timeout, got %v", gateErr)
+	}
+	if stats := s.sandbox.Stats(); stats[0].Running != 0 || stats[0].Runaway != 0 {
+		t.Errorf("expected no running handler after it returned, got %+v", stats)
+	}
+}
+
+func TestHandleWithLimitsRunawayReleasesSlot(t *testing.T) {
+	s := newSandboxTestServer(plugins.PluginLimits{Default: plugins.PluginLimit{Timeout: "10ms", TimeoutDuration: 10 * time.Millisecond, MaxConcurrency: 1}})
+	unblock := make(chan struct{})
+	s.handleInSandbox("stuck", func(plugins.RequestGates) error {
+		<-unblock
+		return nil
+	})
+
+	handled := make(chan struct{})
+	go func() {
+		s.handleInSandbox("stuck", func(plugins.RequestGates) error { return nil })
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the runaway handler to give up its slot")
+	}
+	if stats := s.sandbox.Stats(); stats[0].Handled != 2 || stats[0].Runaway != 1 || stats[0].Running != 1 {
+		t.Errorf("expected one runaway handler, got %+v", stats)
+	}
+	close(unblock)
+	s.GracefulShutdown()
+	if stats := s.sandbox.Stats(); stats[0].Runaway != 0 || stats[0].Running != 0 {
+		t.Errorf("expected no runaway handler after it returned, got %+v", stats)
+	}
+}
+
+func TestHandleWithLimitsConcurrency(t *testing.T) {
+	s := newSandboxTestServer(plugins.PluginLimits{Plugins: map[string]plugins.PluginLimit{"capped": {MaxConcurrency: 2}}})
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleInSandbox("capped", func(plugins.RequestGates) error {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent handlers, got %d", maxRunning)
+	}
+	if stats := s.sandbox.Stats(); stats[0].Handled != 10 || stats[0].Waiting != 0 {
+		t.Errorf("expected 10 handled events and none waiting, got %+v", stats)
+	}
+}
+
+func TestHandleWithLimitsGitHubBudget(t *testing.T) {
+	s := newSandboxTestServer(plugins.PluginLimits{Default: plugins.PluginLimit{GitHubHourlyTokens: 1, GitHubBurst: 1}})
+	var errs []error
+	s.handleInSandbox("chatty", func(gates plugins.RequestGates) error {
+		errs = append(errs, gates.GitHub(), gates.GitHub(), gates.All())
+		return errs[1]
+	})
+	if errs[0] != nil || !errors.Is(errs[1], errGitHubBudgetExceeded) {
+		t.Errorf("expected the second request to exceed the budget, got %v", errs)
+	}
+	if errs[2] != nil {
+		t.Errorf("expected requests to other services to pass, got %v", errs[2])
+	}
+	if stats := s.sandbox.Stats(); stats[0].GitHubBudgetExceeded != 1 || stats[0].Errors != 1 {
+		t.Errorf("expected the handler to fail because of the exceeded budget, got %+v", stats)
+	}
+
+	// Other plugins have budgets of their own.
+	s.handleInSandbox("quiet", func(gates plugins.RequestGates) error { return gates.GitHub() })
+	if stats := s.sandbox.Stats(); stats[1].Plugin != "quiet" || stats[1].Errors != 0 {
+		t.Errorf("expected another plugin to have its own budget, got %+v", stats)
+	}
+}
+
+func TestHandleWithLimitsPanic(t *testing.T) {
+	s := newSandboxTestServer(plugins.PluginLimits{})
+	s.handleInSandbox("broken", func(plugins.RequestGates) error { panic("boom") })
+	if stats := s.sandbox.Stats(); stats[0].Handled != 1 || stats[0].Errors != 1 || stats[0].Running != 0 {
+		t.Errorf("expected the panic to be handled as an error, got %+v", stats)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Second)
+	}
+	for p, expected := range map[float64]float64{0.5: 50, 0.9: 90, 0.99: 99} {
+		if actual := percentile(latencies, p); actual != expected {
+			t.Errorf("expected percentile %v to be %v, got %v", p, expected, actual)
+		}
+	}
+	if actual := percentile(nil, 0.5); actual != 0 {
+		t.Errorf("expected no latency without events, got %v", actual)
+	}
+}
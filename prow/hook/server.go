@@ -18,6 +18,7 @@ package hook
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/hook/eventqueue"
 	_ "k8s.io/test-infra/prow/hook/plugin-imports"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -45,12 +47,26 @@ type Server struct {
 	TokenGenerator func() []byte
 	Metrics        *githubeventserver.Metrics
 	RepoEnabled    func(org, repo string) bool
+	// Queue persists events until they are processed, events are only
+	// kept in memory if it is nil.
+	Queue eventqueue.Queue
+	// ReplayAfter is how long after their receipt queued events that were
+	// not processed yet are assumed to be lost by the replica of hook that
+	// received them, e.g. because it was stopped, and are handled again by
+	// ReplayPending. It is also the lease of the replica that handles them
+	// again.
+	ReplayAfter time.Duration
 
 	// c is an http client used for dispatching events
 	// to external plugin services.
 	c http.Client
 	// Tracks running handlers for graceful shutdown
 	wg sync.WaitGroup
+	// Enforces the limits of plugins on their handlers
+	sandbox pluginSandbox
+	// handling are the GUIDs of the queued events this replica handles
+	handling     map[string]bool
+	handlingLock sync.Mutex
 }
 
 // ServeHTTP validates an incoming webhook and puts it into the event channel.
@@ -67,14 +83,123 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	e := eventqueue.Event{GUID: eventGUID, Type: eventType, Payload: payload, Header: r.Header, Received: time.Now()}
+	if s.Queue != nil {
+		added, err := s.Queue.Enqueue(r.Context(), e)
+		if err != nil {
+			logrus.WithError(err).WithField(github.EventGUID, eventGUID).Error("Failed to enqueue event.")
+			http.Error(w, "500 Internal Server Error: Failed to persist the event.", http.StatusInternalServerError)
+			return
+		}
+		if !added {
+			s.Metrics.DuplicateWebhookCounter.WithLabelValues(eventType).Inc()
+			fmt.Fprint(w, "Event already received.")
+			return
+		}
+	}
 	fmt.Fprint(w, "Event received. Have a nice day.")
 
-	if err := s.demuxEvent(eventType, eventGUID, payload, r.Header); err != nil {
+	s.handleEvent(e)
+}
+
+// eventWaitGroup tracks the handlers of a single event, so that the event
+// can be acked once all of them returned. The wait group of the server
+// tracks them as well for the graceful shutdown.
+type eventWaitGroup struct {
+	server *sync.WaitGroup
+	event  sync.WaitGroup
+}
+
+func (wg *eventWaitGroup) Add(delta int) {
+	wg.server.Add(delta)
+	wg.event.Add(delta)
+}
+
+func (wg *eventWaitGroup) Done() {
+	wg.event.Done()
+	wg.server.Done()
+}
+
+// handleEvent dispatches the event to the plugins and acks it once they
+// handled it. Events that fail to parse are acked as well, handling them
+// again would not help.
+func (s *Server) handleEvent(e eventqueue.Event) {
+	if s.Queue != nil {
+		s.setHandling(e.GUID, true)
+	}
+	wg := &eventWaitGroup{server: &s.wg}
+	if err := s.demuxEvent(e.Type, e.GUID, e.Payload, e.Header, wg); err != nil {
 		logrus.WithError(err).Error("Error parsing event.")
 	}
+	if s.Queue == nil {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		wg.event.Wait()
+		if err := s.Queue.Ack(context.Background(), e.GUID); err != nil {
+			logrus.WithError(err).WithField(github.EventGUID, e.GUID).Error("Failed to ack event, it will be handled again.")
+		}
+		s.setHandling(e.GUID, false)
+	}()
+}
+
+func (s *Server) setHandling(guid string, handling bool) {
+	s.handlingLock.Lock()
+	defer s.handlingLock.Unlock()
+	if s.handling == nil {
+		s.handling = map[string]bool{}
+	}
+	if handling {
+		s.handling[guid] = true
+	} else {
+		delete(s.handling, guid)
+	}
+}
+
+func (s *Server) isHandling(guid string) bool {
+	s.handlingLock.Lock()
+	defer s.handlingLock.Unlock()
+	return s.handling[guid]
+}
+
+// ReplayPending handles the events of the queue that were received but not
+// handled, e.g. because the replica of hook that received them exceeded
+// its grace period when it was stopped. Events are only handled again once
+// they were received ReplayAfter ago, as the replica that received them
+// may still be handling them before, and only by the replica that claims
+// them. It is meant to be called periodically.
+func (s *Server) ReplayPending(ctx context.Context) error {
+	if s.Queue == nil {
+		return nil
+	}
+	entries, err := s.Queue.List(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to list queued events: %w", err)
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if now.Sub(e.Received) < s.ReplayAfter || s.isHandling(e.GUID) {
+			continue
+		}
+		l := logrus.WithFields(logrus.Fields{eventTypeField: e.Type, github.EventGUID: e.GUID})
+		claimed, err := s.Queue.Claim(ctx, e.GUID, s.ReplayAfter)
+		if err != nil {
+			l.WithError(err).Error("Failed to claim queued event.")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		l.Info("Handling event that was not handled after its receipt.")
+		s.Metrics.ReplayedWebhookCounter.WithLabelValues(e.Type).Inc()
+		s.handleEvent(e.Event)
+	}
+	return nil
 }
 
-func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.Header) error {
+func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.Header, wg *eventWaitGroup) error {
 	l := logrus.WithFields(
 		logrus.Fields{
 			eventTypeField:   eventType,
@@ -97,8 +222,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		i.GUID = eventGUID
 		srcRepo = i.Repo.FullName
 		if s.RepoEnabled(i.Repo.Owner.Login, i.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleIssueEvent(l, i)
+			wg.Add(1)
+			go s.handleIssueEvent(l, wg, i)
 		}
 	case "issue_comment":
 		var ic github.IssueCommentEvent
@@ -108,8 +233,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		ic.GUID = eventGUID
 		srcRepo = ic.Repo.FullName
 		if s.RepoEnabled(ic.Repo.Owner.Login, ic.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleIssueCommentEvent(l, ic)
+			wg.Add(1)
+			go s.handleIssueCommentEvent(l, wg, ic)
 		}
 	case "pull_request":
 		var pr github.PullRequestEvent
@@ -119,8 +244,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		pr.GUID = eventGUID
 		srcRepo = pr.Repo.FullName
 		if s.RepoEnabled(pr.Repo.Owner.Login, pr.Repo.Name) {
-			s.wg.Add(1)
-			go s.handlePullRequestEvent(l, pr)
+			wg.Add(1)
+			go s.handlePullRequestEvent(l, wg, pr)
 		}
 	case "pull_request_review":
 		var re github.ReviewEvent
@@ -130,8 +255,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		re.GUID = eventGUID
 		srcRepo = re.Repo.FullName
 		if s.RepoEnabled(re.Repo.Owner.Login, re.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleReviewEvent(l, re)
+			wg.Add(1)
+			go s.handleReviewEvent(l, wg, re)
 		}
 	case "pull_request_review_comment":
 		var rce github.ReviewCommentEvent
@@ -141,8 +266,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		rce.GUID = eventGUID
 		srcRepo = rce.Repo.FullName
 		if s.RepoEnabled(rce.Repo.Owner.Login, rce.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleReviewCommentEvent(l, rce)
+			wg.Add(1)
+			go s.handleReviewCommentEvent(l, wg, rce)
 		}
 	case "push":
 		var pe github.PushEvent
@@ -152,8 +277,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		pe.GUID = eventGUID
 		srcRepo = pe.Repo.FullName
 		if s.RepoEnabled(pe.Repo.Owner.Login, pe.Repo.Name) {
-			s.wg.Add(1)
-			go s.handlePushEvent(l, pe)
+			wg.Add(1)
+			go s.handlePushEvent(l, wg, pe)
 		}
 	case "status":
 		var se github.StatusEvent
@@ -163,8 +288,44 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		se.GUID = eventGUID
 		srcRepo = se.Repo.FullName
 		if s.RepoEnabled(se.Repo.Owner.Login, se.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleStatusEvent(l, se)
+			wg.Add(1)
+			go s.handleStatusEvent(l, wg, se)
+		}
+	case "check_run":
+		var cre github.CheckRunEvent
+		if err := json.Unmarshal(payload, &cre); err != nil {
+			return err
+		}
+		cre.GUID = eventGUID
+		srcRepo = cre.Repo.FullName
+		if s.RepoEnabled(cre.Repo.Owner.Login, cre.Repo.Name) {
+			wg.Add(1)
+			go s.handleCheckRunEvent(l, wg, cre)
+		}
+	case "check_suite":
+		var cse github.CheckSuiteEvent
+		if err := json.Unmarshal(payload, &cse); err != nil {
+			return err
+		}
+		cse.GUID = eventGUID
+		srcRepo = cse.Repo.FullName
+		if s.RepoEnabled(cse.Repo.Owner.Login, cse.Repo.Name) {
+			wg.Add(1)
+			go s.handleCheckSuiteEvent(l, wg, cse)
+		}
+	case "workflow_run":
+		var wre github.WorkflowRunEvent
+		if err := json.Unmarshal(payload, &wre); err != nil {
+			return err
+		}
+		if wre.Repo == nil {
+			return fmt.Errorf("workflow_run event %s has no repository", eventGUID)
+		}
+		wre.GUID = eventGUID
+		srcRepo = wre.Repo.FullName
+		if s.RepoEnabled(wre.Repo.Owner.Login, wre.Repo.Name) {
+			wg.Add(1)
+			go s.handleWorkflowRunEvent(l, wg, wre)
 		}
 	default:
 		var ge github.GenericEvent
@@ -176,8 +337,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 	}
 	// Demux events only to external plugins that require this event.
 	if external := s.needDemux(eventType, srcRepo); len(external) > 0 {
-		s.wg.Add(1)
-		go s.demuxExternal(l, external, payload, h)
+		wg.Add(1)
+		go s.demuxExternal(l, wg, external, payload, h)
 	}
 	return nil
 }
@@ -221,13 +382,13 @@ func (s *Server) needDemux(eventType, orgRepo string) []plugins.ExternalPlugin {
 }
 
 // demuxExternal dispatches the provided payload to the external plugins.
-func (s *Server) demuxExternal(l *logrus.Entry, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
-	defer s.wg.Done()
+func (s *Server) demuxExternal(l *logrus.Entry, wg *eventWaitGroup, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
+	defer wg.Done()
 	h.Set("User-Agent", "ProwHook")
 	for _, p := range externalPlugins {
-		s.wg.Add(1)
+		wg.Add(1)
 		go func(p plugins.ExternalPlugin) {
-			defer s.wg.Done()
+			defer wg.Done()
 			if err := s.dispatch(p.Endpoint, payload, h); err != nil {
 				l.WithError(err).WithField("external-plugin", p.Name).Error("Error dispatching event to external plugin.")
 			} else {
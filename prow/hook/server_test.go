@@ -18,18 +18,23 @@ package hook
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"k8s.io/test-infra/prow/githubeventserver"
+	"k8s.io/test-infra/prow/hook/eventqueue"
 	"k8s.io/test-infra/prow/plugins"
 )
 
@@ -518,6 +523,61 @@ foo/bar:
 
 			ExpectedDispatch: []string{"/coffee", "/water", "/chocolate"},
 		},
+		{
+			name: "Check run event",
+
+			Method: http.MethodPost,
+			Header: map[string]string{
+				"X-GitHub-Event":    "check_run",
+				"X-GitHub-Delivery": "I am unique",
+				"X-Hub-Signature":   hmac,
+				"content-type":      "application/json",
+			},
+			Body: body,
+
+			ExpectedDispatch: []string{"/coffee", "/water"},
+		},
+		{
+			name: "Check suite event",
+
+			Method: http.MethodPost,
+			Header: map[string]string{
+				"X-GitHub-Event":    "check_suite",
+				"X-GitHub-Delivery": "I am unique",
+				"X-Hub-Signature":   hmac,
+				"content-type":      "application/json",
+			},
+			Body: body,
+
+			ExpectedDispatch: []string{"/coffee", "/water"},
+		},
+		{
+			name: "Workflow run event",
+
+			Method: http.MethodPost,
+			Header: map[string]string{
+				"X-GitHub-Event":    "workflow_run",
+				"X-GitHub-Delivery": "I am unique",
+				"X-Hub-Signature":   hmac,
+				"content-type":      "application/json",
+			},
+			Body: body,
+
+			ExpectedDispatch: []string{"/coffee", "/water"},
+		},
+		{
+			name: "Workflow run event without a repository is not dispatched",
+
+			Method: http.MethodPost,
+			Header: map[string]string{
+				"X-GitHub-Event":    "workflow_run",
+				"X-GitHub-Delivery": "I am unique",
+				// echo -n '{"action": "completed"}' | openssl dgst -sha1 -hmac abc
+				"X-Hub-Signature": "sha1=6a9fc7dcb39a22b643cdff054264376595179130",
+				"content-type":    "application/json",
+			},
+			Body: `{"action": "completed"}`,
+		},
 		{
 			name: "Unknown event type gets dispatched to external plugin",
 
@@ -578,3 +638,106 @@ foo/bar:
 		})
 	}
 }
+
+type failingQueue struct {
+	eventqueue.Queue
+}
+
+func (failingQueue) Enqueue(context.Context, eventqueue.Event) (bool, error) {
+	return false, errors.New("disk full")
+}
+
+func TestServeHTTPQueue(t *testing.T) {
+	// This is the SHA1 signature for payload "$BODY" and signature "abc"
+	// echo -n $BODY | openssl dgst -sha1 -hmac abc
+	const hmac string = "sha1=ed18145350f303b1a47307717e7cdfef4c0cb668"
+	const body string = `{"repository": {"full_name": "kubernetes/test-infra"}}`
+
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{
+		ExternalPlugins: map[string][]plugins.ExternalPlugin{"kubernetes": {{Name: "coffee", Endpoint: "/coffee"}}},
+	})
+	var dispatched int32
+	client := newTestClient(func(req *http.Request) *http.Response {
+		atomic.AddInt32(&dispatched, 1)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}
+	})
+	queue, err := eventqueue.NewDiskQueue(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	newServer := func(q eventqueue.Queue) *Server {
+		return &Server{
+			Metrics:        githubeventserver.NewMetrics(),
+			Plugins:        pa,
+			TokenGenerator: func() []byte { return []byte(`'*': [{value: abc, created_at: 2019-10-02T15:00:00Z}]`) },
+			RepoEnabled:    func(org, repo string) bool { return true },
+			Queue:          q,
+			ReplayAfter:    time.Minute,
+			c:              *client,
+		}
+	}
+	post := func(s *Server) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		r.Header.Set("X-GitHub-Event", "repository")
+		r.Header.Set("X-GitHub-Delivery", "delivery")
+		r.Header.Set("X-Hub-Signature", hmac)
+		r.Header.Set("content-type", "application/json")
+		s.ServeHTTP(w, r)
+		s.GracefulShutdown()
+		return w
+	}
+
+	s := newServer(queue)
+	if w := post(s); w.Code != http.StatusOK || dispatched != 1 {
+		t.Fatalf("expected the event to be handled, got %d and %d dispatches", w.Code, dispatched)
+	}
+	if w := post(s); w.Body.String() != "Event already received." || dispatched != 1 {
+		t.Errorf("expected the redelivered event to be ignored, got %q and %d dispatches", w.Body.String(), dispatched)
+	}
+	entries, err := queue.List(context.Background(), true)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Acked {
+		t.Errorf("expected the handled event to be acked, got %+v", entries)
+	}
+
+	// Events that were not handled before a restart are handled once they
+	// were received ReplayAfter ago, recent events may still be handled by
+	// the replica that received them.
+	for _, e := range []eventqueue.Event{
+		{GUID: "pending", Type: "repository", Payload: []byte(body), Header: http.Header{}, Received: time.Now().Add(-time.Hour)},
+		{GUID: "recent", Type: "repository", Payload: []byte(body), Header: http.Header{}, Received: time.Now()},
+	} {
+		if _, err := queue.Enqueue(context.Background(), e); err != nil {
+			t.Fatalf("failed to enqueue event: %v", err)
+		}
+	}
+	s = newServer(queue)
+	if err := s.ReplayPending(context.Background()); err != nil {
+		t.Fatalf("failed to replay events: %v", err)
+	}
+	s.GracefulShutdown()
+	if dispatched != 2 {
+		t.Errorf("expected the pending event to be handled, got %d dispatches", dispatched)
+	}
+	pending, err := queue.List(context.Background(), false)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(pending) != 1 || pending[0].GUID != "recent" {
+		t.Errorf("expected only the recent event to be pending after the replay, got %+v", pending)
+	}
+
+	// Events that can not be persisted are rejected, so that GitHub reports
+	// the failed delivery.
+	if w := post(newServer(failingQueue{})); w.Code != http.StatusInternalServerError || dispatched != 2 {
+		t.Errorf("expected the event to be rejected, got %d and %d dispatches", w.Code, dispatched)
+	}
+}
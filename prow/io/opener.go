@@ -84,32 +84,61 @@ type Opener interface {
 type opener struct {
 	gcsCredentialsFile string
 	gcsClient          storageClient
-	s3Credentials      []byte
+	bucketOptions      providers.BucketOptions
 	cachedBuckets      map[string]*blob.Bucket
 	cachedBucketsMutex sync.Mutex
 }
 
+// OpenerOptions configure the storage providers of an opener.
+// All of the fields are optional.
+type OpenerOptions struct {
+	// GCSCredentialsFile is used for gs:// paths.
+	GCSCredentialsFile string
+	// S3CredentialsFile is used for s3:// paths.
+	S3CredentialsFile string
+	// AzureCredentialsFile is used for azblob:// paths.
+	AzureCredentialsFile string
+	// FileStorageRoot is the directory that holds the buckets of file:// paths.
+	FileStorageRoot string
+}
+
 // NewOpener returns an opener that can read GCS, S3 and local paths.
 // credentialsFile may also be empty
 // For local paths it has to be empty
 // In all other cases gocloud auto-discovery is used to detect credentials, if credentialsFile is empty.
 // For more details about the possible content of the credentialsFile see prow/io/providers.GetBucket
 func NewOpener(ctx context.Context, gcsCredentialsFile, s3CredentialsFile string) (Opener, error) {
-	gcsClient, err := createGCSClient(ctx, gcsCredentialsFile)
+	return NewOpenerWithOptions(ctx, OpenerOptions{
+		GCSCredentialsFile: gcsCredentialsFile,
+		S3CredentialsFile:  s3CredentialsFile,
+	})
+}
+
+// NewOpenerWithOptions returns an opener that can read GCS, S3, Azure Blob Storage,
+// file:// and local paths.
+// Credentials files that are not set are auto-discovered like for NewOpener.
+func NewOpenerWithOptions(ctx context.Context, opts OpenerOptions) (Opener, error) {
+	gcsClient, err := createGCSClient(ctx, opts.GCSCredentialsFile)
 	if err != nil {
 		return nil, err
 	}
-	var s3Credentials []byte
-	if s3CredentialsFile != "" {
-		s3Credentials, err = ioutil.ReadFile(s3CredentialsFile)
+	bucketOptions := providers.BucketOptions{FileStorageRoot: opts.FileStorageRoot}
+	if opts.S3CredentialsFile != "" {
+		bucketOptions.S3Credentials, err = ioutil.ReadFile(opts.S3CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.AzureCredentialsFile != "" {
+		bucketOptions.AzureCredentials, err = ioutil.ReadFile(opts.AzureCredentialsFile)
 		if err != nil {
 			return nil, err
 		}
 	}
 	return &opener{
 		gcsClient:          gcsClient,
-		gcsCredentialsFile: gcsCredentialsFile,
-		s3Credentials:      s3Credentials,
+		gcsCredentialsFile: opts.GCSCredentialsFile,
+		bucketOptions:      bucketOptions,
 		cachedBuckets:      map[string]*blob.Bucket{},
 	}, nil
 }
@@ -186,24 +215,25 @@ func (o *opener) openGCS(path string) (*storage.ObjectHandle, error) {
 
 // getBucket opens a bucket
 // The storageProvider is discovered based on the given path.
-// The buckets are cached per bucket name. So we don't open a bucket multiple times in the same process
+// The buckets are cached per storageProvider and bucket name. So we don't open a bucket multiple times in the same process
 func (o *opener) getBucket(ctx context.Context, path string) (*blob.Bucket, string, error) {
-	_, bucketName, relativePath, err := providers.ParseStoragePath(path)
+	storageProvider, bucketName, relativePath, err := providers.ParseStoragePath(path)
 	if err != nil {
 		return nil, "", fmt.Errorf("could not get bucket: %w", err)
 	}
+	cacheKey := fmt.Sprintf("%s://%s", storageProvider, bucketName)
 
 	o.cachedBucketsMutex.Lock()
 	defer o.cachedBucketsMutex.Unlock()
-	if bucket, ok := o.cachedBuckets[bucketName]; ok {
+	if bucket, ok := o.cachedBuckets[cacheKey]; ok {
 		return bucket, relativePath, nil
 	}
 
-	bucket, err := providers.GetBucket(ctx, o.s3Credentials, path)
+	bucket, err := providers.GetBucket(ctx, o.bucketOptions, path)
 	if err != nil {
 		return nil, "", err
 	}
-	o.cachedBuckets[bucketName] = bucket
+	o.cachedBuckets[cacheKey] = bucket
 	return bucket, relativePath, nil
 }
 
@@ -375,11 +405,19 @@ const (
 	GSCookieHost = "storage.cloud.google.com"
 )
 
+// ErrSignedURLUnsupported is returned by SignedURL if the storage provider can't
+// create a link that is accessible without credentials, e.g. for file:// paths.
+// Callers have to serve the content of such objects themselves.
+var ErrSignedURLUnsupported = errors.New("signed URLs are not supported by the storage provider")
+
 func (o *opener) SignedURL(ctx context.Context, p string, opts SignedURLOptions) (string, error) {
-	_, bucketName, relativePath, err := providers.ParseStoragePath(p)
+	storageProvider, bucketName, relativePath, err := providers.ParseStoragePath(p)
 	if err != nil {
 		return "", fmt.Errorf("could not get bucket: %w", err)
 	}
+	if storageProvider == providers.File {
+		return "", ErrSignedURLUnsupported
+	}
 	if strings.HasPrefix(p, providers.GS+"://") {
 		// We specifically want to use cookie auth, see:
 		// https://cloud.google.com/storage/docs/access-control/cookie-based-authentication
@@ -433,10 +471,15 @@ func (o *opener) SignedURL(ctx context.Context, p string, opts SignedURLOptions)
 	if err != nil {
 		return "", err
 	}
-	return bucket.SignedURL(ctx, relativePath, &blob.SignedURLOptions{
+	signedURL, err := bucket.SignedURL(ctx, relativePath, &blob.SignedURLOptions{
 		Method: "GET",
 		Expiry: 10 * time.Minute,
 	})
+	if gcerrors.Code(err) == gcerrors.Unimplemented {
+		// E.g. Azure Blob Storage accessed with a SAS token instead of the account key.
+		return "", fmt.Errorf("%w: %v", ErrSignedURLUnsupported, err)
+	}
+	return signedURL, err
 }
 
 func (o *opener) Iterator(ctx context.Context, prefix, delimiter string) (ObjectIterator, error) {
@@ -21,12 +21,19 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"cloud.google.com/go/storage"
+	"github.com/google/go-cmp/cmp"
+	"gocloud.dev/blob"
+	utilpointer "k8s.io/utils/pointer"
+
+	"k8s.io/test-infra/prow/io/providers"
 )
 
 func Test_opener_SignedURL(t *testing.T) {
@@ -204,3 +211,86 @@ func TestIsNotExist(t *testing.T) {
 		})
 	}
 }
+
+func TestFileStorageProvider(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "prow-artifacts"), 0755); err != nil {
+		t.Fatalf("failed to create bucket directory: %v", err)
+	}
+	ctx := context.Background()
+	o := &opener{
+		bucketOptions: providers.BucketOptions{FileStorageRoot: root},
+		cachedBuckets: map[string]*blob.Bucket{},
+	}
+
+	const p = "file://prow-artifacts/logs/job/1/build-log.txt"
+	w, err := o.Writer(ctx, p, WriterOptions{
+		ContentEncoding: utilpointer.StringPtr("identity"),
+		Metadata:        map[string]string{"foo": "bar"},
+	})
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "prow-artifacts", "logs", "job", "1", "build-log.txt")); err != nil {
+		t.Errorf("expected object to be stored below the root directory: %v", err)
+	}
+
+	r, err := o.Reader(ctx, p)
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	content, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("expected content %q, got %q", "hello", string(content))
+	}
+
+	attrs, err := o.Attributes(ctx, p)
+	if err != nil {
+		t.Fatalf("Attributes: %v", err)
+	}
+	expectedAttrs := Attributes{ContentEncoding: "identity", Size: 5, Metadata: map[string]string{"foo": "bar"}}
+	if diff := cmp.Diff(expectedAttrs, attrs); diff != "" {
+		t.Errorf("unexpected attributes (-want +got):\n%s", diff)
+	}
+
+	it, err := o.Iterator(ctx, "file://prow-artifacts/logs/job", "/")
+	if err != nil {
+		t.Fatalf("Iterator: %v", err)
+	}
+	var names []string
+	for {
+		attr, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		names = append(names, attr.Name)
+	}
+	if diff := cmp.Diff([]string{"logs/job/1/"}, names); diff != "" {
+		t.Errorf("unexpected objects (-want +got):\n%s", diff)
+	}
+
+	if _, err := o.SignedURL(ctx, p, SignedURLOptions{}); !errors.Is(err, ErrSignedURLUnsupported) {
+		t.Errorf("expected ErrSignedURLUnsupported, got %v", err)
+	}
+
+	if _, err := o.Reader(ctx, "file://prow-artifacts/does-not-exist"); !IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	if _, err := o.Reader(ctx, "file://other-bucket/build-log.txt"); err == nil {
+		t.Error("expected an error for a bucket that does not exist")
+	}
+}
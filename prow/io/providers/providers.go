@@ -19,31 +19,50 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strings"
 
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"gocloud.dev/blob"
+	"gocloud.dev/blob/azureblob"
+	"gocloud.dev/blob/fileblob"
 	_ "gocloud.dev/blob/memblob"
 	"gocloud.dev/blob/s3blob"
 )
 
 const (
-	S3 = "s3"
-	GS = "gs"
+	S3    = "s3"
+	GS    = "gs"
+	File  = "file"
+	Azure = "azblob"
 )
 
+// BucketOptions hold the provider specific settings that are needed to open a bucket.
+type BucketOptions struct {
+	// S3Credentials are the contents of the S3 credentials file, see GetBucket.
+	S3Credentials []byte
+	// AzureCredentials are the contents of the Azure credentials file, see GetBucket.
+	AzureCredentials []byte
+	// FileStorageRoot is the directory that contains the buckets of file:// paths,
+	// e.g. file://prow-artifacts is located at <FileStorageRoot>/prow-artifacts.
+	FileStorageRoot string
+}
+
 // GetBucket opens and returns a gocloud blob.Bucket based on credentials and a path.
 // The path is used to discover which storageProvider should be used.
 //
-// If the storageProvider file is detected, we don't need any credentials and just open a file bucket
+// If the storageProvider file is detected, we don't need any credentials and just open
+// the directory with the bucket name below opts.FileStorageRoot.
 // If no credentials are given, we just fall back to blob.OpenBucket which tries to auto discover credentials
 // e.g. via environment variables. For more details, see: https://gocloud.dev/howto/blob/
 //
-// If we specify credentials and an 3:// path is used, credentials must be given in one of the
+// If we specify credentials and an s3:// or azblob:// path is used, credentials must be given in one of the
 // following formats:
 // * AWS S3 (s3://):
 //    {
@@ -60,13 +79,24 @@ const (
 //      "access_key": "access_key",
 //      "secret_key": "secret_key"
 //    }
-func GetBucket(ctx context.Context, s3Credentials []byte, path string) (*blob.Bucket, error) {
+// * Azure Blob Storage (azblob://), either account_key or sas_token must be set:
+//    {
+//      "account_name": "account_name",
+//      "account_key": "account_key",
+//      "sas_token": "sas_token"
+//    }
+func GetBucket(ctx context.Context, opts BucketOptions, path string) (*blob.Bucket, error) {
 	storageProvider, bucket, _, err := ParseStoragePath(path)
 	if err != nil {
 		return nil, err
 	}
-	if storageProvider == S3 && len(s3Credentials) > 0 {
-		return getS3Bucket(ctx, s3Credentials, bucket)
+	switch {
+	case storageProvider == S3 && len(opts.S3Credentials) > 0:
+		return getS3Bucket(ctx, opts.S3Credentials, bucket)
+	case storageProvider == Azure && len(opts.AzureCredentials) > 0:
+		return getAzureBucket(ctx, opts.AzureCredentials, bucket)
+	case storageProvider == File:
+		return getFileBucket(opts.FileStorageRoot, bucket)
 	}
 
 	bkt, err := blob.OpenBucket(ctx, fmt.Sprintf("%s://%s", storageProvider, bucket))
@@ -127,17 +157,80 @@ func getS3Bucket(ctx context.Context, creds []byte, bucketName string) (*blob.Bu
 	return bkt, nil
 }
 
+// azureCredentials are credentials used to access Azure Blob Storage.
+// Either AccountKey or SASToken has to be set.
+type azureCredentials struct {
+	AccountName string `json:"account_name"`
+	AccountKey  string `json:"account_key"`
+	SASToken    string `json:"sas_token"`
+}
+
+// getAzureBucket opens a gocloud blob.Bucket based on given credentials in the format the
+// struct azureCredentials defines (see documentation of GetBucket for an example)
+func getAzureBucket(ctx context.Context, creds []byte, containerName string) (*blob.Bucket, error) {
+	azureCreds := &azureCredentials{}
+	if err := json.Unmarshal(creds, azureCreds); err != nil {
+		return nil, fmt.Errorf("error getting Azure credentials from JSON: %w", err)
+	}
+	if azureCreds.AccountName == "" {
+		return nil, errors.New("missing account_name in Azure credentials")
+	}
+
+	opts := &azureblob.Options{SASToken: azureblob.SASToken(azureCreds.SASToken)}
+	var credential azblob.Credential = azblob.NewAnonymousCredential()
+	if azureCreds.AccountKey != "" {
+		sharedKey, err := azureblob.NewCredential(azureblob.AccountName(azureCreds.AccountName), azureblob.AccountKey(azureCreds.AccountKey))
+		if err != nil {
+			return nil, fmt.Errorf("error creating Azure credential: %w", err)
+		}
+		// The shared key is also needed to create signed URLs.
+		opts.Credential = sharedKey
+		credential = sharedKey
+	} else if azureCreds.SASToken == "" {
+		return nil, errors.New("either account_key or sas_token must be set in Azure credentials")
+	}
+	pipeline := azureblob.NewPipeline(credential, azblob.PipelineOptions{})
+
+	bkt, err := azureblob.OpenBucket(ctx, pipeline, azureblob.AccountName(azureCreds.AccountName), containerName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error opening Azure bucket: %w", err)
+	}
+	return bkt, nil
+}
+
+// getFileBucket opens the directory of the bucket below root as a gocloud blob.Bucket.
+// Objects are stored as regular files, their attributes in a sidecar file next to them.
+func getFileBucket(root, bucketName string) (*blob.Bucket, error) {
+	if root == "" {
+		return nil, fmt.Errorf("cannot open bucket %q, no root directory for %s:// paths is configured", bucketName, File)
+	}
+	// The bucket name can't contain a slash, but it must not escape the root either.
+	if bucketName == "." || bucketName == ".." {
+		return nil, fmt.Errorf("invalid bucket name %q", bucketName)
+	}
+	bkt, err := fileblob.OpenBucket(filepath.Join(root, bucketName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file bucket: %w", err)
+	}
+	return bkt, nil
+}
+
 // HasStorageProviderPrefix returns true if the given string starts with
 // any of the known storageProviders and a slash, e.g.
 // * gs/kubernetes-jenkins returns true
 // * kubernetes-jenkins returns false
 func HasStorageProviderPrefix(path string) bool {
-	return strings.HasPrefix(path, GS+"/") || strings.HasPrefix(path, S3+"/")
+	for _, storageProvider := range []string{GS, S3, File, Azure} {
+		if strings.HasPrefix(path, storageProvider+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 // ParseStoragePath parses storagePath and returns the storageProvider, bucket and relativePath
 // For example gs://prow-artifacts/test.log results in (gs, prow-artifacts, test.log)
-// Currently detected storageProviders are GS, S3 and file.
+// Currently detected storageProviders are GS, S3, file and azblob.
 // Paths with a leading / instead of a storageProvider prefix are treated as file paths for backwards
 // compatibility reasons.
 // File paths are split into a directory and a file. Directory is returned as bucket, file is returned.
@@ -17,6 +17,9 @@ limitations under the License.
 package providers_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"k8s.io/test-infra/prow/io/providers"
@@ -38,6 +41,16 @@ func TestHasStorageProviderPrefix(t *testing.T) {
 			path: "gs/kubernetes-jenkins",
 			want: true,
 		},
+		{
+			name: "file prefix",
+			path: "file/prow-artifacts",
+			want: true,
+		},
+		{
+			name: "azblob prefix",
+			path: "azblob/prow-artifacts",
+			want: true,
+		},
 		{
 			name: "no prefix",
 			path: "kubernetes-jenkins",
@@ -102,6 +115,20 @@ func TestParseStoragePath(t *testing.T) {
 			wantRelativePath:    "",
 			wantErr:             false,
 		},
+		{
+			name:                "parse file path",
+			args:                args{storagePath: "file://prow-artifacts/pr-logs/bazel-build/test.log"},
+			wantStorageProvider: providers.File,
+			wantBucket:          "prow-artifacts",
+			wantRelativePath:    "pr-logs/bazel-build/test.log",
+		},
+		{
+			name:                "parse azblob path",
+			args:                args{storagePath: "azblob://prow-artifacts/pr-logs/bazel-build/test.log"},
+			wantStorageProvider: providers.Azure,
+			wantBucket:          "prow-artifacts",
+			wantRelativePath:    "pr-logs/bazel-build/test.log",
+		},
 		{
 			name:    "parse gs to short path fails",
 			args:    args{storagePath: "gs://"},
@@ -133,3 +160,61 @@ func TestParseStoragePath(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBucket(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "prow-artifacts"), 0755); err != nil {
+		t.Fatalf("failed to create bucket directory: %v", err)
+	}
+	tests := []struct {
+		name    string
+		opts    providers.BucketOptions
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "file bucket below the root",
+			opts: providers.BucketOptions{FileStorageRoot: root},
+			path: "file://prow-artifacts/logs/test.log",
+		},
+		{
+			name:    "file bucket without a root",
+			path:    "file://prow-artifacts/logs/test.log",
+			wantErr: true,
+		},
+		{
+			name:    "file bucket that does not exist",
+			opts:    providers.BucketOptions{FileStorageRoot: root},
+			path:    "file://other-bucket/logs/test.log",
+			wantErr: true,
+		},
+		{
+			name:    "file bucket escaping the root",
+			opts:    providers.BucketOptions{FileStorageRoot: filepath.Join(root, "prow-artifacts")},
+			path:    "file://../logs/test.log",
+			wantErr: true,
+		},
+		{
+			name:    "azblob credentials without a key or token",
+			opts:    providers.BucketOptions{AzureCredentials: []byte(`{"account_name": "prow"}`)},
+			path:    "azblob://prow-artifacts/logs/test.log",
+			wantErr: true,
+		},
+		{
+			name: "azblob credentials with a sas token",
+			opts: providers.BucketOptions{AzureCredentials: []byte(`{"account_name": "prow", "sas_token": "sv=2019-02-02"}`)},
+			path: "azblob://prow-artifacts/logs/test.log",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, err := providers.GetBucket(context.Background(), tt.opts, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != nil {
+				bucket.Close()
+			}
+		})
+	}
+}
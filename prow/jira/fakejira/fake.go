@@ -110,6 +110,10 @@ func (f *FakeClient) ForPlugin(string) jiraclient.Client {
 	return f
 }
 
+func (f *FakeClient) WithRequestGate(func() error) jiraclient.Client {
+	return f
+}
+
 func (f *FakeClient) AddComment(issueID string, comment *jira.Comment) (*jira.Comment, error) {
 	issue, err := f.GetIssue(issueID)
 	if err != nil {
@@ -88,6 +88,9 @@ type Client interface {
 	// is not consider an error for this function.
 	DeleteRemoteLinkViaURL(issueID, url string) (bool, error)
 	ForPlugin(plugin string) Client
+	// WithRequestGate allows to fail requests with the error of gate, which
+	// is called before every request.
+	WithRequestGate(gate func() error) Client
 	AddComment(issueID string, comment *jira.Comment) (*jira.Comment, error)
 	ListProjects() (*jira.ProjectList, error)
 	JiraClient() *jira.Client
@@ -202,6 +205,33 @@ func (c *clientUsedTransport) RoundTrip(r *http.Request) (*http.Response, error)
 	return c.upstream.RoundTrip(r)
 }
 
+// gatedTransport calls its gate before every request and fails the request
+// with a requestGateError if the gate returns an error.
+type gatedTransport struct {
+	gate     func() error
+	upstream http.RoundTripper
+}
+
+func (g *gatedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := g.gate(); err != nil {
+		return nil, &requestGateError{err: err}
+	}
+	return g.upstream.RoundTrip(r)
+}
+
+// requestGateError marks errors of request gates, which are not retried.
+type requestGateError struct {
+	err error
+}
+
+func (e *requestGateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestGateError) Unwrap() error {
+	return e.err
+}
+
 func (c *clientUsedTransport) Used() bool {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -216,6 +246,7 @@ type client struct {
 	logger     *logrus.Entry
 	upstream   *jira.Client
 	clientUsed used
+	gate       func() error
 	*delegate
 }
 
@@ -576,6 +607,7 @@ func (jc *client) WithFields(fields logrus.Fields) Client {
 	return &client{
 		clientUsed: jc.clientUsed,
 		upstream:   jc.upstream,
+		gate:       jc.gate,
 		logger:     jc.logger.WithFields(fields),
 		delegate:   jc.delegate,
 	}
@@ -584,24 +616,46 @@ func (jc *client) WithFields(fields logrus.Fields) Client {
 // ForPlugin clones the client, keeping the underlying delegate the same but adding
 // a plugin identifier and log field
 func (jc *client) ForPlugin(plugin string) Client {
-	pluginLogger := jc.logger.WithField("plugin", plugin)
+	return jc.clone(jc.logger.WithField("plugin", plugin), jc.gate)
+}
+
+// WithRequestGate clones the client, keeping the underlying delegate the same but
+// calling gate before every request to Jira. Requests fail with the error of the
+// gate if it returns one and are not retried.
+func (jc *client) WithRequestGate(gate func() error) Client {
+	return jc.clone(jc.logger, gate)
+}
+
+func (jc *client) clone(logger *logrus.Entry, gate func() error) Client {
 	retryingClient := retryablehttp.NewClient()
 	usedFlagTransport := &clientUsedTransport{
 		m:        sync.Mutex{},
 		upstream: retryingClient.HTTPClient.Transport,
 	}
 	retryingClient.HTTPClient.Transport = usedFlagTransport
-	retryingClient.Logger = &retryableHTTPLogrusWrapper{log: pluginLogger}
+	if gate != nil {
+		retryingClient.HTTPClient.Transport = &gatedTransport{gate: gate, upstream: usedFlagTransport}
+		checkRetry := retryingClient.CheckRetry
+		retryingClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+			var gateErr *requestGateError
+			if errors.As(err, &gateErr) {
+				return false, gateErr.err
+			}
+			return checkRetry(ctx, resp, err)
+		}
+	}
+	retryingClient.Logger = &retryableHTTPLogrusWrapper{log: logger}
 	// ignore error as url.String() was passed to the delegate
 	jiraClient, err := newJiraClient(jc.url, jc.options, retryingClient)
 	if err != nil {
-		pluginLogger.WithError(err).Error("invalid Jira URL")
+		logger.WithError(err).Error("invalid Jira URL")
 		jiraClient = jc.upstream
 	}
 	return &client{
-		logger:     pluginLogger,
+		logger:     logger,
 		clientUsed: usedFlagTransport,
 		upstream:   jiraClient,
+		gate:       gate,
 		delegate:   jc.delegate,
 	}
 }
@@ -19,6 +19,8 @@ package jira
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/hashicorp/go-retryablehttp"
@@ -63,3 +65,38 @@ func TestJiraErrorBody(t *testing.T) {
 		t.Errorf("body of non-jira error is `%s`; expected empty string", body)
 	}
 }
+
+func TestWithRequestGate(t *testing.T) {
+	var requests int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"id":"1","key":"ABC-1"}`))
+	}))
+	defer testServer.Close()
+
+	jc, err := NewClient(testServer.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	gateErr := errors.New("closed")
+	var closed bool
+	gated := jc.ForPlugin("plugin").WithRequestGate(func() error {
+		if closed {
+			return gateErr
+		}
+		return nil
+	})
+	if _, err := gated.GetIssue("ABC-1"); err != nil {
+		t.Fatalf("expected the request to pass the gate, got %v", err)
+	}
+	closed = true
+	if _, err := gated.GetIssue("ABC-1"); !errors.Is(err, gateErr) {
+		t.Errorf("expected the request to fail with the error of the gate, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the gated request to not be sent nor retried, got %d requests", requests)
+	}
+	if !gated.Used() {
+		t.Error("expected the gated client to be used")
+	}
+}
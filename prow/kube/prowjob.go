@@ -66,6 +66,9 @@ const (
 	// IsOptionalLabel is added in resources created by prow and
 	// carries the Optional from a Presubmit job.
 	IsOptionalLabel = "prow.k8s.io/is-optional"
+	// PriorityClassLabel can be set on a job to select one of the
+	// priority classes configured for plank's fair-share scheduling.
+	PriorityClassLabel = "prow.k8s.io/priority-class"
 
 	// Gerrit related labels that are used by Prow
 
@@ -79,4 +82,13 @@ const (
 	GerritPatchset = "prow.k8s.io/gerrit-patchset"
 	// GerritReportLabel is the gerrit label prow will cast vote on, fallback to CodeReview label if unset
 	GerritReportLabel = "prow.k8s.io/gerrit-report-label"
+
+	// GitLab related labels and annotations that are used by Prow
+
+	// GitLabInstance is the GitLab instance url
+	GitLabInstance = "prow.k8s.io/gitlab-instance"
+	// GitLabProject is the full path of a GitLab project
+	GitLabProject = "prow.k8s.io/gitlab-project"
+	// GitLabRevision is the SHA of the head of a GitLab merge request
+	GitLabRevision = "prow.k8s.io/gitlab-revision"
 )
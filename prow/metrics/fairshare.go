@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FairShareMetrics are the metrics of the fair-share scheduling of plank.
+var FairShareMetrics = struct {
+	TenantJobs *prometheus.GaugeVec
+	Throttled  *prometheus.CounterVec
+}{
+	TenantJobs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "plank_fair_share_tenant_jobs",
+		Help: "Number of pending and triggered prowjobs per fair-share tenant.",
+	}, []string{
+		"tenant",
+		"state",
+	}),
+	Throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plank_fair_share_throttled",
+		Help: "Number of reconcile attempts that did not start a triggered prowjob because of fair-share scheduling. A job that stays throttled is counted again every time it is requeued.",
+	}, []string{
+		"tenant",
+		"reason",
+	}),
+}
+
+// RegisterFairShareMetrics registers the fair-share metrics with the registerer.
+func RegisterFairShareMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(FairShareMetrics.TenantJobs, FairShareMetrics.Throttled)
+}
@@ -101,6 +101,13 @@ func (trw *traceResponseWriter) Write(data []byte) (int, error) {
 	return size, err
 }
 
+// Flush implements http.Flusher so that streamed responses are not buffered.
+func (trw *traceResponseWriter) Flush() {
+	if flusher, ok := trw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Metrics holds the metrics for Prometheus
 type Metrics struct {
 	HTTPRequestDuration *prometheus.HistogramVec
@@ -35,8 +35,34 @@ func SendHook(address, eventType string, payload, hmac []byte) error {
 	req.Header.Set("X-GitHub-Delivery", "GUID")
 	req.Header.Set("X-Hub-Signature", github.PayloadSignature(payload, hmac))
 	req.Header.Set("content-type", "application/json")
+	return send(&http.Client{}, req)
+}
+
+// SendEvent sends a GitHub event that was delivered before to the provided
+// address, e.g. to recover events hook missed. The event keeps the headers
+// of its delivery but is signed again with the hmac tokens of hook.
+func SendEvent(c *http.Client, address, eventType, guid string, payload []byte, header http.Header, tokenGenerator func() []byte) error {
+	sig, err := github.SignPayload(payload, tokenGenerator)
+	if err != nil {
+		return fmt.Errorf("failed to sign payload: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Del("X-Hub-Signature-256")
+	req.Header.Set("X-Hub-Signature", sig)
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-GitHub-Delivery", guid)
+	req.Header.Set("content-type", "application/json")
+	return send(c, req)
+}
 
-	c := &http.Client{}
+func send(c *http.Client, req *http.Request) error {
 	resp, err := c.Do(req)
 	if err != nil {
 		return err
@@ -77,16 +77,22 @@ func NewProwJob(spec prowapi.ProwJobSpec, extraLabels, extraAnnotations map[stri
 // - `job_state_to_report: []`
 // - `report: false`
 // `report: true` also depends on other conditions, such as channel name etc.
+// The same applies to `ReporterConfig.Webhook`.
 func setReportDefault(spec *prowapi.ProwJobSpec) {
-	if spec.ReporterConfig == nil || spec.ReporterConfig.Slack == nil {
+	if spec.ReporterConfig == nil {
 		return
 	}
-	// `job_states_to_report: []` means false
-	if spec.ReporterConfig.Slack.JobStatesToReport != nil && len(spec.ReporterConfig.Slack.JobStatesToReport) == 0 {
-		spec.ReporterConfig.Slack.Report = boolPtr(false)
-	} else {
-		spec.ReporterConfig.Slack.Report = boolPtr(true)
+	if spec.ReporterConfig.Slack != nil {
+		spec.ReporterConfig.Slack.Report = reportFromStates(spec.ReporterConfig.Slack.JobStatesToReport)
 	}
+	if spec.ReporterConfig.Webhook != nil {
+		spec.ReporterConfig.Webhook.Report = reportFromStates(spec.ReporterConfig.Webhook.JobStatesToReport)
+	}
+}
+
+func reportFromStates(states []prowapi.ProwJobState) *bool {
+	// `job_states_to_report: []` means false
+	return boolPtr(states == nil || len(states) != 0)
 }
 
 func CreateRefs(pr github.PullRequest, baseSHA string) prowapi.Refs {
@@ -248,6 +254,12 @@ func CompletePrimaryRefs(refs prowapi.Refs, jb config.JobBase) *prowapi.Refs {
 	if jb.SkipFetchHead {
 		refs.SkipFetchHead = jb.SkipFetchHead
 	}
+	if len(jb.SparseCheckout) > 0 {
+		refs.SparseCheckout = jb.SparseCheckout
+	}
+	if jb.PartialClone {
+		refs.PartialClone = jb.PartialClone
+	}
 	return &refs
 }
 
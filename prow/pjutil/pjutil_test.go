@@ -56,6 +56,8 @@ func TestPostsubmitSpec(t *testing.T) {
 						SkipSubmodules: true,
 						CloneDepth:     7,
 						SkipFetchHead:  true,
+						SparseCheckout: []string{"/cmd/"},
+						PartialClone:   true,
 					},
 				},
 			},
@@ -67,6 +69,8 @@ func TestPostsubmitSpec(t *testing.T) {
 					SkipSubmodules: true,
 					CloneDepth:     7,
 					SkipFetchHead:  true,
+					SparseCheckout: []string{"/cmd/"},
+					PartialClone:   true,
 				},
 				Report: true,
 			},
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/metrics"
+	"k8s.io/test-infra/prow/pjutil"
+)
+
+const (
+	throttleReasonQuota     = "quota"
+	throttleReasonFairShare = "fair_share"
+)
+
+// canExecuteFairShare determines if the job may be started without exceeding
+// its tenant's quota and without taking a slot that fair-share scheduling hands
+// out to another tenant first.
+func (r *reconciler) canExecuteFairShare(ctx context.Context, pj *prowv1.ProwJob) (bool, error) {
+	cfg := r.config()
+	fs := cfg.Plank.FairShare
+	if fs == nil {
+		return true, nil
+	}
+
+	pjs := &prowv1.ProwJobList{}
+	if err := r.pjClient.List(ctx, pjs, optPendingTriggeredProwJobs()); err != nil {
+		return false, fmt.Errorf("failed listing pending and triggered prowjobs: %w", err)
+	}
+
+	tenant := fs.TenantFor(pj)
+	if quota := fs.QuotaFor(tenant); quota > 0 {
+		var pending int
+		for _, foundPJ := range pjs.Items {
+			if foundPJ.UID != pj.UID && foundPJ.Status.State == prowv1.PendingState && fs.TenantFor(&foundPJ) == tenant {
+				pending++
+			}
+		}
+		if pending >= quota {
+			r.log.WithFields(pjutil.ProwJobFields(pj)).
+				Debugf("Not starting another job of tenant %s, have %d pending jobs, %d is the quota", tenant, pending, quota)
+			metrics.FairShareMetrics.Throttled.WithLabelValues(tenant, throttleReasonQuota).Inc()
+			return false, nil
+		}
+	}
+
+	max := cfg.Plank.MaxConcurrency
+	if max <= 0 {
+		return true, nil
+	}
+	var running int
+	listed := false
+	for _, foundPJ := range pjs.Items {
+		if foundPJ.UID == pj.UID {
+			listed = true
+		} else if foundPJ.Status.State == prowv1.PendingState {
+			running++
+		}
+	}
+	if !listed {
+		// The cache may not have caught up with our own ProwJob yet.
+		pjs.Items = append(pjs.Items, *pj)
+	}
+
+	schedule := r.fairShareSchedules.get(fs, cfg.Plank.JobQueueConcurrencies, pjs.Items, max-running)
+	if _, scheduled := schedule[pj.UID]; !scheduled {
+		r.log.WithFields(pjutil.ProwJobFields(pj)).
+			Debugf("Not starting job of tenant %s, %d jobs are running and the remaining slots go to jobs ahead of it, %d is the limit", tenant, running, max)
+		metrics.FairShareMetrics.Throttled.WithLabelValues(tenant, throttleReasonFairShare).Inc()
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// fairShareScheduleCache holds the last computed fair-share schedule. Computing
+// a schedule sorts all waiting jobs, so it is only redone when the listed jobs,
+// the configuration or the number of free slots changed.
+type fairShareScheduleCache struct {
+	lock        sync.Mutex
+	fs          *config.FairShare
+	limit       int
+	fingerprint uint64
+	schedule    map[types.UID]int
+}
+
+func (c *fairShareScheduleCache) get(fs *config.FairShare, queueCapacities map[string]int, pjs []prowv1.ProwJob, limit int) map[types.UID]int {
+	fingerprint := fingerprintProwJobs(pjs)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.schedule != nil && c.fs == fs && c.limit == limit && c.fingerprint == fingerprint {
+		return c.schedule
+	}
+	c.fs, c.limit, c.fingerprint = fs, limit, fingerprint
+	c.schedule = fairShareSchedule(fs, queueCapacities, pjs, limit)
+	return c.schedule
+}
+
+// fingerprintProwJobs returns a hash of the UIDs and resourceVersions of pjs
+// that does not depend on their order.
+func fingerprintProwJobs(pjs []prowv1.ProwJob) uint64 {
+	var fingerprint uint64
+	for _, pj := range pjs {
+		h := fnv.New64a()
+		h.Write([]byte(pj.UID))
+		h.Write([]byte{0})
+		h.Write([]byte(pj.ResourceVersion))
+		fingerprint += h.Sum64()
+	}
+	return fingerprint
+}
+
+// fairShareSchedule returns the order in which fair-share scheduling starts the
+// triggered jobs in pjs, keyed by UID. Only the first limit jobs are scheduled,
+// jobs that are missing from the result can not be started yet.
+//
+// Slots are handed out one at a time to the tenant whose number of pending
+// jobs relative to its weight is the lowest. Within a tenant, jobs are started
+// by priority and then oldest first. Tenants that reached their quota do not
+// get any slots. Jobs that their own max_concurrency or job queue capacity
+// keeps from starting do not hold back jobs of other tenants.
+func fairShareSchedule(fs *config.FairShare, queueCapacities map[string]int, pjs []prowv1.ProwJob, limit int) map[types.UID]int {
+	byName := map[string][]prowv1.ProwJob{}
+	byQueue := map[string][]prowv1.ProwJob{}
+	for _, pj := range pjs {
+		byName[pj.Spec.Job] = append(byName[pj.Spec.Job], pj)
+		if pj.Spec.JobQueueName != "" {
+			byQueue[pj.Spec.JobQueueName] = append(byQueue[pj.Spec.JobQueueName], pj)
+		}
+	}
+	blocked := func(pj *prowv1.ProwJob) bool {
+		if pj.Spec.MaxConcurrency > 0 && countPendingOrOlderTriggeredMatchingPJs(*pj, byName[pj.Spec.Job]) >= pj.Spec.MaxConcurrency {
+			return true
+		}
+		if queueName := pj.Spec.JobQueueName; queueName != "" {
+			capacity, defined := queueCapacities[queueName]
+			if !defined {
+				return true
+			}
+			if capacity > 0 && countPendingOrOlderTriggeredMatchingPJs(*pj, byQueue[queueName]) >= capacity {
+				return true
+			}
+		}
+		return false
+	}
+
+	pending := map[string]int{}
+	waiting := map[string][]*prowv1.ProwJob{}
+	for i := range pjs {
+		pj := &pjs[i]
+		tenant := fs.TenantFor(pj)
+		switch pj.Status.State {
+		case prowv1.PendingState:
+			pending[tenant]++
+		case prowv1.TriggeredState:
+			if !blocked(pj) {
+				waiting[tenant] = append(waiting[tenant], pj)
+			}
+		}
+	}
+
+	var tenants []string
+	for tenant, jobs := range waiting {
+		sort.SliceStable(jobs, func(i, j int) bool {
+			if priorityI, priorityJ := fs.PriorityFor(jobs[i]), fs.PriorityFor(jobs[j]); priorityI != priorityJ {
+				return priorityI > priorityJ
+			}
+			if !jobs[i].CreationTimestamp.Equal(&jobs[j].CreationTimestamp) {
+				return jobs[i].CreationTimestamp.Before(&jobs[j].CreationTimestamp)
+			}
+			return jobs[i].Name < jobs[j].Name
+		})
+		tenants = append(tenants, tenant)
+	}
+	// Make tie breaking deterministic.
+	sort.Strings(tenants)
+
+	schedule := map[types.UID]int{}
+	for len(schedule) < limit {
+		next := ""
+		for _, tenant := range tenants {
+			if len(waiting[tenant]) == 0 {
+				continue
+			}
+			if quota := fs.QuotaFor(tenant); quota > 0 && pending[tenant] >= quota {
+				continue
+			}
+			if next == "" || isBehindInShare(fs, tenant, next, pending, waiting) {
+				next = tenant
+			}
+		}
+		if next == "" {
+			break
+		}
+		schedule[waiting[next][0].UID] = len(schedule)
+		waiting[next] = waiting[next][1:]
+		pending[next]++
+	}
+
+	return schedule
+}
+
+// isBehindInShare returns true if tenant a should get the next slot before tenant b.
+func isBehindInShare(fs *config.FairShare, a, b string, pending map[string]int, waiting map[string][]*prowv1.ProwJob) bool {
+	// Compare pending[a]/weight[a] to pending[b]/weight[b] without dividing.
+	shareA, shareB := pending[a]*fs.WeightFor(b), pending[b]*fs.WeightFor(a)
+	if shareA != shareB {
+		return shareA < shareB
+	}
+	return waiting[a][0].CreationTimestamp.Before(&waiting[b][0].CreationTimestamp)
+}
+
+// gatherFairShareMetrics records the number of pending and triggered jobs per tenant.
+func gatherFairShareMetrics(fs *config.FairShare, pjs []prowv1.ProwJob) {
+	metrics.FairShareMetrics.TenantJobs.Reset()
+	if fs == nil {
+		return
+	}
+	for i := range pjs {
+		if state := pjs[i].Status.State; state == prowv1.PendingState || state == prowv1.TriggeredState {
+			metrics.FairShareMetrics.TenantJobs.WithLabelValues(fs.TenantFor(&pjs[i]), string(state)).Inc()
+		}
+	}
+}
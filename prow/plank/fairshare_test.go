@@ -0,0 +1,323 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func fairSharePJ(name, org string, state prowapi.ProwJobState, age time.Duration, labels map[string]string) prowapi.ProwJob {
+	return prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "prowjobs",
+			UID:               types.UID(name),
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(time.Unix(0, 0).Add(-age)),
+		},
+		Spec: prowapi.ProwJobSpec{
+			Agent: prowapi.KubernetesAgent,
+			Job:   name,
+			Refs:  &prowapi.Refs{Org: org, Repo: "repo"},
+		},
+		Status: prowapi.ProwJobStatus{State: state},
+	}
+}
+
+func fairShareLimitedPJ(name, org string, state prowapi.ProwJobState, age time.Duration, job string, maxConcurrency int, queue string) prowapi.ProwJob {
+	pj := fairSharePJ(name, org, state, age, nil)
+	pj.Spec.Job = job
+	pj.Spec.MaxConcurrency = maxConcurrency
+	pj.Spec.JobQueueName = queue
+	return pj
+}
+
+func TestFairShareSchedule(t *testing.T) {
+	byOrg := &config.FairShare{TenantKey: config.FairShareTenantKeyOrg}
+	testCases := []struct {
+		name            string
+		fs              *config.FairShare
+		queueCapacities map[string]int
+		pj              prowapi.ProwJob
+		existing        []prowapi.ProwJob
+		expected        int
+	}{
+		{
+			name:     "only job is first",
+			fs:       byOrg,
+			pj:       fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			expected: 0,
+		},
+		{
+			name: "older jobs of the same tenant go first",
+			fs:   byOrg,
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("older-1", "a", prowapi.TriggeredState, time.Hour, nil),
+				fairSharePJ("older-2", "a", prowapi.TriggeredState, time.Minute, nil),
+			},
+			expected: 2,
+		},
+		{
+			name: "burst of another tenant does not starve a newer job",
+			fs:   byOrg,
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("burst-1", "b", prowapi.TriggeredState, 4*time.Minute, nil),
+				fairSharePJ("burst-2", "b", prowapi.TriggeredState, 3*time.Minute, nil),
+				fairSharePJ("burst-3", "b", prowapi.TriggeredState, 2*time.Minute, nil),
+				fairSharePJ("burst-4", "b", prowapi.TriggeredState, time.Minute, nil),
+			},
+			// b's oldest job wins the tie, then a is behind in share.
+			expected: 1,
+		},
+		{
+			name: "tenant with running jobs yields to tenant without",
+			fs:   byOrg,
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running", "b", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("waiting", "b", prowapi.TriggeredState, time.Hour, nil),
+			},
+			expected: 0,
+		},
+		{
+			name: "weights are respected",
+			fs:   &config.FairShare{TenantKey: config.FairShareTenantKeyOrg, Weights: map[string]int{"b": 3}},
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 2*time.Hour, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running-a", "a", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("running-b", "b", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("waiting-1", "b", prowapi.TriggeredState, time.Hour, nil),
+				fairSharePJ("waiting-2", "b", prowapi.TriggeredState, time.Hour, nil),
+				fairSharePJ("waiting-3", "b", prowapi.TriggeredState, time.Hour, nil),
+			},
+			// b gets slots until it has three times as many pending jobs as a.
+			expected: 2,
+		},
+		{
+			name: "tenants at quota get no slots",
+			fs:   &config.FairShare{TenantKey: config.FairShareTenantKeyOrg, Quotas: map[string]int{"b": 1}},
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running-a", "a", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("running-b", "b", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("waiting", "b", prowapi.TriggeredState, time.Hour, nil),
+			},
+			expected: 0,
+		},
+		{
+			name: "higher priority jobs of the same tenant go first",
+			fs: &config.FairShare{
+				TenantKey:       config.FairShareTenantKeyOrg,
+				PriorityClasses: map[string]int{"high": 100},
+			},
+			pj: fairSharePJ("mine", "a", prowapi.TriggeredState, time.Hour, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("urgent", "a", prowapi.TriggeredState, 0, map[string]string{kube.PriorityClassLabel: "high"}),
+			},
+			expected: 1,
+		},
+		{
+			name: "jobs blocked by their max concurrency do not hold back other tenants",
+			fs:   byOrg,
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running-a-1", "a", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("running-a-2", "a", prowapi.PendingState, time.Hour, nil),
+				fairShareLimitedPJ("running-b", "b", prowapi.PendingState, time.Hour, "build", 1, ""),
+				fairShareLimitedPJ("waiting-b-1", "b", prowapi.TriggeredState, time.Hour, "build", 1, ""),
+				fairShareLimitedPJ("waiting-b-2", "b", prowapi.TriggeredState, time.Hour, "build", 1, ""),
+			},
+			expected: 0,
+		},
+		{
+			name:            "jobs blocked by their queue capacity do not hold back other tenants",
+			fs:              byOrg,
+			queueCapacities: map[string]int{"queue": 1},
+			pj:              fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running-a-1", "a", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("running-a-2", "a", prowapi.PendingState, time.Hour, nil),
+				fairShareLimitedPJ("running-b", "b", prowapi.PendingState, time.Hour, "build", 0, "queue"),
+				fairShareLimitedPJ("waiting-b-1", "b", prowapi.TriggeredState, time.Hour, "test", 0, "queue"),
+				fairShareLimitedPJ("waiting-b-2", "b", prowapi.TriggeredState, time.Hour, "lint", 0, "queue"),
+			},
+			expected: 0,
+		},
+		{
+			name: "only the jobs within the limit of another tenant are blocked",
+			fs:   byOrg,
+			pj:   fairSharePJ("mine", "a", prowapi.TriggeredState, 0, nil),
+			existing: []prowapi.ProwJob{
+				fairSharePJ("running-a-1", "a", prowapi.PendingState, time.Hour, nil),
+				fairSharePJ("running-a-2", "a", prowapi.PendingState, time.Hour, nil),
+				fairShareLimitedPJ("waiting-b-1", "b", prowapi.TriggeredState, 2*time.Hour, "build", 1, ""),
+				fairShareLimitedPJ("waiting-b-2", "b", prowapi.TriggeredState, time.Hour, "build", 1, ""),
+			},
+			// The oldest build of b can start and goes first, the second one has to wait for it.
+			expected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pjs := append(tc.existing, tc.pj)
+			schedule := fairShareSchedule(tc.fs, tc.queueCapacities, pjs, 100)
+			actual, scheduled := schedule[tc.pj.UID]
+			if !scheduled {
+				t.Fatalf("expected job to be scheduled at position %d, it was not scheduled", tc.expected)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected position %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFairShareScheduleLimit(t *testing.T) {
+	pjs := []prowapi.ProwJob{
+		fairSharePJ("first", "a", prowapi.TriggeredState, 2*time.Hour, nil),
+		fairSharePJ("second", "a", prowapi.TriggeredState, time.Hour, nil),
+		fairSharePJ("running", "b", prowapi.PendingState, time.Hour, nil),
+	}
+	schedule := fairShareSchedule(&config.FairShare{TenantKey: config.FairShareTenantKeyOrg}, nil, pjs, 1)
+	if _, scheduled := schedule["first"]; !scheduled {
+		t.Error("expected the oldest job to get the only slot")
+	}
+	if _, scheduled := schedule["second"]; scheduled {
+		t.Error("expected the second job not to be scheduled without a free slot")
+	}
+}
+
+func TestCanExecuteFairShare(t *testing.T) {
+	testCases := []struct {
+		name           string
+		maxConcurrency int
+		fs             *config.FairShare
+		pendingByOrg   map[string]int
+		expected       bool
+	}{
+		{
+			name:         "no fair share config always runs",
+			pendingByOrg: map[string]int{"a": 100},
+			expected:     true,
+		},
+		{
+			name:         "quota reached",
+			fs:           &config.FairShare{TenantKey: config.FairShareTenantKeyOrg, Quotas: map[string]int{"*": 2}},
+			pendingByOrg: map[string]int{"a": 2},
+			expected:     false,
+		},
+		{
+			name:         "quota of another tenant reached",
+			fs:           &config.FairShare{TenantKey: config.FairShareTenantKeyOrg, Quotas: map[string]int{"*": 2}},
+			pendingByOrg: map[string]int{"b": 2},
+			expected:     true,
+		},
+		{
+			name:           "max concurrency reached",
+			maxConcurrency: 3,
+			fs:             &config.FairShare{TenantKey: config.FairShareTenantKeyOrg},
+			pendingByOrg:   map[string]int{"b": 3},
+			expected:       false,
+		},
+		{
+			name:           "slot available",
+			maxConcurrency: 3,
+			fs:             &config.FairShare{TenantKey: config.FairShareTenantKeyOrg},
+			pendingByOrg:   map[string]int{"b": 2},
+			expected:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var prowJobs []runtime.Object
+			for org, count := range tc.pendingByOrg {
+				for i := 0; i < count; i++ {
+					pj := fairSharePJ(fmt.Sprintf("%s-%d", org, i), org, prowapi.PendingState, time.Hour, nil)
+					prowJobs = append(prowJobs, &pj)
+				}
+			}
+			cfg := newFakeConfigAgent(t, tc.maxConcurrency, nil)
+			cfg.c.Plank.FairShare = tc.fs
+			r := &reconciler{
+				pjClient: &indexingClient{
+					Client:     fakectrlruntimeclient.NewFakeClient(prowJobs...),
+					indexFuncs: map[string]ctrlruntimeclient.IndexerFunc{prowJobIndexName: prowJobIndexer("prowjobs")},
+				},
+				log:    logrus.NewEntry(logrus.StandardLogger()),
+				config: cfg.Config,
+				clock:  clock.RealClock{},
+			}
+			pj := fairSharePJ("under-test", "a", prowapi.TriggeredState, 0, nil)
+			result, err := r.canExecuteFairShare(context.Background(), &pj)
+			if err != nil {
+				t.Fatalf("canExecuteFairShare: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("expected fair share to allow job: %t, result was %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCanExecuteFairShareRecomputesScheduleOnChange(t *testing.T) {
+	running := fairSharePJ("running", "b", prowapi.PendingState, time.Hour, nil)
+	pj := fairSharePJ("under-test", "a", prowapi.TriggeredState, 0, nil)
+	fakeClient := fakectrlruntimeclient.NewFakeClient(&running, &pj)
+	cfg := newFakeConfigAgent(t, 2, nil)
+	cfg.c.Plank.FairShare = &config.FairShare{TenantKey: config.FairShareTenantKeyOrg}
+	r := &reconciler{
+		pjClient: &indexingClient{
+			Client:     fakeClient,
+			indexFuncs: map[string]ctrlruntimeclient.IndexerFunc{prowJobIndexName: prowJobIndexer("prowjobs")},
+		},
+		log:    logrus.NewEntry(logrus.StandardLogger()),
+		config: cfg.Config,
+		clock:  clock.RealClock{},
+	}
+
+	if result, err := r.canExecuteFairShare(context.Background(), &pj); err != nil || !result {
+		t.Fatalf("expected job to be allowed with a free slot, got %t, err: %v", result, err)
+	}
+
+	// Another tenant's job that is ahead in share takes the last slot.
+	other := fairSharePJ("other", "c", prowapi.TriggeredState, time.Hour, nil)
+	if err := fakeClient.Create(context.Background(), &other); err != nil {
+		t.Fatalf("failed to create prowjob: %v", err)
+	}
+	if result, err := r.canExecuteFairShare(context.Background(), &pj); err != nil || result {
+		t.Errorf("expected job to be held back after the listed jobs changed, got %t, err: %v", result, err)
+	}
+}
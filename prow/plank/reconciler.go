@@ -156,6 +156,7 @@ type reconciler struct {
 	totURL             string
 	clock              clock.WithTickerAndDelayedExecution
 	serializationLocks *shardedLock
+	fairShareSchedules fairShareScheduleCache
 }
 
 type shardedLock struct {
@@ -186,6 +187,7 @@ func (r *reconciler) syncMetrics(ctx context.Context) error {
 				continue
 			}
 			kube.GatherProwJobMetrics(r.log, pjs.Items)
+			gatherFairShareMetrics(r.config().Plank.FairShare, pjs.Items)
 		}
 	}
 }
@@ -754,8 +756,9 @@ func (r *reconciler) getBuildID(name string) (string, error) {
 
 // canExecuteConcurrently determines if the cocurrency settings allow our job
 // to be started. We start jobs with a limited concurrency in order, oldest
-// first. This allows us to get away without any global locking by just looking
-// at the jobs in the cluster.
+// first, unless fair-share scheduling between tenants is enabled. This allows
+// us to get away without any global locking by just looking at the jobs in
+// the cluster.
 func (r *reconciler) canExecuteConcurrently(ctx context.Context, pj *prowv1.ProwJob) (bool, error) {
 
 	if max := r.config().Plank.MaxConcurrency; max > 0 {
@@ -775,7 +778,11 @@ func (r *reconciler) canExecuteConcurrently(ctx context.Context, pj *prowv1.Prow
 		return canExecute, err
 	}
 
-	return r.canExecuteConcurrentlyPerQueue(ctx, pj)
+	if canExecute, err := r.canExecuteConcurrentlyPerQueue(ctx, pj); err != nil || !canExecute {
+		return canExecute, err
+	}
+
+	return r.canExecuteFairShare(ctx, pj)
 }
 
 func (r *reconciler) canExecuteConcurrentlyPerJob(ctx context.Context, pj *prowv1.ProwJob) (bool, error) {
@@ -883,6 +890,9 @@ const (
 	// that are currently pending AKA a corresponding pod
 	// exists but didn't yet finish
 	prowJobIndexKeyPending = "pending"
+	// prowJobIndexKeyPendingTriggered is the indexKey for prowjobs
+	// that are either pending or triggered
+	prowJobIndexKeyPendingTriggered = "pending-triggered"
 )
 
 func pendingTriggeredIndexKeyByName(jobName string) string {
@@ -907,6 +917,7 @@ func prowJobIndexer(prowJobNamespace string) ctrlruntimeclient.IndexerFunc {
 		}
 
 		if pj.Status.State == prowv1.PendingState || pj.Status.State == prowv1.TriggeredState {
+			indexes = append(indexes, prowJobIndexKeyPendingTriggered)
 			indexes = append(indexes, pendingTriggeredIndexKeyByName(pj.Spec.Job))
 
 			if pj.Spec.JobQueueName != "" {
@@ -926,6 +937,10 @@ func optPendingProwJobs() ctrlruntimeclient.ListOption {
 	return ctrlruntimeclient.MatchingFields{prowJobIndexName: prowJobIndexKeyPending}
 }
 
+func optPendingTriggeredProwJobs() ctrlruntimeclient.ListOption {
+	return ctrlruntimeclient.MatchingFields{prowJobIndexName: prowJobIndexKeyPendingTriggered}
+}
+
 func optPendingTriggeredJobsNamed(name string) ctrlruntimeclient.ListOption {
 	return ctrlruntimeclient.MatchingFields{prowJobIndexName: pendingTriggeredIndexKeyByName(name)}
 }
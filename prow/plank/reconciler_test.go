@@ -312,6 +312,7 @@ func TestProwJobIndexer(t *testing.T) {
 			expected: []string{
 				prowJobIndexKeyAll,
 				prowJobIndexKeyPending,
+				prowJobIndexKeyPendingTriggered,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
 			},
@@ -321,6 +322,7 @@ func TestProwJobIndexer(t *testing.T) {
 			modify: func(pj *prowv1.ProwJob) { pj.Status.State = prowv1.TriggeredState },
 			expected: []string{
 				prowJobIndexKeyAll,
+				prowJobIndexKeyPendingTriggered,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
 			},
@@ -344,6 +346,7 @@ func TestProwJobIndexer(t *testing.T) {
 			expected: []string{
 				prowJobIndexKeyAll,
 				prowJobIndexKeyPending,
+				prowJobIndexKeyPendingTriggered,
 				pendingTriggeredIndexKeyByName("some-name"),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
 			},
@@ -354,6 +357,7 @@ func TestProwJobIndexer(t *testing.T) {
 			expected: []string{
 				prowJobIndexKeyAll,
 				prowJobIndexKeyPending,
+				prowJobIndexKeyPendingTriggered,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName("some-name"),
 			},
@@ -63,6 +63,8 @@ type githubClient interface {
 	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
 	ListReviews(org, repo string, number int) ([]github.Review, error)
 	ListPullRequestComments(org, repo string, number int) ([]github.ReviewComment, error)
+	ListPRCommits(org, repo string, number int) ([]github.RepositoryCommit, error)
+	GetSingleCommit(org, repo, SHA string) (github.RepositoryCommit, error)
 	DeleteComment(org, repo string, ID int) error
 	CreateComment(org, repo string, number int, comment string) error
 	BotUserChecker() (func(candidate string) bool, error)
@@ -85,6 +87,7 @@ type state struct {
 	author    string
 	assignees []github.User
 	htmlURL   string
+	headSHA   string
 }
 
 func init() {
@@ -111,6 +114,12 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	for _, repo := range enabledRepos {
 		opts := config.ApproveFor(repo.Org, repo.Repo)
 		approveConfig[repo.String()] = fmt.Sprintf("Pull requests %s require an associated issue.<br>Pull request authors %s implicitly approve their own PRs.<br>The /lgtm [cancel] command(s) %s act as approval.<br>A GitHub approved or changes requested review %s act as approval or cancel respectively.", doNot(opts.IssueRequired), doNot(opts.HasSelfApproval()), willNot(opts.LgtmActsAsApprove), willNot(opts.ConsiderReviewState()))
+		if opts.InvalidateApprovalsOnForcePush {
+			approveConfig[repo.String()] += "<br>Approvals are dropped when the approved commit is no longer part of the PR, e.g. after a force push."
+		}
+		if opts.InvalidateApprovalsOnOwnedChanges {
+			approveConfig[repo.String()] += "<br>Approvals are dropped when later commits change files the approver approves."
+		}
 	}
 
 	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
@@ -206,6 +215,7 @@ func handleGenericComment(log *logrus.Entry, ghc githubClient, oc ownersClient,
 			author:    ce.IssueAuthor.Login,
 			assignees: ce.Assignees,
 			htmlURL:   ce.IssueHTMLURL,
+			headSHA:   pr.Head.SHA,
 		},
 	)
 }
@@ -276,6 +286,7 @@ func handleReview(log *logrus.Entry, ghc githubClient, oc ownersClient, githubCo
 			author:    re.PullRequest.User.Login,
 			assignees: re.PullRequest.Assignees,
 			htmlURL:   re.PullRequest.HTMLURL,
+			headSHA:   re.PullRequest.Head.SHA,
 		},
 	)
 
@@ -335,6 +346,7 @@ func handlePullRequest(log *logrus.Entry, ghc githubClient, oc ownersClient, git
 			author:    pre.PullRequest.User.Login,
 			assignees: pre.PullRequest.Assignees,
 			htmlURL:   pre.PullRequest.HTMLURL,
+			headSHA:   pre.PullRequest.Head.SHA,
 		},
 	)
 }
@@ -459,9 +471,18 @@ func handle(log *logrus.Entry, ghc githubClient, repo approvers.Repo, githubConf
 		approversHandler.AddAssignees(user.Login)
 	}
 
-	start = time.Now()
 	notifications := filterComments(commentsFromIssueComments, notificationMatcher(botUserChecker))
 	latestNotification := getLast(notifications)
+
+	if opts.InvalidatesApprovals() {
+		start = time.Now()
+		if err := invalidateApprovals(ghc, repo, opts, pr, &approversHandler, latestNotification, reviews); err != nil {
+			return err
+		}
+		log.WithField("duration", time.Since(start).String()).Debug("Completed invalidating approvals in handle")
+	}
+
+	start = time.Now()
 	newMessage := updateNotification(githubConfig.LinkURL, opts.CommandHelpLink, opts.PrProcessLink, pr.org, pr.repo, pr.branch, latestNotification, approversHandler)
 	log.WithField("duration", time.Since(start).String()).Debug("Completed getting notifications in handle")
 	start = time.Now()
@@ -493,6 +514,100 @@ func handle(log *logrus.Entry, ghc githubClient, repo approvers.Repo, githubConf
 	return nil
 }
 
+// invalidateApprovals records the commit each approver saw and drops the
+// approvals of commits that are no longer part of the PR or that were followed
+// by commits changing files the approver approves, as configured.
+//
+// The commit of an approval is the one of the review it was given in or else
+// the head of the PR when the approval was first handled. It is kept in the
+// approval notification until the approver approves again.
+func invalidateApprovals(ghc githubClient, repo approvers.Repo, opts *plugins.Approve, pr *state, approversHandler *approvers.Approvers, latestNotification *comment, reviews []github.Review) error {
+	recorded := map[string]approvers.RecordedApproval{}
+	if latestNotification != nil {
+		recorded = approvers.GetRecordedApprovals(latestNotification.Body)
+	}
+	reviewCommits := map[string]string{}
+	for _, review := range reviews {
+		if review.CommitID != "" {
+			reviewCommits[review.HTMLURL] = review.CommitID
+		}
+	}
+
+	var commits []github.RepositoryCommit
+	changedFiles := map[string][]string{}
+	for _, approval := range approversHandler.ListApprovals() {
+		// Authors saw all of their commits.
+		if strings.EqualFold(approval.Login, pr.author) {
+			continue
+		}
+		sha := pr.headSHA
+		if r, ok := recorded[strings.ToLower(approval.Login)]; ok && r.Reference == approval.Reference && r.SHA != "" {
+			sha = r.SHA
+		} else if commit, ok := reviewCommits[approval.Reference]; ok {
+			sha = commit
+		}
+		approversHandler.SetApprovalSHA(approval.Login, sha)
+		if sha == pr.headSHA {
+			continue
+		}
+
+		if commits == nil {
+			var err error
+			if commits, err = ghc.ListPRCommits(pr.org, pr.repo, pr.number); err != nil {
+				return fmt.Errorf("failed to get commits for %s/%s#%d: %w", pr.org, pr.repo, pr.number, err)
+			}
+		}
+		approved := -1
+		for i, commit := range commits {
+			if commit.SHA == sha {
+				approved = i
+				break
+			}
+		}
+		if approved < 0 {
+			if opts.InvalidateApprovalsOnForcePush {
+				approversHandler.InvalidateApproval(approval.Login, "the approved commit is no longer part of the PR")
+			}
+			continue
+		}
+		if !opts.InvalidateApprovalsOnOwnedChanges {
+			continue
+		}
+		for _, commit := range commits[approved+1:] {
+			files, ok := changedFiles[commit.SHA]
+			if !ok {
+				details, err := ghc.GetSingleCommit(pr.org, pr.repo, commit.SHA)
+				if err != nil {
+					return fmt.Errorf("failed to get commit %s of %s/%s#%d: %w", commit.SHA, pr.org, pr.repo, pr.number, err)
+				}
+				files = []string{}
+				for _, file := range details.Files {
+					files = append(files, file.Filename)
+				}
+				changedFiles[commit.SHA] = files
+			}
+			if approvesAnyFile(repo, approval.Login, files) {
+				approversHandler.InvalidateApproval(approval.Login, "later commits changed files they approve")
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// approvesAnyFile returns true if login is an approver of any of the files,
+// directly or through a parent directory.
+func approvesAnyFile(repo approvers.Repo, login string, files []string) bool {
+	for _, file := range files {
+		for approver := range repo.Approvers(repo.FindApproverOwnersForFile(file)).Set() {
+			if strings.EqualFold(approver, login) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func humanAddedApproved(ghc githubClient, log *logrus.Entry, org, repo string, number int, hasLabel bool) func() bool {
 	findOut := func() bool {
 		if !hasLabel {
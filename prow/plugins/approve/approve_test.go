@@ -1367,6 +1367,154 @@ Approvers can cancel approval by writing ` + "`/approve cancel`" + ` in a commen
 	}
 }
 
+func TestHandleInvalidatesApprovals(t *testing.T) {
+	approveComment := newTestComment("alice", "/approve")
+	approveComment.HTMLURL = "https://github.com/org/repo/pull/1#issuecomment-1"
+	approveReview := newTestReview("alice", "", github.ReviewStateApproved)
+	approveReview.HTMLURL = "https://github.com/org/repo/pull/1#pullrequestreview-1"
+	approveReview.CommitID = "c1"
+	notification := func(login, reference, sha string) github.IssueComment {
+		return newTestComment(fakegithub.Bot, fmt.Sprintf("[APPROVALNOTIFIER] This PR is **APPROVED**\n\n<!-- APPROVALS={%q:{\"reference\":%q,\"sha\":%q}} -->", login, reference, sha))
+	}
+
+	tests := []struct {
+		name           string
+		comments       []github.IssueComment
+		reviews        []github.Review
+		onForcePush    bool
+		onOwnedChanges bool
+		// commit -> files changed by it
+		commitFiles map[string][]string
+
+		expectApproved bool
+		expectMessage  string
+	}{
+		{
+			name:           "approval of the head is kept",
+			comments:       []github.IssueComment{approveComment},
+			onForcePush:    true,
+			onOwnedChanges: true,
+			expectApproved: true,
+			expectMessage:  `"alice":{"reference":"https://github.com/org/repo/pull/1#issuecomment-1","sha":"c3"}`,
+		},
+		{
+			name:           "later commits changing approved files drop the approval",
+			comments:       []github.IssueComment{approveComment, notification("alice", approveComment.HTMLURL, "c1")},
+			onOwnedChanges: true,
+			commitFiles:    map[string][]string{"c2": {"docs/README.md"}, "c3": {"a/a.go"}},
+			expectMessage:  "later commits changed files they approve",
+		},
+		{
+			name:           "later commits changing files of other approvers keep the approval",
+			comments:       []github.IssueComment{approveComment, notification("alice", approveComment.HTMLURL, "c1")},
+			onOwnedChanges: true,
+			commitFiles:    map[string][]string{"c2": {"docs/README.md"}, "c3": {"c/c.go"}},
+			expectApproved: true,
+			expectMessage:  "(at c1)",
+		},
+		{
+			name:           "later commits are ignored unless configured",
+			comments:       []github.IssueComment{approveComment, notification("alice", approveComment.HTMLURL, "c1")},
+			onForcePush:    true,
+			commitFiles:    map[string][]string{"c2": {"a/a.go"}},
+			expectApproved: true,
+			expectMessage:  "(at c1)",
+		},
+		{
+			name:          "force push drops the approval",
+			comments:      []github.IssueComment{approveComment, notification("alice", approveComment.HTMLURL, "gone")},
+			onForcePush:   true,
+			expectMessage: "the approved commit is no longer part of the PR",
+		},
+		{
+			name:           "force push is ignored unless configured",
+			comments:       []github.IssueComment{approveComment, notification("alice", approveComment.HTMLURL, "gone")},
+			onOwnedChanges: true,
+			expectApproved: true,
+			expectMessage:  "(at gone)",
+		},
+		{
+			name:           "approving again after a force push approves the head",
+			comments:       []github.IssueComment{notification("alice", "https://github.com/org/repo/pull/1#issuecomment-0", "gone"), approveComment},
+			onForcePush:    true,
+			expectApproved: true,
+			expectMessage:  "(at c3)",
+		},
+		{
+			name:           "reviews approve the commit they were submitted for",
+			reviews:        []github.Review{approveReview},
+			onOwnedChanges: true,
+			commitFiles:    map[string][]string{"c2": {"a/a.go"}},
+			expectMessage:  "later commits changed files they approve",
+		},
+	}
+
+	fr := fakeRepo{
+		approvers: map[string]layeredsets.String{
+			"a": layeredsets.NewString("alice"),
+			"c": layeredsets.NewString("cblecker"),
+		},
+		leafApprovers: map[string]sets.String{
+			"a": sets.NewString("alice"),
+			"c": sets.NewString("cblecker"),
+		},
+		approverOwners: map[string]string{
+			"a/a.go": "a",
+			"c/c.go": "c",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fghc := newFakeGitHubClient(false, false, []string{"a/a.go"}, test.comments, test.reviews)
+			fghc.CommitMap[fmt.Sprintf("org/repo#%d", prNumber)] = []github.RepositoryCommit{{SHA: "c1"}, {SHA: "c2"}, {SHA: "c3"}}
+			fghc.Commits = map[string]github.RepositoryCommit{}
+			for sha, files := range test.commitFiles {
+				commit := github.RepositoryCommit{SHA: sha}
+				for _, file := range files {
+					commit.Files = append(commit.Files, github.CommitFile{Filename: file})
+				}
+				fghc.Commits[sha] = commit
+			}
+
+			rsa := true
+			if err := handle(
+				logrus.WithField("plugin", "approve"),
+				fghc,
+				fr,
+				config.GitHubOptions{},
+				&plugins.Approve{
+					Repos:                             []string{"org/repo"},
+					RequireSelfApproval:               &rsa,
+					InvalidateApprovalsOnForcePush:    test.onForcePush,
+					InvalidateApprovalsOnOwnedChanges: test.onOwnedChanges,
+				},
+				&state{
+					org:     "org",
+					repo:    "repo",
+					branch:  "master",
+					number:  prNumber,
+					author:  "author",
+					headSHA: "c3",
+				},
+			); err != nil {
+				t.Fatalf("Unexpected error handling event: %v.", err)
+			}
+
+			approved := sets.NewString(fghc.IssueLabelsAdded...).Has(fmt.Sprintf("org/repo#%d:%s", prNumber, labels.Approved))
+			if approved != test.expectApproved {
+				t.Errorf("Expected approved %t, got %t.", test.expectApproved, approved)
+			}
+			if len(fghc.IssueCommentsAdded) != 1 {
+				t.Fatalf("Expected 1 notification to be added but %d notifications were added.", len(fghc.IssueCommentsAdded))
+			}
+			if !strings.Contains(fghc.IssueCommentsAdded[0], test.expectMessage) {
+				t.Errorf("Expected notification to contain %q, got %q.", test.expectMessage, fghc.IssueCommentsAdded[0])
+			}
+		})
+	}
+}
+
 // TODO: cache approvers 'GetFilesApprovers' and 'GetCCs' since these are called repeatedly and are
 // expensive.
 
@@ -1067,3 +1067,60 @@ Approvers can cancel approval by writing ` + "`/approve cancel`" + ` in a commen
 		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
 	}
 }
+
+func TestGetMessageInvalidatedApprovals(t *testing.T) {
+	ap := NewApprovers(
+		Owners{
+			filenames: []string{"a/a.go", "b/b.go"},
+			repo: createFakeRepo(map[string]sets.String{
+				"a": sets.NewString("Alice"),
+				"b": sets.NewString("Bill"),
+			}),
+			log: logrus.WithField("plugin", "some_plugin"),
+		},
+	)
+	ap.RequireIssue = true
+	ap.AddApprover("Alice", "REFERENCE", false)
+	ap.AddApprover("Bill", "REFERENCE", false)
+	ap.SetApprovalSHA("Alice", "0123456789")
+	ap.SetApprovalSHA("Bill", "abcdef1234")
+	ap.InvalidateApproval("Alice", "later commits changed files they approve")
+
+	want := `[APPROVALNOTIFIER] This PR is **NOT APPROVED**
+
+This pull-request has been approved by: *<a href="REFERENCE" title="Approved">Bill</a>* (at abcdef1)
+
+Approvals dropped because of commits pushed after them, these approvers need to approve again: *<a href="REFERENCE" title="Approved">Alice</a>* (at 0123456): later commits changed files they approve
+**Once this PR has been reviewed and has the lgtm label**, please assign alice for approval by writing ` + "`/assign @alice`" + ` in a comment. For more information see:[The Kubernetes Code Review Process](https://git.k8s.io/community/contributors/guide/owners.md#the-code-review-process).
+
+*No associated issue*. Update pull-request body to add a reference to an issue, or get approval with ` + "`/approve no-issue`" + `
+
+The full list of commands accepted by this bot can be found [here](https://go.k8s.io/bot-commands?repo=org%2Frepo).
+
+<details open>
+Needs approval from an approver in each of these files:
+
+- **[a/OWNERS](https://github.com/org/repo/blob/dev/a/OWNERS)**
+- ~~[b/OWNERS](https://github.com/org/repo/blob/dev/b/OWNERS)~~ [Bill]
+
+Approvers can indicate their approval by writing ` + "`/approve`" + ` in a comment
+Approvers can cancel approval by writing ` + "`/approve cancel`" + ` in a comment
+</details>
+<!-- META={"approvers":["alice"]} -->
+<!-- APPROVALS={"alice":{"reference":"REFERENCE","sha":"0123456789"},"bill":{"reference":"REFERENCE","sha":"abcdef1234"}} -->`
+	got := GetMessage(ap, &url.URL{Scheme: "https", Host: "github.com"}, "https://go.k8s.io/bot-commands", "https://git.k8s.io/community/contributors/guide/owners.md#the-code-review-process", "org", "repo", "dev")
+	if got == nil {
+		t.Fatal("GetMessage() failed")
+	}
+	if *got != want {
+		t.Errorf("GetMessage() = %+v, want = %+v", *got, want)
+	}
+
+	wantRecorded := map[string]RecordedApproval{
+		"alice": {Reference: "REFERENCE", SHA: "0123456789"},
+		"bill":  {Reference: "REFERENCE", SHA: "abcdef1234"},
+	}
+	if recorded := GetRecordedApprovals(*got); !reflect.DeepEqual(recorded, wantRecorded) {
+		t.Errorf("GetRecordedApprovals() = %+v, want = %+v", recorded, wantRecorded)
+	}
+}
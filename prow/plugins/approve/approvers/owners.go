@@ -23,6 +23,7 @@ import (
 	"math/rand"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"text/template"
@@ -246,16 +247,84 @@ type Approval struct {
 	How       string // How did the approver approved
 	Reference string // Where did the approver approved
 	NoIssue   bool   // Approval also accepts missing associated issue
+	SHA       string // Commit the approver saw, only recorded when approvals can be invalidated
 }
 
 // String creates a link for the approval. Use `Login` if you just want the name.
 func (a Approval) String() string {
-	return fmt.Sprintf(
+	link := fmt.Sprintf(
 		`*<a href="%s" title="%s">%s</a>*`,
 		a.Reference,
 		a.How,
 		a.Login,
 	)
+	if a.SHA == "" {
+		return link
+	}
+	return fmt.Sprintf("%s (at %s)", link, shortSHA(a.SHA))
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// InvalidatedApproval is an approval dropped because of commits pushed after it.
+type InvalidatedApproval struct {
+	Approval
+	Reason string
+}
+
+// String creates a link for the approval followed by the reason it was dropped.
+func (i InvalidatedApproval) String() string {
+	return fmt.Sprintf("%s: %s", i.Approval, i.Reason)
+}
+
+// RecordedApproval is the commit an approver saw, as recorded in the approval
+// notification.
+type RecordedApproval struct {
+	Reference string `json:"reference"`
+	SHA       string `json:"sha"`
+}
+
+var recordedApprovalsRegex = regexp.MustCompile(`<!-- APPROVALS=(.*) -->`)
+
+// GetRecordedApprovals returns the approvals recorded in an approval
+// notification, keyed by the lower case login of the approvers.
+func GetRecordedApprovals(notification string) map[string]RecordedApproval {
+	recorded := map[string]RecordedApproval{}
+	match := recordedApprovalsRegex.FindStringSubmatch(notification)
+	if match == nil {
+		return recorded
+	}
+	if err := json.Unmarshal([]byte(match[1]), &recorded); err != nil {
+		return map[string]RecordedApproval{}
+	}
+	return recorded
+}
+
+// getRecordedApprovalsMetadata records the commit each approver saw, including
+// the approvers whose approval was invalidated, so that it survives later runs.
+func getRecordedApprovalsMetadata(ap Approvers) string {
+	recorded := map[string]RecordedApproval{}
+	for login, approval := range ap.approvers {
+		if approval.SHA != "" {
+			recorded[login] = RecordedApproval{Reference: approval.Reference, SHA: approval.SHA}
+		}
+	}
+	for login, invalidated := range ap.invalidated {
+		recorded[login] = RecordedApproval{Reference: invalidated.Reference, SHA: invalidated.SHA}
+	}
+	if len(recorded) == 0 {
+		return ""
+	}
+	bytes, err := json.Marshal(recorded)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n<!-- APPROVALS=%s -->", bytes)
 }
 
 // Approvers is struct that provide functionality with regard to approvals of a specific
@@ -263,6 +332,7 @@ func (a Approval) String() string {
 type Approvers struct {
 	owners          Owners
 	approvers       map[string]Approval // The keys of this map are normalized to lowercase.
+	invalidated     map[string]InvalidatedApproval
 	assignees       sets.String
 	AssociatedIssue int
 	RequireIssue    bool
@@ -290,9 +360,10 @@ func CaseInsensitiveIntersection(one, other sets.String) sets.String {
 // NewApprovers create a new "Approvers" with no approval.
 func NewApprovers(owners Owners) Approvers {
 	return Approvers{
-		owners:    owners,
-		approvers: map[string]Approval{},
-		assignees: sets.NewString(),
+		owners:      owners,
+		approvers:   map[string]Approval{},
+		invalidated: map[string]InvalidatedApproval{},
+		assignees:   sets.NewString(),
 
 		ManuallyApproved: func() bool {
 			return false
@@ -356,6 +427,25 @@ func (ap *Approvers) RemoveApprover(login string) {
 	delete(ap.approvers, strings.ToLower(login))
 }
 
+// SetApprovalSHA records the commit login saw when approving.
+func (ap *Approvers) SetApprovalSHA(login, sha string) {
+	login = strings.ToLower(login)
+	if approval, ok := ap.approvers[login]; ok {
+		approval.SHA = sha
+		ap.approvers[login] = approval
+	}
+}
+
+// InvalidateApproval drops the approval of login. Unlike RemoveApprover, the
+// approval is still listed in the notification together with the reason.
+func (ap *Approvers) InvalidateApproval(login, reason string) {
+	login = strings.ToLower(login)
+	if approval, ok := ap.approvers[login]; ok {
+		ap.invalidated[login] = InvalidatedApproval{Approval: approval, Reason: reason}
+		delete(ap.approvers, login)
+	}
+}
+
 // AddAssignees adds assignees to the list
 func (ap *Approvers) AddAssignees(logins ...string) {
 	for _, login := range logins {
@@ -541,6 +631,18 @@ func (ap Approvers) ListApprovals() []Approval {
 	return approvals
 }
 
+// ListInvalidatedApprovals returns the list of approvals dropped because of
+// commits pushed after them
+func (ap Approvers) ListInvalidatedApprovals() []InvalidatedApproval {
+	approvals := []InvalidatedApproval{}
+
+	for _, approver := range sets.StringKeySet(ap.invalidated).List() {
+		approvals = append(approvals, ap.invalidated[approver])
+	}
+
+	return approvals
+}
+
 // ListNoIssueApprovals returns the list of "no-issue" approvals
 func (ap Approvers) ListNoIssueApprovals() []Approval {
 	approvals := []Approval{}
@@ -637,6 +739,10 @@ Approval requirements bypassed by manually added approval.
 
 {{end -}}
 This pull-request has been approved by:{{range $index, $approval := .ap.ListApprovals}}{{if $index}}, {{else}} {{end}}{{$approval}}{{end}}
+{{- if .ap.ListInvalidatedApprovals }}
+
+Approvals dropped because of commits pushed after them, these approvers need to approve again:{{range $index, $approval := .ap.ListInvalidatedApprovals}}{{if $index}}, {{else}} {{end}}{{$approval}}{{end}}
+{{- end}}
 
 {{- if (and (not .ap.AreFilesApproved) (not (call .ap.ManuallyApproved))) }}
 {{ if len .ap.SuggestedCCs -}}
@@ -685,6 +791,7 @@ Approvers can cancel approval by writing `+"`/approve cancel`"+` in a comment
 		return nil
 	}
 	message += getGubernatorMetadata(ap.GetCCs())
+	message += getRecordedApprovalsMetadata(ap)
 
 	title, err := GenerateTemplate("This PR is **{{if not .IsApproved}}NOT {{end}}APPROVED**", "title", ap)
 	if err != nil {
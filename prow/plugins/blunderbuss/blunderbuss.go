@@ -68,13 +68,17 @@ func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhel
 			MaxReviewerCount:      3,
 			ExcludeApprovers:      true,
 			UseStatusAvailability: true,
+			UseReviewLoad:         true,
+			WorkingHours: map[string]plugins.WorkingHours{
+				"*": {TimeZone: "America/Los_Angeles", Start: 9, End: 17},
+			},
 		},
 	})
 	if err != nil {
 		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", PluginName)
 	}
 	pluginHelp := &pluginhelp.PluginHelp{
-		Description: "The blunderbuss plugin automatically requests reviews from reviewers when a new PR is created. The reviewers are selected based on the reviewers specified in the OWNERS files that apply to the files modified by the PR.",
+		Description: "The blunderbuss plugin automatically requests reviews from reviewers when a new PR is created. The reviewers are selected based on the reviewers specified in the OWNERS files that apply to the files modified by the PR. Reviewers with fewer pending reviews and past review requests who are within their working hours can be preferred.",
 		Config: map[string]string{
 			"": configString(reviewCount),
 		},
@@ -131,10 +135,24 @@ type repoownersClient interface {
 	LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error)
 }
 
+// assignmentCountsFor returns the configured store of assignment counts, if any
+func assignmentCountsFor(pc plugins.Agent) *assignmentCounts {
+	config := pc.PluginConfig.Blunderbuss.AssignmentCounts
+	if config == nil || pc.KubernetesClient == nil {
+		return nil
+	}
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = pc.Config.ProwJobNamespace
+	}
+	return newAssignmentCounts(pc.KubernetesClient.CoreV1().ConfigMaps(namespace), config)
+}
+
 func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error {
 	return handlePullRequest(
 		pc.GitHubClient,
 		pc.OwnersClient,
+		assignmentCountsFor(pc),
 		pc.Logger,
 		pc.PluginConfig.Blunderbuss,
 		pre.Action,
@@ -143,7 +161,7 @@ func handlePullRequestEvent(pc plugins.Agent, pre github.PullRequestEvent) error
 	)
 }
 
-func handlePullRequest(ghc githubClient, roc repoownersClient, log *logrus.Entry, config plugins.Blunderbuss, action github.PullRequestEventAction, pr *github.PullRequest, repo *github.Repo) error {
+func handlePullRequest(ghc githubClient, roc repoownersClient, assignments *assignmentCounts, log *logrus.Entry, config plugins.Blunderbuss, action github.PullRequestEventAction, pr *github.PullRequest, repo *github.Repo) error {
 	if !(action == github.PullRequestActionOpened || action == github.PullRequestActionReadyForReview) || assign.CCRegexp.MatchString(pr.Body) {
 		return nil
 	}
@@ -159,6 +177,7 @@ func handlePullRequest(ghc githubClient, roc repoownersClient, log *logrus.Entry
 		config.MaxReviewerCount,
 		config.ExcludeApprovers,
 		config.UseStatusAvailability,
+		newWeigher(ghc, log, config, repo.Owner.Login, assignments),
 		repo,
 		pr,
 	)
@@ -168,6 +187,7 @@ func handleGenericCommentEvent(pc plugins.Agent, ce github.GenericCommentEvent)
 	return handleGenericComment(
 		pc.GitHubClient,
 		pc.OwnersClient,
+		assignmentCountsFor(pc),
 		pc.Logger,
 		pc.PluginConfig.Blunderbuss,
 		ce.Action,
@@ -179,7 +199,7 @@ func handleGenericCommentEvent(pc plugins.Agent, ce github.GenericCommentEvent)
 	)
 }
 
-func handleGenericComment(ghc githubClient, roc repoownersClient, log *logrus.Entry, config plugins.Blunderbuss, action github.GenericCommentEventAction, isPR bool, prNumber int, issueState string, repo *github.Repo, body string) error {
+func handleGenericComment(ghc githubClient, roc repoownersClient, assignments *assignmentCounts, log *logrus.Entry, config plugins.Blunderbuss, action github.GenericCommentEventAction, isPR bool, prNumber int, issueState string, repo *github.Repo, body string) error {
 	if action != github.GenericCommentActionCreated || !isPR || issueState == "closed" {
 		return nil
 	}
@@ -201,12 +221,13 @@ func handleGenericComment(ghc githubClient, roc repoownersClient, log *logrus.En
 		config.MaxReviewerCount,
 		config.ExcludeApprovers,
 		config.UseStatusAvailability,
+		newWeigher(ghc, log, config, repo.Owner.Login, assignments),
 		repo,
 		pr,
 	)
 }
 
-func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerCount *int, maxReviewers int, excludeApprovers bool, useStatusAvailability bool, repo *github.Repo, pr *github.PullRequest) error {
+func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerCount *int, maxReviewers int, excludeApprovers bool, useStatusAvailability bool, w *weigher, repo *github.Repo, pr *github.PullRequest) error {
 	oc, err := roc.LoadRepoOwners(repo.Owner.Login, repo.Name, pr.Base.Ref)
 	if err != nil {
 		return fmt.Errorf("error loading RepoOwners: %w", err)
@@ -220,7 +241,7 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 	var reviewers []string
 	var requiredReviewers []string
 	if reviewerCount != nil {
-		reviewers, requiredReviewers, err = getReviewers(oc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+		reviewers, requiredReviewers, err = getReviewers(oc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability, w)
 		if err != nil {
 			return err
 		}
@@ -231,7 +252,7 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 				// and approvers and the search might stop too early if it finds
 				// duplicates.
 				frc := fallbackReviewersClient{ownersClient: oc}
-				approvers, _, err := getReviewers(frc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability)
+				approvers, _, err := getReviewers(frc, ghc, log, pr.User.Login, changes, *reviewerCount, useStatusAvailability, w)
 				if err != nil {
 					return err
 				}
@@ -262,12 +283,17 @@ func handle(ghc githubClient, roc repoownersClient, log *logrus.Entry, reviewerC
 
 	if len(reviewers) > 0 {
 		log.Infof("Requesting reviews from users %s.", reviewers)
-		return ghc.RequestReview(repo.Owner.Login, repo.Name, pr.Number, reviewers)
+		if err := ghc.RequestReview(repo.Owner.Login, repo.Name, pr.Number, reviewers); err != nil {
+			return err
+		}
+		if w != nil {
+			w.assigned(reviewers)
+		}
 	}
 	return nil
 }
 
-func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, author string, files []github.PullRequestChange, minReviewers int, useStatusAvailability bool) ([]string, []string, error) {
+func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, author string, files []github.PullRequestChange, minReviewers int, useStatusAvailability bool, w *weigher) ([]string, []string, error) {
 	authorSet := sets.NewString(github.NormLogin(author))
 	reviewers := layeredsets.NewString()
 	requiredReviewers := sets.NewString()
@@ -293,14 +319,14 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 			continue
 		}
 		leafReviewers = leafReviewers.Union(fileUnusedLeafs)
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileUnusedLeafs); r != "" {
+		if r := findReviewer(ghc, log, useStatusAvailability, w, &busyReviewers, &fileUnusedLeafs); r != "" {
 			reviewers.Insert(0, r)
 		}
 	}
 	// now ensure that we request review from at least minReviewers reviewers. Favor leaf reviewers.
 	unusedLeafs := leafReviewers.Difference(reviewers.Set())
 	for reviewers.Len() < minReviewers && unusedLeafs.Len() > 0 {
-		if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &unusedLeafs); r != "" {
+		if r := findReviewer(ghc, log, useStatusAvailability, w, &busyReviewers, &unusedLeafs); r != "" {
 			reviewers.Insert(1, r)
 		}
 	}
@@ -310,7 +336,7 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 		}
 		fileReviewers := rc.Reviewers(file.Filename).Difference(authorSet)
 		for reviewers.Len() < minReviewers && fileReviewers.Len() > 0 {
-			if r := findReviewer(ghc, log, useStatusAvailability, &busyReviewers, &fileReviewers); r != "" {
+			if r := findReviewer(ghc, log, useStatusAvailability, w, &busyReviewers, &fileReviewers); r != "" {
 				reviewers.Insert(2, r)
 			}
 		}
@@ -318,12 +344,21 @@ func getReviewers(rc reviewersClient, ghc githubClient, log *logrus.Entry, autho
 	return reviewers.List(), requiredReviewers.List(), nil
 }
 
+// popCandidate pops a candidate from a set, preferring candidates with a
+// higher weight if a weigher is given.
+func popCandidate(w *weigher, targetSet *layeredsets.String) string {
+	if w == nil {
+		return targetSet.PopRandom()
+	}
+	return targetSet.PopWeighted(w.weight)
+}
+
 // findReviewer finds a reviewer from a set, potentially using status
 // availability.
-func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability bool, busyReviewers *sets.String, targetSet *layeredsets.String) string {
+func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability bool, w *weigher, busyReviewers *sets.String, targetSet *layeredsets.String) string {
 	// if we don't care about status availability, just pop a target from the set
 	if !useStatusAvailability {
-		return targetSet.PopRandom()
+		return popCandidate(w, targetSet)
 	}
 
 	// if we do care, start looping through the candidates
@@ -332,7 +367,7 @@ func findReviewer(ghc githubClient, log *logrus.Entry, useStatusAvailability boo
 			// if there are no candidates left, then break
 			break
 		}
-		candidate := targetSet.PopRandom()
+		candidate := popCandidate(w, targetSet)
 		if busyReviewers.Has(candidate) {
 			// we've already verified this reviewer is busy
 			continue
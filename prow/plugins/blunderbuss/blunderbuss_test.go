@@ -44,6 +44,8 @@ type fakeGitHubClient struct {
 	pr        *github.PullRequest
 	changes   []github.PullRequestChange
 	requested []string
+	// loads is the number of pending review requests per user
+	loads map[string]int
 }
 
 func newFakeGitHubClient(pr *github.PullRequest, filesChanged []string) *fakeGitHubClient {
@@ -86,6 +88,15 @@ func (c *fakeGitHubClient) GetPullRequest(org, repo string, num int) (*github.Pu
 }
 
 func (c *fakeGitHubClient) Query(ctx context.Context, q interface{}, vars map[string]interface{}) error {
+	if lq, ok := q.(*reviewLoadQuery); ok {
+		query := string(vars["query"].(githubql.String))
+		for user, load := range c.loads {
+			if strings.HasSuffix(query, " review-requested:"+user) {
+				lq.Search.IssueCount = githubql.Int(load)
+			}
+		}
+		return nil
+	}
 	sq, ok := q.(*githubAvailabilityQuery)
 	if !ok {
 		return errors.New("unexpected query type")
@@ -347,7 +358,7 @@ func TestHandleWithExcludeApproversOnlyReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, true, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, true, false, nil, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -389,7 +400,7 @@ func TestHandleWithoutExcludeApproversNoReviewers(t *testing.T) {
 
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, false, nil, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -534,7 +545,7 @@ func TestHandleWithoutExcludeApproversMixed(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, false, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, false, nil, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -636,7 +647,7 @@ func TestHandlePullRequest(t *testing.T) {
 			}
 
 			if err := handlePullRequest(
-				fghc, froc, logrus.WithField("plugin", PluginName),
+				fghc, froc, nil, logrus.WithField("plugin", PluginName),
 				c, tc.action, &pr, &repo,
 			); err != nil {
 				t.Fatalf("unexpected error from handle: %v", err)
@@ -731,7 +742,7 @@ func TestHandleGenericComment(t *testing.T) {
 			}
 
 			if err := handleGenericComment(
-				fghc, froc, logrus.WithField("plugin", PluginName), config,
+				fghc, froc, nil, logrus.WithField("plugin", PluginName), config,
 				tc.action, tc.isPR, pr.Number, tc.issueState, &repo, tc.body,
 			); err != nil {
 				t.Fatalf("unexpected error from handle: %v", err)
@@ -861,7 +872,7 @@ func TestPopActiveReviewer(t *testing.T) {
 		fghc := newFakeGitHubClient(&pr, tc.filesChanged)
 		if err := handle(
 			fghc, froc, logrus.WithField("plugin", PluginName),
-			&tc.reviewerCount, tc.maxReviewerCount, false, true, &repo, &pr,
+			&tc.reviewerCount, tc.maxReviewerCount, false, true, nil, &repo, &pr,
 		); err != nil {
 			t.Errorf("[%s] unexpected error from handle: %v", tc.name, err)
 			continue
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blunderbuss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	// offHoursWeight scales the weight of reviewers outside of their working
+	// hours, they are only requested if few others are available
+	offHoursWeight = 0.1
+	// defaultAssignmentPeriod is the period after which assignment counts
+	// are reset if none is configured
+	defaultAssignmentPeriod = 7 * 24 * time.Hour
+	// anyReviewer is the key of the default working hours
+	anyReviewer = "*"
+)
+
+// weigher weights candidates such that reviewers with fewer pending reviews
+// and past review requests who are within their working hours are preferred.
+// A nil weigher weights all candidates the same.
+type weigher struct {
+	ghc           githubClient
+	log           *logrus.Entry
+	org           string
+	useReviewLoad bool
+	workingHours  map[string]plugins.WorkingHours
+	assignments   *assignmentCounts
+	now           func() time.Time
+
+	loads  map[string]int
+	counts map[string]int
+}
+
+// newWeigher returns nil unless weighting is configured
+func newWeigher(ghc githubClient, log *logrus.Entry, config plugins.Blunderbuss, org string, assignments *assignmentCounts) *weigher {
+	if !config.UseReviewLoad && len(config.WorkingHours) == 0 && assignments == nil {
+		return nil
+	}
+	w := &weigher{
+		ghc:           ghc,
+		log:           log,
+		org:           org,
+		useReviewLoad: config.UseReviewLoad,
+		workingHours:  config.WorkingHours,
+		assignments:   assignments,
+		now:           time.Now,
+		loads:         map[string]int{},
+	}
+	if assignments != nil {
+		counts, err := assignments.get(org)
+		if err != nil {
+			log.WithError(err).Warn("Failed to load reviewer assignment counts")
+		}
+		w.counts = counts
+	}
+	return w
+}
+
+// weight returns the relative likelihood of requesting a review from login
+func (w *weigher) weight(login string) float64 {
+	weight := 1 / float64(1+w.reviewLoad(login)+w.counts[login])
+	if !w.available(login) {
+		weight *= offHoursWeight
+	}
+	return weight
+}
+
+type reviewLoadQuery struct {
+	Search struct {
+		IssueCount githubql.Int
+	} `graphql:"search(type: ISSUE, query: $query)"`
+}
+
+// reviewLoad returns the number of open pull requests in the org that
+// request a review from login
+func (w *weigher) reviewLoad(login string) int {
+	if !w.useReviewLoad {
+		return 0
+	}
+	if load, ok := w.loads[login]; ok {
+		return load
+	}
+	var query reviewLoadQuery
+	vars := map[string]interface{}{
+		"query": githubql.String(fmt.Sprintf("is:pr is:open archived:false org:%s review-requested:%s", w.org, login)),
+	}
+	if err := w.ghc.Query(context.Background(), &query, vars); err != nil {
+		w.log.WithField("user", login).WithError(err).Error("Error checking user review load")
+	}
+	w.loads[login] = int(query.Search.IssueCount)
+	return w.loads[login]
+}
+
+// available returns false if login is outside of their working hours
+func (w *weigher) available(login string) bool {
+	hours, ok := w.workingHours[login]
+	if !ok {
+		if hours, ok = w.workingHours[anyReviewer]; !ok {
+			return true
+		}
+	}
+	location, err := time.LoadLocation(hours.TimeZone)
+	if err != nil {
+		w.log.WithField("user", login).WithError(err).Warn("Invalid time zone in working hours")
+		return true
+	}
+	hour := w.now().In(location).Hour()
+	if hours.Start < hours.End {
+		return hours.Start <= hour && hour < hours.End
+	}
+	return hour >= hours.Start || hour < hours.End
+}
+
+// assigned records review requests so that they are rotated fairly
+func (w *weigher) assigned(logins []string) {
+	if w.assignments == nil {
+		return
+	}
+	if err := w.assignments.add(w.org, logins); err != nil {
+		w.log.WithError(err).Warn("Failed to record reviewer assignment counts")
+	}
+}
+
+// assignmentCounts persists the number of review requests per reviewer and
+// org in a ConfigMap, the counts of an org are reset once the period elapsed
+type assignmentCounts struct {
+	client corev1.ConfigMapInterface
+	name   string
+	period time.Duration
+	now    func() time.Time
+}
+
+type orgAssignments struct {
+	Since  time.Time      `json:"since"`
+	Counts map[string]int `json:"counts"`
+}
+
+func newAssignmentCounts(client corev1.ConfigMapInterface, config *plugins.AssignmentCounts) *assignmentCounts {
+	period := defaultAssignmentPeriod
+	if parsed, err := time.ParseDuration(config.Period); err == nil {
+		period = parsed
+	}
+	return &assignmentCounts{client: client, name: config.ConfigMap, period: period, now: time.Now}
+}
+
+// current returns the unexpired counts of org stored in cm
+func (a *assignmentCounts) current(cm *coreapi.ConfigMap, org string) (orgAssignments, error) {
+	fresh := orgAssignments{Since: a.now(), Counts: map[string]int{}}
+	raw, ok := cm.Data[org]
+	if !ok {
+		return fresh, nil
+	}
+	var assignments orgAssignments
+	if err := json.Unmarshal([]byte(raw), &assignments); err != nil {
+		return fresh, fmt.Errorf("failed to parse assignment counts of %s: %w", org, err)
+	}
+	if assignments.Counts == nil || a.now().Sub(assignments.Since) > a.period {
+		return fresh, nil
+	}
+	return assignments, nil
+}
+
+func (a *assignmentCounts) get(org string) (map[string]int, error) {
+	cm, err := a.client.Get(context.TODO(), a.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return map[string]int{}, fmt.Errorf("failed to get configmap %s: %w", a.name, err)
+	}
+	assignments, err := a.current(cm, org)
+	return assignments.Counts, err
+}
+
+func (a *assignmentCounts) add(org string, logins []string) error {
+	// concurrent requests may update or create the ConfigMap at the same time
+	retriable := func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm, err := a.client.Get(context.TODO(), a.name, metav1.GetOptions{})
+		isNotFound := errors.IsNotFound(err)
+		if err != nil && !isNotFound {
+			return fmt.Errorf("failed to get configmap %s: %w", a.name, err)
+		}
+		if isNotFound {
+			cm = &coreapi.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: a.name,
+					Labels: map[string]string{
+						"app.kubernetes.io/name":      "prow",
+						"app.kubernetes.io/component": "blunderbuss-plugin",
+					},
+				},
+			}
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+
+		// a corrupt entry is overwritten
+		assignments, _ := a.current(cm, org)
+		for _, login := range logins {
+			assignments.Counts[login]++
+		}
+		raw, err := json.Marshal(assignments)
+		if err != nil {
+			return fmt.Errorf("failed to marshal assignment counts: %w", err)
+		}
+		cm.Data[org] = string(raw)
+
+		if isNotFound {
+			_, err = a.client.Create(context.TODO(), cm, metav1.CreateOptions{})
+		} else {
+			_, err = a.client.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
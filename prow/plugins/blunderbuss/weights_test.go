@@ -0,0 +1,230 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blunderbuss
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pkg/layeredsets"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestAvailable(t *testing.T) {
+	// 2022-06-01 is a Wednesday, 10:00 UTC is 12:00 in Berlin and 19:00 in Tokyo
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	workingHours := map[string]plugins.WorkingHours{
+		"berlin":    {TimeZone: "Europe/Berlin", Start: 9, End: 17},
+		"tokyo":     {TimeZone: "Asia/Tokyo", Start: 9, End: 17},
+		"nightowl":  {TimeZone: "Asia/Tokyo", Start: 18, End: 2},
+		"earlybird": {Start: 4, End: 10},
+	}
+	testcases := []struct {
+		name              string
+		workingHours      map[string]plugins.WorkingHours
+		login             string
+		expectedAvailable bool
+	}{
+		{
+			name:              "no working hours",
+			login:             "anyone",
+			expectedAvailable: true,
+		},
+		{
+			name:              "within working hours",
+			workingHours:      workingHours,
+			login:             "berlin",
+			expectedAvailable: true,
+		},
+		{
+			name:              "outside of working hours",
+			workingHours:      workingHours,
+			login:             "tokyo",
+			expectedAvailable: false,
+		},
+		{
+			name:              "working hours wrapping around midnight",
+			workingHours:      workingHours,
+			login:             "nightowl",
+			expectedAvailable: true,
+		},
+		{
+			name:              "end of working hours is exclusive",
+			workingHours:      workingHours,
+			login:             "earlybird",
+			expectedAvailable: false,
+		},
+		{
+			name:              "reviewer without working hours",
+			workingHours:      workingHours,
+			login:             "anyone",
+			expectedAvailable: true,
+		},
+		{
+			name: "default working hours",
+			workingHours: map[string]plugins.WorkingHours{
+				anyReviewer: {TimeZone: "America/Los_Angeles", Start: 9, End: 17},
+			},
+			login:             "anyone",
+			expectedAvailable: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &weigher{log: logrus.WithField("plugin", PluginName), workingHours: tc.workingHours, now: func() time.Time { return now }}
+			if available := w.available(tc.login); available != tc.expectedAvailable {
+				t.Errorf("expected available to be %t, got %t", tc.expectedAvailable, available)
+			}
+		})
+	}
+}
+
+func TestWeight(t *testing.T) {
+	fghc := newFakeGitHubClient(nil, nil)
+	fghc.loads = map[string]int{"busy": 3, "idle": 0}
+	config := plugins.Blunderbuss{
+		UseReviewLoad: true,
+		WorkingHours: map[string]plugins.WorkingHours{
+			"sleeping": {Start: 22, End: 6},
+		},
+	}
+	w := newWeigher(fghc, logrus.WithField("plugin", PluginName), config, "org", nil)
+	w.now = func() time.Time { return time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC) }
+	w.counts = map[string]int{"popular": 1}
+
+	expected := map[string]float64{
+		"idle":     1,
+		"busy":     0.25,
+		"popular":  0.5,
+		"sleeping": offHoursWeight,
+	}
+	for login, weight := range expected {
+		if actual := w.weight(login); actual != weight {
+			t.Errorf("expected the weight of %s to be %v, got %v", login, weight, actual)
+		}
+	}
+
+	if w := newWeigher(fghc, logrus.WithField("plugin", PluginName), plugins.Blunderbuss{}, "org", nil); w != nil {
+		t.Errorf("expected no weigher without configuration, got %+v", w)
+	}
+}
+
+func TestPopCandidate(t *testing.T) {
+	w := &weigher{
+		log:    logrus.WithField("plugin", PluginName),
+		loads:  map[string]int{},
+		counts: map[string]int{"alice": 5, "bob": 0},
+		now:    time.Now,
+	}
+	picked := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		set := layeredsets.NewString("alice", "bob")
+		picked[popCandidate(w, &set)]++
+	}
+	// bob should be picked about six times as often as alice
+	if picked["bob"] < 3*picked["alice"] {
+		t.Errorf("expected reviewers with fewer assignments to be preferred, got %v", picked)
+	}
+}
+
+func TestAssignmentCounts(t *testing.T) {
+	client := fake.NewSimpleClientset().CoreV1().ConfigMaps("prow")
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	counts := newAssignmentCounts(client, &plugins.AssignmentCounts{ConfigMap: "blunderbuss", Period: "24h"})
+	counts.now = func() time.Time { return now }
+
+	if current, err := counts.get("org"); err != nil || len(current) != 0 {
+		t.Fatalf("expected no counts before the ConfigMap exists, got %v: %v", current, err)
+	}
+	if err := counts.add("org", []string{"alice", "bob"}); err != nil {
+		t.Fatalf("failed to add counts: %v", err)
+	}
+	if err := counts.add("org", []string{"alice"}); err != nil {
+		t.Fatalf("failed to add counts: %v", err)
+	}
+	if err := counts.add("other", []string{"bob"}); err != nil {
+		t.Fatalf("failed to add counts: %v", err)
+	}
+	current, err := counts.get("org")
+	if err != nil {
+		t.Fatalf("failed to get counts: %v", err)
+	}
+	if expected := map[string]int{"alice": 2, "bob": 1}; !reflect.DeepEqual(current, expected) {
+		t.Errorf("expected counts %v, got %v", expected, current)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if current, err := counts.get("org"); err != nil || len(current) != 0 {
+		t.Errorf("expected counts to be reset after the period, got %v: %v", current, err)
+	}
+	if err := counts.add("org", []string{"bob"}); err != nil {
+		t.Fatalf("failed to add counts: %v", err)
+	}
+	current, err = counts.get("org")
+	if err != nil {
+		t.Fatalf("failed to get counts: %v", err)
+	}
+	if expected := map[string]int{"bob": 1}; !reflect.DeepEqual(current, expected) {
+		t.Errorf("expected counts %v after the reset, got %v", expected, current)
+	}
+}
+
+func TestHandleRecordsAssignments(t *testing.T) {
+	froc := &fakeRepoownersClient{
+		foc: &fakeOwnersClient{
+			owners: map[string]string{"a.go": "1"},
+			leafReviewers: map[string]sets.String{
+				"a.go": sets.NewString("alice"),
+			},
+			reviewers: map[string]layeredsets.String{
+				"a.go": layeredsets.NewString("alice"),
+			},
+		},
+	}
+	pr := github.PullRequest{Number: 5, User: github.User{Login: "author"}}
+	repo := github.Repo{Owner: github.User{Login: "org"}, Name: "repo"}
+	fghc := newFakeGitHubClient(&pr, []string{"a.go"})
+	fkc := fake.NewSimpleClientset()
+	counts := newAssignmentCounts(fkc.CoreV1().ConfigMaps("prow"), &plugins.AssignmentCounts{ConfigMap: "blunderbuss"})
+	reviewerCount := 1
+	config := plugins.Blunderbuss{ReviewerCount: &reviewerCount}
+
+	if err := handlePullRequest(
+		fghc, froc, counts, logrus.WithField("plugin", PluginName),
+		config, github.PullRequestActionOpened, &pr, &repo,
+	); err != nil {
+		t.Fatalf("unexpected error from handle: %v", err)
+	}
+	if !reflect.DeepEqual(fghc.requested, []string{"alice"}) {
+		t.Fatalf("expected the requested reviewers to be [alice], got %q", fghc.requested)
+	}
+	cm, err := fkc.CoreV1().ConfigMaps("prow").Get(context.TODO(), "blunderbuss", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the assignment counts to be stored: %v", err)
+	}
+	if _, ok := cm.Data["org"]; !ok {
+		t.Errorf("expected the assignment counts of the org to be stored, got %v", cm.Data)
+	}
+}
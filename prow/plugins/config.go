@@ -62,6 +62,9 @@ type Configuration struct {
 	// Owners contains configuration related to handling OWNERS files.
 	Owners Owners `json:"owners,omitempty"`
 
+	// PluginLimits restricts how hook runs the event handlers of plugins.
+	PluginLimits PluginLimits `json:"plugin_limits,omitempty"`
+
 	// Built-in plugins specific configuration.
 	Approve              []Approve                    `json:"approve,omitempty"`
 	Blockades            []Blockade                   `json:"blockades,omitempty"`
@@ -112,6 +115,62 @@ func (h *Help) setDefaults() {
 	}
 }
 
+// PluginLimits configures the limits hook enforces when running the event
+// handlers of plugins, so that a slow or misbehaving plugin cannot hold up
+// hook or use up the API tokens shared by all plugins.
+type PluginLimits struct {
+	// Default applies to every plugin.
+	Default PluginLimit `json:"default,omitempty"`
+	// Plugins maps plugin names to the limits of the plugin. Fields left
+	// unset fall back to the ones of Default.
+	Plugins map[string]PluginLimit `json:"plugins,omitempty"`
+}
+
+// PluginLimit restricts how a plugin handles events. Zero values mean no limit.
+type PluginLimit struct {
+	// Timeout is how long the plugin may take to handle a single event,
+	// e.g. "2m". Once it passed, hook stops waiting for the handler and the
+	// GitHub, Jira, Bugzilla and git requests of the handler fail.
+	Timeout         string        `json:"timeout,omitempty"`
+	TimeoutDuration time.Duration `json:"-"`
+	// MaxConcurrency is how many events the plugin may handle at the same
+	// time. Further events wait until a handler finishes or times out.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// GitHubHourlyTokens is how many GitHub API requests per hour the plugin
+	// may make. Requests over the budget fail instead of being sent.
+	GitHubHourlyTokens int `json:"github_hourly_tokens,omitempty"`
+	// GitHubBurst is how many GitHub API requests the plugin may make at once
+	// within its budget. Defaults to GitHubHourlyTokens.
+	GitHubBurst int `json:"github_burst,omitempty"`
+}
+
+// For returns the limits of the given plugin.
+func (l PluginLimits) For(plugin string) PluginLimit {
+	limit := l.Default
+	override, ok := l.Plugins[plugin]
+	if !ok {
+		return limit
+	}
+	if override.Timeout != "" {
+		limit.Timeout = override.Timeout
+		limit.TimeoutDuration = override.TimeoutDuration
+	}
+	if override.MaxConcurrency != 0 {
+		limit.MaxConcurrency = override.MaxConcurrency
+	}
+	if override.GitHubHourlyTokens != 0 {
+		limit.GitHubHourlyTokens = override.GitHubHourlyTokens
+		limit.GitHubBurst = override.GitHubBurst
+	}
+	if override.GitHubBurst != 0 {
+		limit.GitHubBurst = override.GitHubBurst
+	}
+	if limit.GitHubHourlyTokens > 0 && limit.GitHubBurst == 0 {
+		limit.GitHubBurst = limit.GitHubHourlyTokens
+	}
+	return limit
+}
+
 // Golint holds configuration for the golint plugin
 type Golint struct {
 	// MinimumConfidence is the smallest permissible confidence
@@ -163,6 +222,39 @@ type Blunderbuss struct {
 	// IgnoreDrafts instructs the plugin to ignore assigning reviewers
 	// to the PR that is in Draft state. Default it's false.
 	IgnoreDrafts bool `json:"ignore_drafts,omitempty"`
+	// UseReviewLoad controls whether blunderbuss will prefer reviewers with
+	// fewer open pull requests awaiting their review across the org. This will
+	// use one additional token per candidate.
+	UseReviewLoad bool `json:"use_review_load,omitempty"`
+	// WorkingHours maps GitHub logins to the hours during which they review
+	// pull requests, "*" sets the hours of everyone else. Reviewers outside of
+	// their working hours are less likely to be requested.
+	WorkingHours map[string]WorkingHours `json:"working_hours,omitempty"`
+	// AssignmentCounts persists how often each reviewer was requested, so that
+	// reviews rotate fairly over time.
+	AssignmentCounts *AssignmentCounts `json:"assignment_counts,omitempty"`
+}
+
+// WorkingHours is the time of day during which a reviewer is available.
+type WorkingHours struct {
+	// TimeZone is the IANA name of the time zone of the reviewer, e.g.
+	// "Europe/Berlin". Defaults to UTC.
+	TimeZone string `json:"timezone,omitempty"`
+	// Start is the hour of the day at which the reviewer starts working.
+	Start int `json:"start"`
+	// End is the hour of the day at which the reviewer stops working. Hours
+	// that wrap around midnight are supported, e.g. 22 to 6.
+	End int `json:"end"`
+}
+
+// AssignmentCounts configures where blunderbuss stores review request counts.
+type AssignmentCounts struct {
+	// ConfigMap is the name of the ConfigMap holding the counts.
+	ConfigMap string `json:"configmap"`
+	// Namespace of the ConfigMap, defaults to the ProwJob namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Period after which the counts are reset, defaults to 168h (one week).
+	Period string `json:"period,omitempty"`
 }
 
 // Owners contains configuration related to handling OWNERS files.
@@ -317,6 +409,15 @@ type Approve struct {
 	// PrProcessLink is the link to the help page which explains the code review process.
 	// The default value is "https://git.k8s.io/community/contributors/guide/owners.md#the-code-review-process".
 	PrProcessLink string `json:"pr_process_link,omitempty"`
+	// InvalidateApprovalsOnForcePush drops an approval once the commit the approver
+	// saw is no longer part of the PR, e.g. because the PR was force-pushed.
+	// The approval notification records the commit each approver saw.
+	InvalidateApprovalsOnForcePush bool `json:"invalidate_approvals_on_force_push,omitempty"`
+	// InvalidateApprovalsOnOwnedChanges drops an approval once commits pushed after
+	// it change files the approver is an approver of. Changes to files owned only by
+	// other approvers keep the approval.
+	// The approval notification records the commit each approver saw.
+	InvalidateApprovalsOnOwnedChanges bool `json:"invalidate_approvals_on_owned_changes,omitempty"`
 }
 
 var (
@@ -337,6 +438,11 @@ func (a Approve) ConsiderReviewState() bool {
 	return true
 }
 
+// InvalidatesApprovals returns true if commits pushed after an approval can drop it.
+func (a Approve) InvalidatesApprovals() bool {
+	return a.InvalidateApprovalsOnForcePush || a.InvalidateApprovalsOnOwnedChanges
+}
+
 // Lgtm specifies a configuration for a single lgtm.
 // The configuration for the lgtm plugin is defined as a list of these structures.
 type Lgtm struct {
@@ -1121,6 +1227,24 @@ func validateBlunderbuss(b *Blunderbuss) error {
 	if b.ReviewerCount != nil && *b.ReviewerCount < 1 {
 		return fmt.Errorf("invalid request_count: %v (needs to be positive)", *b.ReviewerCount)
 	}
+	for login, hours := range b.WorkingHours {
+		if _, err := time.LoadLocation(hours.TimeZone); err != nil {
+			return fmt.Errorf("invalid timezone for %s in working_hours: %w", login, err)
+		}
+		if hours.Start < 0 || hours.Start > 23 || hours.End < 0 || hours.End > 24 || hours.Start == hours.End {
+			return fmt.Errorf("invalid working_hours for %s: %d to %d (needs to be two different hours of the day)", login, hours.Start, hours.End)
+		}
+	}
+	if b.AssignmentCounts != nil {
+		if b.AssignmentCounts.ConfigMap == "" {
+			return errors.New("assignment_counts needs a configmap")
+		}
+		if b.AssignmentCounts.Period != "" {
+			if period, err := time.ParseDuration(b.AssignmentCounts.Period); err != nil || period <= 0 {
+				return fmt.Errorf("invalid assignment_counts period %q (needs to be a positive duration)", b.AssignmentCounts.Period)
+			}
+		}
+	}
 	return nil
 }
 
@@ -1284,9 +1408,63 @@ func compileRegexpsAndDurations(pc *Configuration) error {
 		}
 		rs[i].GracePeriodDuration = dur
 	}
+
+	if err := pc.PluginLimits.Default.parseTimeout(); err != nil {
+		return fmt.Errorf("plugin_limits.default: %w", err)
+	}
+	for plugin, limit := range pc.PluginLimits.Plugins {
+		if err := limit.parseTimeout(); err != nil {
+			return fmt.Errorf("plugin_limits.plugins.%s: %w", plugin, err)
+		}
+		pc.PluginLimits.Plugins[plugin] = limit
+	}
+	return nil
+}
+
+func (l *PluginLimit) parseTimeout() error {
+	if l.Timeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(l.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to parse timeout %q: %w", l.Timeout, err)
+	}
+	l.TimeoutDuration = timeout
 	return nil
 }
 
+func validatePluginLimits(limits PluginLimits) error {
+	validate := func(name string, l PluginLimit) error {
+		switch {
+		case l.TimeoutDuration < 0:
+			return fmt.Errorf("%s: timeout must not be negative", name)
+		case l.MaxConcurrency < 0:
+			return fmt.Errorf("%s: max_concurrency must not be negative", name)
+		case l.GitHubHourlyTokens < 0 || l.GitHubBurst < 0:
+			return fmt.Errorf("%s: github_hourly_tokens and github_burst must not be negative", name)
+		case l.GitHubBurst > l.GitHubHourlyTokens && l.GitHubHourlyTokens > 0:
+			return fmt.Errorf("%s: github_burst must not exceed github_hourly_tokens", name)
+		}
+		return nil
+	}
+	var errs []error
+	if err := validate("plugin_limits.default", limits.Default); err != nil {
+		errs = append(errs, err)
+	}
+	for plugin, limit := range limits.Plugins {
+		if err := validate("plugin_limits.plugins."+plugin, limit); err != nil {
+			errs = append(errs, err)
+		}
+		if limit.GitHubBurst > 0 && limits.For(plugin).GitHubHourlyTokens == 0 {
+			errs = append(errs, fmt.Errorf("plugin_limits.plugins.%s: github_burst requires github_hourly_tokens", plugin))
+		}
+	}
+	if limits.Default.GitHubBurst > 0 && limits.Default.GitHubHourlyTokens == 0 {
+		errs = append(errs, errors.New("plugin_limits.default: github_burst requires github_hourly_tokens"))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 func (c *Configuration) Validate() error {
 	if len(c.Plugins) == 0 {
 		logrus.Warn("no plugins specified-- check syntax?")
@@ -1323,6 +1501,9 @@ func (c *Configuration) Validate() error {
 	if err := validateTrigger(c.Triggers); err != nil {
 		return err
 	}
+	if err := validatePluginLimits(c.PluginLimits); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -2299,3 +2299,95 @@ func TestMergeFrom(t *testing.T) {
 		}
 	}
 }
+
+func TestPluginLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        string
+		expected      map[string]PluginLimit
+		errorExpected bool
+	}{
+		{
+			name: "plugins inherit unset limits from the default",
+			config: `
+plugin_limits:
+  default:
+    timeout: 1m
+    github_hourly_tokens: 600
+  plugins:
+    jira:
+      timeout: 5m
+      max_concurrency: 2
+    cat:
+      github_hourly_tokens: 60
+      github_burst: 10
+`,
+			expected: map[string]PluginLimit{
+				"jira":  {Timeout: "5m", TimeoutDuration: 5 * time.Minute, MaxConcurrency: 2, GitHubHourlyTokens: 600, GitHubBurst: 600},
+				"cat":   {Timeout: "1m", TimeoutDuration: time.Minute, GitHubHourlyTokens: 60, GitHubBurst: 10},
+				"other": {Timeout: "1m", TimeoutDuration: time.Minute, GitHubHourlyTokens: 600, GitHubBurst: 600},
+			},
+		},
+		{
+			name:     "no limits",
+			config:   `plugins: {}`,
+			expected: map[string]PluginLimit{"other": {}},
+		},
+		{
+			name: "invalid timeout",
+			config: `
+plugin_limits:
+  plugins:
+    jira:
+      timeout: soon
+`,
+			errorExpected: true,
+		},
+		{
+			name: "burst without budget",
+			config: `
+plugin_limits:
+  default:
+    github_burst: 10
+`,
+			errorExpected: true,
+		},
+		{
+			name: "burst over budget",
+			config: `
+plugin_limits:
+  plugins:
+    cat:
+      github_hourly_tokens: 10
+      github_burst: 100
+`,
+			errorExpected: true,
+		},
+		{
+			name: "negative concurrency",
+			config: `
+plugin_limits:
+  default:
+    max_concurrency: -1
+`,
+			errorExpected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Configuration
+			if err := yaml.Unmarshal([]byte(tc.config), &c); err != nil {
+				t.Fatalf("failed to unmarshal config: %v", err)
+			}
+			err := c.Validate()
+			if (err != nil) != tc.errorExpected {
+				t.Fatalf("expected error %t, got %v", tc.errorExpected, err)
+			}
+			for plugin, expected := range tc.expected {
+				if diff := cmp.Diff(expected, c.PluginLimits.For(plugin)); diff != "" {
+					t.Errorf("unexpected limits for %s (-want +got):\n%s", plugin, diff)
+				}
+			}
+		})
+	}
+}
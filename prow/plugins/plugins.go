@@ -62,6 +62,9 @@ var (
 	reviewEventHandlers        = map[string]ReviewEventHandler{}
 	reviewCommentEventHandlers = map[string]ReviewCommentEventHandler{}
 	statusEventHandlers        = map[string]StatusEventHandler{}
+	checkRunEventHandlers      = map[string]CheckRunEventHandler{}
+	checkSuiteEventHandlers    = map[string]CheckSuiteEventHandler{}
+	workflowRunEventHandlers   = map[string]WorkflowRunEventHandler{}
 	// CommentMap is used by many plugins for printing help messages defined in
 	// config.go.
 	CommentMap, _ = genyaml.NewCommentMap(nil)
@@ -131,6 +134,33 @@ func RegisterStatusEventHandler(name string, fn StatusEventHandler, help HelpPro
 	statusEventHandlers[name] = fn
 }
 
+// CheckRunEventHandler defines the function contract for a github.CheckRunEvent handler.
+type CheckRunEventHandler func(Agent, github.CheckRunEvent) error
+
+// RegisterCheckRunEventHandler registers a plugin's github.CheckRunEvent handler.
+func RegisterCheckRunEventHandler(name string, fn CheckRunEventHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	checkRunEventHandlers[name] = fn
+}
+
+// CheckSuiteEventHandler defines the function contract for a github.CheckSuiteEvent handler.
+type CheckSuiteEventHandler func(Agent, github.CheckSuiteEvent) error
+
+// RegisterCheckSuiteEventHandler registers a plugin's github.CheckSuiteEvent handler.
+func RegisterCheckSuiteEventHandler(name string, fn CheckSuiteEventHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	checkSuiteEventHandlers[name] = fn
+}
+
+// WorkflowRunEventHandler defines the function contract for a github.WorkflowRunEvent handler.
+type WorkflowRunEventHandler func(Agent, github.WorkflowRunEvent) error
+
+// RegisterWorkflowRunEventHandler registers a plugin's github.WorkflowRunEvent handler.
+func RegisterWorkflowRunEventHandler(name string, fn WorkflowRunEventHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	workflowRunEventHandlers[name] = fn
+}
+
 // PushEventHandler defines the function contract for a github.PushEvent handler.
 type PushEventHandler func(Agent, github.PushEvent) error
 
@@ -202,23 +232,71 @@ type Agent struct {
 
 // NewAgent bootstraps a new config.Agent struct from the passed dependencies.
 func NewAgent(configAgent *config.Agent, pluginConfigAgent *ConfigAgent, clientAgent *ClientAgent, githubOrg string, metrics *Metrics, logger *logrus.Entry, plugin string) Agent {
+	return NewAgentWithRequestGates(configAgent, pluginConfigAgent, clientAgent, githubOrg, metrics, logger, plugin, RequestGates{})
+}
+
+// RequestGates are called before the requests of the clients of an agent, the
+// requests fail with the error of a gate if it returns one. Nil gates let all
+// requests pass.
+type RequestGates struct {
+	// All is called before the GitHub, Jira and Bugzilla requests, including
+	// the ones of the OWNERS client, and before the git client clones a repo.
+	All func() error
+	// GitHub is called before GitHub requests in addition to All.
+	GitHub func() error
+}
+
+// github combines the gates that apply to GitHub requests.
+func (g RequestGates) github() func() error {
+	switch {
+	case g.All == nil:
+		return g.GitHub
+	case g.GitHub == nil:
+		return g.All
+	}
+	return func() error {
+		if err := g.All(); err != nil {
+			return err
+		}
+		return g.GitHub()
+	}
+}
+
+// NewAgentWithRequestGates bootstraps a new config.Agent struct like NewAgent,
+// but the requests of the clients of the plugin fail with the errors of the gates.
+func NewAgentWithRequestGates(configAgent *config.Agent, pluginConfigAgent *ConfigAgent, clientAgent *ClientAgent, githubOrg string, metrics *Metrics, logger *logrus.Entry, plugin string, gates RequestGates) Agent {
 	logger = logger.WithField("plugin", plugin)
 	prowConfig := configAgent.Config()
 	pluginConfig := pluginConfigAgent.Config()
-	gitHubClient := &githubV4OrgAddingWrapper{org: githubOrg, Client: clientAgent.GitHubClient.WithFields(logger.Data).ForPlugin(plugin)}
+	pluginGitHubClient := clientAgent.GitHubClient.WithFields(logger.Data).ForPlugin(plugin)
+	if gate := gates.github(); gate != nil {
+		pluginGitHubClient = pluginGitHubClient.WithRequestGate(gate)
+	}
+	gitHubClient := &githubV4OrgAddingWrapper{org: githubOrg, Client: pluginGitHubClient}
+	bugzillaClient := clientAgent.BugzillaClient.WithFields(logger.Data).ForPlugin(plugin)
+	gitClient := clientAgent.GitClient
 	jiraClient := clientAgent.JiraClient
 	if jiraClient != nil {
 		jiraClient = clientAgent.JiraClient.WithFields(logger.Data).ForPlugin(plugin)
 	}
+	if gates.All != nil {
+		bugzillaClient = bugzillaClient.WithRequestGate(gates.All)
+		if gitClient != nil {
+			gitClient = &gatedGitClientFactory{ClientFactory: gitClient, gate: gates.All}
+		}
+		if jiraClient != nil {
+			jiraClient = jiraClient.WithRequestGate(gates.All)
+		}
+	}
 	return Agent{
 		GitHubClient:              gitHubClient,
 		KubernetesClient:          clientAgent.KubernetesClient,
 		BuildClusterCoreV1Clients: clientAgent.BuildClusterCoreV1Clients,
 		ProwJobClient:             clientAgent.ProwJobClient,
-		GitClient:                 clientAgent.GitClient,
+		GitClient:                 gitClient,
 		SlackClient:               clientAgent.SlackClient,
 		OwnersClient:              clientAgent.OwnersClient.WithFields(logger.Data).WithGitHubClient(gitHubClient).ForPlugin(plugin),
-		BugzillaClient:            clientAgent.BugzillaClient.WithFields(logger.Data).ForPlugin(plugin),
+		BugzillaClient:            bugzillaClient,
 		JiraClient:                jiraClient,
 		Metrics:                   metrics,
 		Config:                    prowConfig,
@@ -500,6 +578,51 @@ func (pa *ConfigAgent) StatusEventHandlers(owner, repo string) map[string]Status
 	return hs
 }
 
+// CheckRunEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *ConfigAgent) CheckRunEventHandlers(owner, repo string) map[string]CheckRunEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]CheckRunEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := checkRunEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
+// CheckSuiteEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *ConfigAgent) CheckSuiteEventHandlers(owner, repo string) map[string]CheckSuiteEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]CheckSuiteEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := checkSuiteEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
+// WorkflowRunEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *ConfigAgent) WorkflowRunEventHandlers(owner, repo string) map[string]WorkflowRunEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]WorkflowRunEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := workflowRunEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
 // PushEventHandlers returns a map of plugin names to handlers for the repo.
 func (pa *ConfigAgent) PushEventHandlers(owner, repo string) map[string]PushEventHandler {
 	pa.mut.Lock()
@@ -552,6 +675,15 @@ func EventsForPlugin(name string) []string {
 	if _, ok := statusEventHandlers[name]; ok {
 		events = append(events, "status")
 	}
+	if _, ok := checkRunEventHandlers[name]; ok {
+		events = append(events, "check_run")
+	}
+	if _, ok := checkSuiteEventHandlers[name]; ok {
+		events = append(events, "check_suite")
+	}
+	if _, ok := workflowRunEventHandlers[name]; ok {
+		events = append(events, "workflow_run")
+	}
 	if _, ok := genericCommentHandlers[name]; ok {
 		events = append(events, "GenericCommentEvent (any event for user text)")
 	}
@@ -581,6 +713,54 @@ func NewMetrics() *Metrics {
 	}
 }
 
+// HandlerStats summarizes how the event handlers of a plugin performed since
+// hook started. Hook serves them for deck to display.
+type HandlerStats struct {
+	Plugin string `json:"plugin"`
+	// Handled is the number of events the plugin handled, including the
+	// ones it failed or timed out handling.
+	Handled int `json:"handled"`
+	// Errors is the number of events the plugin failed handling, including
+	// the ones it timed out handling.
+	Errors   int `json:"errors"`
+	Timeouts int `json:"timeouts"`
+	// GitHubBudgetExceeded is the number of GitHub API requests that failed
+	// because the plugin used up its budget.
+	GitHubBudgetExceeded int `json:"github_budget_exceeded"`
+	// Running is the number of events the plugin is handling, Waiting the
+	// number of events that wait for it to have a free handler.
+	Running int `json:"running"`
+	Waiting int `json:"waiting"`
+	// Runaway is the number of running handlers that timed out. They do not
+	// count against the concurrency cap of the plugin anymore.
+	Runaway int `json:"runaway"`
+	// Latency percentiles in seconds over the recently handled events.
+	LatencyP50 float64 `json:"latency_p50"`
+	LatencyP90 float64 `json:"latency_p90"`
+	LatencyP99 float64 `json:"latency_p99"`
+}
+
+// gatedGitClientFactory fails creating git clients with the error of its gate.
+// Operations on clients that were already created are not gated.
+type gatedGitClientFactory struct {
+	git.ClientFactory
+	gate func() error
+}
+
+func (f *gatedGitClientFactory) ClientFor(org, repo string) (git.RepoClient, error) {
+	if err := f.gate(); err != nil {
+		return nil, err
+	}
+	return f.ClientFactory.ClientFor(org, repo)
+}
+
+func (f *gatedGitClientFactory) ClientFromDir(org, repo, dir string) (git.RepoClient, error) {
+	if err := f.gate(); err != nil {
+		return nil, err
+	}
+	return f.ClientFactory.ClientFromDir(org, repo, dir)
+}
+
 type githubV4OrgAddingWrapper struct {
 	org string
 	github.Client
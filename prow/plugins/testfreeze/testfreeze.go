@@ -38,6 +38,13 @@ const (
 	templateString              = `Please note that we're already in [Test Freeze](https://github.com/kubernetes/sig-release/blob/master/releases/release_phases.md#test-freeze) for the ` + "`{{ .Branch }}`" + ` branch. This means every merged PR will be automatically fast-forwarded via the periodic [ci-fast-forward](https://testgrid.k8s.io/sig-release-releng-blocking#git-repo-kubernetes-fast-forward) job to the release branch of the upcoming {{ .Tag }} release.
 
 Fast forwards are scheduled to happen every 6 hours, whereas the most recent run was: {{ .LastFastForward }}.
+`
+	freezeTemplateString = `Please note that merging into the ` + "`{{ .Branch }}`" + ` branch is currently frozen:
+{{ range .Freezes }}
+- **{{ .Name }}**{{ if .End }} until {{ .End.UTC.Format "Mon Jan 2 15:04 MST 2006" }}{{ end }}.{{ with .ExemptionLabels }} Pull requests with one of the labels {{ range $i, $label := . }}{{ if $i }}, {{ end }}` + "`{{ $label }}`" + `{{ end }} can be merged during the freeze.{{ end }}{{ with .Message }} {{ . }}{{ end }}
+{{- end }}
+
+Tide will merge this pull request once the freeze is over.
 `
 )
 
@@ -48,7 +55,7 @@ func init() {
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: fmt.Sprintf(
-			"The %s plugin adds additional documentation about cherry-picks during the Test Freeze period.",
+			"The %s plugin comments on pull requests opened against branches frozen by the freezes configured for Tide, and adds additional documentation about cherry-picks during the Test Freeze period of kubernetes/kubernetes.",
 			PluginName,
 		),
 	}
@@ -62,14 +69,16 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 func handlePullRequestEvent(p plugins.Agent, e github.PullRequestEvent) error {
 	h := newHandler()
 	log := p.Logger
+	org, repo, branch := e.Repo.Owner.Login, e.Repo.Name, e.PullRequest.Base.Ref
 	if err := h.handle(
 		log,
 		p.GitHubClient,
 		e.Action,
 		e.Number,
-		e.Repo.Owner.Login,
-		e.Repo.Name,
-		e.PullRequest.Base.Ref,
+		org,
+		repo,
+		branch,
+		p.Config.Tide.ActiveFreezes(org, repo, branch, time.Now()),
 	); err != nil {
 		log.WithError(err).Error("skipping")
 	}
@@ -114,6 +123,7 @@ func (h *handler) handle(
 	action github.PullRequestEventAction,
 	number int,
 	org, repo, branch string,
+	freezes []config.TideFreeze,
 ) error {
 	funcStart := time.Now()
 	defer func() {
@@ -127,6 +137,10 @@ func (h *handler) handle(
 		return nil
 	}
 
+	if len(freezes) > 0 {
+		return h.commentOnFreezes(client, number, org, repo, branch, freezes)
+	}
+
 	if org != defaultKubernetesRepoAndOrg ||
 		repo != defaultKubernetesRepoAndOrg ||
 		branch != defaultKubernetesBranch {
@@ -161,3 +175,31 @@ func (h *handler) handle(
 
 	return nil
 }
+
+// commentOnFreezes explains the active freezes of the branch
+func (h *handler) commentOnFreezes(
+	client plugins.PluginGitHubClient,
+	number int,
+	org, repo, branch string,
+	freezes []config.TideFreeze,
+) error {
+	comment := &strings.Builder{}
+	tpl, err := template.New(PluginName).Parse(freezeTemplateString)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+	data := struct {
+		Branch  string
+		Freezes []config.TideFreeze
+	}{Branch: branch, Freezes: freezes}
+	if err := tpl.Execute(comment, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	if err := h.verifier.CreateComment(
+		client, org, repo, number, comment.String(),
+	); err != nil {
+		return fmt.Errorf("create comment on %s/%s#%d: %q: %w", org, repo, number, comment, err)
+	}
+	return nil
+}
@@ -19,10 +19,12 @@ package testfreeze
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins/testfreeze/checker"
 	"k8s.io/test-infra/prow/plugins/testfreeze/testfreezefakes"
@@ -33,10 +35,13 @@ var errTest = errors.New("")
 func TestHandle(t *testing.T) {
 	t.Parallel()
 
+	end := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
 	for _, tc := range []struct {
 		name              string
 		action            github.PullRequestEventAction
 		org, repo, branch string
+		freezes           []config.TideFreeze
 		prepare           func(*testfreezefakes.FakeVerifier)
 		assert            func(*testfreezefakes.FakeVerifier, error)
 	}{
@@ -125,6 +130,47 @@ func TestHandle(t *testing.T) {
 				assert.Nil(t, err)
 			},
 		},
+		{
+			name:   "success configured freeze",
+			action: github.PullRequestActionOpened,
+			org:    "org",
+			repo:   "repo",
+			branch: "release-1.0",
+			freezes: []config.TideFreeze{{
+				Name:            "release-1.0 code freeze",
+				End:             &end,
+				ExemptionLabels: []string{"critical-fix", "approved-for-release"},
+				Message:         "Contact the release team.",
+			}},
+			prepare: func(mock *testfreezefakes.FakeVerifier) {},
+			assert: func(mock *testfreezefakes.FakeVerifier, err error) {
+				assert.Nil(t, err)
+				assert.Zero(t, mock.CheckInTestFreezeCallCount())
+				assert.Equal(t, 1, mock.CreateCommentCallCount())
+				_, org, repo, _, comment := mock.CreateCommentArgsForCall(0)
+				assert.Equal(t, "org", org)
+				assert.Equal(t, "repo", repo)
+				assert.Contains(t, comment, "merging into the `release-1.0` branch is currently frozen")
+				assert.Contains(t, comment, "- **release-1.0 code freeze** until Wed Jun 1 12:00 UTC 2022.")
+				assert.Contains(t, comment, "labels `critical-fix`, `approved-for-release` can be merged")
+				assert.Contains(t, comment, "Contact the release team.")
+			},
+		},
+		{
+			name:   "success configured freeze filtered action",
+			action: github.PullRequestActionClosed,
+			org:    "org",
+			repo:   "repo",
+			branch: "release-1.0",
+			freezes: []config.TideFreeze{{
+				Name: "release-1.0 code freeze",
+			}},
+			prepare: func(mock *testfreezefakes.FakeVerifier) {},
+			assert: func(mock *testfreezefakes.FakeVerifier, err error) {
+				assert.Nil(t, err)
+				assert.Zero(t, mock.CreateCommentCallCount())
+			},
+		},
 		{
 			name:   "error CheckInTestFreeze",
 			action: github.PullRequestActionOpened,
@@ -163,7 +209,7 @@ func TestHandle(t *testing.T) {
 			sut.verifier = mock
 
 			entry := logrus.NewEntry(logrus.StandardLogger())
-			err := sut.handle(entry, nil, tc.action, 0, tc.org, tc.repo, tc.branch)
+			err := sut.handle(entry, nil, tc.action, 0, tc.org, tc.repo, tc.branch, tc.freezes)
 			tc.assert(mock, err)
 		})
 	}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/report"
+	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// rerunClient is used to check if a user is allowed to rerun a job.
+type rerunClient interface {
+	github.RerunClient
+	BotUserChecker() (func(candidate string) bool, error)
+}
+
+// CanRerunJob determines whether the given user can rerun the job. The user can
+// rerun it if the rerun auth config or the one of the job authorizes them or, for
+// presubmits, if they would be allowed to use /test on the pull request.
+func CanRerunJob(user string, pj prowapi.ProwJob, cfg *prowapi.RerunAuthConfig, cli rerunClient, pluginsCfg func() *plugins.Configuration, log *logrus.Entry) (bool, error) {
+	var org string
+	if pj.Spec.Refs != nil {
+		org = pj.Spec.Refs.Org
+	} else if len(pj.Spec.ExtraRefs) > 0 {
+		org = pj.Spec.ExtraRefs[0].Org
+	}
+
+	// Check config-level rerun auth config.
+	if auth, err := cfg.IsAuthorized(org, user, cli); err != nil {
+		return false, err
+	} else if auth {
+		return true, err
+	}
+
+	// Check job-level rerun auth config.
+	if auth, err := pj.Spec.RerunAuthConfig.IsAuthorized(org, user, cli); err != nil {
+		return false, err
+	} else if auth {
+		return true, nil
+	}
+
+	if cli == nil {
+		log.Warning("No GitHub token was provided, so we cannot retrieve GitHub teams")
+		return false, nil
+	}
+
+	// If the job is a presubmit and has an associated PR, and a plugin config is provided,
+	// do the same checks as for /test
+	if pj.Spec.Type == prowapi.PresubmitJob && pj.Spec.Refs != nil && len(pj.Spec.Refs.Pulls) > 0 {
+		if pluginsCfg == nil {
+			log.Info("No plugin config was provided so we cannot check if the user would be allowed to use /test.")
+		} else {
+			pcfg := pluginsCfg()
+			pull := pj.Spec.Refs.Pulls[0]
+			org := pj.Spec.Refs.Org
+			repo := pj.Spec.Refs.Repo
+			_, allowed, err := TrustedPullRequest(cli, pcfg.TriggerFor(org, repo), user, org, repo, pull.Number, nil)
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func handleCheckRun(pc plugins.Agent, cre github.CheckRunEvent) error {
+	pluginsCfg := func() *plugins.Configuration { return pc.PluginConfig }
+	return handleCRE(getClient(pc), pluginsCfg, cre)
+}
+
+// handleCRE reruns the job of a check run when its rerun action is requested,
+// applying the same permission checks as a rerun from deck.
+func handleCRE(c Client, pluginsCfg func() *plugins.Configuration, cre github.CheckRunEvent) error {
+	if cre.Action != github.CheckRunActionRequestedAction || cre.RequestedAction == nil || cre.RequestedAction.Identifier != report.CheckRunRerunAction {
+		return nil
+	}
+	name := cre.CheckRun.ExternalID
+	if name == "" {
+		return nil
+	}
+	org, repo, user := cre.Repo.Owner.Login, cre.Repo.Name, cre.Sender.Login
+	log := c.Logger.WithFields(logrus.Fields{"prowjob": name, "user": user})
+
+	pj, err := c.ProwJobClient.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("The ProwJob of the check run does not exist anymore, not rerunning it.")
+			return nil
+		}
+		return fmt.Errorf("failed to get ProwJob %s: %w", name, err)
+	}
+	if pj.Spec.Refs == nil || pj.Spec.Refs.Org != org || pj.Spec.Refs.Repo != repo {
+		log.Warningf("The ProwJob of the check run does not belong to %s/%s, not rerunning it.", org, repo)
+		return nil
+	}
+
+	allowed, err := CanRerunJob(user, *pj, c.Config.Deck.GetRerunAuthConfig(&pj.Spec), c.GitHubClient, pluginsCfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s can rerun %s: %w", user, name, err)
+	}
+	if !allowed {
+		log.Info("User is not allowed to rerun the job.")
+		return nil
+	}
+
+	newPJ := pjutil.NewProwJob(pj.Spec, pj.Labels, pj.Annotations)
+	newPJ.Status.Description = fmt.Sprintf("%v successfully reran %v.", user, name)
+	log.WithFields(pjutil.ProwJobFields(&newPJ)).Info("Creating a new prowjob.")
+	return createWithRetry(context.TODO(), c.ProwJobClient, &newPJ)
+}
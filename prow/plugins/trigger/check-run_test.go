@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/github/report"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestCanRerunJob(t *testing.T) {
+	t.Parallel()
+	org := "org"
+	trustedUser := "trusted"
+	untrustedUser := "untrusted"
+
+	pcfg := &plugins.Configuration{
+		Triggers: []plugins.Trigger{{Repos: []string{org}}},
+	}
+	pcfgGetter := func() *plugins.Configuration { return pcfg }
+
+	ghc := fakegithub.NewFakeClient()
+	ghc.OrgMembers = map[string][]string{org: {trustedUser}}
+
+	pj := prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{
+			Refs: &prowapi.Refs{
+				Org:   org,
+				Repo:  "repo",
+				Pulls: []prowapi.Pull{{Author: trustedUser}},
+			},
+			Type: prowapi.PresubmitJob,
+		},
+	}
+	testCases := []struct {
+		name          string
+		user          string
+		expectAllowed bool
+	}{
+		{
+			name:          "Unauthorized user can not rerun",
+			user:          untrustedUser,
+			expectAllowed: false,
+		},
+		{
+			name:          "Authorized user can re-run",
+			user:          trustedUser,
+			expectAllowed: true,
+		},
+	}
+
+	log := logrus.NewEntry(logrus.StandardLogger())
+	for _, tc := range testCases {
+		result, err := CanRerunJob(tc.user, pj, nil, ghc, pcfgGetter, log)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if result != tc.expectAllowed {
+			t.Errorf("got result %t, expected %t", result, tc.expectAllowed)
+		}
+	}
+}
+
+func TestHandleCRE(t *testing.T) {
+	org, repo := "org", "repo"
+	trustedUser := "trusted"
+	untrustedUser := "untrusted"
+	deckUser := "deck-user"
+	jobUser := "job-user"
+
+	rerunEvent := func(user, externalID string) github.CheckRunEvent {
+		return github.CheckRunEvent{
+			Action:          github.CheckRunActionRequestedAction,
+			CheckRun:        github.CheckRun{ExternalID: externalID},
+			RequestedAction: &github.CheckRunRequestedAction{Identifier: report.CheckRunRerunAction},
+			Repo:            github.Repo{Owner: github.User{Login: org}, Name: repo},
+			Sender:          github.User{Login: user},
+		}
+	}
+	job := func(name, org, repo string) prowapi.ProwJob {
+		return prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prowjobs", Labels: map[string]string{"foo": "bar"}},
+			Spec: prowapi.ProwJobSpec{
+				Job:  "pull-test",
+				Type: prowapi.PresubmitJob,
+				Refs: &prowapi.Refs{
+					Org:   org,
+					Repo:  repo,
+					Pulls: []prowapi.Pull{{Number: 1, Author: trustedUser}},
+				},
+				RerunAuthConfig: &prowapi.RerunAuthConfig{GitHubUsers: []string{jobUser}},
+			},
+			Status: prowapi.ProwJobStatus{State: prowapi.FailureState},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		event         github.CheckRunEvent
+		expectedRerun string
+	}{
+		{
+			name: "other actions are ignored",
+			event: func() github.CheckRunEvent {
+				e := rerunEvent(trustedUser, "job")
+				e.Action = github.CheckRunActionRerequested
+				return e
+			}(),
+		},
+		{
+			name: "other requested actions are ignored",
+			event: func() github.CheckRunEvent {
+				e := rerunEvent(trustedUser, "job")
+				e.RequestedAction.Identifier = "fix"
+				return e
+			}(),
+		},
+		{
+			name:  "check runs without a ProwJob are ignored",
+			event: rerunEvent(trustedUser, "gone"),
+		},
+		{
+			name:  "ProwJobs of other repos are not rerun",
+			event: rerunEvent(trustedUser, "other-repo-job"),
+		},
+		{
+			name:  "untrusted user cannot rerun",
+			event: rerunEvent(untrustedUser, "job"),
+		},
+		{
+			name:          "user trusted on the pull request can rerun",
+			event:         rerunEvent(trustedUser, "job"),
+			expectedRerun: trustedUser,
+		},
+		{
+			name:          "user authorized by the deck rerun auth config can rerun",
+			event:         rerunEvent(deckUser, "job"),
+			expectedRerun: deckUser,
+		},
+		{
+			name:          "user authorized by the rerun auth config of the job can rerun",
+			event:         rerunEvent(jobUser, "job"),
+			expectedRerun: jobUser,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			existing := []prowapi.ProwJob{job("job", org, repo), job("other-repo-job", org, "other")}
+			fakeProwJobClient := fake.NewSimpleClientset(&existing[0], &existing[1])
+			ghc := fakegithub.NewFakeClient()
+			ghc.OrgMembers = map[string][]string{org: {trustedUser}}
+			c := Client{
+				GitHubClient:  ghc,
+				ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				Config: &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{
+					DefaultRerunAuthConfigs: []*config.DefaultRerunAuthConfigEntry{{
+						OrgRepo: org + "/" + repo,
+						Config:  &prowapi.RerunAuthConfig{GitHubUsers: []string{deckUser}},
+					}},
+				}}},
+				Logger: logrus.WithField("testcase", tc.name),
+			}
+			pluginsCfg := func() *plugins.Configuration {
+				return &plugins.Configuration{Triggers: []plugins.Trigger{{Repos: []string{org}}}}
+			}
+
+			if err := handleCRE(c, pluginsCfg, tc.event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			pjs, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list ProwJobs: %v", err)
+			}
+			var reruns []prowapi.ProwJob
+			for _, pj := range pjs.Items {
+				if pj.Name != "job" && pj.Name != "other-repo-job" {
+					reruns = append(reruns, pj)
+				}
+			}
+			if tc.expectedRerun == "" {
+				if len(reruns) != 0 {
+					t.Errorf("expected no rerun, got %d", len(reruns))
+				}
+				return
+			}
+			if len(reruns) != 1 {
+				t.Fatalf("expected one rerun, got %d", len(reruns))
+			}
+			rerun := reruns[0]
+			if diff := cmp.Diff(existing[0].Spec, rerun.Spec); diff != "" {
+				t.Errorf("rerun has an unexpected spec (-want, +got):\n%s", diff)
+			}
+			if rerun.Status.State != prowapi.TriggeredState {
+				t.Errorf("expected the rerun to be triggered, got %s", rerun.Status.State)
+			}
+			if expected := tc.expectedRerun + " successfully reran job."; rerun.Status.Description != expected {
+				t.Errorf("expected description %q, got %q", expected, rerun.Status.Description)
+			}
+		})
+	}
+}
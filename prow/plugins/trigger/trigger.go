@@ -75,6 +75,7 @@ func init() {
 	plugins.RegisterGenericCommentHandler(PluginName, handleGenericCommentEvent, helpProvider)
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 	plugins.RegisterPushEventHandler(PluginName, handlePush, helpProvider)
+	plugins.RegisterCheckRunEventHandler(PluginName, handleCheckRun, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -106,7 +107,8 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: `The trigger plugin starts tests in reaction to commands and pull request events. It is responsible for ensuring that test jobs are only run on trusted PRs. A PR is considered trusted if the author is a member of the 'trusted organization' for the repository or if such a member has left an '/ok-to-test' command on the PR.
 <br>Trigger starts jobs automatically when a new trusted PR is created or when an untrusted PR becomes trusted, but it can also be used to start jobs manually via the '/test' command.
-<br>The '/retest' command can be used to rerun jobs that have reported failure.`,
+<br>The '/retest' command can be used to rerun jobs that have reported failure.
+<br>Jobs reported as check runs can be rerun with the 'Run this job again' button of their check run by users who are allowed to rerun them from Deck.`,
 		Config:  configInfo,
 		Snippet: yamlSnippet,
 	}
@@ -159,6 +161,8 @@ type githubClient interface {
 	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
 	ListCommitPullRequests(org, repo, SHA string) ([]github.PullRequest, error)
+	TeamHasMember(org string, teamID int, memberLogin string) (bool, error)
+	GetTeamBySlug(slug string, org string) (*github.Team, error)
 }
 
 type trustedPullRequestClient interface {
@@ -168,6 +172,7 @@ type trustedPullRequestClient interface {
 
 type prowJobClient interface {
 	Create(context.Context, *prowapi.ProwJob, metav1.CreateOptions) (*prowapi.ProwJob, error)
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*prowapi.ProwJob, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*prowapi.ProwJobList, error)
 	Update(context.Context, *prowapi.ProwJob, metav1.UpdateOptions) (*prowapi.ProwJob, error)
 }
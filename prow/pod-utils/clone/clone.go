@@ -20,8 +20,10 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
+	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -41,7 +43,9 @@ type runnable interface {
 
 // Run clones the refs under the prescribed directory and optionally
 // configures the git username and email in the repository as well.
-func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) Record {
+// If cacheDir is set, objects of the repository in the git cache below
+// it are used instead of fetching them.
+func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath, cacheDir string, env []string, userGenerator github.UserGenerator, tokenGenerator github.TokenGenerator) Record {
 	record := Record{Refs: refs}
 
 	var (
@@ -99,6 +103,9 @@ func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, e
 	}
 
 	g := gitCtxForRefs(refs, dir, env, user, token)
+	if cacheDir != "" {
+		g.cacheDir = CachePathForRefs(cacheDir, refs)
+	}
 	if err := runCommands(g.commandsForBaseRef(refs, gitUserName, gitUserEmail, cookiePath)); err != nil {
 		return record
 	}
@@ -132,17 +139,27 @@ func censorToken(msg, token string) string {
 // PathForRefs determines the full path to where
 // refs should be cloned
 func PathForRefs(baseDir string, refs prowapi.Refs) string {
-	var clonePath string
+	clonePath := repoPath(refs)
 	if refs.PathAlias != "" {
 		clonePath = refs.PathAlias
-	} else if refs.RepoLink != "" {
+	}
+	return path.Join(baseDir, "src", clonePath)
+}
+
+// CachePathForRefs determines the full path to the
+// repository of refs in the git cache
+func CachePathForRefs(cacheDir string, refs prowapi.Refs) string {
+	return path.Join(cacheDir, repoPath(refs))
+}
+
+// repoPath returns the path of the repository like github.com/org/repo.
+func repoPath(refs prowapi.Refs) string {
+	if refs.RepoLink != "" {
 		// Drop the protocol from the RepoLink
 		parts := strings.Split(refs.RepoLink, "://")
-		clonePath = parts[len(parts)-1]
-	} else {
-		clonePath = fmt.Sprintf("github.com/%s/%s", refs.Org, refs.Repo)
+		return parts[len(parts)-1]
 	}
-	return path.Join(baseDir, "src", clonePath)
+	return fmt.Sprintf("github.com/%s/%s", refs.Org, refs.Repo)
 }
 
 // gitCtx collects a few common values needed for all git commands.
@@ -150,6 +167,10 @@ type gitCtx struct {
 	cloneDir      string
 	env           []string
 	repositoryURI string
+	// remoteURI is the repositoryURI without credentials.
+	remoteURI string
+	// cacheDir is the repository in the git cache, if any.
+	cacheDir string
 }
 
 // gitCtxForRefs creates a gitCtx based on the provide refs and baseDir.
@@ -169,6 +190,7 @@ func gitCtxForRefs(refs prowapi.Refs, baseDir string, env []string, user, token
 	if refs.CloneURI != "" {
 		g.repositoryURI = refs.CloneURI
 	}
+	g.remoteURI = g.repositoryURI
 
 	if token != "" {
 		u, _ := url.Parse(g.repositoryURI)
@@ -199,6 +221,15 @@ var (
 	}
 )
 
+// remote returns the remote to fetch the refs from. Partial clones need a
+// named remote to fetch missing objects from later on.
+func (g *gitCtx) remote(refs prowapi.Refs) string {
+	if refs.PartialClone {
+		return "origin"
+	}
+	return g.repositoryURI
+}
+
 func (g *gitCtx) gitFetch(fetchArgs ...string) retryCommand {
 	args := []string{"fetch"}
 	args = append(args, fetchArgs...)
@@ -226,15 +257,36 @@ func (g *gitCtx) commandsForBaseRef(refs prowapi.Refs, gitUserName, gitUserEmail
 	if cookiePath != "" && refs.SkipSubmodules {
 		commands = append(commands, g.gitCommand("config", "http.cookiefile", cookiePath))
 	}
+	if g.cacheDir != "" {
+		commands = append(commands, alternatesCommand{cloneDir: g.cloneDir, cacheDir: g.cacheDir})
+	}
+	if len(refs.SparseCheckout) > 0 {
+		// Write the patterns instead of using git sparse-checkout, which
+		// defaults to cone mode with recent versions of git.
+		commands = append(commands, g.gitCommand("config", "core.sparseCheckout", "true"))
+		commands = append(commands, writeFileCommand{
+			path:    filepath.Join(g.cloneDir, ".git", "info", "sparse-checkout"),
+			content: strings.Join(refs.SparseCheckout, "\n") + "\n",
+		})
+	}
+	if refs.PartialClone {
+		commands = append(commands, g.gitCommand("config", "remote.origin.url", g.repositoryURI))
+	}
 
 	var depthArgs []string
 	if d := refs.CloneDepth; d > 0 {
 		depthArgs = append(depthArgs, "--depth", strconv.Itoa(d))
 	}
+	var filterArgs []string
+	if refs.PartialClone {
+		filterArgs = append(filterArgs, "--filter=blob:none")
+	}
 
+	remote := g.remote(refs)
 	if !refs.SkipFetchHead {
-		fetchArgs := []string{g.repositoryURI, "--tags", "--prune"}
+		fetchArgs := []string{remote, "--tags", "--prune"}
 		fetchArgs = append(fetchArgs, depthArgs...)
+		fetchArgs = append(fetchArgs, filterArgs...)
 		commands = append(commands, g.gitFetch(fetchArgs...))
 	}
 
@@ -250,7 +302,8 @@ func (g *gitCtx) commandsForBaseRef(refs prowapi.Refs, gitUserName, gitUserEmail
 
 	{
 		fetchArgs := append([]string{}, depthArgs...)
-		fetchArgs = append(fetchArgs, g.repositoryURI, fetchRef)
+		fetchArgs = append(fetchArgs, filterArgs...)
+		fetchArgs = append(fetchArgs, remote, fetchRef)
 		commands = append(commands, g.gitFetch(fetchArgs...))
 	}
 
@@ -323,7 +376,7 @@ func (g *gitCtx) commandsForPullRefs(refs prowapi.Refs, fakeTimestamp int) []run
 		if prRef.Ref != "" {
 			ref = prRef.Ref
 		}
-		commands = append(commands, g.gitFetch(g.repositoryURI, ref))
+		commands = append(commands, g.gitFetch(g.remote(refs), ref))
 		var prCheckout string
 		if prRef.SHA != "" {
 			prCheckout = prRef.SHA
@@ -341,6 +394,12 @@ func (g *gitCtx) commandsForPullRefs(refs prowapi.Refs, fakeTimestamp int) []run
 		commands = append(commands, g.gitCommand("submodule", "update", "--init", "--recursive"))
 	}
 
+	// keep credentials out of the clone, missing objects of partial
+	// clones are fetched anonymously from now on
+	if refs.PartialClone && g.remoteURI != g.repositoryURI {
+		commands = append(commands, g.gitCommand("config", "remote.origin.url", g.remoteURI))
+	}
+
 	return commands
 }
 
@@ -389,3 +448,38 @@ func (c cloneCommand) run() (string, string, error) {
 func (c cloneCommand) String() string {
 	return fmt.Sprintf("PWD=%s %s %s %s", c.dir, strings.Join(c.env, " "), c.command, strings.Join(c.args, " "))
 }
+
+// writeFileCommand writes content to a file below the clone.
+type writeFileCommand struct {
+	path    string
+	content string
+}
+
+func (c writeFileCommand) run() (string, string, error) {
+	command := fmt.Sprintf("golang: write %q", c.path)
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return command, "", err
+	}
+	return command, c.content, os.WriteFile(c.path, []byte(c.content), 0644)
+}
+
+// alternatesCommand configures the clone to use the objects of a repository
+// in the git cache, which may be bare or not. It does nothing if the cache
+// does not hold the repository.
+type alternatesCommand struct {
+	cloneDir string
+	cacheDir string
+}
+
+func (c alternatesCommand) run() (string, string, error) {
+	for _, objects := range []string{filepath.Join(c.cacheDir, "objects"), filepath.Join(c.cacheDir, ".git", "objects")} {
+		if info, err := os.Stat(objects); err != nil || !info.IsDir() {
+			continue
+		}
+		return writeFileCommand{
+			path:    filepath.Join(c.cloneDir, ".git", "objects", "info", "alternates"),
+			content: objects + "\n",
+		}.run()
+	}
+	return fmt.Sprintf("golang: find git cache %q", c.cacheDir), "no repository found in the git cache", nil
+}
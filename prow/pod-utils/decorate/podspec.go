@@ -45,21 +45,27 @@ import (
 )
 
 const (
-	logMountName            = "logs"
-	logMountPath            = "/logs"
-	artifactsEnv            = "ARTIFACTS"
-	artifactsPath           = logMountPath + "/artifacts"
-	codeMountName           = "code"
-	codeMountPath           = "/home/prow/go"
-	gopathEnv               = "GOPATH"
-	toolsMountName          = "tools"
-	toolsMountPath          = "/tools"
-	gcsCredentialsMountName = "gcs-credentials"
-	gcsCredentialsMountPath = "/secrets/gcs"
-	s3CredentialsMountName  = "s3-credentials"
-	s3CredentialsMountPath  = "/secrets/s3-storage"
-	outputMountName         = "output"
-	outputMountPath         = "/output"
+	logMountName              = "logs"
+	logMountPath              = "/logs"
+	artifactsEnv              = "ARTIFACTS"
+	artifactsPath             = logMountPath + "/artifacts"
+	codeMountName             = "code"
+	codeMountPath             = "/home/prow/go"
+	gopathEnv                 = "GOPATH"
+	toolsMountName            = "tools"
+	toolsMountPath            = "/tools"
+	gcsCredentialsMountName   = "gcs-credentials"
+	gcsCredentialsMountPath   = "/secrets/gcs"
+	s3CredentialsMountName    = "s3-credentials"
+	s3CredentialsMountPath    = "/secrets/s3-storage"
+	azureCredentialsMountName = "azure-credentials"
+	azureCredentialsMountPath = "/secrets/azure-storage"
+	fileStorageMountName      = "file-storage"
+	fileStorageMountPath      = "/file-storage"
+	gitCacheMountName         = "git-cache"
+	gitCacheMountPath         = "/git-cache"
+	outputMountName           = "output"
+	outputMountPath           = "/output"
 )
 
 // Labels returns a string slice with label consts from kube.
@@ -69,7 +75,7 @@ func Labels() []string {
 
 // VolumeMounts returns a string set with *MountName consts in it.
 func VolumeMounts(dc *prowapi.DecorationConfig) sets.String {
-	ret := sets.NewString(logMountName, codeMountName, toolsMountName, gcsCredentialsMountName, s3CredentialsMountName)
+	ret := sets.NewString(logMountName, codeMountName, toolsMountName, gcsCredentialsMountName, s3CredentialsMountName, azureCredentialsMountName, fileStorageMountName, gitCacheMountName)
 	if dc == nil {
 		return ret
 	}
@@ -401,6 +407,31 @@ func cookiefileVolume(secret string) (coreapi.Volume, coreapi.VolumeMount, strin
 	return vol, mount, path.Join(mount.MountPath, base)
 }
 
+// gitCacheVolume converts a git cache into the corresponding volume and mount.
+// The mount is read-only and needs to be added to every container that uses
+// the clones, as they refer to the objects in the cache.
+func gitCacheVolume(cache prowapi.GitCache) (coreapi.Volume, coreapi.VolumeMount) {
+	var source coreapi.VolumeSource
+	if cache.ClaimName != "" {
+		source.PersistentVolumeClaim = &coreapi.PersistentVolumeClaimVolumeSource{
+			ClaimName: cache.ClaimName,
+			ReadOnly:  true,
+		}
+	} else {
+		source.HostPath = &coreapi.HostPathVolumeSource{
+			Path: cache.HostPath,
+		}
+	}
+	return coreapi.Volume{
+			Name:         gitCacheMountName,
+			VolumeSource: source,
+		}, coreapi.VolumeMount{
+			Name:      gitCacheMountName,
+			MountPath: gitCacheMountPath,
+			ReadOnly:  true,
+		}
+}
+
 // CloneRefs constructs the container and volumes necessary to clone the refs requested by the ProwJob.
 //
 // The container checks out repositories specified by the ProwJob Refs to `codeMount`.
@@ -476,8 +507,17 @@ func CloneRefs(pj prowapi.ProwJob, codeMount, logMount coreapi.VolumeMount) (*co
 		cloneArgs = append(cloneArgs, "--cookiefile="+cookiefilePath)
 	}
 
+	var gitCacheDir string
+	if gc := pj.Spec.DecorationConfig.GitCache; gc != nil {
+		v, vm := gitCacheVolume(*gc)
+		cloneMounts = append(cloneMounts, vm)
+		cloneVolumes = append(cloneVolumes, v)
+		gitCacheDir = vm.MountPath
+	}
+
 	env, err := cloneEnv(clonerefs.Options{
 		CookiePath:              cookiefilePath,
+		GitCacheDir:             gitCacheDir,
 		GitRefs:                 refs,
 		GitUserEmail:            clonerefs.DefaultGitUserEmail,
 		GitUserName:             clonerefs.DefaultGitUserName,
@@ -530,6 +570,14 @@ func metadataFile(log coreapi.VolumeMount, prefix string) string {
 	return filepath.Join(ad, fmt.Sprintf("%s-metadata.json", prefix))
 }
 
+func phasesFile(log coreapi.VolumeMount, prefix string) string {
+	ad := artifactsDir(log)
+	if prefix == "" {
+		return filepath.Join(ad, "phases.json")
+	}
+	return filepath.Join(ad, fmt.Sprintf("%s-phases.json", prefix))
+}
+
 func artifactsDir(log coreapi.VolumeMount) string {
 	return filepath.Join(log.MountPath, "artifacts")
 }
@@ -550,6 +598,7 @@ func InjectEntrypoint(c *coreapi.Container, timeout, gracePeriod time.Duration,
 	// TODO(fejta): use flags
 	entrypointConfigEnv, err := entrypoint.Encode(entrypoint.Options{
 		ArtifactDir:    artifactsDir(log),
+		PhasesFile:     phasesFile(log, prefix),
 		GracePeriod:    gracePeriod,
 		Options:        wrapperOptions,
 		Timeout:        timeout,
@@ -586,6 +635,7 @@ func BlobStorageOptions(dc prowapi.DecorationConfig, localMode bool) ([]coreapi.
 		// TODO: pass the artifact dir here too once we figure that out
 		GCSConfiguration: dc.GCSConfiguration,
 		DryRun:           false,
+		ArtifactUploads:  dc.ArtifactUploads,
 	}
 	if localMode {
 		opt.LocalOutputDir = outputMountPath
@@ -625,6 +675,36 @@ func BlobStorageOptions(dc prowapi.DecorationConfig, localMode bool) ([]coreapi.
 		})
 		opt.StorageClientOptions.S3CredentialsFile = fmt.Sprintf("%s/service-account.json", s3CredentialsMountPath)
 	}
+	if dc.AzureCredentialsSecret != nil && *dc.AzureCredentialsSecret != "" {
+		volumes = append(volumes, coreapi.Volume{
+			Name: azureCredentialsMountName,
+			VolumeSource: coreapi.VolumeSource{
+				Secret: &coreapi.SecretVolumeSource{
+					SecretName: *dc.AzureCredentialsSecret,
+				},
+			},
+		})
+		mounts = append(mounts, coreapi.VolumeMount{
+			Name:      azureCredentialsMountName,
+			MountPath: azureCredentialsMountPath,
+		})
+		opt.StorageClientOptions.AzureCredentialsFile = fmt.Sprintf("%s/service-account.json", azureCredentialsMountPath)
+	}
+	if dc.FileStorageClaim != nil && *dc.FileStorageClaim != "" {
+		volumes = append(volumes, coreapi.Volume{
+			Name: fileStorageMountName,
+			VolumeSource: coreapi.VolumeSource{
+				PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{
+					ClaimName: *dc.FileStorageClaim,
+				},
+			},
+		})
+		mounts = append(mounts, coreapi.VolumeMount{
+			Name:      fileStorageMountName,
+			MountPath: fileStorageMountPath,
+		})
+		opt.StorageClientOptions.FileStorageRoot = fileStorageMountPath
+	}
 
 	return volumes, mounts, opt
 }
@@ -811,12 +891,22 @@ func decorate(spec *coreapi.PodSpec, pj *prowapi.ProwJob, rawEnv map[string]stri
 		for i, container := range spec.Containers {
 			spec.Containers[i].WorkingDir = DetermineWorkDir(codeMount.MountPath, refs)
 			spec.Containers[i].VolumeMounts = append(container.VolumeMounts, codeMount)
+			if gc := pj.Spec.DecorationConfig.GitCache; gc != nil && cloner != nil {
+				_, gitCacheMount := gitCacheVolume(*gc)
+				spec.Containers[i].VolumeMounts = append(spec.Containers[i].VolumeMounts, gitCacheMount)
+			}
 		}
 		spec.Volumes = append(spec.Volumes, append(cloneVolumes, codeVolume)...)
 	}
 
 	spec.Containers = append(spec.Containers, *sidecar)
 
+	if pj.Spec.DecorationConfig.ResourceUsage != nil {
+		// sidecar finds the cgroups of the test containers through their processes.
+		shareProcessNamespace := true
+		spec.ShareProcessNamespace = &shareProcessNamespace
+	}
+
 	if spec.TerminationGracePeriodSeconds == nil && pj.Spec.DecorationConfig.GracePeriod != nil {
 		// Unless the user's asked for something specific, we want to set the grace period on the Pod to
 		// a reasonable value, as the overall grace period for the Pod must encompass both the time taken
@@ -863,6 +953,21 @@ func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blo
 		censoringOptions.CensoringBufferSize = config.CensoringOptions.CensoringBufferSize
 		censoringOptions.IncludeDirectories = config.CensoringOptions.IncludeDirectories
 		censoringOptions.ExcludeDirectories = config.CensoringOptions.ExcludeDirectories
+		censoringOptions.Patterns = config.CensoringOptions.Patterns
+		censoringOptions.BuiltinPatterns = config.CensoringOptions.BuiltinPatterns != nil && *config.CensoringOptions.BuiltinPatterns
+		censoringOptions.HighEntropyStrings = config.CensoringOptions.HighEntropyStrings != nil && *config.CensoringOptions.HighEntropyStrings
+		if config.CensoringOptions.EntropyMinLength != nil {
+			censoringOptions.EntropyMinLength = *config.CensoringOptions.EntropyMinLength
+		}
+		entropyThreshold, err := config.CensoringOptions.GetEntropyThreshold()
+		if err != nil {
+			return nil, err
+		}
+		censoringOptions.EntropyThreshold = entropyThreshold
+	}
+	var resourceUsage *sidecar.ResourceUsageOptions
+	if config.ResourceUsage != nil {
+		resourceUsage = &sidecar.ResourceUsageOptions{Interval: config.ResourceUsage.Interval.Get()}
 	}
 	sidecarConfigEnv, err := sidecar.Encode(sidecar.Options{
 		GcsOptions:       &gcsOptions,
@@ -870,6 +975,7 @@ func Sidecar(config *prowapi.DecorationConfig, gcsOptions gcsupload.Options, blo
 		EntryError:       requirePassingEntries,
 		IgnoreInterrupts: ignoreInterrupts,
 		CensoringOptions: censoringOptions,
+		ResourceUsage:    resourceUsage,
 	})
 
 	if err != nil {
@@ -528,6 +528,50 @@ func TestCloneRefs(t *testing.T) {
 				tmpVolume,
 			},
 		},
+		{
+			name: "git cache is mounted read-only",
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					ExtraRefs: []prowapi.Refs{{}},
+					DecorationConfig: &prowapi.DecorationConfig{
+						UtilityImages: &prowapi.UtilityImages{},
+						GitCache:      &prowapi.GitCache{ClaimName: "git-mirrors"},
+					},
+				},
+			},
+			expected: &coreapi.Container{
+				Name: cloneRefsName,
+				Env: envOrDie(clonerefs.Options{
+					GitRefs:            []prowapi.Refs{{}},
+					GitUserEmail:       clonerefs.DefaultGitUserEmail,
+					GitUserName:        clonerefs.DefaultGitUserName,
+					SrcRoot:            codeMount.MountPath,
+					Log:                CloneLogPath(logMount),
+					GitHubAPIEndpoints: []string{github.DefaultAPIEndpoint},
+					GitCacheDir:        "/git-cache",
+				}),
+				VolumeMounts: []coreapi.VolumeMount{
+					logMount, codeMount, tmpMount,
+					{
+						Name:      "git-cache",
+						ReadOnly:  true,
+						MountPath: "/git-cache",
+					},
+				},
+			},
+			volumes: []coreapi.Volume{
+				tmpVolume,
+				{
+					Name: "git-cache",
+					VolumeSource: coreapi.VolumeSource{
+						PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{
+							ClaimName: "git-mirrors",
+							ReadOnly:  true,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -17,11 +17,13 @@ limitations under the License.
 package gcs
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -44,7 +46,7 @@ const retryCount = 4
 // Upload uploads all of the data in the
 // uploadTargets map to blob storage in parallel. The map is
 // keyed on blob storage path under the bucket
-func Upload(ctx context.Context, bucket, gcsCredentialsFile, s3CredentialsFile string, uploadTargets map[string]UploadFunc) error {
+func Upload(ctx context.Context, bucket string, opts pkgio.OpenerOptions, uploadTargets map[string]UploadFunc) error {
 	parsedBucket, err := url.Parse(bucket)
 	if err != nil {
 		return fmt.Errorf("cannot parse bucket name %s: %w", bucket, err)
@@ -53,7 +55,7 @@ func Upload(ctx context.Context, bucket, gcsCredentialsFile, s3CredentialsFile s
 		parsedBucket.Scheme = providers.GS
 	}
 
-	opener, err := pkgio.NewOpener(ctx, gcsCredentialsFile, s3CredentialsFile)
+	opener, err := pkgio.NewOpenerWithOptions(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("new opener: %w", err)
 	}
@@ -162,6 +164,62 @@ func FileUploadWithOptions(file string, opts pkgio.WriterOptions) UploadFunc {
 	}
 }
 
+// TruncatedFileUploadWithOptions returns an UploadFunc which copies at
+// most limit bytes from the file on disk into GCS object, followed by
+// the marker, and also sets the provided attributes on the object.
+func TruncatedFileUploadWithOptions(file string, limit int64, marker string, opts pkgio.WriterOptions) UploadFunc {
+	return func(writer dataWriter) error {
+		reader, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		opts.BufferSize = utilpointer.Int64Ptr(limit + int64(len(marker)))
+		if *opts.BufferSize > 25*1024*1024 {
+			*opts.BufferSize = 25 * 1024 * 1024
+		}
+
+		src := io.MultiReader(io.LimitReader(reader, limit), strings.NewReader(marker))
+		uploadErr := DataUploadWithOptions(src, opts)(writer)
+		if uploadErr != nil {
+			uploadErr = fmt.Errorf("upload error: %w", uploadErr)
+		}
+		closeErr := reader.Close()
+		if closeErr != nil {
+			closeErr = fmt.Errorf("reader close error: %w", closeErr)
+		}
+
+		return utilerrors.NewAggregate([]error{uploadErr, closeErr})
+	}
+}
+
+// GzipUpload returns an UploadFunc which compresses all data that
+// the upload writes with gzip. The object gets the gzip content
+// encoding, so that it is transparently decompressed on download.
+func GzipUpload(upload UploadFunc) UploadFunc {
+	return func(writer dataWriter) error {
+		writer.ApplyWriterOptions(pkgio.WriterOptions{ContentEncoding: utilpointer.StringPtr("gzip")})
+		return upload(&gzipWriter{dataWriter: writer, gzip: gzip.NewWriter(writer)})
+	}
+}
+
+// gzipWriter compresses the data before it is written to the object.
+type gzipWriter struct {
+	dataWriter
+	gzip *gzip.Writer
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	return w.gzip.Write(p)
+}
+
+func (w *gzipWriter) Close() error {
+	gzipErr := w.gzip.Close()
+	if gzipErr != nil {
+		gzipErr = fmt.Errorf("gzip close error: %w", gzipErr)
+	}
+	return utilerrors.NewAggregate([]error{gzipErr, w.dataWriter.Close()})
+}
+
 // DataUpload returns an UploadFunc which copies all
 // data from src reader into GCS.
 func DataUpload(src io.Reader) UploadFunc {
@@ -18,13 +18,18 @@ package gcs
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io/ioutil"
+	"path"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/google/go-cmp/cmp"
+	utilpointer "k8s.io/utils/pointer"
 
 	"k8s.io/test-infra/prow/io"
 )
@@ -236,3 +241,68 @@ func Test_openerObjectWriter_Write(t *testing.T) {
 		})
 	}
 }
+
+type fakeDataWriter struct {
+	bytes.Buffer
+	opts   []io.WriterOptions
+	closed bool
+}
+
+func (w *fakeDataWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func (w *fakeDataWriter) ApplyWriterOptions(opts io.WriterOptions) {
+	w.opts = append(w.opts, opts)
+}
+
+func TestTruncatedFileUploadWithOptions(t *testing.T) {
+	file := path.Join(t.TempDir(), "core")
+	if err := ioutil.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	writer := &fakeDataWriter{}
+	if err := TruncatedFileUploadWithOptions(file, 4, "[truncated]", io.WriterOptions{})(writer); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if diff := cmp.Diff("0123[truncated]", writer.String()); diff != "" {
+		t.Errorf("unexpected content (-want, +got):\n%s", diff)
+	}
+	if !writer.closed {
+		t.Error("expected the writer to be closed")
+	}
+}
+
+func TestGzipUpload(t *testing.T) {
+	writer := &fakeDataWriter{}
+	contentType := utilpointer.StringPtr("text/plain")
+	if err := GzipUpload(DataUploadWithOptions(strings.NewReader("some logs"), io.WriterOptions{ContentType: contentType}))(writer); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if !writer.closed {
+		t.Error("expected the writer to be closed")
+	}
+
+	reader, err := gzip.NewReader(&writer.Buffer)
+	if err != nil {
+		t.Fatalf("failed to read gzip header: %v", err)
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if diff := cmp.Diff("some logs", string(content)); diff != "" {
+		t.Errorf("unexpected content (-want, +got):\n%s", diff)
+	}
+
+	var opts io.WriterOptions
+	for _, o := range writer.opts {
+		o.Apply(&opts)
+	}
+	expected := io.WriterOptions{ContentEncoding: utilpointer.StringPtr("gzip"), ContentType: contentType}
+	if diff := cmp.Diff(expected, opts); diff != "" {
+		t.Errorf("unexpected writer options (-want, +got):\n%s", diff)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -43,7 +44,9 @@ import (
 // defaultBufferSize is the default buffer size, 10MiB.
 const defaultBufferSize = 10 * 1024 * 1024
 
-func (o Options) censor() error {
+// censor censors the logs and artifacts in place and reports the files in which
+// secrets were censored.
+func (o Options) censor() (CensoringReport, error) {
 	logrus.Info("Starting to censor data")
 	startTime := time.Now()
 	defer func() { logrus.WithField("duration", time.Since(startTime).String()).Info("Finished censoring data") }()
@@ -75,11 +78,16 @@ func (o Options) censor() error {
 		// we could be more strict and just bail out at our callsite in run.go:preUpload() instead of just
 		// emitting a warning there. But failing fast combined with just warning about the failure is not
 		// a sound approach for a secret-censoring mechanism.
-		return fmt.Errorf("could not load secrets: %w", err)
+		return CensoringReport{}, fmt.Errorf("could not load secrets: %w", err)
 	}
 	logrus.WithField("secrets", len(secrets)).Debug("Loaded secrets to censor.")
 	censorer := secretutil.NewCensorer()
 	censorer.RefreshBytes(secrets...)
+	detector, err := newDetector(*o.CensoringOptions, censorer)
+	if err != nil {
+		return CensoringReport{}, err
+	}
+	reporter := newCensoringReporter()
 
 	bufferSize := defaultBufferSize
 	if o.CensoringOptions.CensoringBufferSize != nil {
@@ -88,15 +96,18 @@ func (o Options) censor() error {
 	if largest := censorer.LargestSecret(); 2*largest > bufferSize {
 		bufferSize = 2 * largest
 	}
+	if detector.detects() && 2*maxDetectedLength > bufferSize {
+		bufferSize = 2 * maxDetectedLength
+	}
 	logrus.WithField("buffer_size", bufferSize).Debug("Determined censoring buffer size.")
-	censorFile := fileCensorer(sem, errors, censorer, bufferSize)
-	censor := func(file string) {
-		censorFile(wg, file)
+	censorFile := fileCensorer(sem, errors, detector, reporter, bufferSize)
+	censor := func(file, uploadPath string) {
+		censorFile(wg, file, uploadPath)
 	}
 
 	for _, entry := range o.Entries {
 		logPath := entry.ProcessLog
-		censor(logPath)
+		censor(logPath, buildLogName(o.Entries, entry))
 	}
 
 	for _, item := range o.GcsOptions.Items {
@@ -123,6 +134,7 @@ func (o Options) censor() error {
 			if !should {
 				return nil
 			}
+			uploadPath := path.Join(filepath.Base(item), filepath.ToSlash(relpath))
 
 			contentType, err := determineContentType(absPath)
 			if err != nil {
@@ -133,13 +145,16 @@ func (o Options) censor() error {
 			switch contentType {
 			case "application/x-gzip", "application/zip":
 				logger.Debug("Censoring archive.")
-				if err := handleArchive(absPath, censorFile); err != nil {
+				censorArchived := func(wg *sync.WaitGroup, file string) {
+					censorFile(wg, file, uploadPath)
+				}
+				if err := handleArchive(absPath, censorArchived); err != nil {
 					errors <- fmt.Errorf("could not censor archive %s: %w", absPath, err)
 					return nil
 				}
 			default:
 				logger.Debug("Censoring file.")
-				censor(absPath)
+				censor(absPath, uploadPath)
 			}
 			return nil
 		}); err != nil {
@@ -153,7 +168,7 @@ func (o Options) censor() error {
 	wg.Wait()
 	close(errors)
 	errLock.Lock()
-	return kerrors.NewAggregate(errs)
+	return reporter.report(), kerrors.NewAggregate(errs)
 }
 
 func shouldCensor(options CensoringOptions, path string) (bool, error) {
@@ -178,9 +193,10 @@ func shouldCensor(options CensoringOptions, path string) (bool, error) {
 	return len(options.IncludeDirectories) == 0, nil // censor if no explicit includes exist
 }
 
-// fileCensorer returns a closure over all of our synchronization for a clean handler signature
-func fileCensorer(sem *semaphore.Weighted, errors chan<- error, censorer secretutil.Censorer, bufferSize int) func(wg *sync.WaitGroup, file string) {
-	return func(wg *sync.WaitGroup, file string) {
+// fileCensorer returns a closure over all of our synchronization for a clean handler signature,
+// files are reported by the path they are uploaded to
+func fileCensorer(sem *semaphore.Weighted, errors chan<- error, detector *detector, reporter *censoringReporter, bufferSize int) func(wg *sync.WaitGroup, file, uploadPath string) {
+	return func(wg *sync.WaitGroup, file, uploadPath string) {
 		wg.Add(1)
 		go func() {
 			if err := sem.Acquire(context.Background(), 1); err != nil {
@@ -189,7 +205,9 @@ func fileCensorer(sem *semaphore.Weighted, errors chan<- error, censorer secretu
 			}
 			defer sem.Release(1)
 			defer wg.Done()
+			censorer := detector.forFile()
 			errors <- handleFile(file, censorer, bufferSize)
+			reporter.record(uploadPath, censorer.rules)
 		}()
 	}
 }
@@ -428,6 +446,13 @@ func handleFile(path string, censorer secretutil.Censorer, bufferSize int) error
 	return nil
 }
 
+// bufferCensorer censors the buffers of censor knowing which part of the
+// buffer is flushed and whether the buffer ends with the input, so that it
+// can leave secrets at the end of the buffer for the next one.
+type bufferCensorer interface {
+	CensorBuffer(input *[]byte, flushed int, final bool)
+}
+
 // censor censors input data and streams it to the output. We have a memory footprint of bufferSize bytes.
 func censor(input io.ReadCloser, output io.WriteCloser, censorer secretutil.Censorer, bufferSize int) error {
 	if bufferSize%2 != 0 {
@@ -459,7 +484,12 @@ func censor(input io.ReadCloser, output io.WriteCloser, censorer secretutil.Cens
 			return fmt.Errorf("could not read data from input file before censoring: %w", readErr)
 		}
 		// censor the full buffer and flush the first half to the output
-		censorer.Censor(&buffer)
+		if c, ok := censorer.(bufferCensorer); ok {
+			data := buffer[:frameSize+numRead]
+			c.CensorBuffer(&data, frameSize, readErr == io.EOF)
+		} else {
+			censorer.Censor(&buffer)
+		}
 		numWritten, writeErr := output.Write(buffer[:frameSize])
 		if writeErr != nil {
 			return fmt.Errorf("could not write data to output file after censoring: %w", writeErr)
@@ -0,0 +1,297 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/secretutil"
+)
+
+const (
+	// CensoringReportFile lists the files in which secrets were censored.
+	CensoringReportFile = "censoring-report.json"
+
+	// secretsRule is the rule for the secrets loaded from the secret directories.
+	secretsRule = "secrets"
+	// entropyRule is the rule for strings that look random.
+	entropyRule = "high-entropy"
+
+	// defaultEntropyThreshold is the entropy in bits per character from which
+	// strings are censored. Random base64 strings are well above it, while
+	// hex strings like git commits cannot reach it.
+	defaultEntropyThreshold = 4.5
+	// defaultEntropyMinLength is the length of the shortest string that is
+	// checked for entropy.
+	defaultEntropyMinLength = 20
+
+	// maxDetectedLength is the length of the longest secret that is certain
+	// to be detected, longer ones may span more than one buffer.
+	maxDetectedLength = 16 * 1024
+)
+
+// builtinPatterns match common token formats. Where a pattern has a
+// capturing group, only the group is censored.
+var builtinPatterns = []struct {
+	name    string
+	pattern string
+}{
+	{name: "aws-access-key-id", pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{name: "github-token", pattern: `\bgh[pousr]_[0-9A-Za-z]{36,255}\b`},
+	{name: "github-fine-grained-token", pattern: `\bgithub_pat_[0-9A-Za-z_]{82}\b`},
+	{name: "gitlab-token", pattern: `\bglpat-[0-9A-Za-z_-]{20}\b`},
+	{name: "google-api-key", pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{name: "google-oauth-token", pattern: `\bya29\.[0-9A-Za-z_-]{20,}`},
+	{name: "slack-token", pattern: `\bxox[abposr]-[0-9A-Za-z-]{10,}`},
+	{name: "jwt", pattern: `\beyJ[0-9A-Za-z_-]{10,}\.eyJ[0-9A-Za-z_-]{10,}\.[0-9A-Za-z_-]+`},
+	{name: "bearer-token", pattern: `(?i)\bauthorization:\s*bearer\s+([0-9A-Za-z._~+/-]{16,}=*)`},
+	{name: "private-key", pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+}
+
+type pattern struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+// detector censors the secrets loaded from the secret directories as well
+// as the ones it detects through patterns or their entropy.
+type detector struct {
+	secrets          secretutil.Censorer
+	patterns         []pattern
+	entropyThreshold float64
+	entropyMinLength int
+}
+
+func newDetector(options CensoringOptions, secrets secretutil.Censorer) (*detector, error) {
+	d := &detector{secrets: secrets}
+	if options.BuiltinPatterns {
+		for _, p := range builtinPatterns {
+			d.patterns = append(d.patterns, pattern{name: p.name, regex: regexp.MustCompile(p.pattern)})
+		}
+	}
+	for _, p := range options.Patterns {
+		regex, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid censoring pattern %q: %w", p, err)
+		}
+		d.patterns = append(d.patterns, pattern{name: p, regex: regex})
+	}
+	if options.HighEntropyStrings {
+		d.entropyThreshold = defaultEntropyThreshold
+		if options.EntropyThreshold != 0 {
+			d.entropyThreshold = options.EntropyThreshold
+		}
+		d.entropyMinLength = defaultEntropyMinLength
+		if options.EntropyMinLength != 0 {
+			d.entropyMinLength = options.EntropyMinLength
+		}
+	}
+	return d, nil
+}
+
+// detects determines whether the detector looks for secrets on its own.
+func (d *detector) detects() bool {
+	return len(d.patterns) > 0 || d.entropyThreshold > 0
+}
+
+// forFile returns a censorer for a single file, which records the rules
+// that censored anything.
+func (d *detector) forFile() *fileDetector {
+	return &fileDetector{detector: d, rules: sets.NewString()}
+}
+
+// fileDetector censors a single file, it is not safe for concurrent use.
+type fileDetector struct {
+	*detector
+	rules sets.String
+}
+
+var _ secretutil.Censorer = &fileDetector{}
+var _ bufferCensorer = &fileDetector{}
+
+var censored = []byte{'X'}
+
+// Censor censors the input in place without changing its size.
+func (f *fileDetector) Censor(input *[]byte) {
+	f.CensorBuffer(input, len(*input), true)
+}
+
+// CensorBuffer censors a buffer of a file in place without changing its size.
+// Only the first flushed bytes are written after censoring it, the rest is
+// censored again along with the data that is read next. Unless the buffer
+// ends with the file, secrets that touch its end and start after the flushed
+// bytes are therefore left for the next buffer, as they may continue in the
+// data that is read next.
+func (f *fileDetector) CensorBuffer(input *[]byte, flushed int, final bool) {
+	// deferred determines whether the secret between start and end is
+	// censored with the next buffer.
+	deferred := func(start, end int) bool {
+		return !final && end == len(*input) && start >= flushed
+	}
+
+	// The secrets are replaced by as many Xs, so more Xs mean that secrets
+	// were censored.
+	before := bytes.Count(*input, censored)
+	f.secrets.Censor(input)
+	if bytes.Count(*input, censored) > before {
+		f.rules.Insert(secretsRule)
+	}
+
+	for _, p := range f.patterns {
+		for _, match := range p.regex.FindAllSubmatchIndex(*input, -1) {
+			if deferred(match[0], match[1]) {
+				continue
+			}
+			start, end := match[0], match[1]
+			if len(match) >= 4 && match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+			if redact((*input)[start:end]) {
+				f.rules.Insert(p.name)
+			}
+		}
+	}
+
+	if f.entropyThreshold > 0 {
+		f.censorHighEntropy(*input, deferred)
+	}
+}
+
+// censorHighEntropy censors runs of token characters that are long and
+// random enough, unless they are deferred.
+func (f *fileDetector) censorHighEntropy(input []byte, deferred func(start, end int) bool) {
+	start := -1
+	for i := 0; i <= len(input); i++ {
+		if i < len(input) && isTokenChar(input[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && !deferred(start, i) && i-start >= f.entropyMinLength && entropy(input[start:i]) >= f.entropyThreshold {
+			if redact(input[start:i]) {
+				f.rules.Insert(entropyRule)
+			}
+		}
+		start = -1
+	}
+}
+
+// isTokenChar determines whether the character may be part of a token,
+// which covers hex, base64 and base64url.
+func isTokenChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '+' || c == '/' || c == '=' || c == '-' || c == '_'
+}
+
+// entropy returns the Shannon entropy of the data in bits per byte.
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, c := range data {
+		counts[c]++
+	}
+	var e float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// redact replaces the data with Xs and returns whether it was not censored yet.
+func redact(data []byte) bool {
+	if len(data) == 0 || len(bytes.Trim(data, "X")) == 0 {
+		return false
+	}
+	for i := range data {
+		data[i] = 'X'
+	}
+	return true
+}
+
+// CensoringReport lists the files in which secrets were censored and the
+// rules that found them, but never the secrets themselves.
+type CensoringReport struct {
+	Files []CensoredFile `json:"files"`
+}
+
+// CensoredFile is a file in which secrets were censored. The path is the one
+// the file is uploaded to, or the one of the archive it is in.
+type CensoredFile struct {
+	Path  string   `json:"path"`
+	Rules []string `json:"rules"`
+}
+
+// censoringReporter collects the report while files are censored concurrently.
+type censoringReporter struct {
+	lock  sync.Mutex
+	files map[string]sets.String
+}
+
+func newCensoringReporter() *censoringReporter {
+	return &censoringReporter{files: map[string]sets.String{}}
+}
+
+func (r *censoringReporter) record(path string, rules sets.String) {
+	if rules.Len() == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.files[path]; !ok {
+		r.files[path] = sets.NewString()
+	}
+	r.files[path].Insert(rules.UnsortedList()...)
+}
+
+func (r *censoringReporter) report() CensoringReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	report := CensoringReport{Files: []CensoredFile{}}
+	for path, rules := range r.files {
+		report.Files = append(report.Files, CensoredFile{Path: path, Rules: rules.List()})
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	return report
+}
+
+// addTo adds the report to the uploads, unless nothing was censored.
+func (r CensoringReport) addTo(uploads map[string]io.Reader) error {
+	if len(r.Files) == 0 {
+		return nil
+	}
+	content, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not marshal censoring report: %w", err)
+	}
+	uploads[CensoringReportFile] = bytes.NewReader(content)
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"regexp"
 
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
@@ -83,6 +84,10 @@ type Options struct {
 	// CensoringOptions are options that pertain to censoring output before upload.
 	CensoringOptions *CensoringOptions `json:"censoring_options,omitempty"`
 
+	// ResourceUsage enables sampling the resource usage of the test containers,
+	// which is uploaded as resource-usage.json.
+	ResourceUsage *ResourceUsageOptions `json:"resource_usage,omitempty"`
+
 	// SecretDirectories is deprecated, use censoring_options.secret_directories instead.
 	SecretDirectories []string `json:"secret_directories,omitempty"`
 	// CensoringConcurrency is deprecated, use censoring_options.censoring_concurrency instead.
@@ -125,6 +130,25 @@ type CensoringOptions struct {
 	// IniFilenames are secret filenames that should be parsed as INI files in order to
 	// censor the values in the key-value mapping as well as the full content of the file.
 	IniFilenames []string `json:"ini_filenames,omitempty"`
+
+	// Patterns are regular expressions matching secrets that should be censored even
+	// though they are not in the secret directories, e.g. credentials minted by the
+	// test. If a pattern has a capturing group, only the first group is censored.
+	Patterns []string `json:"patterns,omitempty"`
+
+	// BuiltinPatterns enables patterns for common token formats, like GitHub tokens,
+	// cloud provider credentials, JWTs and private keys.
+	BuiltinPatterns bool `json:"builtin_patterns,omitempty"`
+
+	// HighEntropyStrings enables censoring strings which look random, like most
+	// generated credentials. This may censor other random strings too, like hashes.
+	HighEntropyStrings bool `json:"high_entropy_strings,omitempty"`
+	// EntropyThreshold is the Shannon entropy in bits per character from which strings
+	// are censored. If unset, defaults to 4.5, which hex strings cannot reach.
+	EntropyThreshold float64 `json:"entropy_threshold,omitempty"`
+	// EntropyMinLength is the length of the shortest string that is checked for its
+	// entropy. If unset, defaults to 20.
+	EntropyMinLength int `json:"entropy_min_length,omitempty"`
 }
 
 func (o Options) entries() []wrapper.Options {
@@ -149,6 +173,13 @@ func (o *Options) Validate() error {
 		}
 		o.CensoringOptions = &opts
 	}
+	if o.CensoringOptions != nil {
+		for _, pattern := range o.CensoringOptions.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid censoring pattern %q: %w", pattern, err)
+			}
+		}
+	}
 
 	ents := o.entries()
 	if len(ents) == 0 {
@@ -0,0 +1,401 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/entrypoint"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+)
+
+const (
+	// ResourceUsageFile is the artifact with the resource usage of the
+	// test containers.
+	ResourceUsageFile = "resource-usage.json"
+
+	// OOMKilledKey is the key in the metadata of finished.json that lists
+	// the test containers in which processes were killed for running out
+	// of memory.
+	OOMKilledKey = "oom-killed"
+
+	// DefaultResourceUsageInterval is how often the resource usage is
+	// sampled by default.
+	DefaultResourceUsageInterval = 10 * time.Second
+)
+
+// ResourceUsageOptions configures the sampling of the resource usage of the
+// test containers. Sidecar finds the test containers through the processes of
+// their entrypoints, so the pod must share its process namespace and sidecar
+// must be allowed to inspect the processes, e.g. by running as the same user.
+type ResourceUsageOptions struct {
+	// Interval is how often the resource usage is sampled, defaults to
+	// DefaultResourceUsageInterval.
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// ResourceUsage holds the samples of the resource usage of the test containers.
+type ResourceUsage struct {
+	Interval   time.Duration    `json:"interval"`
+	Containers []ContainerUsage `json:"containers"`
+}
+
+// ContainerUsage holds the samples of the resource usage of a test container.
+type ContainerUsage struct {
+	Name string `json:"name"`
+	// PeakMemoryBytes is the most memory the container used, which may be
+	// more than the samples show.
+	PeakMemoryBytes uint64 `json:"peak_memory_bytes"`
+	// OOMKills is how often a process of the container was killed for
+	// running out of memory.
+	OOMKills uint64           `json:"oom_kills,omitempty"`
+	Samples  []ResourceSample `json:"samples"`
+}
+
+// ResourceSample is the resource usage of a test container at a point in time.
+// CPU and IO are counted from the start of the container.
+type ResourceSample struct {
+	Time         time.Time `json:"time"`
+	CPUSeconds   float64   `json:"cpu_seconds"`
+	MemoryBytes  uint64    `json:"memory_bytes"`
+	IOReadBytes  uint64    `json:"io_read_bytes"`
+	IOWriteBytes uint64    `json:"io_write_bytes"`
+}
+
+// resourceSampler samples the cgroups of the test containers.
+type resourceSampler struct {
+	procDir  string
+	interval time.Duration
+	now      func() time.Time
+	entries  []wrapper.Options
+
+	lock sync.Mutex
+	// pids are processes in the test containers, by marker file.
+	pids       map[string]string
+	containers []ContainerUsage
+}
+
+func newResourceSampler(options ResourceUsageOptions, entries []wrapper.Options) *resourceSampler {
+	interval := options.Interval
+	if interval == 0 {
+		interval = DefaultResourceUsageInterval
+	}
+	s := &resourceSampler{
+		procDir:  "/proc",
+		interval: interval,
+		now:      time.Now,
+		entries:  entries,
+		pids:     map[string]string{},
+	}
+	for _, e := range entries {
+		s.containers = append(s.containers, ContainerUsage{Name: e.ContainerName})
+	}
+	return s
+}
+
+// run samples the resource usage until the context is cancelled.
+func (s *resourceSampler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *resourceSampler) sample() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.now()
+	var scanned bool
+	for i, e := range s.entries {
+		stats, err := s.read(e.MarkerFile, &scanned)
+		if err != nil {
+			logrus.WithError(err).WithField("container", e.ContainerName).Debug("Could not sample resource usage.")
+			continue
+		}
+		c := &s.containers[i]
+		c.Samples = append(c.Samples, ResourceSample{
+			Time:         now,
+			CPUSeconds:   stats.cpuSeconds,
+			MemoryBytes:  stats.memoryBytes,
+			IOReadBytes:  stats.ioReadBytes,
+			IOWriteBytes: stats.ioWriteBytes,
+		})
+		if stats.peakMemoryBytes > c.PeakMemoryBytes {
+			c.PeakMemoryBytes = stats.peakMemoryBytes
+		}
+		if stats.memoryBytes > c.PeakMemoryBytes {
+			c.PeakMemoryBytes = stats.memoryBytes
+		}
+		c.OOMKills = stats.oomKills
+	}
+}
+
+// read reads the cgroup of the container with the marker file, looking
+// for one of its processes again if the last one is gone. The processes
+// are only scanned once per sample.
+func (s *resourceSampler) read(markerFile string, scanned *bool) (cgroupStats, error) {
+	if pid, ok := s.pids[markerFile]; ok {
+		stats, err := readCgroup(s.cgroupDir(pid))
+		if err == nil {
+			return stats, nil
+		}
+		delete(s.pids, markerFile)
+	}
+	if !*scanned {
+		*scanned = true
+		if err := s.findContainers(); err != nil {
+			return cgroupStats{}, err
+		}
+	}
+	pid, ok := s.pids[markerFile]
+	if !ok {
+		return cgroupStats{}, errors.New("no process found in the container")
+	}
+	return readCgroup(s.cgroupDir(pid))
+}
+
+func (s *resourceSampler) cgroupDir(pid string) string {
+	return filepath.Join(s.procDir, pid, "root", "sys", "fs", "cgroup")
+}
+
+// findContainers looks for a process of every test container, which
+// all have the options of their entrypoint in their environment.
+func (s *resourceSampler) findContainers() error {
+	procs, err := ioutil.ReadDir(s.procDir)
+	if err != nil {
+		return fmt.Errorf("could not list processes: %w", err)
+	}
+	prefix := []byte(entrypoint.JSONConfigEnvVar + "=")
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		environ, err := ioutil.ReadFile(filepath.Join(s.procDir, proc.Name(), "environ"))
+		if err != nil {
+			continue
+		}
+		for _, env := range bytes.Split(environ, []byte{0}) {
+			if !bytes.HasPrefix(env, prefix) {
+				continue
+			}
+			var options wrapper.Options
+			if err := json.Unmarshal(env[len(prefix):], &options); err != nil {
+				break
+			}
+			if _, ok := s.pids[options.MarkerFile]; !ok {
+				s.pids[options.MarkerFile] = proc.Name()
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// usage returns the resource usage sampled so far.
+func (s *resourceSampler) usage() ResourceUsage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	usage := ResourceUsage{Interval: s.interval}
+	for _, c := range s.containers {
+		c.Samples = append([]ResourceSample(nil), c.Samples...)
+		usage.Containers = append(usage.Containers, c)
+	}
+	return usage
+}
+
+// report adds the resource usage to the uploads and the OOM kills to the
+// metadata.
+func (s *resourceSampler) report(uploads map[string]io.Reader, metadata map[string]interface{}) {
+	usage := s.usage()
+	var oomKilled []string
+	for _, c := range usage.Containers {
+		if c.OOMKills > 0 {
+			oomKilled = append(oomKilled, c.Name)
+		}
+	}
+	if len(oomKilled) > 0 {
+		metadata[OOMKilledKey] = strings.Join(oomKilled, ",")
+	}
+	content, err := json.Marshal(usage)
+	if err != nil {
+		logrus.WithError(err).Warn("Could not marshal resource usage")
+		return
+	}
+	uploads[ResourceUsageFile] = bytes.NewReader(content)
+}
+
+type cgroupStats struct {
+	cpuSeconds      float64
+	memoryBytes     uint64
+	peakMemoryBytes uint64
+	oomKills        uint64
+	ioReadBytes     uint64
+	ioWriteBytes    uint64
+}
+
+// readCgroup reads the stats of the cgroup mounted at dir. The CPU and memory
+// usage are required, everything else is only available on some kernels.
+func readCgroup(dir string) (cgroupStats, error) {
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+		return readCgroupV2(dir)
+	}
+	return readCgroupV1(dir)
+}
+
+func readCgroupV2(dir string) (cgroupStats, error) {
+	var stats cgroupStats
+	cpu, err := readKeyedValue(filepath.Join(dir, "cpu.stat"), "usage_usec")
+	if err != nil {
+		return stats, err
+	}
+	stats.cpuSeconds = float64(cpu) / float64(time.Second/time.Microsecond)
+	if stats.memoryBytes, err = readValue(filepath.Join(dir, "memory.current")); err != nil {
+		return stats, err
+	}
+	stats.peakMemoryBytes, _ = readValue(filepath.Join(dir, "memory.peak"))
+	stats.oomKills, _ = readKeyedValue(filepath.Join(dir, "memory.events"), "oom_kill")
+	stats.ioReadBytes, stats.ioWriteBytes = readIOStat(filepath.Join(dir, "io.stat"))
+	return stats, nil
+}
+
+func readCgroupV1(dir string) (cgroupStats, error) {
+	var stats cgroupStats
+	cpu, err := readValue(filepath.Join(dir, "cpuacct", "cpuacct.usage"))
+	if err != nil {
+		return stats, err
+	}
+	stats.cpuSeconds = float64(cpu) / float64(time.Second)
+	if stats.memoryBytes, err = readValue(filepath.Join(dir, "memory", "memory.usage_in_bytes")); err != nil {
+		return stats, err
+	}
+	stats.peakMemoryBytes, _ = readValue(filepath.Join(dir, "memory", "memory.max_usage_in_bytes"))
+	stats.oomKills, _ = readKeyedValue(filepath.Join(dir, "memory", "memory.oom_control"), "oom_kill")
+	stats.ioReadBytes, stats.ioWriteBytes = readBlkioStat(filepath.Join(dir, "blkio", "blkio.throttle.io_service_bytes"))
+	return stats, nil
+}
+
+// readValue reads a file with a single number.
+func readValue(path string) (uint64, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+// readKeyedValue reads the number with the key from a file with
+// "<key> <number>" lines.
+func readKeyedValue(path, key string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, path)
+}
+
+// readIOStat sums the bytes read and written on all devices from lines like
+// "8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0".
+func readIOStat(path string) (uint64, uint64) {
+	var read, written uint64
+	forEachLine(path, func(fields []string) {
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			n, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				written += n
+			}
+		}
+	})
+	return read, written
+}
+
+// readBlkioStat sums the bytes read and written on all devices from lines
+// like "8:0 Read 1".
+func readBlkioStat(path string) (uint64, uint64) {
+	var read, written uint64
+	forEachLine(path, func(fields []string) {
+		if len(fields) != 3 {
+			return
+		}
+		n, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return
+		}
+		switch fields[1] {
+		case "Read":
+			read += n
+		case "Write":
+			written += n
+		}
+	})
+	return read, written
+}
+
+func forEachLine(path string, f func(fields []string)) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			f(fields)
+		}
+	}
+}
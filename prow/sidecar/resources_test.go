@@ -0,0 +1,191 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestReadCgroup(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		files    map[string]string
+		expected cgroupStats
+		err      bool
+	}{
+		{
+			name: "cgroup v2",
+			files: map[string]string{
+				"cgroup.controllers": "cpu io memory pids\n",
+				"cpu.stat":           "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+				"memory.current":     "1048576\n",
+				"memory.peak":        "4194304\n",
+				"memory.events":      "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+				"io.stat":            "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=1 wios=2 dbytes=0 dios=0\n",
+			},
+			expected: cgroupStats{cpuSeconds: 2.5, memoryBytes: 1048576, peakMemoryBytes: 4194304, oomKills: 1, ioReadBytes: 110, ioWriteBytes: 220},
+		},
+		{
+			name: "cgroup v2 without optional files",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory\n",
+				"cpu.stat":           "usage_usec 1000000\n",
+				"memory.current":     "1024\n",
+			},
+			expected: cgroupStats{cpuSeconds: 1, memoryBytes: 1024},
+		},
+		{
+			name: "cgroup v1",
+			files: map[string]string{
+				"cpuacct/cpuacct.usage":                 "1500000000\n",
+				"memory/memory.usage_in_bytes":          "2048\n",
+				"memory/memory.max_usage_in_bytes":      "8192\n",
+				"memory/memory.oom_control":             "oom_kill_disable 0\nunder_oom 0\noom_kill 2\n",
+				"blkio/blkio.throttle.io_service_bytes": "8:0 Read 100\n8:0 Write 200\n8:0 Sync 300\n8:0 Total 300\nTotal 300\n",
+			},
+			expected: cgroupStats{cpuSeconds: 1.5, memoryBytes: 2048, peakMemoryBytes: 8192, oomKills: 2, ioReadBytes: 100, ioWriteBytes: 200},
+		},
+		{
+			name: "missing memory usage",
+			files: map[string]string{
+				"cgroup.controllers": "cpu\n",
+				"cpu.stat":           "usage_usec 1000000\n",
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			stats, err := readCgroup(dir)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, stats, cmp.AllowUnexported(cgroupStats{})); !tc.err && diff != "" {
+				t.Errorf("unexpected stats (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResourceSampler(t *testing.T) {
+	procDir := t.TempDir()
+	environ := func(options string) string {
+		return "PATH=/bin\x00ENTRYPOINT_OPTIONS=" + options + "\x00HOME=/root\x00"
+	}
+	writeFiles(t, procDir, map[string]string{
+		"self/environ": environ(`{"marker_file":"/logs/test-marker.txt"}`),
+		"1/environ":    "PATH=/bin\x00",
+		"12/environ":   environ(`{"args":["make","test"],"marker_file":"/logs/test-marker.txt"}`),
+		"12/root/sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+		"12/root/sys/fs/cgroup/cpu.stat":           "usage_usec 1000000\n",
+		"12/root/sys/fs/cgroup/memory.current":     "1024\n",
+		"12/root/sys/fs/cgroup/memory.events":      "oom_kill 0\n",
+		"34/environ":                               environ(`{"marker_file":"/logs/e2e-marker.txt"}`),
+		"34/root/sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+		"34/root/sys/fs/cgroup/cpu.stat":           "usage_usec 2000000\n",
+		"34/root/sys/fs/cgroup/memory.current":     "4096\n",
+		"34/root/sys/fs/cgroup/memory.events":      "oom_kill 0\n",
+	})
+
+	start := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	s := newResourceSampler(ResourceUsageOptions{}, []wrapper.Options{
+		{ContainerName: "test", MarkerFile: "/logs/test-marker.txt"},
+		{ContainerName: "e2e", MarkerFile: "/logs/e2e-marker.txt"},
+		{ContainerName: "missing", MarkerFile: "/logs/missing-marker.txt"},
+	})
+	s.procDir = procDir
+	s.now = func() time.Time { return now }
+
+	s.sample()
+	now = now.Add(10 * time.Second)
+	writeFiles(t, procDir, map[string]string{
+		"34/root/sys/fs/cgroup/cpu.stat":      "usage_usec 12000000\n",
+		"34/root/sys/fs/cgroup/memory.events": "oom_kill 1\n",
+	})
+	if err := os.RemoveAll(filepath.Join(procDir, "12")); err != nil {
+		t.Fatalf("failed to remove process: %v", err)
+	}
+	s.sample()
+
+	expected := ResourceUsage{
+		Interval: DefaultResourceUsageInterval,
+		Containers: []ContainerUsage{
+			{
+				Name:            "test",
+				PeakMemoryBytes: 1024,
+				Samples:         []ResourceSample{{Time: start, CPUSeconds: 1, MemoryBytes: 1024}},
+			},
+			{
+				Name:            "e2e",
+				PeakMemoryBytes: 4096,
+				OOMKills:        1,
+				Samples: []ResourceSample{
+					{Time: start, CPUSeconds: 2, MemoryBytes: 4096},
+					{Time: now, CPUSeconds: 12, MemoryBytes: 4096},
+				},
+			},
+			{Name: "missing"},
+		},
+	}
+	if diff := cmp.Diff(expected, s.usage()); diff != "" {
+		t.Errorf("unexpected resource usage (-want, +got):\n%s", diff)
+	}
+
+	uploads := map[string]io.Reader{}
+	metadata := map[string]interface{}{}
+	s.report(uploads, metadata)
+	if diff := cmp.Diff(map[string]interface{}{OOMKilledKey: "e2e"}, metadata); diff != "" {
+		t.Errorf("unexpected metadata (-want, +got):\n%s", diff)
+	}
+	content, err := ioutil.ReadAll(uploads[ResourceUsageFile])
+	if err != nil {
+		t.Fatalf("failed to read resource usage: %v", err)
+	}
+	var uploaded ResourceUsage
+	if err := json.Unmarshal(content, &uploaded); err != nil {
+		t.Fatalf("failed to unmarshal resource usage: %v", err)
+	}
+	if diff := cmp.Diff(expected, uploaded); diff != "" {
+		t.Errorf("unexpected uploaded resource usage (-want, +got):\n%s", diff)
+	}
+}
@@ -102,6 +102,12 @@ func (o Options) Run(ctx context.Context, logFile *os.File) (int, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
+	var sampler *resourceSampler
+	if o.ResourceUsage != nil {
+		sampler = newResourceSampler(*o.ResourceUsage, entries)
+		go sampler.run(ctx)
+	}
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -119,10 +125,7 @@ func (o Options) Run(ctx context.Context, logFile *os.File) (int, error) {
 				logrus.Errorf("Received an interrupt: %s, cancelling...", s)
 
 				// perform pre upload tasks
-				o.preUpload()
-
-				buildLogs := logReaders(entries)
-				metadata := combineMetadata(entries)
+				buildLogs, metadata := o.prepareUpload(entries, sampler)
 
 				//Peform best-effort upload
 				err := o.doUpload(ctx, spec, false, true, metadata, buildLogs, logFile, &once)
@@ -137,6 +140,11 @@ func (o Options) Run(ctx context.Context, logFile *os.File) (int, error) {
 	}()
 
 	passed, aborted, failures := wait(ctx, entries)
+	if sampler != nil {
+		// The test containers exit shortly after writing their markers,
+		// so this is the last chance to see whether they ran out of memory.
+		sampler.sample()
+	}
 
 	cancel()
 	// If we are being asked to terminate by the kubelet but we have
@@ -146,22 +154,24 @@ func (o Options) Run(ctx context.Context, logFile *os.File) (int, error) {
 	// uploading, so we ignore the signals.
 	signal.Ignore(os.Interrupt, syscall.SIGTERM)
 
-	o.preUpload()
-
-	buildLogs := logReaders(entries)
-	metadata := combineMetadata(entries)
+	buildLogs, metadata := o.prepareUpload(entries, sampler)
 	return failures, o.doUpload(context.Background(), spec, passed, aborted, metadata, buildLogs, logFile, &once)
 }
 
 const errorKey = "sidecar-errors"
 
+// buildLogName returns the name the log of the entry is uploaded as.
+func buildLogName(entries []wrapper.Options, opt wrapper.Options) string {
+	if len(entries) > 1 {
+		return fmt.Sprintf("%s-build-log.txt", opt.ContainerName)
+	}
+	return "build-log.txt"
+}
+
 func logReaders(entries []wrapper.Options) map[string]io.Reader {
 	readers := make(map[string]io.Reader)
 	for _, opt := range entries {
-		buildLog := "build-log.txt"
-		if len(entries) > 1 {
-			buildLog = fmt.Sprintf("%s-build-log.txt", opt.ContainerName)
-		}
+		buildLog := buildLogName(entries, opt)
 		log, err := os.Open(opt.ProcessLog)
 		if err != nil {
 			logrus.WithError(err).Errorf("Failed to open %s", opt.ProcessLog)
@@ -210,18 +220,40 @@ func combineMetadata(entries []wrapper.Options) map[string]interface{} {
 	return metadata
 }
 
+// prepareUpload performs the pre upload tasks and returns the files to upload
+// besides the artifacts, and the metadata for finished.json.
+func (o Options) prepareUpload(entries []wrapper.Options, sampler *resourceSampler) (map[string]io.Reader, map[string]interface{}) {
+	report := o.preUpload()
+
+	uploads := logReaders(entries)
+	metadata := combineMetadata(entries)
+	if err := report.addTo(uploads); err != nil {
+		logrus.WithError(err).Warn("Failed to add censoring report")
+	}
+	if sampler != nil {
+		sampler.report(uploads, metadata)
+	}
+	return uploads, metadata
+}
+
 //preUpload peforms steps required before actual upload
-func (o Options) preUpload() {
+func (o Options) preUpload() CensoringReport {
 	if o.DeprecatedWrapperOptions != nil {
 		// This only fires if the prowjob controller and sidecar are at different commits
 		logrus.Warn("Using deprecated wrapper_options instead of entries. Please update prow/pod-utils/decorate before June 2019")
 	}
 
+	var report CensoringReport
 	if o.CensoringOptions != nil {
-		if err := o.censor(); err != nil {
+		var err error
+		if report, err = o.censor(); err != nil {
 			logrus.WithError(err).Warn("Failed to censor data")
 		}
+		for _, file := range report.Files {
+			logrus.WithField("path", file.Path).WithField("rules", file.Rules).Info("Censored secrets.")
+		}
 	}
+	return report
 }
 
 func (o Options) doUpload(ctx context.Context, spec *downwardapi.JobSpec, passed, aborted bool, metadata map[string]interface{}, logReaders map[string]io.Reader, logFile *os.File, once *sync.Once) error {
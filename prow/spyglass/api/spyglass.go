@@ -17,7 +17,9 @@ limitations under the License.
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 
 	"k8s.io/test-infra/prow/config"
 )
@@ -40,6 +42,15 @@ type Lens interface {
 	Callback(artifacts []Artifact, resourceRoot string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string
 }
 
+// StreamingLens is a Lens that can push updates of artifacts that are still
+// being written to its front-end.
+type StreamingLens interface {
+	Lens
+	// Stream calls send with the events for the lens's front-end until the
+	// artifacts are complete or ctx is done.
+	Stream(ctx context.Context, send func(event, data string) error, artifacts []Artifact, resourceRoot string, data string, config json.RawMessage, spyglassConfig config.Spyglass) error
+}
+
 // Artifact represents some output of a prow job
 type Artifact interface {
 	// ReadAt reads len(p) bytes of the artifact at offset off. (unsupported on some compressed files)
@@ -62,6 +73,17 @@ type Artifact interface {
 	UpdateMetadata(map[string]string) error
 }
 
+// StreamingArtifact is an Artifact that is appended to while the job runs,
+// like the log of a running pod.
+type StreamingArtifact interface {
+	Artifact
+	// Live returns true if the artifact may still be appended to
+	Live() bool
+	// Stream returns a reader of the artifact from offset off that follows what
+	// is appended to it. The reader returns io.EOF once the artifact is complete.
+	Stream(ctx context.Context, off int64) (io.ReadCloser, error)
+}
+
 // RequestAction defines the action for a request
 type RequestAction string
 
@@ -72,6 +94,9 @@ const (
 	RequestActionRerender RequestAction = "rerender"
 	// RequestActionCallBack means that this is an arbitrary callback
 	RequestActionCallBack RequestAction = "callback"
+	// RequestActionStream means that this is a request for a stream of
+	// server-sent events from a StreamingLens
+	RequestActionStream RequestAction = "stream"
 )
 
 type LensRequest struct {
@@ -0,0 +1,389 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/junit"
+)
+
+const (
+	// maxCompareLogBytes and maxCompareLogLines limit how much of the end of
+	// the build logs is compared.
+	maxCompareLogBytes = 10e6
+	maxCompareLogLines = 10000
+	// compareLogContext is the number of unchanged lines shown around changes.
+	compareLogContext = 3
+)
+
+// defaultJUnitRegex matches junit artifacts if no junit lens is configured.
+var defaultJUnitRegex = regexp.MustCompile(`^artifacts/junit.*\.xml$`)
+
+// timestampRegexes match the timestamps that build logs commonly contain. They
+// are stripped before aligning the lines of two build logs.
+var timestampRegexes = []*regexp.Regexp{
+	// glog headers, e.g. I0601 12:00:00.000000   1234 main.go:10]
+	regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ `),
+	// dates and times, e.g. 2022-06-01T12:00:00.000Z or 2022/06/01 12:00:00
+	regexp.MustCompile(`(\d{4}[-/]\d{2}[-/]\d{2}[T ])?\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`),
+}
+
+// Comparison holds the differences between the artifacts of two job runs.
+type Comparison struct {
+	// A and B are the sources of the compared runs.
+	A string
+	B string
+	// Tests lists the tests whose result changed from A to B.
+	Tests TestsComparison
+	// Metadata lists the started.json and finished.json fields that differ.
+	Metadata []MetadataDifference
+	// BuildLog aligns the build logs of A and B.
+	BuildLog BuildLogComparison
+}
+
+// TestsComparison lists the names of tests whose result changed.
+type TestsComparison struct {
+	NewlyFailing []string
+	NewlyPassing []string
+	NewlyFlaky   []string
+}
+
+// MetadataDifference is a metadata field with different values in A and B.
+type MetadataDifference struct {
+	Key string
+	A   string
+	B   string
+}
+
+// BuildLogComparison is a side-by-side diff of two build logs.
+type BuildLogComparison struct {
+	Hunks []BuildLogHunk
+	// Truncated is set if only the end of a build log was compared.
+	Truncated bool
+}
+
+// BuildLogHunk is a block of changed lines with some unchanged lines around it.
+type BuildLogHunk struct {
+	// ALine and BLine are the 1-based line numbers the hunk starts at.
+	ALine int
+	BLine int
+	Rows  []BuildLogRow
+}
+
+// BuildLogRow is a row of a side-by-side diff. A or B is empty if the line was
+// only present in the other log.
+type BuildLogRow struct {
+	Changed bool
+	A       string
+	B       string
+}
+
+// Compare fetches the artifacts of the runs at srcA and srcB and compares
+// their test results, build logs and metadata.
+func (sg *Spyglass) Compare(ctx context.Context, srcA, srcB string) (*Comparison, error) {
+	var artifacts [2][]api.Artifact
+	for i, src := range []string{srcA, srcB} {
+		var err error
+		if artifacts[i], err = sg.comparedArtifacts(ctx, strings.TrimSuffix(src, "/")); err != nil {
+			return nil, fmt.Errorf("error fetching artifacts of %s: %w", src, err)
+		}
+	}
+	comparison := compareArtifacts(artifacts[0], artifacts[1])
+	comparison.A, comparison.B = srcA, srcB
+	return &comparison, nil
+}
+
+// comparedArtifacts fetches the artifacts of src that Compare looks at.
+func (sg *Spyglass) comparedArtifacts(ctx context.Context, src string) ([]api.Artifact, error) {
+	src, err := sg.ResolveSymlink(src)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving real path: %w", err)
+	}
+	names, err := sg.ListArtifacts(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("error listing artifacts: %w", err)
+	}
+
+	junitRegexes := []*regexp.Regexp{defaultJUnitRegex}
+	for _, lens := range sg.config().Deck.Spyglass.Lenses {
+		if lens.Lens.Name != "junit" {
+			continue
+		}
+		junitRegexes = nil
+		for _, re := range lens.RequiredFiles {
+			junitRegexes = append(junitRegexes, sg.config().Deck.Spyglass.RegexCache[re])
+		}
+		break
+	}
+
+	var compared []string
+	for _, name := range names {
+		switch name {
+		case singleLogName, prowv1.StartedStatusFile, prowv1.FinishedStatusFile:
+			compared = append(compared, name)
+			continue
+		}
+		for _, re := range junitRegexes {
+			if re != nil && re.MatchString(name) {
+				compared = append(compared, name)
+				break
+			}
+		}
+	}
+	return sg.FetchArtifacts(ctx, src, "", sg.config().Deck.Spyglass.SizeLimit, compared)
+}
+
+// compareArtifacts compares the artifacts of two runs.
+func compareArtifacts(a, b []api.Artifact) Comparison {
+	var junitA, junitB []api.Artifact
+	for _, artifact := range a {
+		if isJUnit(artifact) {
+			junitA = append(junitA, artifact)
+		}
+	}
+	for _, artifact := range b {
+		if isJUnit(artifact) {
+			junitB = append(junitB, artifact)
+		}
+	}
+	logA, truncatedA := readBuildLog(a)
+	logB, truncatedB := readBuildLog(b)
+
+	comparison := Comparison{
+		Tests:    compareTests(junit.Results(junitA), junit.Results(junitB)),
+		Metadata: compareMetadata(readMetadata(a), readMetadata(b)),
+		BuildLog: compareBuildLogs(logA, logB),
+	}
+	comparison.BuildLog.Truncated = truncatedA || truncatedB
+	return comparison
+}
+
+func isJUnit(artifact api.Artifact) bool {
+	switch artifact.JobPath() {
+	case singleLogName, prowv1.StartedStatusFile, prowv1.FinishedStatusFile:
+		return false
+	}
+	return true
+}
+
+func findArtifact(artifacts []api.Artifact, name string) api.Artifact {
+	for _, artifact := range artifacts {
+		if artifact.JobPath() == name {
+			return artifact
+		}
+	}
+	return nil
+}
+
+// testNames returns the names of the tests in results.
+func testNames(results []junit.TestResult) sets.String {
+	names := sets.NewString()
+	for _, result := range results {
+		if len(result.Junit) == 0 {
+			continue
+		}
+		name := result.Junit[0].Name
+		if class := result.Junit[0].ClassName; class != "" {
+			name = class + " " + name
+		}
+		names.Insert(name)
+	}
+	return names
+}
+
+// compareTests lists the tests that fail in b but did not fail in a, that
+// passed in b but failed in a, and that are flaky in b but were not flaky in a.
+func compareTests(a, b junit.JVD) TestsComparison {
+	failedA, failedB := testNames(a.Failed), testNames(b.Failed)
+	flakyA, flakyB := testNames(a.Flaky), testNames(b.Flaky)
+	passedB := testNames(b.Passed)
+	return TestsComparison{
+		NewlyFailing: failedB.Difference(failedA).List(),
+		NewlyPassing: passedB.Intersection(failedA).List(),
+		NewlyFlaky:   flakyB.Difference(flakyA).List(),
+	}
+}
+
+// readMetadata flattens started.json and finished.json into a map from dotted
+// keys to values.
+func readMetadata(artifacts []api.Artifact) map[string]string {
+	res := map[string]string{}
+	for name, into := range map[string]interface{}{
+		prowv1.StartedStatusFile:  &gcs.Started{},
+		prowv1.FinishedStatusFile: &gcs.Finished{},
+	} {
+		artifact := findArtifact(artifacts, name)
+		if artifact == nil {
+			continue
+		}
+		content, err := artifact.ReadAll()
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", artifact.CanonicalLink()).Warn("Error reading artifact.")
+			continue
+		}
+		// Round trip through the typed struct so that both runs use the same keys.
+		if err := json.Unmarshal(content, into); err != nil {
+			logrus.WithError(err).WithField("artifact", artifact.CanonicalLink()).Info("Error parsing metadata.")
+			continue
+		}
+		normalized, err := json.Marshal(into)
+		if err != nil {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(normalized, &fields); err != nil {
+			continue
+		}
+		flattenMetadata(strings.TrimSuffix(name, ".json"), fields, res)
+	}
+	return res
+}
+
+func flattenMetadata(prefix string, fields map[string]interface{}, into map[string]string) {
+	for key, value := range fields {
+		key = prefix + "." + key
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenMetadata(key, v, into)
+		case string:
+			into[key] = v
+		case nil:
+			// Unset fields are left out.
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			into[key] = string(b)
+		}
+	}
+}
+
+// compareMetadata lists the keys that have different values, sorted by key.
+func compareMetadata(a, b map[string]string) []MetadataDifference {
+	keys := sets.NewString()
+	for key := range a {
+		keys.Insert(key)
+	}
+	for key := range b {
+		keys.Insert(key)
+	}
+	var res []MetadataDifference
+	for _, key := range keys.List() {
+		if a[key] != b[key] {
+			res = append(res, MetadataDifference{Key: key, A: a[key], B: b[key]})
+		}
+	}
+	return res
+}
+
+// readBuildLog returns the last lines of the build log and whether the
+// beginning of it was cut off.
+func readBuildLog(artifacts []api.Artifact) ([]string, bool) {
+	artifact := findArtifact(artifacts, singleLogName)
+	if artifact == nil {
+		return nil, false
+	}
+	var truncated bool
+	var content []byte
+	size, err := artifact.Size()
+	if err == nil && size > maxCompareLogBytes {
+		truncated = true
+		content, err = artifact.ReadTail(maxCompareLogBytes)
+	} else if err == nil {
+		content, err = artifact.ReadAll()
+	}
+	if errors.Is(err, lenses.ErrGzipOffsetRead) {
+		truncated = false
+		content, err = artifact.ReadAll()
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("artifact", artifact.CanonicalLink()).Warn("Error reading build log.")
+		return nil, false
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if truncated && len(lines) > 0 {
+		// The first line is likely cut off.
+		lines = lines[1:]
+	}
+	if len(lines) > maxCompareLogLines {
+		truncated = true
+		lines = lines[len(lines)-maxCompareLogLines:]
+	}
+	return lines, truncated
+}
+
+// stripTimestamps removes timestamps from a build log line, so that lines
+// that were logged at different times align.
+func stripTimestamps(line string) string {
+	line = timestampRegexes[0].ReplaceAllString(line, "$1 ")
+	line = timestampRegexes[1].ReplaceAllString(line, "")
+	return strings.TrimSpace(line)
+}
+
+// compareBuildLogs aligns the lines of a and b after stripping timestamps and
+// returns the hunks that contain changes.
+func compareBuildLogs(a, b []string) BuildLogComparison {
+	strippedA := make([]string, len(a))
+	for i, line := range a {
+		strippedA[i] = stripTimestamps(line)
+	}
+	strippedB := make([]string, len(b))
+	for i, line := range b {
+		strippedB[i] = stripTimestamps(line)
+	}
+
+	var res BuildLogComparison
+	matcher := difflib.NewMatcher(strippedA, strippedB)
+	for _, group := range matcher.GetGroupedOpCodes(compareLogContext) {
+		hunk := BuildLogHunk{ALine: group[0].I1 + 1, BLine: group[0].J1 + 1}
+		for _, op := range group {
+			rowsA, rowsB := a[op.I1:op.I2], b[op.J1:op.J2]
+			rows := len(rowsA)
+			if len(rowsB) > rows {
+				rows = len(rowsB)
+			}
+			for i := 0; i < rows; i++ {
+				row := BuildLogRow{Changed: op.Tag != 'e'}
+				if i < len(rowsA) {
+					row.A = rowsA[i]
+				}
+				if i < len(rowsB) {
+					row.B = rowsB[i]
+				}
+				hunk.Rows = append(hunk.Rows, row)
+			}
+		}
+		res.Hunks = append(res.Hunks, hunk)
+	}
+	return res
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+)
+
+func junitArtifact(results ...string) api.Artifact {
+	var cases string
+	for _, result := range results {
+		name, status := result, ""
+		if i := strings.Index(result, ":"); i >= 0 {
+			name, status = result[:i], result[i+1:]
+		}
+		switch status {
+		case "failed":
+			cases += `<testcase classname="pkg" name="` + name + `"><failure>boom</failure></testcase>`
+		case "skipped":
+			cases += `<testcase classname="pkg" name="` + name + `"><skipped/></testcase>`
+		default:
+			cases += `<testcase classname="pkg" name="` + name + `"></testcase>`
+		}
+	}
+	return &fake.Artifact{
+		Path:    "artifacts/junit_01.xml",
+		Content: []byte(`<testsuites><testsuite name="suite">` + cases + `</testsuite></testsuites>`),
+	}
+}
+
+func TestCompareArtifacts(t *testing.T) {
+	a := []api.Artifact{
+		junitArtifact("TestA", "TestB:failed", "TestC", "TestD:failed", "TestD", "TestE"),
+		&fake.Artifact{
+			Path:    "started.json",
+			Content: []byte(`{"timestamp": 1654084800, "repo-version": "abc", "metadata": {"infra-commit": "111"}}`),
+		},
+		&fake.Artifact{
+			Path:    "finished.json",
+			Content: []byte(`{"timestamp": 1654085400, "passed": true, "result": "SUCCESS"}`),
+		},
+		&fake.Artifact{
+			Path: "build-log.txt",
+			Content: []byte(strings.Join([]string{
+				"2022-06-01T12:00:00Z building",
+				"I0601 12:00:01.000000    1234 main.go:10] starting tests",
+				"step 1",
+				"step 2",
+				"step 3",
+				"step 4",
+				"step 5",
+				"tests passed",
+				"done at 12:10:00",
+			}, "\n")),
+		},
+	}
+	b := []api.Artifact{
+		junitArtifact("TestA:failed", "TestB", "TestC", "TestD", "TestE:failed", "TestE"),
+		&fake.Artifact{
+			Path:    "started.json",
+			Content: []byte(`{"timestamp": 1654171200, "repo-version": "def", "metadata": {"infra-commit": "111"}}`),
+		},
+		&fake.Artifact{
+			Path:    "finished.json",
+			Content: []byte(`{"timestamp": 1654171900, "passed": false, "result": "FAILURE"}`),
+		},
+		&fake.Artifact{
+			Path: "build-log.txt",
+			Content: []byte(strings.Join([]string{
+				"2022-06-02T12:00:00Z building",
+				"I0602 12:00:05.000000    5678 main.go:10] starting tests",
+				"step 1",
+				"step 2",
+				"step 3",
+				"step 4",
+				"step 5",
+				"TestA failed",
+				"tests failed",
+				"done at 12:11:40",
+			}, "\n")),
+		},
+	}
+
+	expected := Comparison{
+		Tests: TestsComparison{
+			NewlyFailing: []string{"pkg TestA"},
+			NewlyPassing: []string{"pkg TestB"},
+			NewlyFlaky:   []string{"pkg TestE"},
+		},
+		Metadata: []MetadataDifference{
+			{Key: "finished.passed", A: "true", B: "false"},
+			{Key: "finished.result", A: "SUCCESS", B: "FAILURE"},
+			{Key: "finished.timestamp", A: "1654085400", B: "1654171900"},
+			{Key: "started.repo-version", A: "abc", B: "def"},
+			{Key: "started.timestamp", A: "1654084800", B: "1654171200"},
+		},
+		BuildLog: BuildLogComparison{
+			Hunks: []BuildLogHunk{{
+				ALine: 5,
+				BLine: 5,
+				Rows: []BuildLogRow{
+					{A: "step 3", B: "step 3"},
+					{A: "step 4", B: "step 4"},
+					{A: "step 5", B: "step 5"},
+					{Changed: true, A: "tests passed", B: "TestA failed"},
+					{Changed: true, B: "tests failed"},
+					{A: "done at 12:10:00", B: "done at 12:11:40"},
+				},
+			}},
+		},
+	}
+	if diff := cmp.Diff(expected, compareArtifacts(a, b)); diff != "" {
+		t.Errorf("Comparison differs from expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareIdenticalBuildLogs(t *testing.T) {
+	log := []string{"2022-06-01 12:00:00 one", "two", "three"}
+	other := []string{"2022-06-02 13:00:00 one", "two", "three"}
+	if got := compareBuildLogs(log, other); len(got.Hunks) != 0 {
+		t.Errorf("Expected no hunks for logs that only differ in timestamps, got %+v", got.Hunks)
+	}
+	if got := compareBuildLogs(nil, nil); len(got.Hunks) != 0 {
+		t.Errorf("Expected no hunks for missing logs, got %+v", got.Hunks)
+	}
+}
+
+func TestStripTimestamps(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected string
+	}{
+		{
+			line:     "2022-06-01T12:00:00.123Z building",
+			expected: "building",
+		},
+		{
+			line:     "2022/06/01 12:00:00 building",
+			expected: "building",
+		},
+		{
+			line:     "I0601 12:00:01.000000    1234 main.go:10] starting",
+			expected: "I main.go:10] starting",
+		},
+		{
+			line:     `{"level":"info","time":"2022-06-01T12:00:00+02:00","msg":"done"}`,
+			expected: `{"level":"info","time":"","msg":"done"}`,
+		},
+		{
+			line:     "no timestamp",
+			expected: "no timestamp",
+		},
+	}
+	for _, tc := range testCases {
+		if got := stripTimestamps(tc.line); got != tc.expected {
+			t.Errorf("stripTimestamps(%q) = %q, expected %q", tc.line, got, tc.expected)
+		}
+	}
+}
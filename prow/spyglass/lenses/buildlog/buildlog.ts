@@ -99,6 +99,71 @@ async function replaceElementWithContent(element: HTMLDivElement, top: number, b
   spyglass.contentUpdated();
 }
 
+interface StreamUpdate {
+  html: string;
+  offset: number;
+  startLine: number;
+}
+
+// The streams of live logs by artifact, they are paused while all lines are loaded.
+const streams = new Map<string, EventSource>();
+const streamRetryDelay = 5000;
+const maxStreamRetries = 5;
+
+function bindSkippedGroups(): void {
+  for (const button of Array.from(document.querySelectorAll<HTMLDivElement>(".show-skipped:not(.showable)"))) {
+    button.addEventListener('click', handleShowSkipped);
+    button.classList.add("showable");
+  }
+}
+
+// followLog appends the lines of a live log to its content as they are written.
+function followLog(content: HTMLElement, retries = 0): void {
+  const {artifact, streamOffset, streamLine} = content.dataset;
+  const source = spyglass.stream(JSON.stringify({artifact, offset: Number(streamOffset), startLine: Number(streamLine)}));
+  streams.set(artifact, source);
+  source.addEventListener('lines', (e: MessageEvent) => {
+    retries = 0;
+    const update: StreamUpdate = JSON.parse(e.data);
+    content.dataset.streamOffset = String(update.offset);
+    content.dataset.streamLine = String(update.startLine);
+    content.insertAdjacentHTML('beforeend', ansiToHTML(update.html));
+    bindSkippedGroups();
+    fixLinks(content);
+    spyglass.contentUpdated();
+  });
+  source.addEventListener('done', (e: MessageEvent) => {
+    if (e.data) {
+      console.log("Stopped following log", artifact, e.data);
+    }
+    stopFollowing(artifact);
+    const live = document.getElementById(`${artifact}-live`);
+    if (live) {
+      live.classList.add('ended');
+      live.textContent = 'Ended';
+      live.title = e.data ? `Stopped following the log: ${e.data}` : 'The log is complete';
+    }
+  });
+  source.onerror = () => {
+    // The EventSource would reconnect from the offset it was opened with, so
+    // reconnect from the end of the shown lines instead.
+    stopFollowing(artifact);
+    if (retries < maxStreamRetries) {
+      setTimeout(() => followLog(content, retries + 1), streamRetryDelay);
+    }
+  };
+}
+
+function stopFollowing(artifact: string): boolean {
+  const source = streams.get(artifact);
+  if (!source) {
+    return false;
+  }
+  source.close();
+  streams.delete(artifact);
+  return true;
+}
+
 async function handleShowSkipped(this: HTMLDivElement, e: MouseEvent): Promise<void> {
   // Don't do anything unless they actually clicked the button.
   let target: HTMLButtonElement;
@@ -140,9 +205,27 @@ async function handleShowAll(this: HTMLButtonElement) {
   }
 
   const {artifact} = this.dataset;
-  const content = await spyglass.request(JSON.stringify({artifact, offset: 0, length: -1}));
-  document.getElementById(`${artifact}-content`)!.innerHTML = `<tbody class="shown">${ansiToHTML(content)}</tbody>`;
+  const log = document.getElementById(`${artifact}-content`)!;
+  // Only load the lines of a live log that were streamed so far, and continue
+  // the stream after them.
+  const following = stopFollowing(artifact);
+  let length = -1;
+  if (log.dataset.live !== undefined) {
+    const streamOffset = Number(log.dataset.streamOffset);
+    if (streamOffset === 0) {
+      if (following) {
+        followLog(log);
+      }
+      return;
+    }
+    length = streamOffset - 1;
+  }
+  const content = await spyglass.request(JSON.stringify({artifact, offset: 0, length}));
+  log.innerHTML = `<tbody class="shown">${ansiToHTML(content)}</tbody>`;
   spyglass.contentUpdated();
+  if (following) {
+    followLog(log);
+  }
 }
 
 async function handleAnalyze(this: HTMLButtonElement) {
@@ -418,6 +501,10 @@ window.addEventListener('load', () => {
   for (const container of Array.from(document.querySelectorAll<HTMLElement>('.loglines'))) {
     container.addEventListener('click', handleLineLink, {capture: true});
   }
+
+  for (const content of Array.from(document.querySelectorAll<HTMLElement>('.loglines[data-live]'))) {
+    followLog(content);
+  }
   fixLinks(document.documentElement);
 
   handleHash();
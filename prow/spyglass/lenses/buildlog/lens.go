@@ -18,7 +18,9 @@ limitations under the License.
 package buildlog
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,6 +32,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -48,6 +51,11 @@ const (
 	maxHighlightLength = 10000 // Maximum length of a line worth highlighting
 )
 
+const (
+	streamInterval = time.Second // how often lines appended to a live log are sent
+	maxStreamLines = 1000        // number of appended lines that are sent right away
+)
+
 type config struct {
 	HighlightRegexes []string         `json:"highlight_regexes"`
 	HideRawLog       bool             `json:"hide_raw_log,omitempty"`
@@ -71,7 +79,7 @@ type parsedConfig struct {
 	highlighter    *highlightConfig
 }
 
-var _ api.Lens = Lens{}
+var _ api.StreamingLens = Lens{}
 
 // Lens implements the build lens.
 type Lens struct{}
@@ -149,6 +157,22 @@ func (g LineGroup) LinesSkipped() int {
 	return g.End - g.Start
 }
 
+// streamRequest represents a request to follow a live log from the end of
+// the lines that are shown.
+type streamRequest struct {
+	Artifact  string `json:"artifact"`
+	Offset    int64  `json:"offset"`
+	StartLine int    `json:"startLine"`
+}
+
+// streamUpdate holds the lines appended to a live log and where to continue
+// following it from.
+type streamUpdate struct {
+	HTML      string `json:"html"`
+	Offset    int64  `json:"offset"`
+	StartLine int    `json:"startLine"`
+}
+
 // LogArtifactView holds a single log file's view
 type LogArtifactView struct {
 	ArtifactName string
@@ -158,6 +182,11 @@ type LogArtifactView struct {
 	ShowRawLog   bool
 	CanSave      bool
 	CanAnalyze   bool
+	// Live is set for logs that are still appended to, they are followed
+	// from StreamOffset and StreamLine.
+	Live         bool
+	StreamOffset int64
+	StreamLine   int
 }
 
 // buildLogsView holds each log file view
@@ -218,6 +247,15 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 			logrus.WithError(err).Info("Error reading log.")
 			continue
 		}
+		if streamingArtifact, ok := a.(api.StreamingArtifact); ok && streamingArtifact.Live() {
+			// Only show complete lines, the rest is streamed.
+			lines = lines[:len(lines)-1]
+			av.Live = true
+			av.StreamLine = len(lines)
+			for _, line := range lines {
+				av.StreamOffset += int64(len(line) + 1)
+			}
+		}
 		artifact := av.ArtifactName
 		meta, _ := a.Metadata()
 		start, end := -1, -1
@@ -295,6 +333,122 @@ func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data str
 	return loadLines(&request, artifact, resourceDir, rawConfig)
 }
 
+// Stream sends the lines that are appended to a live log as "lines" events.
+func (lens Lens) Stream(ctx context.Context, send func(event, data string) error, artifacts []api.Artifact, resourceDir string, data string, rawConfig json.RawMessage, spyglassConfig prowconfig.Spyglass) error {
+	var request streamRequest
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		return errors.New(failedUnmarshal)
+	}
+	artifact, ok := artifactByName(artifacts, request.Artifact)
+	if !ok {
+		return fmt.Errorf(missingArtifact, request.Artifact)
+	}
+	streamingArtifact, ok := artifact.(api.StreamingArtifact)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	log, err := streamingArtifact.Stream(ctx, request.Offset)
+	if err != nil {
+		return fmt.Errorf("failed to stream log %q: %w", request.Artifact, err)
+	}
+	defer log.Close()
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		readErr <- readLines(ctx, log, lines)
+	}()
+
+	conf := getConfig(rawConfig)
+	var pending []string
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		update := streamedLines(&request, pending, resourceDir, conf.highlightRegex)
+		pending = nil
+		buf, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		return send("lines", string(buf))
+	}
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+				return <-readErr
+			}
+			pending = append(pending, line)
+			if len(pending) >= maxStreamLines {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// readLines sends the lines of r including their newline, the last line may
+// lack it.
+func readLines(ctx context.Context, r io.Reader, lines chan<- string) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read log: %w", err)
+		}
+	}
+}
+
+// streamedLines renders the line groups of lines appended to a live log and
+// advances the request past them.
+func streamedLines(request *streamRequest, rawLines []string, resourceDir string, highlightRegex *regexp.Regexp) streamUpdate {
+	lines := make([]string, 0, len(rawLines))
+	for _, line := range rawLines {
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	groups := groupLines(&request.Artifact, -1, -1, highlightLines(lines, request.StartLine, &request.Artifact, highlightRegex)...)
+	for i := range groups {
+		groups[i].Start += request.StartLine
+		groups[i].End += request.StartLine
+		groups[i].ByteOffset += request.Offset
+	}
+	for _, line := range rawLines {
+		request.Offset += int64(len(line))
+	}
+	request.StartLine += len(lines)
+	return streamUpdate{
+		HTML:      executeTemplate(resourceDir, "line groups", groups),
+		Offset:    request.Offset,
+		StartLine: request.StartLine,
+	}
+}
+
 type highlightRequest struct {
 	// URL to highlight
 	URL string `json:"url"`
@@ -17,8 +17,10 @@ limitations under the License.
 package buildlog
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -948,6 +950,86 @@ func TestCallback(t *testing.T) {
 	}
 }
 
+// liveArtifact is a log that is appended to while the job runs.
+type liveArtifact struct {
+	fake.Artifact
+	appended string
+}
+
+func (a *liveArtifact) Live() bool {
+	return true
+}
+
+func (a *liveArtifact) Stream(_ context.Context, off int64) (io.ReadCloser, error) {
+	content := string(a.Content) + a.appended
+	return ioutil.NopCloser(strings.NewReader(content[off:])), nil
+}
+
+func TestBodyLive(t *testing.T) {
+	artifact := &liveArtifact{Artifact: fake.Artifact{Path: "foo", Content: []byte("a\nb\npart")}}
+	got := Lens{}.Body([]api.Artifact{artifact}, ".", "", nil, prowconfig.Spyglass{})
+	for _, expected := range []string{`data-live`, `data-stream-offset="4"`, `data-stream-line="2"`, `id="foo-live"`, `id="foo:2"`} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected body to contain %s, got:\n%s", expected, got)
+		}
+	}
+	if strings.Contains(got, "part") {
+		t.Errorf("expected the incomplete last line to be streamed instead of shown, got:\n%s", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	artifact := &liveArtifact{
+		Artifact: fake.Artifact{Path: "foo", Content: []byte("a\nb\npart")},
+		appended: "ial\nERROR: boom\nend",
+	}
+	type event struct {
+		name   string
+		update streamUpdate
+	}
+	var events []event
+	send := func(name, data string) error {
+		e := event{name: name}
+		if err := json.Unmarshal([]byte(data), &e.update); err != nil {
+			t.Errorf("failed to unmarshal %s event: %v", name, err)
+		}
+		events = append(events, e)
+		return nil
+	}
+	err := Lens{}.Stream(context.Background(), send, []api.Artifact{artifact}, ".", `{"artifact": "foo", "offset": 4, "startLine": 2}`, nil, prowconfig.Spyglass{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %+v", events)
+	}
+	if events[0].name != "lines" || events[0].update.Offset != 27 || events[0].update.StartLine != 5 {
+		t.Errorf("unexpected event %s with offset %d and start line %d", events[0].name, events[0].update.Offset, events[0].update.StartLine)
+	}
+	for _, expected := range []string{`id="foo:3"`, `id="foo:4"`, `id="foo:5"`, `class="match-highlighted">ERROR:</span>`, `>partial<`} {
+		if !strings.Contains(events[0].update.HTML, expected) {
+			t.Errorf("expected streamed lines to contain %s, got:\n%s", expected, events[0].update.HTML)
+		}
+	}
+
+	if err := (Lens{}).Stream(context.Background(), send, []api.Artifact{&fake.Artifact{Path: "foo"}}, ".", `{"artifact": "bar"}`, nil, prowconfig.Spyglass{}); err == nil {
+		t.Error("expected an error for a missing artifact")
+	}
+}
+
+func TestStreamedLines(t *testing.T) {
+	request := streamRequest{Artifact: "foo", Offset: 100, StartLine: 10}
+	update := streamedLines(&request, []string{"l0\n", "l1\n", "l2\n", "l3\n", "l4\n", "l5\n"}, ".", defaultErrRE)
+	if update.Offset != 118 || update.StartLine != 16 || request.Offset != 118 || request.StartLine != 16 {
+		t.Errorf("expected the stream to continue at offset 118 and line 16, got %+v and %+v", update, request)
+	}
+	// The lines are skipped as one group that can be loaded from its absolute position.
+	expected := `data-offset="100" data-length="17" data-start-line="10" data-end-line="16"`
+	if !strings.Contains(update.HTML, expected) {
+		t.Errorf("expected streamed lines to contain %s, got:\n%s", expected, update.HTML)
+	}
+}
+
 func marshalHighlightResponse(t *testing.T, hr highlightResponse) string {
 	b, err := json.Marshal(hr)
 	if err != nil {
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
@@ -143,6 +144,37 @@ func newLensHandler(lens api.Lens, opts lensHandlerOpts) http.HandlerFunc {
 		case api.RequestActionCallBack:
 			w.Write([]byte(lens.Callback(artifacts, opts.LensResourcesDir, request.Data, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass)))
 
+		case api.RequestActionStream:
+			streamingLens, ok := lens.(api.StreamingLens)
+			if !ok {
+				writeHTTPError(w, fmt.Errorf("lens %q does not support streaming", opts.LensName), http.StatusBadRequest)
+				return
+			}
+			flusher, ok := w.(http.Flusher)
+			if !ok {
+				writeHTTPError(w, errors.New("streaming is not supported by the response writer"), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			send := func(event, data string) error {
+				if err := writeEvent(w, event, data); err != nil {
+					return err
+				}
+				flusher.Flush()
+				return nil
+			}
+			// The front-end stops listening once it receives the done event,
+			// whose data is the error that ended the stream, if any.
+			var done string
+			if err := streamingLens.Stream(r.Context(), send, artifacts, opts.LensResourcesDir, request.Data, opts.ConfigGetter().Deck.Spyglass.Lenses[request.LensIndex].Lens.Config, opts.ConfigGetter().Deck.Spyglass); err != nil {
+				logrus.WithError(err).WithField("lens", opts.LensName).Debug("Stream ended with an error")
+				done = err.Error()
+			}
+			if err := send("done", done); err != nil {
+				logrus.WithError(err).Debug("Failed to end stream")
+			}
+
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 			// This is a bit weird as we proxy this and the request we are complaining about was issued by Deck, not by the original client that sees this error
@@ -162,6 +194,19 @@ func writeHTTPError(w http.ResponseWriter, err error, statusCode int) {
 	}
 }
 
+// writeEvent writes a server-sent event, see
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+func writeEvent(w io.Writer, event, data string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
 // ArtifactFetcher knows how to fetch artifacts
 type ArtifactFetcher interface {
 	Artifact(ctx context.Context, key string, artifactName string, sizeLimit int64) (api.Artifact, error)
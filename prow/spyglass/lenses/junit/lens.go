@@ -137,6 +137,12 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 	return buf.String()
 }
 
+// Results groups the tests of the junit artifacts into passed, failed,
+// skipped and flaky tests.
+func Results(artifacts []api.Artifact) JVD {
+	return Lens{}.getJvd(artifacts)
+}
+
 func (lens Lens) getJvd(artifacts []api.Artifact) JVD {
 	type testResults struct {
 		// Group results based on their full path name
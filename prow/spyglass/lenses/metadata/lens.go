@@ -37,6 +37,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	k8sreporter "k8s.io/test-infra/prow/crier/reporters/gcs/kubernetes"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/sidecar"
 	"k8s.io/test-infra/prow/spyglass/api"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
@@ -93,9 +94,11 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 		Errored      bool
 		Elapsed      time.Duration
 		Hint         string
+		Phases       []phaseRow
 		Metadata     map[string]interface{}
 	}
 	metadataViewData := MetadataViewData{}
+	var phasesHint string
 	started := gcs.Started{}
 	finished := gcs.Finished{}
 	for _, a := range artifacts {
@@ -103,6 +106,14 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 		if err != nil {
 			logrus.WithError(err).Error("Failed reading from artifact.")
 		}
+		if m := phasesFileRegex.FindStringSubmatch(a.JobPath()); m != nil {
+			rows, hint := phasesFromFile(m[1], read)
+			metadataViewData.Phases = append(metadataViewData.Phases, rows...)
+			if phasesHint == "" {
+				phasesHint = hint
+			}
+			continue
+		}
 		switch a.JobPath() {
 		case prowv1.StartedStatusFile:
 			if len(read) > 0 {
@@ -143,6 +154,15 @@ func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string,
 		}
 	}
 
+	// The pod and prowjob based hints are about the job not running at all,
+	// so they are more useful than the phase the test process timed out in.
+	if metadataViewData.Hint == "" {
+		metadataViewData.Hint = hintFromOOMKills(finished.Metadata)
+	}
+	if metadataViewData.Hint == "" {
+		metadataViewData.Hint = phasesHint
+	}
+
 	if !metadataViewData.StartTime.IsZero() {
 		if metadataViewData.FinishedTime.IsZero() {
 			metadataViewData.Elapsed = time.Since(metadataViewData.StartTime)
@@ -280,6 +300,61 @@ func hintFromProwJob(buf []byte) (string, bool) {
 	return "", false
 }
 
+// hintFromOOMKills reports the containers in which sidecar saw processes
+// being killed for running out of memory.
+func hintFromOOMKills(m metadata.Metadata) string {
+	containers, ok := m[sidecar.OOMKilledKey].(string)
+	if !ok || containers == "" {
+		return ""
+	}
+	return fmt.Sprintf("Processes were killed for running out of memory in the following containers: %s. Check the memory limits of the job.", strings.ReplaceAll(containers, ",", ", "))
+}
+
+// phasesFileRegex matches the phases files written by entrypoint, which are
+// prefixed with the container name if the job has more than one container.
+var phasesFileRegex = regexp.MustCompile(`^artifacts/(?:(.+)-)?phases\.json$`)
+
+// phaseRow is a phase of the test process shown in the metadata table.
+type phaseRow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// phasesFromFile returns the phases in a phases file and a hint if the test
+// process timed out or was aborted during one of them.
+func phasesFromFile(container string, buf []byte) ([]phaseRow, string) {
+	var phases entrypoint.Phases
+	if err := json.Unmarshal(buf, &phases); err != nil {
+		logrus.WithError(err).Info("Failed to decode phases file")
+		return nil, ""
+	}
+
+	var rows []phaseRow
+	for _, p := range phases.Phases {
+		name := p.Name
+		if container != "" {
+			name = fmt.Sprintf("%s: %s", container, name)
+		}
+		rows = append(rows, phaseRow{Name: name, Duration: p.Duration().Round(time.Second)})
+	}
+
+	last := phases.Last()
+	if last == nil {
+		return rows, ""
+	}
+	in := fmt.Sprintf("the %q phase", last.Name)
+	if container != "" {
+		in = fmt.Sprintf("the %q phase of the %s container", last.Name, container)
+	}
+	switch {
+	case phases.TimedOut:
+		return rows, fmt.Sprintf("The job timed out after %s during %s.", phases.Timeout, in)
+	case phases.Aborted:
+		return rows, fmt.Sprintf("The job was aborted during %s.", in)
+	}
+	return rows, ""
+}
+
 // flattenMetadata flattens the metadata for use by Body.
 func (lens Lens) flattenMetadata(metadata map[string]interface{}) map[string]string {
 	results := map[string]string{}
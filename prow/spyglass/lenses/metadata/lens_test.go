@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -28,6 +29,7 @@ import (
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 
 	k8sreporter "k8s.io/test-infra/prow/crier/reporters/gcs/kubernetes"
+	"k8s.io/test-infra/prow/entrypoint"
 )
 
 func TestFlattenMetadata(t *testing.T) {
@@ -609,3 +611,87 @@ func TestHintFromProwJob(t *testing.T) {
 		})
 	}
 }
+
+func TestHintFromOOMKills(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "no OOM kills reports nothing",
+			metadata: map[string]interface{}{"node": "node-1"},
+		},
+		{
+			name:     "OOM kills are reported by container",
+			metadata: map[string]interface{}{"oom-killed": "test,e2e"},
+			expected: "Processes were killed for running out of memory in the following containers: test, e2e. Check the memory limits of the job.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if hint := hintFromOOMKills(tc.metadata); hint != tc.expected {
+				t.Errorf("Expected hint %q, but got %q", tc.expected, hint)
+			}
+		})
+	}
+}
+
+func TestPhasesFromFile(t *testing.T) {
+	start := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	phases := entrypoint.Phases{
+		Start:   start,
+		End:     start.Add(time.Hour),
+		Timeout: time.Hour,
+		Phases: []entrypoint.Phase{
+			{Name: "build", Start: start, End: start.Add(10 * time.Minute)},
+			{Name: "test", Start: start.Add(10 * time.Minute), End: start.Add(time.Hour)},
+		},
+	}
+	tests := []struct {
+		name         string
+		container    string
+		timedOut     bool
+		aborted      bool
+		expectedRows []phaseRow
+		expected     string
+	}{
+		{
+			name:         "finished process reports nothing",
+			expectedRows: []phaseRow{{Name: "build", Duration: 10 * time.Minute}, {Name: "test", Duration: 50 * time.Minute}},
+		},
+		{
+			name:         "timed out process reports the last phase",
+			timedOut:     true,
+			expectedRows: []phaseRow{{Name: "build", Duration: 10 * time.Minute}, {Name: "test", Duration: 50 * time.Minute}},
+			expected:     `The job timed out after 1h0m0s during the "test" phase.`,
+		},
+		{
+			name:         "aborted process reports the last phase and the container",
+			container:    "e2e",
+			aborted:      true,
+			expectedRows: []phaseRow{{Name: "e2e: build", Duration: 10 * time.Minute}, {Name: "e2e: test", Duration: 50 * time.Minute}},
+			expected:     `The job was aborted during the "test" phase of the e2e container.`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := phases
+			p.TimedOut = tc.timedOut
+			p.Aborted = tc.aborted
+			b, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("Unexpected failed to marshal phases to JSON: %v", err)
+			}
+			rows, hint := phasesFromFile(tc.container, b)
+			if diff := cmp.Diff(tc.expectedRows, rows); diff != "" {
+				t.Errorf("Unexpected phases (-want, +got):\n%s", diff)
+			}
+			if hint != tc.expected {
+				t.Errorf("Expected hint %q, but got %q", tc.expected, hint)
+			}
+		})
+	}
+}
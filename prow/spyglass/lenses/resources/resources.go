@@ -0,0 +1,253 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resources provides a viewer for the resource usage of test containers for Spyglass
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/sidecar"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "resources"
+	title    = "Resource Usage"
+	priority = 15
+
+	chartWidth  = 600
+	chartHeight = 120
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a resource usage-rendering Spyglass lens.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+// Body renders charts of the resource usage of every test container.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	if len(artifacts) != 1 {
+		logrus.Errorf("resources Body() called with %d artifacts, which should never happen.", len(artifacts))
+		return fmt.Sprintf("Expected one %s file, got %d.", sidecar.ResourceUsageFile, len(artifacts))
+	}
+	content, err := artifacts[0].ReadAll()
+	if err != nil {
+		logrus.WithError(err).Warn("Couldn't read a resource usage file that should exist.")
+		return fmt.Sprintf("Failed to read the resource usage file: %v", err)
+	}
+	var usage sidecar.ResourceUsage
+	if err := json.Unmarshal(content, &usage); err != nil {
+		logrus.WithError(err).Info("Failed to decode resource usage file")
+		return fmt.Sprintf("Failed to decode the resource usage file: %v", err)
+	}
+
+	var views []containerView
+	for _, c := range usage.Containers {
+		views = append(views, newContainerView(c))
+	}
+
+	resourcesTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := resourcesTemplate.ExecuteTemplate(&buf, "body", views); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// containerView is the view of the resource usage of a test container.
+type containerView struct {
+	Name       string
+	Samples    int
+	Elapsed    time.Duration
+	CPUSeconds string
+	PeakMemory string
+	IORead     string
+	IOWrite    string
+	OOMKills   uint64
+	Charts     []chart
+}
+
+// chart is a line chart drawn as an SVG.
+type chart struct {
+	Title string
+	// Max is the label of the top of the chart.
+	Max    string
+	Width  int
+	Height int
+	Lines  []line
+}
+
+// line is a series of a chart, Points are SVG polyline points.
+type line struct {
+	Class  string
+	Label  string
+	Points string
+}
+
+type point struct {
+	at    time.Duration
+	value float64
+}
+
+// series are the points of a line.
+type series struct {
+	line
+	points []point
+}
+
+func newContainerView(c sidecar.ContainerUsage) containerView {
+	view := containerView{
+		Name:       c.Name,
+		Samples:    len(c.Samples),
+		PeakMemory: formatBytes(float64(c.PeakMemoryBytes)),
+		OOMKills:   c.OOMKills,
+	}
+	if len(c.Samples) == 0 {
+		return view
+	}
+	first, last := c.Samples[0], c.Samples[len(c.Samples)-1]
+	view.Elapsed = last.Time.Sub(first.Time).Round(time.Second)
+	view.CPUSeconds = fmt.Sprintf("%.1fs", last.CPUSeconds)
+	view.IORead = formatBytes(float64(last.IOReadBytes))
+	view.IOWrite = formatBytes(float64(last.IOWriteBytes))
+
+	var cpu, memory, read, written []point
+	for i, s := range c.Samples {
+		at := s.Time.Sub(first.Time)
+		memory = append(memory, point{at: at, value: float64(s.MemoryBytes)})
+		if i == 0 {
+			continue
+		}
+		prev := c.Samples[i-1]
+		seconds := s.Time.Sub(prev.Time).Seconds()
+		if seconds <= 0 {
+			continue
+		}
+		cpu = append(cpu, point{at: at, value: (s.CPUSeconds - prev.CPUSeconds) / seconds})
+		read = append(read, point{at: at, value: rate(prev.IOReadBytes, s.IOReadBytes, seconds)})
+		written = append(written, point{at: at, value: rate(prev.IOWriteBytes, s.IOWriteBytes, seconds)})
+	}
+
+	elapsed := last.Time.Sub(first.Time)
+	formatCores := func(v float64) string { return fmt.Sprintf("%.2f cores", v) }
+	formatRate := func(v float64) string { return formatBytes(v) + "/s" }
+	view.Charts = []chart{
+		newChart("CPU", elapsed, formatCores, series{line{Class: "cpu", Label: "used"}, cpu}),
+		newChart("Memory", elapsed, formatBytes, series{line{Class: "memory", Label: "used"}, memory}),
+		newChart("Disk IO", elapsed, formatRate, series{line{Class: "read", Label: "read"}, read}, series{line{Class: "write", Label: "written"}, written}),
+	}
+	return view
+}
+
+// rate returns how fast a counter grew, which only shrinks if the container
+// was restarted.
+func rate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// newChart scales the series to the chart, which spans the elapsed time and
+// the largest value of all series.
+func newChart(title string, elapsed time.Duration, format func(float64) string, series ...series) chart {
+	c := chart{Title: title, Width: chartWidth, Height: chartHeight}
+	var max float64
+	for _, s := range series {
+		for _, p := range s.points {
+			if p.value > max {
+				max = p.value
+			}
+		}
+	}
+	c.Max = format(max)
+	for _, s := range series {
+		var points []string
+		for _, p := range s.points {
+			var x float64
+			if elapsed > 0 {
+				x = chartWidth * float64(p.at) / float64(elapsed)
+			}
+			y := float64(chartHeight)
+			if max > 0 {
+				y = chartHeight * (1 - p.value/max)
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		l := s.line
+		l.Points = strings.Join(points, " ")
+		c.Lines = append(c.Lines, l)
+	}
+	return c
+}
+
+// formatBytes formats a number of bytes with a binary unit.
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", b, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/sidecar"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+)
+
+var start = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestNewContainerView(t *testing.T) {
+	usage := sidecar.ContainerUsage{
+		Name:            "test",
+		PeakMemoryBytes: 3 << 30,
+		OOMKills:        1,
+		Samples: []sidecar.ResourceSample{
+			{Time: start, CPUSeconds: 1, MemoryBytes: 1 << 30},
+			{Time: start.Add(10 * time.Second), CPUSeconds: 21, MemoryBytes: 2 << 30, IOReadBytes: 10 << 20},
+			{Time: start.Add(20 * time.Second), CPUSeconds: 31, MemoryBytes: 1 << 30, IOReadBytes: 10 << 20, IOWriteBytes: 5 << 20},
+		},
+	}
+	expected := containerView{
+		Name:       "test",
+		Samples:    3,
+		Elapsed:    20 * time.Second,
+		CPUSeconds: "31.0s",
+		PeakMemory: "3.0 GiB",
+		IORead:     "10.0 MiB",
+		IOWrite:    "5.0 MiB",
+		OOMKills:   1,
+		Charts: []chart{
+			{
+				Title: "CPU", Max: "2.00 cores", Width: chartWidth, Height: chartHeight,
+				Lines: []line{{Class: "cpu", Label: "used", Points: "300.0,0.0 600.0,60.0"}},
+			},
+			{
+				Title: "Memory", Max: "2.0 GiB", Width: chartWidth, Height: chartHeight,
+				Lines: []line{{Class: "memory", Label: "used", Points: "0.0,60.0 300.0,0.0 600.0,60.0"}},
+			},
+			{
+				Title: "Disk IO", Max: "1.0 MiB/s", Width: chartWidth, Height: chartHeight,
+				Lines: []line{
+					{Class: "read", Label: "read", Points: "300.0,0.0 600.0,120.0"},
+					{Class: "write", Label: "written", Points: "300.0,120.0 600.0,60.0"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, newContainerView(usage)); diff != "" {
+		t.Errorf("unexpected view (-want, +got):\n%s", diff)
+	}
+}
+
+func TestBody(t *testing.T) {
+	content, err := json.Marshal(sidecar.ResourceUsage{
+		Interval: 10 * time.Second,
+		Containers: []sidecar.ContainerUsage{
+			{
+				Name:     "test",
+				OOMKills: 2,
+				Samples: []sidecar.ResourceSample{
+					{Time: start, CPUSeconds: 1, MemoryBytes: 1024},
+					{Time: start.Add(10 * time.Second), CPUSeconds: 2, MemoryBytes: 2048},
+				},
+			},
+			{Name: "e2e"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal resource usage: %v", err)
+	}
+	body := Lens{}.Body([]api.Artifact{&fake.Artifact{Path: sidecar.ResourceUsageFile, Content: content}}, ".", "", nil, config.Spyglass{})
+	for _, expected := range []string{
+		"killed 2 time(s)",
+		`<polyline class="memory" points="0.0,60.0 600.0,0.0">`,
+		"No resource usage was sampled for this container.",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in body, got %s", expected, body)
+		}
+	}
+}
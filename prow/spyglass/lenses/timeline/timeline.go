@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package timeline provides a viewer for the phases of test processes for Spyglass
+package timeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/entrypoint"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "timeline"
+	title    = "Timeline"
+	priority = 3
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a timeline-rendering Spyglass lens.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	return ""
+}
+
+// Body renders a timeline for every phases file.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
+	var timelines []timeline
+	for _, a := range artifacts {
+		content, err := a.ReadAll()
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Warn("Couldn't read a phases file that should exist.")
+			continue
+		}
+		var phases entrypoint.Phases
+		if err := json.Unmarshal(content, &phases); err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Failed to decode phases file")
+			continue
+		}
+		timelines = append(timelines, newTimeline(containerName(a.JobPath()), phases))
+	}
+	sort.Slice(timelines, func(i, j int) bool {
+		return timelines[i].Container < timelines[j].Container
+	})
+
+	timelineTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := timelineTemplate.ExecuteTemplate(&buf, "body", timelines); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// phasesFileRegex matches the phases files written by entrypoint, which are
+// prefixed with the container name if the job has more than one container.
+var phasesFileRegex = regexp.MustCompile(`^artifacts/(.+)-phases\.json$`)
+
+func containerName(jobPath string) string {
+	if m := phasesFileRegex.FindStringSubmatch(jobPath); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// timeline is the view of a phases file.
+type timeline struct {
+	Container string
+	Elapsed   time.Duration
+	Timeout   time.Duration
+	TimedOut  bool
+	Aborted   bool
+	Phases    []bar
+}
+
+// bar is a phase, placed relative to the runtime of the test process.
+type bar struct {
+	Name     string
+	Duration time.Duration
+	// Offset and Width are percentages of the runtime of the test process.
+	Offset float64
+	Width  float64
+	// Last is set for the phase the test process exited in.
+	Last bool
+}
+
+func newTimeline(container string, phases entrypoint.Phases) timeline {
+	elapsed := phases.End.Sub(phases.Start)
+	t := timeline{
+		Container: container,
+		Elapsed:   elapsed.Round(time.Second),
+		Timeout:   phases.Timeout,
+		TimedOut:  phases.TimedOut,
+		Aborted:   phases.Aborted,
+	}
+	percent := func(d time.Duration) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return 100 * float64(d) / float64(elapsed)
+	}
+	for i, p := range phases.Phases {
+		t.Phases = append(t.Phases, bar{
+			Name:     p.Name,
+			Duration: p.Duration().Round(time.Second),
+			Offset:   percent(p.Start.Sub(phases.Start)),
+			Width:    percent(p.Duration()),
+			Last:     i == len(phases.Phases)-1,
+		})
+	}
+	return t
+}
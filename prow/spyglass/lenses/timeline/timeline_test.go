@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/entrypoint"
+	"k8s.io/test-infra/prow/spyglass/api"
+	"k8s.io/test-infra/prow/spyglass/lenses/fake"
+)
+
+var start = time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestNewTimeline(t *testing.T) {
+	phases := entrypoint.Phases{
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Timeout:  time.Hour,
+		TimedOut: true,
+		Phases: []entrypoint.Phase{
+			{Name: "build", Start: start.Add(15 * time.Minute), End: start.Add(30 * time.Minute)},
+			{Name: "test", Start: start.Add(30 * time.Minute), End: start.Add(time.Hour)},
+		},
+	}
+	expected := timeline{
+		Container: "e2e",
+		Elapsed:   time.Hour,
+		Timeout:   time.Hour,
+		TimedOut:  true,
+		Phases: []bar{
+			{Name: "build", Duration: 15 * time.Minute, Offset: 25, Width: 25},
+			{Name: "test", Duration: 30 * time.Minute, Offset: 50, Width: 50, Last: true},
+		},
+	}
+	if diff := cmp.Diff(expected, newTimeline("e2e", phases)); diff != "" {
+		t.Errorf("unexpected timeline (-want, +got):\n%s", diff)
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifact := func(path, container string) api.Artifact {
+		content, err := json.Marshal(entrypoint.Phases{
+			Start:   start,
+			End:     start.Add(time.Minute),
+			Timeout: time.Hour,
+			Phases:  []entrypoint.Phase{{Name: container + "-phase", Start: start, End: start.Add(time.Minute)}},
+		})
+		if err != nil {
+			t.Fatalf("failed to marshal phases: %v", err)
+		}
+		return &fake.Artifact{Path: path, Content: content}
+	}
+	body := Lens{}.Body([]api.Artifact{
+		artifact("artifacts/test-1-phases.json", "test-1"),
+		artifact("artifacts/test-0-phases.json", "test-0"),
+		&fake.Artifact{Path: "artifacts/broken-phases.json", Content: []byte("{")},
+	}, ".", "", nil, config.Spyglass{})
+
+	first, second := strings.Index(body, "test-0-phase"), strings.Index(body, "test-1-phase")
+	if first < 0 || second < 0 || second < first {
+		t.Errorf("expected the timelines of both containers in order, got %s", body)
+	}
+	if !strings.Contains(body, "width: 100.00%") {
+		t.Errorf("expected phases spanning the whole process, got %s", body)
+	}
+	if strings.Contains(body, "broken") {
+		t.Errorf("expected the broken phases file to be skipped, got %s", body)
+	}
+}
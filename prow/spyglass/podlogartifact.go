@@ -18,9 +18,11 @@ package spyglass
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -32,6 +34,12 @@ type jobAgent interface {
 	GetJobLog(job string, id string, container string) ([]byte, error)
 }
 
+// jobLogStreamer is implemented by job agents that can follow the log of a
+// running job.
+type jobLogStreamer interface {
+	StreamJobLog(ctx context.Context, job string, id string, container string) (io.ReadCloser, error)
+}
+
 // PodLogArtifact holds data for reading from a specific pod log
 type PodLogArtifact struct {
 	name         string
@@ -196,3 +204,33 @@ func (a *PodLogArtifact) Metadata() (map[string]string, error) {
 func (a *PodLogArtifact) UpdateMetadata(meta map[string]string) error {
 	return errors.New("not implemented")
 }
+
+// Live returns true if the job of the pod log has not completed yet
+func (a *PodLogArtifact) Live() bool {
+	if _, ok := a.jobAgent.(jobLogStreamer); !ok {
+		return false
+	}
+	pj, err := a.jobAgent.GetProwJob(a.name, a.buildID)
+	if err != nil {
+		return false
+	}
+	return !pj.Complete()
+}
+
+// Stream follows the pod log from offset off until the container terminates
+func (a *PodLogArtifact) Stream(ctx context.Context, off int64) (io.ReadCloser, error) {
+	streamer, ok := a.jobAgent.(jobLogStreamer)
+	if !ok {
+		return nil, errors.New("streaming pod logs is not supported")
+	}
+	log, err := streamer.StreamJobLog(ctx, a.name, a.buildID, a.container)
+	if err != nil {
+		return nil, fmt.Errorf("error streaming pod log: %w", err)
+	}
+	// Pod logs can't be requested from an offset, so skip what was read before.
+	if _, err := io.CopyN(ioutil.Discard, log, off); err != nil && err != io.EOF {
+		log.Close()
+		return nil, fmt.Errorf("error skipping to offset %d of pod log: %w", off, err)
+	}
+	return log, nil
+}
@@ -18,8 +18,10 @@ package spyglass
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"testing"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -464,3 +466,96 @@ func TestPodLogArtifact_RespectsSizeLimit(t *testing.T) {
 		})
 	}
 }
+
+// fakeStreamingJAgent follows the logs of the fakePodLogJAgent
+type fakeStreamingJAgent struct {
+	fakePodLogJAgent
+	complete bool
+}
+
+func (j *fakeStreamingJAgent) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	var pj prowapi.ProwJob
+	if j.complete {
+		pj.SetComplete()
+	}
+	return pj, nil
+}
+
+func (j *fakeStreamingJAgent) StreamJobLog(_ context.Context, job, id, container string) (io.ReadCloser, error) {
+	log, err := j.GetJobLog(job, id, container)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(log)), nil
+}
+
+func TestStream_PodLog(t *testing.T) {
+	testCases := []struct {
+		name         string
+		agent        jobAgent
+		offset       int64
+		expectedLive bool
+		expected     string
+		expectErr    bool
+	}{
+		{
+			name:      "agent that can't stream",
+			agent:     &fakePodLogJAgent{},
+			expectErr: true,
+		},
+		{
+			name:         "running job",
+			agent:        &fakeStreamingJAgent{},
+			expectedLive: true,
+			expected:     "frobscottle",
+		},
+		{
+			name:         "running job from offset",
+			agent:        &fakeStreamingJAgent{},
+			offset:       5,
+			expectedLive: true,
+			expected:     "cottle",
+		},
+		{
+			name:         "offset after the end of the log",
+			agent:        &fakeStreamingJAgent{},
+			offset:       50,
+			expectedLive: true,
+			expected:     "",
+		},
+		{
+			name:     "complete job",
+			agent:    &fakeStreamingJAgent{complete: true},
+			expected: "frobscottle",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifact, err := NewPodLogArtifact("BFG", "435", singleLogName, kube.TestContainerName, 500e6, tc.agent)
+			if err != nil {
+				t.Fatalf("Pod Log Tests failed to create pod log artifact, err %v", err)
+			}
+			if live := artifact.Live(); live != tc.expectedLive {
+				t.Errorf("expected live to be %t, got %t", tc.expectedLive, live)
+			}
+			log, err := artifact.Stream(context.Background(), tc.offset)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer log.Close()
+			got, err := ioutil.ReadAll(log)
+			if err != nil {
+				t.Fatalf("unexpected error reading stream: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, string(got))
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"io/ioutil"
 	"sync"
+	"time"
 
 	pkgio "k8s.io/test-infra/prow/io"
 	"k8s.io/test-infra/prow/spyglass/lenses"
@@ -47,9 +48,16 @@ type StorageArtifact struct {
 
 	attrs *pkgio.Attributes
 
+	// finished is the handle of the finished.json of the job, which marks
+	// the artifact as complete. Artifacts without it are never live.
+	finished artifactHandle
+
 	lock sync.RWMutex
 }
 
+// storageStreamInterval is how often a live artifact is checked for new content.
+var storageStreamInterval = 5 * time.Second
+
 type artifactHandle interface {
 	Attrs(ctx context.Context) (pkgio.Attributes, error)
 	NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
@@ -298,3 +306,68 @@ func (a *StorageArtifact) gzipped() (bool, error) {
 	}
 	return attrs.ContentEncoding == "gzip", nil
 }
+
+// Live returns true if the job has not finished yet
+func (a *StorageArtifact) Live() bool {
+	if a.finished == nil {
+		return false
+	}
+	_, err := a.finished.Attrs(a.ctx)
+	return pkgio.IsNotExist(err)
+}
+
+// Stream polls the artifact for content appended after offset off until the
+// job finishes
+func (a *StorageArtifact) Stream(ctx context.Context, off int64) (io.ReadCloser, error) {
+	gzipped, err := a.gzipped()
+	if err != nil {
+		return nil, fmt.Errorf("error checking artifact for gzip compression: %w", err)
+	}
+	if gzipped {
+		return nil, lenses.ErrGzipOffsetRead
+	}
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(a.follow(ctx, off, w))
+	}()
+	return r, nil
+}
+
+func (a *StorageArtifact) follow(ctx context.Context, off int64, w io.Writer) error {
+	for {
+		// Check whether the job finished before reading, so that what is
+		// appended before it finished is always read.
+		finished := true
+		if a.finished != nil {
+			_, err := a.finished.Attrs(ctx)
+			if err != nil && !pkgio.IsNotExist(err) {
+				return fmt.Errorf("error checking whether the job finished: %w", err)
+			}
+			finished = err == nil
+		}
+		attrs, err := a.handle.Attrs(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting gcs attributes for artifact: %w", err)
+		}
+		if attrs.Size > off {
+			reader, err := a.handle.NewRangeReader(ctx, off, attrs.Size-off)
+			if err != nil {
+				return fmt.Errorf("error getting artifact reader: %w", err)
+			}
+			n, err := io.Copy(w, reader)
+			reader.Close()
+			off += n
+			if err != nil {
+				return fmt.Errorf("error reading from artifact: %w", err)
+			}
+		}
+		if finished {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(storageStreamInterval):
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	pkgio "k8s.io/test-infra/prow/io"
 	"k8s.io/test-infra/prow/spyglass/api"
@@ -39,6 +40,10 @@ var (
 	ErrCannotParseSource = errors.New("could not create job source from provided source")
 )
 
+// DownloadPath is where deck serves artifacts of storage providers
+// that can't create signed URLs, e.g. file://.
+const DownloadPath = "/spyglass/download/"
+
 // StorageArtifactFetcher contains information used for fetching artifacts from GCS
 type StorageArtifactFetcher struct {
 	opener        pkgio.Opener
@@ -168,9 +173,20 @@ func (af *StorageArtifactFetcher) artifacts(ctx context.Context, key string) ([]
 }
 
 func (af *StorageArtifactFetcher) signURL(ctx context.Context, key string) (string, error) {
-	return af.opener.SignedURL(ctx, key, pkgio.SignedURLOptions{
+	signedURL, err := af.opener.SignedURL(ctx, key, pkgio.SignedURLOptions{
 		UseGSCookieAuth: af.useCookieAuth,
 	})
+	if errors.Is(err, pkgio.ErrSignedURLUnsupported) {
+		return downloadLink(key), nil
+	}
+	return signedURL, err
+}
+
+// downloadLink returns the deck link for an artifact, e.g. for
+// file://prow-artifacts/logs/job/1/build-log.txt it returns
+// /spyglass/download/file/prow-artifacts/logs/job/1/build-log.txt
+func downloadLink(key string) string {
+	return DownloadPath + strings.Replace(key, "://", "/", 1)
 }
 
 type storageArtifactHandle struct {
@@ -213,7 +229,9 @@ func (af *StorageArtifactFetcher) Artifact(ctx context.Context, key string, arti
 	if err != nil {
 		return nil, err
 	}
-	return NewStorageArtifact(context.Background(), obj, signedURL, artifactName, sizeLimit), nil
+	artifact := NewStorageArtifact(context.Background(), obj, signedURL, artifactName, sizeLimit)
+	artifact.finished = &storageArtifactHandle{Opener: af.opener, Name: fmt.Sprintf("%s%s/%s", src.linkPrefix, src.bucket, path.Join(prefix, prowv1.FinishedStatusFile))}
+	return artifact, nil
 }
 
 func extractBucketPrefixPair(storagePath string) (string, string) {
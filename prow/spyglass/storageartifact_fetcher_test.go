@@ -469,3 +469,18 @@ func createConfigGetter(bucketNames ...string) config.Getter {
 	})
 	return ca.Config
 }
+
+func TestSignURLFallsBackToDownloadLink(t *testing.T) {
+	opener, err := io.NewOpenerWithOptions(context.Background(), io.OpenerOptions{FileStorageRoot: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create opener: %v", err)
+	}
+	af := NewStorageArtifactFetcher(opener, createConfigGetter("prow-artifacts"), false)
+	actual, err := af.signURL(context.Background(), "file://prow-artifacts/logs/job/1/build-log.txt")
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+	if expected := "/spyglass/download/file/prow-artifacts/logs/job/1/build-log.txt"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
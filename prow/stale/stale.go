@@ -0,0 +1,326 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stale implements a controller that marks inactive issues and pull
+// requests as stale, then as rotten, and eventually closes them.
+package stale
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/labels"
+)
+
+const ControllerName = "stale"
+
+// The lifecycle stages issues and pull requests are moved to.
+const (
+	StageStale  = "stale"
+	StageRotten = "rotten"
+	StageClosed = "closed"
+)
+
+var staleActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "stale_actions",
+	Help: "Number of issues and pull requests moved to a lifecycle stage.",
+}, []string{"org", "repo", "stage", "dry_run"})
+
+func init() {
+	prometheus.MustRegister(staleActions)
+}
+
+type githubClient interface {
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	CreateComment(org, repo string, number int, comment string) error
+	AddLabel(org, repo string, number int, label string) error
+	RemoveLabel(org, repo string, number int, label string) error
+	CloseIssue(org, repo string, number int) error
+	ClosePR(org, repo string, number int) error
+}
+
+// Action is the move of an issue or pull request to a lifecycle stage.
+type Action struct {
+	Org    string
+	Repo   string
+	Number int
+	URL    string
+	Title  string
+	Stage  string
+	// DryRun is true if the action was only reported
+	DryRun bool
+}
+
+// Report lists the actions of a sync.
+type Report struct {
+	Actions []Action
+	Errors  int
+}
+
+// Add adds the stale controller to the manager. It is only run by the leader.
+func Add(mgr manager.Manager, cfg config.Getter, ghc githubClient) error {
+	c := newController(cfg, ghc)
+	if err := mgr.Add(manager.RunnableFunc(c.run)); err != nil {
+		return fmt.Errorf("failed to add %s controller to manager: %w", ControllerName, err)
+	}
+	return nil
+}
+
+type controller struct {
+	config config.Getter
+	ghc    githubClient
+	log    *logrus.Entry
+	now    func() time.Time
+}
+
+func newController(cfg config.Getter, ghc githubClient) *controller {
+	return &controller{
+		config: cfg,
+		ghc:    ghc,
+		log:    logrus.WithField("controller", ControllerName),
+		now:    time.Now,
+	}
+}
+
+func (c *controller) run(ctx context.Context) error {
+	for {
+		start := time.Now()
+		report := c.sync(ctx)
+		c.log.WithFields(logrus.Fields{
+			"duration": time.Since(start).String(),
+			"actions":  len(report.Actions),
+			"errors":   report.Errors,
+		}).Info("Synced")
+
+		timer := time.NewTimer(c.config().Stale.ResyncPeriod.Duration)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// sync applies all rules once. Later stages are applied first so that issues
+// and pull requests move by at most one stage per sync.
+func (c *controller) sync(ctx context.Context) Report {
+	var report Report
+	now := c.now()
+	for i, rule := range c.config().Stale.Rules {
+		log := c.log.WithField("rule", i)
+		for _, stage := range stages(&rule) {
+			for _, query := range queries(&rule, stage, now) {
+				if ctx.Err() != nil {
+					return report
+				}
+				issues, err := c.ghc.FindIssues(query, "updated", true)
+				if err != nil {
+					log.WithError(err).WithField("query", query).Error("Failed to search for issues.")
+					report.Errors++
+					continue
+				}
+				for _, issue := range issues {
+					action, err := c.apply(&rule, stage, issue)
+					if err != nil {
+						log.WithError(err).WithField("url", issue.HTMLURL).Errorf("Failed to move to %s.", stage)
+						report.Errors++
+						continue
+					}
+					log.WithFields(logrus.Fields{
+						"url":     action.URL,
+						"stage":   action.Stage,
+						"dry_run": action.DryRun,
+					}).Info("Moved to lifecycle stage.")
+					staleActions.WithLabelValues(action.Org, action.Repo, action.Stage, strconv.FormatBool(action.DryRun)).Inc()
+					report.Actions = append(report.Actions, action)
+				}
+			}
+		}
+	}
+	return report
+}
+
+func stages(rule *config.StaleRule) []string {
+	if rule.DisableClose {
+		return []string{StageRotten, StageStale}
+	}
+	return []string{StageClosed, StageRotten, StageStale}
+}
+
+// queries returns the searches for the issues and pull requests that the rule
+// moves to the stage, one per org or repo to keep them short.
+func queries(rule *config.StaleRule, stage string, now time.Time) []string {
+	filters := []string{"is:open", "archived:false"}
+	switch rule.Kind {
+	case config.StaleKindIssue:
+		filters = append(filters, "is:issue")
+	case config.StaleKindPR:
+		filters = append(filters, "is:pr")
+	}
+	for _, label := range append([]string{labels.LifecycleFrozen}, rule.ExemptLabels...) {
+		filters = append(filters, fmt.Sprintf("-label:\"%s\"", label))
+	}
+	var inactive time.Duration
+	switch stage {
+	case StageStale:
+		filters = append(filters, fmt.Sprintf("-label:\"%s\"", labels.LifecycleStale), fmt.Sprintf("-label:\"%s\"", labels.LifecycleRotten))
+		inactive = rule.StaleAfter.Duration
+	case StageRotten:
+		filters = append(filters, fmt.Sprintf("label:\"%s\"", labels.LifecycleStale), fmt.Sprintf("-label:\"%s\"", labels.LifecycleRotten))
+		inactive = rule.RottenAfter.Duration
+	case StageClosed:
+		filters = append(filters, fmt.Sprintf("label:\"%s\"", labels.LifecycleRotten))
+		inactive = rule.CloseAfter.Duration
+	}
+	filters = append(filters, "updated:<"+now.Add(-inactive).UTC().Format(time.RFC3339))
+
+	var queries []string
+	for _, org := range rule.Orgs {
+		query := append([]string{"org:" + org}, filters...)
+		for _, repo := range rule.ExcludedRepos {
+			if strings.HasPrefix(repo, org+"/") {
+				query = append(query, "-repo:"+repo)
+			}
+		}
+		queries = append(queries, strings.Join(query, " "))
+	}
+	for _, repo := range rule.Repos {
+		queries = append(queries, strings.Join(append([]string{"repo:" + repo}, filters...), " "))
+	}
+	return queries
+}
+
+// apply moves the issue to the stage unless the rule is a dry run
+func (c *controller) apply(rule *config.StaleRule, stage string, issue github.Issue) (Action, error) {
+	org, repo, err := orgRepo(issue.HTMLURL)
+	if err != nil {
+		return Action{}, err
+	}
+	action := Action{
+		Org:    org,
+		Repo:   repo,
+		Number: issue.Number,
+		URL:    issue.HTMLURL,
+		Title:  issue.Title,
+		Stage:  stage,
+		DryRun: rule.DryRun,
+	}
+	comment, err := stageComment(rule, stage, issue.IsPullRequest())
+	if err != nil {
+		return action, err
+	}
+	if rule.DryRun {
+		return action, nil
+	}
+
+	switch stage {
+	case StageStale:
+		if err := c.ghc.AddLabel(org, repo, issue.Number, labels.LifecycleStale); err != nil {
+			return action, err
+		}
+	case StageRotten:
+		if err := c.ghc.RemoveLabel(org, repo, issue.Number, labels.LifecycleStale); err != nil {
+			return action, err
+		}
+		if err := c.ghc.AddLabel(org, repo, issue.Number, labels.LifecycleRotten); err != nil {
+			return action, err
+		}
+	}
+	if err := c.ghc.CreateComment(org, repo, issue.Number, comment); err != nil {
+		return action, err
+	}
+	if stage == StageClosed {
+		if issue.IsPullRequest() {
+			return action, c.ghc.ClosePR(org, repo, issue.Number)
+		}
+		return action, c.ghc.CloseIssue(org, repo, issue.Number)
+	}
+	return action, nil
+}
+
+// orgRepo parses the org and repo from the URL of an issue or pull request,
+// e.g. https://github.com/org/repo/issues/1
+func orgRepo(htmlURL string) (string, string, error) {
+	u, err := url.Parse(htmlURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse URL %q: %w", htmlURL, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 {
+		return "", "", fmt.Errorf("URL %q is not the URL of an issue or pull request", htmlURL)
+	}
+	return parts[0], parts[1], nil
+}
+
+func stageComment(rule *config.StaleRule, stage string, isPR bool) (string, error) {
+	text := rule.StaleComment
+	switch stage {
+	case StageRotten:
+		text = rule.RottenComment
+	case StageClosed:
+		text = rule.CloseComment
+	}
+	tmpl, err := template.New(stage).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s comment: %w", stage, err)
+	}
+	data := struct {
+		Kind        string
+		StaleAfter  string
+		RottenAfter string
+		CloseAfter  string
+	}{
+		Kind:        "issue",
+		StaleAfter:  period(rule.StaleAfter.Duration),
+		RottenAfter: period(rule.RottenAfter.Duration),
+	}
+	if isPR {
+		data.Kind = "pull request"
+	}
+	if !rule.DisableClose {
+		data.CloseAfter = period(rule.CloseAfter.Duration)
+	}
+	var comment strings.Builder
+	if err := tmpl.Execute(&comment, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s comment: %w", stage, err)
+	}
+	return comment.String(), nil
+}
+
+// period formats whole days as such, e.g. "90 days"
+func period(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d == day:
+		return "1 day"
+	case d%day == 0:
+		return fmt.Sprintf("%d days", d/day)
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stale
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+type fakeClient struct {
+	// results maps substrings of queries to the issues they find
+	results map[string][]github.Issue
+	calls   []string
+}
+
+func (f *fakeClient) FindIssues(query, sort string, asc bool) ([]github.Issue, error) {
+	for substring, issues := range f.results {
+		if strings.Contains(query, substring) {
+			return issues, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeClient) CreateComment(org, repo string, number int, comment string) error {
+	f.calls = append(f.calls, fmt.Sprintf("comment %s/%s#%d", org, repo, number))
+	return nil
+}
+
+func (f *fakeClient) AddLabel(org, repo string, number int, label string) error {
+	f.calls = append(f.calls, fmt.Sprintf("label %s/%s#%d %s", org, repo, number, label))
+	return nil
+}
+
+func (f *fakeClient) RemoveLabel(org, repo string, number int, label string) error {
+	f.calls = append(f.calls, fmt.Sprintf("unlabel %s/%s#%d %s", org, repo, number, label))
+	return nil
+}
+
+func (f *fakeClient) CloseIssue(org, repo string, number int) error {
+	f.calls = append(f.calls, fmt.Sprintf("close issue %s/%s#%d", org, repo, number))
+	return nil
+}
+
+func (f *fakeClient) ClosePR(org, repo string, number int) error {
+	f.calls = append(f.calls, fmt.Sprintf("close pr %s/%s#%d", org, repo, number))
+	return nil
+}
+
+func days(n int) *metav1.Duration {
+	return &metav1.Duration{Duration: time.Duration(n) * 24 * time.Hour}
+}
+
+func TestQueries(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	rule := config.StaleRule{
+		Orgs:          []string{"org"},
+		Repos:         []string{"other/repo"},
+		ExcludedRepos: []string{"org/excluded", "other/excluded"},
+		Kind:          config.StaleKindPR,
+		ExemptLabels:  []string{"good first issue"},
+		StaleAfter:    days(90),
+		RottenAfter:   days(30),
+		CloseAfter:    days(10),
+	}
+	testCases := []struct {
+		stage    string
+		expected []string
+	}{
+		{
+			stage: StageStale,
+			expected: []string{
+				`org:org is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" -label:"lifecycle/stale" -label:"lifecycle/rotten" updated:<2022-03-03T00:00:00Z -repo:org/excluded`,
+				`repo:other/repo is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" -label:"lifecycle/stale" -label:"lifecycle/rotten" updated:<2022-03-03T00:00:00Z`,
+			},
+		},
+		{
+			stage: StageRotten,
+			expected: []string{
+				`org:org is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" label:"lifecycle/stale" -label:"lifecycle/rotten" updated:<2022-05-02T00:00:00Z -repo:org/excluded`,
+				`repo:other/repo is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" label:"lifecycle/stale" -label:"lifecycle/rotten" updated:<2022-05-02T00:00:00Z`,
+			},
+		},
+		{
+			stage: StageClosed,
+			expected: []string{
+				`org:org is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" label:"lifecycle/rotten" updated:<2022-05-22T00:00:00Z -repo:org/excluded`,
+				`repo:other/repo is:open archived:false is:pr -label:"lifecycle/frozen" -label:"good first issue" label:"lifecycle/rotten" updated:<2022-05-22T00:00:00Z`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.stage, func(t *testing.T) {
+			if actual := queries(&rule, tc.stage, now); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected queries\n%q\ngot\n%q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	issue := func(repo string, number int, isPR bool) github.Issue {
+		kind := "issues"
+		issue := github.Issue{Number: number}
+		if isPR {
+			kind = "pull"
+			issue.PullRequest = &struct{}{}
+		}
+		issue.HTMLURL = fmt.Sprintf("https://github.com/%s/%s/%d", repo, kind, number)
+		return issue
+	}
+	results := map[string][]github.Issue{
+		// the leading spaces tell including and excluding label filters apart
+		`-label:"lifecycle/stale"`:          {issue("org/repo", 1, false)},
+		` label:"lifecycle/stale"`:          {issue("org/repo", 2, true)},
+		` label:"lifecycle/rotten" updated`: {issue("org/repo", 3, false), issue("org/repo", 4, true)},
+	}
+	testCases := []struct {
+		name            string
+		disableClose    bool
+		dryRun          bool
+		expectedStages  []string
+		expectedCalls   []string
+		expectedDryRuns bool
+	}{
+		{
+			name:           "all stages",
+			expectedStages: []string{StageClosed, StageClosed, StageRotten, StageStale},
+			expectedCalls: []string{
+				"comment org/repo#3",
+				"close issue org/repo#3",
+				"comment org/repo#4",
+				"close pr org/repo#4",
+				"unlabel org/repo#2 lifecycle/stale",
+				"label org/repo#2 lifecycle/rotten",
+				"comment org/repo#2",
+				"label org/repo#1 lifecycle/stale",
+				"comment org/repo#1",
+			},
+		},
+		{
+			name:           "closing disabled",
+			disableClose:   true,
+			expectedStages: []string{StageRotten, StageStale},
+			expectedCalls: []string{
+				"unlabel org/repo#2 lifecycle/stale",
+				"label org/repo#2 lifecycle/rotten",
+				"comment org/repo#2",
+				"label org/repo#1 lifecycle/stale",
+				"comment org/repo#1",
+			},
+		},
+		{
+			name:            "dry run only reports",
+			dryRun:          true,
+			expectedStages:  []string{StageClosed, StageClosed, StageRotten, StageStale},
+			expectedDryRuns: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Stale.Rules = []config.StaleRule{{
+				Repos:        []string{"org/repo"},
+				StaleAfter:   days(90),
+				RottenAfter:  days(30),
+				CloseAfter:   days(30),
+				DisableClose: tc.disableClose,
+				DryRun:       tc.dryRun,
+			}}
+			ghc := &fakeClient{results: results}
+			c := newController(func() *config.Config { return cfg }, ghc)
+
+			report := c.sync(context.Background())
+			if report.Errors != 0 {
+				t.Errorf("expected no errors, got %d", report.Errors)
+			}
+			var stages []string
+			for _, action := range report.Actions {
+				stages = append(stages, action.Stage)
+				if action.DryRun != tc.expectedDryRuns {
+					t.Errorf("expected dry run to be %t for %s", tc.expectedDryRuns, action.URL)
+				}
+			}
+			if !reflect.DeepEqual(stages, tc.expectedStages) {
+				t.Errorf("expected stages %v, got %v", tc.expectedStages, stages)
+			}
+			if !reflect.DeepEqual(ghc.calls, tc.expectedCalls) {
+				t.Errorf("expected calls\n%q\ngot\n%q", tc.expectedCalls, ghc.calls)
+			}
+		})
+	}
+}
+
+func TestStageComment(t *testing.T) {
+	rule := config.StaleRule{
+		StaleAfter:   days(90),
+		RottenAfter:  &metav1.Duration{Duration: 36 * time.Hour},
+		CloseAfter:   days(1),
+		StaleComment: "This {{ .Kind }} is stale after {{ .StaleAfter }}, rotten after {{ .RottenAfter }} and closed after {{ .CloseAfter }}.",
+	}
+	comment, err := stageComment(&rule, StageStale, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "This pull request is stale after 90 days, rotten after 36h0m0s and closed after 1 day."; comment != expected {
+		t.Errorf("expected comment %q, got %q", expected, comment)
+	}
+}
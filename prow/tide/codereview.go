@@ -27,6 +27,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/types"
 	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/gitlab"
 	"k8s.io/test-infra/prow/tide/blockers"
 
 	githubql "github.com/shurcooL/githubv4"
@@ -105,6 +106,7 @@ type CodeReviewCommon struct {
 
 	GitHub *PullRequest
 	Gerrit *gerrit.ChangeInfo
+	GitLab *gitlab.MergeRequest
 }
 
 func (crc *CodeReviewCommon) logFields() logrus.Fields {
@@ -235,6 +237,48 @@ func CodeReviewCommonFromGerrit(gci *gerrit.ChangeInfo, instance string) *CodeRe
 	return crc
 }
 
+// CodeReviewCommonFromGitLab derives CodeReviewCommon struct from a GitLab
+// MergeRequest struct, by extracting shared fields among different code
+// review providers.
+//
+// Like for Gerrit, the host of the GitLab instance is used as the org, and the
+// full path of the project as the repo.
+func CodeReviewCommonFromGitLab(mr *gitlab.MergeRequest, host, project string) *CodeReviewCommon {
+	if mr == nil {
+		return nil
+	}
+	// Make a copy
+	mrCopy := *mr
+
+	mergeable := string(githubql.MergeableStateUnknown)
+	if mr.HasConflicts || mr.MergeStatus == gitlab.MergeStatusCannotBeMerged {
+		mergeable = string(githubql.MergeableStateConflicting)
+	} else if mr.MergeStatus == gitlab.MergeStatusCanBeMerged {
+		mergeable = string(githubql.MergeableStateMergeable)
+	}
+	crc := &CodeReviewCommon{
+		NameWithOwner: host + "/" + project,
+		Number:        mr.IID,
+		Org:           host,
+		Repo:          project,
+		BaseRefPrefix: "refs/heads/",
+		BaseRefName:   mr.TargetBranch,
+		HeadRefName:   mr.SourceBranch,
+		HeadRefOID:    mr.SHA,
+		Title:         mr.Title,
+		Body:          mr.Description,
+		AuthorLogin:   mr.Author.Username,
+		Mergeable:     mergeable,
+
+		GitLab: &mrCopy,
+	}
+	if mr.UpdatedAt != nil {
+		crc.UpdatedAtTime = *mr.UpdatedAt
+	}
+
+	return crc
+}
+
 // provider is the interface implemented by each source code
 // providers, such as GitHub and Gerrit.
 type provider interface {
@@ -258,4 +302,7 @@ type provider interface {
 	mergePRs(sp subpool, prs []CodeReviewCommon, dontUpdateStatus *threadSafePRSet) error
 	GetTideContextPolicy(gitClient git.ClientFactory, org, repo, branch string, baseSHAGetter config.RefGetter, pr *CodeReviewCommon) (contextChecker, error)
 	prMergeMethod(crc *CodeReviewCommon) (types.PullRequestMergeType, error)
+	// GetChangedFiles returns the paths of the files changed by a PR, which
+	// decide whether conditional presubmits and merge policies apply to it.
+	GetChangedFiles(org, repo string, number int) ([]string, error)
 }
@@ -218,6 +218,11 @@ func (p *GerritProvider) mergePRs(sp subpool, prs []CodeReviewCommon, dontUpdate
 	return nil
 }
 
+func (p *GerritProvider) GetChangedFiles(org, repo string, number int) ([]string, error) {
+	// This is not supported yet.
+	return nil, errors.New("getting the changed files of changes is not supported for Gerrit yet")
+}
+
 // GetTideContextPolicy gets context policy defined by users + requirements from
 // prow jobs.
 func (p *GerritProvider) GetTideContextPolicy(gitClient git.ClientFactory, org, repo, branch string, baseSHAGetter config.RefGetter, crc *CodeReviewCommon) (contextChecker, error) {
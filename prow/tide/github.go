@@ -147,6 +147,18 @@ func (gi *GitHubProvider) GetRef(org, repo, ref string) (string, error) {
 	return gi.ghc.GetRef(org, repo, ref)
 }
 
+func (gi *GitHubProvider) GetChangedFiles(org, repo string, number int) ([]string, error) {
+	changes, err := gi.ghc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("error getting PR changes for #%d: %w", number, err)
+	}
+	changedFiles := make([]string, 0, len(changes))
+	for _, change := range changes {
+		changedFiles = append(changedFiles, change.Filename)
+	}
+	return changedFiles, nil
+}
+
 func (gi *GitHubProvider) GetTideContextPolicy(gitClient git.ClientFactory, org, repo, branch string, baseSHAGetter config.RefGetter, pr *CodeReviewCommon) (contextChecker, error) {
 	return gi.cfg().GetTideContextPolicy(gitClient, org, repo, branch, baseSHAGetter, pr.HeadRefOID)
 }
@@ -204,6 +216,27 @@ func (gi *GitHubProvider) search(query querier, log *logrus.Entry, q string, sta
 	return ret, nil
 }
 
+// pullRequest fetches a single PR with the same data that the pool queries
+// return for it.
+func (gi *GitHubProvider) pullRequest(org, repo string, number int) (*PullRequest, error) {
+	var q pullRequestQuery
+	vars := map[string]interface{}{
+		"owner":  githubql.String(org),
+		"name":   githubql.String(repo),
+		"number": githubql.Int(number),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := gi.ghc.QueryWithGitHubAppsSupport(ctx, &q, vars, org); err != nil {
+		return nil, fmt.Errorf("failed to query PR %s/%s#%d: %w", org, repo, number, err)
+	}
+	pr := q.Repository.PullRequest
+	if pr.Number == 0 {
+		return nil, fmt.Errorf("PR %s/%s#%d not found", org, repo, number)
+	}
+	return &pr, nil
+}
+
 func (gi *GitHubProvider) prepareMergeDetails(commitTemplates config.TideMergeCommitTemplate, pr CodeReviewCommon, mergeMethod types.PullRequestMergeType) github.MergeDetails {
 	ghMergeDetails := github.MergeDetails{
 		SHA:         pr.HeadRefOID,
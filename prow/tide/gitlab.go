@@ -0,0 +1,300 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/git/types"
+	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/tide/blockers"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+// Enforcing interface implementation check at compile time
+var _ provider = (*GitLabProvider)(nil)
+
+// NewGitLabController makes a Controller that merges the merge requests of a
+// GitLab instance. GitLab has no status controller, Tide only reports through
+// its history and the pools it serves. Merge policies only apply to GitHub,
+// so they are not evaluated.
+func NewGitLabController(
+	glc gitlab.Client,
+	mgr manager,
+	cfg config.Getter,
+	gc git.ClientFactory,
+	maxRecordsPerPool int,
+	opener io.Opener,
+	historyURI,
+	historyArchiveURI string,
+	logger *logrus.Entry,
+) (*Controller, error) {
+	if logger == nil {
+		logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	hist, err := history.NewWithArchive(maxRecordsPerPool, opener, historyURI, historyArchiveURI)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing history client from %q: %w", historyURI, err)
+	}
+
+	statusUpdate := &statusUpdate{
+		dontUpdateStatus: &threadSafePRSet{},
+		newPoolPending:   make(chan bool),
+	}
+	provider := newGitLabProvider(logger, cfg, glc)
+	syncCtrl, err := newSyncController(context.Background(), logger, mgr, provider, cfg, &gitLabClientFactory{ClientFactory: gc}, hist, statusUpdate)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{syncCtrl: syncCtrl}, nil
+}
+
+// gitLabClientFactory clones the projects of merge requests. Their org is the
+// host of the instance and their repo is the full path of the project, while
+// the wrapped factory, which is configured with the host, clones org/repo.
+type gitLabClientFactory struct {
+	git.ClientFactory
+}
+
+func (f *gitLabClientFactory) ClientFor(org, repo string) (git.RepoClient, error) {
+	group, project := splitProjectPath(repo)
+	return f.ClientFactory.ClientFor(group, project)
+}
+
+func (f *gitLabClientFactory) ClientFromDir(org, repo, dir string) (git.RepoClient, error) {
+	group, project := splitProjectPath(repo)
+	return f.ClientFactory.ClientFromDir(group, project, dir)
+}
+
+// splitProjectPath splits the full path of a project, e.g. group/sub/project,
+// into the path of its group and its name.
+func splitProjectPath(project string) (string, string) {
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		return project[:i], project[i+1:]
+	}
+	return "", project
+}
+
+// GitLabProvider implements provider, used by tide Controller for
+// interacting directly with a GitLab instance.
+//
+// Merge requests are identified like the GitLab adapter identifies them in
+// the refs of ProwJobs: the org is the host of the instance and the repo is
+// the full path of the project.
+type GitLabProvider struct {
+	cfg config.Getter
+	gc  gitlab.Client
+	// host of the GitLab instance, e.g. gitlab.example.com
+	host string
+
+	logger *logrus.Entry
+}
+
+func newGitLabProvider(logger *logrus.Entry, cfg config.Getter, gc gitlab.Client) *GitLabProvider {
+	host := gc.Instance()
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return &GitLabProvider{
+		cfg:    cfg,
+		gc:     gc,
+		host:   host,
+		logger: logger,
+	}
+}
+
+// Query returns the merge requests selected by the configured queries.
+func (p *GitLabProvider) Query() (map[string]CodeReviewCommon, error) {
+	res := make(map[string]CodeReviewCommon)
+	gitlabCfg := p.cfg().Tide.GitLab
+	if gitlabCfg == nil {
+		return res, nil
+	}
+	// This is querying serially, like the GerritProvider does.
+	for _, query := range gitlabCfg.Queries {
+		missingLabels := sets.NewString(query.MissingLabels...)
+		for _, project := range query.Projects {
+			mrs, err := p.gc.ListMergeRequests(project, query.Labels)
+			if err != nil {
+				p.logger.WithField("project", project).WithError(err).Error("Querying GitLab project for merge requests.")
+				continue
+			}
+			for _, mr := range mrs {
+				if mr.Draft || hasAnyLabel(&mr, missingLabels) {
+					continue
+				}
+				crc := CodeReviewCommonFromGitLab(&mr, p.host, project)
+				res[prKey(crc)] = *crc
+			}
+		}
+	}
+	return res, nil
+}
+
+func hasAnyLabel(mr *gitlab.MergeRequest, labels sets.String) bool {
+	for _, label := range labels.List() {
+		if mr.HasLabel(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *GitLabProvider) blockers() (blockers.Blockers, error) {
+	// Blocking issues are not supported for GitLab.
+	return blockers.Blockers{}, nil
+}
+
+func (p *GitLabProvider) isAllowedToMerge(crc *CodeReviewCommon) (string, error) {
+	if crc.Mergeable == string(githubql.MergeableStateConflicting) {
+		return "PR has a merge conflict.", nil
+	}
+	return "", nil
+}
+
+// GetRef gets the latest revision of a branch, ref is like "heads/master".
+func (p *GitLabProvider) GetRef(org, repo, ref string) (string, error) {
+	branch, err := p.gc.GetBranch(repo, strings.TrimPrefix(ref, "heads/"))
+	if err != nil {
+		return "", err
+	}
+	return branch.Commit.ID, nil
+}
+
+// headContexts transforms the commit statuses of the head of the merge
+// request into contexts. These include the statuses set by the GitLab crier
+// reporter as well as the ones of GitLab CI pipelines.
+func (p *GitLabProvider) headContexts(crc *CodeReviewCommon) ([]Context, error) {
+	statuses, err := p.gc.ListCommitStatuses(crc.Repo, crc.HeadRefOID)
+	if err != nil {
+		return nil, err
+	}
+	var res []Context
+	for _, status := range statuses {
+		res = append(res, Context{
+			Context:     githubql.String(status.Name),
+			Description: githubql.String(status.Description),
+			State:       contextState(status.Status),
+		})
+	}
+	return res, nil
+}
+
+func contextState(status string) githubql.StatusState {
+	switch status {
+	case gitlab.StatusSuccess:
+		return githubql.StatusStateSuccess
+	case gitlab.StatusFailed, gitlab.StatusCanceled:
+		return githubql.StatusStateFailure
+	default:
+		return githubql.StatusStatePending
+	}
+}
+
+// mergePRs accepts the merge requests, GitLab only merges them if their head
+// did not change since they were tested.
+func (p *GitLabProvider) mergePRs(sp subpool, prs []CodeReviewCommon, _ *threadSafePRSet) error {
+	var merged, failed []int
+	defer func() {
+		if len(merged) == 0 {
+			return
+		}
+		tideMetrics.merges.WithLabelValues(sp.org, sp.repo, sp.branch).Observe(float64(len(merged)))
+	}()
+
+	var errs []error
+	log := sp.log.WithField("merge-targets", prNumbers(prs))
+	for _, pr := range prs {
+		log := log.WithFields(pr.logFields())
+		mergeMethod, err := p.prMergeMethod(&pr)
+		if err != nil {
+			errs = append(errs, err)
+			failed = append(failed, pr.Number)
+			continue
+		}
+		if err := p.gc.AcceptMergeRequest(pr.Repo, pr.Number, pr.HeadRefOID, mergeMethod == types.MergeSquash); err != nil {
+			log.WithError(err).Warn("Merge failed.")
+			errs = append(errs, err)
+			failed = append(failed, pr.Number)
+			continue
+		}
+		log.Info("Merged.")
+		merged = append(merged, pr.Number)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed merging %v: %w", failed, utilerrors.NewAggregate(errs))
+}
+
+// GetTideContextPolicy requires the contexts of the presubmits that are not
+// optional. Like for GitHub, any other context, e.g. of a GitLab CI pipeline,
+// is required as well.
+func (p *GitLabProvider) GetTideContextPolicy(gitClient git.ClientFactory, org, repo, branch string, baseSHAGetter config.RefGetter, crc *CodeReviewCommon) (contextChecker, error) {
+	required := sets.NewString()
+	requiredIfPresent := sets.NewString()
+	optional := sets.NewString()
+	for _, ps := range p.cfg().GetPresubmitsStatic(org + "/" + repo) {
+		if !ps.CouldRun(branch) {
+			continue
+		}
+		switch {
+		case ps.Optional:
+			optional.Insert(ps.Context)
+		case ps.TriggersConditionally():
+			requiredIfPresent.Insert(ps.Context)
+		default:
+			required.Insert(ps.Context)
+		}
+	}
+
+	t := &config.TideContextPolicy{
+		RequiredContexts:          required.List(),
+		RequiredIfPresentContexts: requiredIfPresent.List(),
+		OptionalContexts:          optional.List(),
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// GetChangedFiles lists the files changed by the merge request, repo is the
+// full path of its project.
+func (p *GitLabProvider) GetChangedFiles(org, repo string, number int) ([]string, error) {
+	changes, err := p.gc.GetMergeRequestChanges(repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("error getting changes of merge request !%d: %w", number, err)
+	}
+	return changes, nil
+}
+
+func (p *GitLabProvider) prMergeMethod(crc *CodeReviewCommon) (types.PullRequestMergeType, error) {
+	return p.cfg().Tide.MergeMethod(config.OrgRepo{Org: crc.Org, Repo: crc.Repo}), nil
+}
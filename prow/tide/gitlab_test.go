@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/git/types"
+	"k8s.io/test-infra/prow/git/v2"
+	"k8s.io/test-infra/prow/gitlab"
+	"k8s.io/test-infra/prow/gitlab/fakegitlab"
+)
+
+func newTestGitLabProvider(cfg *config.Config, gc *fakegitlab.Client) *GitLabProvider {
+	return newGitLabProvider(logrus.WithField("test", "gitlab"), func() *config.Config { return cfg }, gc)
+}
+
+func TestGitLabQuery(t *testing.T) {
+	gc := fakegitlab.NewClient()
+	gc.MergeRequests["group/project"] = []gitlab.MergeRequest{
+		{IID: 1, State: "opened", Labels: []string{"lgtm", "approved"}},
+		{IID: 2, State: "opened", Labels: []string{"lgtm"}},
+		{IID: 3, State: "opened", Labels: []string{"lgtm", "approved", "do-not-merge/hold"}},
+		{IID: 4, State: "opened", Labels: []string{"lgtm", "approved"}, Draft: true},
+		{IID: 5, State: "merged", Labels: []string{"lgtm", "approved"}},
+	}
+	gc.MergeRequests["group/other"] = []gitlab.MergeRequest{
+		{IID: 1, State: "opened", Labels: []string{"LGTM", "Approved"}},
+	}
+	cfg := &config.Config{}
+	cfg.Tide.GitLab = &config.TideGitLabConfig{Queries: []config.TideGitLabQuery{{
+		Projects:      []string{"group/project", "group/other"},
+		Labels:        []string{"lgtm", "approved"},
+		MissingLabels: []string{"do-not-merge/hold"},
+	}}}
+
+	res, err := newTestGitLabProvider(cfg, gc).Query()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var keys []string
+	for key, crc := range res {
+		keys = append(keys, key)
+		if crc.Org != "gitlab.example.com" || crc.GitLab == nil {
+			t.Errorf("%s: unexpected code review %+v", key, crc)
+		}
+	}
+	sort.Strings(keys)
+	if expected := []string{"gitlab.example.com/group/other#1", "gitlab.example.com/group/project#1"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected merge requests %v, got %v", expected, keys)
+	}
+}
+
+func TestGitLabIsAllowedToMerge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mr       gitlab.MergeRequest
+		expected string
+	}{
+		{
+			name: "mergeable",
+			mr:   gitlab.MergeRequest{MergeStatus: gitlab.MergeStatusCanBeMerged},
+		},
+		{
+			name: "merge status not checked yet",
+			mr:   gitlab.MergeRequest{MergeStatus: "checking"},
+		},
+		{
+			name:     "conflicts",
+			mr:       gitlab.MergeRequest{HasConflicts: true},
+			expected: "PR has a merge conflict.",
+		},
+		{
+			name:     "cannot be merged",
+			mr:       gitlab.MergeRequest{MergeStatus: gitlab.MergeStatusCannotBeMerged},
+			expected: "PR has a merge conflict.",
+		},
+	}
+	p := newTestGitLabProvider(&config.Config{}, fakegitlab.NewClient())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := p.isAllowedToMerge(CodeReviewCommonFromGitLab(&tc.mr, "gitlab.example.com", "group/project"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGitLabGetRef(t *testing.T) {
+	gc := fakegitlab.NewClient()
+	gc.Branches["group/project/main"] = "abc"
+	p := newTestGitLabProvider(&config.Config{}, gc)
+	sha, err := p.GetRef("gitlab.example.com", "group/project", "heads/main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sha != "abc" {
+		t.Errorf("expected abc, got %s", sha)
+	}
+}
+
+func TestGitLabGetChangedFiles(t *testing.T) {
+	gc := fakegitlab.NewClient()
+	gc.Changes["group/project"] = map[int][]string{1: {"README.md", "api/types.go"}}
+	p := newTestGitLabProvider(&config.Config{}, gc)
+	files, err := p.GetChangedFiles("gitlab.example.com", "group/project", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"README.md", "api/types.go"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected changed files %v, got %v", expected, files)
+	}
+}
+
+type recordingClientFactory struct {
+	git.ClientFactory
+	cloned []string
+}
+
+func (f *recordingClientFactory) ClientFor(org, repo string) (git.RepoClient, error) {
+	f.cloned = append(f.cloned, org+"|"+repo)
+	return nil, nil
+}
+
+func TestGitLabClientFactory(t *testing.T) {
+	inner := &recordingClientFactory{}
+	f := &gitLabClientFactory{ClientFactory: inner}
+	for _, repo := range []string{"group/project", "group/sub/project", "project"} {
+		if _, err := f.ClientFor("gitlab.example.com", repo); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if expected := []string{"group|project", "group/sub|project", "|project"}; !reflect.DeepEqual(inner.cloned, expected) {
+		t.Errorf("expected clones of %v, got %v", expected, inner.cloned)
+	}
+}
+
+func TestGitLabHeadContexts(t *testing.T) {
+	gc := fakegitlab.NewClient()
+	gc.Statuses["group/project"] = map[string][]gitlab.CommitStatus{"head": {
+		{Name: "unit", Status: gitlab.StatusRunning},
+		{Name: "unit", Status: gitlab.StatusSuccess, Description: "Job succeeded."},
+		{Name: "e2e", Status: gitlab.StatusCanceled},
+		{Name: "gitlab-ci", Status: gitlab.StatusPending},
+	}}
+	p := newTestGitLabProvider(&config.Config{}, gc)
+	actual, err := p.headContexts(&CodeReviewCommon{Repo: "group/project", HeadRefOID: "head"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Context{
+		{Context: "unit", Description: "Job succeeded.", State: githubql.StatusStateSuccess},
+		{Context: "e2e", State: githubql.StatusStateFailure},
+		{Context: "gitlab-ci", State: githubql.StatusStatePending},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected contexts (-want +got):\n%s", diff)
+	}
+}
+
+func TestGitLabMergePRs(t *testing.T) {
+	gc := fakegitlab.NewClient()
+	gc.MergeRequests["group/project"] = []gitlab.MergeRequest{
+		{IID: 1, State: "opened", SHA: "tested"},
+		{IID: 2, State: "opened", SHA: "pushed-after-testing"},
+		{IID: 3, State: "opened", SHA: "tested"},
+	}
+	gc.MergeErrors["group/project!3"] = errors.New("boom")
+	cfg := &config.Config{}
+	cfg.Tide.MergeType = map[string]types.PullRequestMergeType{"gitlab.example.com/group/project": types.MergeSquash}
+	p := newTestGitLabProvider(cfg, gc)
+
+	var prs []CodeReviewCommon
+	for _, iid := range []int{1, 2, 3} {
+		prs = append(prs, CodeReviewCommon{Org: "gitlab.example.com", Repo: "group/project", Number: iid, HeadRefOID: "tested"})
+	}
+	sp := subpool{log: logrus.WithField("test", "gitlab"), org: "gitlab.example.com", repo: "group/project", branch: "main"}
+	if err := p.mergePRs(sp, prs, nil); err == nil {
+		t.Error("expected merge errors")
+	}
+	if expected := []string{"group/project!1"}; !reflect.DeepEqual(gc.Merged, expected) {
+		t.Errorf("expected merged %v, got %v", expected, gc.Merged)
+	}
+}
+
+func TestGitLabGetTideContextPolicy(t *testing.T) {
+	presubmits := []config.Presubmit{
+		{AlwaysRun: true, Reporter: config.Reporter{Context: "unit"}},
+		{AlwaysRun: true, Optional: true, Reporter: config.Reporter{Context: "lint"}},
+		{Reporter: config.Reporter{Context: "docs"}, RegexpChangeMatcher: config.RegexpChangeMatcher{RunIfChanged: "^docs/"}},
+		{AlwaysRun: true, Reporter: config.Reporter{Context: "release"}, Brancher: config.Brancher{Branches: []string{"release"}}},
+	}
+	if err := config.SetPresubmitRegexes(presubmits); err != nil {
+		t.Fatalf("failed to set presubmit regexes: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.PresubmitsStatic = map[string][]config.Presubmit{"gitlab.example.com/group/project": presubmits}
+	p := newTestGitLabProvider(cfg, fakegitlab.NewClient())
+
+	actual, err := p.GetTideContextPolicy(nil, "gitlab.example.com", "group/project", "main", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &config.TideContextPolicy{
+		RequiredContexts:          []string{"unit"},
+		RequiredIfPresentContexts: []string{"docs"},
+		OptionalContexts:          []string{"lint"},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected context policy (-want +got):\n%s", diff)
+	}
+}
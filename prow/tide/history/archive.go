@@ -0,0 +1,379 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdio "io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/test-infra/prow/cache"
+	"k8s.io/test-infra/prow/io"
+	"k8s.io/test-infra/prow/io/providers"
+)
+
+const (
+	// partitionLayout is the layout of the daily partitions of the archive.
+	partitionLayout = "2006-01-02"
+	// defaultQueryRange is the time range of queries without a start time.
+	defaultQueryRange = 7 * 24 * time.Hour
+	// maxQueryRange limits how many partitions a single query reads.
+	maxQueryRange = 90 * 24 * time.Hour
+	// maxQueryRecords limits how many records a single query returns.
+	maxQueryRecords = 10000
+	// archiveBatchSize and archiveFlushInterval batch the records of several
+	// flushes into a single object per partition. Records are written once
+	// there are archiveBatchSize of them or the oldest one is pending for
+	// archiveFlushInterval, so a partition holds at most a few hundred
+	// objects.
+	archiveBatchSize     = 1000
+	archiveFlushInterval = 10 * time.Minute
+	// maxArchivePending limits how many records are kept in memory while
+	// writing to the archive fails. The oldest records are dropped first.
+	maxArchivePending = 50000
+	// partitionCacheSize is how many partitions of past days are cached for
+	// queries. Past partitions only change if records are written late.
+	partitionCacheSize = 31
+)
+
+// errTooManyRecords is returned by queries that match more than
+// maxQueryRecords records.
+var errTooManyRecords = fmt.Errorf("query matches more than %d records, narrow it down by repo, branch or time range", maxQueryRecords)
+
+// archiveOpener has methods to read, write and list paths.
+type archiveOpener interface {
+	opener
+	Iterator(ctx context.Context, prefix, delimiter string) (io.ObjectIterator, error)
+}
+
+// ArchivedRecord is a Record together with the pool it belongs to.
+type ArchivedRecord struct {
+	Pool string `json:"pool"`
+	*Record
+}
+
+// archive stores records append-only in daily partitions. Every write puts a
+// batch of records into new objects, existing objects are never rewritten.
+type archive struct {
+	opener archiveOpener
+	// storageProvider, bucket and prefix locate the archive.
+	storageProvider string
+	bucket          string
+	prefix          string
+
+	// partitions caches the records of the partitions of past days by
+	// partition key.
+	partitions *cache.LRUCache
+}
+
+func newArchive(opener archiveOpener, path string) (*archive, error) {
+	storageProvider, bucket, prefix, err := providers.ParseStoragePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("history archive must be a storage path like gs://bucket/path: %w", err)
+	}
+	partitions, err := cache.NewLRUCache(partitionCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create partition cache: %w", err)
+	}
+	return &archive{
+		opener:          opener,
+		storageProvider: storageProvider,
+		bucket:          bucket,
+		prefix:          strings.TrimSuffix(prefix, "/"),
+		partitions:      partitions,
+	}, nil
+}
+
+func (a *archive) objectPath(key string) string {
+	return fmt.Sprintf("%s://%s/%s", a.storageProvider, a.bucket, key)
+}
+
+func (a *archive) partitionKey(day time.Time) string {
+	partition := day.UTC().Format(partitionLayout) + "/"
+	if a.prefix == "" {
+		return partition
+	}
+	return a.prefix + "/" + partition
+}
+
+// write writes records into new objects of their partitions. It returns the
+// records that could not be written.
+func (a *archive) write(records []ArchivedRecord) ([]ArchivedRecord, error) {
+	byPartition := map[string][]ArchivedRecord{}
+	for _, rec := range records {
+		key := a.partitionKey(rec.Time)
+		byPartition[key] = append(byPartition[key], rec)
+	}
+
+	var failed []ArchivedRecord
+	var errs []error
+	for key, records := range byPartition {
+		path := a.objectPath(key + strconv.FormatInt(now().UnixNano(), 10) + ".json")
+		if err := writeArchivedRecords(a.opener, path, records); err != nil {
+			failed = append(failed, records...)
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		// Records can be written late into partitions of past days, e.g.
+		// after failed writes, so their cached records are outdated.
+		a.partitions.Lock()
+		a.partitions.Remove(key)
+		a.partitions.Unlock()
+	}
+	return failed, utilerrors.NewAggregate(errs)
+}
+
+func writeArchivedRecords(opener opener, path string, records []ArchivedRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	b, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	writer, err := opener.Writer(ctx, path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	if _, err := writer.Write(b); err != nil {
+		io.LogClose(writer)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
+
+// query reads all partitions that overlap with the time range of q and returns
+// the matching records, including the pending ones that were not written yet.
+// It fails with errTooManyRecords if more than maxQueryRecords records match.
+func (a *archive) query(ctx context.Context, q Query, pending []ArchivedRecord) ([]ArchivedRecord, error) {
+	var res []ArchivedRecord
+	add := func(records []ArchivedRecord) error {
+		for _, rec := range records {
+			if !q.matches(rec) {
+				continue
+			}
+			if len(res) == maxQueryRecords {
+				return errTooManyRecords
+			}
+			res = append(res, rec)
+		}
+		return nil
+	}
+	if err := add(pending); err != nil {
+		return nil, err
+	}
+
+	today := now().UTC().Truncate(24 * time.Hour)
+	for day := q.From.UTC().Truncate(24 * time.Hour); !day.After(q.To); day = day.Add(24 * time.Hour) {
+		var records []ArchivedRecord
+		var err error
+		if day.Before(today) {
+			records, err = a.cachedPartition(ctx, day)
+		} else {
+			records, err = a.readPartition(ctx, day)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := add(records); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// cachedPartition returns the records of the partition of a past day, reading
+// it only if it is not cached yet.
+func (a *archive) cachedPartition(ctx context.Context, day time.Time) ([]ArchivedRecord, error) {
+	val, _, err := a.partitions.GetOrAdd(a.partitionKey(day), func() (interface{}, error) {
+		return a.readPartition(ctx, day)
+	})
+	if err != nil {
+		return nil, err
+	}
+	records, ok := val.([]ArchivedRecord)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T in partition cache", val)
+	}
+	return records, nil
+}
+
+// readPartition reads all records of the partition of the day.
+func (a *archive) readPartition(ctx context.Context, day time.Time) ([]ArchivedRecord, error) {
+	it, err := a.opener.Iterator(ctx, a.objectPath(a.partitionKey(day)), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partition %s: %w", day.Format(partitionLayout), err)
+	}
+	var res []ArchivedRecord
+	for {
+		attrs, err := it.Next(ctx)
+		if errors.Is(err, stdio.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list partition %s: %w", day.Format(partitionLayout), err)
+		}
+		if attrs.IsDir || !strings.HasSuffix(attrs.Name, ".json") {
+			continue
+		}
+		records, err := readArchivedRecords(ctx, a.opener, a.objectPath(attrs.Name))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, records...)
+	}
+	return res, nil
+}
+
+func readArchivedRecords(ctx context.Context, opener opener, path string) ([]ArchivedRecord, error) {
+	reader, err := opener.Reader(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer io.LogClose(reader)
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var records []ArchivedRecord
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Query selects archived records. Empty fields match all records.
+type Query struct {
+	// Repo is the org/repo of the pool.
+	Repo   string
+	Branch string
+	// PR and Author match records that target the PR or a PR of the author.
+	PR     int
+	Author string
+	Action string
+	// From and To limit the time range, both are inclusive.
+	From time.Time
+	To   time.Time
+}
+
+// queryParameters are the URL parameters that ParseQuery understands.
+var queryParameters = []string{"repo", "branch", "pr", "author", "action", "from", "to"}
+
+// IsQuery returns true if values contain any query parameters.
+func IsQuery(values url.Values) bool {
+	for _, param := range queryParameters {
+		if _, ok := values[param]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseQuery parses a Query from the repo, branch, pr, author, action, from
+// and to URL parameters. Times are RFC3339. Without a start time, the query
+// covers the week before its end time, which defaults to now.
+func ParseQuery(values url.Values) (Query, error) {
+	q := Query{
+		Repo:   values.Get("repo"),
+		Branch: values.Get("branch"),
+		Author: values.Get("author"),
+		Action: values.Get("action"),
+		To:     now(),
+	}
+	if pr := values.Get("pr"); pr != "" {
+		number, err := strconv.Atoi(pr)
+		if err != nil {
+			return q, fmt.Errorf("invalid pr %q: %w", pr, err)
+		}
+		q.PR = number
+	}
+	if to := values.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return q, fmt.Errorf("invalid to %q: %w", to, err)
+		}
+		q.To = t
+	}
+	q.From = q.To.Add(-defaultQueryRange)
+	if from := values.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return q, fmt.Errorf("invalid from %q: %w", from, err)
+		}
+		q.From = t
+	}
+	if q.From.After(q.To) {
+		return q, fmt.Errorf("from %s is after to %s", q.From.Format(time.RFC3339), q.To.Format(time.RFC3339))
+	}
+	if q.To.Sub(q.From) > maxQueryRange {
+		return q, fmt.Errorf("time range must not exceed %s", maxQueryRange)
+	}
+	return q, nil
+}
+
+func (q Query) matches(rec ArchivedRecord) bool {
+	if rec.Record == nil || rec.Time.Before(q.From) || rec.Time.After(q.To) {
+		return false
+	}
+	repo, branch := rec.Pool, ""
+	if i := strings.LastIndex(rec.Pool, ":"); i >= 0 {
+		repo, branch = rec.Pool[:i], rec.Pool[i+1:]
+	}
+	if q.Repo != "" && q.Repo != repo {
+		return false
+	}
+	if q.Branch != "" && q.Branch != branch {
+		return false
+	}
+	if q.Action != "" && q.Action != rec.Action {
+		return false
+	}
+	if q.PR == 0 && q.Author == "" {
+		return true
+	}
+	for _, pull := range rec.Target {
+		if (q.PR == 0 || pull.Number == q.PR) && (q.Author == "" || strings.EqualFold(pull.Author, q.Author)) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByPool turns records into a map from pool key -> records for the pool,
+// newest first, the same format that AllRecords returns.
+func groupByPool(records []ArchivedRecord) map[string][]*Record {
+	res := map[string][]*Record{}
+	for _, rec := range records {
+		res[rec.Pool] = append(res[rec.Pool], rec.Record)
+	}
+	for _, recs := range res {
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].Time.After(recs[j].Time) })
+	}
+	return res
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	pkgio "k8s.io/test-infra/prow/io"
+)
+
+func TestArchive(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "tide"), 0755); err != nil {
+		t.Fatalf("Failed to create bucket directory: %v", err)
+	}
+	opener, err := pkgio.NewOpenerWithOptions(context.Background(), pkgio.OpenerOptions{FileStorageRoot: root})
+	if err != nil {
+		t.Fatalf("Failed to create opener: %v", err)
+	}
+	hist, err := NewWithArchive(1, opener, "", "file://tide/history")
+	if err != nil {
+		t.Fatalf("Failed to create history: %v", err)
+	}
+
+	day := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	oldNow := now
+	defer func() { now = oldNow }()
+	record := func(t time.Time, poolKey, action string, pulls ...prowapi.Pull) {
+		now = func() time.Time { return t }
+		hist.Record(poolKey, action, "sha", "", pulls, nil)
+	}
+
+	record(day, "o/r:master", "MERGE", prowapi.Pull{Number: 1, Author: "alice"})
+	record(day.Add(time.Hour), "o/r:master", "TRIGGER", prowapi.Pull{Number: 2, Author: "bob"})
+	record(day.Add(24*time.Hour), "o/other:master", "MERGE", prowapi.Pull{Number: 3, Author: "alice"})
+	hist.Flush()
+	if len(hist.archivePending) != 0 {
+		t.Fatalf("Expected all records to be archived, %d are pending.", len(hist.archivePending))
+	}
+	// Not flushed yet, but should be included in query results.
+	record(day.Add(48*time.Hour), "o/r:release", "MERGE", prowapi.Pull{Number: 4, Author: "alice"})
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected map[string][]int
+	}{
+		{
+			name:  "all records of the week",
+			query: "to=2022-06-05T00:00:00Z",
+			expected: map[string][]int{
+				"o/r:master":     {2, 1},
+				"o/other:master": {3},
+				"o/r:release":    {4},
+			},
+		},
+		{
+			name:     "by repo and branch",
+			query:    "repo=o/r&branch=master&to=2022-06-05T00:00:00Z",
+			expected: map[string][]int{"o/r:master": {2, 1}},
+		},
+		{
+			name:  "by author",
+			query: "author=alice&to=2022-06-05T00:00:00Z",
+			expected: map[string][]int{
+				"o/r:master":     {1},
+				"o/other:master": {3},
+				"o/r:release":    {4},
+			},
+		},
+		{
+			name:     "by PR and action",
+			query:    "pr=3&action=MERGE&to=2022-06-05T00:00:00Z",
+			expected: map[string][]int{"o/other:master": {3}},
+		},
+		{
+			name:     "by time range",
+			query:    "from=2022-06-01T12:30:00Z&to=2022-06-02T12:00:00Z",
+			expected: map[string][]int{"o/r:master": {2}, "o/other:master": {3}},
+		},
+		{
+			name:     "no matches",
+			query:    "repo=o/missing&to=2022-06-05T00:00:00Z",
+			expected: map[string][]int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			hist.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/history?"+tc.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			var records map[string][]*Record
+			if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			got := map[string][]int{}
+			for poolKey, recs := range records {
+				for _, rec := range recs {
+					got[poolKey] = append(got[poolKey], rec.Target[0].Number)
+				}
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("Query results differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The record log only keeps the latest record, the archive keeps all of them.
+	if recs := hist.AllRecords()["o/r:master"]; len(recs) != 1 {
+		t.Errorf("Expected the record log to keep 1 record, got %d.", len(recs))
+	}
+}
+
+func TestQueryWithoutArchive(t *testing.T) {
+	hist, err := New(10, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to create history: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	hist.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/history?repo=o/r", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d.", http.StatusInternalServerError, rr.Code)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	nowTime := time.Date(2022, time.June, 8, 0, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	testCases := []struct {
+		name        string
+		query       string
+		expected    Query
+		expectedErr bool
+	}{
+		{
+			name:     "defaults to the last week",
+			query:    "repo=o/r",
+			expected: Query{Repo: "o/r", From: nowTime.Add(-7 * 24 * time.Hour), To: nowTime},
+		},
+		{
+			name:  "all parameters",
+			query: "repo=o/r&branch=master&pr=1&author=alice&action=MERGE&from=2022-05-01T00:00:00Z&to=2022-05-02T00:00:00Z",
+			expected: Query{
+				Repo:   "o/r",
+				Branch: "master",
+				PR:     1,
+				Author: "alice",
+				Action: "MERGE",
+				From:   time.Date(2022, time.May, 1, 0, 0, 0, 0, time.UTC),
+				To:     time.Date(2022, time.May, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:        "invalid PR number",
+			query:       "pr=one",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid time",
+			query:       "from=yesterday",
+			expectedErr: true,
+		},
+		{
+			name:        "from after to",
+			query:       "from=2022-05-02T00:00:00Z&to=2022-05-01T00:00:00Z",
+			expectedErr: true,
+		},
+		{
+			name:        "time range too large",
+			query:       "from=2022-01-01T00:00:00Z",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+			if !IsQuery(values) {
+				t.Fatalf("Expected %q to be a query.", tc.query)
+			}
+			q, err := ParseQuery(values)
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("Expected an error, got none.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, q); diff != "" {
+				t.Errorf("Query differs from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestArchiveBatching(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "tide"), 0755); err != nil {
+		t.Fatalf("Failed to create bucket directory: %v", err)
+	}
+	opener, err := pkgio.NewOpenerWithOptions(context.Background(), pkgio.OpenerOptions{FileStorageRoot: root})
+	if err != nil {
+		t.Fatalf("Failed to create opener: %v", err)
+	}
+	hist, err := NewWithArchive(1, opener, "", "file://tide/history")
+	if err != nil {
+		t.Fatalf("Failed to create history: %v", err)
+	}
+
+	day := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return day }
+	hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: 1}}, nil)
+	countObjects := func() int {
+		entries, err := os.ReadDir(filepath.Join(root, "tide", "history", "2022-06-01"))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("Failed to list partition: %v", err)
+		}
+		return len(entries)
+	}
+
+	hist.Flush()
+	if n := countObjects(); n != 0 {
+		t.Errorf("Expected a single record not to be archived before the flush interval, found %d objects.", n)
+	}
+	now = func() time.Time { return day.Add(time.Minute) }
+	hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: 2}}, nil)
+	now = func() time.Time { return day.Add(archiveFlushInterval) }
+	hist.Flush()
+	if n := countObjects(); n != 1 {
+		t.Errorf("Expected the records to be archived in a single object after the flush interval, found %d objects.", n)
+	}
+
+	hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: 3}}, nil)
+	hist.Flush()
+	if n := countObjects(); n != 1 {
+		t.Errorf("Expected the new record to be batched, found %d objects.", n)
+	}
+	now = func() time.Time { return day.Add(archiveFlushInterval + time.Minute) }
+	hist.FlushArchive()
+	if n := countObjects(); n != 2 {
+		t.Errorf("Expected FlushArchive to write the pending record, found %d objects.", n)
+	}
+	if len(hist.archivePending) != 0 {
+		t.Errorf("Expected all records to be archived, %d are pending.", len(hist.archivePending))
+	}
+}
+
+func TestArchiveQueryCache(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "tide"), 0755); err != nil {
+		t.Fatalf("Failed to create bucket directory: %v", err)
+	}
+	opener, err := pkgio.NewOpenerWithOptions(context.Background(), pkgio.OpenerOptions{FileStorageRoot: root})
+	if err != nil {
+		t.Fatalf("Failed to create opener: %v", err)
+	}
+	hist, err := NewWithArchive(1, opener, "", "file://tide/history")
+	if err != nil {
+		t.Fatalf("Failed to create history: %v", err)
+	}
+
+	day := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return day }
+	hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: 1}}, nil)
+	hist.FlushArchive()
+
+	now = func() time.Time { return day.Add(48 * time.Hour) }
+	q := Query{From: day.Add(-time.Hour), To: day.Add(time.Hour)}
+	query := func() int {
+		records, err := hist.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Failed to query: %v", err)
+		}
+		return len(records["o/r:master"])
+	}
+	if n := query(); n != 1 {
+		t.Fatalf("Expected 1 record, got %d.", n)
+	}
+
+	// Records written by other means are not visible while the partition of
+	// the past day is cached.
+	if err := writeArchivedRecords(opener, "file://tide/history/2022-06-01/external.json", []ArchivedRecord{
+		{Pool: "o/r:master", Record: &Record{Time: day, Action: "MERGE", Target: []prowapi.Pull{{Number: 2}}}},
+	}); err != nil {
+		t.Fatalf("Failed to write records: %v", err)
+	}
+	if n := query(); n != 1 {
+		t.Errorf("Expected the cached partition to be used, got %d records.", n)
+	}
+
+	// Late records written by the history invalidate the cached partition.
+	now = func() time.Time { return day.Add(time.Minute) }
+	hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: 3}}, nil)
+	now = func() time.Time { return day.Add(48 * time.Hour) }
+	hist.FlushArchive()
+	if n := query(); n != 3 {
+		t.Errorf("Expected the partition to be read again, got %d records.", n)
+	}
+}
+
+func TestArchiveLimits(t *testing.T) {
+	hist := &History{logs: map[string]*recordLog{}, logSizeLimit: 1, archive: &archive{}}
+	oldNow := now
+	defer func() { now = oldNow }()
+	day := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return day }
+	for i := 1; i <= maxArchivePending+1; i++ {
+		hist.Record("o/r:master", "MERGE", "sha", "", []prowapi.Pull{{Number: i}}, nil)
+	}
+	if n := len(hist.archivePending); n != maxArchivePending {
+		t.Fatalf("Expected %d pending records, got %d.", maxArchivePending, n)
+	}
+	if oldest := hist.archivePending[0].Target[0].Number; oldest != 2 {
+		t.Errorf("Expected the oldest record to be dropped, the oldest pending one is for PR %d.", oldest)
+	}
+
+	rr := httptest.NewRecorder()
+	hist.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/history?repo=o/r&to=2022-06-01T12:00:00Z&from=2022-06-01T12:00:00Z", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for too many records, got %d.", http.StatusBadRequest, rr.Code)
+	}
+}
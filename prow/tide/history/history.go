@@ -15,12 +15,14 @@ limitations under the License.
 */
 
 // Package history provides an append only, size limited log of recent actions
-// that Tide has taken for each subpool.
+// that Tide has taken for each subpool, and optionally an archive of all
+// actions that can be queried.
 package history
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -47,6 +49,11 @@ type History struct {
 
 	opener opener
 	path   string
+
+	// archive keeps all records, it is nil if no archive is configured.
+	archive *archive
+	// archivePending holds the records that were not archived yet.
+	archivePending []ArchivedRecord
 }
 
 // opener has methods to read and write paths
@@ -124,6 +131,13 @@ type Record struct {
 
 // New creates a new History struct with the specificed recordLog size limit.
 func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error) {
+	return NewWithArchive(maxRecordsPerKey, opener, path, "")
+}
+
+// NewWithArchive creates a new History struct that additionally appends every
+// record to the archive at archivePath, if it is not empty. Unlike the
+// recordLogs, the archive is not size limited and can be queried.
+func NewWithArchive(maxRecordsPerKey int, opener io.Opener, path, archivePath string) (*History, error) {
 	hist := &History{
 		logs:         map[string]*recordLog{},
 		logSizeLimit: maxRecordsPerKey,
@@ -145,6 +159,13 @@ func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error)
 		}).Debugf("Successfully read action history for %d pools.", len(hist.logs))
 	}
 
+	if archivePath != "" {
+		var err error
+		if hist.archive, err = newArchive(opener, archivePath); err != nil {
+			return nil, err
+		}
+	}
+
 	return hist, nil
 }
 
@@ -172,11 +193,42 @@ func (h *History) addRecord(poolKey string, rec *Record) {
 		h.logs[poolKey] = newRecordLog(h.logSizeLimit)
 	}
 	h.logs[poolKey].add(rec)
+	if h.archive != nil {
+		h.archivePending = append(h.archivePending, ArchivedRecord{Pool: poolKey, Record: rec})
+		h.limitArchivePending()
+	}
+}
+
+// limitArchivePending drops the oldest pending records if there are more than
+// maxArchivePending of them. It must be called with the lock held.
+func (h *History) limitArchivePending() {
+	if dropped := len(h.archivePending) - maxArchivePending; dropped > 0 {
+		logrus.WithField("dropped", dropped).Warning("Too many records are pending for the action history archive, dropping the oldest ones.")
+		h.archivePending = append([]ArchivedRecord(nil), h.archivePending[dropped:]...)
+	}
 }
 
 // ServeHTTP serves a JSON mapping from pool key -> sorted records for the pool.
+// If the request has query parameters, the records are looked up in the
+// archive instead, see ParseQuery.
 func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	b, err := json.Marshal(h.AllRecords())
+	records := h.AllRecords()
+	if values := r.URL.Query(); IsQuery(values) {
+		q, err := ParseQuery(values)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if records, err = h.Query(r.Context(), q); errors.Is(err, errTooManyRecords) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logrus.WithError(err).Error("Querying history archive.")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	b, err := json.Marshal(records)
 	if err != nil {
 		logrus.WithError(err).Error("Encoding JSON history.")
 		b = []byte("{}")
@@ -186,8 +238,27 @@ func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Query returns the archived records that match q as a map from pool key ->
+// sorted records for the pool.
+func (h *History) Query(ctx context.Context, q Query) (map[string][]*Record, error) {
+	if h.archive == nil {
+		return nil, errors.New("no history archive is configured")
+	}
+	h.Lock()
+	pending := append([]ArchivedRecord(nil), h.archivePending...)
+	h.Unlock()
+
+	records, err := h.archive.query(ctx, q, pending)
+	if err != nil {
+		return nil, err
+	}
+	return groupByPool(records), nil
+}
+
 // Flush writes the action history to persistent storage if configured to do so.
+// Records are appended to the archive in batches, see FlushArchive.
 func (h *History) Flush() {
+	h.flushArchive(false)
 	if h.path == "" {
 		return
 	}
@@ -205,6 +276,42 @@ func (h *History) Flush() {
 	}
 }
 
+// FlushArchive appends all pending records to the archive, even if they do not
+// make up a full batch yet. It should be called before shutting down.
+func (h *History) FlushArchive() {
+	h.flushArchive(true)
+}
+
+// flushArchive appends the pending records to the archive once there are
+// archiveBatchSize of them or the oldest one is pending for
+// archiveFlushInterval, or always if force is set.
+func (h *History) flushArchive(force bool) {
+	if h.archive == nil {
+		return
+	}
+	h.Lock()
+	pending := h.archivePending
+	if len(pending) == 0 || !force && len(pending) < archiveBatchSize && now().Sub(pending[0].Time) < archiveFlushInterval {
+		h.Unlock()
+		return
+	}
+	h.archivePending = nil
+	h.Unlock()
+
+	start := time.Now()
+	failed, err := h.archive.write(pending)
+	log := logrus.WithField("duration", time.Since(start).String())
+	if err != nil {
+		log.WithError(err).Error("Error appending action history to the archive.")
+		h.Lock()
+		h.archivePending = append(failed, h.archivePending...)
+		h.limitArchivePending()
+		h.Unlock()
+		return
+	}
+	log.Debugf("Successfully archived %d action history records.", len(pending))
+}
+
 // AllRecords generates a map from pool key -> sorted records for the pool.
 func (h *History) AllRecords() map[string][]*Record {
 	h.Lock()
@@ -0,0 +1,418 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type policyGitHubClient interface {
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+}
+
+type ownersLoader interface {
+	LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error)
+}
+
+// policyEvaluator checks PRs against the merge policies configured for their
+// branch. Policies only apply to GitHub PRs, a nil policyEvaluator allows all
+// PRs to merge.
+type policyEvaluator struct {
+	config       config.Getter
+	changedFiles *changedFilesAgent
+	reviews      *reviewsAgent
+	owners       ownersLoader
+
+	// expressions caches the parsed expressions of policies.
+	expressions     map[string]*template.Template
+	expressionsLock sync.Mutex
+}
+
+func newPolicyEvaluator(cfg config.Getter, changedFiles *changedFilesAgent, ghc policyGitHubClient, owners ownersLoader) *policyEvaluator {
+	return &policyEvaluator{
+		config:       cfg,
+		changedFiles: changedFiles,
+		reviews: &reviewsAgent{
+			ghc:       ghc,
+			nextCache: make(map[reviewsCacheKey][]github.Review),
+		},
+		owners:      owners,
+		expressions: map[string]*template.Template{},
+	}
+}
+
+// prune expires the cached reviews that were not used since the last prune.
+// The changed files are pruned by the sync controller that shares them.
+func (p *policyEvaluator) prune() {
+	if p == nil {
+		return
+	}
+	p.reviews.prune()
+}
+
+func (p *policyEvaluator) expression(require *config.TidePolicyRequirements) (*template.Template, error) {
+	p.expressionsLock.Lock()
+	defer p.expressionsLock.Unlock()
+	if tmpl, ok := p.expressions[require.Expression]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := require.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	p.expressions[require.Expression] = tmpl
+	return tmpl, nil
+}
+
+// unmetPolicies returns why the PR does not satisfy the merge policies of its
+// branch, or "" if it satisfies all of them.
+func (p *policyEvaluator) unmetPolicies(crc *CodeReviewCommon) (string, error) {
+	if p == nil || crc.GitHub == nil {
+		return "", nil
+	}
+	policies := p.config().Tide.MergePoliciesFor(crc.Org, crc.Repo, crc.BaseRefName)
+	if len(policies) == 0 {
+		return "", nil
+	}
+
+	in := &policyInput{evaluator: p, crc: crc, labels: sets.NewString()}
+	for _, label := range crc.GitHub.Labels.Nodes {
+		in.labels.Insert(string(label.Name))
+	}
+	var reasons []string
+	for _, policy := range policies {
+		applies, err := in.matches(&policy.If)
+		if err != nil {
+			return "", fmt.Errorf("failed to check conditions of merge policy %s: %w", policy.Name, err)
+		}
+		if !applies {
+			continue
+		}
+		unmet, err := in.unmet(&policy)
+		if err != nil {
+			return "", fmt.Errorf("failed to check requirements of merge policy %s: %w", policy.Name, err)
+		}
+		if len(unmet) > 0 {
+			reasons = append(reasons, fmt.Sprintf("Merge policy %s requires %s.", policy.Name, strings.Join(unmet, " and ")))
+		}
+	}
+	return strings.Join(reasons, " "), nil
+}
+
+// policyInput is the data of a PR that policies are evaluated against. The
+// changed files and reviews are only fetched if a policy needs them.
+type policyInput struct {
+	evaluator *policyEvaluator
+	crc       *CodeReviewCommon
+	labels    sets.String
+
+	approvals []string
+}
+
+func (in *policyInput) changedFiles() ([]string, error) {
+	return in.evaluator.changedFiles.prChanges(in.crc)()
+}
+
+// approvers returns the reviewers whose latest review approves the PR
+func (in *policyInput) approvers() ([]string, error) {
+	if in.approvals != nil {
+		return in.approvals, nil
+	}
+	reviews, err := in.evaluator.reviews.prReviews(in.crc)
+	if err != nil {
+		return nil, err
+	}
+	// reviews are listed in chronological order
+	latest := map[string]github.ReviewState{}
+	for _, review := range reviews {
+		if review.State == github.ReviewStateCommented || review.State == github.ReviewStatePending {
+			continue
+		}
+		latest[github.NormLogin(review.User.Login)] = review.State
+	}
+	in.approvals = []string{}
+	for login, state := range latest {
+		if state == github.ReviewStateApproved {
+			in.approvals = append(in.approvals, login)
+		}
+	}
+	return in.approvals, nil
+}
+
+func (in *policyInput) matches(conditions *config.TidePolicyConditions) (bool, error) {
+	if !in.labels.HasAll(conditions.Labels...) {
+		return false, nil
+	}
+	if conditions.ChangedFiles == "" {
+		return true, nil
+	}
+	files, err := in.changedFiles()
+	if err != nil {
+		return false, err
+	}
+	return conditions.MatchesFiles(files)
+}
+
+// unmet lists the requirements of the policy that the PR does not satisfy
+func (in *policyInput) unmet(policy *config.TideMergePolicy) ([]string, error) {
+	require := policy.Require
+	var unmet []string
+	if missing := sets.NewString(require.Labels...).Difference(in.labels); missing.Len() > 0 {
+		unmet = append(unmet, fmt.Sprintf("%s %s", plural("label", missing.Len()), strings.Join(missing.List(), ", ")))
+	}
+	if present := sets.NewString(require.MissingLabels...).Intersection(in.labels); present.Len() > 0 {
+		unmet = append(unmet, fmt.Sprintf("no %s %s", plural("label", present.Len()), strings.Join(present.List(), ", ")))
+	}
+	if require.Approvals > 0 {
+		desc, err := in.unmetApprovals(policy)
+		if err != nil {
+			return nil, err
+		}
+		if desc != "" {
+			unmet = append(unmet, desc)
+		}
+	}
+	if require.Expression != "" {
+		desc, err := in.unmetExpression(policy)
+		if err != nil {
+			return nil, err
+		}
+		if desc != "" {
+			unmet = append(unmet, desc)
+		}
+	}
+	return unmet, nil
+}
+
+// unmetApprovals describes the approvals that the policy requires but the PR
+// does not have, or returns "".
+func (in *policyInput) unmetApprovals(policy *config.TideMergePolicy) (string, error) {
+	require := policy.Require
+	approvers, err := in.approvers()
+	if err != nil {
+		return "", err
+	}
+	counted := sets.NewString(approvers...)
+	if len(require.Approvers) > 0 {
+		eligible := sets.NewString()
+		for _, login := range require.Approvers {
+			eligible.Insert(github.NormLogin(login))
+		}
+		counted = counted.Intersection(eligible)
+	}
+	if require.OwnersApprovers && counted.Len() > 0 {
+		eligible, err := in.ownersApprovers(&policy.If)
+		if err != nil {
+			return "", err
+		}
+		counted = counted.Intersection(eligible)
+	}
+
+	var desc string
+	if counted.Len() < require.Approvals {
+		desc = fmt.Sprintf("%d %s", require.Approvals, plural("approval", require.Approvals))
+	}
+	if require.DistinctGroups > 0 {
+		groups := sets.NewString()
+		for name, members := range require.Groups {
+			for _, login := range members {
+				if counted.Has(github.NormLogin(login)) {
+					groups.Insert(name)
+					break
+				}
+			}
+		}
+		if groups.Len() < require.DistinctGroups {
+			if desc == "" {
+				desc = "approvals"
+			}
+			desc = fmt.Sprintf("%s from %d groups", desc, require.DistinctGroups)
+		}
+	}
+	return desc, nil
+}
+
+// unmetExpression returns what the expression of the policy renders for the
+// PR, which is "" if the PR satisfies it.
+func (in *policyInput) unmetExpression(policy *config.TideMergePolicy) (string, error) {
+	tmpl, err := in.evaluator.expression(&policy.Require)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse expression: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, &policyExpressionData{in: in, conditions: &policy.If}); err != nil {
+		return "", fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// ownersApprovers returns the approvers of the changed files that match the
+// conditions according to the OWNERS files of the base branch
+func (in *policyInput) ownersApprovers(conditions *config.TidePolicyConditions) (sets.String, error) {
+	if in.evaluator.owners == nil {
+		return nil, errors.New("OWNERS files are not available")
+	}
+	owners, err := in.evaluator.owners.LoadRepoOwners(in.crc.Org, in.crc.Repo, in.crc.BaseRefName)
+	if err != nil {
+		return nil, fmt.Errorf("error loading RepoOwners: %w", err)
+	}
+	files, err := in.changedFiles()
+	if err != nil {
+		return nil, err
+	}
+	approvers := sets.NewString()
+	for _, file := range files {
+		if matches, err := conditions.MatchesFiles([]string{file}); err != nil {
+			return nil, err
+		} else if !matches {
+			continue
+		}
+		for _, login := range owners.Approvers(file).UnsortedList() {
+			approvers.Insert(github.NormLogin(login))
+		}
+	}
+	return approvers, nil
+}
+
+// policyExpressionData is what expressions of merge policies are evaluated
+// against, see config.TidePolicyRequirements.Expression. Changed files,
+// reviews and OWNERS files are only fetched if the expression uses them.
+type policyExpressionData struct {
+	in         *policyInput
+	conditions *config.TidePolicyConditions
+}
+
+func (d *policyExpressionData) Org() string {
+	return d.in.crc.Org
+}
+
+func (d *policyExpressionData) Repo() string {
+	return d.in.crc.Repo
+}
+
+func (d *policyExpressionData) Branch() string {
+	return d.in.crc.BaseRefName
+}
+
+func (d *policyExpressionData) Number() int {
+	return d.in.crc.Number
+}
+
+func (d *policyExpressionData) Author() string {
+	return github.NormLogin(d.in.crc.AuthorLogin)
+}
+
+func (d *policyExpressionData) Labels() []string {
+	return d.in.labels.List()
+}
+
+func (d *policyExpressionData) ChangedFiles() ([]string, error) {
+	return d.in.changedFiles()
+}
+
+// Approvers are the normalized logins of the reviewers whose latest review
+// approves the PR.
+func (d *policyExpressionData) Approvers() ([]string, error) {
+	approvers, err := d.in.approvers()
+	if err != nil {
+		return nil, err
+	}
+	return sets.NewString(approvers...).List(), nil
+}
+
+// OwnersApprovers are the normalized logins of the OWNERS approvers of the
+// changed files matching the conditions of the policy.
+func (d *policyExpressionData) OwnersApprovers() ([]string, error) {
+	approvers, err := d.in.ownersApprovers(d.conditions)
+	if err != nil {
+		return nil, err
+	}
+	return approvers.List(), nil
+}
+
+// reviewsAgent queries and caches the reviews of PRs. Entries are keyed by the
+// head SHA and the update time of PRs, which changes with every review, and
+// expire if they are not used between two prunes.
+type reviewsAgent struct {
+	ghc       policyGitHubClient
+	cache     map[reviewsCacheKey][]github.Review
+	nextCache map[reviewsCacheKey][]github.Review
+	sync.Mutex
+}
+
+type reviewsCacheKey struct {
+	org, repo string
+	number    int
+	sha       string
+	updatedAt time.Time
+}
+
+func (a *reviewsAgent) prReviews(pr *CodeReviewCommon) ([]github.Review, error) {
+	key := reviewsCacheKey{
+		org:       pr.Org,
+		repo:      pr.Repo,
+		number:    pr.Number,
+		sha:       pr.HeadRefOID,
+		updatedAt: pr.UpdatedAtTime,
+	}
+	a.Lock()
+	reviews, ok := a.nextCache[key]
+	if !ok {
+		if reviews, ok = a.cache[key]; ok {
+			a.nextCache[key] = reviews
+		}
+	}
+	a.Unlock()
+	if ok {
+		return reviews, nil
+	}
+
+	reviews, err := a.ghc.ListReviews(pr.Org, pr.Repo, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("error listing reviews of #%d: %w", pr.Number, err)
+	}
+	a.Lock()
+	a.nextCache[key] = reviews
+	a.Unlock()
+	return reviews, nil
+}
+
+func (a *reviewsAgent) prune() {
+	a.Lock()
+	defer a.Unlock()
+	a.cache = a.nextCache
+	a.nextCache = make(map[reviewsCacheKey][]github.Review)
+}
+
+func plural(word string, count int) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
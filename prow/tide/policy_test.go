@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/pkg/layeredsets"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakePolicyGitHubClient struct {
+	githubClient
+	changes map[int][]string
+	reviews map[int][]github.Review
+
+	changesCalls, reviewsCalls int
+}
+
+func (f *fakePolicyGitHubClient) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	f.changesCalls++
+	var changes []github.PullRequestChange
+	for _, file := range f.changes[number] {
+		changes = append(changes, github.PullRequestChange{Filename: file})
+	}
+	return changes, nil
+}
+
+func (f *fakePolicyGitHubClient) ListReviews(org, repo string, number int) ([]github.Review, error) {
+	f.reviewsCalls++
+	return f.reviews[number], nil
+}
+
+type fakePolicyOwners struct {
+	repoowners.RepoOwner
+	approvers map[string]layeredsets.String
+}
+
+func (f *fakePolicyOwners) LoadRepoOwners(org, repo, base string) (repoowners.RepoOwner, error) {
+	return f, nil
+}
+
+func (f *fakePolicyOwners) Approvers(path string) layeredsets.String {
+	return f.approvers[path]
+}
+
+func review(login string, state github.ReviewState) github.Review {
+	return github.Review{User: github.User{Login: login}, State: state}
+}
+
+func TestUnmetPolicies(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Tide.MergePolicies = []config.TideMergePolicy{
+		{
+			Name:  "api-review",
+			Repos: []string{"org/repo"},
+			If:    config.TidePolicyConditions{ChangedFiles: "^api/"},
+			Require: config.TidePolicyRequirements{
+				Approvals:       2,
+				OwnersApprovers: true,
+				Groups: map[string][]string{
+					"company-a": {"alice", "carol"},
+					"company-b": {"bob"},
+				},
+				DistinctGroups: 2,
+			},
+		},
+		{
+			Name: "security",
+			Orgs: []string{"org"},
+			If:   config.TidePolicyConditions{Labels: []string{"security"}},
+			Require: config.TidePolicyRequirements{
+				MissingLabels: []string{"do-not-merge/hold"},
+				Approvals:     1,
+				Approvers:     []string{"Sec-Lead"},
+			},
+		},
+		{
+			Name:  "release-notes",
+			Repos: []string{"org/repo"},
+			If:    config.TidePolicyConditions{ChangedFiles: "^api/"},
+			Require: config.TidePolicyRequirements{
+				Expression: `{{if and (matching "^api/types.go$" .ChangedFiles) (not (intersect .Approvers .OwnersApprovers))}}` +
+					`an approval of an OWNERS approver of {{.Repo}} for the types{{end}}`,
+			},
+		},
+	}
+	owners := &fakePolicyOwners{approvers: map[string]layeredsets.String{
+		"api/types.go": layeredsets.NewString("alice", "bob", "carol"),
+		"api/other.go": layeredsets.NewString("alice", "carol"),
+	}}
+
+	testCases := []struct {
+		name           string
+		labels         []string
+		changes        []string
+		reviews        []github.Review
+		notGitHub      bool
+		expectedReason string
+	}{
+		{
+			name:    "no policy applies",
+			changes: []string{"docs/README.md"},
+		},
+		{
+			name:    "approvals from distinct groups",
+			changes: []string{"api/types.go"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateApproved),
+				review("Bob", github.ReviewStateApproved),
+			},
+		},
+		{
+			name:    "approvals from a single group",
+			changes: []string{"api/types.go"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateApproved),
+				review("carol", github.ReviewStateApproved),
+			},
+			expectedReason: "Merge policy api-review requires approvals from 2 groups.",
+		},
+		{
+			name:    "comments do not withdraw an approval but requested changes do",
+			changes: []string{"api/types.go"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateApproved),
+				review("alice", github.ReviewStateCommented),
+				review("bob", github.ReviewStateApproved),
+				review("bob", github.ReviewStateChangesRequested),
+			},
+			expectedReason: "Merge policy api-review requires 2 approvals from 2 groups.",
+		},
+		{
+			name:    "approvals from reviewers who are not OWNERS approvers are not counted",
+			changes: []string{"api/other.go", "README.md"},
+			reviews: []github.Review{
+				review("alice", github.ReviewStateApproved),
+				review("bob", github.ReviewStateApproved),
+			},
+			expectedReason: "Merge policy api-review requires 2 approvals from 2 groups.",
+		},
+		{
+			name:           "missing approval of a configured approver",
+			labels:         []string{"security"},
+			changes:        []string{"docs/README.md"},
+			reviews:        []github.Review{review("alice", github.ReviewStateApproved)},
+			expectedReason: "Merge policy security requires 1 approval.",
+		},
+		{
+			name:    "approval of a configured approver",
+			labels:  []string{"security"},
+			changes: []string{"docs/README.md"},
+			reviews: []github.Review{review("sec-lead", github.ReviewStateApproved)},
+		},
+		{
+			name:           "all unmet policies are reported",
+			labels:         []string{"security", "do-not-merge/hold"},
+			changes:        []string{"api/types.go"},
+			expectedReason: "Merge policy api-review requires 2 approvals from 2 groups. Merge policy security requires no label do-not-merge/hold and 1 approval. Merge policy release-notes requires an approval of an OWNERS approver of repo for the types.",
+		},
+		{
+			name:           "expression is not satisfied",
+			changes:        []string{"api/types.go"},
+			reviews:        []github.Review{review("dave", github.ReviewStateApproved)},
+			expectedReason: "Merge policy api-review requires 2 approvals from 2 groups. Merge policy release-notes requires an approval of an OWNERS approver of repo for the types.",
+		},
+		{
+			name:      "policies only apply to GitHub PRs",
+			labels:    []string{"security"},
+			notGitHub: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fakePolicyGitHubClient{
+				changes: map[int][]string{1: tc.changes},
+				reviews: map[int][]github.Review{1: tc.reviews},
+			}
+			changedFiles := &changedFilesAgent{provider: &GitHubProvider{ghc: ghc}, nextChangeCache: map[changeCacheKey][]string{}}
+			evaluator := newPolicyEvaluator(func() *config.Config { return cfg }, changedFiles, ghc, owners)
+
+			pr := PullRequest{}
+			pr.Number = 1
+			pr.Repository.Name = "repo"
+			pr.Repository.Owner.Login = "org"
+			pr.BaseRef.Name = "master"
+			for _, label := range tc.labels {
+				pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+			}
+			crc := CodeReviewCommonFromPullRequest(&pr)
+			if tc.notGitHub {
+				crc.GitHub = nil
+			}
+
+			reason, err := evaluator.unmetPolicies(crc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v.", err)
+			}
+			if reason != tc.expectedReason {
+				t.Errorf("Expected reason %q, got %q.", tc.expectedReason, reason)
+			}
+		})
+	}
+
+	var nilEvaluator *policyEvaluator
+	if reason, err := nilEvaluator.unmetPolicies(&CodeReviewCommon{}); reason != "" || err != nil {
+		t.Errorf("Expected a nil evaluator to allow merging, got %q: %v.", reason, err)
+	}
+}
+
+func TestPolicyEvaluatorCaches(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Tide.MergePolicies = []config.TideMergePolicy{{
+		Name:    "api-review",
+		Repos:   []string{"org/repo"},
+		If:      config.TidePolicyConditions{ChangedFiles: "^api/"},
+		Require: config.TidePolicyRequirements{Approvals: 1},
+	}}
+	ghc := &fakePolicyGitHubClient{
+		changes: map[int][]string{1: {"api/types.go"}},
+		reviews: map[int][]github.Review{1: {review("alice", github.ReviewStateApproved)}},
+	}
+	changedFiles := &changedFilesAgent{provider: &GitHubProvider{ghc: ghc}, nextChangeCache: map[changeCacheKey][]string{}}
+	evaluator := newPolicyEvaluator(func() *config.Config { return cfg }, changedFiles, ghc, nil)
+
+	pr := PullRequest{}
+	pr.Number = 1
+	pr.Repository.Name = "repo"
+	pr.Repository.Owner.Login = "org"
+	pr.BaseRef.Name = "master"
+	pr.HeadRefOID = "sha"
+	crc := CodeReviewCommonFromPullRequest(&pr)
+
+	evaluate := func(expectedChangesCalls, expectedReviewsCalls int) {
+		t.Helper()
+		if reason, err := evaluator.unmetPolicies(crc); err != nil || reason != "" {
+			t.Fatalf("Expected the PR to satisfy the policies, got %q: %v.", reason, err)
+		}
+		if ghc.changesCalls != expectedChangesCalls || ghc.reviewsCalls != expectedReviewsCalls {
+			t.Errorf("Expected %d calls for changes and %d for reviews, got %d and %d.", expectedChangesCalls, expectedReviewsCalls, ghc.changesCalls, ghc.reviewsCalls)
+		}
+	}
+	evaluate(1, 1)
+	evaluate(1, 1)
+	changedFiles.prune()
+	evaluator.prune()
+	evaluate(1, 1)
+
+	// a new review updates the PR, but not the changed files
+	crc.UpdatedAtTime = crc.UpdatedAtTime.Add(time.Minute)
+	evaluate(1, 2)
+
+	// a new commit changes both
+	crc.HeadRefOID = "new-sha"
+	evaluate(2, 3)
+}
@@ -71,6 +71,8 @@ type statusController struct {
 	ghc                githubClient
 	gc                 git.ClientFactory
 	usesGitHubAppsAuth bool
+	// policies checks PRs against the configured merge policies.
+	policies *policyEvaluator
 
 	// shutDown is used to signal to the main controller that the statusController
 	// has completed processing after newPoolPending is closed.
@@ -291,6 +293,10 @@ func (sc *statusController) expectedStatus(log *logrus.Entry, queryMap *config.Q
 	}
 
 	if _, ok := pool[prKey(crc)]; !ok {
+		// a frozen PR waits for the end of the freeze whatever its diff
+		if reason := frozenReason(sc.config().Tide, crc, time.Now()); reason != "" {
+			return github.StatusError, fmt.Sprintf(statusNotInPool, " "+reason), nil
+		}
 		// if the branch is blocked forget checking for a diff
 		blockingIssues := blocks.GetApplicable(crc.Org, crc.Repo, crc.BaseRefName)
 		var numbers []string
@@ -339,6 +345,11 @@ func (sc *statusController) expectedStatus(log *logrus.Entry, queryMap *config.Q
 		if !hasFullfilledQuery {
 			return github.StatusPending, fmt.Sprintf(statusNotInPool, minDiff), nil
 		}
+		if reason, err := sc.policies.unmetPolicies(crc); err != nil {
+			return "", "", fmt.Errorf("error checking merge policies: %w", err)
+		} else if reason != "" {
+			return github.StatusPending, fmt.Sprintf(statusNotInPool, " "+reason), nil
+		}
 	}
 
 	indexKey := indexKeyPassingJobs(repo, baseSHA, crc.HeadRefOID)
@@ -59,6 +59,7 @@ func TestExpectedStatus(t *testing.T) {
 		checkRuns             []CheckRun
 		inPool                bool
 		blocks                []int
+		freezes               []config.TideFreeze
 		prowJobs              []runtime.Object
 		requiredContexts      []string
 		mergeConflicts        bool
@@ -512,6 +513,34 @@ func TestExpectedStatus(t *testing.T) {
 			state: github.StatusError,
 			desc:  fmt.Sprintf(statusNotInPool, " Merging is blocked by issues 1, 2."),
 		},
+		{
+			name:              "check that freezes take precedence over other queries",
+			labels:            []string{"3", "4", "5", "6", "7"},
+			author:            "batman",
+			firstQueryAuthor:  "batman",
+			secondQueryAuthor: "batman",
+			milestone:         "v1.0",
+			inPool:            false,
+			blocks:            []int{1, 2},
+			freezes:           []config.TideFreeze{{Name: "Code Freeze", Orgs: []string{""}, ExemptionLabels: []string{"freeze-exempt"}}},
+
+			state: github.StatusError,
+			desc:  fmt.Sprintf(statusNotInPool, " Merging is frozen for Code Freeze."),
+		},
+		{
+			name:              "check that exempt PRs are not frozen",
+			labels:            []string{"3", "4", "5", "6", "7", "freeze-exempt"},
+			author:            "batman",
+			firstQueryAuthor:  "batman",
+			secondQueryAuthor: "batman",
+			milestone:         "v1.0",
+			inPool:            false,
+			blocks:            []int{1, 2},
+			freezes:           []config.TideFreeze{{Name: "Code Freeze", Orgs: []string{""}, ExemptionLabels: []string{"freeze-exempt"}}},
+
+			state: github.StatusError,
+			desc:  fmt.Sprintf(statusNotInPool, " Merging is blocked by issues 1, 2."),
+		},
 		{
 			name:             "missing passing up-to-date context",
 			inPool:           true,
@@ -815,6 +844,7 @@ func TestExpectedStatus(t *testing.T) {
 
 			ca := &config.Agent{}
 			ca.Set(&config.Config{ProwConfig: config.ProwConfig{Tide: config.Tide{
+				Freezes:          tc.freezes,
 				TideGitHubConfig: config.TideGitHubConfig{DisplayAllQueriesInStatus: tc.displayAllTideQueries}}}})
 			mmc := newMergeChecker(ca.Config, &fgc{})
 
@@ -43,6 +43,9 @@ import (
 	"k8s.io/test-infra/prow/io"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/ownersconfig"
+	"k8s.io/test-infra/prow/repoowners"
 	"k8s.io/test-infra/prow/tide/blockers"
 	"k8s.io/test-infra/prow/tide/history"
 	_ "k8s.io/test-infra/prow/version"
@@ -86,6 +89,9 @@ type syncController struct {
 	// Cache entries expire if they are not used during a sync loop.
 	changedFiles *changedFilesAgent
 
+	// policies checks PRs against the configured merge policies.
+	policies *policyEvaluator
+
 	History *history.History
 
 	// Shared fields with status controller
@@ -304,7 +310,11 @@ type Controller struct {
 // Controller.Sync() should not be used after this function is called.
 func (c *Controller) Shutdown() {
 	c.syncCtrl.History.Flush()
-	c.statusCtrl.shutdown()
+	c.syncCtrl.History.FlushArchive()
+	// Only GitHub has a status controller.
+	if c.statusCtrl != nil {
+		c.statusCtrl.shutdown()
+	}
 }
 
 func (c *Controller) Sync() error {
@@ -319,16 +329,19 @@ func (c *Controller) History() *history.History {
 	return c.syncCtrl.History
 }
 
-// NewController makes a Controller out of the given clients.
+// NewController makes a Controller out of the given clients. The plugin config
+// is optional, if it is set merge policies use its OWNERS file configuration.
 func NewController(
 	ghcSync,
 	ghcStatus github.Client,
 	mgr manager,
 	cfg config.Getter,
+	pluginsCfg func() *plugins.Configuration,
 	gc git.ClientFactory,
 	maxRecordsPerPool int,
 	opener io.Opener,
 	historyURI,
+	historyArchiveURI,
 	statusURI string,
 	logger *logrus.Entry,
 	usesGitHubAppsAuth bool,
@@ -336,7 +349,7 @@ func NewController(
 	if logger == nil {
 		logger = logrus.NewEntry(logrus.StandardLogger())
 	}
-	hist, err := history.New(maxRecordsPerPool, opener, historyURI)
+	hist, err := history.NewWithArchive(maxRecordsPerPool, opener, historyURI, historyArchiveURI)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing history client from %q: %w", historyURI, err)
 	}
@@ -354,12 +367,19 @@ func NewController(
 	if err != nil {
 		return nil, err
 	}
-	go sc.run()
 
-	syncCtrl, err := newSyncController(ctx, logger, ghcSync, mgr, cfg, gc, hist, mergeChecker, usesGitHubAppsAuth, statusUpdate)
+	provider := newGitHubProvider(logger, ghcSync, cfg, mergeChecker, usesGitHubAppsAuth)
+	syncCtrl, err := newSyncController(ctx, logger, mgr, provider, cfg, gc, hist, statusUpdate)
 	if err != nil {
 		return nil, err
 	}
+
+	// Policies share the changed files cached by the sync controller, which
+	// also prunes them.
+	policies := newPolicyEvaluator(cfg, syncCtrl.changedFiles, ghcSync, newOwnersClient(cfg, pluginsCfg, gc, ghcSync))
+	sc.policies = policies
+	syncCtrl.policies = policies
+	go sc.run()
 	return &Controller{syncCtrl: syncCtrl, statusCtrl: sc}, nil
 }
 
@@ -397,13 +417,11 @@ func newStatusController(
 func newSyncController(
 	ctx context.Context,
 	logger *logrus.Entry,
-	ghcSync githubClient,
 	mgr manager,
+	provider provider,
 	cfg config.Getter,
 	gc git.ClientFactory,
 	hist *history.History,
-	mergeChecker *mergeChecker,
-	usesGitHubAppsAuth bool,
 	statusUpdate *statusUpdate,
 ) (*syncController, error) {
 	if err := mgr.GetFieldIndexer().IndexField(
@@ -423,7 +441,6 @@ func newSyncController(
 		return nil, fmt.Errorf("failed to add index for non failed batches: %w", err)
 	}
 
-	provider := newGitHubProvider(logger, ghcSync, cfg, mergeChecker, usesGitHubAppsAuth)
 	return &syncController{
 		ctx:           ctx,
 		logger:        logger.WithField("controller", "sync"),
@@ -433,7 +450,7 @@ func newSyncController(
 		provider:      provider,
 		pickNewBatch:  pickNewBatch(gc, cfg, provider),
 		changedFiles: &changedFilesAgent{
-			ghc:             ghcSync,
+			provider:        provider,
 			nextChangeCache: make(map[changeCacheKey][]string),
 		},
 		History:      hist,
@@ -441,6 +458,35 @@ func newSyncController(
 	}, nil
 }
 
+// newOwnersClient loads the OWNERS files that merge policies refer to using
+// the OWNERS file configuration of the plugin config. Without a plugin config
+// the default OWNERS file names are used and approvers are not filtered by
+// collaborators.
+func newOwnersClient(cfg config.Getter, pluginsCfg func() *plugins.Configuration, gc git.ClientFactory, ghc github.Client) *repoowners.Client {
+	mdYAMLEnabled := func(org, repo string) bool {
+		return pluginsCfg != nil && pluginsCfg().MDYAMLEnabled(org, repo)
+	}
+	skipCollaborators := func(org, repo string) bool {
+		return pluginsCfg == nil || pluginsCfg().SkipCollaborators(org, repo)
+	}
+	ownersDirDenylist := func() *config.OwnersDirDenylist {
+		if l := cfg().OwnersDirDenylist; l != nil {
+			return l
+		}
+		return &config.OwnersDirDenylist{}
+	}
+	resolver := func(org, repo string) ownersconfig.Filenames {
+		if pluginsCfg == nil {
+			return ownersconfig.Filenames{
+				Owners:        ownersconfig.DefaultOwnersFile,
+				OwnersAliases: ownersconfig.DefaultOwnersAliasesFile,
+			}
+		}
+		return pluginsCfg().OwnersFilenames(org, repo)
+	}
+	return repoowners.NewClient(gc, ghc, mdYAMLEnabled, skipCollaborators, ownersDirDenylist, resolver)
+}
+
 func prKey(pr *CodeReviewCommon) string {
 	return fmt.Sprintf("%s#%d", string(pr.NameWithOwner), pr.Number)
 }
@@ -473,6 +519,7 @@ func (c *syncController) Sync() error {
 		tideMetrics.syncHeartbeat.WithLabelValues("sync").Inc()
 	}()
 	defer c.changedFiles.prune()
+	defer c.policies.prune()
 	c.config().BranchProtectionWarnings(c.logger, c.config().PresubmitsStatic)
 
 	c.logger.Debug("Building tide pool.")
@@ -497,7 +544,7 @@ func (c *syncController) Sync() error {
 	if err != nil {
 		return err
 	}
-	filteredPools := c.filterSubpools(c.provider.isAllowedToMerge, rawPools)
+	filteredPools := c.filterSubpools(c.mergeAllowed, rawPools)
 
 	// Notify statusController about the new pool.
 	c.statusUpdate.Lock()
@@ -700,6 +747,36 @@ func filterPR(provider provider, mergeAllowed func(*CodeReviewCommon) (string, e
 	return false
 }
 
+// mergeAllowed returns why the PR can not be merged regardless of its
+// contexts, or "" if it can be merged. It is shared by syncs and what-if
+// requests, so both agree on which PRs are eligible for the pool.
+func (c *syncController) mergeAllowed(crc *CodeReviewCommon) (string, error) {
+	if reason := frozenReason(c.config().Tide, crc, time.Now()); reason != "" {
+		return reason, nil
+	}
+	if reason, err := c.provider.isAllowedToMerge(crc); err != nil || reason != "" {
+		return reason, err
+	}
+	return c.policies.unmetPolicies(crc)
+}
+
+// frozenReason returns why a PR can not be merged during an active freeze of
+// its branch, or "" if no freeze applies or the PR is exempt from all of them.
+func frozenReason(tide config.Tide, crc *CodeReviewCommon, now time.Time) string {
+	var labels []string
+	if prLabels := crc.GitHubLabels(); prLabels != nil {
+		for _, label := range prLabels.Nodes {
+			labels = append(labels, string(label.Name))
+		}
+	}
+	for _, freeze := range tide.ActiveFreezes(crc.Org, crc.Repo, crc.BaseRefName, now) {
+		if !freeze.Exempts(labels) {
+			return fmt.Sprintf("Merging is frozen for %s.", freeze.Name)
+		}
+	}
+	return ""
+}
+
 func baseSHAMap(subpoolMap map[string]*subpool) map[string]string {
 	baseSHAs := make(map[string]string, len(subpoolMap))
 	for key, sp := range subpoolMap {
@@ -1404,7 +1481,7 @@ func (c *syncController) takeAction(sp subpool, batchPending, successes, pending
 // changedFilesAgent queries and caches the names of files changed by PRs.
 // Cache entries expire if they are not used during a sync loop.
 type changedFilesAgent struct {
-	ghc         githubClient
+	provider    provider
 	changeCache map[changeCacheKey][]string
 	// nextChangeCache caches file change info that is relevant this sync for use next sync.
 	// This becomes the new changeCache when prune() is called at the end of each sync.
@@ -1419,7 +1496,7 @@ type changeCacheKey struct {
 }
 
 // prChanges gets the files changed by the PR, either from the cache or by
-// querying GitHub.
+// querying the provider.
 func (c *changedFilesAgent) prChanges(pr *CodeReviewCommon) config.ChangedFilesProvider {
 	return func() ([]string, error) {
 		cacheKey := changeCacheKey{
@@ -1444,18 +1521,10 @@ func (c *changedFilesAgent) prChanges(pr *CodeReviewCommon) config.ChangedFilesP
 		}
 		c.RUnlock()
 
-		// We need to query the changes from GitHub.
-		changes, err := c.ghc.GetPullRequestChanges(
-			pr.Org,
-			pr.Repo,
-			pr.Number,
-		)
+		// We need to query the changes from the provider.
+		changedFiles, err := c.provider.GetChangedFiles(pr.Org, pr.Repo, pr.Number)
 		if err != nil {
-			return nil, fmt.Errorf("error getting PR changes for #%d: %w", pr.Number, err)
-		}
-		changedFiles = make([]string, 0, len(changes))
-		for _, change := range changes {
-			changedFiles = append(changedFiles, change.Filename)
+			return nil, err
 		}
 
 		c.Lock()
@@ -1738,22 +1807,33 @@ func (c *syncController) dividePool(pool map[string]CodeReviewCommon) (map[strin
 		sps[fn].prs = append(sps[fn].prs, pr)
 	}
 
-	for subpoolkey, sp := range sps {
-		pjs := &prowapi.ProwJobList{}
-		err := c.prowJobClient.List(
-			c.ctx,
-			pjs,
-			ctrlruntimeclient.MatchingFields{cacheIndexName: cacheIndexKey(sp.org, sp.repo, sp.branch, sp.sha)},
-			ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace))
+	for _, sp := range sps {
+		pjs, err := c.subpoolProwJobs(sp)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list jobs for subpool %s: %w", subpoolkey, err)
+			return nil, err
 		}
-		c.logger.WithField("subpool", subpoolkey).WithField("pj_count", len(pjs.Items)).Debug("Found prowjobs")
-		sps[subpoolkey].pjs = pjs.Items
+		sp.pjs = pjs
 	}
 	return sps, nil
 }
 
+// subpoolProwJobs lists the ProwJobs of the subpool's org, repo, branch and
+// base SHA.
+func (c *syncController) subpoolProwJobs(sp *subpool) ([]prowapi.ProwJob, error) {
+	subpoolkey := poolKey(sp.org, sp.repo, sp.branch)
+	pjs := &prowapi.ProwJobList{}
+	err := c.prowJobClient.List(
+		c.ctx,
+		pjs,
+		ctrlruntimeclient.MatchingFields{cacheIndexName: cacheIndexKey(sp.org, sp.repo, sp.branch, sp.sha)},
+		ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs for subpool %s: %w", subpoolkey, err)
+	}
+	c.logger.WithField("subpool", subpoolkey).WithField("pj_count", len(pjs.Items)).Debug("Found prowjobs")
+	return pjs.Items, nil
+}
+
 // PullRequest holds graphql data about a PR, including its commits and their
 // contexts.
 // This struct is GitHub specific
@@ -1880,6 +1960,12 @@ type searchQuery struct {
 	} `graphql:"search(type: ISSUE, first: 37, after: $searchCursor, query: $query)"`
 }
 
+type pullRequestQuery struct {
+	Repository struct {
+		PullRequest PullRequest `graphql:"pullRequest(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
 // orgRepoQueryStrings returns the GitHub query strings for given orgs and
 // repos. Make sure that this is only used by GitHub interactor.
 func orgRepoQueryStrings(orgs, repos []string, orgExceptions map[string]sets.String) map[string]string {
@@ -731,6 +731,17 @@ func (f *fgc) GetRef(o, r, ref string) (string, error) {
 }
 
 func (f *fgc) QueryWithGitHubAppsSupport(ctx context.Context, q interface{}, vars map[string]interface{}, org string) error {
+	if prq, ok := q.(*pullRequestQuery); ok {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		f.queryCalls++
+		for _, pr := range f.prs[org] {
+			if pr.Repository.Name == vars["name"] && pr.Number == vars["number"] {
+				prq.Repository.PullRequest = pr
+			}
+		}
+		return nil
+	}
 	sq, ok := q.(*searchQuery)
 	if !ok {
 		return errors.New("unexpected query type")
@@ -918,13 +929,11 @@ func TestDividePool(t *testing.T) {
 	c, err := newSyncController(
 		context.Background(),
 		log,
-		fc,
 		mgr,
+		newGitHubProvider(log, fc, configGetter, mmc, false),
 		configGetter,
 		nil,
 		nil,
-		mmc,
-		false,
 		&statusUpdate{
 			dontUpdateStatus: &threadSafePRSet{},
 			newPoolPending:   make(chan bool),
@@ -1941,13 +1950,11 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 			c, err := newSyncController(
 				context.Background(),
 				logrus.WithField("controller", "tide"),
-				&fgc,
 				newFakeManager(tc.preExistingJobs...),
+				newGitHubProvider(logrus.WithField("controller", "tide"), &fgc, ca.Config, nil, false),
 				ca.Config,
 				gc,
 				nil,
-				nil,
-				false,
 				&statusUpdate{
 					dontUpdateStatus: &threadSafePRSet{},
 					newPoolPending:   make(chan bool),
@@ -1957,7 +1964,7 @@ func testTakeAction(clients localgit.Clients, t *testing.T) {
 				t.Fatalf("failed to construct sync controller: %v", err)
 			}
 			c.changedFiles = &changedFilesAgent{
-				ghc:             &fgc,
+				provider:        &GitHubProvider{ghc: &fgc},
 				nextChangeCache: make(map[changeCacheKey][]string),
 			}
 			var batchPending []CodeReviewCommon
@@ -2243,7 +2250,7 @@ func TestSync(t *testing.T) {
 				prowJobClient: fakectrlruntimeclient.NewFakeClient(),
 				logger:        logrus.WithField("controller", "sync"),
 				changedFiles: &changedFilesAgent{
-					ghc:             fgc,
+					provider:        &GitHubProvider{ghc: fgc},
 					nextChangeCache: make(map[changeCacheKey][]string),
 				},
 				History: hist,
@@ -3145,7 +3152,7 @@ func TestPresubmitsByPull(t *testing.T) {
 			},
 			gc: nil,
 			changedFiles: &changedFilesAgent{
-				ghc:             &fgc{},
+				provider:        &GitHubProvider{ghc: &fgc{}},
 				changeCache:     tc.initialChangeCache,
 				nextChangeCache: make(map[changeCacheKey][]string),
 			},
@@ -4792,13 +4799,11 @@ func TestBatchPickingConsidersPRThatIsCurrentlyBeingSeriallyRetested(t *testing.
 	c, err := newSyncController(
 		context.Background(),
 		log,
-		ghc,
 		mgr,
+		newGitHubProvider(log, ghc, configGetter, mmc, false),
 		configGetter,
 		nil,
 		history,
-		mmc,
-		false,
 		&statusUpdate{
 			dontUpdateStatus: &threadSafePRSet{},
 			newPoolPending:   make(chan bool),
@@ -5074,13 +5079,11 @@ func TestSerialRetestingConsidersPRThatIsCurrentlyBeingSRetested(t *testing.T) {
 	c, err := newSyncController(
 		context.Background(),
 		log,
-		ghc,
 		mgr,
+		newGitHubProvider(log, ghc, configGetter, mmc, false),
 		configGetter,
 		nil,
 		history,
-		mmc,
-		false,
 		&statusUpdate{
 			dontUpdateStatus: &threadSafePRSet{},
 			newPoolPending:   make(chan bool),
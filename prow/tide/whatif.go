@@ -0,0 +1,381 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+// WhatIfRequest describes a PR and hypothetical changes to it that Tide should
+// evaluate without acting on them.
+type WhatIfRequest struct {
+	Org    string
+	Repo   string
+	Number int
+
+	// AddLabels and RemoveLabels are applied to the current labels of the PR.
+	AddLabels    []string
+	RemoveLabels []string
+	// Contexts overrides the state of status contexts and check runs on the
+	// head commit of the PR, keyed by context name.
+	Contexts map[string]githubql.StatusState
+}
+
+// WhatIfResult explains how Tide would treat a PR.
+type WhatIfResult struct {
+	Org    string
+	Repo   string
+	Number int
+	Branch string
+
+	// InPool is true if the PR would be in the merge pool.
+	InPool bool
+	// Reasons lists everything that keeps the PR out of the pool.
+	Reasons []string
+	// Queries holds the result of matching the PR against every Tide query
+	// that applies to its repo.
+	Queries []WhatIfQuery
+	// UnsuccessfulContexts lists the contexts that the context policy
+	// considers and that are not successful.
+	UnsuccessfulContexts []string
+	// Batch is the batch the PR is or would be tested in. It is empty if the PR
+	// is not in the pool, batching is disabled for the repo or the PR does not
+	// fit into the next batch.
+	Batch *WhatIfBatch
+}
+
+// WhatIfQuery is the result of matching a PR against a single Tide query.
+type WhatIfQuery struct {
+	Query   string
+	Matches bool
+	// Unmet describes the most relevant criterion of the query that the PR
+	// does not meet.
+	Unmet string
+}
+
+// WhatIfBatch describes a batch of PRs.
+type WhatIfBatch struct {
+	// Pending is true if the batch is currently being tested.
+	Pending bool
+	// PRs are the numbers of the PRs in the batch. For a batch that is not
+	// pending, this is a prediction based on the pool of the last sync that
+	// assumes that all PRs merge cleanly with each other.
+	PRs []int
+}
+
+// ServeWhatIf serves what-if requests that explain how Tide would treat a PR.
+func (c *Controller) ServeWhatIf(w http.ResponseWriter, r *http.Request) {
+	c.syncCtrl.serveWhatIf(w, r)
+}
+
+// serveWhatIf evaluates a PR for the request parameters org, repo and pr.
+// The optional and repeatable add-label, remove-label and context parameters
+// describe hypothetical changes, a context is given as <name>:<state>.
+func (c *syncController) serveWhatIf(w http.ResponseWriter, r *http.Request) {
+	req, err := parseWhatIfRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := c.whatIf(req)
+	if err != nil {
+		c.logger.WithError(err).WithField("pr", fmt.Sprintf("%s/%s#%d", req.Org, req.Repo, req.Number)).Warning("Failed to evaluate what-if request.")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		c.logger.WithError(err).Error("Encoding JSON.")
+		http.Error(w, "failed to encode result", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(b); err != nil {
+		c.logger.WithError(err).Error("Writing JSON response.")
+	}
+}
+
+func parseWhatIfRequest(r *http.Request) (WhatIfRequest, error) {
+	query := r.URL.Query()
+	req := WhatIfRequest{
+		Org:          query.Get("org"),
+		Repo:         query.Get("repo"),
+		AddLabels:    query["add-label"],
+		RemoveLabels: query["remove-label"],
+	}
+	if req.Org == "" || req.Repo == "" {
+		return req, errors.New("the org and repo parameters are required")
+	}
+	number, err := strconv.Atoi(query.Get("pr"))
+	if err != nil || number <= 0 {
+		return req, fmt.Errorf("invalid pr parameter %q", query.Get("pr"))
+	}
+	req.Number = number
+
+	for _, context := range query["context"] {
+		i := strings.LastIndex(context, ":")
+		if i <= 0 {
+			return req, fmt.Errorf("context %q must have the form <name>:<state>", context)
+		}
+		state := githubql.StatusState(strings.ToUpper(context[i+1:]))
+		switch state {
+		case githubql.StatusStateError, githubql.StatusStateFailure, githubql.StatusStatePending, githubql.StatusStateSuccess, githubql.StatusStateExpected:
+		default:
+			return req, fmt.Errorf("context %q has unknown state %q", context, context[i+1:])
+		}
+		if req.Contexts == nil {
+			req.Contexts = map[string]githubql.StatusState{}
+		}
+		req.Contexts[context[:i]] = state
+	}
+	return req, nil
+}
+
+// whatIf evaluates the PR of the request the same way a sync does, after
+// applying the hypothetical changes of the request.
+func (c *syncController) whatIf(req WhatIfRequest) (*WhatIfResult, error) {
+	gh, ok := c.provider.(*GitHubProvider)
+	if !ok {
+		return nil, errors.New("what-if requests are only supported for GitHub")
+	}
+	pr, err := gh.pullRequest(req.Org, req.Repo, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	crc := CodeReviewCommonFromPullRequest(pr)
+	log := c.logger.WithFields(crc.logFields())
+	// headContexts adds the head commit to the PR if the query did not return
+	// it, so the hypothetical contexts can be applied to it.
+	contexts, err := c.provider.headContexts(crc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head contexts: %w", err)
+	}
+	contexts = applyWhatIfRequest(crc.GitHub, contexts, req)
+
+	result := &WhatIfResult{
+		Org:    crc.Org,
+		Repo:   crc.Repo,
+		Number: crc.Number,
+		Branch: crc.BaseRefName,
+	}
+
+	baseSHA, err := c.provider.GetRef(crc.Org, crc.Repo, "heads/"+crc.BaseRefName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the base SHA of branch %s: %w", crc.BaseRefName, err)
+	}
+	cc, err := c.provider.GetTideContextPolicy(c.gc, crc.Org, crc.Repo, crc.BaseRefName, refGetterFactory(baseSHA), crc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up context checker: %w", err)
+	}
+
+	if reason, err := c.mergeAllowed(crc); err != nil {
+		return nil, fmt.Errorf("error checking if merge is allowed: %w", err)
+	} else if reason != "" {
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	c.statusUpdate.Lock()
+	blocks := c.statusUpdate.blocks
+	c.statusUpdate.Unlock()
+	for _, issue := range blocks.GetApplicable(crc.Org, crc.Repo, crc.BaseRefName) {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("Merging is blocked by issue %d.", issue.Number))
+	}
+
+	var matchesQuery bool
+	for _, q := range c.config().Tide.Queries.QueryMap().ForRepo(config.OrgRepo{Org: crc.Org, Repo: crc.Repo}) {
+		unmet, diff := requirementDiff(crc.GitHub, &q, cc)
+		result.Queries = append(result.Queries, WhatIfQuery{
+			Query:   q.Query(),
+			Matches: diff == 0,
+			Unmet:   strings.TrimSpace(unmet),
+		})
+		matchesQuery = matchesQuery || diff == 0
+	}
+	if !matchesQuery {
+		result.Reasons = append(result.Reasons, "PR does not match any Tide query.")
+	}
+
+	// Only pending contexts of required presubmits are allowed, because Tide
+	// retests those itself. See filterPR.
+	sp := &subpool{
+		log:    log,
+		org:    crc.Org,
+		repo:   crc.Repo,
+		branch: crc.BaseRefName,
+		sha:    baseSHA,
+		prs:    []CodeReviewCommon{*crc},
+	}
+	presubmits, err := c.presubmitsByPull(sp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine required presubmits: %w", err)
+	}
+	prowContexts := sets.NewString()
+	for _, ps := range presubmits[crc.Number] {
+		prowContexts.Insert(ps.Context)
+	}
+	for _, ctx := range unsuccessfulContexts(contexts, cc, log) {
+		name := string(ctx.Context)
+		result.UnsuccessfulContexts = append(result.UnsuccessfulContexts, name)
+		if ctx.State != githubql.StatusStatePending {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("Context %s is in state %s.", name, ctx.State))
+		} else if !prowContexts.Has(name) {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("Context %s is pending but not a required Prow job.", name))
+		}
+	}
+
+	result.InPool = len(result.Reasons) == 0
+	if result.InPool {
+		if result.Batch, err = c.whatIfBatch(log, crc, baseSHA, cc); err != nil {
+			return nil, fmt.Errorf("failed to pick the batch: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// applyWhatIfRequest applies the hypothetical label and context changes of
+// req to pr, whose head commit has the given contexts. It returns the
+// contexts of the head commit after the changes.
+func applyWhatIfRequest(pr *PullRequest, contexts []Context, req WhatIfRequest) []Context {
+	remove := sets.NewString(req.RemoveLabels...)
+	labels := pr.Labels.Nodes[:0:0]
+	for _, label := range pr.Labels.Nodes {
+		if !remove.Has(string(label.Name)) {
+			labels = append(labels, label)
+		}
+	}
+	for _, name := range req.AddLabels {
+		if remove.Has(name) || hasLabel(labels, name) {
+			continue
+		}
+		labels = append(labels, struct{ Name githubql.String }{Name: githubql.String(name)})
+	}
+	pr.Labels.Nodes = labels
+
+	if len(req.Contexts) == 0 {
+		return contexts
+	}
+	applied := make([]Context, 0, len(contexts)+len(req.Contexts))
+	for _, ctx := range contexts {
+		if _, overridden := req.Contexts[string(ctx.Context)]; !overridden {
+			applied = append(applied, ctx)
+		}
+	}
+	var names []string
+	for name := range req.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		applied = append(applied, Context{Context: githubql.String(name), State: req.Contexts[name]})
+	}
+
+	// Copy the commits, as they are shared with the PR the request was
+	// created from. The check runs of the head commit are already part of
+	// its contexts, so only the contexts are kept.
+	nodes := make([]struct{ Commit Commit }, len(pr.Commits.Nodes))
+	copy(nodes, pr.Commits.Nodes)
+	pr.Commits.Nodes = nodes
+	for i := range nodes {
+		commit := &nodes[i].Commit
+		if commit.OID != pr.HeadRefOID {
+			continue
+		}
+		commit.StatusCheckRollup.Contexts.Nodes = nil
+		commit.Status.Contexts = applied
+	}
+	return applied
+}
+
+func hasLabel(labels []struct{ Name githubql.String }, name string) bool {
+	for _, label := range labels {
+		if string(label.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// whatIfBatch returns the batch that the pool PR crc is or would be tested in.
+// The next batch is picked the same way a sync picks it, from the pool of the
+// last sync and crc in its hypothetical state.
+func (c *syncController) whatIfBatch(log *logrus.Entry, crc *CodeReviewCommon, baseSHA string, cc contextChecker) (*WhatIfBatch, error) {
+	sp := subpool{
+		log:    log,
+		org:    crc.Org,
+		repo:   crc.Repo,
+		branch: crc.BaseRefName,
+		sha:    baseSHA,
+		prs:    []CodeReviewCommon{*crc},
+		cc:     map[int]contextChecker{crc.Number: cc},
+	}
+	c.m.Lock()
+	for _, pool := range c.pools {
+		if pool.Org != crc.Org || pool.Repo != crc.Repo || pool.Branch != crc.BaseRefName {
+			continue
+		}
+		pending := sets.NewInt(prNumbers(pool.BatchPending)...)
+		if pending.Has(crc.Number) {
+			c.m.Unlock()
+			return &WhatIfBatch{Pending: true, PRs: pending.List()}, nil
+		}
+		for _, prs := range [][]CodeReviewCommon{pool.SuccessPRs, pool.PendingPRs, pool.MissingPRs} {
+			for _, pr := range prs {
+				if pr.Number != crc.Number && !pending.Has(pr.Number) {
+					sp.prs = append(sp.prs, pr)
+				}
+			}
+		}
+	}
+	c.m.Unlock()
+	// Like in a sync, a batch is only tested if there is another PR for it.
+	if len(sp.prs) < 2 {
+		return nil, nil
+	}
+
+	for _, pr := range sp.prs[1:] {
+		var err error
+		sp.cc[pr.Number], err = c.provider.GetTideContextPolicy(c.gc, sp.org, sp.repo, sp.branch, refGetterFactory(baseSHA), &pr)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up context checker for pr %d: %w", pr.Number, err)
+		}
+	}
+	var err error
+	if sp.pjs, err = c.subpoolProwJobs(&sp); err != nil {
+		return nil, err
+	}
+	batch, _, err := c.pickBatch(sp, sp.cc, c.pickNewBatch)
+	if err != nil {
+		return nil, err
+	}
+	numbers := sets.NewInt(prNumbers(batch)...)
+	if len(batch) < 2 || !numbers.Has(crc.Number) {
+		return nil, nil
+	}
+	return &WhatIfBatch{PRs: numbers.List()}, nil
+}
@@ -0,0 +1,371 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestParseWhatIfRequest(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       string
+		expected    WhatIfRequest
+		expectedErr bool
+	}{
+		{
+			name:     "plain PR",
+			query:    "org=org&repo=repo&pr=1",
+			expected: WhatIfRequest{Org: "org", Repo: "repo", Number: 1},
+		},
+		{
+			name:  "labels and contexts",
+			query: "org=org&repo=repo&pr=1&add-label=lgtm&add-label=approved&remove-label=hold&context=ci/circleci:%20build:success",
+			expected: WhatIfRequest{
+				Org:          "org",
+				Repo:         "repo",
+				Number:       1,
+				AddLabels:    []string{"lgtm", "approved"},
+				RemoveLabels: []string{"hold"},
+				Contexts:     map[string]githubql.StatusState{"ci/circleci: build": githubql.StatusStateSuccess},
+			},
+		},
+		{
+			name:        "missing repo",
+			query:       "org=org&pr=1",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid PR number",
+			query:       "org=org&repo=repo&pr=abc",
+			expectedErr: true,
+		},
+		{
+			name:        "context without state",
+			query:       "org=org&repo=repo&pr=1&context=test",
+			expectedErr: true,
+		},
+		{
+			name:        "context with unknown state",
+			query:       "org=org&repo=repo&pr=1&context=test:green",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := parseWhatIfRequest(httptest.NewRequest(http.MethodGet, "/what-if?"+tc.query, nil))
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, req); diff != "" {
+				t.Errorf("request differs from expected: %s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyWhatIfRequest(t *testing.T) {
+	pr := PullRequest{HeadRefOID: "head"}
+	pr.Labels.Nodes = []struct{ Name githubql.String }{{Name: "hold"}, {Name: "lgtm"}}
+	pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{OID: "head"}}}
+	pr.Commits.Nodes[0].Commit.Status.Contexts = []Context{
+		{Context: "unit", State: githubql.StatusStateFailure},
+		{Context: "lint", State: githubql.StatusStateSuccess},
+	}
+	pr.Commits.Nodes[0].Commit.StatusCheckRollup.Contexts.Nodes = []CheckRunNode{
+		{CheckRun: CheckRun{Name: "e2e", Conclusion: "FAILURE", Status: "COMPLETED"}},
+	}
+	original := pr.Commits.Nodes
+	contexts := []Context{
+		{Context: "unit", State: githubql.StatusStateFailure},
+		{Context: "lint", State: githubql.StatusStateSuccess},
+		{Context: "e2e", State: githubql.StatusStateFailure},
+		{Context: "docs", State: githubql.StatusStateSuccess},
+	}
+
+	applied := applyWhatIfRequest(&pr, contexts, WhatIfRequest{
+		AddLabels:    []string{"approved", "lgtm"},
+		RemoveLabels: []string{"hold"},
+		Contexts: map[string]githubql.StatusState{
+			"unit": githubql.StatusStateSuccess,
+			"e2e":  githubql.StatusStateSuccess,
+		},
+	})
+
+	var labels []string
+	for _, label := range pr.Labels.Nodes {
+		labels = append(labels, string(label.Name))
+	}
+	if diff := cmp.Diff([]string{"lgtm", "approved"}, labels); diff != "" {
+		t.Errorf("labels differ from expected: %s", diff)
+	}
+	expectedContexts := []Context{
+		{Context: "lint", State: githubql.StatusStateSuccess},
+		{Context: "docs", State: githubql.StatusStateSuccess},
+		{Context: "e2e", State: githubql.StatusStateSuccess},
+		{Context: "unit", State: githubql.StatusStateSuccess},
+	}
+	if diff := cmp.Diff(expectedContexts, applied); diff != "" {
+		t.Errorf("returned contexts differ from expected: %s", diff)
+	}
+	commit := pr.Commits.Nodes[0].Commit
+	if diff := cmp.Diff(expectedContexts, commit.Status.Contexts); diff != "" {
+		t.Errorf("contexts differ from expected: %s", diff)
+	}
+	if len(commit.StatusCheckRollup.Contexts.Nodes) != 0 {
+		t.Errorf("expected the check runs to be replaced by contexts, got %v", commit.StatusCheckRollup.Contexts.Nodes)
+	}
+	if state := original[0].Commit.Status.Contexts[0].State; state != githubql.StatusStateFailure {
+		t.Errorf("expected the original commits to be unchanged, unit context is %s", state)
+	}
+}
+
+func TestWhatIf(t *testing.T) {
+	pr := PullRequest{
+		Number:     1,
+		HeadRefOID: "head",
+		Mergeable:  githubql.MergeableStateMergeable,
+	}
+	pr.BaseRef.Name = "master"
+	pr.Repository.Name = "repo"
+	pr.Repository.NameWithOwner = "org/repo"
+	pr.Repository.Owner.Login = "org"
+	pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{OID: "head"}}}
+	pr.Commits.Nodes[0].Commit.Status.Contexts = []Context{{Context: "pj-a", State: githubql.StatusStateFailure}}
+	poolPR := func(number int, state githubql.StatusState) CodeReviewCommon {
+		pr := PullRequest{Number: githubql.Int(number), HeadRefOID: githubql.String(fmt.Sprintf("head-%d", number))}
+		pr.BaseRef.Name = "master"
+		pr.Repository.Name = "repo"
+		pr.Repository.Owner.Login = "org"
+		pr.Commits.Nodes = []struct{ Commit Commit }{{Commit: Commit{OID: pr.HeadRefOID}}}
+		pr.Commits.Nodes[0].Commit.Status.Contexts = []Context{{Context: "pj-a", State: state}}
+		return *CodeReviewCommonFromPullRequest(&pr)
+	}
+
+	testCases := []struct {
+		name       string
+		req        WhatIfRequest
+		batchLimit int
+		freezes    []config.TideFreeze
+		pools      []Pool
+		expected   WhatIfResult
+	}{
+		{
+			name: "current state is not in the pool",
+			req:  WhatIfRequest{Org: "org", Repo: "repo", Number: 1},
+			expected: WhatIfResult{
+				Reasons: []string{
+					"PR does not match any Tide query.",
+					"Context pj-a is in state FAILURE.",
+				},
+				Queries:              []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Unmet: "Needs lgtm label."}},
+				UnsuccessfulContexts: []string{"pj-a"},
+			},
+		},
+		{
+			name: "label and passing job put the PR into the pool and the next batch",
+			req: WhatIfRequest{
+				Org:       "org",
+				Repo:      "repo",
+				Number:    1,
+				AddLabels: []string{"lgtm"},
+				Contexts:  map[string]githubql.StatusState{"pj-a": githubql.StatusStateSuccess},
+			},
+			pools: []Pool{{
+				Org:        "org",
+				Repo:       "repo",
+				Branch:     "master",
+				SuccessPRs: []CodeReviewCommon{poolPR(4, githubql.StatusStateSuccess)},
+				MissingPRs: []CodeReviewCommon{poolPR(2, githubql.StatusStateFailure)},
+			}},
+			expected: WhatIfResult{
+				InPool:  true,
+				Queries: []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Matches: true}},
+				Batch:   &WhatIfBatch{PRs: []int{1, 4}},
+			},
+		},
+		{
+			name: "batch is limited to the oldest PRs",
+			req: WhatIfRequest{
+				Org:       "org",
+				Repo:      "repo",
+				Number:    1,
+				AddLabels: []string{"lgtm"},
+				Contexts:  map[string]githubql.StatusState{"pj-a": githubql.StatusStateSuccess},
+			},
+			batchLimit: 2,
+			pools: []Pool{{
+				Org:        "org",
+				Repo:       "repo",
+				Branch:     "master",
+				SuccessPRs: []CodeReviewCommon{poolPR(3, githubql.StatusStateSuccess), poolPR(2, githubql.StatusStateSuccess)},
+			}},
+			expected: WhatIfResult{
+				InPool:  true,
+				Queries: []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Matches: true}},
+				Batch:   &WhatIfBatch{PRs: []int{1, 2}},
+			},
+		},
+		{
+			name: "active freeze keeps the PR out of the pool",
+			req: WhatIfRequest{
+				Org:       "org",
+				Repo:      "repo",
+				Number:    1,
+				AddLabels: []string{"lgtm"},
+				Contexts:  map[string]githubql.StatusState{"pj-a": githubql.StatusStateSuccess},
+			},
+			freezes: []config.TideFreeze{{Name: "Code Freeze", Repos: []string{"org/repo"}, Start: time.Now().Add(-time.Hour)}},
+			expected: WhatIfResult{
+				Reasons: []string{"Merging is frozen for Code Freeze."},
+				Queries: []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Matches: true}},
+			},
+		},
+		{
+			name: "PR alone in the pool is not batched",
+			req: WhatIfRequest{
+				Org:       "org",
+				Repo:      "repo",
+				Number:    1,
+				AddLabels: []string{"lgtm"},
+				Contexts:  map[string]githubql.StatusState{"pj-a": githubql.StatusStateSuccess},
+			},
+			expected: WhatIfResult{
+				InPool:  true,
+				Queries: []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Matches: true}},
+			},
+		},
+		{
+			name: "pending prow job keeps the PR in the pending batch",
+			req: WhatIfRequest{
+				Org:       "org",
+				Repo:      "repo",
+				Number:    1,
+				AddLabels: []string{"lgtm"},
+				Contexts:  map[string]githubql.StatusState{"pj-a": githubql.StatusStatePending},
+			},
+			pools: []Pool{{
+				Org:          "org",
+				Repo:         "repo",
+				Branch:       "master",
+				BatchPending: []CodeReviewCommon{{Number: 1}, {Number: 3}},
+			}},
+			expected: WhatIfResult{
+				InPool:               true,
+				Queries:              []WhatIfQuery{{Query: `is:pr state:open archived:false label:"lgtm" repo:"org/repo"`, Matches: true}},
+				UnsuccessfulContexts: []string{"pj-a"},
+				Batch:                &WhatIfBatch{Pending: true, PRs: []int{1, 3}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Tide.Queries = config.TideQueries{{Repos: []string{"org/repo"}, Labels: []string{"lgtm"}}}
+			cfg.Tide.BatchSizeLimitMap = map[string]int{"*": tc.batchLimit}
+			cfg.Tide.Freezes = tc.freezes
+			if err := cfg.SetPresubmits(map[string][]config.Presubmit{
+				"org/repo": {{JobBase: config.JobBase{Name: "pj-a"}, Reporter: config.Reporter{Context: "pj-a"}, AlwaysRun: true}},
+			}); err != nil {
+				t.Fatalf("failed to set presubmits: %v", err)
+			}
+			cfgAgent := &config.Agent{}
+			cfgAgent.Set(cfg)
+
+			ghc := &fgc{
+				prs:  map[string][]PullRequest{"org": {pr}},
+				refs: map[string]string{"org/repo heads/master": "base"},
+			}
+			mgr := newFakeManager()
+			if err := mgr.GetFieldIndexer().IndexField(context.Background(), &prowapi.ProwJob{}, cacheIndexName, cacheIndexFunc); err != nil {
+				t.Fatalf("failed to add index: %v", err)
+			}
+			c := &syncController{
+				ctx:           context.Background(),
+				config:        cfgAgent.Config,
+				prowJobClient: mgr.GetClient(),
+				provider: &GitHubProvider{
+					cfg:          cfgAgent.Config,
+					ghc:          ghc,
+					mergeChecker: newMergeChecker(cfgAgent.Config, ghc),
+					logger:       logrus.WithField("controller", "sync"),
+				},
+				logger: logrus.WithField("controller", "sync"),
+				changedFiles: &changedFilesAgent{
+					provider:        &GitHubProvider{ghc: ghc},
+					nextChangeCache: make(map[changeCacheKey][]string),
+				},
+				statusUpdate: &statusUpdate{},
+				pools:        tc.pools,
+				pickNewBatch: func(sp subpool, candidates []CodeReviewCommon, maxBatchSize int) ([]CodeReviewCommon, error) {
+					if maxBatchSize > 0 && len(candidates) > maxBatchSize {
+						candidates = candidates[:maxBatchSize]
+					}
+					return candidates, nil
+				},
+			}
+
+			s := httptest.NewServer(http.HandlerFunc(c.serveWhatIf))
+			defer s.Close()
+			query := s.URL + "?org=org&repo=repo&pr=1"
+			for _, label := range tc.req.AddLabels {
+				query += "&add-label=" + label
+			}
+			for name, state := range tc.req.Contexts {
+				query += "&context=" + name + ":" + string(state)
+			}
+			resp, err := http.Get(query)
+			if err != nil {
+				t.Fatalf("GET error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+			var result WhatIfResult
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("JSON decoding error: %v", err)
+			}
+
+			tc.expected.Org, tc.expected.Repo, tc.expected.Number, tc.expected.Branch = "org", "repo", 1, "master"
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("result differs from expected: %s", diff)
+			}
+		})
+	}
+}